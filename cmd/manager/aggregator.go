@@ -25,10 +25,11 @@ import (
 	"html"
 	"io"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/antchfx/xmlquery"
 	semver "github.com/blang/semver/v4"
 	backoff "github.com/cenkalti/backoff/v4"
 	"github.com/dsnet/compress/bzip2"
@@ -53,6 +54,7 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 
 	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/cloudevents"
 	"github.com/openshift/compliance-operator/pkg/controller/common"
 	"github.com/openshift/compliance-operator/pkg/utils"
 )
@@ -61,6 +63,13 @@ const (
 	configMapRemediationsProcessed = "compliance-remediations/processed"
 	configMapCompressed            = "openscap-scan-result/compressed"
 	apiserverOperatorName          = "openshift-apiserver"
+	// The time the aggregator waits for the CloudEvents of the check
+	// results to be sent before exiting
+	aggregatorEventsFlushTimeout = 2 * time.Minute
+	// The number of result ConfigMaps listed, and thus held in memory, at once
+	configMapPageSize = 10
+	// The number of check results created or updated at once by default
+	defaultAggregatorParallelism = 5
 )
 
 var aggregatorCmd = &cobra.Command{
@@ -76,9 +85,13 @@ func init() {
 }
 
 type aggregatorConfig struct {
-	Content   string
-	ScanName  string
-	Namespace string
+	Content       string
+	ContentDigest string
+	ScanName      string
+	Namespace     string
+	Parallelism   int
+	// Where the CloudEvents of the check results are posted, if anywhere
+	CloudEventsSink string
 }
 
 type aggregatorCrClient interface {
@@ -108,8 +121,11 @@ func createAggregatorCrClient(config *rest.Config) (aggregatorCrClient, error) {
 
 func defineAggregatorFlags(cmd *cobra.Command) {
 	cmd.Flags().String("content", "", "The path to the OpenScap content")
+	cmd.Flags().String("content-digest", "", "The SHA-256 digest the content must have, if it was verified.")
 	cmd.Flags().String("scan", "", "The compliance scan that owns the configMap objects.")
 	cmd.Flags().String("namespace", "openshift-compliance", "Running pod namespace.")
+	cmd.Flags().String("cloudevents-sink", "", "The URL the CloudEvents of the check results are posted to, if any.")
+	cmd.Flags().Int("parallelism", defaultAggregatorParallelism, "The number of check results to create or update at once.")
 
 	flags := cmd.Flags()
 	flags.AddFlagSet(zap.FlagSet())
@@ -122,18 +138,24 @@ func defineAggregatorFlags(cmd *cobra.Command) {
 func parseAggregatorConfig(cmd *cobra.Command) *aggregatorConfig {
 	var conf aggregatorConfig
 	conf.Content = getValidStringArg(cmd, "content")
+	conf.ContentDigest, _ = cmd.Flags().GetString("content-digest")
 	conf.ScanName = getValidStringArg(cmd, "scan")
 	conf.Namespace = getValidStringArg(cmd, "namespace")
+	conf.CloudEventsSink, _ = cmd.Flags().GetString("cloudevents-sink")
+	conf.Parallelism, _ = cmd.Flags().GetInt("parallelism")
+	if conf.Parallelism < 1 {
+		conf.Parallelism = 1
+	}
 
 	logf.SetLogger(zap.Logger())
 
 	return &conf
 }
 
-func getScanConfigMaps(crClient aggregatorCrClient, scan, namespace string) ([]v1.ConfigMap, error) {
-	cMapList := &v1.ConfigMapList{}
-	var err error
-
+// forEachScanConfigMap calls fn with each of the result ConfigMaps of a scan,
+// along with their total number, or 0 if it isn't known. The ConfigMaps are
+// listed a page at a time, so that only a page of them is held in memory.
+func forEachScanConfigMap(crClient aggregatorCrClient, scan, namespace string, fn func(cm *v1.ConfigMap, total int) error) error {
 	// Look for configMap with this scan label
 	inNs := client.InNamespace(namespace)
 	withLabel := client.MatchingLabels{
@@ -141,19 +163,41 @@ func getScanConfigMaps(crClient aggregatorCrClient, scan, namespace string) ([]v
 		compv1alpha1.ResultLabel:         "",
 	}
 
-	err = crClient.getClient().List(context.TODO(), cMapList, inNs, withLabel)
-	if err != nil {
-		log.Error(err, "Error waiting for CMs of scan", "ComplianceScan.Name", scan)
-		return nil, err
-	}
+	total := 0
+	continueToken := ""
+	for page := 0; ; page++ {
+		cMapList := &v1.ConfigMapList{}
+		err := crClient.getClient().List(context.TODO(), cMapList, inNs, withLabel,
+			client.Limit(configMapPageSize), client.Continue(continueToken))
+		if err != nil {
+			log.Error(err, "Error waiting for CMs of scan", "ComplianceScan.Name", scan)
+			return err
+		}
 
-	if len(cMapList.Items) == 0 {
-		log.Info("Scan has no results", "ComplianceScan.Name", scan)
-		return make([]v1.ConfigMap, 0), nil
-	}
+		if page == 0 {
+			if cMapList.Continue == "" {
+				total = len(cMapList.Items)
+			} else if cMapList.RemainingItemCount != nil {
+				total = len(cMapList.Items) + int(*cMapList.RemainingItemCount)
+			}
+			if len(cMapList.Items) == 0 {
+				log.Info("Scan has no results", "ComplianceScan.Name", scan)
+				return nil
+			}
+			log.Info("Scan has results", "ComplianceScan.Name", scan, "results-length", total)
+		}
 
-	log.Info("Scan has results", "ComplianceScan.Name", scan, "results-length", len(cMapList.Items))
-	return cMapList.Items, nil
+		for i := range cMapList.Items {
+			if err := fn(&cMapList.Items[i], total); err != nil {
+				return err
+			}
+		}
+
+		continueToken = cMapList.Continue
+		if continueToken == "" {
+			return nil
+		}
+	}
 }
 
 func readCompressedData(compressed string) (*bzip2.Reader, error) {
@@ -171,7 +215,7 @@ func readCompressedData(compressed string) (*bzip2.Reader, error) {
 // Returns a triple of (array-of-ParseResults, source, error) where source identifies the entity whose
 // scan produced this configMap -- typically a nodeName for node scans. For platform scans, the source
 // is empty. The source is used later when reconciling inconsistent results
-func parseResultRemediations(scheme *runtime.Scheme, scanName, namespace string, content *xmlquery.Node, cm *v1.ConfigMap) ([]*utils.ParseResult, string, error) {
+func parseResultRemediations(scheme *runtime.Scheme, scanName, namespace string, content *utils.ContentTables, cm *v1.ConfigMap) ([]*utils.ParseResult, string, error) {
 	var scanReader io.Reader
 
 	_, ok := cm.Annotations[configMapRemediationsProcessed]
@@ -201,8 +245,20 @@ func parseResultRemediations(scheme *runtime.Scheme, scanName, namespace string,
 	// This would return an empty string for a platform check that is handled later explicitly
 	nodeName := cm.Annotations["openscap-scan-result/node"]
 
-	table, err := utils.ParseResultsFromContentAndXccdf(scheme, scanName, namespace, content, scanReader)
-	return table, nodeName, err
+	table, err := utils.ParseResultsFromContentTablesAndXccdf(scheme, scanName, namespace, content, scanReader)
+	if err != nil {
+		return table, nodeName, err
+	}
+
+	// Only platform scans evaluate CustomRules
+	if customRuleResults, ok := cm.Data[compv1alpha1.CustomRuleResultsKey]; ok {
+		customTable, err := utils.ParseCustomRuleResults(scanName, namespace, customRuleResults)
+		if err != nil {
+			return nil, nodeName, err
+		}
+		table = append(table, customTable...)
+	}
+	return table, nodeName, nil
 }
 
 func getScanResult(cm *v1.ConfigMap) (compv1alpha1.ComplianceScanStatusResult, string) {
@@ -226,6 +282,11 @@ func getScanResult(cm *v1.ConfigMap) (compv1alpha1.ComplianceScanStatusResult, s
 
 func annotateCMWithScanResult(cm *v1.ConfigMap, cmParsedResults []*utils.ParseResult) *v1.ConfigMap {
 	scanResult, errMsg := getScanResult(cm)
+	if scanResult == compv1alpha1.ResultCompliant && customRuleFailed(cmParsedResults) {
+		// OpenSCAP doesn't know about the CustomRules, so its exit code
+		// doesn't account for them
+		scanResult = compv1alpha1.ResultNonCompliant
+	}
 	if scanResult == compv1alpha1.ResultCompliant {
 		// Special case: If the OS didn't match at all and SCAP skipped all the tests,
 		// then we would have gotten COMPLIANT. Let's make sure that at least one
@@ -258,16 +319,56 @@ func annotateCMWithScanResult(cm *v1.ConfigMap, cmParsedResults []*utils.ParseRe
 	return cm.DeepCopy()
 }
 
-func markConfigMapAsProcessed(crClient aggregatorCrClient, cm *v1.ConfigMap) error {
-	cmCopy := cm.DeepCopy()
+func customRuleFailed(cmParsedResults []*utils.ParseResult) bool {
+	for i := range cmParsedResults {
+		if cmParsedResults[i] == nil || cmParsedResults[i].CheckResult == nil {
+			continue
+		}
+		if _, ok := cmParsedResults[i].CheckResult.Labels[compv1alpha1.CustomRuleLabel]; !ok {
+			continue
+		}
+		if cmParsedResults[i].CheckResult.Status == compv1alpha1.CheckResultFail {
+			return true
+		}
+	}
+	return false
+}
+
+// processedConfigMap remembers the annotations to set on a result ConfigMap
+// once its results are saved, so that the ConfigMap itself needn't be kept
+// in memory until then
+type processedConfigMap struct {
+	key         types.NamespacedName
+	annotations map[string]string
+}
 
-	if cmCopy.Annotations == nil {
-		cmCopy.Annotations = make(map[string]string)
+func newProcessedConfigMap(cm *v1.ConfigMap) processedConfigMap {
+	processed := processedConfigMap{
+		key:         getObjKey(cm.Name, cm.Namespace),
+		annotations: make(map[string]string),
+	}
+	for _, key := range []string{compv1alpha1.CmScanResultAnnotation, compv1alpha1.CmScanResultErrMsg} {
+		if value, ok := cm.Annotations[key]; ok {
+			processed.annotations[key] = value
+		}
 	}
-	cmCopy.Annotations[configMapRemediationsProcessed] = ""
+	return processed
+}
 
+func markConfigMapAsProcessed(crClient aggregatorCrClient, processed processedConfigMap) error {
 	err := backoff.Retry(func() error {
-		return crClient.getClient().Update(context.TODO(), cmCopy)
+		cm := &v1.ConfigMap{}
+		if err := crClient.getClient().Get(context.TODO(), processed.key, cm); err != nil {
+			return err
+		}
+		if cm.Annotations == nil {
+			cm.Annotations = make(map[string]string)
+		}
+		for key, value := range processed.annotations {
+			cm.Annotations[key] = value
+		}
+		cm.Annotations[configMapRemediationsProcessed] = ""
+		return crClient.getClient().Update(context.TODO(), cm)
 	}, backoff.WithMaxRetries(backoff.NewExponentialBackOff(), maxRetries))
 	return err
 }
@@ -498,6 +599,10 @@ func getCheckResultLabels(pr *utils.ParseResult, resultLabels map[string]string,
 		labels[compv1alpha1.ComplianceCheckResultHasRemediation] = ""
 	}
 
+	if customRule, ok := pr.CheckResult.Labels[compv1alpha1.CustomRuleLabel]; ok {
+		labels[compv1alpha1.CustomRuleLabel] = customRule
+	}
+
 	for k, v := range resultLabels {
 		labels[k] = v
 	}
@@ -515,59 +620,134 @@ func getCheckResultAnnotations(cr *compv1alpha1.ComplianceCheckResult, resultAnn
 	return annotations
 }
 
-func createResults(crClient aggregatorCrClient, scan *compv1alpha1.ComplianceScan, consistentResults []*utils.ParseResultContextItem) error {
+// checkRegressed returns whether a check that passed in the previous run of
+// the scan fails in this one
+func checkRegressed(previous, current compv1alpha1.ComplianceCheckStatus) bool {
+	return previous == compv1alpha1.CheckResultPass && current == compv1alpha1.CheckResultFail
+}
+
+// retainsCheckResult returns whether the check result is kept in the cluster
+// as per the check result retention settings of the scan. The check results
+// of the rules that have remediations are always kept, as the remediations
+// are owned by them.
+func retainsCheckResult(scan *compv1alpha1.ComplianceScan, pr *utils.ParseResultContextItem) bool {
+	retention := scan.Spec.CheckResultRetention
+	if retention == nil || len(pr.Remediations) > 0 {
+		return true
+	}
+	return retention.Retains(pr.CheckResult, scan.Status.CurrentIndex)
+}
+
+// createResults creates or updates the check results and their remediations,
+// parallelism of them at once. It stops at the first error.
+func createResults(crClient aggregatorCrClient, scan *compv1alpha1.ComplianceScan, consistentResults []*utils.ParseResultContextItem,
+	summary *checkResultSummary, parallelism int, progress *aggregatorProgress) error {
 	log.Info("Will create result objects", "objects", len(consistentResults))
 	if len(consistentResults) == 0 {
 		log.Info("Nothing to create")
 		return nil
 	}
 
+	work := make(chan *utils.ParseResultContextItem)
+	failed := make(chan struct{})
+	var firstErr error
+	var failOnce sync.Once
+	var wg sync.WaitGroup
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pr := range work {
+				if err := createResult(crClient, scan, pr, summary); err != nil {
+					failOnce.Do(func() {
+						firstErr = err
+						close(failed)
+					})
+					return
+				}
+				progress.checkResultProcessed()
+			}
+		}()
+	}
+
+feed:
 	for _, pr := range consistentResults {
-		if pr == nil || pr.CheckResult == nil {
-			log.Info("nil result or result.check, this shouldn't happen")
-			continue
+		select {
+		case work <- pr:
+		case <-failed:
+			break feed
 		}
+	}
+	close(work)
+	wg.Wait()
+	return firstErr
+}
 
-		checkResultLabels := getCheckResultLabels(&pr.ParseResult, pr.Labels, scan)
-		checkResultAnnotations := getCheckResultAnnotations(pr.CheckResult, pr.Annotations)
+func createResult(crClient aggregatorCrClient, scan *compv1alpha1.ComplianceScan, pr *utils.ParseResultContextItem, summary *checkResultSummary) error {
+	if pr == nil || pr.CheckResult == nil {
+		log.Info("nil result or result.check, this shouldn't happen")
+		return nil
+	}
 
-		crkey := getObjKey(pr.CheckResult.GetName(), pr.CheckResult.GetNamespace())
-		foundCheckResult := &compv1alpha1.ComplianceCheckResult{}
-		// Copy type metadata so dynamic client copies data correctly
-		foundCheckResult.TypeMeta = pr.CheckResult.TypeMeta
-		log.Info("Getting ComplianceCheckResult", "ComplianceCheckResult.Name", crkey.Name,
-			"ComplianceCheckResult.Namespace", crkey.Namespace)
-		checkResultExists := getObjectIfFound(crClient, crkey, foundCheckResult)
-		if checkResultExists {
-			// Copy resource version and other metadata needed for update
-			foundCheckResult.ObjectMeta.DeepCopyInto(&pr.CheckResult.ObjectMeta)
-		} else if !scan.Spec.ShowNotApplicable && pr.CheckResult.Status == compv1alpha1.CheckResultNotApplicable {
-			// If the result is not applicable we skip creation
-			// Note that updating a not-applicable result should still
-			// work in order to get older deployments to keep working.
-			continue
-		}
-		// check is owned by the scan
-		if err := createOrUpdateOneResult(crClient, scan, checkResultLabels, checkResultAnnotations, checkResultExists, pr.CheckResult); err != nil {
-			return fmt.Errorf("cannot create or update checkResult %s: %v", pr.CheckResult.Name, err)
-		}
+	checkResultLabels := getCheckResultLabels(&pr.ParseResult, pr.Labels, scan)
+	checkResultAnnotations := getCheckResultAnnotations(pr.CheckResult, pr.Annotations)
+	checkResultAnnotations[compv1alpha1.ComplianceCheckResultScanIndexAnnotation] = strconv.FormatInt(scan.Status.CurrentIndex, 10)
 
-		if pr.Remediations == nil ||
-			(pr.CheckResult.Status != compv1alpha1.CheckResultFail &&
-				pr.CheckResult.Status != compv1alpha1.CheckResultInfo &&
-				pr.CheckResult.Status != compv1alpha1.CheckResultPass && /* even passing remediations might need to be updated */
-				pr.CheckResult.Status != compv1alpha1.CheckResultInconsistent) {
-			continue
+	crkey := getObjKey(pr.CheckResult.GetName(), pr.CheckResult.GetNamespace())
+	foundCheckResult := &compv1alpha1.ComplianceCheckResult{}
+	// Copy type metadata so dynamic client copies data correctly
+	foundCheckResult.TypeMeta = pr.CheckResult.TypeMeta
+	log.Info("Getting ComplianceCheckResult", "ComplianceCheckResult.Name", crkey.Name,
+		"ComplianceCheckResult.Namespace", crkey.Namespace)
+	checkResultExists := getObjectIfFound(crClient, crkey, foundCheckResult)
+	if checkResultExists {
+		// Copy resource version and other metadata needed for update
+		foundCheckResult.ObjectMeta.DeepCopyInto(&pr.CheckResult.ObjectMeta)
+	} else if !scan.Spec.ShowNotApplicable && pr.CheckResult.Status == compv1alpha1.CheckResultNotApplicable {
+		// If the result is not applicable we skip creation
+		// Note that updating a not-applicable result should still
+		// work in order to get older deployments to keep working.
+		return nil
+	}
+	previous, known := summary.previousStatus(crkey.Name, foundCheckResult, checkResultExists)
+	if known {
+		checkResultAnnotations[compv1alpha1.ComplianceCheckResultPreviousStatusAnnotation] = string(previous)
+		if checkRegressed(previous, pr.CheckResult.Status) {
+			checkResultAnnotations[compv1alpha1.ComplianceCheckResultRegressedAnnotation] = string(previous)
 		}
+	}
+	pr.CheckResult.SetAnnotations(checkResultAnnotations)
 
-		for idx := range pr.Remediations {
-			rem := pr.Remediations[idx]
-			if remErr := handleRemediation(crClient, rem, pr.CheckResult, scan); remErr != nil {
-				return remErr
+	retained := retainsCheckResult(scan, pr)
+	summary.add(pr.CheckResult, previous, known, retained)
+	if !retained {
+		if checkResultExists {
+			log.Info("Deleting the ComplianceCheckResult that isn't retained", "ComplianceCheckResult.Name", crkey.Name)
+			if err := crClient.getClient().Delete(context.TODO(), foundCheckResult); err != nil && !errors.IsNotFound(err) {
+				return fmt.Errorf("cannot delete checkResult %s: %v", crkey.Name, err)
 			}
 		}
+		return nil
+	}
+	// check is owned by the scan
+	if err := createOrUpdateOneResult(crClient, scan, checkResultLabels, checkResultAnnotations, checkResultExists, pr.CheckResult); err != nil {
+		return fmt.Errorf("cannot create or update checkResult %s: %v", pr.CheckResult.Name, err)
+	}
+
+	if pr.Remediations == nil ||
+		(pr.CheckResult.Status != compv1alpha1.CheckResultFail &&
+			pr.CheckResult.Status != compv1alpha1.CheckResultInfo &&
+			pr.CheckResult.Status != compv1alpha1.CheckResultPass && /* even passing remediations might need to be updated */
+			pr.CheckResult.Status != compv1alpha1.CheckResultInconsistent) {
+		return nil
 	}
 
+	for idx := range pr.Remediations {
+		rem := pr.Remediations[idx]
+		if remErr := handleRemediation(crClient, rem, pr.CheckResult, scan); remErr != nil {
+			return remErr
+		}
+	}
 	return nil
 }
 
@@ -605,6 +785,8 @@ func handleRemediation(crClient aggregatorCrClient, rem *compv1alpha1.Compliance
 		// the remediation if the payload differs. Let's not create remediations for checks that are passing
 		// needlessly and let's not trigger the remediation controller needlessly
 		if foundRemediation.Status.ApplicationState == compv1alpha1.RemediationApplied ||
+			foundRemediation.Status.ApplicationState == compv1alpha1.RemediationDrifted ||
+			foundRemediation.Status.ApplicationState == compv1alpha1.RemediationManagedExternally ||
 			foundRemediation.Status.ApplicationState == compv1alpha1.RemediationOutdated {
 			if !foundRemediation.RemediationPayloadDiffers(rem) {
 				log.Info("Not updating passing remediation that was the same between runs", "ComplianceRemediation.Name", foundRemediation.Name)
@@ -613,7 +795,7 @@ func handleRemediation(crClient aggregatorCrClient, rem *compv1alpha1.Compliance
 
 			// Applied remediation that differs must be updated, let's set the appropriate state
 			stateUpdate = compv1alpha1.RemediationOutdated
-			if foundRemediation.Status.ApplicationState == compv1alpha1.RemediationApplied {
+			if foundRemediation.Status.ApplicationState != compv1alpha1.RemediationOutdated {
 				// For applied remediations, the old state must be kept in the outdated field
 				// so that the admin can switch to the current state at their own pace
 				foundRemediation.Spec.Current.DeepCopyInto(&rem.Spec.Outdated)
@@ -731,13 +913,41 @@ func aggregator(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	// Find all the configmaps for a scan
-	configMaps, err := getScanConfigMaps(crclient, aggregatorConf.ScanName, common.GetComplianceOperatorNamespace())
+	events, err := cloudevents.NewEmitter(cloudevents.Options{
+		Sink:  aggregatorConf.CloudEventsSink,
+		Types: []string{cloudevents.EventTypeCheckStatus},
+	})
+	if err != nil {
+		log.Error(err, "Invalid CloudEvents options")
+		os.Exit(1)
+	}
+	if events != nil {
+		stopEvents := make(chan struct{})
+		defer close(stopEvents)
+		go func() {
+			_ = events.Start(stopEvents)
+		}()
+	}
+
+	summary, err := newCheckResultSummary(crclient, scan, events)
 	if err != nil {
-		log.Error(err, "getScanConfigMaps failed")
+		log.Error(err, "Cannot get the check results the previous run didn't retain")
 		os.Exit(1)
 	}
 
+	if aggregatorConf.ContentDigest != "" {
+		digest, err := getFileDigest(aggregatorConf.Content)
+		if err != nil {
+			log.Error(err, "Cannot read the content")
+			os.Exit(1)
+		}
+		if digest != aggregatorConf.ContentDigest {
+			log.Info("The content isn't the verified one, refusing to create remediations from it",
+				"digest", digest, "verifiedDigest", aggregatorConf.ContentDigest)
+			os.Exit(1)
+		}
+	}
+
 	contentFile, err := readContent(aggregatorConf.Content)
 	if err != nil {
 		log.Error(err, "Cannot read the content")
@@ -752,46 +962,73 @@ func aggregator(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	// The lookup tables are built once rather than for each result
+	contentTables := utils.NewContentTables(contentDom)
+
 	prCtx := utils.NewParseResultContext()
+	progress := newAggregatorProgress(crclient, scan)
+	var processedConfigMaps []processedConfigMap
 
-	// For each configmap, create a list of remediations
-	for i := range configMaps {
-		cm := &configMaps[i]
+	// Parse the configmaps of the scan one at a time, only keeping the
+	// parsed results, and how to annotate each configmap once the results
+	// are saved
+	err = forEachScanConfigMap(crclient, aggregatorConf.ScanName, common.GetComplianceOperatorNamespace(), func(cm *v1.ConfigMap, total int) error {
 		log.Info("processing ConfigMap", "ConfigMap.Name", cm.Name)
 
-		cmParsedResults, source, err := parseResultRemediations(crclient.getScheme(), aggregatorConf.ScanName, aggregatorConf.Namespace, contentDom, cm)
+		cmParsedResults, source, err := parseResultRemediations(crclient.getScheme(), aggregatorConf.ScanName, aggregatorConf.Namespace, contentTables, cm)
 		if err != nil {
 			log.Error(err, "Cannot parse ConfigMap into remediations", "ConfigMap.Name", cm.Name)
 		} else if cmParsedResults == nil {
 			log.Info("Either no parsed results found in result or result already processed")
-			continue
+			progress.resultParsed(total)
+			return nil
 		}
 		log.Info("ConfigMap contained parsed results", "ConfigMap.Name", cm.Name, "results", len(cmParsedResults))
 
 		prCtx.AddResults(source, cmParsedResults)
 		// If the CM was processed, annotate it with the result
-		annotateCMWithScanResult(&configMaps[i], cmParsedResults)
+		annotateCMWithScanResult(cm, cmParsedResults)
+		processedConfigMaps = append(processedConfigMaps, newProcessedConfigMap(cm))
+		progress.resultParsed(total)
+		return nil
+	})
+	if err != nil {
+		log.Error(err, "Cannot list the result ConfigMaps")
+		os.Exit(1)
 	}
 
 	// Once we gathered all results, try to reconcile those that are inconsistent
 	consistentParsedResults := prCtx.GetConsistentResults()
+	progress.parsingDone(len(consistentParsedResults))
 
 	// At this point either scanRemediations is nil or contains a list
 	// of remediations for this scan
 	// Create the remediations
-	log.Info("Creating result objects")
-	if err := createResults(crclient, scan, consistentParsedResults); err != nil {
+	log.Info("Creating result objects", "parallelism", aggregatorConf.Parallelism)
+	if err := createResults(crclient, scan, consistentParsedResults, summary, aggregatorConf.Parallelism, progress); err != nil {
 		log.Error(err, "Could not create remediation objects")
 		os.Exit(1)
 	}
+	// Unless all the ConfigMaps were processed by an earlier attempt
+	if len(consistentParsedResults) > 0 {
+		if err := summary.report(crclient); err != nil {
+			log.Error(err, "Cannot report the check results of the scan")
+			os.Exit(1)
+		}
+	}
 
 	// Annotate configMaps, so we don't need to re-parse them
 	log.Info("Annotating ConfigMaps")
-	for idx := range configMaps {
-		err = markConfigMapAsProcessed(crclient, &configMaps[idx])
+	for idx := range processedConfigMaps {
+		err = markConfigMapAsProcessed(crclient, processedConfigMaps[idx])
 		if err != nil {
 			log.Error(err, "Cannot annotate the ConfigMap")
 			os.Exit(1)
 		}
 	}
+	progress.done()
+
+	if !events.Flush(aggregatorEventsFlushTimeout) {
+		log.Info("Not all the CloudEvents of the check results could be sent")
+	}
 }
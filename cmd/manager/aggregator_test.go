@@ -8,9 +8,12 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	ocpcfgv1 "github.com/openshift/api/config/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/kubernetes"
@@ -21,6 +24,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/utils"
 )
 
 type aggregatorCrClientFake struct {
@@ -291,4 +295,220 @@ var _ = Describe("Aggregator Tests", func() {
 			})
 		})
 	})
+
+	Context("Aggregating the results of many nodes", func() {
+		var (
+			scan      *compv1alpha1.ComplianceScan
+			crClient  *aggregatorCrClientFake
+			ctx       = context.Background()
+			namespace = "test-ns"
+		)
+
+		newResultConfigMap := func(name, scanName string) *v1.ConfigMap {
+			return &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+					Labels: map[string]string{
+						compv1alpha1.ComplianceScanLabel: scanName,
+						compv1alpha1.ResultLabel:         "",
+					},
+				},
+				Data: map[string]string{"results": "<xml/>"},
+			}
+		}
+
+		BeforeEach(func() {
+			scheme := getScheme()
+			scan = &compv1alpha1.ComplianceScan{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "workers",
+					Namespace: namespace,
+				},
+				Status: compv1alpha1.ComplianceScanStatus{CurrentIndex: 3},
+			}
+			objs := []runtime.Object{scan, newResultConfigMap("other-node", "masters")}
+			for i := 0; i < 3; i++ {
+				objs = append(objs, newResultConfigMap(fmt.Sprintf("workers-node-%d", i), scan.Name))
+			}
+			crClient = &aggregatorCrClientFake{
+				scheme:      scheme,
+				client:      fake.NewFakeClientWithScheme(scheme, objs...),
+				recorder:    fakerec.NewFakeRecorder(10),
+				fakevgetter: &fakeversionget{},
+			}
+		})
+
+		It("visits each result ConfigMap of the scan", func() {
+			var visited []string
+			err := forEachScanConfigMap(crClient, scan.Name, namespace, func(cm *v1.ConfigMap, total int) error {
+				Expect(total).To(Equal(3))
+				visited = append(visited, cm.Name)
+				return nil
+			})
+			Expect(err).To(BeNil())
+			Expect(visited).To(ConsistOf("workers-node-0", "workers-node-1", "workers-node-2"))
+		})
+
+		It("annotates the processed ConfigMaps", func() {
+			cm := newResultConfigMap("workers-node-0", scan.Name)
+			cm.Annotations = map[string]string{compv1alpha1.CmScanResultAnnotation: string(compv1alpha1.ResultCompliant)}
+			processed := newProcessedConfigMap(cm)
+
+			Expect(markConfigMapAsProcessed(crClient, processed)).To(Succeed())
+			found := &v1.ConfigMap{}
+			Expect(crClient.client.Get(ctx, processed.key, found)).To(Succeed())
+			Expect(found.Annotations).To(HaveKeyWithValue(compv1alpha1.CmScanResultAnnotation, string(compv1alpha1.ResultCompliant)))
+			Expect(found.Annotations).To(HaveKey(configMapRemediationsProcessed))
+			Expect(found.Data).To(HaveKey("results"))
+		})
+
+		It("creates the check results in parallel and reports the progress", func() {
+			var results []*utils.ParseResultContextItem
+			for i := 0; i < 20; i++ {
+				results = append(results, &utils.ParseResultContextItem{
+					ParseResult: utils.ParseResult{
+						Id: fmt.Sprintf("check-%d", i),
+						CheckResult: &compv1alpha1.ComplianceCheckResult{
+							ObjectMeta: metav1.ObjectMeta{
+								Name:      fmt.Sprintf("workers-check-%d", i),
+								Namespace: namespace,
+							},
+							Status: compv1alpha1.CheckResultPass,
+						},
+					},
+				})
+			}
+			progress := newAggregatorProgress(crClient, scan)
+			progress.parsingDone(len(results))
+			summary, err := newCheckResultSummary(crClient, scan, nil)
+			Expect(err).To(BeNil())
+
+			err = createResults(crClient, scan, results, summary, 4, progress)
+			Expect(err).To(BeNil())
+			Expect(summary.report(crClient)).To(Succeed())
+			progress.done()
+
+			checks := &compv1alpha1.ComplianceCheckResultList{}
+			Expect(crClient.client.List(ctx, checks)).To(Succeed())
+			Expect(checks.Items).To(HaveLen(20))
+			Expect(checks.Items[0].Annotations).To(HaveKeyWithValue(compv1alpha1.ComplianceCheckResultScanIndexAnnotation, "3"))
+
+			found := &compv1alpha1.ComplianceScan{}
+			Expect(crClient.client.Get(ctx, getObjKey(scan.Name, namespace), found)).To(Succeed())
+			Expect(found.Status.AggregationProgress).NotTo(BeNil())
+			Expect(found.Status.AggregationProgress.TotalCheckResults).To(Equal(20))
+			Expect(found.Status.AggregationProgress.ProcessedCheckResults).To(Equal(20))
+			Expect(found.Status.CheckResultCounts).To(Equal([]compv1alpha1.CheckResultCount{
+				{Status: compv1alpha1.CheckResultPass, Count: 20},
+			}))
+		})
+
+		It("doesn't overwrite the reported progress with an older snapshot", func() {
+			progress := newAggregatorProgress(crClient, scan)
+			progress.report(2, &compv1alpha1.ComplianceScanAggregationProgress{ParsedResults: 2})
+			progress.report(1, &compv1alpha1.ComplianceScanAggregationProgress{ParsedResults: 1})
+
+			found := &compv1alpha1.ComplianceScan{}
+			Expect(crClient.client.Get(ctx, getObjKey(scan.Name, namespace), found)).To(Succeed())
+			Expect(found.Status.AggregationProgress).NotTo(BeNil())
+			Expect(found.Status.AggregationProgress.ParsedResults).To(Equal(2))
+		})
+	})
+
+	Context("Check result summary", func() {
+		var scan *compv1alpha1.ComplianceScan
+		var crClient *aggregatorCrClientFake
+		var unretainedKey types.NamespacedName
+
+		newCheck := func(name string, status compv1alpha1.ComplianceCheckStatus, severity compv1alpha1.ComplianceCheckResultSeverity) *compv1alpha1.ComplianceCheckResult {
+			return &compv1alpha1.ComplianceCheckResult{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "bar"},
+				Status:     status,
+				Severity:   severity,
+			}
+		}
+
+		BeforeEach(func() {
+			scheme := getScheme()
+			scan = &compv1alpha1.ComplianceScan{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "foo",
+					Namespace: "bar",
+				},
+			}
+			scan.Status.CurrentIndex = 2
+			unretainedKey = types.NamespacedName{Name: compv1alpha1.GetUnretainedCheckResultsName("foo"), Namespace: "bar"}
+			// Written by the previous run
+			previous := &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      unretainedKey.Name,
+					Namespace: unretainedKey.Namespace,
+					Annotations: map[string]string{
+						compv1alpha1.ComplianceCheckResultScanIndexAnnotation: "1",
+					},
+				},
+				Data: map[string]string{"PASS": "foo-a\nfoo-b"},
+			}
+			crClient = &aggregatorCrClientFake{
+				scheme: scheme,
+				client: fake.NewFakeClientWithScheme(scheme, scan, previous),
+			}
+		})
+
+		It("Finds the previous status of the check results that weren't retained", func() {
+			summary, err := newCheckResultSummary(crClient, scan, nil)
+			Expect(err).To(BeNil())
+			status, known := summary.previousStatus("foo-a", &compv1alpha1.ComplianceCheckResult{}, false)
+			Expect(known).To(BeTrue())
+			Expect(status).To(Equal(compv1alpha1.CheckResultPass))
+			_, known = summary.previousStatus("foo-c", &compv1alpha1.ComplianceCheckResult{}, false)
+			Expect(known).To(BeFalse())
+
+			// The check results that exist know their previous status
+			found := newCheck("foo-a", compv1alpha1.CheckResultFail, compv1alpha1.CheckResultSeverityHigh)
+			status, known = summary.previousStatus("foo-a", found, true)
+			Expect(known).To(BeTrue())
+			Expect(status).To(Equal(compv1alpha1.CheckResultFail))
+		})
+
+		It("Reports the counts and the check results that weren't retained", func() {
+			summary, err := newCheckResultSummary(crClient, scan, nil)
+			Expect(err).To(BeNil())
+			summary.add(newCheck("foo-a", compv1alpha1.CheckResultFail, compv1alpha1.CheckResultSeverityHigh), compv1alpha1.CheckResultPass, true, true)
+			summary.add(newCheck("foo-c", compv1alpha1.CheckResultPass, compv1alpha1.CheckResultSeverityLow), "", false, false)
+			summary.add(newCheck("foo-b", compv1alpha1.CheckResultPass, compv1alpha1.CheckResultSeverityHigh), compv1alpha1.CheckResultPass, true, false)
+			Expect(summary.report(crClient)).To(Succeed())
+
+			found := &compv1alpha1.ComplianceScan{}
+			Expect(crClient.client.Get(context.TODO(), types.NamespacedName{Name: "foo", Namespace: "bar"}, found)).To(Succeed())
+			Expect(found.Status.CheckResultCounts).To(Equal([]compv1alpha1.CheckResultCount{
+				{Status: compv1alpha1.CheckResultFail, Severity: compv1alpha1.CheckResultSeverityHigh, Count: 1},
+				{Status: compv1alpha1.CheckResultPass, Severity: compv1alpha1.CheckResultSeverityHigh, Count: 1},
+				{Status: compv1alpha1.CheckResultPass, Severity: compv1alpha1.CheckResultSeverityLow, Count: 1},
+			}))
+
+			cm := &v1.ConfigMap{}
+			Expect(crClient.client.Get(context.TODO(), unretainedKey, cm)).To(Succeed())
+			Expect(cm.Data).To(Equal(map[string]string{"PASS": "foo-b\nfoo-c"}))
+			Expect(cm.Annotations[compv1alpha1.ComplianceCheckResultScanIndexAnnotation]).To(Equal("2"))
+
+			// Another attempt at aggregating the same run doesn't take
+			// the statuses of this run for the previous ones
+			summary, err = newCheckResultSummary(crClient, scan, nil)
+			Expect(err).To(BeNil())
+			_, known := summary.previousStatus("foo-b", &compv1alpha1.ComplianceCheckResult{}, false)
+			Expect(known).To(BeFalse())
+		})
+
+		It("Deletes the ConfigMap when all the check results are retained", func() {
+			summary, err := newCheckResultSummary(crClient, scan, nil)
+			Expect(err).To(BeNil())
+			summary.add(newCheck("foo-a", compv1alpha1.CheckResultFail, compv1alpha1.CheckResultSeverityHigh), "", false, true)
+			Expect(summary.report(crClient)).To(Succeed())
+
+			err = crClient.client.Get(context.TODO(), unretainedKey, &v1.ConfigMap{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+	})
 })
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+// progressReportInterval is how often the aggregator reports its progress
+// in the status of the scan at most
+const progressReportInterval = 10 * time.Second
+
+// aggregatorProgress keeps track of how far the aggregator got and reports it
+// in the status of the scan. It's safe to use from several goroutines, and a
+// nil aggregatorProgress doesn't report anything.
+type aggregatorProgress struct {
+	crClient aggregatorCrClient
+	scan     types.NamespacedName
+	interval time.Duration
+
+	// mu protects the progress itself, and is never held while reporting
+	// it, so that recording the progress doesn't wait for the API server
+	mu         sync.Mutex
+	progress   compv1alpha1.ComplianceScanAggregationProgress
+	lastReport time.Time
+	snapshots  int
+
+	// reportMu orders the reports, so that a snapshot never overwrites a
+	// newer one
+	reportMu sync.Mutex
+	reported int
+}
+
+func newAggregatorProgress(crClient aggregatorCrClient, scan *compv1alpha1.ComplianceScan) *aggregatorProgress {
+	return &aggregatorProgress{
+		crClient: crClient,
+		scan:     types.NamespacedName{Name: scan.Name, Namespace: scan.Namespace},
+		interval: progressReportInterval,
+	}
+}
+
+// resultParsed records that the results of a node were parsed, out of total
+// results if that's known
+func (p *aggregatorProgress) resultParsed(total int) {
+	p.update(false, func(progress *compv1alpha1.ComplianceScanAggregationProgress) {
+		progress.ParsedResults++
+		progress.TotalResults = total
+	})
+}
+
+// parsingDone records that all the results were parsed into total check
+// results to process
+func (p *aggregatorProgress) parsingDone(total int) {
+	p.update(true, func(progress *compv1alpha1.ComplianceScanAggregationProgress) {
+		progress.TotalResults = progress.ParsedResults
+		progress.TotalCheckResults = total
+	})
+}
+
+// checkResultProcessed records that a check result was created, updated or
+// skipped
+func (p *aggregatorProgress) checkResultProcessed() {
+	p.update(false, func(progress *compv1alpha1.ComplianceScanAggregationProgress) {
+		progress.ProcessedCheckResults++
+	})
+}
+
+// done reports the final progress
+func (p *aggregatorProgress) done() {
+	p.update(true, func(*compv1alpha1.ComplianceScanAggregationProgress) {})
+}
+
+func (p *aggregatorProgress) update(force bool, change func(*compv1alpha1.ComplianceScanAggregationProgress)) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	change(&p.progress)
+	if !force && time.Since(p.lastReport) < p.interval {
+		p.mu.Unlock()
+		return
+	}
+	p.lastReport = time.Now()
+	p.progress.LastUpdateTime = metav1.NewTime(p.lastReport)
+	p.snapshots++
+	snapshot := p.snapshots
+	progress := p.progress.DeepCopy()
+	p.mu.Unlock()
+
+	p.report(snapshot, progress)
+}
+
+func (p *aggregatorProgress) report(snapshot int, progress *compv1alpha1.ComplianceScanAggregationProgress) {
+	p.reportMu.Lock()
+	defer p.reportMu.Unlock()
+	if snapshot <= p.reported {
+		return
+	}
+
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		scan := &compv1alpha1.ComplianceScan{}
+		if err := p.crClient.getClient().Get(context.TODO(), p.scan, scan); err != nil {
+			return err
+		}
+		scan.Status.AggregationProgress = progress
+		return p.crClient.getClient().Status().Update(context.TODO(), scan)
+	})
+	if err != nil {
+		// The progress is only informative, carry on
+		log.Error(err, "Cannot report the progress in the scan status", "ComplianceScan.Name", p.scan.Name)
+		return
+	}
+	p.reported = snapshot
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/cloudevents"
+)
+
+type checkResultCountKey struct {
+	status   compv1alpha1.ComplianceCheckStatus
+	severity compv1alpha1.ComplianceCheckResultSeverity
+}
+
+// checkResultSummary collects the check results of a run of a scan while
+// they're created: their number by status and severity, and the statuses of
+// those that aren't kept as per the check result retention settings. It
+// emits the CloudEvents of the check results whose status changed, kept or
+// not. It's safe to use from several goroutines.
+type checkResultSummary struct {
+	scan   *compv1alpha1.ComplianceScan
+	events *cloudevents.Emitter
+	// The statuses the previous run reported for the check results it
+	// didn't keep
+	previousUnretained map[string]compv1alpha1.ComplianceCheckStatus
+
+	mu         sync.Mutex
+	counts     map[checkResultCountKey]int
+	unretained map[compv1alpha1.ComplianceCheckStatus][]string
+}
+
+func newCheckResultSummary(crClient aggregatorCrClient, scan *compv1alpha1.ComplianceScan, events *cloudevents.Emitter) (*checkResultSummary, error) {
+	previous, err := getUnretainedCheckResults(crClient, scan)
+	if err != nil {
+		return nil, err
+	}
+	return &checkResultSummary{
+		scan:               scan,
+		events:             events,
+		previousUnretained: previous,
+		counts:             make(map[checkResultCountKey]int),
+		unretained:         make(map[compv1alpha1.ComplianceCheckStatus][]string),
+	}, nil
+}
+
+// getUnretainedCheckResults returns the statuses of the check results the
+// previous run of the scan didn't keep, by name
+func getUnretainedCheckResults(crClient aggregatorCrClient, scan *compv1alpha1.ComplianceScan) (map[string]compv1alpha1.ComplianceCheckStatus, error) {
+	statuses := make(map[string]compv1alpha1.ComplianceCheckStatus)
+	cm := &v1.ConfigMap{}
+	key := getObjKey(compv1alpha1.GetUnretainedCheckResultsName(scan.Name), scan.Namespace)
+	err := crClient.getClient().Get(context.TODO(), key, cm)
+	if errors.IsNotFound(err) {
+		return statuses, nil
+	} else if err != nil {
+		return nil, err
+	}
+	// Written by an earlier attempt at aggregating this run
+	if cm.Annotations[compv1alpha1.ComplianceCheckResultScanIndexAnnotation] == strconv.FormatInt(scan.Status.CurrentIndex, 10) {
+		return statuses, nil
+	}
+	for status, names := range cm.Data {
+		for _, name := range strings.Split(names, "\n") {
+			if name != "" {
+				statuses[name] = compv1alpha1.ComplianceCheckStatus(status)
+			}
+		}
+	}
+	return statuses, nil
+}
+
+// previousStatus returns the status a check result had in the previous run
+// of the scan, if it's known. found is the check result in the cluster, if
+// it exists.
+func (s *checkResultSummary) previousStatus(name string, found *compv1alpha1.ComplianceCheckResult, exists bool) (compv1alpha1.ComplianceCheckStatus, bool) {
+	if !exists {
+		status, ok := s.previousUnretained[name]
+		return status, ok
+	}
+	if index, ok := found.ScanIndex(); ok && index == s.scan.Status.CurrentIndex {
+		// The check was already updated by this run, keep the status of
+		// the previous one
+		return found.PreviousStatus()
+	}
+	return found.Status, true
+}
+
+// add records a check result of the run and whether it's kept in the
+// cluster, and emits its CloudEvent if its status changed from the previous
+// one
+func (s *checkResultSummary) add(check *compv1alpha1.ComplianceCheckResult, previous compv1alpha1.ComplianceCheckStatus, known, retained bool) {
+	s.events.ObserveCheckResult(s.scan, check, previous, known)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[checkResultCountKey{check.Status, check.Severity}]++
+	if !retained {
+		s.unretained[check.Status] = append(s.unretained[check.Status], check.Name)
+	}
+}
+
+// report sets the check result counts in the status of the scan, and keeps
+// the statuses of the check results that weren't retained in a ConfigMap
+// owned by the scan
+func (s *checkResultSummary) report(crClient aggregatorCrClient) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make([]compv1alpha1.CheckResultCount, 0, len(s.counts))
+	for key, count := range s.counts {
+		counts = append(counts, compv1alpha1.CheckResultCount{
+			Status:   key.status,
+			Severity: key.severity,
+			Count:    count,
+		})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Status != counts[j].Status {
+			return counts[i].Status < counts[j].Status
+		}
+		return counts[i].Severity < counts[j].Severity
+	})
+
+	key := getObjKey(s.scan.Name, s.scan.Namespace)
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		found := &compv1alpha1.ComplianceScan{}
+		if err := crClient.getClient().Get(context.TODO(), key, found); err != nil {
+			return err
+		}
+		found.Status.CheckResultCounts = counts
+		return crClient.getClient().Status().Update(context.TODO(), found)
+	})
+	if err != nil {
+		return err
+	}
+	return s.reportUnretained(crClient)
+}
+
+func (s *checkResultSummary) reportUnretained(crClient aggregatorCrClient) error {
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      compv1alpha1.GetUnretainedCheckResultsName(s.scan.Name),
+			Namespace: s.scan.Namespace,
+			Labels: map[string]string{
+				compv1alpha1.ComplianceScanLabel: s.scan.Name,
+			},
+			Annotations: map[string]string{
+				compv1alpha1.ComplianceCheckResultScanIndexAnnotation: strconv.FormatInt(s.scan.Status.CurrentIndex, 10),
+			},
+		},
+	}
+	if len(s.unretained) == 0 {
+		err := crClient.getClient().Delete(context.TODO(), cm)
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	cm.Data = make(map[string]string)
+	for status, names := range s.unretained {
+		sort.Strings(names)
+		cm.Data[string(status)] = strings.Join(names, "\n")
+	}
+	if err := controllerutil.SetControllerReference(s.scan, cm, crClient.getScheme()); err != nil {
+		return err
+	}
+	err := crClient.getClient().Create(context.TODO(), cm)
+	if !errors.IsAlreadyExists(err) {
+		return err
+	}
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		found := &v1.ConfigMap{}
+		if err := crClient.getClient().Get(context.TODO(), getObjKey(cm.Name, cm.Namespace), found); err != nil {
+			return err
+		}
+		found.Annotations = cm.Annotations
+		found.Data = cm.Data
+		return crClient.getClient().Update(context.TODO(), found)
+	})
+}
@@ -16,7 +16,9 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
+	"io"
 
 	"github.com/operator-framework/operator-sdk/pkg/log/zap"
 	"github.com/spf13/cobra"
@@ -62,6 +64,9 @@ type fetcherConfig struct {
 	Profile            string
 	ExitCodeFile       string
 	WarningsOutputFile string
+	// The CustomRules to evaluate and where to write their results
+	CustomRules           string
+	CustomRuleResultsFile string
 }
 
 func defineAPIResourceCollectorFlags(cmd *cobra.Command) {
@@ -70,6 +75,8 @@ func defineAPIResourceCollectorFlags(cmd *cobra.Command) {
 	cmd.Flags().String("resultdir", "", "The directory to write the collected object files to.")
 	cmd.Flags().String("profile", "", "The scan profile.")
 	cmd.Flags().String("warnings-output-file", "", "A file containing the warnings output.")
+	cmd.Flags().String("custom-rules", "", "The path to the custom rules to evaluate, if any.")
+	cmd.Flags().String("custom-rules-results-file", "", "The file to write the results of the custom rules to.")
 	cmd.Flags().Bool("debug", false, "Print debug messages.")
 
 	flags := cmd.Flags()
@@ -88,6 +95,8 @@ func parseAPIResourceCollectorConfig(cmd *cobra.Command) *fetcherConfig {
 	conf.WarningsOutputFile = getValidStringArg(cmd, "warnings-output-file")
 	debugLog, _ = cmd.Flags().GetBool("debug")
 	conf.Tailoring, _ = cmd.Flags().GetString("tailoring")
+	conf.CustomRules, _ = cmd.Flags().GetString("custom-rules")
+	conf.CustomRuleResultsFile, _ = cmd.Flags().GetString("custom-rules-results-file")
 	return &conf
 }
 
@@ -130,4 +139,17 @@ func runAPIResourceCollector(cmd *cobra.Command, args []string) {
 	if err := fetcher.SaveResources(fetcherConf.ResultDir); err != nil {
 		FATAL("Error saving resources: %v", err)
 	}
+
+	customRules, err := loadCustomRules(fetcherConf.CustomRules)
+	if err != nil {
+		FATAL("Error loading custom rules: %v", err)
+	}
+	if len(customRules) > 0 && fetcherConf.CustomRuleResultsFile != "" {
+		results := evaluateCustomRules(func(uri string) (io.ReadCloser, error) {
+			return kubeClient.RESTClient().Get().RequestURI(uri).Stream(context.Background())
+		}, customRules)
+		if err := saveCustomRuleResults(fetcherConf.CustomRuleResultsFile, results); err != nil {
+			FATAL("Error saving custom rule results: %v", err)
+		}
+	}
 }
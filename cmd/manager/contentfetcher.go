@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	backoff "github.com/cenkalti/backoff/v4"
+	"github.com/dsnet/compress/bzip2"
+	libgocrypto "github.com/openshift/library-go/pkg/crypto"
+	"github.com/spf13/cobra"
+)
+
+var contentFetcherCmd = &cobra.Command{
+	Use:   "fetch-content",
+	Short: "Fetches the content of a ProfileBundle from a file or an HTTPS URL.",
+	Long:  "Fetches the content of a ProfileBundle from a file or an HTTPS URL.",
+	Run:   runContentFetcher,
+}
+
+const (
+	contentDownloadTimeout = 5 * time.Minute
+	// defaultMaxContentSize is large enough for any data stream, while
+	// keeping a compression bomb from filling up the volume
+	defaultMaxContentSize = 256 * 1024 * 1024
+)
+
+func init() {
+	rootCmd.AddCommand(contentFetcherCmd)
+	defineContentFetcherFlags(contentFetcherCmd)
+}
+
+type contentFetcherConfig struct {
+	File     string
+	URL      string
+	CAFile   string
+	Checksum string
+	Output   string
+	MaxSize  int64
+}
+
+func defineContentFetcherFlags(cmd *cobra.Command) {
+	cmd.Flags().String("file", "", "The file to read the content from.")
+	cmd.Flags().String("url", "", "The HTTPS URL to download the content from.")
+	cmd.Flags().String("ca-file", "", "The CA certificates to verify the server with, instead of the system ones.")
+	cmd.Flags().String("checksum", "", "The expected SHA-256 checksum of the content, as sha256:<hex>.")
+	cmd.Flags().String("output", "", "The path to write the content to.")
+	cmd.Flags().Int64("max-size", defaultMaxContentSize, "The maximum size of the content once decompressed, in bytes.")
+	cmd.Flags().Bool("debug", false, "Print debug messages.")
+
+	flags := cmd.Flags()
+
+	// Add flags registered by imported packages (e.g. glog and
+	// controller-runtime)
+	flags.AddGoFlagSet(flag.CommandLine)
+}
+
+func parseContentFetcherConfig(cmd *cobra.Command) *contentFetcherConfig {
+	var conf contentFetcherConfig
+	conf.File, _ = cmd.Flags().GetString("file")
+	conf.URL, _ = cmd.Flags().GetString("url")
+	conf.CAFile, _ = cmd.Flags().GetString("ca-file")
+	conf.Checksum, _ = cmd.Flags().GetString("checksum")
+	conf.Output = getValidStringArg(cmd, "output")
+	conf.MaxSize, _ = cmd.Flags().GetInt64("max-size")
+	debugLog, _ = cmd.Flags().GetBool("debug")
+	return &conf
+}
+
+func runContentFetcher(cmd *cobra.Command, args []string) {
+	conf := parseContentFetcherConfig(cmd)
+	if (conf.File == "") == (conf.URL == "") {
+		FATAL("Exactly one of --file or --url must be given")
+	}
+	if conf.URL != "" && conf.Checksum == "" {
+		FATAL("A --checksum is required to download the content")
+	}
+
+	var err error
+	if conf.File != "" {
+		LOG("Reading the content from %s", conf.File)
+		err = fetchContentFromFile(conf)
+	} else {
+		LOG("Downloading the content from %s", conf.URL)
+		err = backoff.Retry(func() error {
+			err := fetchContentFromURL(conf)
+			switch err.(type) {
+			case *checksumError, *contentSizeError:
+				// Downloading it again won't help
+				return backoff.Permanent(err)
+			}
+			return err
+		}, backoff.WithMaxRetries(backoff.NewExponentialBackOff(), maxRetries))
+	}
+	if err != nil {
+		FATAL("Error fetching the content: %v", err)
+	}
+	LOG("The content was written to %s", conf.Output)
+}
+
+func fetchContentFromFile(conf *contentFetcherConfig) error {
+	f, err := os.Open(filepath.Clean(conf.File))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeContent(f, conf.Checksum, conf.Output, conf.MaxSize)
+}
+
+func fetchContentFromURL(conf *contentFetcherConfig) error {
+	u, err := url.Parse(conf.URL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("only HTTPS URLs are supported")
+	}
+
+	tlsConfig := libgocrypto.SecureTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12})
+	if conf.CAFile != "" {
+		ca, err := ioutil.ReadFile(filepath.Clean(conf.CAFile))
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return fmt.Errorf("no certificates found in %s", conf.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	client := &http.Client{
+		Timeout: contentDownloadTimeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("downloading %s: %s", conf.URL, resp.Status)
+	}
+	return writeContent(resp.Body, conf.Checksum, conf.Output, conf.MaxSize)
+}
+
+type checksumError struct {
+	expected, actual string
+}
+
+func (e *checksumError) Error() string {
+	return fmt.Sprintf("the checksum of the content is %s instead of %s", e.actual, e.expected)
+}
+
+type contentSizeError struct {
+	maxSize int64
+}
+
+func (e *contentSizeError) Error() string {
+	return fmt.Sprintf("the content is larger than %d bytes once decompressed", e.maxSize)
+}
+
+// writeContent writes the content to the output file, decompressing it if
+// it's compressed with gzip or bzip2. The checksum is verified against the
+// content as read, and the output is removed if it doesn't match or is
+// larger than maxSize once decompressed.
+func writeContent(r io.Reader, checksum, output string, maxSize int64) error {
+	hash := sha256.New()
+	source := bufio.NewReader(io.TeeReader(r, hash))
+
+	content, err := newDecompressingReader(source)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(output), 0750); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(filepath.Clean(output), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	written, copyErr := io.Copy(out, io.LimitReader(content, maxSize+1))
+	if copyErr == nil && written > maxSize {
+		copyErr = &contentSizeError{maxSize: maxSize}
+	}
+	// Whatever the decompressor didn't consume still counts for the checksum
+	if copyErr == nil {
+		_, copyErr = io.Copy(ioutil.Discard, source)
+	}
+	if closeErr := out.Close(); copyErr == nil {
+		copyErr = closeErr
+	}
+	if copyErr != nil {
+		os.Remove(output)
+		return copyErr
+	}
+
+	actual := "sha256:" + hex.EncodeToString(hash.Sum(nil))
+	if checksum != "" && !strings.EqualFold(checksum, actual) {
+		os.Remove(output)
+		return &checksumError{expected: checksum, actual: actual}
+	}
+	DBG("The checksum of the content is %s", actual)
+	return nil
+}
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+)
+
+func newDecompressingReader(r *bufio.Reader) (io.Reader, error) {
+	// Peeking fails for content shorter than the magic, which can't be
+	// compressed anyway
+	if magic, err := r.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		DBG("The content is compressed with gzip")
+		return gzip.NewReader(r)
+	}
+	if magic, err := r.Peek(len(bzip2Magic)); err == nil && bytes.Equal(magic, bzip2Magic) {
+		DBG("The content is compressed with bzip2")
+		return bzip2.NewReader(r, &bzip2.ReaderConfig{})
+	}
+	return r, nil
+}
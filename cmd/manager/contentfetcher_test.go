@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/dsnet/compress/bzip2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fetching content", func() {
+	const content = "<ds:data-stream-collection/>"
+
+	var (
+		dir    string
+		output string
+	)
+
+	checksumOf := func(data []byte) string {
+		sum := sha256.Sum256(data)
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "content-fetcher")
+		Expect(err).To(BeNil())
+		output = filepath.Join(dir, "content", "ssg-ocp4-ds.xml")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("writes uncompressed content as is", func() {
+		err := writeContent(bytes.NewBufferString(content), checksumOf([]byte(content)), output, defaultMaxContentSize)
+		Expect(err).To(BeNil())
+		written, err := ioutil.ReadFile(output)
+		Expect(err).To(BeNil())
+		Expect(string(written)).To(Equal(content))
+	})
+
+	It("decompresses content compressed with gzip", func() {
+		var compressed bytes.Buffer
+		gw := gzip.NewWriter(&compressed)
+		_, err := gw.Write([]byte(content))
+		Expect(err).To(BeNil())
+		Expect(gw.Close()).To(Succeed())
+		checksum := checksumOf(compressed.Bytes())
+
+		Expect(writeContent(&compressed, checksum, output, defaultMaxContentSize)).To(Succeed())
+		written, err := ioutil.ReadFile(output)
+		Expect(err).To(BeNil())
+		Expect(string(written)).To(Equal(content))
+	})
+
+	It("decompresses content compressed with bzip2", func() {
+		var compressed bytes.Buffer
+		bw, err := bzip2.NewWriter(&compressed, &bzip2.WriterConfig{})
+		Expect(err).To(BeNil())
+		_, err = bw.Write([]byte(content))
+		Expect(err).To(BeNil())
+		Expect(bw.Close()).To(Succeed())
+		checksum := checksumOf(compressed.Bytes())
+
+		Expect(writeContent(&compressed, checksum, output, defaultMaxContentSize)).To(Succeed())
+		written, err := ioutil.ReadFile(output)
+		Expect(err).To(BeNil())
+		Expect(string(written)).To(Equal(content))
+	})
+
+	It("rejects content that doesn't match the checksum", func() {
+		err := writeContent(bytes.NewBufferString(content), checksumOf([]byte("something else")), output, defaultMaxContentSize)
+		Expect(err).To(BeAssignableToTypeOf(&checksumError{}))
+		_, err = os.Stat(output)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("rejects content larger than the maximum once decompressed", func() {
+		var compressed bytes.Buffer
+		gw := gzip.NewWriter(&compressed)
+		_, err := gw.Write(bytes.Repeat([]byte(content), 1024))
+		Expect(err).To(BeNil())
+		Expect(gw.Close()).To(Succeed())
+		checksum := checksumOf(compressed.Bytes())
+
+		err = writeContent(&compressed, checksum, output, int64(len(content)))
+		Expect(err).To(BeAssignableToTypeOf(&contentSizeError{}))
+		_, err = os.Stat(output)
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/utils"
+)
+
+// loadCustomRules reads the CustomRules that the TailoredProfile stored in
+// the tailoring ConfigMap. Not having the file isn't an error, it just
+// means that no CustomRules were enabled.
+func loadCustomRules(filename string) ([]compv1alpha1.CustomRule, error) {
+	if filename == "" {
+		return nil, nil
+	}
+	contents, err := ioutil.ReadFile(filepath.Clean(filename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var rules []compv1alpha1.CustomRule
+	if err := json.Unmarshal(contents, &rules); err != nil {
+		return nil, fmt.Errorf("parsing the custom rules: %w", err)
+	}
+	return rules, nil
+}
+
+// evaluateCustomRules fetches the resource each CustomRule checks and
+// evaluates the rule against it. Resources that don't exist are passed as
+// null, so that rules can check for their absence. Any other error fetching
+// a resource results in the ERROR status for the rule.
+func evaluateCustomRules(streamFunc func(string) (io.ReadCloser, error), rules []compv1alpha1.CustomRule) []*utils.CustomRuleResult {
+	ctx := context.Background()
+	results := make([]*utils.CustomRuleResult, 0, len(rules))
+	for i := range rules {
+		rule := &rules[i]
+		LOG("Evaluating custom rule '%s' against '%s'", rule.Name, rule.Spec.Input.APIPath)
+		resource, err := fetchCustomRuleResource(streamFunc, rule.Spec.Input.APIPath)
+		if err != nil {
+			DBG("Couldn't fetch the resource of custom rule '%s': %s", rule.Name, err)
+			results = append(results, utils.CustomRuleErrorResult(rule, err))
+			continue
+		}
+		result := utils.EvaluateCustomRule(ctx, rule, resource)
+		DBG("Custom rule '%s' result: %s", rule.Name, result.Status)
+		results = append(results, result)
+	}
+	return results
+}
+
+func fetchCustomRuleResource(streamFunc func(string) (io.ReadCloser, error), uri string) (interface{}, error) {
+	stream, err := streamFunc(uri)
+	if kerrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not fetch %s: %w", uri, err)
+	}
+	defer stream.Close()
+
+	body, err := ioutil.ReadAll(stream)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", uri, err)
+	}
+	var resource interface{}
+	if err := json.Unmarshal(body, &resource); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", uri, err)
+	}
+	return resource, nil
+}
+
+func saveCustomRuleResults(filename string, results []*utils.CustomRuleResult) error {
+	contents, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, contents, 0600)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+var _ = Describe("Evaluating CustomRules", func() {
+	newCustomRule := func(name, apiPath string) compv1alpha1.CustomRule {
+		customRule := compv1alpha1.CustomRule{
+			Spec: compv1alpha1.CustomRuleSpec{
+				Title:      name,
+				Input:      compv1alpha1.CustomRuleInput{APIPath: apiPath},
+				Expression: `. != null and .metadata.labels.secure == "true"`,
+			},
+		}
+		customRule.Name = name
+		return customRule
+	}
+
+	streamFunc := func(uri string) (io.ReadCloser, error) {
+		switch uri {
+		case "/api/v1/namespaces/secure":
+			return ioutil.NopCloser(strings.NewReader(`{"metadata": {"labels": {"secure": "true"}}}`)), nil
+		case "/api/v1/namespaces/missing":
+			return nil, kerrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, "missing")
+		default:
+			return nil, fmt.Errorf("forbidden")
+		}
+	}
+
+	It("evaluates every rule against its resource", func() {
+		rules := []compv1alpha1.CustomRule{
+			newCustomRule("secure", "/api/v1/namespaces/secure"),
+			newCustomRule("missing", "/api/v1/namespaces/missing"),
+			newCustomRule("forbidden", "/api/v1/namespaces/forbidden"),
+		}
+		results := evaluateCustomRules(streamFunc, rules)
+		Expect(results).To(HaveLen(3))
+		Expect(results[0].Status).To(Equal(compv1alpha1.CheckResultPass))
+		// Rules get null when the resource doesn't exist
+		Expect(results[1].Status).To(Equal(compv1alpha1.CheckResultFail))
+		Expect(results[2].Status).To(Equal(compv1alpha1.CheckResultError))
+	})
+
+	It("doesn't load any rule without the rules file", func() {
+		rules, err := loadCustomRules("/nonexistent/custom-rules.json")
+		Expect(err).To(BeNil())
+		Expect(rules).To(BeEmpty())
+	})
+})
@@ -19,6 +19,9 @@ package main
 import (
 	"fmt"
 	"os"
+	// The time zones of the suite schedules are validated against the
+	// embedded database, as the image may not have one
+	_ "time/tzdata"
 
 	"github.com/spf13/cobra"
 )
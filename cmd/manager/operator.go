@@ -42,9 +42,11 @@ import (
 	"github.com/openshift/compliance-operator/pkg/apis"
 	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
 	"github.com/openshift/compliance-operator/pkg/controller"
+	"github.com/openshift/compliance-operator/pkg/controller/cloudevents"
 	"github.com/openshift/compliance-operator/pkg/controller/common"
 	ctrlMetrics "github.com/openshift/compliance-operator/pkg/controller/metrics"
 	"github.com/openshift/compliance-operator/pkg/utils"
+	"github.com/openshift/compliance-operator/pkg/webhook"
 	"github.com/openshift/compliance-operator/version"
 )
 
@@ -112,6 +114,20 @@ func defineOperatorFlags(cmd *cobra.Command) {
 	cmd.Flags().String("platform", "OpenShift",
 		"Specifies the Platform the Compliance Operator is running on. "+
 			"This will affect the defaults created.")
+	cmd.Flags().Bool("skip-webhooks", false,
+		"Skips serving the validating and defaulting admission webhooks.")
+	cmd.Flags().String("webhook-cert-dir", webhook.DefaultCertDir,
+		"The directory the webhook serving certificate is written to.")
+	cmd.Flags().Bool("metrics-failing-rules", true,
+		"Exports a series for each rule that failed in the latest run of a scan.")
+	cmd.Flags().Int("metrics-max-failing-rules", ctrlMetrics.DefaultMaxFailingRulesPerScan,
+		"The maximum number of failing rule series exported per scan, the most severe first. 0 means no limit.")
+	cmd.Flags().String("cloudevents-sink", "",
+		"The URL CloudEvents are posted to on the transitions of scans, suites, check results and remediations. No event is emitted if empty.")
+	cmd.Flags().StringSlice("cloudevents-types", []string{},
+		fmt.Sprintf("The types of the CloudEvents emitted, all of them if empty. One or more of %s.", strings.Join(cloudevents.AllEventTypes, ", ")))
+	cmd.Flags().Int("cloudevents-buffer-size", cloudevents.DefaultBufferSize,
+		"The number of CloudEvents kept while the sink can't be reached. The events emitted while the buffer is full are dropped.")
 
 	// Add the zap logger flag set to the CLI. The flag set must
 	// be added before calling pflag.Parse().
@@ -228,6 +244,12 @@ func RunOperator(cmd *cobra.Command, args []string) {
 		log.Error(err, "")
 	}
 
+	// The bindings reconcile the PrometheusRules of their alerts
+	if err := monitoring.AddToScheme(mgrscheme); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
 	// Index the ID field of Checks
 	if err := mgr.GetFieldIndexer().IndexField(ctx, &compv1alpha1.ComplianceCheckResult{}, compv1alpha1.ComplianceRemediationDependencyField, func(rawObj k8sruntime.Object) []string {
 		check, ok := rawObj.(*compv1alpha1.ComplianceCheckResult)
@@ -241,11 +263,36 @@ func RunOperator(cmd *cobra.Command, args []string) {
 	}
 
 	met := ctrlMetrics.New()
+	failingRules, _ := flags.GetBool("metrics-failing-rules")
+	maxFailingRules, _ := flags.GetInt("metrics-max-failing-rules")
+	met.SetCardinalityOptions(ctrlMetrics.CardinalityOptions{
+		FailingRules:           failingRules,
+		MaxFailingRulesPerScan: maxFailingRules,
+	})
 	if err := met.Register(); err != nil {
 		log.Error(err, "Error registering metrics")
 		os.Exit(1)
 	}
 
+	sink, _ := flags.GetString("cloudevents-sink")
+	eventTypes, _ := flags.GetStringSlice("cloudevents-types")
+	bufferSize, _ := flags.GetInt("cloudevents-buffer-size")
+	events, err := cloudevents.NewEmitter(cloudevents.Options{
+		Sink:       sink,
+		Types:      eventTypes,
+		BufferSize: bufferSize,
+	})
+	if err != nil {
+		log.Error(err, "Invalid CloudEvents options")
+		os.Exit(1)
+	}
+	if events != nil {
+		if err := mgr.Add(events); err != nil {
+			log.Error(err, "Error adding the CloudEvents emitter")
+			os.Exit(1)
+		}
+	}
+
 	si, getSIErr := getSchedulingInfo(ctx, mgr.GetAPIReader())
 	if getSIErr != nil {
 		log.Error(getSIErr, "Getting control plane scheduling info")
@@ -253,10 +300,17 @@ func RunOperator(cmd *cobra.Command, args []string) {
 	}
 
 	// Setup all Controllers
-	if err := controller.AddToManager(mgr, met, si); err != nil {
+	if err := controller.AddToManager(mgr, met, si, events); err != nil {
 		log.Error(err, "")
 		os.Exit(1)
 	}
+
+	skipWebhooks, _ := flags.GetBool("skip-webhooks")
+	if !skipWebhooks {
+		certDir, _ := flags.GetString("webhook-cert-dir")
+		addWebhooks(ctx, mgr, kubeClient, certDir)
+	}
+
 	pflag, _ := flags.GetString("platform")
 	platform := getValidPlatform(pflag)
 
@@ -326,12 +380,46 @@ func addMetrics(ctx context.Context, cfg *rest.Config, kClient *kubernetes.Clien
 		os.Exit(1)
 	}
 
-	if err := createNonComplianceAlert(ctx, mClient, operatorNs); err != nil {
+	if err := createBuiltinAlerts(ctx, mClient, operatorNs); err != nil {
 		log.Error(err, "Error creating PrometheusRule")
 		os.Exit(1)
 	}
 }
 
+// addWebhooks sets up the PKI, Service and webhook configurations needed for the
+// API server to call the operator's admission webhooks, and registers the
+// webhooks with the manager
+func addWebhooks(ctx context.Context, mgr manager.Manager, kClient *kubernetes.Clientset, certDir string) {
+	operatorNs, err := k8sutil.GetOperatorNamespace()
+	if err != nil {
+		if errors.Is(err, k8sutil.ErrRunLocal) {
+			log.Info("Skipping admission webhooks creation; not running in a cluster.")
+			return
+		}
+	}
+
+	caBundle, err := webhook.EnsureServingCert(ctx, kClient, operatorNs, certDir)
+	if err != nil {
+		log.Error(err, "Error creating webhook serving certificate")
+		os.Exit(1)
+	}
+
+	if err := webhook.EnsureService(ctx, kClient, operatorNs); err != nil {
+		log.Error(err, "Error creating webhook service")
+		os.Exit(1)
+	}
+
+	if err := webhook.AddToManager(mgr, certDir); err != nil {
+		log.Error(err, "Error registering admission webhooks")
+		os.Exit(1)
+	}
+
+	if err := webhook.EnsureConfigurations(ctx, kClient, operatorNs, caBundle); err != nil {
+		log.Error(err, "Error creating webhook configurations")
+		os.Exit(1)
+	}
+}
+
 func ensureMetricsServiceAndSecret(ctx context.Context, kClient *kubernetes.Clientset, ns string) (*v1.Service, error) {
 	var mService *v1.Service
 	var err error
@@ -613,38 +701,42 @@ func serveCRMetrics(cfg *rest.Config, operatorNs string) error {
 	return nil
 }
 
-// createNonComplianceAlert tries to create the default PrometheusRule. Returns nil.
-func createNonComplianceAlert(ctx context.Context, client *monclientv1.MonitoringV1Client, namespace string) error {
-	rule := monitoring.Rule{
-		Alert: "NonCompliant",
-		Expr:  intstr.FromString(`compliance_operator_compliance_state{name=~".+"} > 0`),
-		For:   "1s",
-		Labels: map[string]string{
-			"severity": "warning",
-		},
-		Annotations: map[string]string{
-			"summary":     "The cluster is out-of-compliance",
-			"description": "The compliance suite {{ $labels.name }} returned as NON-COMPLIANT, ERROR, or INCONSISTENT",
+// createBuiltinAlerts tries to create or update the PrometheusRule holding
+// the alerts that don't depend on the ScanSettings. The alerts for the
+// results of the suites are reconciled per ScanSettingBinding. Returns nil.
+func createBuiltinAlerts(ctx context.Context, client *monclientv1.MonitoringV1Client, namespace string) error {
+	spec := monitoring.PrometheusRuleSpec{
+		Groups: []monitoring.RuleGroup{
+			{
+				Name:  "compliance",
+				Rules: ctrlMetrics.BuiltinAlertRules(),
+			},
 		},
 	}
-	_, createErr := client.PrometheusRules(namespace).Create(ctx, &monitoring.PrometheusRule{
-		ObjectMeta: metav1.ObjectMeta{
-			Namespace: namespace,
-			Name:      alertName,
-		},
-		Spec: monitoring.PrometheusRuleSpec{
-			Groups: []monitoring.RuleGroup{
-				{
-					Name: "compliance",
-					Rules: []monitoring.Rule{
-						rule,
-					},
-				},
+	found, getErr := client.PrometheusRules(namespace).Get(ctx, alertName, metav1.GetOptions{})
+	if kerr.IsNotFound(getErr) {
+		_, createErr := client.PrometheusRules(namespace).Create(ctx, &monitoring.PrometheusRule{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      alertName,
 			},
-		},
-	}, metav1.CreateOptions{})
-	if createErr != nil && !kerr.IsAlreadyExists(createErr) {
-		log.Info("could not create prometheus rule for alert", createErr)
+			Spec: spec,
+		}, metav1.CreateOptions{})
+		if createErr != nil && !kerr.IsAlreadyExists(createErr) {
+			log.Info("could not create prometheus rule for alert", createErr)
+		}
+		return nil
+	} else if getErr != nil {
+		log.Error(getErr, "could not get prometheus rule for alert")
+		return nil
+	}
+
+	// Previous versions fired the NonCompliant alert of every suite from
+	// this rule. Each suite now gets it from the rule of its binding, or
+	// from its own rule if it has no binding.
+	found.Spec = spec
+	if _, updateErr := client.PrometheusRules(namespace).Update(ctx, found, metav1.UpdateOptions{}); updateErr != nil {
+		log.Error(updateErr, "could not update prometheus rule for alert")
 	}
 	return nil
 }
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/reference"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	cmpv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/profileparser"
+)
+
+// maxContentChangelogs is the number of changelog ConfigMaps that are kept
+// for a bundle
+const maxContentChangelogs = 5
+
+// maxContentChangelogSize is the size the rendered changelog is truncated
+// to. ConfigMaps are limited to 1MiB, which leaves room for the metadata.
+const maxContentChangelogSize = 900 * 1024
+
+// newContentChangelog returns an empty changelog for parsing the given
+// version of the content of a bundle
+func newContentChangelog(pb *cmpv1alpha1.ProfileBundle, version *cmpv1alpha1.ProfileBundleContentVersion) *profileparser.ContentChangelog {
+	changelog := profileparser.NewContentChangelog()
+	changelog.DataStreamVersion = version.DataStreamVersion
+	changelog.ImageDigest = version.ImageDigest
+	if pb.Status.ContentVersion != nil {
+		changelog.PreviousDataStreamVersion = pb.Status.ContentVersion.DataStreamVersion
+		changelog.PreviousImageDigest = pb.Status.ContentVersion.ImageDigest
+	}
+	return changelog
+}
+
+// saveContentChangelog stores the changelog recorded while parsing in a
+// ConfigMap and issues an event summarizing it. Nothing is stored when
+// the content didn't change or was parsed for the first time. Failing to
+// store the changelog doesn't fail the parsing, the summary reports the
+// error instead.
+func saveContentChangelog(pcfg *profileparser.ParserConfig, pb *cmpv1alpha1.ProfileBundle) *cmpv1alpha1.ProfileBundleChangelogSummary {
+	changelog := pcfg.Changelog
+	if changelog == nil || changelog.IsInitial() || changelog.IsEmpty() {
+		return nil
+	}
+
+	added, removed, modified := changelog.Summary()
+	summary := &cmpv1alpha1.ProfileBundleChangelogSummary{
+		Added:    added,
+		Removed:  removed,
+		Modified: modified,
+	}
+	cm, err := newContentChangelogConfigMap(pcfg, pb, changelog)
+	if err != nil {
+		log.Error(err, "Couldn't render the content changelog")
+		summary.ErrorMessage = fmt.Sprintf("Couldn't render the changelog: %v", err)
+		return summary
+	}
+	if err := pcfg.Client.Create(context.TODO(), cm); err != nil {
+		log.Error(err, "Couldn't create the content changelog")
+		summary.ErrorMessage = fmt.Sprintf("Couldn't create the changelog ConfigMap: %v", err)
+		return summary
+	}
+	summary.ConfigMapName = cm.Name
+	summary.Truncated = changelog.Truncated
+	log.Info("Recorded the changes of the content", "ConfigMap", cm.Name,
+		"added", added, "removed", removed, "modified", modified, "truncated", changelog.Truncated)
+
+	if err := pruneContentChangelogs(pcfg.Client, pb); err != nil {
+		log.Error(err, "Couldn't delete old content changelogs")
+	}
+
+	message := fmt.Sprintf("The content changed: %d profiles, rules and variables were added, %d removed and %d modified. "+
+		"See the ConfigMap %s for details", added, removed, modified, cm.Name)
+	if changelog.Truncated {
+		message += ", it only lists part of the changes as the changelog was too large"
+	}
+	if err := createProfileBundleEvent(pcfg, pb, "ContentChanged", message); err != nil {
+		log.Error(err, "Couldn't create the event summarizing the content changelog")
+	}
+
+	return summary
+}
+
+func newContentChangelogConfigMap(pcfg *profileparser.ParserConfig, pb *cmpv1alpha1.ProfileBundle, changelog *profileparser.ContentChangelog) (*corev1.ConfigMap, error) {
+	data, err := changelog.Render(maxContentChangelogSize)
+	if err != nil {
+		return nil, err
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: pb.Name + "-changelog-",
+			Namespace:    pb.Namespace,
+			Labels: map[string]string{
+				cmpv1alpha1.ProfileBundleChangelogLabel: pb.Name,
+			},
+		},
+		Data: map[string]string{
+			cmpv1alpha1.ProfileBundleChangelogKey: string(data),
+		},
+	}
+	if err := controllerutil.SetControllerReference(pb, cm, pcfg.Scheme); err != nil {
+		return nil, err
+	}
+	return cm, nil
+}
+
+// pruneContentChangelogs deletes the oldest changelogs of a bundle, so
+// that only maxContentChangelogs are kept
+func pruneContentChangelogs(c client.Client, pb *cmpv1alpha1.ProfileBundle) error {
+	cmList := corev1.ConfigMapList{}
+	err := c.List(context.TODO(), &cmList, client.InNamespace(pb.Namespace),
+		client.MatchingLabels{cmpv1alpha1.ProfileBundleChangelogLabel: pb.Name})
+	if err != nil {
+		return err
+	}
+	if len(cmList.Items) <= maxContentChangelogs {
+		return nil
+	}
+
+	// Newest first
+	sort.Slice(cmList.Items, func(i, j int) bool {
+		return cmList.Items[j].CreationTimestamp.Before(&cmList.Items[i].CreationTimestamp)
+	})
+	for i := maxContentChangelogs; i < len(cmList.Items); i++ {
+		log.Info("Deleting old content changelog", "ConfigMap", cmList.Items[i].Name)
+		if err := c.Delete(context.TODO(), &cmList.Items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createProfileBundleEvent creates a normal event for a bundle. This is
+// done directly instead of using an event recorder, as those send events
+// asynchronously and the parser exits right after.
+func createProfileBundleEvent(pcfg *profileparser.ParserConfig, pb *cmpv1alpha1.ProfileBundle, reason, message string) error {
+	ref, err := reference.GetReference(pcfg.Scheme, pb)
+	if err != nil {
+		return err
+	}
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%v.%x", pb.Name, now.UnixNano()),
+			Namespace: pb.Namespace,
+		},
+		InvolvedObject: *ref,
+		Reason:         reason,
+		Message:        message,
+		Type:           corev1.EventTypeNormal,
+		Source:         corev1.EventSource{Component: "profileparser"},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	return pcfg.Client.Create(context.TODO(), event)
+}
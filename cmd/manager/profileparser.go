@@ -3,22 +3,37 @@ package main
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	"github.com/antchfx/xmlquery"
 	"github.com/operator-framework/operator-sdk/pkg/log/zap"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	cmpv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
 	"github.com/openshift/compliance-operator/pkg/profileparser"
+	"github.com/openshift/compliance-operator/pkg/utils"
 )
 
+// maxPreviousContentVersions is the number of previous content versions that
+// are kept for a bundle, not counting those that are pinned
+const maxPreviousContentVersions = 3
+
 var profileparserCmd = &cobra.Command{
 	Use:   "profileparser",
 	Short: "Runs the profile parser",
@@ -35,6 +50,9 @@ func defineProfileParserFlags(cmd *cobra.Command) {
 	cmd.Flags().String("ds-path", "/content/ssg-ocp4-ds.xml", "Path to the datastream xml file")
 	cmd.Flags().String("name", "", "Name of the ProfileBundle object")
 	cmd.Flags().String("namespace", "", "Namespace of the ProfileBundle object")
+	cmd.Flags().String("verify-digest", "", "The SHA-256 digest the datastream must have")
+	cmd.Flags().String("public-key-file", "", "Path to the public key to verify the signature of the datastream with")
+	cmd.Flags().String("signature-file", "", "Path to the signature of the datastream")
 
 	flags := cmd.Flags()
 	flags.AddFlagSet(zap.FlagSet())
@@ -79,6 +97,151 @@ func newParserConfig(cmd *cobra.Command) *profileparser.ParserConfig {
 	return &pcfg
 }
 
+// verifyDataStream verifies the data stream as configured by the flags and
+// returns its digest. Whether any verification was done is returned as
+// well.
+func verifyDataStream(cmd *cobra.Command, dsPath string) (string, bool, error) {
+	expectedDigest, _ := cmd.Flags().GetString("verify-digest")
+	publicKeyFile, _ := cmd.Flags().GetString("public-key-file")
+	signatureFile, _ := cmd.Flags().GetString("signature-file")
+
+	content, err := ioutil.ReadFile(filepath.Clean(dsPath))
+	if err != nil {
+		return "", false, err
+	}
+	digest := utils.ContentDigest(content)
+
+	if expectedDigest != "" {
+		if err := utils.VerifyContentDigest(content, expectedDigest); err != nil {
+			return digest, false, err
+		}
+	}
+	if publicKeyFile != "" {
+		publicKey, err := ioutil.ReadFile(filepath.Clean(publicKeyFile))
+		if err != nil {
+			return digest, false, fmt.Errorf("reading the public key: %w", err)
+		}
+		signature, err := ioutil.ReadFile(filepath.Clean(signatureFile))
+		if err != nil {
+			return digest, false, fmt.Errorf("reading the signature: %w", err)
+		}
+		if err := utils.VerifyContentSignature(content, publicKey, signature); err != nil {
+			return digest, false, err
+		}
+	}
+	return digest, expectedDigest != "" || publicKeyFile != "", nil
+}
+
+// getContentDigest returns the digest of the content image as resolved by
+// the container runtime for the pod the parser runs in. If that isn't
+// available, the digest of the content image reference is used, if any.
+func getContentDigest(pcfg *profileparser.ParserConfig, pb *cmpv1alpha1.ProfileBundle) string {
+	if pb.Spec.ContentSource != nil {
+		// There's no image, so the content itself identifies the version
+		digest, err := getFileDigest(pcfg.DataStreamPath)
+		if err != nil {
+			log.Error(err, "Couldn't compute the digest of the content")
+		}
+		return digest
+	}
+	podName := os.Getenv("POD_NAME")
+	if podName != "" {
+		pod := corev1.Pod{}
+		key := types.NamespacedName{Name: podName, Namespace: pcfg.ProfileBundleKey.Namespace}
+		if err := pcfg.Client.Get(context.TODO(), key, &pod); err != nil {
+			log.Error(err, "Couldn't get the profile parser pod, can't resolve the content image digest")
+		} else if digest := getContentDigestFromPod(&pod); digest != "" {
+			return digest
+		}
+	}
+	return getDigestFromImage(pb.Spec.ContentImage)
+}
+
+func getContentDigestFromPod(pod *corev1.Pod) string {
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.Name == "content-container" {
+			return getDigestFromImage(status.ImageID)
+		}
+	}
+	return ""
+}
+
+func getFileDigest(path string) (string, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// getDigestFromImage returns the digest in an image reference or ID, e.g.
+// sha256:abc for quay.io/foo/bar@sha256:abc
+func getDigestFromImage(image string) string {
+	i := strings.LastIndex(image, "@")
+	if i < 0 {
+		return ""
+	}
+	return image[i+1:]
+}
+
+// getPinnedContentDigests returns the digests of the content versions of
+// the given bundle that ScanSettingBindings pin
+func getPinnedContentDigests(pcfg *profileparser.ParserConfig, pb *cmpv1alpha1.ProfileBundle) ([]string, error) {
+	ssbList := cmpv1alpha1.ScanSettingBindingList{}
+	if err := pcfg.Client.List(context.TODO(), &ssbList, client.InNamespace(pb.Namespace)); err != nil {
+		return nil, err
+	}
+	var pinned []string
+	for i := range ssbList.Items {
+		if digest := ssbList.Items[i].GetPinnedContentDigest(pb.Name); digest != "" {
+			pinned = append(pinned, digest)
+		}
+	}
+	return pinned, nil
+}
+
+// getContentVersionHistory returns the previous content versions of the
+// bundle once the given version is parsed. The version that is current
+// becomes the newest previous one, if it's a different one. Only
+// maxPreviousContentVersions versions are kept, apart from those that are
+// pinned, which are always kept.
+func getContentVersionHistory(pb *cmpv1alpha1.ProfileBundle, version *cmpv1alpha1.ProfileBundleContentVersion, pinned []string) []cmpv1alpha1.ProfileBundleContentVersion {
+	var candidates []cmpv1alpha1.ProfileBundleContentVersion
+	if pb.Status.ContentVersion != nil {
+		candidates = append(candidates, *pb.Status.ContentVersion)
+	}
+	candidates = append(candidates, pb.Status.PreviousContentVersions...)
+
+	var history []cmpv1alpha1.ProfileBundleContentVersion
+	seen := map[string]bool{version.ImageDigest: true}
+	kept := 0
+	for _, candidate := range candidates {
+		if candidate.ImageDigest == "" || seen[candidate.ImageDigest] {
+			continue
+		}
+		seen[candidate.ImageDigest] = true
+		if kept >= maxPreviousContentVersions && !containsString(pinned, candidate.ImageDigest) {
+			continue
+		}
+		history = append(history, candidate)
+		kept++
+	}
+	return history
+}
+
+func getContentDigests(versions []cmpv1alpha1.ProfileBundleContentVersion) []string {
+	digests := make([]string, 0, len(versions))
+	for _, version := range versions {
+		digests = append(digests, version.ImageDigest)
+	}
+	return digests
+}
+
 func getProfileBundle(pcfg *profileparser.ParserConfig) (*cmpv1alpha1.ProfileBundle, error) {
 	pb := cmpv1alpha1.ProfileBundle{}
 
@@ -94,6 +257,24 @@ func getProfileBundle(pcfg *profileparser.ParserConfig) (*cmpv1alpha1.ProfileBun
 // updateProfileBundleStatus updates the status of the given ProfileBundle. If
 // the given error is nil, the status will be valid, else it'll be invalid
 func updateProfileBundleStatus(pcfg *profileparser.ParserConfig, pb *cmpv1alpha1.ProfileBundle, err error) {
+	updateProfileBundleStatusWithContent(pcfg, pb, err, nil)
+}
+
+// parsedContent is what gets recorded in the status of a ProfileBundle
+// once its content was parsed successfully
+type parsedContent struct {
+	// The version that becomes the current one
+	version *cmpv1alpha1.ProfileBundleContentVersion
+	// The previous versions
+	history []cmpv1alpha1.ProfileBundleContentVersion
+	// The changes compared to the previous version, if any
+	changelog *cmpv1alpha1.ProfileBundleChangelogSummary
+}
+
+// updateProfileBundleStatusWithContent updates the status of the given
+// ProfileBundle like updateProfileBundleStatus does. If the content was
+// parsed successfully, it's recorded in the status as well.
+func updateProfileBundleStatusWithContent(pcfg *profileparser.ParserConfig, pb *cmpv1alpha1.ProfileBundle, err error, content *parsedContent) {
 	if err != nil {
 		// Never update a fetched object, always just a copy
 		pbCopy := pb.DeepCopy()
@@ -110,6 +291,13 @@ func updateProfileBundleStatus(pcfg *profileparser.ParserConfig, pb *cmpv1alpha1
 		pbCopy := pb.DeepCopy()
 		pbCopy.Status.DataStreamStatus = cmpv1alpha1.DataStreamValid
 		pbCopy.Status.SetConditionReady()
+		if content != nil {
+			pbCopy.Status.ContentVersion = content.version
+			pbCopy.Status.PreviousContentVersions = content.history
+			if content.changelog != nil {
+				pbCopy.Status.LastChangelog = content.changelog
+			}
+		}
 		err = pcfg.Client.Status().Update(context.TODO(), pbCopy)
 		if err != nil {
 			log.Error(err, "Couldn't update ProfileBundle status")
@@ -128,6 +316,13 @@ func runProfileParser(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	dsDigest, verified, err := verifyDataStream(cmd, pcfg.DataStreamPath)
+	if err != nil {
+		log.Error(err, "Couldn't verify the content")
+		updateProfileBundleStatus(pcfg, pb, fmt.Errorf("Couldn't verify the content: %s", err))
+		os.Exit(1)
+	}
+
 	contentFile, err := readContent(pcfg.DataStreamPath)
 	if err != nil {
 		log.Error(err, "Couldn't read the content")
@@ -145,11 +340,40 @@ func runProfileParser(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	now := metav1.Now()
+	version := &cmpv1alpha1.ProfileBundleContentVersion{
+		DataStreamVersion: profileparser.GetDataStreamVersion(contentDom),
+		ContentImage:      pb.Spec.ContentImage,
+		ImageDigest:       getContentDigest(pcfg, pb),
+		DataStreamDigest:  dsDigest,
+		Verified:          verified,
+		ParsedTime:        &now,
+	}
+	if current := pb.Status.ContentVersion; current != nil && current.ImageDigest == version.ImageDigest &&
+		current.ContentImage == version.ContentImage && current.DataStreamVersion == version.DataStreamVersion {
+		// Same content parsed again, keep the original time
+		version.ParsedTime = current.ParsedTime
+	}
+	pinned, err := getPinnedContentDigests(pcfg, pb)
+	if err != nil {
+		log.Error(err, "Couldn't list the content versions pinned by ScanSettingBindings")
+		os.Exit(1)
+	}
+	history := getContentVersionHistory(pb, version, pinned)
+	pcfg.ContentDigest = version.ImageDigest
+	pcfg.RetainedContentDigests = getContentDigests(history)
+	pcfg.Changelog = newContentChangelog(pb, version)
+
 	err = profileparser.ParseBundle(contentDom, pb, pcfg)
 
+	content := &parsedContent{version: version, history: history}
+	if err == nil {
+		content.changelog = saveContentChangelog(pcfg, pb)
+	}
+
 	// The err variable might be nil, this is fine, it'll just update the status
 	// to valid
-	updateProfileBundleStatus(pcfg, pb, err)
+	updateProfileBundleStatusWithContent(pcfg, pb, err, content)
 
 	if err != nil {
 		log.Error(err, "Parsing the bundle failed, will restart the container")
@@ -160,3 +384,12 @@ func runProfileParser(cmd *cobra.Command, args []string) {
 		log.Error(err, "Couldn't close the content file")
 	}
 }
+
+func containsString(s []string, str string) bool {
+	for _, item := range s {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/utils"
+)
+
+var _ = Describe("Recording the content versions of a ProfileBundle", func() {
+	Context("resolving the digest of the content image", func() {
+		It("should use the image ID of the content container", func() {
+			pod := &corev1.Pod{
+				Status: corev1.PodStatus{
+					InitContainerStatuses: []corev1.ContainerStatus{
+						{Name: "content-container", ImageID: "quay.io/foo/content@sha256:abc"},
+						{Name: "profileparser", ImageID: "quay.io/foo/operator@sha256:def"},
+					},
+				},
+			}
+			Expect(getContentDigestFromPod(pod)).To(Equal("sha256:abc"))
+		})
+
+		It("should only find digests in image references that have one", func() {
+			Expect(getDigestFromImage("quay.io/foo/content@sha256:abc")).To(Equal("sha256:abc"))
+			Expect(getDigestFromImage("quay.io/foo/content:latest")).To(BeEmpty())
+		})
+	})
+
+	Context("keeping the previous versions", func() {
+		var pb *compv1alpha1.ProfileBundle
+
+		newVersion := func(digest string) compv1alpha1.ProfileBundleContentVersion {
+			return compv1alpha1.ProfileBundleContentVersion{ImageDigest: digest}
+		}
+
+		BeforeEach(func() {
+			current := newVersion("sha256:4")
+			pb = &compv1alpha1.ProfileBundle{
+				Status: compv1alpha1.ProfileBundleStatus{
+					ContentVersion: &current,
+					PreviousContentVersions: []compv1alpha1.ProfileBundleContentVersion{
+						newVersion("sha256:3"),
+						newVersion("sha256:2"),
+						newVersion("sha256:1"),
+					},
+				},
+			}
+		})
+
+		It("should keep the history when the same content is parsed again", func() {
+			version := newVersion("sha256:4")
+			history := getContentVersionHistory(pb, &version, nil)
+			Expect(getContentDigests(history)).To(Equal([]string{"sha256:3", "sha256:2", "sha256:1"}))
+		})
+
+		It("should make the current version the newest previous one", func() {
+			version := newVersion("sha256:5")
+			history := getContentVersionHistory(pb, &version, nil)
+			Expect(getContentDigests(history)).To(Equal([]string{"sha256:4", "sha256:3", "sha256:2"}))
+		})
+
+		It("should always keep pinned versions", func() {
+			version := newVersion("sha256:5")
+			history := getContentVersionHistory(pb, &version, []string{"sha256:1"})
+			Expect(getContentDigests(history)).To(Equal([]string{"sha256:4", "sha256:3", "sha256:2", "sha256:1"}))
+		})
+	})
+})
+
+var _ = Describe("Verifying the data stream of a ProfileBundle", func() {
+	const content = "<ds:data-stream-collection/>"
+
+	var (
+		dir    string
+		dsPath string
+		cmd    *cobra.Command
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "profileparser")
+		Expect(err).To(BeNil())
+		dsPath = filepath.Join(dir, "ssg-ocp4-ds.xml")
+		Expect(ioutil.WriteFile(dsPath, []byte(content), 0600)).To(Succeed())
+
+		cmd = &cobra.Command{}
+		defineProfileParserFlags(cmd)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("only reports the digest without a verification", func() {
+		digest, verified, err := verifyDataStream(cmd, dsPath)
+		Expect(err).To(BeNil())
+		Expect(verified).To(BeFalse())
+		Expect(digest).To(Equal(utils.ContentDigest([]byte(content))))
+	})
+
+	It("verifies the digest of the data stream", func() {
+		Expect(cmd.Flags().Set("verify-digest", utils.ContentDigest([]byte(content)))).To(Succeed())
+		_, verified, err := verifyDataStream(cmd, dsPath)
+		Expect(err).To(BeNil())
+		Expect(verified).To(BeTrue())
+
+		Expect(cmd.Flags().Set("verify-digest", utils.ContentDigest([]byte("tampered")))).To(Succeed())
+		_, verified, err = verifyDataStream(cmd, dsPath)
+		Expect(err).ToNot(BeNil())
+		Expect(verified).To(BeFalse())
+	})
+
+	It("verifies the signature of the data stream", func() {
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).To(BeNil())
+		der, err := x509.MarshalPKIXPublicKey(public)
+		Expect(err).To(BeNil())
+		publicKeyPath := filepath.Join(dir, "cosign.pub")
+		Expect(ioutil.WriteFile(publicKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600)).To(Succeed())
+		signaturePath := filepath.Join(dir, "ssg-ocp4-ds.xml.sig")
+		signature := base64.StdEncoding.EncodeToString(ed25519.Sign(private, []byte(content)))
+		Expect(ioutil.WriteFile(signaturePath, []byte(signature), 0600)).To(Succeed())
+
+		Expect(cmd.Flags().Set("public-key-file", publicKeyPath)).To(Succeed())
+		Expect(cmd.Flags().Set("signature-file", signaturePath)).To(Succeed())
+		_, verified, err := verifyDataStream(cmd, dsPath)
+		Expect(err).To(BeNil())
+		Expect(verified).To(BeTrue())
+
+		Expect(ioutil.WriteFile(dsPath, []byte("tampered"), 0600)).To(Succeed())
+		_, _, err = verifyDataStream(cmd, dsPath)
+		Expect(err).ToNot(BeNil())
+	})
+})
@@ -0,0 +1,470 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	backoff "github.com/cenkalti/backoff/v4"
+	"github.com/ghodss/yaml"
+	"github.com/operator-framework/operator-sdk/pkg/log/zap"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/complianceremediation"
+)
+
+const (
+	exportFormatKustomize = "kustomize"
+	exportFormatHelm      = "helm"
+	// syncWaveAnnotation orders the objects when they're synced by Argo CD
+	syncWaveAnnotation = "argocd.argoproj.io/sync-wave"
+)
+
+var exportRemediationsCmd = &cobra.Command{
+	Use:   "export-remediations",
+	Short: "Exports the remediations of a ComplianceSuite",
+	Long: `Renders the remediations of a ComplianceSuite into a Kustomize directory or
+a Helm chart, so that they can be applied by a GitOps tool instead of the operator.`,
+	Run: ExportRemediations,
+}
+
+func init() {
+	rootCmd.AddCommand(exportRemediationsCmd)
+	defineExportRemediationsFlags(exportRemediationsCmd)
+}
+
+type exportRemediationsConfig struct {
+	Suite            string
+	Namespace        string
+	Format           string
+	OutputDir        string
+	MarkManaged      bool
+	IncludeUnapplied bool
+	client           *complianceCrClient
+}
+
+func defineExportRemediationsFlags(cmd *cobra.Command) {
+	cmd.Flags().String("suite", "", "The name of the ComplianceSuite whose remediations to export")
+	cmd.Flags().String("namespace", "openshift-compliance", "The namespace of the ComplianceSuite")
+	cmd.Flags().String("format", exportFormatKustomize, "The format to export to, either kustomize or helm")
+	cmd.Flags().String("output", "", "The directory to write the export to. It must not exist or be empty.")
+	cmd.Flags().Bool("mark-managed-externally", true,
+		"Mark the exported remediations as managed externally, so that the operator doesn't apply them")
+	cmd.Flags().Bool("include-unapplied", false,
+		"Also export the remediations that aren't set to be applied")
+
+	flags := cmd.Flags()
+	flags.AddFlagSet(zap.FlagSet())
+
+	// Add flags registered by imported packages (e.g. glog and
+	// controller-runtime)
+	flags.AddGoFlagSet(flag.CommandLine)
+}
+
+func getExportRemediationsConfig(cmd *cobra.Command) *exportRemediationsConfig {
+	var conf exportRemediationsConfig
+	conf.Suite = getValidStringArg(cmd, "suite")
+	conf.Namespace = getValidStringArg(cmd, "namespace")
+	conf.Format = getValidStringArg(cmd, "format")
+	conf.OutputDir = getValidStringArg(cmd, "output")
+	conf.MarkManaged, _ = cmd.Flags().GetBool("mark-managed-externally")
+	conf.IncludeUnapplied, _ = cmd.Flags().GetBool("include-unapplied")
+
+	if conf.Format != exportFormatKustomize && conf.Format != exportFormatHelm {
+		fmt.Fprintf(os.Stderr, "Unknown format '%s', expected %s or %s\n", conf.Format, exportFormatKustomize, exportFormatHelm)
+		os.Exit(1)
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
+	crclient, err := createCrClient(cfg)
+	if err != nil {
+		fmt.Printf("Cannot create client for our types: %v\n", err)
+		os.Exit(1)
+	}
+	conf.client = crclient
+	return &conf
+}
+
+// ExportRemediations renders the remediations of a suite and writes them
+// to a Kustomize directory or a Helm chart
+func ExportRemediations(cmd *cobra.Command, args []string) {
+	conf := getExportRemediationsConfig(cmd)
+	c := conf.client.getClient()
+
+	if err := ensureEmptyDir(conf.OutputDir); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot use the output directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	export, err := renderSuiteRemediations(c, conf.Suite, conf.Namespace, conf.IncludeUnapplied)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot render the remediations of ComplianceSuite '%s': %v\n", conf.Suite, err)
+		os.Exit(1)
+	}
+	for _, skipped := range export.skipped {
+		fmt.Printf("Skipping ComplianceRemediation '%s': %s\n", skipped.name, skipped.reason)
+	}
+
+	if conf.Format == exportFormatHelm {
+		err = writeHelmChart(conf.OutputDir, conf.Suite, export.objects)
+	} else {
+		err = writeKustomization(conf.OutputDir, export.objects)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot write the export: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Exported %d objects from %d remediations to '%s'\n",
+		len(export.objects), len(export.remediations), conf.OutputDir)
+
+	if !conf.MarkManaged {
+		return
+	}
+	for _, rem := range export.remediations {
+		if err := markManagedExternally(c, rem); err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot mark ComplianceRemediation '%s' as managed externally: %v\n", rem.Name, err)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("Marked %d remediations as managed externally\n", len(export.remediations))
+}
+
+// exportedObject is an object rendered from one or more remediations. Several
+// remediations may render the same object, e.g. a KubeletConfig for a pool.
+type exportedObject struct {
+	obj          *unstructured.Unstructured
+	remediations []string
+	// wave orders the objects so that objects are applied after the
+	// objects of the remediations they depend on
+	wave int
+}
+
+type skippedRemediation struct {
+	name   string
+	reason string
+}
+
+type remediationExport struct {
+	objects      []*exportedObject
+	remediations []*compv1alpha1.ComplianceRemediation
+	skipped      []skippedRemediation
+}
+
+// renderSuiteRemediations renders the objects of all the remediations of a
+// suite that can be applied, ordered by their dependencies. Like the operator,
+// it leaves out the remediations that aren't set to be applied, unless asked
+// to include them.
+func renderSuiteRemediations(c runtimeclient.Client, suite, namespace string, includeUnapplied bool) (*remediationExport, error) {
+	suiteSelector := runtimeclient.MatchingLabels{compv1alpha1.SuiteLabel: suite}
+	remList := &compv1alpha1.ComplianceRemediationList{}
+	if err := c.List(context.TODO(), remList, runtimeclient.InNamespace(namespace), suiteSelector); err != nil {
+		return nil, fmt.Errorf("listing remediations: %w", err)
+	}
+	sort.Slice(remList.Items, func(i, j int) bool {
+		return remList.Items[i].Name < remList.Items[j].Name
+	})
+	graph, err := complianceremediation.BuildDependencyGraph(c, suite, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("resolving the remediation dependencies: %w", err)
+	}
+
+	export := &remediationExport{}
+	rendered := map[string]*unstructured.Unstructured{}
+	rems := map[string]*compv1alpha1.ComplianceRemediation{}
+	for i := range remList.Items {
+		rem := &remList.Items[i]
+		if !rem.Spec.Apply && !includeUnapplied {
+			export.skip(rem.Name, "it isn't set to be applied")
+			continue
+		}
+		if rem.Spec.Current.Object == nil {
+			export.skip(rem.Name, "it has no object")
+			continue
+		}
+		unset, err := complianceremediation.GetUnsetRemediationValues(c, rem)
+		if err != nil {
+			return nil, err
+		}
+		if len(unset) > 0 {
+			export.skip(rem.Name, fmt.Sprintf("it needs values that aren't set: %s", strings.Join(unset, ",")))
+			continue
+		}
+		obj, err := complianceremediation.RenderRemediation(c, rem)
+		if err != nil {
+			export.skip(rem.Name, err.Error())
+			continue
+		}
+		rendered[rem.Name] = obj
+		rems[rem.Name] = rem
+	}
+
+	skipUnmetExportDependencies(graph, rendered, export)
+	waves := graph.Waves()
+
+	// Merge the objects that several remediations render, in the order of
+	// the remediation names
+	byKey := map[string]*exportedObject{}
+	names := make([]string, 0, len(rendered))
+	for name := range rendered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		obj := rendered[name]
+		key := strings.Join([]string{obj.GetAPIVersion(), obj.GetKind(), obj.GetNamespace(), obj.GetName()}, "/")
+		exported, ok := byKey[key]
+		if !ok {
+			exported = &exportedObject{obj: obj}
+			byKey[key] = exported
+			export.objects = append(export.objects, exported)
+		} else {
+			mergeExportedContent(exported.obj.Object, obj.Object)
+		}
+		exported.remediations = append(exported.remediations, name)
+		if waves[name] > exported.wave {
+			exported.wave = waves[name]
+		}
+		export.remediations = append(export.remediations, rems[name])
+	}
+
+	for _, exported := range export.objects {
+		annotations := exported.obj.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[syncWaveAnnotation] = strconv.Itoa(exported.wave)
+		exported.obj.SetAnnotations(annotations)
+	}
+	sort.SliceStable(export.objects, func(i, j int) bool {
+		if export.objects[i].wave != export.objects[j].wave {
+			return export.objects[i].wave < export.objects[j].wave
+		}
+		return exportFileName(export.objects[i]) < exportFileName(export.objects[j])
+	})
+	return export, nil
+}
+
+func (e *remediationExport) skip(name, reason string) {
+	e.skipped = append(e.skipped, skippedRemediation{name: name, reason: reason})
+}
+
+// skipUnmetExportDependencies removes the remediations whose dependencies
+// can't be met, or are only provided by remediations that aren't exported,
+// from the rendered remediations
+func skipUnmetExportDependencies(graph *complianceremediation.DependencyGraph,
+	rendered map[string]*unstructured.Unstructured, export *remediationExport) {
+	// Skipping a remediation might leave others with unmet dependencies,
+	// so repeat until nothing changes
+	for {
+		skipped := false
+		for _, edge := range graph.Edges {
+			if _, ok := rendered[edge.Remediation]; !ok {
+				continue
+			}
+			reason := ""
+			switch edge.State {
+			case complianceremediation.DependencyUnsatisfiable:
+				reason = fmt.Sprintf("it depends on %s, which can't be met: %s", edge.Dependency, edge.Reason)
+			case complianceremediation.DependencyPending:
+				if !isProvidedByExport(edge, rendered) {
+					reason = fmt.Sprintf("it depends on %s, which is only provided by remediations that aren't exported: %s",
+						edge.Dependency, strings.Join(edge.ProvidedBy, ", "))
+				}
+			}
+			if reason != "" {
+				export.skip(edge.Remediation, reason)
+				delete(rendered, edge.Remediation)
+				skipped = true
+			}
+		}
+		if !skipped {
+			return
+		}
+	}
+}
+
+func isProvidedByExport(edge complianceremediation.DependencyEdge, rendered map[string]*unstructured.Unstructured) bool {
+	for _, provider := range edge.ProvidedBy {
+		if _, ok := rendered[provider]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeExportedContent merges src into dst the way a merge patch would,
+// which is how the operator applies objects that already exist
+func mergeExportedContent(dst, src map[string]interface{}) {
+	for key, srcVal := range src {
+		srcMap, srcIsMap := srcVal.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeExportedContent(dstMap, srcMap)
+			continue
+		}
+		dst[key] = srcVal
+	}
+}
+
+func exportFileName(exported *exportedObject) string {
+	return fmt.Sprintf("%02d-%s-%s.yaml", exported.wave,
+		strings.ToLower(exported.obj.GetKind()), exported.obj.GetName())
+}
+
+func exportObjectContent(exported *exportedObject) ([]byte, error) {
+	content, err := yaml.Marshal(exported.obj.Object)
+	if err != nil {
+		return nil, err
+	}
+	header := fmt.Sprintf("# Rendered from the ComplianceRemediations: %s\n", strings.Join(exported.remediations, ", "))
+	return append([]byte(header), content...), nil
+}
+
+type kustomization struct {
+	APIVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Resources  []string `json:"resources"`
+}
+
+// writeKustomization writes one file per object and a kustomization.yaml
+// listing them in the order they are to be applied
+func writeKustomization(dir string, objects []*exportedObject) error {
+	k := kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Resources:  []string{},
+	}
+	for _, exported := range objects {
+		content, err := exportObjectContent(exported)
+		if err != nil {
+			return err
+		}
+		fileName := exportFileName(exported)
+		if err := ioutil.WriteFile(filepath.Join(dir, fileName), content, 0600); err != nil {
+			return err
+		}
+		k.Resources = append(k.Resources, fileName)
+	}
+
+	content, err := yaml.Marshal(k)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "kustomization.yaml"), content, 0600)
+}
+
+type helmChart struct {
+	APIVersion  string `json:"apiVersion"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Version     string `json:"version"`
+}
+
+// writeHelmChart writes a chart whose templates include the objects as
+// files, so that their content is never interpreted as a template. Each
+// remediation can be disabled through the values of the chart.
+func writeHelmChart(dir, suite string, objects []*exportedObject) error {
+	for _, sub := range []string{"files", "templates"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0750); err != nil {
+			return err
+		}
+	}
+
+	chart := helmChart{
+		APIVersion:  "v2",
+		Name:        suite + "-remediations",
+		Description: fmt.Sprintf("Remediations of the ComplianceSuite %s", suite),
+		Type:        "application",
+		Version:     "0.1.0",
+	}
+	content, err := yaml.Marshal(chart)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "Chart.yaml"), content, 0600); err != nil {
+		return err
+	}
+
+	values := map[string]map[string]bool{"remediations": {}}
+	for _, exported := range objects {
+		content, err := exportObjectContent(exported)
+		if err != nil {
+			return err
+		}
+		fileName := exportFileName(exported)
+		if err := ioutil.WriteFile(filepath.Join(dir, "files", fileName), content, 0600); err != nil {
+			return err
+		}
+
+		conditions := make([]string, 0, len(exported.remediations))
+		for _, name := range exported.remediations {
+			values["remediations"][name] = true
+			conditions = append(conditions, fmt.Sprintf("(index .Values.remediations %q)", name))
+		}
+		template := fmt.Sprintf("{{- if and %s }}\n{{ .Files.Get %q }}\n{{- end }}\n",
+			strings.Join(conditions, " "), "files/"+fileName)
+		if err := ioutil.WriteFile(filepath.Join(dir, "templates", fileName), []byte(template), 0600); err != nil {
+			return err
+		}
+	}
+
+	content, err = yaml.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "values.yaml"), content, 0600)
+}
+
+func ensureEmptyDir(dir string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return os.MkdirAll(dir, 0750)
+	} else if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("the directory %s is not empty", dir)
+	}
+	return nil
+}
+
+// markManagedExternally annotates the remediation so that the operator no
+// longer applies it. The outdated content is dropped, as the current
+// content is what was exported.
+func markManagedExternally(c runtimeclient.Client, rem *compv1alpha1.ComplianceRemediation) error {
+	key := types.NamespacedName{Name: rem.GetName(), Namespace: rem.GetNamespace()}
+	return backoff.Retry(func() error {
+		found := &compv1alpha1.ComplianceRemediation{}
+		if err := c.Get(context.TODO(), key, found); err != nil {
+			if errors.IsNotFound(err) {
+				return backoff.Permanent(err)
+			}
+			return err
+		}
+		remCopy := found.DeepCopy()
+		if remCopy.Annotations == nil {
+			remCopy.Annotations = make(map[string]string)
+		}
+		remCopy.Annotations[compv1alpha1.RemediationManagedExternallyAnnotation] = ""
+		remCopy.Spec.Outdated.Object = nil
+		return c.Update(context.TODO(), remCopy)
+	}, backoff.WithMaxRetries(backoff.NewExponentialBackOff(), maxRetries))
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+const exportSuite = "my-suite"
+
+func newExportCheck(name, id string, status compv1alpha1.ComplianceCheckStatus) *compv1alpha1.ComplianceCheckResult {
+	return &compv1alpha1.ComplianceCheckResult{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "test-ns",
+			Labels:    map[string]string{compv1alpha1.SuiteLabel: exportSuite},
+		},
+		ID:     id,
+		Status: status,
+	}
+}
+
+func newExportRemediation(name, check, cmName string, data map[string]interface{}, dependsOn string) *compv1alpha1.ComplianceRemediation {
+	rem := &compv1alpha1.ComplianceRemediation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "test-ns",
+			Labels:    map[string]string{compv1alpha1.SuiteLabel: exportSuite},
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: "ComplianceCheckResult", Name: check},
+			},
+		},
+	}
+	rem.Spec.Apply = true
+	if dependsOn != "" {
+		rem.Annotations = map[string]string{compv1alpha1.RemediationDependencyAnnotation: dependsOn}
+	}
+	rem.Spec.Current.Object = &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      cmName,
+			"namespace": "other-ns",
+		},
+		"data": data,
+	}}
+	return rem
+}
+
+var _ = Describe("Exporting remediations", func() {
+	var client runtimeclient.Client
+
+	exportedRemediations := func(export *remediationExport) []string {
+		var names []string
+		for _, exported := range export.objects {
+			names = append(names, exported.remediations...)
+		}
+		return names
+	}
+
+	Context("with remediations that depend on each other", func() {
+		BeforeEach(func() {
+			client = fake.NewFakeClientWithScheme(getScheme(),
+				newExportCheck("check-a", "xccdf_rule_a", compv1alpha1.CheckResultFail),
+				newExportCheck("check-b", "xccdf_rule_b", compv1alpha1.CheckResultFail),
+				newExportCheck("check-c", "xccdf_rule_c", compv1alpha1.CheckResultPass),
+				newExportCheck("check-d", "xccdf_rule_d", compv1alpha1.CheckResultFail),
+				newExportCheck("check-e", "xccdf_rule_e", compv1alpha1.CheckResultFail),
+				newExportRemediation("rem-a", "check-a", "cm-a", map[string]interface{}{"a": "1"}, ""),
+				newExportRemediation("rem-b", "check-b", "cm-b", map[string]interface{}{"b": "1"}, "xccdf_rule_a,xccdf_rule_c"),
+				// the check this depends on fails and has no remediation
+				newExportRemediation("rem-e", "check-e", "cm-e", map[string]interface{}{"e": "1"}, "xccdf_rule_d"),
+				// same object as rem-a
+				newExportRemediation("rem-shared", "check-a", "cm-a", map[string]interface{}{"shared": "1"}, ""),
+			)
+		})
+
+		It("should order the objects by their dependencies", func() {
+			export, err := renderSuiteRemediations(client, exportSuite, "test-ns", false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(export.objects).To(HaveLen(2))
+			Expect(export.objects[0].obj.GetName()).To(Equal("cm-a"))
+			Expect(export.objects[0].wave).To(Equal(0))
+			Expect(export.objects[1].obj.GetName()).To(Equal("cm-b"))
+			Expect(export.objects[1].wave).To(Equal(1))
+			Expect(export.objects[1].obj.GetAnnotations()).To(HaveKeyWithValue(syncWaveAnnotation, "1"))
+		})
+
+		It("should merge objects rendered by several remediations", func() {
+			export, err := renderSuiteRemediations(client, exportSuite, "test-ns", false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(export.objects[0].remediations).To(Equal([]string{"rem-a", "rem-shared"}))
+			data, _, err := unstructured.NestedStringMap(export.objects[0].obj.Object, "data")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(map[string]string{"a": "1", "shared": "1"}))
+		})
+
+		It("should skip remediations with unmet dependencies", func() {
+			export, err := renderSuiteRemediations(client, exportSuite, "test-ns", false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exportedRemediations(export)).ToNot(ContainElement("rem-e"))
+			Expect(export.skipped).To(HaveLen(1))
+			Expect(export.skipped[0].name).To(Equal("rem-e"))
+		})
+
+		It("should write a kustomization", func() {
+			export, err := renderSuiteRemediations(client, exportSuite, "test-ns", false)
+			Expect(err).ToNot(HaveOccurred())
+			dir, err := ioutil.TempDir("", "export")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			Expect(writeKustomization(dir, export.objects)).To(Succeed())
+			content, err := ioutil.ReadFile(filepath.Join(dir, "kustomization.yaml"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(ContainSubstring("- 00-configmap-cm-a.yaml\n- 01-configmap-cm-b.yaml"))
+			Expect(filepath.Join(dir, "01-configmap-cm-b.yaml")).To(BeAnExistingFile())
+		})
+
+		It("should write a helm chart", func() {
+			export, err := renderSuiteRemediations(client, exportSuite, "test-ns", false)
+			Expect(err).ToNot(HaveOccurred())
+			dir, err := ioutil.TempDir("", "export")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			Expect(writeHelmChart(dir, exportSuite, export.objects)).To(Succeed())
+			Expect(filepath.Join(dir, "Chart.yaml")).To(BeAnExistingFile())
+			Expect(filepath.Join(dir, "files", "00-configmap-cm-a.yaml")).To(BeAnExistingFile())
+			template, err := ioutil.ReadFile(filepath.Join(dir, "templates", "00-configmap-cm-a.yaml"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(template)).To(ContainSubstring(`(index .Values.remediations "rem-shared")`))
+			values, err := ioutil.ReadFile(filepath.Join(dir, "values.yaml"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(values)).To(ContainSubstring("rem-b: true"))
+		})
+
+		It("should mark the remediations as managed externally", func() {
+			export, err := renderSuiteRemediations(client, exportSuite, "test-ns", false)
+			Expect(err).ToNot(HaveOccurred())
+			for _, rem := range export.remediations {
+				Expect(markManagedExternally(client, rem)).To(Succeed())
+			}
+			found := &compv1alpha1.ComplianceRemediation{}
+			err = client.Get(context.TODO(), types.NamespacedName{Name: "rem-b", Namespace: "test-ns"}, found)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found.IsManagedExternally()).To(BeTrue())
+		})
+	})
+
+	Context("with remediations that aren't set to be applied", func() {
+		BeforeEach(func() {
+			unapplied := newExportRemediation("rem-b", "check-b", "cm-b", map[string]interface{}{"b": "1"}, "")
+			unapplied.Spec.Apply = false
+			client = fake.NewFakeClientWithScheme(getScheme(),
+				newExportCheck("check-a", "xccdf_rule_a", compv1alpha1.CheckResultFail),
+				newExportCheck("check-b", "xccdf_rule_b", compv1alpha1.CheckResultFail),
+				newExportRemediation("rem-a", "check-a", "cm-a", map[string]interface{}{"a": "1"}, ""),
+				unapplied,
+			)
+		})
+
+		It("should skip them", func() {
+			export, err := renderSuiteRemediations(client, exportSuite, "test-ns", false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exportedRemediations(export)).To(Equal([]string{"rem-a"}))
+			Expect(export.skipped).To(HaveLen(1))
+			Expect(export.skipped[0].name).To(Equal("rem-b"))
+		})
+
+		It("should export them if asked to", func() {
+			export, err := renderSuiteRemediations(client, exportSuite, "test-ns", true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exportedRemediations(export)).To(Equal([]string{"rem-a", "rem-b"}))
+			Expect(export.skipped).To(BeEmpty())
+		})
+	})
+
+	Context("with checks of the same rule in several scans", func() {
+		BeforeEach(func() {
+			inScan := func(obj metav1.Object, scan string) runtime.Object {
+				obj.GetLabels()[compv1alpha1.ComplianceScanLabel] = scan
+				return obj.(runtime.Object)
+			}
+			client = fake.NewFakeClientWithScheme(getScheme(),
+				inScan(newExportCheck("masters-a", "xccdf_rule_a", compv1alpha1.CheckResultPass), "masters"),
+				inScan(newExportCheck("masters-b", "xccdf_rule_b", compv1alpha1.CheckResultFail), "masters"),
+				inScan(newExportCheck("workers-a", "xccdf_rule_a", compv1alpha1.CheckResultFail), "workers"),
+				inScan(newExportRemediation("masters-b", "masters-b", "cm-b", map[string]interface{}{"b": "1"}, "xccdf_rule_a"), "masters"),
+				inScan(newExportRemediation("workers-a", "workers-a", "cm-a", map[string]interface{}{"a": "1"}, ""), "workers"),
+			)
+		})
+
+		It("should only depend on the check of the same scan", func() {
+			export, err := renderSuiteRemediations(client, exportSuite, "test-ns", false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(export.skipped).To(BeEmpty())
+			Expect(export.objects).To(HaveLen(2))
+			Expect(export.objects[0].wave).To(Equal(0))
+			Expect(export.objects[1].wave).To(Equal(0))
+		})
+	})
+
+	Context("with remediations that depend on each other in a cycle", func() {
+		BeforeEach(func() {
+			client = fake.NewFakeClientWithScheme(getScheme(),
+				newExportCheck("check-a", "xccdf_rule_a", compv1alpha1.CheckResultFail),
+				newExportCheck("check-b", "xccdf_rule_b", compv1alpha1.CheckResultFail),
+				newExportRemediation("rem-a", "check-a", "cm-a", map[string]interface{}{"a": "1"}, "xccdf_rule_b"),
+				newExportRemediation("rem-b", "check-b", "cm-b", map[string]interface{}{"b": "1"}, "xccdf_rule_a"),
+			)
+		})
+
+		It("should skip them", func() {
+			export, err := renderSuiteRemediations(client, exportSuite, "test-ns", false)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(export.objects).To(BeEmpty())
+			Expect(export.skipped).To(HaveLen(2))
+			Expect(export.skipped[0].reason).To(ContainSubstring("dependency cycle"))
+		})
+	})
+})
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/operator-framework/operator-sdk/pkg/log/zap"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	"github.com/openshift/compliance-operator/pkg/controller/complianceremediation"
+)
+
+const (
+	graphFormatDOT  = "dot"
+	graphFormatJSON = "json"
+)
+
+var remediationGraphCmd = &cobra.Command{
+	Use:   "remediation-graph",
+	Short: "Prints the dependency graph of the remediations of a ComplianceSuite",
+	Long: `Prints the dependencies between the remediations of a ComplianceSuite in the
+DOT language of Graphviz or in JSON, including cycles and dependencies that can't be met.`,
+	Run: PrintRemediationGraph,
+}
+
+func init() {
+	rootCmd.AddCommand(remediationGraphCmd)
+	defineRemediationGraphFlags(remediationGraphCmd)
+}
+
+func defineRemediationGraphFlags(cmd *cobra.Command) {
+	cmd.Flags().String("suite", "", "The name of the ComplianceSuite whose remediations to show")
+	cmd.Flags().String("namespace", "openshift-compliance", "The namespace of the ComplianceSuite")
+	cmd.Flags().String("format", graphFormatDOT, "The format to print the graph in, either dot or json")
+
+	flags := cmd.Flags()
+	flags.AddFlagSet(zap.FlagSet())
+
+	// Add flags registered by imported packages (e.g. glog and
+	// controller-runtime)
+	flags.AddGoFlagSet(flag.CommandLine)
+}
+
+// PrintRemediationGraph prints the dependency graph of the remediations of
+// a suite to stdout
+func PrintRemediationGraph(cmd *cobra.Command, args []string) {
+	suite := getValidStringArg(cmd, "suite")
+	namespace := getValidStringArg(cmd, "namespace")
+	format := getValidStringArg(cmd, "format")
+	if format != graphFormatDOT && format != graphFormatJSON {
+		fmt.Fprintf(os.Stderr, "Unknown format '%s', expected %s or %s\n", format, graphFormatDOT, graphFormatJSON)
+		os.Exit(1)
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+	crclient, err := createCrClient(cfg)
+	if err != nil {
+		fmt.Printf("Cannot create client for our types: %v\n", err)
+		os.Exit(1)
+	}
+
+	graph, err := complianceremediation.BuildDependencyGraph(crclient.getClient(), suite, namespace)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot build the dependency graph of ComplianceSuite '%s': %v\n", suite, err)
+		os.Exit(1)
+	}
+
+	if format == graphFormatJSON {
+		out, err := graph.JSON()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot marshal the dependency graph: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(out))
+		return
+	}
+	fmt.Print(graph.DOT())
+}
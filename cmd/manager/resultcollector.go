@@ -71,6 +71,7 @@ type scapresultsConfig struct {
 	ExitCodeFile       string
 	CmdOutputFile      string
 	WarningsOutputFile string
+	CustomRuleResults  string
 	ScanName           string
 	ConfigMapName      string
 	NodeName           string
@@ -88,6 +89,7 @@ func defineResultcollectorFlags(cmd *cobra.Command) {
 	cmd.Flags().String("exit-code-file", "", "A file containing the oscap command's exit code.")
 	cmd.Flags().String("oscap-output-file", "", "A file containing the oscap command's output.")
 	cmd.Flags().String("warnings-output-file", "", "A file containing the warnings to output.")
+	cmd.Flags().String("custom-rules-results-file", "", "A file containing the results of the custom rules.")
 	cmd.Flags().String("owner", "", "The compliance scan that owns the configMap objects.")
 	cmd.Flags().String("config-map-name", "", "The configMap to upload to, typically the podname.")
 	cmd.Flags().String("node-name", "", "The node that was scanned.")
@@ -125,6 +127,7 @@ func parseConfig(cmd *cobra.Command) *scapresultsConfig {
 		conf.ResultServerURI = "http://" + conf.ScanName + "-rs:8080/"
 	}
 	conf.WarningsOutputFile, _ = cmd.Flags().GetString("warnings-output-file")
+	conf.CustomRuleResults, _ = cmd.Flags().GetString("custom-rules-results-file")
 
 	// platform scans have no node name
 	conf.NodeName, _ = cmd.Flags().GetString("node-name")
@@ -298,6 +301,23 @@ func readWarningsFile(filename string) string {
 	return strings.Trim(string(contents), "\n")
 }
 
+func readCustomRuleResultsFile(filename string) string {
+	if filename == "" {
+		return ""
+	}
+	contents, err := ioutil.ReadFile(filepath.Clean(filename))
+	if os.IsNotExist(err) {
+		// No custom rules were evaluated
+		return ""
+	}
+	if err != nil {
+		DBG("Error while reading custom rule results file: %v", err)
+		return ""
+	}
+
+	return string(contents)
+}
+
 func uploadToResultServer(arfContents *resultFileContents, scapresultsconf *scapresultsConfig) error {
 	return backoff.Retry(func() error {
 		url := scapresultsconf.ResultServerURI
@@ -333,6 +353,7 @@ func uploadToResultServer(arfContents *resultFileContents, scapresultsconf *scap
 func uploadResultConfigMap(xccdfContents *resultFileContents, exitcode string,
 	scapresultsconf *scapresultsConfig, client *complianceCrClient) error {
 	warnings := readWarningsFile(scapresultsconf.WarningsOutputFile)
+	customRuleResults := readCustomRuleResultsFile(scapresultsconf.CustomRuleResults)
 
 	return backoff.Retry(func() error {
 		log.Info("Trying to upload results ConfigMap")
@@ -342,6 +363,9 @@ func uploadResultConfigMap(xccdfContents *resultFileContents, exitcode string,
 		}
 		confMap := utils.GetResultConfigMap(openscapScan, scapresultsconf.ConfigMapName, "results",
 			scapresultsconf.NodeName, xccdfContents.contents, xccdfContents.compressed, exitcode, warnings)
+		if customRuleResults != "" {
+			confMap.Data[compv1alpha1.CustomRuleResultsKey] = customRuleResults
+		}
 		err = client.client.Create(context.TODO(), confMap)
 
 		if errors.IsAlreadyExists(err) {
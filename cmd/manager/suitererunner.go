@@ -4,7 +4,9 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"math/rand"
 	"os"
+	"time"
 
 	backoff "github.com/cenkalti/backoff/v4"
 	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
@@ -34,12 +36,14 @@ func init() {
 type rerunnerconfig struct {
 	Name      string
 	Namespace string
+	Jitter    time.Duration
 	client    *complianceCrClient
 }
 
 func defineRerunnerFlags(cmd *cobra.Command) {
 	cmd.Flags().String("name", "", "The name of the ComplianceSuite to be re-run")
 	cmd.Flags().String("namespace", "", "The namespace of the ComplianceSuite to be re-run")
+	cmd.Flags().Duration("jitter", 0, "Waits for a random duration up to this one before re-running the ComplianceSuite")
 
 	flags := cmd.Flags()
 	flags.AddFlagSet(zap.FlagSet())
@@ -53,6 +57,12 @@ func getRerunnerConfig(cmd *cobra.Command) *rerunnerconfig {
 	var conf rerunnerconfig
 	conf.Name = getValidStringArg(cmd, "name")
 	conf.Namespace = getValidStringArg(cmd, "namespace")
+	jitter, err := cmd.Flags().GetDuration("jitter")
+	if err != nil {
+		fmt.Printf("Invalid jitter: %v\n", err)
+		os.Exit(1)
+	}
+	conf.Jitter = jitter
 
 	cfg, err := config.GetConfig()
 	if err != nil {
@@ -72,6 +82,15 @@ func getRerunnerConfig(cmd *cobra.Command) *rerunnerconfig {
 func RerunSuite(cmd *cobra.Command, args []string) {
 	conf := getRerunnerConfig(cmd)
 
+	if conf.Jitter > 0 {
+		// Seeded so that the rerunners sharing a schedule don't all wait as
+		// long
+		rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+		delay := time.Duration(rng.Int63n(int64(conf.Jitter)))
+		fmt.Printf("Waiting %s before re-running the ComplianceSuite '%s'\n", delay.Round(time.Second), conf.Name)
+		time.Sleep(delay)
+	}
+
 	scans := &compv1alpha1.ComplianceScanList{}
 	scanSuiteSelector := make(map[string]string)
 	scanSuiteSelector[compv1alpha1.SuiteLabel] = conf.Name
@@ -87,6 +106,11 @@ func RerunSuite(cmd *cobra.Command, args []string) {
 
 	fmt.Printf("Got %d scans from the ComplianceSuite '%s'\n", len(scans.Items), conf.Name)
 
+	if scan := getScanInProgress(scans.Items); scan != nil {
+		fmt.Printf("Skipping this run, the ComplianceScan '%s' is still in the %s phase\n", scan.Name, scan.Status.Phase)
+		return
+	}
+
 	for idx := range scans.Items {
 		currentScan := &scans.Items[idx]
 		key := types.NamespacedName{Name: currentScan.GetName(), Namespace: currentScan.GetNamespace()}
@@ -126,3 +150,14 @@ func RerunSuite(cmd *cobra.Command, args []string) {
 		}
 	}
 }
+
+// getScanInProgress returns a scan of the suite whose previous run didn't
+// finish, if any
+func getScanInProgress(scans []compv1alpha1.ComplianceScan) *compv1alpha1.ComplianceScan {
+	for i := range scans {
+		if scans[i].Status.Phase != compv1alpha1.PhaseDone {
+			return &scans[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/ghodss/yaml"
+	"github.com/operator-framework/operator-sdk/pkg/log/zap"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/common"
+	"github.com/openshift/compliance-operator/pkg/xccdf"
+)
+
+var importTailoringCmd = &cobra.Command{
+	Use:   "import-tailoring",
+	Short: "Imports an XCCDF tailoring file as a TailoredProfile",
+	Long: `Converts a profile of an XCCDF tailoring file, e.g. one written by SCAP Workbench,
+into a TailoredProfile. The profile it extends, its rules and its variables are
+validated against the content of a ProfileBundle.`,
+	Run: ImportTailoring,
+}
+
+var exportTailoringCmd = &cobra.Command{
+	Use:   "export-tailoring",
+	Short: "Exports a TailoredProfile as an XCCDF tailoring file",
+	Long: `Writes the tailoring generated for a TailoredProfile to a standalone XCCDF
+tailoring file, which can be used with oscap or SCAP Workbench along with the
+data stream of the ProfileBundle.`,
+	Run: ExportTailoring,
+}
+
+func init() {
+	rootCmd.AddCommand(importTailoringCmd)
+	rootCmd.AddCommand(exportTailoringCmd)
+	defineImportTailoringFlags(importTailoringCmd)
+	defineExportTailoringFlags(exportTailoringCmd)
+}
+
+type importTailoringConfig struct {
+	File          string
+	ProfileID     string
+	ProfileBundle string
+	Namespace     string
+	Name          string
+	Output        string
+	Create        bool
+	client        *complianceCrClient
+}
+
+type exportTailoringConfig struct {
+	TailoredProfile string
+	Namespace       string
+	BenchmarkHref   string
+	Output          string
+	client          *complianceCrClient
+}
+
+func addTailoringCommonFlags(cmd *cobra.Command) {
+	cmd.Flags().String("namespace", "openshift-compliance", "The namespace of the TailoredProfile")
+	cmd.Flags().String("output", "-", "The file to write to, or - for the standard output")
+
+	flags := cmd.Flags()
+	flags.AddFlagSet(zap.FlagSet())
+
+	// Add flags registered by imported packages (e.g. glog and
+	// controller-runtime)
+	flags.AddGoFlagSet(flag.CommandLine)
+}
+
+func defineImportTailoringFlags(cmd *cobra.Command) {
+	cmd.Flags().String("file", "", "The XCCDF tailoring file to import")
+	cmd.Flags().String("profile-id", "", "The XCCDF ID of the profile to import, if the file contains several")
+	cmd.Flags().String("profile-bundle", "", "The ProfileBundle whose content the tailoring applies to")
+	cmd.Flags().String("name", "", "The name of the TailoredProfile. Defaults to one derived from the profile ID.")
+	cmd.Flags().Bool("create", false, "Create the TailoredProfile instead of only writing it")
+	addTailoringCommonFlags(cmd)
+}
+
+func defineExportTailoringFlags(cmd *cobra.Command) {
+	cmd.Flags().String("tailored-profile", "", "The TailoredProfile to export")
+	cmd.Flags().String("benchmark-href", "",
+		"The path of the data stream the tailoring refers to. Defaults to the file name of the data stream of the ProfileBundle.")
+	addTailoringCommonFlags(cmd)
+}
+
+func getTailoringClient() *complianceCrClient {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
+	crclient, err := createCrClient(cfg)
+	if err != nil {
+		fmt.Printf("Cannot create client for our types: %v\n", err)
+		os.Exit(1)
+	}
+	return crclient
+}
+
+func getImportTailoringConfig(cmd *cobra.Command) *importTailoringConfig {
+	var conf importTailoringConfig
+	conf.File = getValidStringArg(cmd, "file")
+	conf.ProfileBundle = getValidStringArg(cmd, "profile-bundle")
+	conf.Namespace = getValidStringArg(cmd, "namespace")
+	conf.Output = getValidStringArg(cmd, "output")
+	conf.ProfileID, _ = cmd.Flags().GetString("profile-id")
+	conf.Name, _ = cmd.Flags().GetString("name")
+	conf.Create, _ = cmd.Flags().GetBool("create")
+	conf.client = getTailoringClient()
+	return &conf
+}
+
+func getExportTailoringConfig(cmd *cobra.Command) *exportTailoringConfig {
+	var conf exportTailoringConfig
+	conf.TailoredProfile = getValidStringArg(cmd, "tailored-profile")
+	conf.Namespace = getValidStringArg(cmd, "namespace")
+	conf.Output = getValidStringArg(cmd, "output")
+	conf.BenchmarkHref, _ = cmd.Flags().GetString("benchmark-href")
+	conf.client = getTailoringClient()
+	return &conf
+}
+
+// ImportTailoring converts a profile of a tailoring file into a
+// TailoredProfile and writes or creates it
+func ImportTailoring(cmd *cobra.Command, args []string) {
+	conf := getImportTailoringConfig(cmd)
+	c := conf.client.getClient()
+
+	f, err := readContent(conf.File)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot open the tailoring file: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	tailoring, err := xccdf.ParseTailoring(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot read the tailoring file: %v\n", err)
+		os.Exit(1)
+	}
+
+	tp, err := importTailoredProfile(c, tailoring, conf.ProfileID, conf.ProfileBundle, conf.Namespace, conf.Name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot import the tailoring file: %v\n", err)
+		os.Exit(1)
+	}
+
+	out, err := yaml.Marshal(tp)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot render the TailoredProfile: %v\n", err)
+		os.Exit(1)
+	}
+	if err := writeTailoringOutput(conf.Output, out); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot write the TailoredProfile: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !conf.Create {
+		return
+	}
+	if err := c.Create(context.TODO(), tp); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot create TailoredProfile '%s': %v\n", tp.Name, err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Created TailoredProfile '%s'\n", tp.Name)
+}
+
+// ExportTailoring writes the tailoring of a TailoredProfile to a
+// standalone tailoring file
+func ExportTailoring(cmd *cobra.Command, args []string) {
+	conf := getExportTailoringConfig(cmd)
+	c := conf.client.getClient()
+
+	tailoring, skipped, err := exportTailoredProfile(c, conf.TailoredProfile, conf.Namespace, conf.BenchmarkHref)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot export TailoredProfile '%s': %v\n", conf.TailoredProfile, err)
+		os.Exit(1)
+	}
+	for _, name := range skipped {
+		fmt.Fprintf(os.Stderr, "Skipping CustomRule '%s': CustomRules can't be expressed in XCCDF\n", name)
+	}
+
+	if err := writeTailoringOutput(conf.Output, []byte(tailoring)); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot write the tailoring file: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func writeTailoringOutput(output string, data []byte) error {
+	if output == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(output, data, 0600)
+}
+
+// importTailoredProfile converts a profile of a tailoring into a
+// TailoredProfile validated against the content of the bundle
+func importTailoredProfile(c runtimeclient.Client, tailoring *xccdf.Tailoring, profileID, bundle, namespace, name string) (*compv1alpha1.TailoredProfile, error) {
+	pb := &compv1alpha1.ProfileBundle{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: bundle, Namespace: namespace}, pb); err != nil {
+		return nil, fmt.Errorf("fetching ProfileBundle '%s': %w", bundle, err)
+	}
+	if pb.Status.DataStreamStatus != compv1alpha1.DataStreamValid {
+		return nil, fmt.Errorf("the content of ProfileBundle '%s' isn't valid yet", bundle)
+	}
+
+	profile, err := tailoring.GetProfile(profileID)
+	if err != nil {
+		return nil, err
+	}
+	content, err := common.GetBundleContent(c, bundle, namespace)
+	if err != nil {
+		return nil, err
+	}
+	rationale := fmt.Sprintf("Imported from the XCCDF tailoring %s", tailoring.ID)
+	spec, err := xccdf.TailoringProfileToSpec(profile, content, rationale)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name = xccdf.GetTailoredProfileName(profile.ID)
+	}
+	tp := &compv1alpha1.TailoredProfile{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "TailoredProfile",
+			APIVersion: compv1alpha1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: *spec,
+	}
+	// Keep the product type of the extended profile, otherwise the
+	// controller infers it
+	if extended, ok := content.Profiles[profile.Extends]; ok {
+		if productType, ok := extended.Annotations[compv1alpha1.ProductTypeAnnotation]; ok {
+			tp.Annotations = map[string]string{compv1alpha1.ProductTypeAnnotation: productType}
+		}
+	}
+	return tp, nil
+}
+
+// exportTailoredProfile returns the tailoring the controller generated for
+// a TailoredProfile as a standalone tailoring file, along with the names
+// of the CustomRules it enables, which the file can't express
+func exportTailoredProfile(c runtimeclient.Client, name, namespace, benchmarkHref string) (string, []string, error) {
+	tp := &compv1alpha1.TailoredProfile{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, tp); err != nil {
+		return "", nil, err
+	}
+	if tp.Status.State != compv1alpha1.TailoredProfileStateReady {
+		return "", nil, fmt.Errorf("the TailoredProfile isn't ready: %s", tp.Status.ErrorMessage)
+	}
+
+	cm := &corev1.ConfigMap{}
+	cmKey := types.NamespacedName{Name: tp.Status.OutputRef.Name, Namespace: tp.Status.OutputRef.Namespace}
+	if err := c.Get(context.TODO(), cmKey, cm); err != nil {
+		return "", nil, fmt.Errorf("fetching the tailoring of the TailoredProfile: %w", err)
+	}
+	data, ok := cm.Data["tailoring.xml"]
+	if !ok {
+		return "", nil, fmt.Errorf("ConfigMap '%s' doesn't contain a tailoring", cm.Name)
+	}
+
+	tailoring, err := xccdf.PortableTailoring(data, benchmarkHref)
+	if err != nil {
+		return "", nil, err
+	}
+	var skipped []string
+	for _, ref := range tp.Spec.CustomRules {
+		skipped = append(skipped, ref.Name)
+	}
+	return tailoring, skipped, nil
+}
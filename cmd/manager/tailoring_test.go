@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/xccdf"
+)
+
+const importedTailoring = `<?xml version="1.0" encoding="UTF-8"?>
+<xccdf:Tailoring xmlns:xccdf="http://checklists.nist.gov/xccdf/1.2" id="xccdf_scap-workbench_tailoring_default">
+  <xccdf:benchmark href="ssg-ocp4-ds.xml"/>
+  <xccdf:version time="2021-06-01T10:00:00">1</xccdf:version>
+  <xccdf:Profile id="xccdf_org.ssgproject.content_profile_cis_customized" extends="xccdf_org.ssgproject.content_profile_cis">
+    <xccdf:title override="true">CIS customized</xccdf:title>
+    <xccdf:select idref="xccdf_org.ssgproject.content_rule_audit_logging" selected="false"/>
+  </xccdf:Profile>
+</xccdf:Tailoring>
+`
+
+func newTailoringContent(kind, name, id, bundle string) metav1.Object {
+	meta := metav1.ObjectMeta{
+		Name:      name,
+		Namespace: "test-ns",
+		Labels:    map[string]string{compv1alpha1.ProfileBundleOwnerLabel: bundle},
+	}
+	switch kind {
+	case "Profile":
+		return &compv1alpha1.Profile{
+			ObjectMeta: meta,
+			ProfilePayload: compv1alpha1.ProfilePayload{
+				ID: id,
+			},
+		}
+	default:
+		return &compv1alpha1.Rule{
+			ObjectMeta: meta,
+			RulePayload: compv1alpha1.RulePayload{
+				ID: id,
+			},
+		}
+	}
+}
+
+var _ = Describe("Importing and exporting tailorings", func() {
+	It("imports a tailoring file against the current content of a bundle", func() {
+		pb := &compv1alpha1.ProfileBundle{
+			ObjectMeta: metav1.ObjectMeta{Name: "ocp4", Namespace: "test-ns"},
+			Status:     compv1alpha1.ProfileBundleStatus{DataStreamStatus: compv1alpha1.DataStreamValid},
+		}
+		profile := newTailoringContent("Profile", "ocp4-cis", "xccdf_org.ssgproject.content_profile_cis", "ocp4").(*compv1alpha1.Profile)
+		profile.Annotations = map[string]string{compv1alpha1.ProductTypeAnnotation: "Platform"}
+		rule := newTailoringContent("Rule", "ocp4-audit-logging", "xccdf_org.ssgproject.content_rule_audit_logging", "ocp4").(*compv1alpha1.Rule)
+		otherRule := newTailoringContent("Rule", "rhcos4-audit-logging", "xccdf_org.ssgproject.content_rule_audit_logging", "rhcos4").(*compv1alpha1.Rule)
+		client := fake.NewFakeClientWithScheme(getScheme(), pb, profile, rule, otherRule)
+
+		tailoring, err := xccdf.ParseTailoring(strings.NewReader(importedTailoring))
+		Expect(err).To(BeNil())
+		tp, err := importTailoredProfile(client, tailoring, "", "ocp4", "test-ns", "")
+		Expect(err).To(BeNil())
+		Expect(tp.Name).To(Equal("cis-customized"))
+		Expect(tp.Namespace).To(Equal("test-ns"))
+		Expect(tp.Annotations).To(HaveKeyWithValue(compv1alpha1.ProductTypeAnnotation, "Platform"))
+		Expect(tp.Spec.Extends).To(Equal("ocp4-cis"))
+		Expect(tp.Spec.DisableRules).To(HaveLen(1))
+		Expect(tp.Spec.DisableRules[0].Name).To(Equal("ocp4-audit-logging"))
+
+		// The rule isn't part of the other bundle
+		_, err = importTailoredProfile(client, tailoring, "", "rhcos4", "test-ns", "")
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("exports the tailoring of a ready TailoredProfile", func() {
+		tp := &compv1alpha1.TailoredProfile{
+			ObjectMeta: metav1.ObjectMeta{Name: "cis-customized", Namespace: "test-ns"},
+			Spec: compv1alpha1.TailoredProfileSpec{
+				CustomRules: []compv1alpha1.RuleReferenceSpec{{Name: "my-rule"}},
+			},
+			Status: compv1alpha1.TailoredProfileStatus{
+				State:     compv1alpha1.TailoredProfileStateReady,
+				OutputRef: compv1alpha1.OutputRef{Name: "cis-customized-tp", Namespace: "test-ns"},
+			},
+		}
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "cis-customized-tp", Namespace: "test-ns"},
+			Data: map[string]string{
+				"tailoring.xml": strings.Replace(importedTailoring, `href="ssg-ocp4-ds.xml"`, `href="/content/ssg-ocp4-ds.xml"`, 1),
+			},
+		}
+		client := fake.NewFakeClientWithScheme(getScheme(), tp, cm)
+
+		tailoring, skipped, err := exportTailoredProfile(client, "cis-customized", "test-ns", "")
+		Expect(err).To(BeNil())
+		Expect(tailoring).To(ContainSubstring(`href="ssg-ocp4-ds.xml"`))
+		Expect(tailoring).To(ContainSubstring(`idref="xccdf_org.ssgproject.content_rule_audit_logging"`))
+		Expect(skipped).To(ConsistOf("my-rule"))
+
+		tailoring, _, err = exportTailoredProfile(client, "cis-customized", "test-ns", "/usr/share/ds.xml")
+		Expect(err).To(BeNil())
+		Expect(tailoring).To(ContainSubstring(`href="/usr/share/ds.xml"`))
+	})
+})
@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	"strconv"
 	"strings"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -40,6 +41,20 @@ const ComplianceCheckResultInconsistentSourceAnnotation = "compliance.openshift.
 const ComplianceCheckResultMostCommonAnnotation = "compliance.openshift.io/most-common-status"
 const ComplianceCheckResultErrorAnnotation = "compliance.openshift.io/error-msg"
 
+// ComplianceCheckResultRegressedAnnotation marks a check that passed in the
+// previous run of its scan and doesn't in the latest one. It holds the
+// status of the previous run.
+const ComplianceCheckResultRegressedAnnotation = "compliance.openshift.io/regressed-from"
+
+// ComplianceCheckResultScanIndexAnnotation holds the index of the run of the
+// scan that last reported the check. See the currentIndex of the scan status.
+const ComplianceCheckResultScanIndexAnnotation = "compliance.openshift.io/scan-index"
+
+// ComplianceCheckResultPreviousStatusAnnotation holds the status the check
+// had in the previous run of its scan. It's not set on the checks the
+// previous run didn't report.
+const ComplianceCheckResultPreviousStatusAnnotation = "compliance.openshift.io/previous-status"
+
 const (
 	// The check ran to completion and passed
 	CheckResultPass ComplianceCheckStatus = "PASS"
@@ -106,6 +121,27 @@ func (ccr *ComplianceCheckResult) IDToDNSFriendlyName() string {
 	return strings.ToLower(dnsFriendlyFixID)
 }
 
+// ScanIndex returns the index of the run of the scan that last reported the
+// check, and whether it's known
+func (ccr *ComplianceCheckResult) ScanIndex() (int64, bool) {
+	value, ok := ccr.GetAnnotations()[ComplianceCheckResultScanIndexAnnotation]
+	if !ok {
+		return 0, false
+	}
+	index, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return index, true
+}
+
+// PreviousStatus returns the status the check had in the previous run of
+// its scan, and whether it's known
+func (ccr *ComplianceCheckResult) PreviousStatus() (ComplianceCheckStatus, bool) {
+	value, ok := ccr.GetAnnotations()[ComplianceCheckResultPreviousStatusAnnotation]
+	return ComplianceCheckStatus(value), ok
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // ComplianceCheckResultList contains a list of ComplianceCheckResult
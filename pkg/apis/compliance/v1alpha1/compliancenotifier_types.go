@@ -0,0 +1,216 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NotificationEvent is a transition that a ComplianceNotifier sends a
+// notification for
+type NotificationEvent string
+
+const (
+	// NotifySuiteNonCompliant is sent when a run of a suite finishes as
+	// NON-COMPLIANT
+	NotifySuiteNonCompliant NotificationEvent = "SuiteNonCompliant"
+	// NotifySuiteInconsistent is sent when a run of a suite finishes as
+	// INCONSISTENT
+	NotifySuiteInconsistent NotificationEvent = "SuiteInconsistent"
+	// NotifySuiteError is sent when a run of a suite finishes as ERROR
+	NotifySuiteError NotificationEvent = "SuiteError"
+	// NotifySuiteCompliant is sent when a run of a suite finishes as
+	// COMPLIANT
+	NotifySuiteCompliant NotificationEvent = "SuiteCompliant"
+	// NotifyRuleRegressed is sent for the rules of a suite that passed in
+	// the previous run of their scan and fail in the latest one
+	NotifyRuleRegressed NotificationEvent = "RuleRegressed"
+	// NotifyRemediationFailed is sent when a remediation fails to be
+	// applied
+	NotifyRemediationFailed NotificationEvent = "RemediationFailed"
+)
+
+// NotifierFormat is the format of the notifications a ComplianceNotifier
+// sends
+type NotifierFormat string
+
+const (
+	// NotifierFormatGeneric sends the notification as a JSON document
+	NotifierFormatGeneric NotifierFormat = "Generic"
+	// NotifierFormatSlack sends the notification as a message understood
+	// by Slack-compatible incoming webhooks
+	NotifierFormatSlack NotifierFormat = "Slack"
+)
+
+const (
+	// DefaultNotifierRetries is the number of times a notification is
+	// retried unless the notifier sets it
+	DefaultNotifierRetries = 3
+	// DefaultNotifierHMACHeader is the header that holds the signature of
+	// the body unless the notifier sets it
+	DefaultNotifierHMACHeader = "X-Compliance-Signature"
+)
+
+// NotifierSecretKeySelector selects a key of a Secret in the namespace of
+// the notifier
+type NotifierSecretKeySelector struct {
+	// The name of the Secret
+	Name string `json:"name"`
+	// The key that holds the value
+	Key string `json:"key"`
+}
+
+// NotifierHMAC configures the signature of the notifications
+type NotifierHMAC struct {
+	// The key the body is signed with
+	Secret NotifierSecretKeySelector `json:"secret"`
+	// The header that holds the signature, as sha256=<hex-encoded HMAC>.
+	// Defaults to X-Compliance-Signature.
+	// +optional
+	Header string `json:"header,omitempty"`
+}
+
+// NotifierTLS configures the TLS connection to the webhook
+type NotifierTLS struct {
+	// The name of a kubernetes.io/tls Secret holding the client
+	// certificate that's presented to the webhook
+	// +optional
+	ClientCertSecret string `json:"clientCertSecret,omitempty"`
+	// The name of a ConfigMap with a `ca-bundle.crt` key holding the
+	// certificates of the CAs that the webhook is verified with. The
+	// system CAs are used if it's not set.
+	// +optional
+	CAConfigMap string `json:"caConfigMap,omitempty"`
+}
+
+// ComplianceNotifierSpec defines where and when notifications on the
+// results of the suites and the remediations of a namespace are sent
+type ComplianceNotifierSpec struct {
+	// The transitions that send a notification
+	// +kubebuilder:validation:MinItems=1
+	Events []NotificationEvent `json:"events"`
+	// Restricts the notifications to the suites, and their remediations,
+	// with matching labels. All the suites of the namespace by default.
+	// +optional
+	SuiteSelector *metav1.LabelSelector `json:"suiteSelector,omitempty"`
+	// The format of the notifications. Generic sends a JSON document,
+	// rendered from the bodyTemplate if set; Slack sends a message for
+	// Slack-compatible incoming webhooks.
+	// +kubebuilder:validation:Enum=Generic;Slack
+	// +kubebuilder:default=Generic
+	// +optional
+	Format NotifierFormat `json:"format,omitempty"`
+	// The URL of the webhook
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +optional
+	URL string `json:"url,omitempty"`
+	// A key of a Secret holding the URL of the webhook, for webhooks whose
+	// URL embeds a token. Takes precedence over the url.
+	// +optional
+	URLSecret *NotifierSecretKeySelector `json:"urlSecret,omitempty"`
+	// A Go template rendering the JSON body of Generic notifications from
+	// the notification
+	// +optional
+	BodyTemplate string `json:"bodyTemplate,omitempty"`
+	// Signs the body of the notifications with HMAC-SHA256
+	// +optional
+	HMAC *NotifierHMAC `json:"hmac,omitempty"`
+	// Configures the TLS connection to the webhook, e.g. for mutual TLS
+	// +optional
+	TLS *NotifierTLS `json:"tls,omitempty"`
+	// How many times a notification that couldn't be delivered is retried.
+	// Defaults to 3. A notification isn't retried for more than a minute.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
+	// +optional
+	Retries *int32 `json:"retries,omitempty"`
+}
+
+// NotificationDelivery reports the delivery of a notification
+type NotificationDelivery struct {
+	// The transition the notification was sent for
+	Event NotificationEvent `json:"event"`
+	// The object the notification is about, as <kind>/<name>
+	Object string `json:"object"`
+	// When the delivery finished
+	Time metav1.Time `json:"time"`
+	// How many times the notification was sent
+	Attempts int32 `json:"attempts"`
+	// The HTTP status code the webhook last answered with, if any
+	// +optional
+	StatusCode int32 `json:"statusCode,omitempty"`
+	// Why the notification couldn't be delivered
+	// +optional
+	ErrorMessage string `json:"errorMessage,omitempty"`
+}
+
+// ComplianceNotifierStatus reports the deliveries of a notifier
+type ComplianceNotifierStatus struct {
+	// The number of notifications delivered
+	// +optional
+	Delivered int64 `json:"delivered,omitempty"`
+	// The number of notifications that couldn't be delivered, even after
+	// retrying
+	// +optional
+	Failed int64 `json:"failed,omitempty"`
+	// The last delivery of a notification, successful or not
+	// +optional
+	LastDelivery *NotificationDelivery `json:"lastDelivery,omitempty"`
+	// The last delivery of a notification that failed
+	// +optional
+	LastFailure *NotificationDelivery `json:"lastFailure,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ComplianceNotifier sends notifications to a webhook when the suites or the
+// remediations of its namespace go through certain transitions
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=compliancenotifiers,scope=Namespaced,shortName=notifier
+// +kubebuilder:printcolumn:name="Format",type="string",JSONPath=`.spec.format`
+// +kubebuilder:printcolumn:name="Delivered",type="integer",JSONPath=`.status.delivered`
+// +kubebuilder:printcolumn:name="Failed",type="integer",JSONPath=`.status.failed`
+type ComplianceNotifier struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ComplianceNotifierSpec   `json:"spec,omitempty"`
+	Status ComplianceNotifierStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ComplianceNotifierList contains a list of ComplianceNotifier
+type ComplianceNotifierList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ComplianceNotifier `json:"items"`
+}
+
+// GetFormat returns the format of the notifications, Generic by default
+func (n *ComplianceNotifier) GetFormat() NotifierFormat {
+	if n.Spec.Format == "" {
+		return NotifierFormatGeneric
+	}
+	return n.Spec.Format
+}
+
+// GetRetries returns how many times a notification is retried
+func (n *ComplianceNotifier) GetRetries() int {
+	if n.Spec.Retries == nil {
+		return DefaultNotifierRetries
+	}
+	return int(*n.Spec.Retries)
+}
+
+// WantsEvent returns whether the notifier sends notifications for an event
+func (n *ComplianceNotifier) WantsEvent(event NotificationEvent) bool {
+	for _, e := range n.Spec.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	SchemeBuilder.Register(&ComplianceNotifier{}, &ComplianceNotifierList{})
+}
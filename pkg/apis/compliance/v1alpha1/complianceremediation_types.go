@@ -21,6 +21,13 @@ const (
 	RemediationError               RemediationApplicationState = "Error"
 	RemediationMissingDependencies RemediationApplicationState = "MissingDependencies"
 	RemediationNeedsReview         RemediationApplicationState = "NeedsReview"
+	// RemediationDrifted means that the object the remediation applied was
+	// modified or deleted afterwards and no longer matches the remediation
+	RemediationDrifted RemediationApplicationState = "Drifted"
+	// RemediationManagedExternally means that the remediation was exported
+	// to be applied by an external tool, such as a GitOps controller, and
+	// the operator doesn't apply it itself
+	RemediationManagedExternally RemediationApplicationState = "ManagedExternally"
 )
 
 // +kubebuilder:validation:Enum=Configuration;Enforcement
@@ -88,6 +95,16 @@ const (
 	// K8SVersionDependencyAnnotation specifies that the k8s cluster needs to fall
 	// into a range in order to be applied
 	K8SVersionDependencyAnnotation = "compliance.openshift.io/k8s-version"
+	// MergedRemediationsAnnotation lists the remediations whose MachineConfig
+	// objects were merged into a MachineConfig
+	MergedRemediationsAnnotation = "compliance.openshift.io/merged-remediations"
+	// RemediationManagedExternallyAnnotation specifies that a remediation was
+	// exported and is applied by an external tool instead of the operator
+	RemediationManagedExternallyAnnotation = "compliance.openshift.io/managed-externally"
+	// RemediationPendingVerificationAnnotation specifies that a remediation
+	// was applied by its suite and that the suite still has to rescan its
+	// scan to verify that the remediated check passes
+	RemediationPendingVerificationAnnotation = "compliance.openshift.io/pending-verification"
 )
 
 var (
@@ -141,6 +158,10 @@ type ComplianceRemediationStatus struct {
 	// +kubebuilder:default="NotApplied"
 	ApplicationState RemediationApplicationState `json:"applicationState,omitempty"`
 	ErrorMessage     string                      `json:"errorMessage,omitempty"`
+	// The generation of the remediation that was last applied. This is used
+	// to tell changes to the remediation apart from drift of the object the
+	// remediation applied.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -183,6 +204,16 @@ func (r *ComplianceRemediation) GetMcName() string {
 	return mcName
 }
 
+// GetMergedMcName returns the name of the MachineConfig that all the
+// MachineConfig remediations of the scan are merged into
+func (r *ComplianceRemediation) GetMergedMcName() string {
+	if r.GetScan() == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("75-%s-merged", r.GetScan())
+}
+
 // AddOwnershipLabels labels an object to say it was created
 // by this operator and is owned by a specific scan and suite
 func (r *ComplianceRemediation) AddOwnershipLabels(obj metav1.Object) {
@@ -207,8 +238,15 @@ func (r *ComplianceRemediation) IsApplied() bool {
 	applied := r.Status.ApplicationState == RemediationApplied
 	outDatedButApplied := r.Spec.Apply && r.Status.ApplicationState == RemediationOutdated
 	appliedButUnmet := r.Spec.Apply && r.Status.ApplicationState == RemediationMissingDependencies
+	appliedButDrifted := r.Spec.Apply && r.Status.ApplicationState == RemediationDrifted
+
+	return applied || outDatedButApplied || appliedButUnmet || appliedButDrifted
+}
 
-	return applied || outDatedButApplied || appliedButUnmet
+// IsManagedExternally tells whether the ComplianceRemediation is applied
+// by an external tool instead of the operator
+func (r *ComplianceRemediation) IsManagedExternally() bool {
+	return r.HasAnnotation(RemediationManagedExternallyAnnotation)
 }
 
 func (r *ComplianceRemediation) HasUnmetDependencies() bool {
@@ -2,6 +2,7 @@ package v1alpha1
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
@@ -193,6 +194,81 @@ type ComplianceScanSettings struct {
 	// Determines whether to hide or show results that are not applicable.
 	// +kubebuilder:default=false
 	ShowNotApplicable bool `json:"showNotApplicable,omitempty"`
+
+	// Specifies which ComplianceCheckResults of the scan are kept in the
+	// cluster once the scan is done. By default, all of them are kept.
+	// +optional
+	// +nullable
+	CheckResultRetention *CheckResultRetentionSettings `json:"checkResultRetention,omitempty"`
+}
+
+// CheckResultRetentionSettings defines which ComplianceCheckResults of a scan
+// are kept in the cluster. The ones that aren't are deleted once the scan is
+// done, and are only available in the raw results. The check results that
+// own remediations are always kept.
+type CheckResultRetentionSettings struct {
+	// The statuses of the check results to keep, e.g. FAIL and MANUAL. If
+	// empty, the check results of all statuses are kept.
+	// +listType=atomic
+	// +optional
+	Statuses []ComplianceCheckStatus `json:"statuses,omitempty"`
+	// The number of runs of the scan a check result is kept for. A check
+	// result that none of the last maxRuns runs reported, for instance
+	// because its rule was removed from the profile, is deleted. Defaults
+	// to 0, which keeps the check results regardless of the run that
+	// reported them.
+	// +optional
+	MaxRuns uint16 `json:"maxRuns,omitempty"`
+}
+
+// Validate returns an error if the check result retention settings are
+// not valid
+func (r *CheckResultRetentionSettings) Validate() error {
+	for _, status := range r.Statuses {
+		switch status {
+		case CheckResultPass, CheckResultFail, CheckResultInfo, CheckResultManual,
+			CheckResultError, CheckResultNotApplicable, CheckResultInconsistent:
+		default:
+			return fmt.Errorf("check result retention status '%s' is not valid", status)
+		}
+	}
+	return nil
+}
+
+// Retains returns whether a check result of a scan is kept in the cluster,
+// given the index of the current run of the scan
+func (r *CheckResultRetentionSettings) Retains(check *ComplianceCheckResult, currentIndex int64) bool {
+	if len(r.Statuses) > 0 && !containsCheckStatus(r.Statuses, check.Status) {
+		return false
+	}
+	if r.MaxRuns == 0 {
+		return true
+	}
+	index, ok := check.ScanIndex()
+	if !ok {
+		// Reported before the runs were recorded, assume it's current
+		return true
+	}
+	// The index wraps around, a higher index is an older run
+	return index <= currentIndex && currentIndex-index < int64(r.MaxRuns)
+}
+
+func containsCheckStatus(statuses []ComplianceCheckStatus, status ComplianceCheckStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// GetUnretainedCheckResultsName returns the name of the ConfigMap that keeps
+// the statuses of the check results of the latest run of a scan that aren't
+// retained, so that the next run finds whether they changed. Each key of
+// the ConfigMap is a status, and its value the names of the check results
+// that have it, one per line.
+func GetUnretainedCheckResultsName(scanName string) string {
+	return scanName + "-unretained-checks"
 }
 
 // ComplianceScanSpec defines the desired state of ComplianceScan
@@ -215,6 +291,17 @@ type ComplianceScanSpec struct {
 	// Note that the path needs to be relative to the `/` (root) directory, as
 	// it is in the ContentImage
 	Content string `json:"content,omitempty"`
+	// Loads the content from somewhere other than the ContentImage, as
+	// configured in the ProfileBundle. The content is stored under the
+	// Content path.
+	// +optional
+	// +nullable
+	ContentSource *ContentSource `json:"contentSource,omitempty"`
+	// The SHA-256 digest the content must have, as verified by the
+	// ProfileBundle. The scan fails instead of running against any other
+	// content.
+	// +optional
+	ContentDigest string `json:"contentDigest,omitempty"`
 	// By setting this, it's possible to only run the scan on certain nodes in
 	// the cluster. Note that when applying remediations generated from the
 	// scan, this should match the selector of the MachineConfigPool you want
@@ -250,6 +337,117 @@ type ComplianceScanStatus struct {
 	// If there are warnings on the scan, this will be filled up with warning
 	// messages.
 	Warnings string `json:"warnings,omitempty"`
+	// The time the current run of the scan started, that is when it last
+	// entered the phase PENDING.
+	// +optional
+	StartTimestamp *metav1.Time `json:"startTimestamp,omitempty"`
+	// The time the current run of the scan reached the phase DONE.
+	// +optional
+	EndTimestamp *metav1.Time `json:"endTimestamp,omitempty"`
+	// The time a run of the scan last reached the phase DONE with a result
+	// other than ERROR.
+	// +optional
+	LastCompletedTime *metav1.Time `json:"lastCompletedTime,omitempty"`
+	// The phases the current run of the scan went through and when it
+	// entered and left them, in order.
+	// +listType=atomic
+	// +optional
+	PhaseTimings []ComplianceScanPhaseTiming `json:"phaseTimings,omitempty"`
+	// The number of check results the latest run of the scan reported, by
+	// status and severity. These include the check results that aren't
+	// kept as per the check result retention settings.
+	// +listType=atomic
+	// +optional
+	CheckResultCounts []CheckResultCount `json:"checkResultCounts,omitempty"`
+	// How far the aggregation of the results of the current run of the scan
+	// got. Set by the aggregator while the scan is in the phase AGGREGATING.
+	// +optional
+	// +nullable
+	AggregationProgress *ComplianceScanAggregationProgress `json:"aggregationProgress,omitempty"`
+}
+
+// CheckResultCount is the number of check results of a run of a scan that
+// have a given status and severity
+type CheckResultCount struct {
+	Status   ComplianceCheckStatus         `json:"status"`
+	Severity ComplianceCheckResultSeverity `json:"severity"`
+	Count    int                           `json:"count"`
+}
+
+// ComplianceScanAggregationProgress reports how far the aggregator got in
+// turning the results of a run of a scan into check results
+type ComplianceScanAggregationProgress struct {
+	// The number of results parsed, one per node for node scans
+	ParsedResults int `json:"parsedResults"`
+	// The number of results to parse. Unset when it isn't known.
+	// +optional
+	TotalResults int `json:"totalResults,omitempty"`
+	// The number of check results processed, that is created, updated or
+	// skipped
+	ProcessedCheckResults int `json:"processedCheckResults"`
+	// The number of check results to process. Unset until all the results
+	// are parsed.
+	// +optional
+	TotalCheckResults int `json:"totalCheckResults,omitempty"`
+	// When the progress was last updated
+	LastUpdateTime metav1.Time `json:"lastUpdateTime"`
+}
+
+// MaxPhaseTimings is the maximum number of phase timings kept in the status
+// of a scan. The oldest are dropped when a scan goes back and forth between
+// phases more than that.
+const MaxPhaseTimings = 16
+
+// ComplianceScanPhaseTiming records when a run of a scan entered and left a
+// phase
+type ComplianceScanPhaseTiming struct {
+	// The phase the scan was in
+	Phase ComplianceScanStatusPhase `json:"phase"`
+	// When the scan entered the phase
+	StartTime metav1.Time `json:"startTime"`
+	// When the scan left the phase. Unset while the scan is in the phase.
+	// +optional
+	EndTime *metav1.Time `json:"endTime,omitempty"`
+}
+
+// SetPhase moves the scan to a phase and records when the scan entered it
+// and left the previous one. Moving to PENDING starts a new run; reaching
+// DONE ends it, so the result must be set before.
+func (s *ComplianceScanStatus) SetPhase(phase ComplianceScanStatusPhase) {
+	s.setPhaseAt(phase, metav1.Now())
+}
+
+func (s *ComplianceScanStatus) setPhaseAt(phase ComplianceScanStatusPhase, now metav1.Time) {
+	if s.Phase == phase {
+		return
+	}
+	s.Phase = phase
+
+	if phase == PhasePending {
+		s.StartTimestamp = now.DeepCopy()
+		s.EndTimestamp = nil
+		s.PhaseTimings = nil
+		s.AggregationProgress = nil
+	}
+	if n := len(s.PhaseTimings); n > 0 && s.PhaseTimings[n-1].EndTime == nil {
+		s.PhaseTimings[n-1].EndTime = now.DeepCopy()
+	}
+
+	if phase == PhaseDone {
+		s.EndTimestamp = now.DeepCopy()
+		if s.Result != ResultError && s.Result != ResultNotAvailable && s.Result != "" {
+			s.LastCompletedTime = now.DeepCopy()
+		}
+		return
+	}
+
+	if len(s.PhaseTimings) >= MaxPhaseTimings {
+		s.PhaseTimings = s.PhaseTimings[len(s.PhaseTimings)-MaxPhaseTimings+1:]
+	}
+	s.PhaseTimings = append(s.PhaseTimings, ComplianceScanPhaseTiming{
+		Phase:     phase,
+		StartTime: now,
+	})
 }
 
 // StorageReference stores a reference to where certain objects are being stored
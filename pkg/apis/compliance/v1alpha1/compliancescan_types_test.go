@@ -0,0 +1,120 @@
+package v1alpha1
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Testing the phase timings of scans", func() {
+	var status *ComplianceScanStatus
+	var start time.Time
+
+	at := func(offset time.Duration) metav1.Time {
+		return metav1.NewTime(start.Add(offset))
+	}
+
+	BeforeEach(func() {
+		status = &ComplianceScanStatus{}
+		start = time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+		status.setPhaseAt(PhasePending, at(0))
+		status.setPhaseAt(PhaseLaunching, at(time.Second))
+		status.setPhaseAt(PhaseRunning, at(10*time.Second))
+		status.setPhaseAt(PhaseAggregating, at(time.Minute))
+	})
+
+	It("records when the run entered and left each phase", func() {
+		Expect(status.StartTimestamp).To(Equal(&metav1.Time{Time: start}))
+		Expect(status.EndTimestamp).To(BeNil())
+		Expect(status.PhaseTimings).To(HaveLen(4))
+		Expect(status.PhaseTimings[2].Phase).To(Equal(PhaseRunning))
+		Expect(status.PhaseTimings[2].StartTime).To(Equal(at(10 * time.Second)))
+		Expect(*status.PhaseTimings[2].EndTime).To(Equal(at(time.Minute)))
+		Expect(status.PhaseTimings[3].EndTime).To(BeNil())
+	})
+
+	It("doesn't record staying in a phase", func() {
+		status.setPhaseAt(PhaseAggregating, at(2*time.Minute))
+		Expect(status.PhaseTimings).To(HaveLen(4))
+		Expect(status.PhaseTimings[3].StartTime).To(Equal(at(time.Minute)))
+	})
+
+	It("records the completion of a successful run", func() {
+		status.Result = ResultNonCompliant
+		status.setPhaseAt(PhaseDone, at(2*time.Minute))
+		Expect(*status.EndTimestamp).To(Equal(at(2 * time.Minute)))
+		Expect(*status.LastCompletedTime).To(Equal(at(2 * time.Minute)))
+		Expect(status.PhaseTimings).To(HaveLen(4))
+		Expect(*status.PhaseTimings[3].EndTime).To(Equal(at(2 * time.Minute)))
+	})
+
+	It("keeps the last completion when a run fails", func() {
+		status.Result = ResultCompliant
+		status.AggregationProgress = &ComplianceScanAggregationProgress{ParsedResults: 3, TotalResults: 3}
+		status.setPhaseAt(PhaseDone, at(2*time.Minute))
+		Expect(status.AggregationProgress).NotTo(BeNil())
+
+		status.Result = ResultNotAvailable
+		status.setPhaseAt(PhasePending, at(time.Hour))
+		Expect(*status.StartTimestamp).To(Equal(at(time.Hour)))
+		Expect(status.EndTimestamp).To(BeNil())
+		Expect(status.PhaseTimings).To(HaveLen(1))
+		Expect(status.AggregationProgress).To(BeNil())
+
+		status.Result = ResultError
+		status.setPhaseAt(PhaseDone, at(time.Hour+time.Minute))
+		Expect(*status.EndTimestamp).To(Equal(at(time.Hour + time.Minute)))
+		Expect(*status.LastCompletedTime).To(Equal(at(2 * time.Minute)))
+	})
+
+	It("keeps a bounded number of timings", func() {
+		for i := 0; i < MaxPhaseTimings; i++ {
+			status.setPhaseAt(PhaseLaunching, at(time.Duration(2*i)*time.Hour))
+			status.setPhaseAt(PhaseRunning, at(time.Duration(2*i+1)*time.Hour))
+		}
+		Expect(status.PhaseTimings).To(HaveLen(MaxPhaseTimings))
+		Expect(status.PhaseTimings[MaxPhaseTimings-1].Phase).To(Equal(PhaseRunning))
+		Expect(status.PhaseTimings[MaxPhaseTimings-1].EndTime).To(BeNil())
+	})
+})
+
+var _ = Describe("Testing the check result retention of scans", func() {
+	check := func(status ComplianceCheckStatus, index string) *ComplianceCheckResult {
+		c := &ComplianceCheckResult{Status: status}
+		if index != "" {
+			c.SetAnnotations(map[string]string{ComplianceCheckResultScanIndexAnnotation: index})
+		}
+		return c
+	}
+
+	It("keeps the check results of the retained statuses", func() {
+		retention := &CheckResultRetentionSettings{
+			Statuses: []ComplianceCheckStatus{CheckResultFail, CheckResultManual},
+		}
+		Expect(retention.Retains(check(CheckResultFail, "3"), 3)).To(BeTrue())
+		Expect(retention.Retains(check(CheckResultManual, "0"), 3)).To(BeTrue())
+		Expect(retention.Retains(check(CheckResultPass, "3"), 3)).To(BeFalse())
+	})
+
+	It("keeps the check results of the last runs", func() {
+		retention := &CheckResultRetentionSettings{MaxRuns: 2}
+		Expect(retention.Retains(check(CheckResultPass, "3"), 3)).To(BeTrue())
+		Expect(retention.Retains(check(CheckResultPass, "2"), 3)).To(BeTrue())
+		Expect(retention.Retains(check(CheckResultPass, "1"), 3)).To(BeFalse())
+		// Reported before the index wrapped around
+		Expect(retention.Retains(check(CheckResultPass, "9223372036854775807"), 0)).To(BeFalse())
+		// Reported before the runs were recorded
+		Expect(retention.Retains(check(CheckResultPass, ""), 3)).To(BeTrue())
+	})
+
+	It("validates the statuses", func() {
+		retention := &CheckResultRetentionSettings{
+			Statuses: []ComplianceCheckStatus{CheckResultFail, "FAILED"},
+		}
+		Expect(retention.Validate()).NotTo(BeNil())
+		retention.Statuses = []ComplianceCheckStatus{CheckResultInconsistent}
+		Expect(retention.Validate()).To(BeNil())
+	})
+})
@@ -1,11 +1,16 @@
 package v1alpha1
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
 	conditions "github.com/operator-framework/operator-sdk/pkg/status"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 // SuiteLabel indicates that an object (normally the ComplianceScan
@@ -80,10 +85,200 @@ type ComplianceSuiteSettings struct {
 	// Defines whether or not the remediations should be updated automatically.
 	// This is done by deleting the "outdated" object from the remediation.
 	AutoUpdateRemediations bool `json:"autoUpdateRemediations,omitempty"`
+	// Defines whether or not applied remediations whose objects were
+	// modified or deleted afterwards should be re-applied automatically.
+	// If not set, such remediations are only reported as Drifted.
+	ReapplyDriftedRemediations bool `json:"reapplyDriftedRemediations,omitempty"`
+	// Defines whether or not the MachineConfig remediations of a scan should
+	// be merged into a single MachineConfig instead of creating one
+	// MachineConfig per remediation.
+	MergeMachineConfigRemediations bool `json:"mergeMachineConfigRemediations,omitempty"`
 	// Defines a schedule for the scans to run. This is in cronjob format.
 	// Note the scan will still be triggered immediately, and the scheduled
 	// scans will start running only after the initial results are ready.
+	// A scheduled run is skipped if the previous one is still in progress.
 	Schedule string `json:"schedule,omitempty"`
+	// Suspends the scheduled runs of the scans. The runs that already
+	// started aren't affected.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+	// Delays the start of each scheduled run by a random duration up to
+	// this one, e.g. 30m, so that the suites sharing a schedule don't all
+	// start at the same time. It must be shorter than a day.
+	// +optional
+	ScheduleJitter string `json:"scheduleJitter,omitempty"`
+	// How many seconds after its scheduled time a run can still start, e.g.
+	// when the cluster was down at that time. The runs that missed their
+	// deadline are skipped. If not set, a missed run starts as soon as
+	// possible.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
+	// The time zone the schedule is interpreted in, as a name of the IANA
+	// time zone database, e.g. Europe/Paris. Defaults to the time zone of
+	// the kube-controller-manager. Requires a cluster that supports the
+	// timeZone attribute of CronJobs.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+	// Rescans the scans of the suite when the resources these triggers
+	// watch change
+	// +listType=atomic
+	// +optional
+	RescanTriggers []RescanTrigger `json:"rescanTriggers,omitempty"`
+	// How long the resources watched by the rescan triggers must stay
+	// unchanged before the scans are rescanned, e.g. 10m, so that a rollout
+	// changing several of them only triggers one rescan. Defaults to 5m.
+	// +optional
+	RescanTriggerDelay string `json:"rescanTriggerDelay,omitempty"`
+}
+
+// RescanTrigger selects resources whose changes rescan the scans of a suite.
+// The operator is only allowed to watch the config.openshift.io resources
+// and the MachineConfigs out of the box; the triggers on other kinds fail
+// with a RescanTriggerError event unless its role is extended.
+type RescanTrigger struct {
+	// The API version of the watched resources, e.g. config.openshift.io/v1
+	APIVersion string `json:"apiVersion"`
+	// The kind of the watched resources, e.g. APIServer
+	Kind string `json:"kind"`
+	// Restricts the trigger to the resources with this name
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Restricts the trigger to the resources of this namespace, for
+	// namespaced kinds
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Restricts the trigger to the resources with matching labels
+	// +optional
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+	// The type of the scans that are rescanned, Platform or Node. All the
+	// scans of the suite are rescanned if it isn't set.
+	// +kubebuilder:validation:Enum=Platform;Node
+	// +optional
+	ScanType ComplianceScanType `json:"scanType,omitempty"`
+}
+
+// RescanTriggerStatus reports the last rescan of a suite triggered by a
+// change of the resources its triggers watch
+type RescanTriggerStatus struct {
+	// When the scans were rescanned
+	Time metav1.Time `json:"time"`
+	// The changes that triggered the rescan
+	Reason string `json:"reason"`
+	// The scans that were rescanned
+	// +listType=atomic
+	// +optional
+	Scans []string `json:"scans,omitempty"`
+}
+
+// DefaultRescanTriggerDelay is how long the resources watched by the rescan
+// triggers must stay unchanged before a rescan unless set otherwise
+const DefaultRescanTriggerDelay = 5 * time.Minute
+
+// MaxScheduleJitter is the longest delay the scheduled runs can be delayed
+// by
+const MaxScheduleJitter = 24 * time.Hour
+
+// GetScheduleJitter returns the longest delay the scheduled runs are delayed
+// by, 0 if they aren't
+func (s *ComplianceSuiteSettings) GetScheduleJitter() (time.Duration, error) {
+	if s.ScheduleJitter == "" {
+		return 0, nil
+	}
+	jitter, err := time.ParseDuration(s.ScheduleJitter)
+	if err != nil {
+		return 0, fmt.Errorf("scheduleJitter '%s' is not a valid duration: %w", s.ScheduleJitter, err)
+	}
+	if jitter < 0 || jitter >= MaxScheduleJitter {
+		return 0, fmt.Errorf("scheduleJitter '%s' must be positive and shorter than %s", s.ScheduleJitter, MaxScheduleJitter)
+	}
+	return jitter, nil
+}
+
+// GetRescanTriggerDelay returns how long the resources watched by the rescan
+// triggers must stay unchanged before a rescan
+func (s *ComplianceSuiteSettings) GetRescanTriggerDelay() (time.Duration, error) {
+	if s.RescanTriggerDelay == "" {
+		return DefaultRescanTriggerDelay, nil
+	}
+	delay, err := time.ParseDuration(s.RescanTriggerDelay)
+	if err != nil {
+		return 0, fmt.Errorf("rescanTriggerDelay '%s' is not a valid duration: %w", s.RescanTriggerDelay, err)
+	}
+	if delay < 0 {
+		return 0, fmt.Errorf("rescanTriggerDelay '%s' must not be negative", s.RescanTriggerDelay)
+	}
+	return delay, nil
+}
+
+// ValidateRescanTriggers validates the rescan triggers and their delay
+func (s *ComplianceSuiteSettings) ValidateRescanTriggers() error {
+	if _, err := s.GetRescanTriggerDelay(); err != nil {
+		return err
+	}
+	for i := range s.RescanTriggers {
+		trigger := &s.RescanTriggers[i]
+		if _, err := schema.ParseGroupVersion(trigger.APIVersion); err != nil || trigger.APIVersion == "" {
+			return fmt.Errorf("rescan trigger %d: apiVersion '%s' is invalid", i, trigger.APIVersion)
+		}
+		if trigger.Kind == "" {
+			return fmt.Errorf("rescan trigger %d has no kind", i)
+		}
+		if _, err := metav1.LabelSelectorAsSelector(trigger.LabelSelector); err != nil {
+			return fmt.Errorf("rescan trigger %d: %w", i, err)
+		}
+		if trigger.ScanType != "" && trigger.ScanType != ScanTypePlatform && trigger.ScanType != ScanTypeNode {
+			return fmt.Errorf("rescan trigger %d: scanType must be %s or %s", i, ScanTypePlatform, ScanTypeNode)
+		}
+	}
+	return nil
+}
+
+// GroupVersionKind returns the kind of the resources the trigger watches
+func (t *RescanTrigger) GroupVersionKind() schema.GroupVersionKind {
+	gv, _ := schema.ParseGroupVersion(t.APIVersion)
+	return gv.WithKind(t.Kind)
+}
+
+// Matches returns whether the trigger watches a resource of its kind
+func (t *RescanTrigger) Matches(obj metav1.Object) bool {
+	if t.Name != "" && obj.GetName() != t.Name {
+		return false
+	}
+	if t.Namespace != "" && obj.GetNamespace() != t.Namespace {
+		return false
+	}
+	if t.LabelSelector == nil {
+		return true
+	}
+	selector, err := metav1.LabelSelectorAsSelector(t.LabelSelector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+// ValidateScheduleOptions validates the options of the scheduled runs, other
+// than the schedule itself
+func (s *ComplianceSuiteSettings) ValidateScheduleOptions() error {
+	if _, err := s.GetScheduleJitter(); err != nil {
+		return err
+	}
+	if s.StartingDeadlineSeconds != nil && *s.StartingDeadlineSeconds < 0 {
+		return fmt.Errorf("startingDeadlineSeconds must not be negative")
+	}
+	if s.TimeZone == "" {
+		return nil
+	}
+	// As in Kubernetes, Local would depend on the node the CronJob
+	// controller runs on
+	if strings.EqualFold(s.TimeZone, "Local") {
+		return fmt.Errorf("timeZone must be the name of a time zone, not Local")
+	}
+	if _, err := time.LoadLocation(s.TimeZone); err != nil {
+		return fmt.Errorf("timeZone '%s' is not a known time zone: %w", s.TimeZone, err)
+	}
+	return nil
 }
 
 // ComplianceSuiteSpec defines the desired state of ComplianceSuite
@@ -105,6 +300,117 @@ type ComplianceSuiteStatus struct {
 	ErrorMessage string                        `json:"errorMessage,omitempty"`
 	// +optional
 	Conditions conditions.Conditions `json:"conditions,omitempty"`
+	// Reports the dependencies between the remediations of the suite
+	// that can't be met
+	// +optional
+	RemediationDependencies *RemediationDependencyStatus `json:"remediationDependencies,omitempty"`
+	// A digest of the remediations and check results the remediation
+	// dependencies were resolved for. The dependencies are only resolved
+	// again once these change.
+	// +optional
+	RemediationDependenciesVersion string `json:"remediationDependenciesVersion,omitempty"`
+	// The time the scans of the suite last all reached the phase DONE
+	// with a result other than ERROR
+	// +optional
+	LastCompletedTime *metav1.Time `json:"lastCompletedTime,omitempty"`
+	// The last rescan of the suite triggered by its rescan triggers
+	// +optional
+	LastRescanTrigger *RescanTriggerStatus `json:"lastRescanTrigger,omitempty"`
+	// Reports the verification of the last remediations the suite applied
+	// +optional
+	RemediationVerification *RemediationVerificationStatus `json:"remediationVerification,omitempty"`
+}
+
+type RemediationVerificationPhase string
+
+const (
+	// RemediationVerificationWaiting means that the suite waits for the
+	// remediations to be applied and for the MachineConfigPools they
+	// affect to be updated
+	RemediationVerificationWaiting RemediationVerificationPhase = "Waiting"
+	// RemediationVerificationRescanning means that the scans of the
+	// remediations are being rescanned
+	RemediationVerificationRescanning RemediationVerificationPhase = "Rescanning"
+	// RemediationVerificationDone means that the results of the rescan
+	// are available
+	RemediationVerificationDone RemediationVerificationPhase = "Done"
+)
+
+// RemediationVerificationStatus reports the rescan that verifies the
+// remediations a suite applied
+// +k8s:openapi-gen=true
+type RemediationVerificationStatus struct {
+	Phase RemediationVerificationPhase `json:"phase"`
+	// The remediations being verified
+	// +listType=atomic
+	// +optional
+	Remediations []string `json:"remediations,omitempty"`
+	// The MachineConfigPools that haven't finished updating yet
+	// +listType=atomic
+	// +optional
+	Pools []string `json:"pools,omitempty"`
+	// When the verification rescan started
+	// +optional
+	RescanTime *metav1.Time `json:"rescanTime,omitempty"`
+	// How many of the remediated checks pass after the rescan
+	// +optional
+	Passed int `json:"passed,omitempty"`
+	// How many of the remediated checks still don't pass after the rescan
+	// +optional
+	Failed int `json:"failed,omitempty"`
+	// The results of the remediated checks after the rescan
+	// +listType=atomic
+	// +optional
+	Results []RemediationVerificationResult `json:"results,omitempty"`
+}
+
+// RemediationVerificationResult is the result of a remediated check after
+// the verification rescan
+// +k8s:openapi-gen=true
+type RemediationVerificationResult struct {
+	// The name of the remediation
+	Remediation string `json:"remediation"`
+	// The name of the ComplianceCheckResult the remediation was created for
+	CheckResult string `json:"checkResult"`
+	// The status of the check after the rescan
+	Status ComplianceCheckStatus `json:"status"`
+}
+
+// RemediationDependencyStatus summarizes the dependencies between the
+// remediations of a suite that can't be met
+// +k8s:openapi-gen=true
+type RemediationDependencyStatus struct {
+	// Groups of remediations that depend on each other and thus can't be
+	// applied
+	// +listType=atomic
+	// +optional
+	Cycles []RemediationDependencyCycle `json:"cycles,omitempty"`
+	// Dependencies of remediations that can't be met by applying the
+	// remediations of the suite
+	// +listType=atomic
+	// +optional
+	UnsatisfiableDependencies []UnsatisfiableRemediationDependency `json:"unsatisfiableDependencies,omitempty"`
+}
+
+// RemediationDependencyCycle is a group of remediations that depend on each
+// other
+// +k8s:openapi-gen=true
+type RemediationDependencyCycle struct {
+	// +listType=atomic
+	Remediations []string `json:"remediations"`
+}
+
+// UnsatisfiableRemediationDependency is a dependency of a remediation that
+// can't be met
+// +k8s:openapi-gen=true
+type UnsatisfiableRemediationDependency struct {
+	// The name of the remediation that has the dependency
+	Remediation string `json:"remediation"`
+	// The XCCDF ID of the check, or the reference of the kube object, the
+	// remediation depends on
+	Dependency string `json:"dependency"`
+	// Why the dependency can't be met
+	Reason string `json:"reason"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -145,7 +451,7 @@ func init() {
 func ScanStatusWrapperFromScan(s *ComplianceScan) ComplianceScanStatusWrapper {
 	return ComplianceScanStatusWrapper{
 		Name:                 s.Name,
-		ComplianceScanStatus: s.Status,
+		ComplianceScanStatus: *s.Status.DeepCopy(),
 	}
 }
 
@@ -189,6 +495,30 @@ func (s *ComplianceSuite) LowestCommonResult() ComplianceScanStatusResult {
 	return lowestCommonResult
 }
 
+// LatestCompletedTime returns the time the last scan of the suite completed,
+// if all the scans of the suite are done and completed successfully
+func (s *ComplianceSuite) LatestCompletedTime() *metav1.Time {
+	if s.LowestCommonState() != PhaseDone || len(s.Status.ScanStatuses) == 0 {
+		return nil
+	}
+	result := s.LowestCommonResult()
+	if result == ResultError || result == ResultNotAvailable {
+		return nil
+	}
+
+	var latest *metav1.Time
+	for i := range s.Status.ScanStatuses {
+		completed := s.Status.ScanStatuses[i].LastCompletedTime
+		if completed == nil {
+			return nil
+		}
+		if latest == nil || latest.Before(completed) {
+			latest = completed
+		}
+	}
+	return latest.DeepCopy()
+}
+
 func (s *ComplianceSuite) IsResultAvailable() bool {
 	result := s.LowestCommonResult()
 	return result != "" && result != ResultNotAvailable
@@ -265,6 +595,20 @@ func (s *ComplianceSuiteStatus) SetConditionsProcessing() {
 	})
 }
 
+// ConditionTimeZoneSupported is set on the suites whose time zone the
+// cluster doesn't support, and that run on the schedule interpreted in the
+// time zone of the kube-controller-manager instead
+const ConditionTimeZoneSupported = "TimeZoneSupported"
+
+func (s *ComplianceSuiteStatus) SetConditionTimeZoneUnsupported() {
+	s.Conditions.SetCondition(conditions.Condition{
+		Type:    ConditionTimeZoneSupported,
+		Status:  corev1.ConditionFalse,
+		Reason:  "Pruned",
+		Message: "The cluster doesn't support the time zone of CronJobs, the schedule is interpreted in the time zone of the kube-controller-manager",
+	})
+}
+
 func (s *ComplianceSuiteStatus) SetConditionReady() {
 	s.Conditions.SetCondition(conditions.Condition{
 		Type:    "Ready",
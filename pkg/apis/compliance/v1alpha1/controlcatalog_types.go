@@ -0,0 +1,56 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ControlCatalogFrameworkLabel holds the name of the compliance framework
+// that a ControlCatalog lists the controls of
+const ControlCatalogFrameworkLabel = "compliance.openshift.io/control-framework"
+
+// ControlCatalogControl is a control of a compliance framework along with
+// the rules that map to it
+type ControlCatalogControl struct {
+	// The identifier of the control in the framework
+	ID string `json:"id"`
+	// The names of the rules that map to the control
+	// +listType=atomic
+	Rules []string `json:"rules"`
+}
+
+type ControlCatalogPayload struct {
+	// The name of the compliance framework
+	Framework string `json:"framework"`
+	// The controls of the framework that at least one rule of the
+	// ProfileBundle maps to
+	// +nullable
+	// +optional
+	// +listType=atomic
+	Controls []ControlCatalogControl `json:"controls,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ControlCatalog lists the controls of a compliance framework that the
+// rules of a ProfileBundle map to
+// +kubebuilder:resource:path=controlcatalogs,scope=Namespaced,shortName=catalog;catalogs
+// +kubebuilder:printcolumn:name="Framework",type="string",JSONPath=`.framework`
+type ControlCatalog struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	ControlCatalogPayload `json:",inline"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ControlCatalogList contains a list of ControlCatalog
+type ControlCatalogList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ControlCatalog `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ControlCatalog{}, &ControlCatalogList{})
+}
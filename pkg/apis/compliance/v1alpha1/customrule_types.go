@@ -0,0 +1,111 @@
+package v1alpha1
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CustomRulesTailoringKey is the key of the tailoring ConfigMap that holds
+// the CustomRules enabled by a TailoredProfile, serialized as JSON
+const CustomRulesTailoringKey = "custom-rules.json"
+
+// CustomRuleResultsKey is the key of the result ConfigMap of a platform
+// scan that holds the results of the CustomRules, serialized as JSON
+const CustomRuleResultsKey = "custom-rules-results"
+
+// CustomRuleLabel marks the ComplianceCheckResults of CustomRules
+const CustomRuleLabel = "compliance.openshift.io/custom-rule"
+
+// CustomRuleCheckResultPrefix is prepended to the name of a CustomRule in
+// the names of its ComplianceCheckResults, so that they don't collide with
+// those of the rules of the content
+const CustomRuleCheckResultPrefix = "custom"
+
+// GetCustomRuleCheckResultName returns the name of the ComplianceCheckResult
+// of a CustomRule evaluated by the given scan
+func GetCustomRuleCheckResultName(scanName, customRuleName string) string {
+	return fmt.Sprintf("%s-%s-%s", scanName, CustomRuleCheckResultPrefix, customRuleName)
+}
+
+// CustomRuleInput points to the API resource that a CustomRule checks
+type CustomRuleInput struct {
+	// The path of the resource in the Kubernetes API, e.g.
+	// /apis/config.openshift.io/v1/apiservers/cluster
+	// +kubebuilder:validation:Pattern=^/
+	APIPath string `json:"apiPath"`
+	// A jq filter that's applied to the resource before evaluating the
+	// expression. It must return a single value.
+	// +optional
+	Filter string `json:"filter,omitempty"`
+}
+
+// CustomRuleSpec defines a check that's evaluated against a resource of the
+// Kubernetes API, without the need of building a content image
+type CustomRuleSpec struct {
+	// The title of the rule
+	// +kubebuilder:validation:Pattern=^.+$
+	Title string `json:"title"`
+	// The description of the rule
+	// +optional
+	Description string `json:"description,omitempty"`
+	// The rationale of the rule
+	// +optional
+	Rationale string `json:"rationale,omitempty"`
+	// The severity of the rule
+	// +kubebuilder:validation:Enum=unknown;info;low;medium;high
+	// +kubebuilder:default=medium
+	// +optional
+	Severity string `json:"severity,omitempty"`
+	// Instructions for auditing this rule manually
+	// +optional
+	Instructions string `json:"instructions,omitempty"`
+	// What type of check the rule executes. Only Platform is supported.
+	// +kubebuilder:validation:Enum=Platform
+	// +kubebuilder:default=Platform
+	// +optional
+	CheckType string `json:"checkType,omitempty"`
+	// The resource the rule checks
+	Input CustomRuleInput `json:"input"`
+	// A jq expression that's evaluated against the filtered resource, or
+	// null if the resource doesn't exist. The rule passes if it returns
+	// true and fails if it returns false.
+	Expression string `json:"expression"`
+	// The message reported when the rule fails
+	// +optional
+	FailureMessage string `json:"failureMessage,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CustomRule is the Schema for the customrules API
+// +kubebuilder:resource:path=customrules,scope=Namespaced,shortName=crule
+// +kubebuilder:printcolumn:name="Severity",type="string",JSONPath=`.spec.severity`
+// +kubebuilder:printcolumn:name="API Path",type="string",JSONPath=`.spec.input.apiPath`
+type CustomRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec CustomRuleSpec `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CustomRuleList contains a list of CustomRule
+type CustomRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CustomRule `json:"items"`
+}
+
+// GetSeverity returns the severity of the rule, which defaults to medium
+func (r *CustomRule) GetSeverity() string {
+	if r.Spec.Severity == "" {
+		return "medium"
+	}
+	return r.Spec.Severity
+}
+
+func init() {
+	SchemeBuilder.Register(&CustomRule{}, &CustomRuleList{})
+}
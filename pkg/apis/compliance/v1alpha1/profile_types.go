@@ -23,6 +23,30 @@ func NewProfileRule(rule string) ProfileRule {
 // ProfileValue defines a value for a setting in the profile
 type ProfileValue string
 
+// ProfileRuleSource records which profile in the extends chain of a
+// profile selected one of its rules
+type ProfileRuleSource struct {
+	// The name of the rule
+	Rule ProfileRule `json:"rule"`
+	// The name of the profile that selected the rule
+	Profile string `json:"profile"`
+}
+
+// ProfileRefinedValue is the value a profile sets for a variable, either
+// by refining it with a selector or by setting it directly
+type ProfileRefinedValue struct {
+	// The name of the variable
+	Variable string `json:"variable"`
+	// The selector of the value the variable is refined to, if any
+	// +optional
+	Selector string `json:"selector,omitempty"`
+	// The value the variable is set to
+	// +optional
+	Value string `json:"value,omitempty"`
+	// The name of the profile that sets the value
+	Profile string `json:"profile"`
+}
+
 type ProfilePayload struct {
 	Title       string `json:"title"`
 	Description string `json:"description"`
@@ -35,6 +59,25 @@ type ProfilePayload struct {
 	// +optional
 	// +listType=atomic
 	Values []ProfileValue `json:"values,omitempty"`
+	// The names of the profiles this profile extends, starting with the
+	// one it extends directly
+	// +nullable
+	// +optional
+	// +listType=atomic
+	Extends []string `json:"extends,omitempty"`
+	// Which profile selected each of the rules the profile ends up with,
+	// including those it inherits, while rules only lists those it selects
+	// itself. Only set if the profile extends others.
+	// +nullable
+	// +optional
+	// +listType=atomic
+	RuleSources []ProfileRuleSource `json:"ruleSources,omitempty"`
+	// The values of the variables that the profile, or the profiles it
+	// extends, set
+	// +nullable
+	// +optional
+	// +listType=atomic
+	RefinedValues []ProfileRefinedValue `json:"refinedValues,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -48,6 +91,19 @@ type Profile struct {
 	ProfilePayload `json:",inline"`
 }
 
+// GetEffectiveRules returns the rules the profile ends up selecting,
+// including those it inherits from the profiles it extends
+func (p *Profile) GetEffectiveRules() []ProfileRule {
+	if len(p.Extends) == 0 {
+		return p.Rules
+	}
+	rules := make([]ProfileRule, 0, len(p.RuleSources))
+	for _, source := range p.RuleSources {
+		rules = append(rules, source.Rule)
+	}
+	return rules
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // ProfileList contains a list of Profile
@@ -1,6 +1,8 @@
 package v1alpha1
 
 import (
+	"strings"
+
 	conditions "github.com/operator-framework/operator-sdk/pkg/status"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -17,6 +19,34 @@ const ProfileBundleOwnerLabel = "compliance.openshift.io/profile-bundle"
 // ProfileImageDigestAnnotation is the parsed out digest of the content image
 const ProfileImageDigestAnnotation = "compliance.openshift.io/image-digest"
 
+// ProfileContentDigestAnnotation is the digest of the content image a
+// profile, rule or variable was last parsed from
+const ProfileContentDigestAnnotation = "compliance.openshift.io/content-digest"
+
+// ProfileContentDigestsAnnotation lists the digests of all the content
+// images a profile, rule or variable was parsed from without changes,
+// separated by commas. Only the digests that are still retained are kept.
+const ProfileContentDigestsAnnotation = "compliance.openshift.io/content-digests"
+
+// ArchivedContentLabel marks a copy of a profile, rule or variable as parsed
+// from a previous content version of the bundle given as the value. The
+// copies are named using GetArchivedContentName. The copies of
+// TailoredProfiles that ScanSettingBindings pin to a content version carry
+// it as well. Objects with this label are only used internally: they are
+// skipped when listing or looking up content, and can't be referenced.
+const ArchivedContentLabel = "compliance.openshift.io/archived-content"
+
+// ArchivedFromAnnotation is the name of the profile, rule or variable an
+// archived copy was made from
+const ArchivedFromAnnotation = "compliance.openshift.io/archived-from"
+
+// ProfileBundleChangelogLabel marks a ConfigMap containing the changelog of
+// an update of the content of the profile bundle given as the value
+const ProfileBundleChangelogLabel = "compliance.openshift.io/profile-bundle-changelog"
+
+// ProfileBundleChangelogKey is the key of the changelog in its ConfigMap
+const ProfileBundleChangelogKey = "changelog.json"
+
 // DataStreamStatusType is the type for the data stream status
 type DataStreamStatusType string
 
@@ -33,9 +63,171 @@ const (
 // Defines the desired state of ProfileBundle
 type ProfileBundleSpec struct {
 	// Is the path for the image that contains the content for this bundle.
-	ContentImage string `json:"contentImage"`
+	// Either contentImage or contentSource must be set.
+	// +optional
+	ContentImage string `json:"contentImage,omitempty"`
 	// Is the path for the file in the image that contains the content for this bundle.
+	// When using a contentSource, this is the name the content is stored as.
 	ContentFile string `json:"contentFile"`
+	// Loads the content from a ConfigMap, a Secret, a PersistentVolumeClaim
+	// or an HTTPS URL instead of a container image
+	// +optional
+	// +nullable
+	ContentSource *ContentSource `json:"contentSource,omitempty"`
+	// Verifies the data stream before it's parsed. Scans of the bundle
+	// refuse to run against a data stream other than the verified one.
+	// +optional
+	// +nullable
+	Verification *ContentVerification `json:"verification,omitempty"`
+	// Compliance frameworks that the rules reference, in addition to the
+	// built-in NIST-800-53, CIS-OCP, CIS-RHEL, NERC-CIP and PCI-DSS ones.
+	// A ControlCatalog is generated for each framework.
+	// +optional
+	// +listType=atomic
+	ControlFrameworks []ControlFramework `json:"controlFrameworks,omitempty"`
+}
+
+// ControlFramework registers a compliance framework whose controls the
+// rules of a bundle reference
+type ControlFramework struct {
+	// The name of the framework, as used in the control annotations of the
+	// rules and in the name of its ControlCatalog
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=^[A-Za-z0-9][A-Za-z0-9-]*$
+	Name string `json:"name"`
+	// A regular expression that the href attribute of the XCCDF references
+	// to the framework matches. The text of the reference lists the
+	// controls.
+	ReferencePattern string `json:"referencePattern"`
+}
+
+// ContentSource points to content that's not shipped in a container image.
+// Exactly one of the sources must be set. ConfigMaps, Secrets and
+// PersistentVolumeClaims have to be in the namespace of the operator, as
+// they're mounted by the pods it creates. Content compressed with gzip or
+// bzip2 is decompressed.
+type ContentSource struct {
+	// A key of a ConfigMap that holds the content
+	// +optional
+	// +nullable
+	ConfigMap *ContentKeySelector `json:"configMap,omitempty"`
+	// A key of a Secret that holds the content
+	// +optional
+	// +nullable
+	Secret *ContentKeySelector `json:"secret,omitempty"`
+	// A file in a PersistentVolumeClaim that holds the content. The claim
+	// needs the ReadOnlyMany or ReadWriteMany access mode.
+	// +optional
+	// +nullable
+	PersistentVolumeClaim *ContentVolumeSource `json:"persistentVolumeClaim,omitempty"`
+	// An HTTPS URL the content is downloaded from
+	// +optional
+	// +nullable
+	HTTPS *ContentHTTPSSource `json:"https,omitempty"`
+	// The SHA-256 checksum of the content as stored in the source, before
+	// decompressing it, in the sha256:<hex> format. The content is rejected
+	// if it doesn't match. It's required for HTTPS URLs.
+	// +kubebuilder:validation:Pattern=`^sha256:[a-f0-9]{64}$`
+	// +optional
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// ContentKeySelector selects a key of a ConfigMap or Secret
+type ContentKeySelector struct {
+	// The name of the ConfigMap or Secret
+	Name string `json:"name"`
+	// The key that holds the content
+	Key string `json:"key"`
+}
+
+// ContentVolumeSource selects a file in a PersistentVolumeClaim
+type ContentVolumeSource struct {
+	// The name of the PersistentVolumeClaim
+	ClaimName string `json:"claimName"`
+	// The path of the content, relative to the root of the volume
+	Path string `json:"path"`
+}
+
+// ContentHTTPSSource points to content served over HTTPS
+type ContentHTTPSSource struct {
+	// The URL the content is downloaded from
+	// +kubebuilder:validation:Pattern=`^https://`
+	URL string `json:"url"`
+	// The name of a ConfigMap with a `ca-bundle.crt` key holding the
+	// certificates of the CAs that the server is verified with. The system
+	// CAs are used if it's not set.
+	// +optional
+	CAConfigMap string `json:"caConfigMap,omitempty"`
+}
+
+// ContentVerification configures how the data stream of a ProfileBundle is
+// verified. At least one of the checks must be set; all that are set must
+// pass.
+type ContentVerification struct {
+	// The SHA-256 digest the data stream must have, in the sha256:<hex>
+	// format
+	// +kubebuilder:validation:Pattern=`^sha256:[a-f0-9]{64}$`
+	// +optional
+	Digest string `json:"digest,omitempty"`
+	// Verifies a signature of the data stream with a public key
+	// +optional
+	// +nullable
+	PublicKey *ContentSignatureVerification `json:"publicKey,omitempty"`
+}
+
+// ContentSignatureVerification verifies a cosign-style signature of the data
+// stream: a base64-encoded ECDSA, RSA (PKCS #1 v1.5) or Ed25519 signature,
+// as created by `cosign sign-blob`. The signature is shipped in the content
+// image along with the data stream.
+type ContentSignatureVerification struct {
+	// A key of a ConfigMap in the namespace of the operator that holds the
+	// PEM-encoded public key
+	KeyConfigMap ContentKeySelector `json:"keyConfigMap"`
+	// The path of the signature in the content image. Defaults to the
+	// contentFile with a .sig suffix.
+	// +optional
+	SignatureFile string `json:"signatureFile,omitempty"`
+}
+
+// ProfileBundleContentVersion identifies a version of the content that was
+// parsed for a ProfileBundle
+type ProfileBundleContentVersion struct {
+	// The version of the benchmark in the data stream
+	DataStreamVersion string `json:"dataStreamVersion,omitempty"`
+	// The content image the version was parsed from
+	ContentImage string `json:"contentImage,omitempty"`
+	// The digest of the content image the version was parsed from
+	ImageDigest string `json:"imageDigest,omitempty"`
+	// The SHA-256 digest of the data stream
+	DataStreamDigest string `json:"dataStreamDigest,omitempty"`
+	// Whether the data stream was verified as configured in the
+	// verification attribute of the ProfileBundle
+	Verified bool `json:"verified,omitempty"`
+	// When the version was parsed
+	// +optional
+	// +nullable
+	ParsedTime *metav1.Time `json:"parsedTime,omitempty"`
+}
+
+// ProfileBundleChangelogSummary summarizes how the content of a
+// ProfileBundle changed when it was last updated
+type ProfileBundleChangelogSummary struct {
+	// The name of the ConfigMap containing the full changelog
+	// +optional
+	ConfigMapName string `json:"configMapName,omitempty"`
+	// The number of profiles, rules and variables that were added
+	Added int `json:"added"`
+	// The number of profiles, rules and variables that were removed
+	Removed int `json:"removed"`
+	// The number of profiles, rules and variables that were modified
+	Modified int `json:"modified"`
+	// Set if the changelog was too large for a ConfigMap, in which case
+	// the ConfigMap only lists part of the objects that changed
+	// +optional
+	Truncated bool `json:"truncated,omitempty"`
+	// The reason the changelog couldn't be stored, if it couldn't
+	// +optional
+	ErrorMessage string `json:"errorMessage,omitempty"`
 }
 
 // Defines the observed state of ProfileBundle
@@ -49,6 +241,19 @@ type ProfileBundleStatus struct {
 	//  - Ready: Indicates if the ProfileBundle is Ready parsing or not.
 	// +optional
 	Conditions conditions.Conditions `json:"conditions,omitempty"`
+	// The version of the content that was parsed last
+	// +optional
+	// +nullable
+	ContentVersion *ProfileBundleContentVersion `json:"contentVersion,omitempty"`
+	// The versions of the content that were parsed before, newest first.
+	// ScanSettingBindings can pin any of these or the current version.
+	// +optional
+	// +listType=atomic
+	PreviousContentVersions []ProfileBundleContentVersion `json:"previousContentVersions,omitempty"`
+	// Summarizes the changes of the last update of the content
+	// +optional
+	// +nullable
+	LastChangelog *ProfileBundleChangelogSummary `json:"lastChangelog,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -59,6 +264,7 @@ type ProfileBundleStatus struct {
 // +kubebuilder:printcolumn:name="ContentImage",type="string",JSONPath=`.spec.contentImage`
 // +kubebuilder:printcolumn:name="ContentFile",type="string",JSONPath=`.spec.contentFile`
 // +kubebuilder:printcolumn:name="Status",type="string",JSONPath=`.status.dataStreamStatus`
+// +kubebuilder:printcolumn:name="Version",type="string",JSONPath=`.status.contentVersion.dataStreamVersion`
 type ProfileBundle struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -76,6 +282,82 @@ type ProfileBundleList struct {
 	Items           []ProfileBundle `json:"items"`
 }
 
+// GetContentVersion returns the current or previous content version of
+// the bundle that was parsed from the image with the given digest, or nil
+// if there's none
+func (pb *ProfileBundle) GetContentVersion(digest string) *ProfileBundleContentVersion {
+	if digest == "" {
+		return nil
+	}
+	if pb.Status.ContentVersion != nil && pb.Status.ContentVersion.ImageDigest == digest {
+		return pb.Status.ContentVersion
+	}
+	for i := range pb.Status.PreviousContentVersions {
+		if pb.Status.PreviousContentVersions[i].ImageDigest == digest {
+			return &pb.Status.PreviousContentVersions[i]
+		}
+	}
+	return nil
+}
+
+// GetSignatureFile returns the path of the signature of the data stream in
+// the content image, if the bundle verifies one
+func (pb *ProfileBundle) GetSignatureFile() string {
+	if pb.Spec.Verification == nil || pb.Spec.Verification.PublicKey == nil {
+		return ""
+	}
+	if pb.Spec.Verification.PublicKey.SignatureFile != "" {
+		return pb.Spec.Verification.PublicKey.SignatureFile
+	}
+	return pb.Spec.ContentFile + ".sig"
+}
+
+// GetArchivedContentName returns the name of the copy of a profile, rule or
+// variable that was parsed from the content image with the given digest
+func GetArchivedContentName(name, digest string) string {
+	short := digest[strings.Index(digest, ":")+1:]
+	if len(short) > 12 {
+		short = short[:12]
+	}
+	return name + "-" + short
+}
+
+// IsArchivedContent returns true if the object is an archived copy of a
+// profile, rule or variable, or a TailoredProfile pinned to a content
+// version
+func IsArchivedContent(obj metav1.Object) bool {
+	return obj.GetLabels()[ArchivedContentLabel] != ""
+}
+
+// GetContentDigests returns the digests of the content images the profile,
+// rule or variable was parsed from without changes
+func GetContentDigests(obj metav1.Object) []string {
+	annotations := obj.GetAnnotations()
+	if digests := annotations[ProfileContentDigestsAnnotation]; digests != "" {
+		return strings.Split(digests, ",")
+	}
+	if digest := annotations[ProfileContentDigestAnnotation]; digest != "" {
+		return []string{digest}
+	}
+	return nil
+}
+
+// IsParsedFromContentDigest returns true if the profile, rule or variable
+// is the same as it was parsed from the content image with the given
+// digest. Objects parsed before the digests were recorded match any digest.
+func IsParsedFromContentDigest(obj metav1.Object, digest string) bool {
+	digests := GetContentDigests(obj)
+	if digests == nil {
+		return true
+	}
+	for _, d := range digests {
+		if d == digest {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *ProfileBundleStatus) SetConditionPending() {
 	s.Conditions.SetCondition(conditions.Condition{
 		Type:    "Ready",
@@ -33,6 +33,82 @@ type ScanSetting struct {
 	// Note that tolerations must still be configured for
 	// the opeartor to appropriately schedule scans.
 	Roles []string `json:"roles,omitempty"`
+	// Configures the alerts fired for the results of the ComplianceSuites
+	// of the ScanSettingBindings that use this ScanSetting. The alerts
+	// are enabled with the defaults if unset.
+	// +optional
+	Alerting *ScanSettingAlerting `json:"alerting,omitempty"`
+}
+
+// DefaultAlertFor is how long a result lasts before its alert fires unless
+// configured otherwise
+const DefaultAlertFor = "1s"
+
+// DefaultAlertSeverity is the severity of the alerts unless configured
+// otherwise
+const DefaultAlertSeverity = "warning"
+
+// AlertResult is a result of a ComplianceSuite that fires an alert
+// +kubebuilder:validation:Enum=NON-COMPLIANT;INCONSISTENT;ERROR
+type AlertResult string
+
+// ScanSettingAlerting configures the alerts fired for the results of
+// ComplianceSuites. They're reconciled into a PrometheusRule per
+// ScanSettingBinding.
+type ScanSettingAlerting struct {
+	// Disables the alerts
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+	// The results that fire an alert. Defaults to NON-COMPLIANT,
+	// INCONSISTENT and ERROR.
+	// +optional
+	// +nullable
+	// +listType=set
+	Results []AlertResult `json:"results,omitempty"`
+	// The severity label of the alert of each result, keyed by result.
+	// Results without a severity use "warning".
+	// +optional
+	// +nullable
+	Severities map[string]string `json:"severities,omitempty"`
+	// How long a result must last before its alert fires, as a Prometheus
+	// duration. Defaults to 1s.
+	// +kubebuilder:validation:Pattern=^([0-9]+(ms|s|m|h|d|w|y))+$
+	// +optional
+	For string `json:"for,omitempty"`
+	// Labels added to the alerts, e.g. to route them
+	// +optional
+	// +nullable
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// GetAlertResults returns the results that fire an alert
+func (a *ScanSettingAlerting) GetAlertResults() []AlertResult {
+	if a == nil || len(a.Results) == 0 {
+		return []AlertResult{
+			AlertResult(ResultNonCompliant),
+			AlertResult(ResultInconsistent),
+			AlertResult(ResultError),
+		}
+	}
+	return a.Results
+}
+
+// GetSeverity returns the severity of the alert of a result
+func (a *ScanSettingAlerting) GetSeverity(result AlertResult) string {
+	if a != nil {
+		if severity, ok := a.Severities[string(result)]; ok && severity != "" {
+			return severity
+		}
+	}
+	return DefaultAlertSeverity
+}
+
+// GetFor returns how long a result must last before its alert fires
+func (a *ScanSettingAlerting) GetFor() string {
+	if a == nil || a.For == "" {
+		return DefaultAlertFor
+	}
+	return a.For
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -24,10 +24,24 @@ type ScanSettingBinding struct {
 
 	Profiles    []NamedObjectReference `json:"profiles,omitempty"`
 	SettingsRef *NamedObjectReference  `json:"settingsRef,omitempty"`
+	// Pins the content of ProfileBundles to a previously parsed version,
+	// so that the scans keep using it when the bundles are updated
+	// +optional
+	// +listType=atomic
+	ContentVersions []ContentVersionPin `json:"contentVersions,omitempty"`
 	// +optional
 	Status ScanSettingBindingStatus `json:"status,omitempty"`
 }
 
+// ContentVersionPin pins the content of a ProfileBundle to a version
+type ContentVersionPin struct {
+	// The name of the ProfileBundle
+	ProfileBundle string `json:"profileBundle"`
+	// The digest of the content image to use, as listed in the
+	// contentVersion or previousContentVersions of the bundle status
+	ImageDigest string `json:"imageDigest"`
+}
+
 type ScanSettingBindingStatus struct {
 	// +optional
 	Conditions conditions.Conditions `json:"conditions,omitempty"`
@@ -35,6 +49,39 @@ type ScanSettingBindingStatus struct {
 	// +optional
 	// +nullable
 	OutputRef *corev1.TypedLocalObjectReference `json:"outputRef,omitempty"`
+	// How the latest scans of the binding cover the controls of each
+	// compliance framework the rules map to
+	// +optional
+	// +nullable
+	// +listType=atomic
+	ControlCoverage []ControlFrameworkCoverage `json:"controlCoverage,omitempty"`
+	// A digest of the runs of the scans the control coverage was computed
+	// for. The coverage is only computed again once the scans run again.
+	// +optional
+	ControlCoverageVersion string `json:"controlCoverageVersion,omitempty"`
+}
+
+// ControlFrameworkCoverage summarizes how the results of a scan cover the
+// controls of a compliance framework. A control is covered if at least one
+// of its rules was checked. It passed if none of its rules failed and at
+// least one passed, and failed if any of its rules failed.
+type ControlFrameworkCoverage struct {
+	// The name of the compliance framework
+	Framework string `json:"framework"`
+	// The number of controls that the rules of the scanned
+	// ProfileBundles map to
+	TotalControls int `json:"totalControls"`
+	// The number of controls with at least one checked rule
+	CoveredControls int `json:"coveredControls"`
+	// The number of covered controls that passed
+	PassedControls int `json:"passedControls"`
+	// The number of covered controls that failed
+	FailedControls int `json:"failedControls"`
+	// The identifiers of the controls that failed
+	// +optional
+	// +nullable
+	// +listType=atomic
+	FailedControlIDs []string `json:"failedControlIDs,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -46,6 +93,17 @@ type ScanSettingBindingList struct {
 	Items           []ScanSettingBinding `json:"items"`
 }
 
+// GetPinnedContentDigest returns the digest of the content image the
+// binding pins the given ProfileBundle to, or an empty string
+func (s *ScanSettingBinding) GetPinnedContentDigest(bundle string) string {
+	for _, pin := range s.ContentVersions {
+		if pin.ProfileBundle == bundle {
+			return pin.ImageDigest
+		}
+	}
+	return ""
+}
+
 func (s *ScanSettingBindingStatus) SetConditionPending() {
 	s.Conditions.SetCondition(conditions.Condition{
 		Type:    "Ready",
@@ -4,16 +4,65 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// PinnedContentDigestAnnotation marks a TailoredProfile as a copy that a
+// ScanSettingBinding made to pin the content the TailoredProfile is built
+// from. The value is the digest of the pinned content image, and the
+// profiles, rules and variables are resolved as parsed from it.
+const PinnedContentDigestAnnotation = "compliance.openshift.io/pinned-content-digest"
+
+// ImportedTailoringDigestAnnotation records the digest of the tailoring
+// file a TailoredProfile was last imported from, see ImportTailoring
+const ImportedTailoringDigestAnnotation = "compliance.openshift.io/imported-tailoring-digest"
+
 // FIXME: move name/rationale to a common struct with an interface?
 
 // RuleReferenceSpec specifies a rule to be selected/deselected, as well as the reason why
 type RuleReferenceSpec struct {
-	// Name of the rule that's being referenced
-	Name string `json:"name"`
+	// Name of the rule that's being referenced. Either name or selector
+	// must be set.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Selects all the rules that match instead of a single rule by name
+	// +optional
+	Selector *RuleSelectorSpec `json:"selector,omitempty"`
 	// Rationale of why this rule is being selected/deselected
 	Rationale string `json:"rationale"`
 }
 
+// RuleSelectorSpec selects rules by their labels, annotations, severity and
+// check type. Only the rules that match all the criteria that are set are
+// selected.
+type RuleSelectorSpec struct {
+	// Selects rules by their labels
+	// +optional
+	// +nullable
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+	// Selects rules by their annotations. Annotations holding a list of
+	// values separated by ';' or ',', such as the control annotations,
+	// match if any of the values matches. Values can use '*' as a
+	// wildcard, e.g. "AC-*".
+	// +optional
+	// +nullable
+	MatchAnnotations map[string]string `json:"matchAnnotations,omitempty"`
+	// Selects rules with any of these severities
+	// +optional
+	// +nullable
+	// +listType=atomic
+	Severities []string `json:"severities,omitempty"`
+	// Selects rules with this check type. Defaults to the product type of
+	// the TailoredProfile, along with the rules that have no check.
+	// +kubebuilder:validation:Enum=Platform;Node
+	// +optional
+	CheckType string `json:"checkType,omitempty"`
+}
+
+// IsEmpty returns true if the selector doesn't set any criteria, which
+// would select every rule
+func (s *RuleSelectorSpec) IsEmpty() bool {
+	return s.LabelSelector == nil && len(s.MatchAnnotations) == 0 &&
+		len(s.Severities) == 0 && s.CheckType == ""
+}
+
 // ValueReferenceSpec specifies a value to be set for a variable with a reason why
 type VariableValueSpec struct {
 	// Name of the variable that's being referenced
@@ -24,11 +73,57 @@ type VariableValueSpec struct {
 	Value string `json:"value"`
 }
 
+// ExtendedProfileReference points to a Profile or TailoredProfile that a
+// TailoredProfile extends
+type ExtendedProfileReference struct {
+	// Name of the Profile or TailoredProfile
+	Name string `json:"name"`
+	// Kind of the referenced object, either Profile or TailoredProfile
+	// +kubebuilder:validation:Enum=Profile;TailoredProfile
+	// +kubebuilder:default=Profile
+	// +optional
+	Kind string `json:"kind,omitempty"`
+}
+
+// TailoringImportSource points to an XCCDF tailoring file, e.g. one
+// written by SCAP Workbench, that a TailoredProfile is imported from
+type TailoringImportSource struct {
+	// Name of the ConfigMap holding the tailoring file, in the namespace
+	// of the TailoredProfile
+	ConfigMap string `json:"configMap"`
+	// Key of the ConfigMap holding the tailoring file
+	// +kubebuilder:default=tailoring.xml
+	// +optional
+	Key string `json:"key,omitempty"`
+	// XCCDF ID of the profile to import, if the file contains several
+	// +optional
+	ProfileID string `json:"profileID,omitempty"`
+	// The ProfileBundle whose content the tailoring applies to
+	ProfileBundle string `json:"profileBundle"`
+}
+
+// GetKey returns the key of the ConfigMap holding the tailoring file
+func (s *TailoringImportSource) GetKey() string {
+	if s.Key == "" {
+		return "tailoring.xml"
+	}
+	return s.Key
+}
+
 // TailoredProfileSpec defines the desired state of TailoredProfile
 type TailoredProfileSpec struct {
 	// +optional
 	// Points to the name of the profile to extend
 	Extends string `json:"extends,omitempty"`
+	// Points to several Profiles or TailoredProfiles to extend. Their rule
+	// selections and variable values are merged in order, so later ones
+	// take precedence over earlier ones, and the rules and variables of
+	// this TailoredProfile take precedence over all of them. Can't be
+	// combined with extends.
+	// +optional
+	// +nullable
+	// +listType=atomic
+	ExtendsProfiles []ExtendedProfileReference `json:"extendsProfiles,omitempty"`
 	// Title for the tailored profile. It can't be empty.
 	// +kubebuilder:validation:Pattern=^.+$
 	Title string `json:"title"`
@@ -47,6 +142,17 @@ type TailoredProfileSpec struct {
 	// +optional
 	// +nullable
 	SetValues []VariableValueSpec `json:"setValues,omitempty"`
+	// Enables the referenced CustomRules. Only platform TailoredProfiles
+	// can enable CustomRules.
+	// +optional
+	// +nullable
+	CustomRules []RuleReferenceSpec `json:"customRules,omitempty"`
+	// Imports the profile of an XCCDF tailoring file. The controller
+	// replaces extends, enableRules, disableRules and setValues with the
+	// ones of the imported profile whenever the tailoring file changes.
+	// Can't be combined with extendsProfiles.
+	// +optional
+	ImportTailoring *TailoringImportSource `json:"importTailoring,omitempty"`
 }
 
 // TailoredProfileState defines the state fo the tailored profile
@@ -70,6 +176,25 @@ type TailoredProfileStatus struct {
 	// The current state of the tailored profile
 	State        TailoredProfileState `json:"state,omitempty"`
 	ErrorMessage string               `json:"errorMessage,omitempty"`
+	// The rules that the selectors in enableRules and disableRules
+	// resolved to
+	// +optional
+	ResolvedRules *ResolvedRuleSelections `json:"resolvedRules,omitempty"`
+}
+
+// ResolvedRuleSelections lists the names of the rules that selectors
+// resolved to
+type ResolvedRuleSelections struct {
+	// The rules enabled through selectors
+	// +optional
+	// +nullable
+	// +listType=atomic
+	Enabled []string `json:"enabled,omitempty"`
+	// The rules disabled through selectors
+	// +optional
+	// +nullable
+	// +listType=atomic
+	Disabled []string `json:"disabled,omitempty"`
 }
 
 // OutputRef is a reference to the object created from the tailored profile
@@ -101,6 +226,45 @@ type TailoredProfileList struct {
 	Items           []TailoredProfile `json:"items"`
 }
 
+// GetPinnedContentDigest returns the digest of the content image the
+// TailoredProfile is pinned to, or an empty string
+func (tp *TailoredProfile) GetPinnedContentDigest() string {
+	return tp.GetAnnotations()[PinnedContentDigestAnnotation]
+}
+
+// IsComposed returns true if the TailoredProfile extends several Profiles
+// or TailoredProfiles
+func (tp *TailoredProfile) IsComposed() bool {
+	return len(tp.Spec.ExtendsProfiles) > 0
+}
+
+// HasRuleSelectors returns true if any of the rules are selected through
+// selectors
+func (tp *TailoredProfile) HasRuleSelectors() bool {
+	for _, selection := range append(tp.Spec.EnableRules, tp.Spec.DisableRules...) {
+		if selection.Selector != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// GetExtendedProfiles returns the Profiles and TailoredProfiles the
+// TailoredProfile extends
+func (tp *TailoredProfile) GetExtendedProfiles() []ExtendedProfileReference {
+	if tp.Spec.Extends != "" {
+		return []ExtendedProfileReference{{Name: tp.Spec.Extends, Kind: "Profile"}}
+	}
+	refs := make([]ExtendedProfileReference, 0, len(tp.Spec.ExtendsProfiles))
+	for _, ref := range tp.Spec.ExtendsProfiles {
+		if ref.Kind == "" {
+			ref.Kind = "Profile"
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
 func init() {
 	SchemeBuilder.Register(&TailoredProfile{}, &TailoredProfileList{})
 }
@@ -7,9 +7,47 @@ package v1alpha1
 import (
 	status "github.com/operator-framework/operator-sdk/pkg/status"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CheckResultCount) DeepCopyInto(out *CheckResultCount) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CheckResultCount.
+func (in *CheckResultCount) DeepCopy() *CheckResultCount {
+	if in == nil {
+		return nil
+	}
+	out := new(CheckResultCount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CheckResultRetentionSettings) DeepCopyInto(out *CheckResultRetentionSettings) {
+	*out = *in
+	if in.Statuses != nil {
+		in, out := &in.Statuses, &out.Statuses
+		*out = make([]ComplianceCheckStatus, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CheckResultRetentionSettings.
+func (in *CheckResultRetentionSettings) DeepCopy() *CheckResultRetentionSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(CheckResultRetentionSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComplianceCheckResult) DeepCopyInto(out *ComplianceCheckResult) {
 	*out = *in
@@ -79,6 +117,139 @@ func (in *ComplianceCheckResultList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComplianceNotifier) DeepCopyInto(out *ComplianceNotifier) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComplianceNotifier.
+func (in *ComplianceNotifier) DeepCopy() *ComplianceNotifier {
+	if in == nil {
+		return nil
+	}
+	out := new(ComplianceNotifier)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ComplianceNotifier) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComplianceNotifierList) DeepCopyInto(out *ComplianceNotifierList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ComplianceNotifier, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComplianceNotifierList.
+func (in *ComplianceNotifierList) DeepCopy() *ComplianceNotifierList {
+	if in == nil {
+		return nil
+	}
+	out := new(ComplianceNotifierList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ComplianceNotifierList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComplianceNotifierSpec) DeepCopyInto(out *ComplianceNotifierSpec) {
+	*out = *in
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]NotificationEvent, len(*in))
+		copy(*out, *in)
+	}
+	if in.SuiteSelector != nil {
+		in, out := &in.SuiteSelector, &out.SuiteSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.URLSecret != nil {
+		in, out := &in.URLSecret, &out.URLSecret
+		*out = new(NotifierSecretKeySelector)
+		**out = **in
+	}
+	if in.HMAC != nil {
+		in, out := &in.HMAC, &out.HMAC
+		*out = new(NotifierHMAC)
+		**out = **in
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(NotifierTLS)
+		**out = **in
+	}
+	if in.Retries != nil {
+		in, out := &in.Retries, &out.Retries
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComplianceNotifierSpec.
+func (in *ComplianceNotifierSpec) DeepCopy() *ComplianceNotifierSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ComplianceNotifierSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComplianceNotifierStatus) DeepCopyInto(out *ComplianceNotifierStatus) {
+	*out = *in
+	if in.LastDelivery != nil {
+		in, out := &in.LastDelivery, &out.LastDelivery
+		*out = new(NotificationDelivery)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastFailure != nil {
+		in, out := &in.LastFailure, &out.LastFailure
+		*out = new(NotificationDelivery)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComplianceNotifierStatus.
+func (in *ComplianceNotifierStatus) DeepCopy() *ComplianceNotifierStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ComplianceNotifierStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComplianceRemediation) DeepCopyInto(out *ComplianceRemediation) {
 	*out = *in
@@ -217,7 +388,7 @@ func (in *ComplianceScan) DeepCopyInto(out *ComplianceScan) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -239,6 +410,23 @@ func (in *ComplianceScan) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComplianceScanAggregationProgress) DeepCopyInto(out *ComplianceScanAggregationProgress) {
+	*out = *in
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComplianceScanAggregationProgress.
+func (in *ComplianceScanAggregationProgress) DeepCopy() *ComplianceScanAggregationProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(ComplianceScanAggregationProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComplianceScanList) DeepCopyInto(out *ComplianceScanList) {
 	*out = *in
@@ -272,6 +460,27 @@ func (in *ComplianceScanList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComplianceScanPhaseTiming) DeepCopyInto(out *ComplianceScanPhaseTiming) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	if in.EndTime != nil {
+		in, out := &in.EndTime, &out.EndTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComplianceScanPhaseTiming.
+func (in *ComplianceScanPhaseTiming) DeepCopy() *ComplianceScanPhaseTiming {
+	if in == nil {
+		return nil
+	}
+	out := new(ComplianceScanPhaseTiming)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComplianceScanSettings) DeepCopyInto(out *ComplianceScanSettings) {
 	*out = *in
@@ -288,6 +497,11 @@ func (in *ComplianceScanSettings) DeepCopyInto(out *ComplianceScanSettings) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.CheckResultRetention != nil {
+		in, out := &in.CheckResultRetention, &out.CheckResultRetention
+		*out = new(CheckResultRetentionSettings)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -304,6 +518,11 @@ func (in *ComplianceScanSettings) DeepCopy() *ComplianceScanSettings {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComplianceScanSpec) DeepCopyInto(out *ComplianceScanSpec) {
 	*out = *in
+	if in.ContentSource != nil {
+		in, out := &in.ContentSource, &out.ContentSource
+		*out = new(ContentSource)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.NodeSelector != nil {
 		in, out := &in.NodeSelector, &out.NodeSelector
 		*out = make(map[string]string, len(*in))
@@ -351,6 +570,35 @@ func (in *ComplianceScanSpecWrapper) DeepCopy() *ComplianceScanSpecWrapper {
 func (in *ComplianceScanStatus) DeepCopyInto(out *ComplianceScanStatus) {
 	*out = *in
 	out.ResultsStorage = in.ResultsStorage
+	if in.StartTimestamp != nil {
+		in, out := &in.StartTimestamp, &out.StartTimestamp
+		*out = (*in).DeepCopy()
+	}
+	if in.EndTimestamp != nil {
+		in, out := &in.EndTimestamp, &out.EndTimestamp
+		*out = (*in).DeepCopy()
+	}
+	if in.LastCompletedTime != nil {
+		in, out := &in.LastCompletedTime, &out.LastCompletedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.PhaseTimings != nil {
+		in, out := &in.PhaseTimings, &out.PhaseTimings
+		*out = make([]ComplianceScanPhaseTiming, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CheckResultCounts != nil {
+		in, out := &in.CheckResultCounts, &out.CheckResultCounts
+		*out = make([]CheckResultCount, len(*in))
+		copy(*out, *in)
+	}
+	if in.AggregationProgress != nil {
+		in, out := &in.AggregationProgress, &out.AggregationProgress
+		*out = new(ComplianceScanAggregationProgress)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -367,7 +615,7 @@ func (in *ComplianceScanStatus) DeepCopy() *ComplianceScanStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComplianceScanStatusWrapper) DeepCopyInto(out *ComplianceScanStatusWrapper) {
 	*out = *in
-	out.ComplianceScanStatus = in.ComplianceScanStatus
+	in.ComplianceScanStatus.DeepCopyInto(&out.ComplianceScanStatus)
 	return
 }
 
@@ -445,6 +693,18 @@ func (in *ComplianceSuiteList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComplianceSuiteSettings) DeepCopyInto(out *ComplianceSuiteSettings) {
 	*out = *in
+	if in.StartingDeadlineSeconds != nil {
+		in, out := &in.StartingDeadlineSeconds, &out.StartingDeadlineSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.RescanTriggers != nil {
+		in, out := &in.RescanTriggers, &out.RescanTriggers
+		*out = make([]RescanTrigger, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -461,7 +721,7 @@ func (in *ComplianceSuiteSettings) DeepCopy() *ComplianceSuiteSettings {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComplianceSuiteSpec) DeepCopyInto(out *ComplianceSuiteSpec) {
 	*out = *in
-	out.ComplianceSuiteSettings = in.ComplianceSuiteSettings
+	in.ComplianceSuiteSettings.DeepCopyInto(&out.ComplianceSuiteSettings)
 	if in.Scans != nil {
 		in, out := &in.Scans, &out.Scans
 		*out = make([]ComplianceScanSpecWrapper, len(*in))
@@ -488,7 +748,9 @@ func (in *ComplianceSuiteStatus) DeepCopyInto(out *ComplianceSuiteStatus) {
 	if in.ScanStatuses != nil {
 		in, out := &in.ScanStatuses, &out.ScanStatuses
 		*out = make([]ComplianceScanStatusWrapper, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
@@ -497,6 +759,25 @@ func (in *ComplianceSuiteStatus) DeepCopyInto(out *ComplianceSuiteStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.RemediationDependencies != nil {
+		in, out := &in.RemediationDependencies, &out.RemediationDependencies
+		*out = new(RemediationDependencyStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastCompletedTime != nil {
+		in, out := &in.LastCompletedTime, &out.LastCompletedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastRescanTrigger != nil {
+		in, out := &in.LastRescanTrigger, &out.LastRescanTrigger
+		*out = new(RescanTriggerStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RemediationVerification != nil {
+		in, out := &in.RemediationVerification, &out.RemediationVerification
+		*out = new(RemediationVerificationStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -511,133 +792,623 @@ func (in *ComplianceSuiteStatus) DeepCopy() *ComplianceSuiteStatus {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *FixDefinition) DeepCopyInto(out *FixDefinition) {
+func (in *ContentHTTPSSource) DeepCopyInto(out *ContentHTTPSSource) {
 	*out = *in
-	if in.FixObject != nil {
-		in, out := &in.FixObject, &out.FixObject
-		*out = (*in).DeepCopy()
-	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FixDefinition.
-func (in *FixDefinition) DeepCopy() *FixDefinition {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContentHTTPSSource.
+func (in *ContentHTTPSSource) DeepCopy() *ContentHTTPSSource {
 	if in == nil {
 		return nil
 	}
-	out := new(FixDefinition)
+	out := new(ContentHTTPSSource)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NamedObjectReference) DeepCopyInto(out *NamedObjectReference) {
+func (in *ContentKeySelector) DeepCopyInto(out *ContentKeySelector) {
 	*out = *in
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamedObjectReference.
-func (in *NamedObjectReference) DeepCopy() *NamedObjectReference {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContentKeySelector.
+func (in *ContentKeySelector) DeepCopy() *ContentKeySelector {
 	if in == nil {
 		return nil
 	}
-	out := new(NamedObjectReference)
+	out := new(ContentKeySelector)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OutputRef) DeepCopyInto(out *OutputRef) {
+func (in *ContentSignatureVerification) DeepCopyInto(out *ContentSignatureVerification) {
 	*out = *in
+	out.KeyConfigMap = in.KeyConfigMap
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OutputRef.
-func (in *OutputRef) DeepCopy() *OutputRef {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContentSignatureVerification.
+func (in *ContentSignatureVerification) DeepCopy() *ContentSignatureVerification {
 	if in == nil {
 		return nil
 	}
-	out := new(OutputRef)
+	out := new(ContentSignatureVerification)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Profile) DeepCopyInto(out *Profile) {
+func (in *ContentSource) DeepCopyInto(out *ContentSource) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.ProfilePayload.DeepCopyInto(&out.ProfilePayload)
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(ContentKeySelector)
+		**out = **in
+	}
+	if in.Secret != nil {
+		in, out := &in.Secret, &out.Secret
+		*out = new(ContentKeySelector)
+		**out = **in
+	}
+	if in.PersistentVolumeClaim != nil {
+		in, out := &in.PersistentVolumeClaim, &out.PersistentVolumeClaim
+		*out = new(ContentVolumeSource)
+		**out = **in
+	}
+	if in.HTTPS != nil {
+		in, out := &in.HTTPS, &out.HTTPS
+		*out = new(ContentHTTPSSource)
+		**out = **in
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Profile.
-func (in *Profile) DeepCopy() *Profile {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContentSource.
+func (in *ContentSource) DeepCopy() *ContentSource {
 	if in == nil {
 		return nil
 	}
-	out := new(Profile)
+	out := new(ContentSource)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Profile) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProfileBundle) DeepCopyInto(out *ProfileBundle) {
+func (in *ContentVersionPin) DeepCopyInto(out *ContentVersionPin) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProfileBundle.
-func (in *ProfileBundle) DeepCopy() *ProfileBundle {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContentVersionPin.
+func (in *ContentVersionPin) DeepCopy() *ContentVersionPin {
 	if in == nil {
 		return nil
 	}
-	out := new(ProfileBundle)
+	out := new(ContentVersionPin)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ProfileBundle) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContentVerification) DeepCopyInto(out *ContentVerification) {
+	*out = *in
+	if in.PublicKey != nil {
+		in, out := &in.PublicKey, &out.PublicKey
+		*out = new(ContentSignatureVerification)
+		**out = **in
 	}
-	return nil
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContentVerification.
+func (in *ContentVerification) DeepCopy() *ContentVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(ContentVerification)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProfileBundleList) DeepCopyInto(out *ProfileBundleList) {
+func (in *ContentVolumeSource) DeepCopyInto(out *ContentVolumeSource) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]ProfileBundle, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProfileBundleList.
-func (in *ProfileBundleList) DeepCopy() *ProfileBundleList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContentVolumeSource.
+func (in *ContentVolumeSource) DeepCopy() *ContentVolumeSource {
 	if in == nil {
 		return nil
 	}
-	out := new(ProfileBundleList)
+	out := new(ContentVolumeSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlCatalog) DeepCopyInto(out *ControlCatalog) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.ControlCatalogPayload.DeepCopyInto(&out.ControlCatalogPayload)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlCatalog.
+func (in *ControlCatalog) DeepCopy() *ControlCatalog {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlCatalog)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ControlCatalog) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlCatalogControl) DeepCopyInto(out *ControlCatalogControl) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlCatalogControl.
+func (in *ControlCatalogControl) DeepCopy() *ControlCatalogControl {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlCatalogControl)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlCatalogList) DeepCopyInto(out *ControlCatalogList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ControlCatalog, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlCatalogList.
+func (in *ControlCatalogList) DeepCopy() *ControlCatalogList {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlCatalogList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ControlCatalogList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlCatalogPayload) DeepCopyInto(out *ControlCatalogPayload) {
+	*out = *in
+	if in.Controls != nil {
+		in, out := &in.Controls, &out.Controls
+		*out = make([]ControlCatalogControl, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlCatalogPayload.
+func (in *ControlCatalogPayload) DeepCopy() *ControlCatalogPayload {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlCatalogPayload)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlFramework) DeepCopyInto(out *ControlFramework) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlFramework.
+func (in *ControlFramework) DeepCopy() *ControlFramework {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlFramework)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlFrameworkCoverage) DeepCopyInto(out *ControlFrameworkCoverage) {
+	*out = *in
+	if in.FailedControlIDs != nil {
+		in, out := &in.FailedControlIDs, &out.FailedControlIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlFrameworkCoverage.
+func (in *ControlFrameworkCoverage) DeepCopy() *ControlFrameworkCoverage {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlFrameworkCoverage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CustomRule) DeepCopyInto(out *CustomRule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomRule.
+func (in *CustomRule) DeepCopy() *CustomRule {
+	if in == nil {
+		return nil
+	}
+	out := new(CustomRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CustomRule) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CustomRuleInput) DeepCopyInto(out *CustomRuleInput) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomRuleInput.
+func (in *CustomRuleInput) DeepCopy() *CustomRuleInput {
+	if in == nil {
+		return nil
+	}
+	out := new(CustomRuleInput)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CustomRuleList) DeepCopyInto(out *CustomRuleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CustomRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomRuleList.
+func (in *CustomRuleList) DeepCopy() *CustomRuleList {
+	if in == nil {
+		return nil
+	}
+	out := new(CustomRuleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CustomRuleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CustomRuleSpec) DeepCopyInto(out *CustomRuleSpec) {
+	*out = *in
+	out.Input = in.Input
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomRuleSpec.
+func (in *CustomRuleSpec) DeepCopy() *CustomRuleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CustomRuleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExtendedProfileReference) DeepCopyInto(out *ExtendedProfileReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExtendedProfileReference.
+func (in *ExtendedProfileReference) DeepCopy() *ExtendedProfileReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ExtendedProfileReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FixDefinition) DeepCopyInto(out *FixDefinition) {
+	*out = *in
+	if in.FixObject != nil {
+		in, out := &in.FixObject, &out.FixObject
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FixDefinition.
+func (in *FixDefinition) DeepCopy() *FixDefinition {
+	if in == nil {
+		return nil
+	}
+	out := new(FixDefinition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamedObjectReference) DeepCopyInto(out *NamedObjectReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamedObjectReference.
+func (in *NamedObjectReference) DeepCopy() *NamedObjectReference {
+	if in == nil {
+		return nil
+	}
+	out := new(NamedObjectReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotificationDelivery) DeepCopyInto(out *NotificationDelivery) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationDelivery.
+func (in *NotificationDelivery) DeepCopy() *NotificationDelivery {
+	if in == nil {
+		return nil
+	}
+	out := new(NotificationDelivery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotifierHMAC) DeepCopyInto(out *NotifierHMAC) {
+	*out = *in
+	out.Secret = in.Secret
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotifierHMAC.
+func (in *NotifierHMAC) DeepCopy() *NotifierHMAC {
+	if in == nil {
+		return nil
+	}
+	out := new(NotifierHMAC)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotifierSecretKeySelector) DeepCopyInto(out *NotifierSecretKeySelector) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotifierSecretKeySelector.
+func (in *NotifierSecretKeySelector) DeepCopy() *NotifierSecretKeySelector {
+	if in == nil {
+		return nil
+	}
+	out := new(NotifierSecretKeySelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotifierTLS) DeepCopyInto(out *NotifierTLS) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotifierTLS.
+func (in *NotifierTLS) DeepCopy() *NotifierTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(NotifierTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OutputRef) DeepCopyInto(out *OutputRef) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OutputRef.
+func (in *OutputRef) DeepCopy() *OutputRef {
+	if in == nil {
+		return nil
+	}
+	out := new(OutputRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Profile) DeepCopyInto(out *Profile) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.ProfilePayload.DeepCopyInto(&out.ProfilePayload)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Profile.
+func (in *Profile) DeepCopy() *Profile {
+	if in == nil {
+		return nil
+	}
+	out := new(Profile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Profile) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProfileBundle) DeepCopyInto(out *ProfileBundle) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProfileBundle.
+func (in *ProfileBundle) DeepCopy() *ProfileBundle {
+	if in == nil {
+		return nil
+	}
+	out := new(ProfileBundle)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProfileBundle) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProfileBundleChangelogSummary) DeepCopyInto(out *ProfileBundleChangelogSummary) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProfileBundleChangelogSummary.
+func (in *ProfileBundleChangelogSummary) DeepCopy() *ProfileBundleChangelogSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(ProfileBundleChangelogSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProfileBundleContentVersion) DeepCopyInto(out *ProfileBundleContentVersion) {
+	*out = *in
+	if in.ParsedTime != nil {
+		in, out := &in.ParsedTime, &out.ParsedTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProfileBundleContentVersion.
+func (in *ProfileBundleContentVersion) DeepCopy() *ProfileBundleContentVersion {
+	if in == nil {
+		return nil
+	}
+	out := new(ProfileBundleContentVersion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProfileBundleList) DeepCopyInto(out *ProfileBundleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ProfileBundle, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProfileBundleList.
+func (in *ProfileBundleList) DeepCopy() *ProfileBundleList {
+	if in == nil {
+		return nil
+	}
+	out := new(ProfileBundleList)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -653,6 +1424,21 @@ func (in *ProfileBundleList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProfileBundleSpec) DeepCopyInto(out *ProfileBundleSpec) {
 	*out = *in
+	if in.ContentSource != nil {
+		in, out := &in.ContentSource, &out.ContentSource
+		*out = new(ContentSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(ContentVerification)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ControlFrameworks != nil {
+		in, out := &in.ControlFrameworks, &out.ControlFrameworks
+		*out = make([]ControlFramework, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -676,6 +1462,23 @@ func (in *ProfileBundleStatus) DeepCopyInto(out *ProfileBundleStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ContentVersion != nil {
+		in, out := &in.ContentVersion, &out.ContentVersion
+		*out = new(ProfileBundleContentVersion)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PreviousContentVersions != nil {
+		in, out := &in.PreviousContentVersions, &out.PreviousContentVersions
+		*out = make([]ProfileBundleContentVersion, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastChangelog != nil {
+		in, out := &in.LastChangelog, &out.LastChangelog
+		*out = new(ProfileBundleChangelogSummary)
+		**out = **in
+	}
 	return
 }
 
@@ -735,6 +1538,21 @@ func (in *ProfilePayload) DeepCopyInto(out *ProfilePayload) {
 		*out = make([]ProfileValue, len(*in))
 		copy(*out, *in)
 	}
+	if in.Extends != nil {
+		in, out := &in.Extends, &out.Extends
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RuleSources != nil {
+		in, out := &in.RuleSources, &out.RuleSources
+		*out = make([]ProfileRuleSource, len(*in))
+		copy(*out, *in)
+	}
+	if in.RefinedValues != nil {
+		in, out := &in.RefinedValues, &out.RefinedValues
+		*out = make([]ProfileRefinedValue, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -748,6 +1566,38 @@ func (in *ProfilePayload) DeepCopy() *ProfilePayload {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProfileRefinedValue) DeepCopyInto(out *ProfileRefinedValue) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProfileRefinedValue.
+func (in *ProfileRefinedValue) DeepCopy() *ProfileRefinedValue {
+	if in == nil {
+		return nil
+	}
+	out := new(ProfileRefinedValue)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProfileRuleSource) DeepCopyInto(out *ProfileRuleSource) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProfileRuleSource.
+func (in *ProfileRuleSource) DeepCopy() *ProfileRuleSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ProfileRuleSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RawResultStorageSettings) DeepCopyInto(out *RawResultStorageSettings) {
 	*out = *in
@@ -788,6 +1638,55 @@ func (in *RawResultStorageSettings) DeepCopy() *RawResultStorageSettings {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemediationDependencyCycle) DeepCopyInto(out *RemediationDependencyCycle) {
+	*out = *in
+	if in.Remediations != nil {
+		in, out := &in.Remediations, &out.Remediations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemediationDependencyCycle.
+func (in *RemediationDependencyCycle) DeepCopy() *RemediationDependencyCycle {
+	if in == nil {
+		return nil
+	}
+	out := new(RemediationDependencyCycle)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemediationDependencyStatus) DeepCopyInto(out *RemediationDependencyStatus) {
+	*out = *in
+	if in.Cycles != nil {
+		in, out := &in.Cycles, &out.Cycles
+		*out = make([]RemediationDependencyCycle, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.UnsatisfiableDependencies != nil {
+		in, out := &in.UnsatisfiableDependencies, &out.UnsatisfiableDependencies
+		*out = make([]UnsatisfiableRemediationDependency, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemediationDependencyStatus.
+func (in *RemediationDependencyStatus) DeepCopy() *RemediationDependencyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RemediationDependencyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RemediationObjectDependencyReference) DeepCopyInto(out *RemediationObjectDependencyReference) {
 	*out = *in
@@ -805,6 +1704,126 @@ func (in *RemediationObjectDependencyReference) DeepCopy() *RemediationObjectDep
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemediationVerificationResult) DeepCopyInto(out *RemediationVerificationResult) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemediationVerificationResult.
+func (in *RemediationVerificationResult) DeepCopy() *RemediationVerificationResult {
+	if in == nil {
+		return nil
+	}
+	out := new(RemediationVerificationResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemediationVerificationStatus) DeepCopyInto(out *RemediationVerificationStatus) {
+	*out = *in
+	if in.Remediations != nil {
+		in, out := &in.Remediations, &out.Remediations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Pools != nil {
+		in, out := &in.Pools, &out.Pools
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RescanTime != nil {
+		in, out := &in.RescanTime, &out.RescanTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Results != nil {
+		in, out := &in.Results, &out.Results
+		*out = make([]RemediationVerificationResult, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemediationVerificationStatus.
+func (in *RemediationVerificationStatus) DeepCopy() *RemediationVerificationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RemediationVerificationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RescanTrigger) DeepCopyInto(out *RescanTrigger) {
+	*out = *in
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RescanTrigger.
+func (in *RescanTrigger) DeepCopy() *RescanTrigger {
+	if in == nil {
+		return nil
+	}
+	out := new(RescanTrigger)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RescanTriggerStatus) DeepCopyInto(out *RescanTriggerStatus) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.Scans != nil {
+		in, out := &in.Scans, &out.Scans
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RescanTriggerStatus.
+func (in *RescanTriggerStatus) DeepCopy() *RescanTriggerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RescanTriggerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResolvedRuleSelections) DeepCopyInto(out *ResolvedRuleSelections) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Disabled != nil {
+		in, out := &in.Disabled, &out.Disabled
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResolvedRuleSelections.
+func (in *ResolvedRuleSelections) DeepCopy() *ResolvedRuleSelections {
+	if in == nil {
+		return nil
+	}
+	out := new(ResolvedRuleSelections)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Rule) DeepCopyInto(out *Rule) {
 	*out = *in
@@ -891,6 +1910,11 @@ func (in *RulePayload) DeepCopy() *RulePayload {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RuleReferenceSpec) DeepCopyInto(out *RuleReferenceSpec) {
 	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(RuleSelectorSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -904,18 +1928,56 @@ func (in *RuleReferenceSpec) DeepCopy() *RuleReferenceSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RuleSelectorSpec) DeepCopyInto(out *RuleSelectorSpec) {
+	*out = *in
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MatchAnnotations != nil {
+		in, out := &in.MatchAnnotations, &out.MatchAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Severities != nil {
+		in, out := &in.Severities, &out.Severities
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuleSelectorSpec.
+func (in *RuleSelectorSpec) DeepCopy() *RuleSelectorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RuleSelectorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScanSetting) DeepCopyInto(out *ScanSetting) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.ComplianceSuiteSettings = in.ComplianceSuiteSettings
+	in.ComplianceSuiteSettings.DeepCopyInto(&out.ComplianceSuiteSettings)
 	in.ComplianceScanSettings.DeepCopyInto(&out.ComplianceScanSettings)
 	if in.Roles != nil {
 		in, out := &in.Roles, &out.Roles
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Alerting != nil {
+		in, out := &in.Alerting, &out.Alerting
+		*out = new(ScanSettingAlerting)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -937,6 +1999,41 @@ func (in *ScanSetting) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScanSettingAlerting) DeepCopyInto(out *ScanSettingAlerting) {
+	*out = *in
+	if in.Results != nil {
+		in, out := &in.Results, &out.Results
+		*out = make([]AlertResult, len(*in))
+		copy(*out, *in)
+	}
+	if in.Severities != nil {
+		in, out := &in.Severities, &out.Severities
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScanSettingAlerting.
+func (in *ScanSettingAlerting) DeepCopy() *ScanSettingAlerting {
+	if in == nil {
+		return nil
+	}
+	out := new(ScanSettingAlerting)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScanSettingBinding) DeepCopyInto(out *ScanSettingBinding) {
 	*out = *in
@@ -952,6 +2049,11 @@ func (in *ScanSettingBinding) DeepCopyInto(out *ScanSettingBinding) {
 		*out = new(NamedObjectReference)
 		**out = **in
 	}
+	if in.ContentVersions != nil {
+		in, out := &in.ContentVersions, &out.ContentVersions
+		*out = make([]ContentVersionPin, len(*in))
+		copy(*out, *in)
+	}
 	in.Status.DeepCopyInto(&out.Status)
 	return
 }
@@ -1022,6 +2124,13 @@ func (in *ScanSettingBindingStatus) DeepCopyInto(out *ScanSettingBindingStatus)
 		*out = new(v1.TypedLocalObjectReference)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ControlCoverage != nil {
+		in, out := &in.ControlCoverage, &out.ControlCoverage
+		*out = make([]ControlFrameworkCoverage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -1090,7 +2199,7 @@ func (in *TailoredProfile) DeepCopyInto(out *TailoredProfile) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -1148,21 +2257,42 @@ func (in *TailoredProfileList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TailoredProfileSpec) DeepCopyInto(out *TailoredProfileSpec) {
 	*out = *in
+	if in.ExtendsProfiles != nil {
+		in, out := &in.ExtendsProfiles, &out.ExtendsProfiles
+		*out = make([]ExtendedProfileReference, len(*in))
+		copy(*out, *in)
+	}
 	if in.EnableRules != nil {
 		in, out := &in.EnableRules, &out.EnableRules
 		*out = make([]RuleReferenceSpec, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.DisableRules != nil {
 		in, out := &in.DisableRules, &out.DisableRules
 		*out = make([]RuleReferenceSpec, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.SetValues != nil {
 		in, out := &in.SetValues, &out.SetValues
 		*out = make([]VariableValueSpec, len(*in))
 		copy(*out, *in)
 	}
+	if in.CustomRules != nil {
+		in, out := &in.CustomRules, &out.CustomRules
+		*out = make([]RuleReferenceSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ImportTailoring != nil {
+		in, out := &in.ImportTailoring, &out.ImportTailoring
+		*out = new(TailoringImportSource)
+		**out = **in
+	}
 	return
 }
 
@@ -1180,6 +2310,11 @@ func (in *TailoredProfileSpec) DeepCopy() *TailoredProfileSpec {
 func (in *TailoredProfileStatus) DeepCopyInto(out *TailoredProfileStatus) {
 	*out = *in
 	out.OutputRef = in.OutputRef
+	if in.ResolvedRules != nil {
+		in, out := &in.ResolvedRules, &out.ResolvedRules
+		*out = new(ResolvedRuleSelections)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -1193,6 +2328,22 @@ func (in *TailoredProfileStatus) DeepCopy() *TailoredProfileStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TailoringImportSource) DeepCopyInto(out *TailoringImportSource) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TailoringImportSource.
+func (in *TailoringImportSource) DeepCopy() *TailoringImportSource {
+	if in == nil {
+		return nil
+	}
+	out := new(TailoringImportSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TailoringConfigMapRef) DeepCopyInto(out *TailoringConfigMapRef) {
 	*out = *in
@@ -1209,6 +2360,22 @@ func (in *TailoringConfigMapRef) DeepCopy() *TailoringConfigMapRef {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UnsatisfiableRemediationDependency) DeepCopyInto(out *UnsatisfiableRemediationDependency) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UnsatisfiableRemediationDependency.
+func (in *UnsatisfiableRemediationDependency) DeepCopy() *UnsatisfiableRemediationDependency {
+	if in == nil {
+		return nil
+	}
+	out := new(UnsatisfiableRemediationDependency)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ValueSelection) DeepCopyInto(out *ValueSelection) {
 	*out = *in
@@ -0,0 +1,10 @@
+package controller
+
+import (
+	"github.com/openshift/compliance-operator/pkg/controller/rescantrigger"
+)
+
+func init() {
+	// AddToManagerFuncs is a list of functions to create controllers and add them to a manager.
+	AddToManagerFuncs = append(AddToManagerFuncs, rescantrigger.Add)
+}
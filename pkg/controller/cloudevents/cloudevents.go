@@ -0,0 +1,377 @@
+package cloudevents
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+var log = logf.Log.WithName("cloudevents")
+
+// The types of the events emitted
+const (
+	// EventTypeScanPhase is emitted when a scan changes phase
+	EventTypeScanPhase = "io.openshift.compliance.scan.phase"
+	// EventTypeSuiteResult is emitted when a run of a suite finishes
+	EventTypeSuiteResult = "io.openshift.compliance.suite.result"
+	// EventTypeCheckStatus is emitted when the status of a check result
+	// changes, or a check result is created
+	EventTypeCheckStatus = "io.openshift.compliance.check.status"
+	// EventTypeRemediationState is emitted when the application state of a
+	// remediation changes
+	EventTypeRemediationState = "io.openshift.compliance.remediation.state"
+)
+
+// AllEventTypes are the types of the events that can be emitted
+var AllEventTypes = []string{
+	EventTypeScanPhase,
+	EventTypeSuiteResult,
+	EventTypeCheckStatus,
+	EventTypeRemediationState,
+}
+
+const (
+	// DefaultBufferSize is the number of events that are kept while the sink
+	// can't be reached unless set otherwise
+	DefaultBufferSize = 1000
+	specVersion       = "1.0"
+	// The time an attempt to send an event can take
+	requestTimeout = 10 * time.Second
+	// The time spent retrying an event before dropping it
+	maxRetryTime = time.Minute
+)
+
+// Options configure where, and which, events are emitted
+type Options struct {
+	// The URL the events are posted to. No event is emitted if it's empty.
+	Sink string
+	// The types of the events emitted, all of them if empty
+	Types []string
+	// The number of events kept while they wait to be sent. The events
+	// emitted while the buffer is full are dropped.
+	BufferSize int
+}
+
+// Data is the payload of the events
+type Data struct {
+	// The object the event is about
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// The suite and the scan of the object, if any
+	Suite string `json:"suite,omitempty"`
+	Scan  string `json:"scan,omitempty"`
+	// The rule and severity of a check result
+	Rule     string `json:"rule,omitempty"`
+	Severity string `json:"severity,omitempty"`
+	// The phase, result, status or application state the object is in
+	// now, and the one it was in before, if it was known
+	State         string `json:"state"`
+	PreviousState string `json:"previousState,omitempty"`
+	// The result of a scan or suite
+	Result string `json:"result,omitempty"`
+	// The error message of the object, if any
+	ErrorMessage string `json:"errorMessage,omitempty"`
+}
+
+type event struct {
+	id      string
+	typ     string
+	source  string
+	subject string
+	time    time.Time
+	data    Data
+}
+
+// Emitter emits CloudEvents on the transitions of the scans, suites, check
+// results and remediations, using the HTTP binding in binary mode. The
+// events are buffered and sent in the background, so a sink that's down
+// never blocks the reconciliations. A nil Emitter doesn't emit anything.
+//
+// The Emitter keeps no state about the objects: the controllers pass the
+// state an object was in before they updated it, so that the transitions
+// are found the same way before and after the operator restarts.
+type Emitter struct {
+	sink   string
+	types  map[string]bool
+	queue  chan event
+	client *http.Client
+	log    logr.Logger
+	// Returns the policy to retry an event with
+	newBackOff func() backoff.BackOff
+
+	mutex sync.Mutex
+	// Whether events are being dropped, to log when it starts and stops
+	dropping bool
+	dropped  int
+	// The events buffered or being sent
+	pending sync.WaitGroup
+}
+
+// NewEmitter returns an Emitter with the given options, or nil if they
+// don't set a sink
+func NewEmitter(opts Options) (*Emitter, error) {
+	if opts.Sink == "" {
+		return nil, nil
+	}
+	types := map[string]bool{}
+	for _, t := range opts.Types {
+		if !isEventType(t) {
+			return nil, fmt.Errorf("unknown event type %s, expected one of %v", t, AllEventTypes)
+		}
+		types[t] = true
+	}
+	if len(types) == 0 {
+		for _, t := range AllEventTypes {
+			types[t] = true
+		}
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultBufferSize
+	}
+	return &Emitter{
+		sink:  opts.Sink,
+		types: types,
+		queue: make(chan event, opts.BufferSize),
+		client: &http.Client{
+			Timeout: requestTimeout,
+		},
+		log: log,
+		newBackOff: func() backoff.BackOff {
+			b := backoff.NewExponentialBackOff()
+			b.MaxElapsedTime = maxRetryTime
+			return b
+		},
+	}, nil
+}
+
+func isEventType(t string) bool {
+	for _, known := range AllEventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Start sends the buffered events until the stop channel is closed. It
+// implements the Runnable interface of the manager.
+func (e *Emitter) Start(stop <-chan struct{}) error {
+	e.log.Info("Starting to emit CloudEvents", "sink", e.sink)
+	// Stops retrying the event being sent too
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev := <-e.queue:
+			e.send(ctx, ev)
+			e.pending.Done()
+		}
+	}
+}
+
+// Sink returns the URL the events are posted to, or an empty string if the
+// Emitter is nil
+func (e *Emitter) Sink() string {
+	if e == nil {
+		return ""
+	}
+	return e.sink
+}
+
+// Emits returns whether the events of the given type are emitted
+func (e *Emitter) Emits(typ string) bool {
+	return e != nil && e.types[typ]
+}
+
+// Flush waits for the buffered events to be sent, or dropped after their
+// retries, for at most the given timeout. It returns whether they all were.
+// It's meant for the processes that exit once they're done, like the
+// aggregator, while the Emitter is started.
+func (e *Emitter) Flush(timeout time.Duration) bool {
+	if e == nil {
+		return true
+	}
+	done := make(chan struct{})
+	go func() {
+		e.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// ObserveScan emits an event if the scan changed phase from the previous
+// one, which is the phase it was in before its status was updated
+func (e *Emitter) ObserveScan(previous compv1alpha1.ComplianceScanStatusPhase, scan *compv1alpha1.ComplianceScan) {
+	if e == nil || !e.types[EventTypeScanPhase] || previous == scan.Status.Phase {
+		return
+	}
+	e.emit(EventTypeScanPhase, "compliancescans", &scan.ObjectMeta, Data{
+		Kind:          "ComplianceScan",
+		Suite:         scan.Labels[compv1alpha1.SuiteLabel],
+		State:         string(scan.Status.Phase),
+		PreviousState: string(previous),
+		Result:        string(scan.Status.Result),
+		ErrorMessage:  scan.Status.ErrorMessage,
+	})
+}
+
+// ObserveSuite emits an event if a run of the suite finished, that is, if
+// it's done and the previous phase wasn't
+func (e *Emitter) ObserveSuite(previous compv1alpha1.ComplianceScanStatusPhase, suite *compv1alpha1.ComplianceSuite) {
+	if e == nil || !e.types[EventTypeSuiteResult] {
+		return
+	}
+	if suite.Status.Phase != compv1alpha1.PhaseDone || previous == compv1alpha1.PhaseDone {
+		return
+	}
+	e.emit(EventTypeSuiteResult, "compliancesuites", &suite.ObjectMeta, Data{
+		Kind:         "ComplianceSuite",
+		Suite:        suite.Name,
+		State:        string(suite.Status.Result),
+		Result:       string(suite.Status.Result),
+		ErrorMessage: suite.Status.ErrorMessage,
+	})
+}
+
+// ObserveCheckResult emits an event if the check result of the scan is new,
+// that is if its previous status isn't known, or if its status changed from
+// the previous one. The check result doesn't need to exist in the cluster, so
+// that the ones that aren't retained are observed too.
+func (e *Emitter) ObserveCheckResult(scan *compv1alpha1.ComplianceScan, check *compv1alpha1.ComplianceCheckResult,
+	previous compv1alpha1.ComplianceCheckStatus, known bool) {
+	if e == nil || !e.types[EventTypeCheckStatus] || (known && previous == check.Status) {
+		return
+	}
+	e.emit(EventTypeCheckStatus, "compliancecheckresults", &check.ObjectMeta, Data{
+		Kind:          "ComplianceCheckResult",
+		Suite:         scan.Labels[compv1alpha1.SuiteLabel],
+		Scan:          scan.Name,
+		Rule:          check.Annotations[compv1alpha1.ComplianceCheckResultRuleAnnotation],
+		Severity:      string(check.Severity),
+		State:         string(check.Status),
+		PreviousState: string(previous),
+	})
+}
+
+// ObserveRemediation emits an event if the application state of the
+// remediation changed from the previous one
+func (e *Emitter) ObserveRemediation(previous compv1alpha1.RemediationApplicationState, rem *compv1alpha1.ComplianceRemediation) {
+	if e == nil || !e.types[EventTypeRemediationState] || previous == rem.Status.ApplicationState {
+		return
+	}
+	e.emit(EventTypeRemediationState, "complianceremediations", &rem.ObjectMeta, Data{
+		Kind:          "ComplianceRemediation",
+		Suite:         rem.Labels[compv1alpha1.SuiteLabel],
+		Scan:          rem.Labels[compv1alpha1.ComplianceScanLabel],
+		State:         string(rem.Status.ApplicationState),
+		PreviousState: string(previous),
+		ErrorMessage:  rem.Status.ErrorMessage,
+	})
+}
+
+// emit buffers an event, or drops it if the buffer is full
+func (e *Emitter) emit(typ, resource string, obj *metav1.ObjectMeta, data Data) {
+	data.Name = obj.Name
+	data.Namespace = obj.Namespace
+	ev := event{
+		id:   newID(),
+		typ:  typ,
+		time: time.Now(),
+		source: fmt.Sprintf("/apis/%s/namespaces/%s/%s",
+			compv1alpha1.SchemeGroupVersion, obj.Namespace, resource),
+		subject: obj.Name,
+		data:    data,
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.pending.Add(1)
+	select {
+	case e.queue <- ev:
+		if e.dropping {
+			e.log.Info("Resumed buffering CloudEvents", "dropped", e.dropped)
+			e.dropping = false
+			e.dropped = 0
+		}
+	default:
+		e.pending.Done()
+		if !e.dropping {
+			e.log.Info("The CloudEvents buffer is full, dropping events until the sink catches up")
+			e.dropping = true
+		}
+		e.dropped++
+	}
+}
+
+// send posts an event to the sink, retrying with a backoff while it can't be
+// reached or answers with a server error
+func (e *Emitter) send(ctx context.Context, ev event) {
+	body, err := json.Marshal(ev.data)
+	if err != nil {
+		e.log.Error(err, "Cannot marshal the CloudEvent", "type", ev.typ)
+		return
+	}
+	b := backoff.WithContext(e.newBackOff(), ctx)
+	err = backoff.Retry(func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.sink, bytes.NewReader(body))
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("ce-specversion", specVersion)
+		req.Header.Set("ce-id", ev.id)
+		req.Header.Set("ce-type", ev.typ)
+		req.Header.Set("ce-source", ev.source)
+		req.Header.Set("ce-subject", ev.subject)
+		req.Header.Set("ce-time", ev.time.UTC().Format(time.RFC3339Nano))
+		resp, err := e.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		switch {
+		case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+			return fmt.Errorf("the sink answered with %s", resp.Status)
+		case resp.StatusCode >= 300:
+			return backoff.Permanent(fmt.Errorf("the sink answered with %s", resp.Status))
+		}
+		return nil
+	}, b)
+	if err != nil {
+		e.log.Info("Couldn't send the CloudEvent", "type", ev.typ, "subject", ev.subject, "error", err.Error())
+	}
+}
+
+func newID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,244 @@
+package cloudevents
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+const testNamespace = "test-ns"
+
+// sink records the events it receives, answering the first ones with the
+// given status codes and the rest with 202
+type sink struct {
+	sync.Mutex
+	codes    []int
+	headers  []http.Header
+	payloads []Data
+	received chan struct{}
+}
+
+func newSink(codes ...int) *sink {
+	return &sink{codes: codes, received: make(chan struct{}, 100)}
+}
+
+func (s *sink) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	code := http.StatusAccepted
+	if len(s.codes) > 0 {
+		code, s.codes = s.codes[0], s.codes[1:]
+	}
+	if code < 300 {
+		body, _ := ioutil.ReadAll(req.Body)
+		data := Data{}
+		_ = json.Unmarshal(body, &data)
+		s.headers = append(s.headers, req.Header)
+		s.payloads = append(s.payloads, data)
+		s.received <- struct{}{}
+	}
+	rw.WriteHeader(code)
+}
+
+func (s *sink) wait(t *testing.T, n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-s.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d events, expected %d", i, n)
+		}
+	}
+}
+
+func newTestEmitter(t *testing.T, url string, types ...string) *Emitter {
+	e, err := NewEmitter(Options{Sink: url, Types: types})
+	require.NoError(t, err)
+	e.newBackOff = func() backoff.BackOff {
+		return backoff.WithMaxRetries(&backoff.ZeroBackOff{}, 3)
+	}
+	return e
+}
+
+func start(e *Emitter) func() {
+	stop := make(chan struct{})
+	go func() {
+		_ = e.Start(stop)
+	}()
+	return func() {
+		close(stop)
+	}
+}
+
+func newMeta(name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: testNamespace,
+		Labels:    map[string]string{compv1alpha1.SuiteLabel: "suite"},
+	}
+}
+
+func TestNewEmitter(t *testing.T) {
+	e, err := NewEmitter(Options{})
+	require.NoError(t, err)
+	require.Nil(t, e)
+	// A nil emitter emits nothing
+	e.ObserveScan("", &compv1alpha1.ComplianceScan{})
+
+	_, err = NewEmitter(Options{Sink: "http://sink", Types: []string{"io.openshift.compliance.unknown"}})
+	require.Error(t, err)
+}
+
+func TestScanPhaseEvents(t *testing.T) {
+	s := newSink(http.StatusServiceUnavailable)
+	server := httptest.NewServer(s)
+	defer server.Close()
+	e := newTestEmitter(t, server.URL)
+	defer start(e)()
+
+	scan := &compv1alpha1.ComplianceScan{ObjectMeta: newMeta("scan")}
+	scan.Status.Phase = compv1alpha1.PhasePending
+	e.ObserveScan("", scan)
+	// The phase didn't change
+	e.ObserveScan(compv1alpha1.PhasePending, scan)
+	scan.Status.Phase = compv1alpha1.PhaseLaunching
+	e.ObserveScan(compv1alpha1.PhasePending, scan)
+	s.wait(t, 2)
+
+	s.Lock()
+	defer s.Unlock()
+	require.Len(t, s.payloads, 2)
+	require.Equal(t, "PENDING", s.payloads[0].State)
+	require.Equal(t, "", s.payloads[0].PreviousState)
+	require.Equal(t, "LAUNCHING", s.payloads[1].State)
+	require.Equal(t, "PENDING", s.payloads[1].PreviousState)
+	require.Equal(t, "suite", s.payloads[1].Suite)
+
+	headers := s.headers[1]
+	require.Equal(t, "1.0", headers.Get("ce-specversion"))
+	require.Equal(t, EventTypeScanPhase, headers.Get("ce-type"))
+	require.Equal(t, "/apis/compliance.openshift.io/v1alpha1/namespaces/test-ns/compliancescans", headers.Get("ce-source"))
+	require.Equal(t, "scan", headers.Get("ce-subject"))
+	require.NotEmpty(t, headers.Get("ce-id"))
+	require.NotEqual(t, s.headers[0].Get("ce-id"), headers.Get("ce-id"))
+	require.Equal(t, "application/json", headers.Get("Content-Type"))
+}
+
+func newCheck(name string, status compv1alpha1.ComplianceCheckStatus, annotations map[string]string) compv1alpha1.ComplianceCheckResult {
+	check := compv1alpha1.ComplianceCheckResult{ObjectMeta: newMeta(name), Status: status}
+	check.Annotations = annotations
+	return check
+}
+
+func TestCheckStatusEvents(t *testing.T) {
+	s := newSink()
+	server := httptest.NewServer(s)
+	defer server.Close()
+	e := newTestEmitter(t, server.URL, EventTypeCheckStatus)
+	defer start(e)()
+
+	scan := &compv1alpha1.ComplianceScan{ObjectMeta: newMeta("scan")}
+	created := newCheck("scan-created", compv1alpha1.CheckResultFail, map[string]string{
+		compv1alpha1.ComplianceCheckResultRuleAnnotation: "created",
+	})
+	e.ObserveCheckResult(scan, &created, "", false)
+	unchanged := newCheck("scan-unchanged", compv1alpha1.CheckResultPass, nil)
+	e.ObserveCheckResult(scan, &unchanged, compv1alpha1.CheckResultPass, true)
+	changed := newCheck("scan-changed", compv1alpha1.CheckResultFail, nil)
+	e.ObserveCheckResult(scan, &changed, compv1alpha1.CheckResultPass, true)
+	// Scans aren't emitted, as their type isn't enabled
+	e.ObserveScan("", scan)
+	s.wait(t, 2)
+
+	s.Lock()
+	defer s.Unlock()
+	require.Len(t, s.payloads, 2)
+	require.Equal(t, "scan-created", s.payloads[0].Name)
+	require.Equal(t, "created", s.payloads[0].Rule)
+	require.Equal(t, "FAIL", s.payloads[0].State)
+	require.Equal(t, "", s.payloads[0].PreviousState)
+	require.Equal(t, "scan-changed", s.payloads[1].Name)
+	require.Equal(t, "PASS", s.payloads[1].PreviousState)
+	require.Equal(t, "FAIL", s.payloads[1].State)
+	require.Equal(t, "scan", s.payloads[1].Scan)
+	require.Equal(t, "suite", s.payloads[1].Suite)
+}
+
+func TestFlush(t *testing.T) {
+	s := newSink(http.StatusServiceUnavailable)
+	server := httptest.NewServer(s)
+	defer server.Close()
+	e := newTestEmitter(t, server.URL)
+
+	scan := &compv1alpha1.ComplianceScan{ObjectMeta: newMeta("scan")}
+	scan.Status.Phase = compv1alpha1.PhaseDone
+	e.ObserveScan(compv1alpha1.PhaseAggregating, scan)
+	// Nothing sends the event until the emitter is started
+	require.False(t, e.Flush(10*time.Millisecond))
+
+	defer start(e)()
+	require.True(t, e.Flush(5*time.Second))
+	s.Lock()
+	defer s.Unlock()
+	require.Len(t, s.payloads, 1)
+}
+
+func TestSuiteAndRemediationEvents(t *testing.T) {
+	s := newSink()
+	server := httptest.NewServer(s)
+	defer server.Close()
+	e := newTestEmitter(t, server.URL)
+	defer start(e)()
+
+	suite := &compv1alpha1.ComplianceSuite{ObjectMeta: newMeta("suite")}
+	suite.Status.Phase = compv1alpha1.PhaseRunning
+	e.ObserveSuite(compv1alpha1.PhaseLaunching, suite)
+	suite.Status.Phase = compv1alpha1.PhaseDone
+	suite.Status.Result = compv1alpha1.ResultNonCompliant
+	e.ObserveSuite(compv1alpha1.PhaseRunning, suite)
+	// The run already finished
+	e.ObserveSuite(compv1alpha1.PhaseDone, suite)
+
+	rem := &compv1alpha1.ComplianceRemediation{ObjectMeta: newMeta("rem")}
+	rem.Status.ApplicationState = compv1alpha1.RemediationError
+	rem.Status.ErrorMessage = "boom"
+	e.ObserveRemediation(compv1alpha1.RemediationError, rem)
+	e.ObserveRemediation(compv1alpha1.RemediationPending, rem)
+	s.wait(t, 2)
+
+	s.Lock()
+	defer s.Unlock()
+	require.Len(t, s.payloads, 2)
+	require.Equal(t, EventTypeSuiteResult, s.headers[0].Get("ce-type"))
+	require.Equal(t, "NON-COMPLIANT", s.payloads[0].Result)
+	require.Equal(t, EventTypeRemediationState, s.headers[1].Get("ce-type"))
+	require.Equal(t, "Error", s.payloads[1].State)
+	require.Equal(t, "Pending", s.payloads[1].PreviousState)
+	require.Equal(t, "boom", s.payloads[1].ErrorMessage)
+}
+
+func TestFullBufferDropsEvents(t *testing.T) {
+	e, err := NewEmitter(Options{Sink: "http://sink.invalid", BufferSize: 2})
+	require.NoError(t, err)
+
+	// The emitter isn't started, so the events aren't sent and the ones
+	// that don't fit in the buffer are dropped without blocking
+	for i := 0; i < 5; i++ {
+		rem := &compv1alpha1.ComplianceRemediation{ObjectMeta: newMeta("rem")}
+		rem.Name = rem.Name + string(rune('a'+i))
+		rem.Status.ApplicationState = compv1alpha1.RemediationApplied
+		e.ObserveRemediation(compv1alpha1.RemediationPending, rem)
+	}
+	require.Len(t, e.queue, 2)
+	require.True(t, e.dropping)
+	require.Equal(t, 3, e.dropped)
+}
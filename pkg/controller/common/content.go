@@ -0,0 +1,179 @@
+package common
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/xccdf"
+)
+
+// GetPinnedContent returns the profile, rule or variable of the given kind
+// as it was parsed from the content image with the given digest. That's
+// either the current object, if it didn't change since, or the copy that
+// was archived when it did. Archived copies are returned the way they were
+// parsed: under the original name and labeled as owned by their bundle. If
+// there's neither, a NotFound error is returned.
+func GetPinnedContent(c client.Client, kind string, key types.NamespacedName, digest string) (*unstructured.Unstructured, error) {
+	current := newContentObject(kind)
+	err := c.Get(context.TODO(), key, current)
+	if err == nil && !compv1alpha1.IsArchivedContent(current) && compv1alpha1.IsParsedFromContentDigest(current, digest) {
+		return current, nil
+	} else if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	// The copy is named after the digest when it was archived right after
+	// the pinned content version
+	archived := newContentObject(kind)
+	archivedKey := types.NamespacedName{
+		Namespace: key.Namespace,
+		Name:      compv1alpha1.GetArchivedContentName(key.Name, digest),
+	}
+	err = c.Get(context.TODO(), archivedKey, archived)
+	if err == nil && isArchivedCopy(archived, key.Name, digest) {
+		return restoreArchivedContent(archived), nil
+	} else if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	// Otherwise it was archived after later content versions that didn't
+	// change it
+	list := newContentList(kind)
+	inNs := client.InNamespace(key.Namespace)
+	if err := c.List(context.TODO(), list, inNs, client.HasLabels{compv1alpha1.ArchivedContentLabel}); err != nil {
+		return nil, err
+	}
+	for i := range list.Items {
+		if isArchivedCopy(&list.Items[i], key.Name, digest) {
+			return restoreArchivedContent(&list.Items[i]), nil
+		}
+	}
+
+	return nil, errors.NewNotFound(schema.GroupResource{
+		Group:    compv1alpha1.SchemeGroupVersion.Group,
+		Resource: kind,
+	}, key.Name)
+}
+
+// ListPinnedContent lists the profiles, rules or variables of the given kind
+// as they were parsed from the content image with the given digest, see
+// GetPinnedContent. If pbName is empty, the content of all the bundles is
+// listed.
+func ListPinnedContent(c client.Client, kind, namespace, pbName, digest string) ([]unstructured.Unstructured, error) {
+	current := newContentList(kind)
+	if err := c.List(context.TODO(), current, client.InNamespace(namespace), withBundleLabel(compv1alpha1.ProfileBundleOwnerLabel, pbName)); err != nil {
+		return nil, err
+	}
+	archived := newContentList(kind)
+	if err := c.List(context.TODO(), archived, client.InNamespace(namespace), withBundleLabel(compv1alpha1.ArchivedContentLabel, pbName)); err != nil {
+		return nil, err
+	}
+
+	var items []unstructured.Unstructured
+	seen := make(map[string]bool)
+	for i := range current.Items {
+		item := &current.Items[i]
+		if compv1alpha1.IsParsedFromContentDigest(item, digest) {
+			items = append(items, *item)
+			seen[item.GetName()] = true
+		}
+	}
+	for i := range archived.Items {
+		item := &archived.Items[i]
+		name := item.GetAnnotations()[compv1alpha1.ArchivedFromAnnotation]
+		if seen[name] || !isArchivedCopy(item, name, digest) {
+			continue
+		}
+		items = append(items, *restoreArchivedContent(item))
+		seen[name] = true
+	}
+	return items, nil
+}
+
+// GetBundleContent lists the current profiles, rules and variables of a
+// bundle, keyed by XCCDF ID. Archived copies of previous content versions
+// don't carry the owner label and are left out.
+func GetBundleContent(c client.Client, bundle, namespace string) (*xccdf.BundleContent, error) {
+	inBundle := client.MatchingLabels{compv1alpha1.ProfileBundleOwnerLabel: bundle}
+	content := &xccdf.BundleContent{
+		Profiles:  map[string]*compv1alpha1.Profile{},
+		Rules:     map[string]*compv1alpha1.Rule{},
+		Variables: map[string]*compv1alpha1.Variable{},
+	}
+
+	profiles := compv1alpha1.ProfileList{}
+	if err := c.List(context.TODO(), &profiles, client.InNamespace(namespace), inBundle); err != nil {
+		return nil, err
+	}
+	for i := range profiles.Items {
+		content.Profiles[profiles.Items[i].ID] = &profiles.Items[i]
+	}
+
+	rules := compv1alpha1.RuleList{}
+	if err := c.List(context.TODO(), &rules, client.InNamespace(namespace), inBundle); err != nil {
+		return nil, err
+	}
+	for i := range rules.Items {
+		content.Rules[rules.Items[i].ID] = &rules.Items[i]
+	}
+
+	variables := compv1alpha1.VariableList{}
+	if err := c.List(context.TODO(), &variables, client.InNamespace(namespace), inBundle); err != nil {
+		return nil, err
+	}
+	for i := range variables.Items {
+		content.Variables[variables.Items[i].ID] = &variables.Items[i]
+	}
+	return content, nil
+}
+
+func withBundleLabel(label, pbName string) client.ListOption {
+	if pbName == "" {
+		return client.HasLabels{label}
+	}
+	return client.MatchingLabels{label: pbName}
+}
+
+func isArchivedCopy(obj *unstructured.Unstructured, name, digest string) bool {
+	annotations := obj.GetAnnotations()
+	if obj.GetLabels()[compv1alpha1.ArchivedContentLabel] == "" || annotations[compv1alpha1.ArchivedFromAnnotation] != name {
+		return false
+	}
+	for _, d := range compv1alpha1.GetContentDigests(obj) {
+		if d == digest {
+			return true
+		}
+	}
+	return false
+}
+
+func restoreArchivedContent(archived *unstructured.Unstructured) *unstructured.Unstructured {
+	obj := archived.DeepCopy()
+	labels := obj.GetLabels()
+	labels[compv1alpha1.ProfileBundleOwnerLabel] = labels[compv1alpha1.ArchivedContentLabel]
+	delete(labels, compv1alpha1.ArchivedContentLabel)
+	obj.SetLabels(labels)
+	annotations := obj.GetAnnotations()
+	obj.SetName(annotations[compv1alpha1.ArchivedFromAnnotation])
+	delete(annotations, compv1alpha1.ArchivedFromAnnotation)
+	obj.SetAnnotations(annotations)
+	return obj
+}
+
+func newContentObject(kind string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(compv1alpha1.SchemeGroupVersion.WithKind(kind))
+	return obj
+}
+
+func newContentList(kind string) *unstructured.UnstructuredList {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(compv1alpha1.SchemeGroupVersion.WithKind(kind + "List"))
+	return list
+}
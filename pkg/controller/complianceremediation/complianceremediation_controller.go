@@ -28,7 +28,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/cloudevents"
 	"github.com/openshift/compliance-operator/pkg/controller/metrics"
+	"github.com/openshift/compliance-operator/pkg/controller/notifier"
+	"github.com/openshift/compliance-operator/pkg/controller/shared"
 )
 
 const ctrlName = "remediationctrl"
@@ -42,15 +45,17 @@ const (
 
 // Add creates a new ComplianceRemediation Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
-func Add(mgr manager.Manager, met *metrics.Metrics, _ utils.CtlplaneSchedulingInfo) error {
-	return add(mgr, newReconciler(mgr, met))
+func Add(mgr manager.Manager, deps shared.Dependencies) error {
+	return add(mgr, newReconciler(mgr, deps.Metrics, deps.Notifier, deps.Events))
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager, met *metrics.Metrics) reconcile.Reconciler {
+func newReconciler(mgr manager.Manager, met *metrics.Metrics, n *notifier.Notifier, events *cloudevents.Emitter) reconcile.Reconciler {
 	return &ReconcileComplianceRemediation{client: mgr.GetClient(), scheme: mgr.GetScheme(),
 		recorder: common.NewSafeRecorder(ctrlName, mgr),
 		metrics:  met,
+		events:   events,
+		notifier: n,
 	}
 }
 
@@ -68,6 +73,12 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// The objects created by remediations are watched once they are
+	// applied, as their kinds might not exist on every cluster
+	if remReconciler, ok := r.(*ReconcileComplianceRemediation); ok {
+		remReconciler.watcher = newRemediatedObjectWatcher(c, mgr.GetClient(), mgr.GetRESTMapper())
+	}
+
 	return nil
 }
 
@@ -82,6 +93,9 @@ type ReconcileComplianceRemediation struct {
 	scheme   *runtime.Scheme
 	recorder record.EventRecorder
 	metrics  *metrics.Metrics
+	events   *cloudevents.Emitter
+	watcher  *remediatedObjectWatcher
+	notifier *notifier.Notifier
 }
 
 // Reconcile reads that state of the cluster for a ComplianceRemediation object and makes changes based on the state read
@@ -100,12 +114,16 @@ func (r *ReconcileComplianceRemediation) Reconcile(request reconcile.Request) (r
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
+			r.metrics.DeleteComplianceRemediationState(request.Namespace, request.Name)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
 		reqLogger.Error(getErr, "Cannot retrieve remediation")
 		return reconcile.Result{}, getErr
 	}
+	// Also counts the remediations that were last updated by a previous
+	// instance of the operator
+	r.metrics.SetComplianceRemediationState(remediationInstance)
 
 	if remediationInstance.Spec.Type == "" {
 		reqLogger.Info("Updating remediation due to missing type")
@@ -127,6 +145,19 @@ func (r *ReconcileComplianceRemediation) Reconcile(request reconcile.Request) (r
 			return reconcile.Result{}, fmt.Errorf("updating default remediation application state: %s", updErr)
 		}
 		r.metrics.IncComplianceRemediationStatus(rCopy.Name, rCopy.Status)
+		r.events.ObserveRemediation(remediationInstance.Status.ApplicationState, rCopy)
+		r.metrics.SetComplianceRemediationState(rCopy)
+		return reconcile.Result{}, nil
+	}
+	if needsObservedGenerationBackfill(remediationInstance) {
+		// Remediations applied by an operator version that didn't record
+		// the observed generation are assumed to be applied as they are
+		reqLogger.Info("Updating remediation due to missing observed generation")
+		rCopy := remediationInstance.DeepCopy()
+		rCopy.Status.ObservedGeneration = rCopy.GetGeneration()
+		if updErr := r.client.Status().Update(context.TODO(), rCopy); updErr != nil {
+			return reconcile.Result{}, fmt.Errorf("updating remediation observed generation: %s", updErr)
+		}
 		return reconcile.Result{}, nil
 	}
 	if isNoLongerOutdated(remediationInstance) {
@@ -140,6 +171,10 @@ func (r *ReconcileComplianceRemediation) Reconcile(request reconcile.Request) (r
 		return reconcile.Result{}, nil
 	}
 
+	if remediationInstance.IsManagedExternally() {
+		return r.reconcileManagedExternally(remediationInstance, reqLogger)
+	}
+
 	if remediationInstance.Spec.Current.Object == nil {
 		err := fmt.Errorf("No remediation specified. spec.object is empty")
 		return common.ReturnWithRetriableError(reqLogger, common.WrapNonRetriableCtrlError(err))
@@ -166,13 +201,14 @@ func (r *ReconcileComplianceRemediation) Reconcile(request reconcile.Request) (r
 		}
 	}
 	//if no UnmetDependencies, UnsetValue, ValueRequired
-	if !(remediationInstance.HasUnmetDependencies() || remediationInstance.HasAnnotation(compv1alpha1.RemediationUnsetValueAnnotation) || remediationInstance.HasAnnotation(compv1alpha1.RemediationValueRequiredAnnotation)) {
+	if canBeReconciled(remediationInstance) {
 		reconcileErr = r.reconcileRemediation(remediationInstance, reqLogger)
 	}
 
 	// this would have been much nicer with go 1.13 using errors.Is()
 	// Only return if the error is retriable. Else, we persist it in the status
-	if reconcileErr != nil && common.IsRetriable(reconcileErr) {
+	// Drift is persisted in the status, too
+	if reconcileErr != nil && !isDriftError(reconcileErr) && common.IsRetriable(reconcileErr) {
 		return common.ReturnWithRetriableError(reqLogger, reconcileErr)
 	}
 
@@ -187,10 +223,45 @@ func (r *ReconcileComplianceRemediation) Reconcile(request reconcile.Request) (r
 		reqLogger.Info("Has unmet kubernetes object dependencies. Requeuing")
 		return reconcile.Result{Requeue: true, RequeueAfter: defaultDependencyRequeueTime}, nil
 	}
+	if remediationInstance.Spec.Apply && (reconcileErr == nil || isDriftError(reconcileErr)) &&
+		!r.watcher.isWatched(remediationInstance.Spec.Current.Object.GroupVersionKind()) {
+		// Objects of kinds that can't be watched are checked for drift
+		// periodically
+		reqLogger.Info("Done reconciling, checking for drift later")
+		return reconcile.Result{RequeueAfter: driftCheckInterval}, nil
+	}
 	reqLogger.Info("Done reconciling")
 	return reconcile.Result{}, nil
 }
 
+// reconcileManagedExternally only updates the status of a remediation that
+// was exported to be applied by an external tool. The object of the
+// remediation is left alone, whether it exists or not, as the external tool
+// owns it now.
+func (r *ReconcileComplianceRemediation) reconcileManagedExternally(instance *compv1alpha1.ComplianceRemediation, logger logr.Logger) (reconcile.Result, error) {
+	state := compv1alpha1.RemediationManagedExternally
+	if instance.Spec.Outdated.Object != nil {
+		// The content changed since the remediation was exported
+		state = compv1alpha1.RemediationOutdated
+	}
+	if instance.Status.ApplicationState == state {
+		logger.Info("Remediation is managed externally, nothing to do")
+		return reconcile.Result{}, nil
+	}
+
+	logger.Info("Remediation is managed externally, updating its status", "state", state)
+	instanceCopy := instance.DeepCopy()
+	instanceCopy.Status.ApplicationState = state
+	instanceCopy.Status.ErrorMessage = ""
+	if err := r.client.Status().Update(context.TODO(), instanceCopy); err != nil {
+		return reconcile.Result{}, err
+	}
+	r.metrics.IncComplianceRemediationStatus(instanceCopy.Name, instanceCopy.Status)
+	r.events.ObserveRemediation(instance.Status.ApplicationState, instanceCopy)
+	r.metrics.SetComplianceRemediationState(instanceCopy)
+	return reconcile.Result{}, nil
+}
+
 // Gets a remediation and ensures the object exists in the cluster if the
 // remediation if applicable
 func (r *ReconcileComplianceRemediation) reconcileRemediation(instance *compv1alpha1.ComplianceRemediation, logger logr.Logger) error {
@@ -200,7 +271,15 @@ func (r *ReconcileComplianceRemediation) reconcileRemediation(instance *compv1al
 	if obj == nil {
 		return common.NewNonRetriableCtrlError("Invalid Remediation: No object given")
 	}
+	if utils.IsMachineConfig(obj) && r.getSuiteSettings(instance, logger).MergeMachineConfigRemediations {
+		return r.reconcileMergedMC(instance, logger)
+	}
 	if utils.IsMachineConfig(obj) {
+		// Merging might have been turned off since the remediation was
+		// merged
+		if err := r.unmergeMC(instance, logger); err != nil {
+			return err
+		}
 		if err := r.verifyAndCompleteMC(obj, instance); err != nil {
 			return err
 		}
@@ -229,8 +308,17 @@ func (r *ReconcileComplianceRemediation) reconcileRemediation(instance *compv1al
 				"Make sure the CRD is installed: %w", err)
 	} else if kerrors.IsNotFound(err) {
 		if instance.Spec.Apply {
+			if canDrift(instance) {
+				if driftErr := r.handleDrift(instance, "the object was deleted", objectLogger); driftErr != nil {
+					return driftErr
+				}
+			}
 			instance.AddOwnershipLabels(obj)
-			return r.createRemediation(obj, objectLogger)
+			if createErr := r.createRemediation(obj, objectLogger); createErr != nil {
+				return createErr
+			}
+			r.watcher.ensureWatch(obj, objectLogger)
+			return nil
 		}
 
 		objectLogger.Info("The object wasn't found, so no action is needed to unapply it")
@@ -240,7 +328,24 @@ func (r *ReconcileComplianceRemediation) reconcileRemediation(instance *compv1al
 	}
 
 	if instance.Spec.Apply {
-		return r.patchRemediation(obj, objectLogger)
+		if canDrift(instance) && compv1alpha1.RemediationWasCreatedByOperator(found) {
+			diff, diffErr := getObjectDrift(obj, found)
+			if diffErr != nil {
+				return fmt.Errorf("comparing the remediation object: %w", diffErr)
+			}
+			if diff != nil {
+				if driftErr := r.handleDrift(instance, describeDrift(diff), objectLogger); driftErr != nil {
+					return driftErr
+				}
+			}
+		}
+		if patchErr := r.patchRemediation(obj, objectLogger); patchErr != nil {
+			return patchErr
+		}
+		if compv1alpha1.RemediationWasCreatedByOperator(found) {
+			r.watcher.ensureWatch(obj, objectLogger)
+		}
+		return nil
 	}
 
 	return r.deleteRemediation(obj, found, objectLogger)
@@ -414,7 +519,7 @@ func (r *ReconcileComplianceRemediation) handleValueRequired(rem *compv1alpha1.C
 		annotations = make(map[string]string)
 	}
 	requiredValues := annotations[compv1alpha1.RemediationValueRequiredAnnotation]
-	requiredValuesList := removeEmptyStrings(strings.Split(requiredValues, ","))
+	requiredValuesList := utils.RemoveEmptyStrings(strings.Split(requiredValues, ","))
 	if len(requiredValuesList) == 0 {
 		return false, fmt.Errorf("Error has value-required annotation but empty list")
 	}
@@ -436,8 +541,8 @@ func (r *ReconcileComplianceRemediation) handleValueRequired(rem *compv1alpha1.C
 
 	}
 
-	currentUnsetValues := removeEmptyStrings(strings.Split(annotations[compv1alpha1.RemediationUnsetValueAnnotation], ","))
-	currentUsedValues := removeEmptyStrings(strings.Split(annotations[compv1alpha1.RemediationValueUsedAnnotation], ","))
+	currentUnsetValues := utils.RemoveEmptyStrings(strings.Split(annotations[compv1alpha1.RemediationUnsetValueAnnotation], ","))
+	currentUsedValues := utils.RemoveEmptyStrings(strings.Split(annotations[compv1alpha1.RemediationValueUsedAnnotation], ","))
 	if !isRequiredValuesProcessed {
 		logger.Info("Updating remediation to denote values have been processed")
 		labels[compv1alpha1.RemediationValueRequiredProcessedLabel] = ""
@@ -524,18 +629,32 @@ func (r *ReconcileComplianceRemediation) countXCCDFUnsetValues(rem *compv1alpha1
 	if notSetValues == "" {
 		return 0
 	}
-	return len(removeEmptyStrings(strings.Split(notSetValues, ",")))
+	return len(utils.RemoveEmptyStrings(strings.Split(notSetValues, ",")))
 }
 
-func removeEmptyStrings(s []string) []string {
-	var resultString []string
-	for _, str := range s {
-		if str == "" {
-			continue
-		}
-		resultString = append(resultString, str)
+// canBeReconciled tells whether the remediation object can be reconciled,
+// that is, whether the remediation has no unmet dependencies or values
+// that still need to be set
+func canBeReconciled(rem *compv1alpha1.ComplianceRemediation) bool {
+	return !(rem.HasUnmetDependencies() ||
+		rem.HasAnnotation(compv1alpha1.RemediationUnsetValueAnnotation) ||
+		rem.HasAnnotation(compv1alpha1.RemediationValueRequiredAnnotation))
+}
+
+// getSuiteSettings returns the settings of the suite the remediation
+// belongs to. If the suite can't be found, the default settings are
+// returned.
+func (r *ReconcileComplianceRemediation) getSuiteSettings(rem *compv1alpha1.ComplianceRemediation, logger logr.Logger) *compv1alpha1.ComplianceSuiteSettings {
+	if rem.GetSuite() == "" {
+		return &compv1alpha1.ComplianceSuiteSettings{}
+	}
+	suite := &compv1alpha1.ComplianceSuite{}
+	key := types.NamespacedName{Name: rem.GetSuite(), Namespace: rem.GetNamespace()}
+	if err := r.client.Get(context.TODO(), key, suite); err != nil {
+		logger.Info("Couldn't get the suite of the remediation, using the default settings", "error", err.Error())
+		return &compv1alpha1.ComplianceSuiteSettings{}
 	}
-	return resultString
+	return &suite.Spec.ComplianceSuiteSettings
 }
 
 func getObjFromKubeDep(dep compv1alpha1.RemediationObjectDependencyReference) runtime.Object {
@@ -595,6 +714,12 @@ func (r *ReconcileComplianceRemediation) reconcileRemediationStatus(instance *co
 		return err
 	}
 	r.metrics.IncComplianceRemediationStatus(instanceCopy.Name, instanceCopy.Status)
+	r.events.ObserveRemediation(instance.Status.ApplicationState, instanceCopy)
+	r.metrics.SetComplianceRemediationState(instanceCopy)
+	if instanceCopy.Status.ApplicationState == compv1alpha1.RemediationError &&
+		instance.Status.ApplicationState != compv1alpha1.RemediationError {
+		r.notifyFailure(instanceCopy, logger)
+	}
 
 	return nil
 }
@@ -716,6 +841,18 @@ func isNoLongerOutdated(r *compv1alpha1.ComplianceRemediation) bool {
 }
 
 func (r *ReconcileComplianceRemediation) setRemediationStatus(rem *compv1alpha1.ComplianceRemediation, errorApplying error, logger logr.Logger) {
+	if isDriftError(errorApplying) {
+		if rem.Status.ApplicationState != compv1alpha1.RemediationDrifted {
+			logger.Info("Remediation has drifted")
+			r.recorder.Eventf(rem, corev1.EventTypeWarning, "RemediationDrifted",
+				"The object applied by the remediation was changed: %s", errorApplying)
+			r.metrics.IncComplianceRemediationDrift(rem.Name, metrics.DriftActionReported)
+		}
+		rem.Status.ApplicationState = compv1alpha1.RemediationDrifted
+		rem.Status.ErrorMessage = errorApplying.Error()
+		return
+	}
+
 	if errorApplying != nil {
 		if wasErrorOnOptionalRemediation(rem, errorApplying) {
 			logger.Info("Optional remediation couldn't be applied")
@@ -756,6 +893,8 @@ func (r *ReconcileComplianceRemediation) setRemediationStatus(rem *compv1alpha1.
 
 	logger.Info("Remediation will now be applied")
 	rem.Status.ApplicationState = compv1alpha1.RemediationApplied
+	rem.Status.ErrorMessage = ""
+	rem.Status.ObservedGeneration = rem.GetGeneration()
 }
 
 func wasErrorOnOptionalRemediation(r *compv1alpha1.ComplianceRemediation, errorApplying error) bool {
@@ -777,3 +916,22 @@ func wasErrorOnOptionalRemediation(r *compv1alpha1.ComplianceRemediation, errorA
 
 	return runtime.IsNotRegisteredError(wrapped) || meta.IsNoMatchError(wrapped)
 }
+
+// notifyFailure notifies that a remediation failed to be applied, through
+// the notifiers that select its suite
+func (r *ReconcileComplianceRemediation) notifyFailure(rem *compv1alpha1.ComplianceRemediation, logger logr.Logger) {
+	if r.notifier == nil {
+		return
+	}
+	var suiteLabels map[string]string
+	if suiteName, ok := rem.Labels[compv1alpha1.SuiteLabel]; ok {
+		suite := &compv1alpha1.ComplianceSuite{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: suiteName, Namespace: rem.Namespace}, suite)
+		if err != nil && !kerrors.IsNotFound(err) {
+			logger.Error(err, "Cannot get the suite of the remediation to notify its failure")
+			return
+		}
+		suiteLabels = suite.Labels
+	}
+	r.notifier.Notify(notifier.ForRemediationFailure(rem), suiteLabels)
+}
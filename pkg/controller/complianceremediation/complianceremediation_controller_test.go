@@ -21,8 +21,10 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -169,6 +171,157 @@ var _ = Describe("Testing complianceremediation controller", func() {
 				Expect(foundCM.GetName()).To(Equal("my-cm"))
 				Expect(foundCM.Data["key"]).To(Equal("val"))
 			})
+
+			It("should check the applied object for drift periodically", func() {
+				// no values to review, the remediation can be applied
+				remediationinstance.Annotations = nil
+				remediationinstance.Status.ApplicationState = compv1alpha1.RemediationPending
+				err := reconciler.client.Update(context.TODO(), remediationinstance)
+				Expect(err).To(BeNil())
+
+				key := types.NamespacedName{Name: remediationinstance.Name, Namespace: remediationinstance.Namespace}
+				res, err := reconciler.Reconcile(reconcile.Request{NamespacedName: key})
+				Expect(err).To(BeNil())
+				Expect(res.RequeueAfter).To(Equal(driftCheckInterval))
+			})
+
+			It("should not check the applied object periodically if its kind is watched", func() {
+				reconciler.watcher = &remediatedObjectWatcher{
+					watched: map[schema.GroupVersionKind]bool{
+						{Version: "v1", Kind: "ConfigMap"}: true,
+					},
+				}
+				remediationinstance.Annotations = nil
+				remediationinstance.Status.ApplicationState = compv1alpha1.RemediationPending
+				err := reconciler.client.Update(context.TODO(), remediationinstance)
+				Expect(err).To(BeNil())
+
+				key := types.NamespacedName{Name: remediationinstance.Name, Namespace: remediationinstance.Namespace}
+				res, err := reconciler.Reconcile(reconcile.Request{NamespacedName: key})
+				Expect(err).To(BeNil())
+				Expect(res).To(Equal(reconcile.Result{}))
+			})
+		})
+
+		Context("with an applied ConfigMap remediation object that drifted", func() {
+			var cmKey types.NamespacedName
+
+			BeforeEach(func() {
+				cmKey = types.NamespacedName{Name: "my-cm", Namespace: "test-ns"}
+				cm := &corev1.ConfigMap{
+					TypeMeta: metav1.TypeMeta{
+						Kind:       "ConfigMap",
+						APIVersion: "v1",
+					},
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      cmKey.Name,
+						Namespace: cmKey.Namespace,
+					},
+					Data: map[string]string{
+						"key": "val",
+					},
+				}
+				unstructuredCM, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cm)
+				Expect(err).ToNot(HaveOccurred())
+				remediationinstance.Spec.Current.Object = &unstructured.Unstructured{
+					Object: unstructuredCM,
+				}
+				// no values to review, the remediation can be applied
+				remediationinstance.Annotations = nil
+				reconciler.recorder = record.NewFakeRecorder(10)
+
+				By("applying the remediation")
+				err = reconciler.reconcileRemediation(remediationinstance, logger)
+				Expect(err).To(BeNil())
+				reconciler.setRemediationStatus(remediationinstance, nil, logger)
+				Expect(remediationinstance.Status.ApplicationState).To(Equal(compv1alpha1.RemediationApplied))
+
+				By("changing the applied object")
+				foundCM := &corev1.ConfigMap{}
+				err = reconciler.client.Get(context.TODO(), cmKey, foundCM)
+				Expect(err).ToNot(HaveOccurred())
+				foundCM.Data["key"] = "changed"
+				err = reconciler.client.Update(context.TODO(), foundCM)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("should report the drift", func() {
+				err := reconciler.reconcileRemediation(remediationinstance, logger)
+				Expect(isDriftError(err)).To(BeTrue())
+				Expect(err.Error()).To(ContainSubstring("/data/key expected val, got changed"))
+
+				reconciler.setRemediationStatus(remediationinstance, err, logger)
+				Expect(remediationinstance.Status.ApplicationState).To(Equal(compv1alpha1.RemediationDrifted))
+				Expect(remediationinstance.IsApplied()).To(BeTrue())
+
+				By("leaving the object alone")
+				foundCM := &corev1.ConfigMap{}
+				err = reconciler.client.Get(context.TODO(), cmKey, foundCM)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(foundCM.Data["key"]).To(Equal("changed"))
+			})
+
+			It("should report the drift if the object was deleted", func() {
+				err := reconciler.client.Delete(context.TODO(), &corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: cmKey.Name, Namespace: cmKey.Namespace},
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				err = reconciler.reconcileRemediation(remediationinstance, logger)
+				Expect(isDriftError(err)).To(BeTrue())
+				Expect(err.Error()).To(ContainSubstring("the object was deleted"))
+			})
+
+			It("should re-apply the remediation if the suite asks for it", func() {
+				suite := &compv1alpha1.ComplianceSuite{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "mySuite",
+					},
+				}
+				suite.Spec.ReapplyDriftedRemediations = true
+				err := reconciler.client.Create(context.TODO(), suite)
+				Expect(err).ToNot(HaveOccurred())
+
+				err = reconciler.reconcileRemediation(remediationinstance, logger)
+				Expect(err).To(BeNil())
+
+				foundCM := &corev1.ConfigMap{}
+				err = reconciler.client.Get(context.TODO(), cmKey, foundCM)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(foundCM.Data["key"]).To(Equal("val"))
+			})
+
+			It("should not report drift if the remediation itself changed", func() {
+				remediationinstance.Status.ObservedGeneration = remediationinstance.GetGeneration() + 1
+
+				err := reconciler.reconcileRemediation(remediationinstance, logger)
+				Expect(err).To(BeNil())
+
+				foundCM := &corev1.ConfigMap{}
+				err = reconciler.client.Get(context.TODO(), cmKey, foundCM)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(foundCM.Data["key"]).To(Equal("val"))
+			})
+
+			It("should backfill the observed generation of remediations applied before it was recorded", func() {
+				remediationinstance.SetGeneration(3)
+				err := reconciler.client.Update(context.TODO(), remediationinstance)
+				Expect(err).ToNot(HaveOccurred())
+				remediationinstance.Status.ObservedGeneration = 0
+				err = reconciler.client.Status().Update(context.TODO(), remediationinstance)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(canDrift(remediationinstance)).To(BeFalse())
+
+				key := types.NamespacedName{Name: remediationinstance.GetName()}
+				_, err = reconciler.Reconcile(reconcile.Request{NamespacedName: key})
+				Expect(err).To(BeNil())
+
+				foundRem := &compv1alpha1.ComplianceRemediation{}
+				err = reconciler.client.Get(context.TODO(), key, foundRem)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(foundRem.Status.ObservedGeneration).To(BeEquivalentTo(3))
+				Expect(canDrift(foundRem)).To(BeTrue())
+			})
 		})
 
 		Context("with current MachineConfig remediation object", func() {
@@ -207,6 +360,153 @@ var _ = Describe("Testing complianceremediation controller", func() {
 			})
 		})
 
+		Context("with MachineConfig remediations that are merged", func() {
+			const (
+				fileA      = `{"ignition":{"version":"3.1.0"},"storage":{"files":[{"path":"/etc/a","mode":420}]}}`
+				fileB      = `{"ignition":{"version":"3.1.0"},"storage":{"files":[{"path":"/etc/b","mode":420}]}}`
+				fileAOther = `{"ignition":{"version":"3.1.0"},"storage":{"files":[{"path":"/etc/a","mode":384}]}}`
+			)
+
+			var mergedKey types.NamespacedName
+
+			createOtherRemediation := func(ignConfig string) {
+				other := newMCRemediation("other-rem", ignConfig)
+				other.Labels = testRemLabels
+				err := reconciler.client.Create(context.TODO(), other)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			BeforeEach(func() {
+				suite := &compv1alpha1.ComplianceSuite{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "mySuite",
+					},
+				}
+				suite.Spec.MergeMachineConfigRemediations = true
+				err := reconciler.client.Create(context.TODO(), suite)
+				Expect(err).NotTo(HaveOccurred())
+
+				remediationinstance.Spec.Current.Object = newMCRemediation("", fileA).Spec.Current.Object
+				remediationinstance.Annotations = nil
+				err = reconciler.client.Update(context.TODO(), remediationinstance)
+				Expect(err).NotTo(HaveOccurred())
+				mergedKey = types.NamespacedName{Name: remediationinstance.GetMergedMcName()}
+			})
+
+			It("should merge the remediations of the scan into one MachineConfig", func() {
+				createOtherRemediation(fileB)
+
+				err := reconciler.reconcileRemediation(remediationinstance, logger)
+				Expect(err).To(BeNil())
+
+				foundMC := &mcfgv1.MachineConfig{}
+				err = reconciler.client.Get(context.TODO(), mergedKey, foundMC)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(foundMC.Annotations).To(HaveKeyWithValue(
+					compv1alpha1.MergedRemediationsAnnotation, "other-rem,testRem"))
+				Expect(foundMC.Labels).To(HaveKeyWithValue(compv1alpha1.ComplianceScanLabel, "myScan"))
+				Expect(string(foundMC.Spec.Config.Raw)).To(ContainSubstring("/etc/a"))
+				Expect(string(foundMC.Spec.Config.Raw)).To(ContainSubstring("/etc/b"))
+
+				By("not creating a MachineConfig for the remediation alone")
+				err = reconciler.client.Get(context.TODO(), types.NamespacedName{Name: remediationinstance.GetMcName()}, foundMC)
+				Expect(kerrors.IsNotFound(err)).To(BeTrue())
+
+				By("removing the remediation from the merged MachineConfig when un-applying it")
+				remediationinstance.Spec.Apply = false
+				err = reconciler.reconcileRemediation(remediationinstance, logger)
+				Expect(err).To(BeNil())
+				foundMC = &mcfgv1.MachineConfig{}
+				err = reconciler.client.Get(context.TODO(), mergedKey, foundMC)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(foundMC.Annotations).To(HaveKeyWithValue(
+					compv1alpha1.MergedRemediationsAnnotation, "other-rem"))
+				Expect(string(foundMC.Spec.Config.Raw)).ToNot(ContainSubstring("/etc/a"))
+			})
+
+			It("should report a conflict with another remediation", func() {
+				createOtherRemediation(fileAOther)
+
+				err := reconciler.reconcileRemediation(remediationinstance, logger)
+				Expect(err).ToNot(BeNil())
+				Expect(err.Error()).To(ContainSubstring("file /etc/a conflicts with remediation other-rem"))
+
+				By("still merging the other remediation")
+				foundMC := &mcfgv1.MachineConfig{}
+				err = reconciler.client.Get(context.TODO(), mergedKey, foundMC)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(foundMC.Annotations).To(HaveKeyWithValue(
+					compv1alpha1.MergedRemediationsAnnotation, "other-rem"))
+			})
+
+			Context("when merging is turned off", func() {
+				var other *compv1alpha1.ComplianceRemediation
+
+				BeforeEach(func() {
+					createOtherRemediation(fileB)
+					err := reconciler.reconcileRemediation(remediationinstance, logger)
+					Expect(err).To(BeNil())
+					reconciler.setRemediationStatus(remediationinstance, nil, logger)
+
+					suite := &compv1alpha1.ComplianceSuite{}
+					err = reconciler.client.Get(context.TODO(), types.NamespacedName{Name: "mySuite"}, suite)
+					Expect(err).NotTo(HaveOccurred())
+					suite.Spec.MergeMachineConfigRemediations = false
+					err = reconciler.client.Update(context.TODO(), suite)
+					Expect(err).NotTo(HaveOccurred())
+
+					other = &compv1alpha1.ComplianceRemediation{}
+					err = reconciler.client.Get(context.TODO(), types.NamespacedName{Name: "other-rem"}, other)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("should move the remediations to their own MachineConfigs", func() {
+					err := reconciler.reconcileRemediation(remediationinstance, logger)
+					Expect(err).To(BeNil())
+
+					By("applying the remediation on its own")
+					foundMC := &mcfgv1.MachineConfig{}
+					err = reconciler.client.Get(context.TODO(), types.NamespacedName{Name: remediationinstance.GetMcName()}, foundMC)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(foundMC.Spec.Config.Raw)).To(ContainSubstring("/etc/a"))
+
+					By("leaving only the other remediation merged")
+					foundMC = &mcfgv1.MachineConfig{}
+					err = reconciler.client.Get(context.TODO(), mergedKey, foundMC)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(foundMC.Annotations).To(HaveKeyWithValue(
+						compv1alpha1.MergedRemediationsAnnotation, "other-rem"))
+					Expect(string(foundMC.Spec.Config.Raw)).ToNot(ContainSubstring("/etc/a"))
+					Expect(string(foundMC.Spec.Config.Raw)).To(ContainSubstring("/etc/b"))
+
+					By("deleting the merged MachineConfig once no remediation is left in it")
+					err = reconciler.reconcileRemediation(other, logger)
+					Expect(err).To(BeNil())
+					err = reconciler.client.Get(context.TODO(), mergedKey, &mcfgv1.MachineConfig{})
+					Expect(kerrors.IsNotFound(err)).To(BeTrue())
+					err = reconciler.client.Get(context.TODO(), types.NamespacedName{Name: other.GetMcName()}, foundMC)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(foundMC.Spec.Config.Raw)).To(ContainSubstring("/etc/b"))
+				})
+
+				It("should remove an un-applied remediation from the merged MachineConfig", func() {
+					remediationinstance.Spec.Apply = false
+					err := reconciler.reconcileRemediation(remediationinstance, logger)
+					Expect(err).To(BeNil())
+
+					foundMC := &mcfgv1.MachineConfig{}
+					err = reconciler.client.Get(context.TODO(), mergedKey, foundMC)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(foundMC.Annotations).To(HaveKeyWithValue(
+						compv1alpha1.MergedRemediationsAnnotation, "other-rem"))
+					Expect(string(foundMC.Spec.Config.Raw)).ToNot(ContainSubstring("/etc/a"))
+
+					err = reconciler.client.Get(context.TODO(), types.NamespacedName{Name: remediationinstance.GetMcName()}, foundMC)
+					Expect(kerrors.IsNotFound(err)).To(BeTrue())
+				})
+			})
+		})
+
 		Context("with current KubeletConfig remediation object and default no custom kubelet config", func() {
 			BeforeEach(func() {
 
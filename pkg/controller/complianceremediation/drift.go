@@ -0,0 +1,315 @@
+package complianceremediation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	authv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/metrics"
+	"github.com/openshift/compliance-operator/pkg/utils"
+)
+
+const (
+	// driftCheckInterval is how often applied remediations are checked for
+	// drift if the operator isn't able to watch the kind of their objects.
+	// Objects of watched kinds are checked as soon as they change.
+	driftCheckInterval = 15 * time.Minute
+	// maxDriftRows limits how many differences are shown in the status
+	maxDriftRows = 3
+)
+
+// driftError is returned when the object applied by a remediation no
+// longer matches the remediation and the drift should only be reported
+type driftError struct {
+	reason string
+}
+
+func (e *driftError) Error() string {
+	return fmt.Sprintf("The remediation object has drifted: %s", e.reason)
+}
+
+func isDriftError(err error) bool {
+	var dErr *driftError
+	return errors.As(err, &dErr)
+}
+
+// canDrift tells whether the object of a remediation is expected to match
+// what was applied. This is the case if the remediation was applied and
+// the remediation itself didn't change since.
+func canDrift(rem *compv1alpha1.ComplianceRemediation) bool {
+	if rem.Status.ObservedGeneration != rem.GetGeneration() {
+		return false
+	}
+	return rem.Status.ApplicationState == compv1alpha1.RemediationApplied ||
+		rem.Status.ApplicationState == compv1alpha1.RemediationDrifted
+}
+
+// needsObservedGenerationBackfill tells whether a remediation was applied
+// before the observed generation was recorded, in which case canDrift would
+// never consider it
+func needsObservedGenerationBackfill(rem *compv1alpha1.ComplianceRemediation) bool {
+	if rem.Status.ObservedGeneration != 0 || rem.GetGeneration() == 0 {
+		return false
+	}
+	return rem.Status.ApplicationState == compv1alpha1.RemediationApplied ||
+		rem.Status.ApplicationState == compv1alpha1.RemediationDrifted
+}
+
+// getObjectDrift compares the object as the remediation would apply it to
+// the object found in the cluster. It returns nil if the found object
+// still contains everything the remediation applied.
+func getObjectDrift(expected, found *unstructured.Unstructured) (*utils.JSONDiff, error) {
+	expectedJSON, err := json.Marshal(comparableContent(expected))
+	if err != nil {
+		return nil, err
+	}
+	foundJSON, err := json.Marshal(comparableContent(found))
+	if err != nil {
+		return nil, err
+	}
+
+	isSubset, diff, err := utils.JSONIsSubset(expectedJSON, foundJSON)
+	if err != nil {
+		return nil, err
+	}
+	if isSubset {
+		return nil, nil
+	}
+	return diff, nil
+}
+
+// comparableContent returns the content of an object without the status and
+// the metadata that's managed by the API server
+func comparableContent(obj *unstructured.Unstructured) map[string]interface{} {
+	content := obj.DeepCopy().UnstructuredContent()
+	delete(content, "status")
+
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	content["metadata"] = map[string]interface{}{
+		"labels":      labels,
+		"annotations": annotations,
+	}
+	return content
+}
+
+func describeDrift(diff *utils.JSONDiff) string {
+	rows := make([]string, 0, maxDriftRows)
+	for i, row := range diff.Rows {
+		if i == maxDriftRows {
+			rows = append(rows, fmt.Sprintf("and %d more", len(diff.Rows)-maxDriftRows))
+			break
+		}
+		rows = append(rows, fmt.Sprintf("%s expected %v, got %v", row.Key, row.Expected, row.Got))
+	}
+	return strings.Join(rows, "; ")
+}
+
+// handleDrift reports the drift of a remediation object by returning a
+// driftError, unless the suite of the remediation asks for drifted
+// remediations to be re-applied. In that case nil is returned and the
+// caller is expected to re-apply the remediation.
+func (r *ReconcileComplianceRemediation) handleDrift(rem *compv1alpha1.ComplianceRemediation, reason string, logger logr.Logger) error {
+	if !r.shouldReapplyDrifted(rem, logger) {
+		logger.Info("Remediation object has drifted", "reason", reason)
+		return &driftError{reason: reason}
+	}
+
+	logger.Info("Re-applying drifted remediation", "reason", reason)
+	r.recorder.Eventf(rem, corev1.EventTypeWarning, "RemediationReapplied",
+		"The remediation object had drifted and was re-applied: %s", reason)
+	r.metrics.IncComplianceRemediationDrift(rem.Name, metrics.DriftActionReapplied)
+	return nil
+}
+
+func (r *ReconcileComplianceRemediation) shouldReapplyDrifted(rem *compv1alpha1.ComplianceRemediation, logger logr.Logger) bool {
+	return r.getSuiteSettings(rem, logger).ReapplyDriftedRemediations
+}
+
+// remediatedObjectWatcher sets up watches for the kinds of objects that
+// remediations applied, so that drift is noticed as soon as it happens.
+// Watches are only set up once per kind and only if the operator is
+// allowed to list and watch that kind, as the informer would otherwise
+// block waiting for its cache to sync.
+type remediatedObjectWatcher struct {
+	mu      sync.Mutex
+	ctrl    controller.Controller
+	client  client.Client
+	mapper  meta.RESTMapper
+	watched map[schema.GroupVersionKind]bool
+}
+
+func newRemediatedObjectWatcher(c controller.Controller, cl client.Client, mapper meta.RESTMapper) *remediatedObjectWatcher {
+	return &remediatedObjectWatcher{
+		ctrl:    c,
+		client:  cl,
+		mapper:  mapper,
+		watched: make(map[schema.GroupVersionKind]bool),
+	}
+}
+
+func (w *remediatedObjectWatcher) ensureWatch(obj *unstructured.Unstructured, logger logr.Logger) {
+	if w == nil {
+		return
+	}
+	gvk := obj.GroupVersionKind()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.watched[gvk]; ok {
+		return
+	}
+
+	allowed, err := w.canWatch(gvk)
+	if err != nil {
+		// Try again the next time
+		logger.Info("Couldn't determine whether remediated objects can be watched",
+			"kind", gvk.String(), "error", err.Error())
+		return
+	}
+	w.watched[gvk] = allowed
+	if !allowed {
+		logger.Info("Not allowed to watch remediated objects, they will be checked for drift periodically",
+			"kind", gvk.String())
+		return
+	}
+
+	watched := &unstructured.Unstructured{}
+	watched.SetGroupVersionKind(gvk)
+	err = w.ctrl.Watch(&source.Kind{Type: watched}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: &remediatedObjectMapper{w.client},
+	}, remediatedObjectPredicate())
+	if err != nil {
+		logger.Error(err, "Couldn't watch remediated objects", "kind", gvk.String())
+		w.watched[gvk] = false
+		return
+	}
+	logger.Info("Watching remediated objects for drift", "kind", gvk.String())
+}
+
+// isWatched tells whether changes to objects of the given kind are already
+// watched, in which case they don't need to be checked for drift
+// periodically
+func (w *remediatedObjectWatcher) isWatched(gvk schema.GroupVersionKind) bool {
+	if w == nil {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.watched[gvk]
+}
+
+// canWatch asks the API server whether the operator may list and watch
+// objects of the given kind
+func (w *remediatedObjectWatcher) canWatch(gvk schema.GroupVersionKind) (bool, error) {
+	mapping, err := w.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return false, err
+	}
+	for _, verb := range []string{"list", "watch"} {
+		review := &authv1.SelfSubjectAccessReview{
+			Spec: authv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authv1.ResourceAttributes{
+					Group:    mapping.Resource.Group,
+					Version:  mapping.Resource.Version,
+					Resource: mapping.Resource.Resource,
+					Verb:     verb,
+				},
+			},
+		}
+		if err := w.client.Create(context.TODO(), review); err != nil {
+			return false, err
+		}
+		if !review.Status.Allowed {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// remediatedObjectPredicate only lets through changes of objects that the
+// operator created when applying a remediation. Creation events are
+// ignored, as the operator creates these objects itself.
+func remediatedObjectPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return false
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			if !compv1alpha1.RemediationWasCreatedByOperator(e.MetaNew) {
+				return false
+			}
+			// Objects that don't track their generation are
+			// compared on every update
+			if e.MetaNew.GetGeneration() == 0 {
+				return e.MetaNew.GetResourceVersion() != e.MetaOld.GetResourceVersion()
+			}
+			return e.MetaNew.GetGeneration() != e.MetaOld.GetGeneration()
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return compv1alpha1.RemediationWasCreatedByOperator(e.Meta)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// remediatedObjectMapper maps a remediated object to the applied
+// remediations of the same kind that belong to the scan the object was
+// labeled with when it was created
+type remediatedObjectMapper struct {
+	client.Client
+}
+
+func (m *remediatedObjectMapper) Map(obj handler.MapObject) []reconcile.Request {
+	scan, ok := obj.Meta.GetLabels()[compv1alpha1.ComplianceScanLabel]
+	if !ok {
+		return nil
+	}
+	kind := obj.Object.GetObjectKind().GroupVersionKind().Kind
+
+	remList := &compv1alpha1.ComplianceRemediationList{}
+	if err := m.List(context.TODO(), remList, client.MatchingLabels{compv1alpha1.ComplianceScanLabel: scan}); err != nil {
+		log.Error(err, "Couldn't list remediations for remediated object", "Object.Name", obj.Meta.GetName())
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for i := range remList.Items {
+		rem := &remList.Items[i]
+		if !rem.Spec.Apply || rem.Spec.Current.Object == nil || rem.Spec.Current.Object.GetKind() != kind {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: rem.Name, Namespace: rem.Namespace},
+		})
+	}
+	return requests
+}
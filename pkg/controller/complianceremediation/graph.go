@@ -0,0 +1,454 @@
+package complianceremediation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/utils"
+)
+
+// DependencyState tells whether a dependency of a remediation is met
+type DependencyState string
+
+const (
+	// DependencySatisfied means that the check passes or the object exists
+	DependencySatisfied DependencyState = "Satisfied"
+	// DependencyPending means that the dependency is met once the
+	// remediations providing it are applied
+	DependencyPending DependencyState = "Pending"
+	// DependencyUnsatisfiable means that the dependency can't be met by
+	// applying the remediations of the suite
+	DependencyUnsatisfiable DependencyState = "Unsatisfiable"
+)
+
+// DependencyEdge is a dependency of a remediation on a check or an object
+type DependencyEdge struct {
+	// The remediation that has the dependency
+	Remediation string `json:"remediation"`
+	// The XCCDF ID of the check, or the reference of the object the
+	// remediation depends on
+	Dependency string `json:"dependency"`
+	// The remediations that provide the dependency once applied
+	ProvidedBy []string        `json:"providedBy,omitempty"`
+	State      DependencyState `json:"state"`
+	Reason     string          `json:"reason,omitempty"`
+}
+
+// DependencyGraph holds the dependencies between the remediations of a suite
+type DependencyGraph struct {
+	Suite        string           `json:"suite"`
+	Remediations []string         `json:"remediations"`
+	Edges        []DependencyEdge `json:"edges"`
+	// Groups of remediations that depend on each other
+	Cycles [][]string `json:"cycles,omitempty"`
+}
+
+// BuildDependencyGraph resolves the dependencies of all the remediations of
+// a suite. Remediations that depend on remediations that can't be applied,
+// whether because of a cycle or of a dependency that can't be met, can't
+// be applied either.
+func BuildDependencyGraph(c client.Client, suite, namespace string) (*DependencyGraph, error) {
+	suiteSelector := client.MatchingLabels{compv1alpha1.SuiteLabel: suite}
+	remList := &compv1alpha1.ComplianceRemediationList{}
+	if err := c.List(context.TODO(), remList, client.InNamespace(namespace), suiteSelector); err != nil {
+		return nil, fmt.Errorf("listing remediations: %w", err)
+	}
+	checkList := &compv1alpha1.ComplianceCheckResultList{}
+	if err := c.List(context.TODO(), checkList, client.InNamespace(namespace), suiteSelector); err != nil {
+		return nil, fmt.Errorf("listing check results: %w", err)
+	}
+	sort.Slice(remList.Items, func(i, j int) bool {
+		return remList.Items[i].Name < remList.Items[j].Name
+	})
+
+	g := &DependencyGraph{Suite: suite, Remediations: []string{}, Edges: []DependencyEdge{}}
+	remsByCheck := map[string][]string{}
+	for i := range remList.Items {
+		rem := &remList.Items[i]
+		g.Remediations = append(g.Remediations, rem.Name)
+		check := GetOwningCheckName(rem)
+		remsByCheck[check] = append(remsByCheck[check], rem.Name)
+	}
+	checksByID := map[string][]*compv1alpha1.ComplianceCheckResult{}
+	for i := range checkList.Items {
+		check := &checkList.Items[i]
+		checksByID[check.ID] = append(checksByID[check.ID], check)
+	}
+
+	for i := range remList.Items {
+		rem := &remList.Items[i]
+		for _, id := range utils.RemoveEmptyStrings(strings.Split(rem.Annotations[compv1alpha1.RemediationDependencyAnnotation], ",")) {
+			g.Edges = append(g.Edges, resolveCheckDependency(rem, id, checksByID, remsByCheck))
+		}
+		if !rem.HasAnnotation(compv1alpha1.RemediationObjectDependencyAnnotation) {
+			continue
+		}
+		deps, err := rem.ParseRemediationDependencyRefs()
+		if err != nil {
+			g.Edges = append(g.Edges, DependencyEdge{
+				Remediation: rem.Name,
+				Dependency:  rem.Annotations[compv1alpha1.RemediationObjectDependencyAnnotation],
+				State:       DependencyUnsatisfiable,
+				Reason:      err.Error(),
+			})
+			continue
+		}
+		for _, dep := range deps {
+			edge, err := resolveObjectDependency(c, rem, dep, remList.Items)
+			if err != nil {
+				return nil, err
+			}
+			g.Edges = append(g.Edges, edge)
+		}
+	}
+
+	g.findCycles()
+	g.propagateUnsatisfiable()
+	return g, nil
+}
+
+func resolveCheckDependency(rem *compv1alpha1.ComplianceRemediation, id string,
+	checksByID map[string][]*compv1alpha1.ComplianceCheckResult, remsByCheck map[string][]string) DependencyEdge {
+	edge := DependencyEdge{Remediation: rem.Name, Dependency: id}
+
+	// A check with the same ID is created for every scan of the suite, the
+	// remediation depends on the one of its own scan
+	var checks []*compv1alpha1.ComplianceCheckResult
+	for _, check := range checksByID[id] {
+		if check.Labels[compv1alpha1.ComplianceScanLabel] == rem.GetScan() {
+			checks = append(checks, check)
+		}
+	}
+	if len(checks) == 0 {
+		checks = checksByID[id]
+	}
+	if len(checks) == 0 {
+		edge.State = DependencyUnsatisfiable
+		edge.Reason = "the check is not part of the benchmark"
+		return edge
+	}
+
+	edge.State = DependencySatisfied
+	for _, check := range checks {
+		switch check.Status {
+		case compv1alpha1.CheckResultPass:
+			continue
+		case compv1alpha1.CheckResultFail, compv1alpha1.CheckResultInfo:
+			providers := remsByCheck[check.Name]
+			if len(providers) == 0 {
+				edge.State = DependencyUnsatisfiable
+				edge.Reason = fmt.Sprintf("the check %s has the status %s and no remediation", check.Name, check.Status)
+				return edge
+			}
+			edge.State = DependencyPending
+			edge.ProvidedBy = append(edge.ProvidedBy, providers...)
+		default:
+			edge.State = DependencyUnsatisfiable
+			edge.Reason = fmt.Sprintf("the check %s has the status %s", check.Name, check.Status)
+			return edge
+		}
+	}
+	return edge
+}
+
+func resolveObjectDependency(c client.Client, rem *compv1alpha1.ComplianceRemediation,
+	dep compv1alpha1.RemediationObjectDependencyReference, rems []compv1alpha1.ComplianceRemediation) (DependencyEdge, error) {
+	edge := DependencyEdge{Remediation: rem.Name, Dependency: describeObjectDependency(dep)}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(dep.APIVersion)
+	obj.SetKind(dep.Kind)
+	key := types.NamespacedName{Name: dep.Name, Namespace: dep.Namespace}
+	err := c.Get(context.TODO(), key, obj)
+	if err == nil {
+		edge.State = DependencySatisfied
+		return edge, nil
+	} else if !kerrors.IsNotFound(err) && !meta.IsNoMatchError(err) && !runtime.IsNotRegisteredError(err) {
+		return edge, fmt.Errorf("getting kube object dependency %s: %w", edge.Dependency, err)
+	}
+
+	for i := range rems {
+		remObj := rems[i].Spec.Current.Object
+		if remObj == nil || rems[i].Name == rem.Name {
+			continue
+		}
+		if remObj.GetAPIVersion() == dep.APIVersion && remObj.GetKind() == dep.Kind &&
+			remObj.GetName() == dep.Name && remObj.GetNamespace() == dep.Namespace {
+			edge.ProvidedBy = append(edge.ProvidedBy, rems[i].Name)
+		}
+	}
+	if len(edge.ProvidedBy) == 0 {
+		edge.State = DependencyUnsatisfiable
+		edge.Reason = "the object doesn't exist and no remediation creates it"
+		return edge, nil
+	}
+	edge.State = DependencyPending
+	return edge, nil
+}
+
+func describeObjectDependency(dep compv1alpha1.RemediationObjectDependencyReference) string {
+	if dep.Namespace == "" {
+		return fmt.Sprintf("%s/%s %s", dep.APIVersion, dep.Kind, dep.Name)
+	}
+	return fmt.Sprintf("%s/%s %s/%s", dep.APIVersion, dep.Kind, dep.Namespace, dep.Name)
+}
+
+// findCycles finds the strongly connected components of the remediations
+// using Tarjan's algorithm. Every component with more than one remediation,
+// or with a remediation that depends on itself, is a cycle.
+func (g *DependencyGraph) findCycles() {
+	deps := g.remediationDependencies()
+	index := map[string]int{}
+	lowlink := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+	next := 0
+
+	var connect func(name string)
+	connect = func(name string) {
+		index[name] = next
+		lowlink[name] = next
+		next++
+		stack = append(stack, name)
+		onStack[name] = true
+
+		for _, dep := range deps[name] {
+			if _, visited := index[dep]; !visited {
+				connect(dep)
+				if lowlink[dep] < lowlink[name] {
+					lowlink[name] = lowlink[dep]
+				}
+			} else if onStack[dep] && index[dep] < lowlink[name] {
+				lowlink[name] = index[dep]
+			}
+		}
+
+		if lowlink[name] != index[name] {
+			return
+		}
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == name {
+				break
+			}
+		}
+		if len(component) > 1 || containsString(deps[name], name) {
+			sort.Strings(component)
+			g.Cycles = append(g.Cycles, component)
+		}
+	}
+
+	for _, name := range g.Remediations {
+		if _, visited := index[name]; !visited {
+			connect(name)
+		}
+	}
+	sort.Slice(g.Cycles, func(i, j int) bool {
+		return g.Cycles[i][0] < g.Cycles[j][0]
+	})
+
+	inCycle := map[string]int{}
+	for i, cycle := range g.Cycles {
+		for _, name := range cycle {
+			inCycle[name] = i
+		}
+	}
+	for i := range g.Edges {
+		edge := &g.Edges[i]
+		if edge.State != DependencyPending {
+			continue
+		}
+		cycle, ok := inCycle[edge.Remediation]
+		if !ok {
+			continue
+		}
+		for _, provider := range edge.ProvidedBy {
+			if other, ok := inCycle[provider]; ok && other == cycle {
+				edge.State = DependencyUnsatisfiable
+				edge.Reason = "dependency cycle between the remediations " + strings.Join(g.Cycles[cycle], ", ")
+				break
+			}
+		}
+	}
+}
+
+// propagateUnsatisfiable marks the dependencies provided by remediations
+// that can't be applied as unsatisfiable, until nothing changes
+func (g *DependencyGraph) propagateUnsatisfiable() {
+	for {
+		blocked := g.BlockedRemediations()
+		changed := false
+		for i := range g.Edges {
+			edge := &g.Edges[i]
+			if edge.State != DependencyPending {
+				continue
+			}
+			var blockedProviders []string
+			for _, provider := range edge.ProvidedBy {
+				if blocked[provider] {
+					blockedProviders = append(blockedProviders, provider)
+				}
+			}
+			if len(blockedProviders) > 0 {
+				edge.State = DependencyUnsatisfiable
+				edge.Reason = "it is provided by remediations that can't be applied: " + strings.Join(blockedProviders, ", ")
+				changed = true
+			}
+		}
+		if !changed {
+			return
+		}
+	}
+}
+
+func (g *DependencyGraph) remediationDependencies() map[string][]string {
+	deps := map[string][]string{}
+	for _, edge := range g.Edges {
+		if edge.State == DependencyPending {
+			deps[edge.Remediation] = append(deps[edge.Remediation], edge.ProvidedBy...)
+		}
+	}
+	return deps
+}
+
+// BlockedRemediations returns the remediations that have a dependency that
+// can't be met
+func (g *DependencyGraph) BlockedRemediations() map[string]bool {
+	blocked := map[string]bool{}
+	for _, edge := range g.Edges {
+		if edge.State == DependencyUnsatisfiable {
+			blocked[edge.Remediation] = true
+		}
+	}
+	return blocked
+}
+
+// Waves assigns each remediation that can be applied a wave that's higher
+// than the waves of the remediations providing its dependencies, so that
+// applying the remediations wave by wave meets all the dependencies
+func (g *DependencyGraph) Waves() map[string]int {
+	deps := g.remediationDependencies()
+	blocked := g.BlockedRemediations()
+	waves := map[string]int{}
+	var visit func(name string) int
+	visit = func(name string) int {
+		if wave, ok := waves[name]; ok {
+			return wave
+		}
+		// The remediations that can be applied don't depend on each
+		// other in a cycle
+		wave := 0
+		for _, dep := range deps[name] {
+			if depWave := visit(dep); depWave+1 > wave {
+				wave = depWave + 1
+			}
+		}
+		waves[name] = wave
+		return wave
+	}
+	for _, name := range g.Remediations {
+		if !blocked[name] {
+			visit(name)
+		}
+	}
+	return waves
+}
+
+// Status returns the summary of the dependencies that can't be met as
+// reported in the status of the suite, or nil if there are none
+func (g *DependencyGraph) Status() *compv1alpha1.RemediationDependencyStatus {
+	if len(g.Cycles) == 0 && len(g.BlockedRemediations()) == 0 {
+		return nil
+	}
+	status := &compv1alpha1.RemediationDependencyStatus{}
+	for _, cycle := range g.Cycles {
+		status.Cycles = append(status.Cycles, compv1alpha1.RemediationDependencyCycle{
+			Remediations: cycle,
+		})
+	}
+	for _, edge := range g.Edges {
+		if edge.State != DependencyUnsatisfiable {
+			continue
+		}
+		status.UnsatisfiableDependencies = append(status.UnsatisfiableDependencies,
+			compv1alpha1.UnsatisfiableRemediationDependency{
+				Remediation: edge.Remediation,
+				Dependency:  edge.Dependency,
+				Reason:      edge.Reason,
+			})
+	}
+	return status
+}
+
+// JSON returns the graph in JSON format
+func (g *DependencyGraph) JSON() ([]byte, error) {
+	return json.MarshalIndent(g, "", "  ")
+}
+
+// DOT returns the graph in the DOT language of Graphviz. Remediations point
+// to the remediations that provide their dependencies, dependencies that
+// aren't provided by a remediation are drawn as separate nodes.
+func (g *DependencyGraph) DOT() string {
+	var b strings.Builder
+	blocked := g.BlockedRemediations()
+
+	fmt.Fprintf(&b, "digraph %q {\n", g.Suite)
+	b.WriteString("  node [shape=box];\n")
+	for _, name := range g.Remediations {
+		if blocked[name] {
+			fmt.Fprintf(&b, "  %q [color=red];\n", name)
+		} else {
+			fmt.Fprintf(&b, "  %q;\n", name)
+		}
+	}
+	for _, edge := range g.Edges {
+		attrs := dotEdgeAttributes(edge)
+		if len(edge.ProvidedBy) == 0 {
+			fmt.Fprintf(&b, "  %q [shape=ellipse];\n", edge.Dependency)
+			fmt.Fprintf(&b, "  %q -> %q [%s];\n", edge.Remediation, edge.Dependency, attrs)
+			continue
+		}
+		for _, provider := range edge.ProvidedBy {
+			fmt.Fprintf(&b, "  %q -> %q [%s];\n", edge.Remediation, provider, attrs)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func dotEdgeAttributes(edge DependencyEdge) string {
+	attrs := fmt.Sprintf("label=%q", edge.Dependency)
+	switch edge.State {
+	case DependencySatisfied:
+		attrs += ", color=green"
+	case DependencyUnsatisfiable:
+		attrs += fmt.Sprintf(", color=red, tooltip=%q", edge.Reason)
+	}
+	return attrs
+}
+
+// GetOwningCheckName returns the name of the check the remediation was
+// created for
+func GetOwningCheckName(rem *compv1alpha1.ComplianceRemediation) string {
+	for _, ref := range rem.GetOwnerReferences() {
+		if ref.Kind == "ComplianceCheckResult" {
+			return ref.Name
+		}
+	}
+	return rem.Name
+}
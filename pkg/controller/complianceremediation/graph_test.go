@@ -0,0 +1,171 @@
+package complianceremediation
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/compliance-operator/pkg/apis"
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const graphSuite = "graph-suite"
+
+func newGraphCheck(name, id string, status compv1alpha1.ComplianceCheckStatus) *compv1alpha1.ComplianceCheckResult {
+	return &compv1alpha1.ComplianceCheckResult{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "test-ns",
+			Labels:    map[string]string{compv1alpha1.SuiteLabel: graphSuite},
+		},
+		ID:     id,
+		Status: status,
+	}
+}
+
+func newGraphRemediation(name, check string, dependsOn string, objDependsOn ...compv1alpha1.RemediationObjectDependencyReference) *compv1alpha1.ComplianceRemediation {
+	rem := &compv1alpha1.ComplianceRemediation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "test-ns",
+			Labels:      map[string]string{compv1alpha1.SuiteLabel: graphSuite},
+			Annotations: map[string]string{},
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: "ComplianceCheckResult", Name: check},
+			},
+		},
+	}
+	if dependsOn != "" {
+		rem.Annotations[compv1alpha1.RemediationDependencyAnnotation] = dependsOn
+	}
+	if len(objDependsOn) > 0 {
+		deps, err := json.Marshal(objDependsOn)
+		Expect(err).ToNot(HaveOccurred())
+		rem.Annotations[compv1alpha1.RemediationObjectDependencyAnnotation] = string(deps)
+	}
+	rem.Spec.Current.Object = &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      "cm-" + name,
+			"namespace": "test-ns",
+		},
+	}}
+	return rem
+}
+
+func configMapDependency(name string) compv1alpha1.RemediationObjectDependencyReference {
+	dep := compv1alpha1.RemediationObjectDependencyReference{Name: name, Namespace: "test-ns"}
+	dep.APIVersion = "v1"
+	dep.Kind = "ConfigMap"
+	return dep
+}
+
+var _ = Describe("Building the remediation dependency graph", func() {
+	var graph *DependencyGraph
+
+	getEdge := func(rem, dependency string) DependencyEdge {
+		for _, edge := range graph.Edges {
+			if edge.Remediation == rem && edge.Dependency == dependency {
+				return edge
+			}
+		}
+		Fail("no edge from " + rem + " to " + dependency)
+		return DependencyEdge{}
+	}
+
+	BeforeEach(func() {
+		cscheme := scheme.Scheme
+		Expect(apis.AddToScheme(cscheme)).To(Succeed())
+		client := fake.NewFakeClientWithScheme(cscheme,
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "test-ns"}},
+			newGraphCheck("check-a", "xccdf_rule_a", compv1alpha1.CheckResultFail),
+			newGraphCheck("check-b", "xccdf_rule_b", compv1alpha1.CheckResultFail),
+			newGraphCheck("check-c", "xccdf_rule_c", compv1alpha1.CheckResultFail),
+			newGraphCheck("check-d", "xccdf_rule_d", compv1alpha1.CheckResultFail),
+			newGraphCheck("check-e", "xccdf_rule_e", compv1alpha1.CheckResultFail),
+			newGraphCheck("check-f", "xccdf_rule_f", compv1alpha1.CheckResultFail),
+			newGraphCheck("check-g", "xccdf_rule_g", compv1alpha1.CheckResultFail),
+			newGraphCheck("check-pass", "xccdf_rule_pass", compv1alpha1.CheckResultPass),
+			newGraphCheck("check-error", "xccdf_rule_error", compv1alpha1.CheckResultError),
+			newGraphRemediation("rem-a", "check-a", "xccdf_rule_pass"),
+			newGraphRemediation("rem-b", "check-b", "xccdf_rule_a"),
+			newGraphRemediation("rem-c", "check-c", "xccdf_rule_missing"),
+			// blocked by rem-c
+			newGraphRemediation("rem-d", "check-d", "xccdf_rule_c"),
+			// cycle
+			newGraphRemediation("rem-e", "check-e", "xccdf_rule_f"),
+			newGraphRemediation("rem-f", "check-f", "xccdf_rule_e,xccdf_rule_error"),
+			newGraphRemediation("rem-g", "check-g", "",
+				configMapDependency("existing"),
+				configMapDependency("cm-rem-a"),
+				configMapDependency("nonexistent")),
+		)
+
+		var err error
+		graph, err = BuildDependencyGraph(client, graphSuite, "test-ns")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should resolve dependencies on checks", func() {
+		Expect(getEdge("rem-a", "xccdf_rule_pass").State).To(Equal(DependencySatisfied))
+		edge := getEdge("rem-b", "xccdf_rule_a")
+		Expect(edge.State).To(Equal(DependencyPending))
+		Expect(edge.ProvidedBy).To(Equal([]string{"rem-a"}))
+		Expect(getEdge("rem-f", "xccdf_rule_error").State).To(Equal(DependencyUnsatisfiable))
+	})
+
+	It("should resolve dependencies on objects", func() {
+		Expect(getEdge("rem-g", "v1/ConfigMap test-ns/existing").State).To(Equal(DependencySatisfied))
+		edge := getEdge("rem-g", "v1/ConfigMap test-ns/cm-rem-a")
+		Expect(edge.State).To(Equal(DependencyPending))
+		Expect(edge.ProvidedBy).To(Equal([]string{"rem-a"}))
+		edge = getEdge("rem-g", "v1/ConfigMap test-ns/nonexistent")
+		Expect(edge.State).To(Equal(DependencyUnsatisfiable))
+		Expect(edge.Reason).To(Equal("the object doesn't exist and no remediation creates it"))
+	})
+
+	It("should detect cycles", func() {
+		Expect(graph.Cycles).To(Equal([][]string{{"rem-e", "rem-f"}}))
+		Expect(getEdge("rem-e", "xccdf_rule_f").State).To(Equal(DependencyUnsatisfiable))
+	})
+
+	It("should propagate unsatisfiable dependencies", func() {
+		Expect(getEdge("rem-c", "xccdf_rule_missing").Reason).To(Equal("the check is not part of the benchmark"))
+		edge := getEdge("rem-d", "xccdf_rule_c")
+		Expect(edge.State).To(Equal(DependencyUnsatisfiable))
+		Expect(edge.Reason).To(ContainSubstring("rem-c"))
+		Expect(graph.BlockedRemediations()).To(Equal(map[string]bool{
+			"rem-c": true, "rem-d": true, "rem-e": true, "rem-f": true, "rem-g": true,
+		}))
+	})
+
+	It("should order the remediations that can be applied in waves", func() {
+		Expect(graph.Waves()).To(Equal(map[string]int{"rem-a": 0, "rem-b": 1}))
+	})
+
+	It("should summarize the dependencies that can't be met", func() {
+		status := graph.Status()
+		Expect(status).ToNot(BeNil())
+		Expect(status.Cycles).To(HaveLen(1))
+		Expect(status.UnsatisfiableDependencies).To(HaveLen(6))
+	})
+
+	It("should export the graph", func() {
+		dot := graph.DOT()
+		Expect(dot).To(HavePrefix(`digraph "graph-suite" {`))
+		Expect(dot).To(ContainSubstring(`"rem-b" -> "rem-a" [label="xccdf_rule_a"];`))
+		Expect(dot).To(ContainSubstring(`"rem-c" [color=red];`))
+
+		out, err := graph.JSON()
+		Expect(err).ToNot(HaveOccurred())
+		parsed := &DependencyGraph{}
+		Expect(json.Unmarshal(out, parsed)).To(Succeed())
+		Expect(parsed).To(Equal(graph))
+	})
+})
@@ -0,0 +1,595 @@
+package complianceremediation
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	semver "github.com/blang/semver/v4"
+	"github.com/go-logr/logr"
+	mcfgv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/common"
+	"github.com/openshift/compliance-operator/pkg/utils"
+)
+
+// keyedIgnitionLists are the lists in an Ignition config whose items are
+// identified by one of their fields. Items with the same identifier coming
+// from different remediations must be equal, otherwise they conflict.
+var keyedIgnitionLists = map[string]string{
+	"storage.files":       "path",
+	"storage.directories": "path",
+	"storage.links":       "path",
+	"systemd.units":       "name",
+	"passwd.users":        "name",
+	"passwd.groups":       "name",
+}
+
+// reconcileMergedMC reconciles the MachineConfig that the MachineConfig
+// remediations of the scan of the given remediation are merged into. The
+// merged MachineConfig is regenerated from all the applied remediations of
+// the scan, so reconciling any of them brings it up to date. The status of
+// each remediation is still tracked separately: a remediation that
+// conflicts with another one is reported as an error.
+func (r *ReconcileComplianceRemediation) reconcileMergedMC(instance *compv1alpha1.ComplianceRemediation, logger logr.Logger) error {
+	remList := &compv1alpha1.ComplianceRemediationList{}
+	listOpts := []client.ListOption{
+		client.InNamespace(instance.GetNamespace()),
+		client.MatchingLabels{compv1alpha1.ComplianceScanLabel: instance.GetScan()},
+	}
+	if err := r.client.List(context.TODO(), remList, listOpts...); err != nil {
+		return fmt.Errorf("couldn't list the remediations of the scan: %w", err)
+	}
+
+	// The instance is more recent than what the list returns
+	toMerge := []*compv1alpha1.ComplianceRemediation{}
+	if instance.Spec.Apply {
+		toMerge = append(toMerge, instance)
+	}
+	for i := range remList.Items {
+		rem := &remList.Items[i]
+		if rem.Name != instance.Name && rem.Spec.Apply && canBeReconciled(rem) {
+			toMerge = append(toMerge, rem)
+		}
+	}
+	merge := mergeMachineConfigRemediations(toMerge, logger)
+
+	obj, err := r.newMergedMC(instance, merge)
+	if err != nil {
+		return err
+	}
+
+	objectLogger := logger.WithValues("Object.Name", obj.GetName(), "Object.Kind", obj.GetKind())
+	objectLogger.Info("Reconciling merged remediation object", "ComplianceRemediations", merge.merged)
+
+	// The remediation might have been applied on its own before
+	if err := r.deleteUnmergedMC(instance, objectLogger); err != nil {
+		return err
+	}
+
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(obj.GroupVersionKind())
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: obj.GetName()}, found)
+	if kerrors.IsNotFound(err) {
+		if len(merge.merged) == 0 {
+			objectLogger.Info("No remediations to merge and the object wasn't found, nothing to do")
+			return mergeConflictError(instance, merge)
+		}
+		if instance.Spec.Apply && canDrift(instance) {
+			if driftErr := r.handleDrift(instance, "the object was deleted", objectLogger); driftErr != nil {
+				return driftErr
+			}
+		}
+		if createErr := r.createRemediation(obj, objectLogger); createErr != nil {
+			return createErr
+		}
+		r.watcher.ensureWatch(obj, objectLogger)
+		return mergeConflictError(instance, merge)
+	} else if kerrors.IsForbidden(err) {
+		return common.NewNonRetriableCtrlError(
+			"Unable to get merged fix object. "+
+				"Please update the compliance-operator's permissions: %w", err)
+	} else if err != nil {
+		return err
+	}
+
+	if !compv1alpha1.RemediationWasCreatedByOperator(found) {
+		return common.NewNonRetriableCtrlError(
+			"The MachineConfig %s exists, but wasn't created by the operator", found.GetName())
+	}
+
+	if len(merge.merged) == 0 {
+		objectLogger.Info("No remediations left to merge, deleting the merged object")
+		if deleteErr := r.client.Delete(context.TODO(), found); deleteErr != nil && !kerrors.IsNotFound(deleteErr) {
+			return deleteErr
+		}
+		return mergeConflictError(instance, merge)
+	}
+
+	// Unless the set of merged remediations changed, the object is
+	// expected to still contain what was merged the last time
+	sameRemediations := found.GetAnnotations()[compv1alpha1.MergedRemediationsAnnotation] ==
+		obj.GetAnnotations()[compv1alpha1.MergedRemediationsAnnotation]
+	if instance.Spec.Apply && canDrift(instance) && sameRemediations {
+		diff, diffErr := getObjectDrift(obj, found)
+		if diffErr != nil {
+			return fmt.Errorf("comparing the merged remediation object: %w", diffErr)
+		}
+		if diff != nil {
+			if driftErr := r.handleDrift(instance, describeDrift(diff), objectLogger); driftErr != nil {
+				return driftErr
+			}
+		}
+	}
+
+	if updateErr := r.updateMergedMC(found, obj, objectLogger); updateErr != nil {
+		return updateErr
+	}
+	r.watcher.ensureWatch(obj, objectLogger)
+	return mergeConflictError(instance, merge)
+}
+
+// newMergedMC returns the MachineConfig that the given merge of the
+// remediations of the scan of instance results in
+func (r *ReconcileComplianceRemediation) newMergedMC(instance *compv1alpha1.ComplianceRemediation, merge *machineConfigMerge) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(mcfgv1.SchemeGroupVersion.WithKind("MachineConfig"))
+	obj.Object["spec"] = merge.spec
+	if err := r.verifyAndCompleteMC(obj, instance); err != nil {
+		return nil, err
+	}
+	obj.SetName(instance.GetMergedMcName())
+	instance.AddOwnershipLabels(obj)
+	obj.SetAnnotations(map[string]string{
+		compv1alpha1.MergedRemediationsAnnotation: strings.Join(merge.merged, ","),
+	})
+	return obj, nil
+}
+
+// updateMergedMC updates the merged MachineConfig found in the cluster to
+// the one given. It updates rather than patches, so that the content of the
+// remediations that are no longer merged is removed.
+func (r *ReconcileComplianceRemediation) updateMergedMC(found, obj *unstructured.Unstructured, logger logr.Logger) error {
+	foundCopy := found.DeepCopy()
+	foundCopy.Object["spec"] = obj.Object["spec"]
+	labels := foundCopy.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	for k, v := range obj.GetLabels() {
+		labels[k] = v
+	}
+	foundCopy.SetLabels(labels)
+	annotations := foundCopy.GetAnnotations()
+	annotations[compv1alpha1.MergedRemediationsAnnotation] = obj.GetAnnotations()[compv1alpha1.MergedRemediationsAnnotation]
+	foundCopy.SetAnnotations(annotations)
+
+	logger.Info("Updating merged remediation object")
+	if updateErr := r.client.Update(context.TODO(), foundCopy); updateErr != nil {
+		if kerrors.IsForbidden(updateErr) {
+			return common.NewNonRetriableCtrlError(
+				"Unable to update merged fix object. "+
+					"Please update the compliance-operator's permissions: %s", updateErr)
+		}
+		return updateErr
+	}
+	return nil
+}
+
+// unmergeMC takes a remediation out of the merged MachineConfig of its scan,
+// for when merging MachineConfig remediations was turned off after the
+// remediation was merged. If the remediation is still to be applied, its own
+// MachineConfig is created first, so that its content never goes missing.
+// The merged MachineConfig is deleted once no remediation is left in it.
+func (r *ReconcileComplianceRemediation) unmergeMC(instance *compv1alpha1.ComplianceRemediation, logger logr.Logger) error {
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(mcfgv1.SchemeGroupVersion.WithKind("MachineConfig"))
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: instance.GetMergedMcName()}, found)
+	if kerrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !compv1alpha1.RemediationWasCreatedByOperator(found) {
+		return nil
+	}
+	mergedNames := strings.Split(found.GetAnnotations()[compv1alpha1.MergedRemediationsAnnotation], ",")
+	if !containsString(mergedNames, instance.Name) {
+		return nil
+	}
+
+	objectLogger := logger.WithValues("Object.Name", found.GetName(), "Object.Kind", found.GetKind())
+	objectLogger.Info("Taking the remediation out of the merged remediation object")
+
+	if instance.Spec.Apply {
+		obj := getApplicableObject(instance, logger)
+		if err := r.verifyAndCompleteMC(obj, instance); err != nil {
+			return err
+		}
+		instance.AddOwnershipLabels(obj)
+		if err := r.createRemediation(obj, objectLogger); err != nil && !kerrors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+
+	remList := &compv1alpha1.ComplianceRemediationList{}
+	listOpts := []client.ListOption{
+		client.InNamespace(instance.GetNamespace()),
+		client.MatchingLabels{compv1alpha1.ComplianceScanLabel: instance.GetScan()},
+	}
+	if err := r.client.List(context.TODO(), remList, listOpts...); err != nil {
+		return fmt.Errorf("couldn't list the remediations of the scan: %w", err)
+	}
+	// Only the remediations that weren't taken out yet are left merged
+	toMerge := []*compv1alpha1.ComplianceRemediation{}
+	for i := range remList.Items {
+		rem := &remList.Items[i]
+		if rem.Name != instance.Name && containsString(mergedNames, rem.Name) && rem.Spec.Apply && canBeReconciled(rem) {
+			toMerge = append(toMerge, rem)
+		}
+	}
+	merge := mergeMachineConfigRemediations(toMerge, logger)
+
+	if len(merge.merged) == 0 {
+		objectLogger.Info("No remediations left to merge, deleting the merged object")
+		if deleteErr := r.client.Delete(context.TODO(), found); deleteErr != nil && !kerrors.IsNotFound(deleteErr) {
+			return deleteErr
+		}
+		return nil
+	}
+	obj, err := r.newMergedMC(instance, merge)
+	if err != nil {
+		return err
+	}
+	return r.updateMergedMC(found, obj, objectLogger)
+}
+
+// deleteUnmergedMC deletes the MachineConfig that the remediation created
+// when it was applied on its own
+func (r *ReconcileComplianceRemediation) deleteUnmergedMC(instance *compv1alpha1.ComplianceRemediation, logger logr.Logger) error {
+	unmerged := &unstructured.Unstructured{}
+	unmerged.SetGroupVersionKind(mcfgv1.SchemeGroupVersion.WithKind("MachineConfig"))
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: instance.GetMcName()}, unmerged)
+	if kerrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if !compv1alpha1.RemediationWasCreatedByOperator(unmerged) {
+		return nil
+	}
+	logger.Info("Deleting the MachineConfig the remediation created before being merged",
+		"MachineConfig.Name", unmerged.GetName())
+	if err := r.client.Delete(context.TODO(), unmerged); err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// mergeConflictError returns an error if the remediation is to be applied,
+// but couldn't be merged with the other remediations
+func mergeConflictError(instance *compv1alpha1.ComplianceRemediation, merge *machineConfigMerge) error {
+	if !instance.Spec.Apply {
+		return nil
+	}
+	conflict, ok := merge.conflicts[instance.Name]
+	if !ok {
+		return nil
+	}
+	return common.NewNonRetriableCtrlError("The remediation can't be merged: %s", conflict)
+}
+
+// machineConfigMerge is the result of merging the MachineConfig objects of
+// several remediations
+type machineConfigMerge struct {
+	// spec is the merged MachineConfig spec
+	spec map[string]interface{}
+	// merged lists the remediations that are part of the merged spec
+	merged []string
+	// conflicts maps the remediations that couldn't be merged to the
+	// conflict that prevented it
+	conflicts map[string]error
+	// owners tracks which remediation set which part of the spec
+	owners map[string]string
+}
+
+// mergeMachineConfigRemediations merges the MachineConfig objects of the
+// given remediations into a single MachineConfig spec. The remediations
+// are merged in the order of their names; a remediation that conflicts
+// with one merged before is left out of the result entirely.
+func mergeMachineConfigRemediations(rems []*compv1alpha1.ComplianceRemediation, logger logr.Logger) *machineConfigMerge {
+	sorted := make([]*compv1alpha1.ComplianceRemediation, len(rems))
+	copy(sorted, rems)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	m := &machineConfigMerge{
+		spec:      map[string]interface{}{},
+		conflicts: map[string]error{},
+		owners:    map[string]string{},
+	}
+	for _, rem := range sorted {
+		obj := getApplicableObject(rem, logger)
+		if obj == nil || !utils.IsMachineConfig(obj) {
+			continue
+		}
+		srcSpec, _, err := unstructured.NestedMap(obj.Object, "spec")
+		if err != nil {
+			m.conflicts[rem.Name] = fmt.Errorf("the MachineConfig spec can't be read: %w", err)
+			continue
+		}
+
+		// Merge into copies so that a conflicting remediation
+		// doesn't leave anything behind
+		spec := runtime.DeepCopyJSON(m.spec)
+		owners := make(map[string]string, len(m.owners))
+		for k, v := range m.owners {
+			owners[k] = v
+		}
+		if err := mergeMachineConfigSpec(spec, srcSpec, rem.Name, owners); err != nil {
+			m.conflicts[rem.Name] = err
+			continue
+		}
+		m.spec = spec
+		m.owners = owners
+		m.merged = append(m.merged, rem.Name)
+	}
+	return m
+}
+
+func mergeMachineConfigSpec(dst, src map[string]interface{}, owner string, owners map[string]string) error {
+	for key, srcVal := range src {
+		switch key {
+		case "config":
+			srcConfig, ok := srcVal.(map[string]interface{})
+			if !ok {
+				if srcVal == nil {
+					continue
+				}
+				return fmt.Errorf("the MachineConfig has an unexpected config of type %T", srcVal)
+			}
+			dstConfig, _ := dst[key].(map[string]interface{})
+			if dstConfig == nil {
+				dstConfig = map[string]interface{}{}
+				dst[key] = dstConfig
+			}
+			if err := mergeIgnitionConfig(dstConfig, srcConfig, owner, owners); err != nil {
+				return err
+			}
+		case "kernelArguments", "extensions":
+			dst[key] = appendUniqueValues(dst[key], srcVal)
+		case "fips":
+			// Enabling FIPS mode can't conflict with not asking for it
+			srcFIPS, _ := srcVal.(bool)
+			dstFIPS, _ := dst[key].(bool)
+			dst[key] = srcFIPS || dstFIPS
+		default:
+			// Empty values, such as the defaults of osImageURL or
+			// kernelType, don't override anything
+			if isEmptyValue(srcVal) {
+				if _, ok := dst[key]; !ok {
+					dst[key] = srcVal
+				}
+				continue
+			}
+			if err := mergeValue(dst, key, srcVal, key, owner, owners); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func mergeIgnitionConfig(dst, src map[string]interface{}, owner string, owners map[string]string) error {
+	for section, srcVal := range src {
+		if section == "ignition" {
+			if err := mergeIgnitionSection(dst, srcVal); err != nil {
+				return err
+			}
+			continue
+		}
+
+		srcSection, ok := srcVal.(map[string]interface{})
+		if !ok {
+			if err := mergeValue(dst, section, srcVal, section, owner, owners); err != nil {
+				return err
+			}
+			continue
+		}
+		dstSection, _ := dst[section].(map[string]interface{})
+		if dstSection == nil {
+			dstSection = map[string]interface{}{}
+			dst[section] = dstSection
+		}
+		for key, val := range srcSection {
+			path := section + "." + key
+			idField, isKeyed := keyedIgnitionLists[path]
+			if !isKeyed {
+				if err := mergeValue(dstSection, key, val, path, owner, owners); err != nil {
+					return err
+				}
+				continue
+			}
+			merged, err := mergeKeyedList(dstSection[key], val, idField, path, owner, owners)
+			if err != nil {
+				return err
+			}
+			dstSection[key] = merged
+		}
+	}
+	return nil
+}
+
+// mergeIgnitionSection merges the "ignition" section of a config. The
+// highest Ignition version wins, as the specs are backwards compatible
+// within the same major version.
+func mergeIgnitionSection(dst map[string]interface{}, srcVal interface{}) error {
+	src, ok := srcVal.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("the Ignition config has an unexpected ignition section of type %T", srcVal)
+	}
+	dstIgn, _ := dst["ignition"].(map[string]interface{})
+	if dstIgn == nil {
+		dst["ignition"] = runtime.DeepCopyJSONValue(src)
+		return nil
+	}
+
+	for key, val := range src {
+		if key != "version" {
+			if _, ok := dstIgn[key]; !ok {
+				dstIgn[key] = runtime.DeepCopyJSONValue(val)
+			} else if !reflect.DeepEqual(dstIgn[key], val) {
+				return fmt.Errorf("the Ignition setting ignition.%s differs from the other remediations", key)
+			}
+			continue
+		}
+
+		srcVersion, err := semver.ParseTolerant(fmt.Sprint(val))
+		if err != nil {
+			return fmt.Errorf("the Ignition version %v can't be parsed: %w", val, err)
+		}
+		dstVersion, err := semver.ParseTolerant(fmt.Sprint(dstIgn["version"]))
+		if err != nil {
+			dstIgn["version"] = val
+			continue
+		}
+		if srcVersion.Major != dstVersion.Major {
+			return fmt.Errorf("the Ignition version %s differs in the major version from %s", srcVersion, dstVersion)
+		}
+		if srcVersion.GT(dstVersion) {
+			dstIgn["version"] = val
+		}
+	}
+	return nil
+}
+
+// mergeKeyedList appends the items of src to dst, unless an item with the same
+// identifier is already present. Items with the same identifier but different
+// content conflict.
+func mergeKeyedList(dst, src interface{}, idField, path, owner string, owners map[string]string) ([]interface{}, error) {
+	dstList, _ := dst.([]interface{})
+	srcList, ok := src.([]interface{})
+	if !ok {
+		if src == nil {
+			return dstList, nil
+		}
+		return nil, fmt.Errorf("the Ignition config has an unexpected %s of type %T", path, src)
+	}
+
+	for _, srcItem := range srcList {
+		srcMap, ok := srcItem.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("the Ignition config has an unexpected item in %s of type %T", path, srcItem)
+		}
+		id := fmt.Sprint(srcMap[idField])
+		ownerKey := path + ":" + id
+
+		found := false
+		for _, dstItem := range dstList {
+			dstMap, _ := dstItem.(map[string]interface{})
+			if fmt.Sprint(dstMap[idField]) != id {
+				continue
+			}
+			found = true
+			if !reflect.DeepEqual(dstMap, srcMap) {
+				return nil, fmt.Errorf("%s %s conflicts with remediation %s",
+					singularIgnitionItem(path), id, owners[ownerKey])
+			}
+			break
+		}
+		if !found {
+			dstList = append(dstList, runtime.DeepCopyJSONValue(srcMap))
+			owners[ownerKey] = owner
+		}
+	}
+	return dstList, nil
+}
+
+// mergeValue sets dst[key] to val unless dst[key] is already set to a
+// different value
+func mergeValue(dst map[string]interface{}, key string, val interface{}, path, owner string, owners map[string]string) error {
+	existing, ok := dst[key]
+	if !ok || isEmptyValue(existing) {
+		dst[key] = runtime.DeepCopyJSONValue(val)
+		owners[path] = owner
+		return nil
+	}
+	if !reflect.DeepEqual(existing, val) {
+		return fmt.Errorf("%s conflicts with remediation %s", path, owners[path])
+	}
+	return nil
+}
+
+func appendUniqueValues(dst, src interface{}) []interface{} {
+	dstList, _ := dst.([]interface{})
+	srcList, _ := src.([]interface{})
+	if dstList == nil {
+		dstList = []interface{}{}
+	}
+	for _, srcItem := range srcList {
+		found := false
+		for _, dstItem := range dstList {
+			if reflect.DeepEqual(srcItem, dstItem) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			dstList = append(dstList, srcItem)
+		}
+	}
+	return dstList
+}
+
+func isEmptyValue(val interface{}) bool {
+	if val == nil {
+		return true
+	}
+	switch v := val.(type) {
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func singularIgnitionItem(path string) string {
+	item := path[strings.LastIndex(path, ".")+1:]
+	switch item {
+	case "files":
+		return "file"
+	case "directories":
+		return "directory"
+	case "links":
+		return "link"
+	case "units":
+		return "systemd unit"
+	case "users":
+		return "user"
+	case "groups":
+		return "group"
+	}
+	return item
+}
+
+func containsString(s []string, str string) bool {
+	for _, item := range s {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package complianceremediation
+
+import (
+	"github.com/go-logr/zapr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	mcfgapi "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io"
+	mcfgv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newMCRemediation(name, ignConfig string, kernelArgs ...string) *compv1alpha1.ComplianceRemediation {
+	mc := &mcfgv1.MachineConfig{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "MachineConfig",
+			APIVersion: mcfgapi.GroupName + "/v1",
+		},
+		Spec: mcfgv1.MachineConfigSpec{
+			Config:          runtime.RawExtension{Raw: []byte(ignConfig)},
+			KernelArguments: kernelArgs,
+		},
+	}
+	unstructuredMC, err := runtime.DefaultUnstructuredConverter.ToUnstructured(mc)
+	Expect(err).ToNot(HaveOccurred())
+
+	rem := &compv1alpha1.ComplianceRemediation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+	rem.Spec.Apply = true
+	rem.Spec.Current.Object = &unstructured.Unstructured{Object: unstructuredMC}
+	return rem
+}
+
+var _ = Describe("Merging MachineConfig remediations", func() {
+	const (
+		fileA = `{"ignition":{"version":"3.1.0"},"storage":{"files":[{"path":"/etc/a","mode":420,"contents":{"source":"data:,a"}}]}}`
+		fileB = `{"ignition":{"version":"3.2.0"},"storage":{"files":[{"path":"/etc/b","mode":420,"contents":{"source":"data:,b"}}]}}`
+		// same path as fileA, different contents
+		fileAOther = `{"ignition":{"version":"3.1.0"},"storage":{"files":[{"path":"/etc/a","mode":420,"contents":{"source":"data:,other"}}]}}`
+		unit       = `{"ignition":{"version":"3.1.0"},"systemd":{"units":[{"name":"foo.service","enabled":true}]}}`
+	)
+
+	var merge *machineConfigMerge
+
+	mergedFiles := func() []interface{} {
+		files, found, err := unstructured.NestedSlice(merge.spec, "config", "storage", "files")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue())
+		return files
+	}
+
+	zaplog, _ := zap.NewDevelopment()
+	logger := zapr.NewLogger(zaplog)
+
+	Context("with remediations that don't conflict", func() {
+		BeforeEach(func() {
+			merge = mergeMachineConfigRemediations([]*compv1alpha1.ComplianceRemediation{
+				newMCRemediation("rem-b", fileB, "audit=1"),
+				newMCRemediation("rem-a", fileA, "audit=1", "slub_debug=P"),
+				newMCRemediation("rem-unit", unit),
+				// identical content is merged only once
+				newMCRemediation("rem-a-again", fileA),
+			}, logger)
+		})
+
+		It("should merge all of them", func() {
+			Expect(merge.conflicts).To(BeEmpty())
+			Expect(merge.merged).To(Equal([]string{"rem-a", "rem-a-again", "rem-b", "rem-unit"}))
+		})
+
+		It("should combine the files and units", func() {
+			Expect(mergedFiles()).To(HaveLen(2))
+			units, _, err := unstructured.NestedSlice(merge.spec, "config", "systemd", "units")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(units).To(HaveLen(1))
+		})
+
+		It("should use the highest Ignition version", func() {
+			version, _, err := unstructured.NestedString(merge.spec, "config", "ignition", "version")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(version).To(Equal("3.2.0"))
+		})
+
+		It("should combine the kernel arguments without duplicates", func() {
+			args, _, err := unstructured.NestedSlice(merge.spec, "kernelArguments")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(ConsistOf("audit=1", "slub_debug=P"))
+		})
+	})
+
+	Context("with remediations writing the same file", func() {
+		BeforeEach(func() {
+			merge = mergeMachineConfigRemediations([]*compv1alpha1.ComplianceRemediation{
+				newMCRemediation("rem-b", fileAOther, "audit=0"),
+				newMCRemediation("rem-a", fileA),
+				newMCRemediation("rem-c", fileB),
+			}, logger)
+		})
+
+		It("should leave out the conflicting remediation", func() {
+			Expect(merge.merged).To(Equal([]string{"rem-a", "rem-c"}))
+			Expect(merge.conflicts).To(HaveKey("rem-b"))
+			Expect(merge.conflicts["rem-b"].Error()).To(Equal("file /etc/a conflicts with remediation rem-a"))
+		})
+
+		It("should not keep anything of the conflicting remediation", func() {
+			Expect(mergedFiles()).To(HaveLen(2))
+			args, _, err := unstructured.NestedSlice(merge.spec, "kernelArguments")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(args).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,63 @@
+package complianceremediation
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/utils"
+)
+
+// RenderRemediation returns the object of the current content of the
+// remediation the way the operator would apply it, including the naming
+// and labeling of MachineConfig and KubeletConfig objects for the pool
+// the scan of the remediation targets. This is used to export remediations
+// so that they can be applied by other tools.
+func RenderRemediation(c client.Client, rem *compv1alpha1.ComplianceRemediation) (*unstructured.Unstructured, error) {
+	if rem.Spec.Current.Object == nil {
+		return nil, fmt.Errorf("the remediation %s has no object", rem.Name)
+	}
+	obj := rem.Spec.Current.Object.DeepCopy()
+
+	r := &ReconcileComplianceRemediation{client: c}
+	if utils.IsMachineConfig(obj) {
+		if err := r.verifyAndCompleteMC(obj, rem); err != nil {
+			return nil, err
+		}
+	}
+	if utils.IsKubeletConfig(obj) {
+		if err := r.verifyAndCompleteKC(obj, rem); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
+
+// GetUnsetRemediationValues returns the names of the variables that the
+// remediation needs, but that the tailoring of its scan doesn't set
+func GetUnsetRemediationValues(c client.Client, rem *compv1alpha1.ComplianceRemediation) ([]string, error) {
+	annotations := rem.GetAnnotations()
+	unset := utils.RemoveEmptyStrings(strings.Split(annotations[compv1alpha1.RemediationUnsetValueAnnotation], ","))
+
+	// Once processed, the required values that aren't set were added to
+	// the unset values already
+	if _, processed := rem.GetLabels()[compv1alpha1.RemediationValueRequiredProcessedLabel]; processed {
+		return unset, nil
+	}
+
+	r := &ReconcileComplianceRemediation{client: c}
+	required := utils.RemoveEmptyStrings(strings.Split(annotations[compv1alpha1.RemediationValueRequiredAnnotation], ","))
+	for _, requiredValue := range required {
+		found, err := r.isRequiredValueSet(rem, requiredValue)
+		if err != nil {
+			return nil, fmt.Errorf("finding if required value %s is set: %w", requiredValue, err)
+		}
+		if !found {
+			unset = append(unset, requiredValue)
+		}
+	}
+	return unset, nil
+}
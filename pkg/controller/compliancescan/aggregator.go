@@ -11,6 +11,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/cloudevents"
 	"github.com/openshift/compliance-operator/pkg/controller/common"
 	"github.com/openshift/compliance-operator/pkg/utils"
 )
@@ -32,7 +33,7 @@ func (r *ReconcileComplianceScan) newAggregatorPod(scanInstance *compv1alpha1.Co
 	falseP := false
 	trueP := true
 
-	return &corev1.Pod{
+	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      podName,
 			Namespace: common.GetComplianceOperatorNamespace(),
@@ -101,6 +102,28 @@ func (r *ReconcileComplianceScan) newAggregatorPod(scanInstance *compv1alpha1.Co
 			},
 		},
 	}
+	utils.SetContentSource(&pod.Spec, scanInstance.Spec.ContentSource, scanInstance.Spec.Content)
+	return pod
+}
+
+// setAggregatorContentDigest makes the aggregator refuse to create
+// remediations out of content other than the verified one
+func setAggregatorContentDigest(pod *corev1.Pod, digest string) {
+	if digest == "" {
+		return
+	}
+	aggregator := &pod.Spec.Containers[0]
+	aggregator.Command = append(aggregator.Command, "--content-digest="+digest)
+}
+
+// setAggregatorCloudEvents makes the aggregator emit the CloudEvents of the
+// check results, which it creates, if they're enabled
+func setAggregatorCloudEvents(pod *corev1.Pod, events *cloudevents.Emitter) {
+	if !events.Emits(cloudevents.EventTypeCheckStatus) {
+		return
+	}
+	aggregator := &pod.Spec.Containers[0]
+	aggregator.Command = append(aggregator.Command, "--cloudevents-sink="+events.Sink())
 }
 
 func (r *ReconcileComplianceScan) launchAggregatorPod(scanInstance *compv1alpha1.ComplianceScan, pod *corev1.Pod, logger logr.Logger) error {
@@ -23,8 +23,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/cloudevents"
 	"github.com/openshift/compliance-operator/pkg/controller/common"
 	"github.com/openshift/compliance-operator/pkg/controller/metrics"
+	"github.com/openshift/compliance-operator/pkg/controller/shared"
 	"github.com/openshift/compliance-operator/pkg/utils"
 )
 
@@ -53,17 +55,18 @@ const (
 
 // Add creates a new ComplianceScan Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
-func Add(mgr manager.Manager, met *metrics.Metrics, si utils.CtlplaneSchedulingInfo) error {
-	return add(mgr, newReconciler(mgr, met, si))
+func Add(mgr manager.Manager, deps shared.Dependencies) error {
+	return add(mgr, newReconciler(mgr, deps.Metrics, deps.SchedulingInfo, deps.Events))
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager, met *metrics.Metrics, si utils.CtlplaneSchedulingInfo) reconcile.Reconciler {
+func newReconciler(mgr manager.Manager, met *metrics.Metrics, si utils.CtlplaneSchedulingInfo, events *cloudevents.Emitter) reconcile.Reconciler {
 	return &ReconcileComplianceScan{
 		client:         mgr.GetClient(),
 		scheme:         mgr.GetScheme(),
 		recorder:       mgr.GetEventRecorderFor("scanctrl"),
 		metrics:        met,
+		events:         events,
 		schedulingInfo: si,
 	}
 }
@@ -96,6 +99,7 @@ type ReconcileComplianceScan struct {
 	scheme   *runtime.Scheme
 	recorder record.EventRecorder
 	metrics  *metrics.Metrics
+	events   *cloudevents.Emitter
 	// helps us schedule platform scans on the nodes labeled for the
 	// compliance operator's control plane
 	schedulingInfo utils.CtlplaneSchedulingInfo
@@ -184,12 +188,13 @@ func (r *ReconcileComplianceScan) validate(instance *compv1alpha1.ComplianceScan
 	// If no phase set, default to pending (the initial phase):
 	if instance.Status.Phase == "" {
 		instanceCopy := instance.DeepCopy()
-		instanceCopy.Status.Phase = compv1alpha1.PhasePending
+		instanceCopy.Status.SetPhase(compv1alpha1.PhasePending)
 		updateErr := r.client.Status().Update(context.TODO(), instanceCopy)
 		if updateErr != nil {
 			return false, updateErr
 		}
 		r.metrics.IncComplianceScanStatus(instanceCopy.Name, instanceCopy.Status)
+		r.events.ObserveScan(instance.Status.Phase, instanceCopy)
 		return false, nil
 	}
 
@@ -208,12 +213,13 @@ func (r *ReconcileComplianceScan) validate(instance *compv1alpha1.ComplianceScan
 		instanceCopy := instance.DeepCopy()
 		instanceCopy.Status.Result = compv1alpha1.ResultError
 		instanceCopy.Status.ErrorMessage = fmt.Sprintf("Scan type '%s' is not valid", instance.Spec.ScanType)
-		instanceCopy.Status.Phase = compv1alpha1.PhaseDone
+		instanceCopy.Status.SetPhase(compv1alpha1.PhaseDone)
 		updateErr := r.client.Status().Update(context.TODO(), instanceCopy)
 		if updateErr != nil {
 			return false, updateErr
 		}
 		r.metrics.IncComplianceScanStatus(instanceCopy.Name, instanceCopy.Status)
+		r.events.ObserveScan(instance.Status.Phase, instanceCopy)
 		return false, nil
 	}
 
@@ -237,12 +243,13 @@ func (r *ReconcileComplianceScan) validate(instance *compv1alpha1.ComplianceScan
 		instanceCopy := instance.DeepCopy()
 		instanceCopy.Status.ErrorMessage = fmt.Sprintf("Error parsing RawResultsStorageSize: %s", err)
 		instanceCopy.Status.Result = compv1alpha1.ResultError
-		instanceCopy.Status.Phase = compv1alpha1.PhaseDone
+		instanceCopy.Status.SetPhase(compv1alpha1.PhaseDone)
 		err := r.client.Status().Update(context.TODO(), instanceCopy)
 		if err != nil {
 			return false, err
 		}
 		r.metrics.IncComplianceScanStatus(instanceCopy.Name, instanceCopy.Status)
+		r.events.ObserveScan(instance.Status.Phase, instanceCopy)
 		return false, nil
 	}
 
@@ -261,7 +268,7 @@ func (r *ReconcileComplianceScan) phasePendingHandler(instance *compv1alpha1.Com
 	}
 
 	// Update the scan instance, the next phase is running
-	instance.Status.Phase = compv1alpha1.PhaseLaunching
+	instance.Status.SetPhase(compv1alpha1.PhaseLaunching)
 	instance.Status.Result = compv1alpha1.ResultNotAvailable
 	err := r.client.Status().Update(context.TODO(), instance)
 	if err != nil {
@@ -273,6 +280,8 @@ func (r *ReconcileComplianceScan) phasePendingHandler(instance *compv1alpha1.Com
 	// adds/removes nodes while the scan is running, we just work on the same set?
 
 	r.metrics.IncComplianceScanStatus(instance.Name, instance.Status)
+
+	r.events.ObserveScan(compv1alpha1.PhasePending, instance)
 	return reconcile.Result{}, nil
 }
 
@@ -322,24 +331,26 @@ func (r *ReconcileComplianceScan) phaseLaunchingHandler(h scanTypeHandler, logge
 			scanCopy := scan.DeepCopy()
 			scanCopy.Status.ErrorMessage = err.Error()
 			scanCopy.Status.Result = compv1alpha1.ResultError
-			scanCopy.Status.Phase = compv1alpha1.PhaseDone
+			scanCopy.Status.SetPhase(compv1alpha1.PhaseDone)
 			if updateerr := r.client.Status().Update(context.TODO(), scanCopy); updateerr != nil {
 				logger.Error(updateerr, "Failed to update a scan")
 				return reconcile.Result{}, updateerr
 			}
 			r.metrics.IncComplianceScanStatus(scanCopy.Name, scanCopy.Status)
+			r.events.ObserveScan(scan.Status.Phase, scanCopy)
 		}
 		return common.ReturnWithRetriableError(logger, err)
 	}
 
 	// if we got here, there are no new pods to be created, move to the next phase
-	scan.Status.Phase = compv1alpha1.PhaseRunning
+	scan.Status.SetPhase(compv1alpha1.PhaseRunning)
 	err = r.client.Status().Update(context.TODO(), scan)
 	if err != nil {
 		// metric status update error
 		return reconcile.Result{}, err
 	}
 	r.metrics.IncComplianceScanStatus(scan.Name, scan.Status)
+	r.events.ObserveScan(compv1alpha1.PhaseLaunching, scan)
 	return reconcile.Result{}, nil
 }
 
@@ -358,13 +369,14 @@ func (r *ReconcileComplianceScan) phaseRunningHandler(h scanTypeHandler, logger
 
 	scan := h.getScan()
 	// if we got here, there are no pods running, move to the Aggregating phase
-	scan.Status.Phase = compv1alpha1.PhaseAggregating
+	scan.Status.SetPhase(compv1alpha1.PhaseAggregating)
 	err = r.client.Status().Update(context.TODO(), scan)
 	if err != nil {
 		// metric status update error
 		return reconcile.Result{}, err
 	}
 	r.metrics.IncComplianceScanStatus(scan.Name, scan.Status)
+	r.events.ObserveScan(compv1alpha1.PhaseRunning, scan)
 	return reconcile.Result{}, nil
 }
 
@@ -384,20 +396,27 @@ func (r *ReconcileComplianceScan) phaseAggregatingHandler(h scanTypeHandler, log
 	}
 
 	if err != nil {
-		instance.Status.Phase = compv1alpha1.PhaseDone
 		instance.Status.Result = compv1alpha1.ResultError
 		instance.Status.ErrorMessage = err.Error()
+		instance.Status.SetPhase(compv1alpha1.PhaseDone)
 		err = r.updateStatusWithEvent(instance, logger)
 		if err != nil {
 			// metric status update error
 			return reconcile.Result{}, err
 		}
 		r.metrics.IncComplianceScanStatus(instance.Name, instance.Status)
+		r.events.ObserveScan(compv1alpha1.PhaseAggregating, instance)
 		return reconcile.Result{}, nil
 	}
 
 	logger.Info("Creating an aggregator pod for scan")
 	aggregator := r.newAggregatorPod(instance, logger)
+	digest, err := r.getVerifiedContentDigest(instance)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	setAggregatorContentDigest(aggregator, digest)
+	setAggregatorCloudEvents(aggregator, r.events)
 	err = r.launchAggregatorPod(instance, aggregator, logger)
 	if err != nil {
 		logger.Error(err, "Failed to launch aggregator pod", "aggregator", aggregator)
@@ -415,7 +434,7 @@ func (r *ReconcileComplianceScan) phaseAggregatingHandler(h scanTypeHandler, log
 
 	if running {
 		logger.Info("Remaining in the aggregating phase")
-		instance.Status.Phase = compv1alpha1.PhaseAggregating
+		instance.Status.SetPhase(compv1alpha1.PhaseAggregating)
 		err = r.client.Status().Update(context.TODO(), instance)
 		if err != nil {
 			logger.Error(err, "Cannot update the status, requeueing")
@@ -440,16 +459,49 @@ func (r *ReconcileComplianceScan) phaseAggregatingHandler(h scanTypeHandler, log
 		instance.Status.ErrorMessage = err.Error()
 	}
 
-	instance.Status.Phase = compv1alpha1.PhaseDone
+	instance.Status.SetPhase(compv1alpha1.PhaseDone)
 	err = r.updateStatusWithEvent(instance, logger)
 	if err != nil {
 		// metric status update error
 		return reconcile.Result{}, err
 	}
 	r.metrics.IncComplianceScanStatus(instance.Name, instance.Status)
+	r.events.ObserveScan(compv1alpha1.PhaseAggregating, instance)
+	if err := r.setCheckResultMetrics(instance); err != nil {
+		// The results are already there, the metrics are set again on
+		// the next run
+		logger.Error(err, "Cannot set the check result metrics")
+	}
 	return reconcile.Result{}, nil
 }
 
+// setCheckResultMetrics sets the metrics derived from the check result
+// counts the aggregator reported for the latest run of the scan, and from
+// the check results that failed in it
+func (r *ReconcileComplianceScan) setCheckResultMetrics(instance *compv1alpha1.ComplianceScan) error {
+	checks := compv1alpha1.ComplianceCheckResultList{}
+	err := r.client.List(context.TODO(), &checks, client.InNamespace(instance.Namespace),
+		client.MatchingLabels{
+			compv1alpha1.ComplianceScanLabel:              instance.Name,
+			compv1alpha1.ComplianceCheckResultStatusLabel: string(compv1alpha1.CheckResultFail),
+		})
+	if err != nil {
+		return err
+	}
+	failing := make([]compv1alpha1.ComplianceCheckResult, 0, len(checks.Items))
+	for _, check := range checks.Items {
+		// Leave out the check results of the previous runs that are
+		// still around
+		if index, ok := check.ScanIndex(); ok && index != instance.Status.CurrentIndex {
+			continue
+		}
+		failing = append(failing, check)
+	}
+	r.metrics.SetComplianceCheckResults(instance.Labels[compv1alpha1.SuiteLabel], instance.Name,
+		instance.Status.CheckResultCounts, failing)
+	return nil
+}
+
 func (r *ReconcileComplianceScan) phaseDoneHandler(h scanTypeHandler, instance *compv1alpha1.ComplianceScan, logger logr.Logger, doDelete bool) (reconcile.Result, error) {
 	var err error
 	logger.Info("Phase: Done")
@@ -510,7 +562,7 @@ func (r *ReconcileComplianceScan) phaseDoneHandler(h scanTypeHandler, instance *
 			// reset phase
 			logger.Info("Resetting scan")
 			instanceCopy := instance.DeepCopy()
-			instanceCopy.Status.Phase = compv1alpha1.PhasePending
+			instanceCopy.Status.SetPhase(compv1alpha1.PhasePending)
 			instanceCopy.Status.Result = compv1alpha1.ResultNotAvailable
 			if instance.Status.CurrentIndex == math.MaxInt64 {
 				instanceCopy.Status.CurrentIndex = 0
@@ -523,11 +575,17 @@ func (r *ReconcileComplianceScan) phaseDoneHandler(h scanTypeHandler, instance *
 				return reconcile.Result{}, err
 			}
 			r.metrics.IncComplianceScanStatus(instanceCopy.Name, instanceCopy.Status)
+			r.events.ObserveScan(instance.Status.Phase, instanceCopy)
 			return reconcile.Result{}, nil
 		}
 	} else {
 		// If we're done with the scan but we're not cleaning up just yet.
 
+		if err := r.compactCheckResults(instance, logger); err != nil {
+			logger.Error(err, "Cannot delete the check results that aren't retained")
+			return reconcile.Result{}, err
+		}
+
 		// scale down resultserver so it's not still listening for requests.
 		if err := r.scaleDownResultServer(instance, logger); err != nil {
 			logger.Error(err, "Cannot scale down result server")
@@ -570,6 +628,8 @@ func (r *ReconcileComplianceScan) scanDeleteHandler(instance *compv1alpha1.Compl
 			return reconcile.Result{}, err
 		}
 
+		r.metrics.DeleteComplianceScan(scanToBeDeleted.Name)
+
 		// remove our finalizer from the list and update it.
 		scanToBeDeleted.ObjectMeta.Finalizers = common.RemoveFinalizer(scanToBeDeleted.ObjectMeta.Finalizers, compv1alpha1.ScanFinalizer)
 		if err := r.client.Update(context.TODO(), scanToBeDeleted); err != nil {
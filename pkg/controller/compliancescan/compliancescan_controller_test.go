@@ -108,7 +108,8 @@ var _ = Describe("Testing compliancescan controller phases", func() {
 
 		objs = append(objs, nodeinstance1, nodeinstance2, caSecret, serverSecret, clientSecret, ns)
 		scheme := scheme.Scheme
-		scheme.AddKnownTypes(compv1alpha1.SchemeGroupVersion, compliancescaninstance)
+		scheme.AddKnownTypes(compv1alpha1.SchemeGroupVersion, compliancescaninstance,
+			&compv1alpha1.ProfileBundle{}, &compv1alpha1.ProfileBundleList{})
 
 		client := fake.NewFakeClientWithScheme(scheme, objs...)
 		var err error
@@ -231,6 +232,70 @@ var _ = Describe("Testing compliancescan controller phases", func() {
 				Expect(err).To(BeNil())
 				Expect(compliancescaninstance.Status.Phase).To(Equal(compv1alpha1.PhaseRunning))
 			})
+
+			Context("using the content of a ProfileBundle that verifies it", func() {
+				const digest = "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+				var pb *compv1alpha1.ProfileBundle
+
+				BeforeEach(func() {
+					// The scan was created directly, without the digest
+					compliancescaninstance.Spec.Content = "ssg-ocp4-ds.xml"
+					compliancescaninstance.Spec.ContentImage = "quay.io/compliance/content:latest"
+					Expect(reconciler.client.Update(context.TODO(), compliancescaninstance)).To(Succeed())
+
+					pb = &compv1alpha1.ProfileBundle{
+						ObjectMeta: metav1.ObjectMeta{Name: "ocp4"},
+						Spec: compv1alpha1.ProfileBundleSpec{
+							ContentImage: "quay.io/compliance/content:latest",
+							ContentFile:  "ssg-ocp4-ds.xml",
+							Verification: &compv1alpha1.ContentVerification{Digest: digest},
+						},
+						Status: compv1alpha1.ProfileBundleStatus{
+							ContentVersion: &compv1alpha1.ProfileBundleContentVersion{
+								ContentImage:     "quay.io/compliance/content:latest",
+								DataStreamDigest: digest,
+								Verified:         true,
+							},
+						},
+					}
+				})
+
+				It("makes the scan pods check the verified digest", func() {
+					Expect(reconciler.client.Create(context.TODO(), pb)).To(Succeed())
+					_, err := reconciler.phaseLaunchingHandler(handler, logger)
+					Expect(err).To(BeNil())
+
+					pod := &corev1.Pod{}
+					key := types.NamespacedName{
+						Name:      getPodForNodeName(compliancescaninstance.Name, nodeinstance1.Name),
+						Namespace: common.GetComplianceOperatorNamespace(),
+					}
+					Expect(reconciler.client.Get(context.TODO(), key, pod)).To(Succeed())
+					var env []corev1.EnvVar
+					for _, container := range pod.Spec.Containers {
+						if container.Name == OpenSCAPScanContainerName {
+							env = container.Env
+						}
+					}
+					Expect(env).To(ContainElement(corev1.EnvVar{
+						Name:  OpenScapContentDigestEnvName,
+						Value: digest,
+					}))
+				})
+
+				It("fails the scan if the content wasn't verified", func() {
+					pb.Status.ContentVersion.Verified = false
+					Expect(reconciler.client.Create(context.TODO(), pb)).To(Succeed())
+					_, err := reconciler.phaseLaunchingHandler(handler, logger)
+					Expect(err).To(BeNil())
+
+					scan := &compv1alpha1.ComplianceScan{}
+					key := types.NamespacedName{Name: compliancescaninstance.Name}
+					Expect(reconciler.client.Get(context.TODO(), key, scan)).To(Succeed())
+					Expect(scan.Status.Result).To(Equal(compv1alpha1.ResultError))
+					Expect(scan.Status.ErrorMessage).To(ContainSubstring("wasn't verified"))
+				})
+			})
 		})
 	})
 
@@ -402,5 +467,69 @@ var _ = Describe("Testing compliancescan controller phases", func() {
 				Expect(secrets.Items).ToNot(BeEmpty())
 			})
 		})
+		Context("with a check result retention policy", func() {
+			newCheck := func(name string, status compv1alpha1.ComplianceCheckStatus, index string) *compv1alpha1.ComplianceCheckResult {
+				return &compv1alpha1.ComplianceCheckResult{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        name,
+						Namespace:   compliancescaninstance.Namespace,
+						Labels:      map[string]string{compv1alpha1.ComplianceScanLabel: compliancescaninstance.Name},
+						Annotations: map[string]string{compv1alpha1.ComplianceCheckResultScanIndexAnnotation: index},
+					},
+					Status: status,
+				}
+			}
+
+			checkExists := func(name string) bool {
+				check := &compv1alpha1.ComplianceCheckResult{}
+				key := types.NamespacedName{Name: name, Namespace: compliancescaninstance.Namespace}
+				return reconciler.client.Get(context.TODO(), key, check) == nil
+			}
+
+			BeforeEach(func() {
+				scheme.Scheme.AddKnownTypes(compv1alpha1.SchemeGroupVersion,
+					&compv1alpha1.ComplianceCheckResult{}, &compv1alpha1.ComplianceCheckResultList{},
+					&compv1alpha1.ComplianceRemediation{}, &compv1alpha1.ComplianceRemediationList{})
+
+				compliancescaninstance.Status.Phase = compv1alpha1.PhaseDone
+				compliancescaninstance.Status.CurrentIndex = 2
+				compliancescaninstance.Spec.CheckResultRetention = &compv1alpha1.CheckResultRetentionSettings{
+					Statuses: []compv1alpha1.ComplianceCheckStatus{compv1alpha1.CheckResultFail, compv1alpha1.CheckResultManual},
+					MaxRuns:  1,
+				}
+
+				for _, check := range []*compv1alpha1.ComplianceCheckResult{
+					newCheck("test-pass", compv1alpha1.CheckResultPass, "2"),
+					newCheck("test-fail", compv1alpha1.CheckResultFail, "2"),
+					newCheck("test-manual", compv1alpha1.CheckResultManual, "2"),
+					newCheck("test-removed-rule", compv1alpha1.CheckResultFail, "1"),
+					newCheck("test-remediated", compv1alpha1.CheckResultPass, "2"),
+				} {
+					Expect(reconciler.client.Create(context.TODO(), check)).To(Succeed())
+				}
+				Expect(reconciler.client.Create(context.TODO(), &compv1alpha1.ComplianceRemediation{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-remediated",
+						Namespace: compliancescaninstance.Namespace,
+						Labels:    map[string]string{compv1alpha1.ComplianceScanLabel: compliancescaninstance.Name},
+						OwnerReferences: []metav1.OwnerReference{
+							{Kind: "ComplianceCheckResult", Name: "test-remediated"},
+						},
+					},
+				})).To(Succeed())
+			})
+
+			It("deletes the check results that aren't retained", func() {
+				_, err := reconciler.phaseDoneHandler(handler, compliancescaninstance, logger, dontDelete)
+				Expect(err).To(BeNil())
+
+				Expect(checkExists("test-fail")).To(BeTrue())
+				Expect(checkExists("test-manual")).To(BeTrue())
+				Expect(checkExists("test-pass")).To(BeFalse())
+				Expect(checkExists("test-removed-rule")).To(BeFalse())
+				// The remediations of the check would go with it
+				Expect(checkExists("test-remediated")).To(BeTrue())
+			})
+		})
 	})
 })
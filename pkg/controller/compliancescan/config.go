@@ -26,15 +26,16 @@ const (
 	OpenScapPlatformEnvConfigMapName = "openscap-env-map-platform"
 
 	// environment variables the default script consumes
-	OpenScapHostRootEnvName     = "HOSTROOT"
-	OpenScapProfileEnvName      = "PROFILE"
-	OpenScapContentEnvName      = "CONTENT"
-	OpenScapReportDirEnvName    = "REPORT_DIR"
-	OpenScapRuleEnvName         = "RULE"
-	OpenScapVerbosityeEnvName   = "VERBOSITY"
-	OpenScapTailoringDirEnvName = "TAILORING_DIR"
-	HTTPSProxyEnvName           = "HTTPS_PROXY"
-	DisconnectedInstallEnvName  = "DISCONNECTED"
+	OpenScapHostRootEnvName      = "HOSTROOT"
+	OpenScapProfileEnvName       = "PROFILE"
+	OpenScapContentEnvName       = "CONTENT"
+	OpenScapReportDirEnvName     = "REPORT_DIR"
+	OpenScapRuleEnvName          = "RULE"
+	OpenScapVerbosityeEnvName    = "VERBOSITY"
+	OpenScapTailoringDirEnvName  = "TAILORING_DIR"
+	OpenScapContentDigestEnvName = "CONTENT_DIGEST"
+	HTTPSProxyEnvName            = "HTTPS_PROXY"
+	DisconnectedInstallEnvName   = "DISCONNECTED"
 
 	ResultServerPort = int32(8443)
 
@@ -71,6 +72,16 @@ if [ -f "$REPORT_DIR/exit_code" ]; then
 	exit 0
 fi
 
+# Refuse to scan with content other than the one the ProfileBundle verified
+if [ ! -z "$CONTENT_DIGEST" ]; then
+	digest="sha256:$(sha256sum $CONTENT | cut -d' ' -f1)"
+	if [ "$digest" != "$CONTENT_DIGEST" ]; then
+		echo "The digest of the content is $digest instead of the verified $CONTENT_DIGEST. Refusing to scan." | tee $REPORT_DIR/cmd_output
+		echo "1" > $REPORT_DIR/exit_code
+		exit 0
+	fi
+fi
+
 if [ -z $HOSTROOT ]; then
 	echo "HOSTROOT not set, using normal oscap"
 	cmd=(
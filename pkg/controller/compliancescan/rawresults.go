@@ -31,9 +31,9 @@ func (r *ReconcileComplianceScan) handleRawResultsForScan(instance *compv1alpha1
 		// Handle resource limit issues
 		if errors.IsForbidden(err) {
 			scanCopy := instance.DeepCopy()
-			scanCopy.Status.Phase = compv1alpha1.PhaseDone
 			scanCopy.Status.Result = compv1alpha1.ResultError
 			scanCopy.Status.ErrorMessage = rawStorageAllocationErrorPrefix + err.Error()
+			scanCopy.Status.SetPhase(compv1alpha1.PhaseDone)
 			return false, r.client.Status().Update(context.TODO(), scanCopy)
 		}
 		return false, err
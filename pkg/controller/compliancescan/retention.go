@@ -0,0 +1,52 @@
+package compliancescan
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/complianceremediation"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// compactCheckResults deletes the check results of the scan that its check
+// result retention settings don't keep. The check results that own
+// remediations are kept, as deleting them would delete the remediations.
+func (r *ReconcileComplianceScan) compactCheckResults(instance *compv1alpha1.ComplianceScan, logger logr.Logger) error {
+	retention := instance.Spec.CheckResultRetention
+	if retention == nil {
+		return nil
+	}
+
+	inNs := client.InNamespace(instance.Namespace)
+	withLabel := client.MatchingLabels{compv1alpha1.ComplianceScanLabel: instance.Name}
+	checks := compv1alpha1.ComplianceCheckResultList{}
+	if err := r.client.List(context.TODO(), &checks, inNs, withLabel); err != nil {
+		return err
+	}
+	remediations := compv1alpha1.ComplianceRemediationList{}
+	if err := r.client.List(context.TODO(), &remediations, inNs, withLabel); err != nil {
+		return err
+	}
+	remediated := make(map[string]bool)
+	for i := range remediations.Items {
+		remediated[complianceremediation.GetOwningCheckName(&remediations.Items[i])] = true
+	}
+
+	deleted := 0
+	for i := range checks.Items {
+		check := &checks.Items[i]
+		if remediated[check.Name] || retention.Retains(check, instance.Status.CurrentIndex) {
+			continue
+		}
+		if err := r.client.Delete(context.TODO(), check); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		deleted++
+	}
+	if deleted > 0 {
+		logger.Info("Deleted the check results that aren't retained", "ComplianceCheckResults", deleted)
+	}
+	return nil
+}
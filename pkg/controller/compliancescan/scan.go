@@ -4,14 +4,17 @@ import (
 	"context"
 	"fmt"
 	"path"
+	"reflect"
 	"strings"
 
 	"github.com/go-logr/logr"
+	"github.com/openshift/library-go/pkg/image/reference"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
@@ -24,10 +27,17 @@ const (
 	apiResourceCollectorSA  = "api-resource-collector"
 	tailoringCMVolumeName   = "tailoring"
 	tailoringNotFoundPrefix = "Tailoring ConfigMap not found: "
+	customRuleResultsFile   = "/reports/custom-rules-results.json"
 )
 
 func (r *ReconcileComplianceScan) launchScanPod(instance *compv1alpha1.ComplianceScan, pod *corev1.Pod, logger logr.Logger) error {
 	podLogger := logger.WithValues("Pod.Name", pod.Name)
+	digest, err := r.getVerifiedContentDigest(instance)
+	if err != nil {
+		return err
+	}
+	setContentDigestEnv(pod, digest)
+
 	if instance.Spec.TailoringConfigMap != nil {
 		if err := r.reconcileTailoring(instance, pod, logger); err != nil {
 			return err
@@ -35,7 +45,7 @@ func (r *ReconcileComplianceScan) launchScanPod(instance *compv1alpha1.Complianc
 	}
 
 	// ..and launch it..
-	err := r.client.Create(context.TODO(), pod)
+	err = r.client.Create(context.TODO(), pod)
 	if errors.IsAlreadyExists(err) {
 		podLogger.Info("Pod already exists. This is fine.")
 	} else if err != nil {
@@ -60,7 +70,7 @@ func newScanPodForNode(scanInstance *compv1alpha1.ComplianceScan, node *corev1.N
 	falseP := false
 	trueP := true
 
-	return &corev1.Pod{
+	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      podName,
 			Namespace: common.GetComplianceOperatorNamespace(),
@@ -271,6 +281,97 @@ func newScanPodForNode(scanInstance *compv1alpha1.ComplianceScan, node *corev1.N
 			},
 		},
 	}
+	utils.SetContentSource(&pod.Spec, scanInstance.Spec.ContentSource, scanInstance.Spec.Content)
+	return pod
+}
+
+// setContentDigestEnv makes the scanner refuse to run against content other
+// than the one the ProfileBundle verified
+func setContentDigestEnv(pod *corev1.Pod, digest string) {
+	if digest == "" {
+		return
+	}
+	for i := range pod.Spec.Containers {
+		container := &pod.Spec.Containers[i]
+		if container.Name == OpenSCAPScanContainerName {
+			container.Env = append(container.Env, corev1.EnvVar{
+				Name:  OpenScapContentDigestEnvName,
+				Value: digest,
+			})
+		}
+	}
+}
+
+// getVerifiedContentDigest returns the digest of the data stream the scan
+// must run against. If the scan uses the content of a ProfileBundle that
+// verifies it, that's the digest of the verified version, no matter how the
+// scan was created. A non-retriable error is returned if that version
+// wasn't verified, or if the scan expects another digest.
+func (r *ReconcileComplianceScan) getVerifiedContentDigest(scanInstance *compv1alpha1.ComplianceScan) (string, error) {
+	pbList := compv1alpha1.ProfileBundleList{}
+	if err := r.client.List(context.TODO(), &pbList, client.InNamespace(scanInstance.Namespace)); err != nil {
+		return "", err
+	}
+
+	for i := range pbList.Items {
+		pb := &pbList.Items[i]
+		if pb.Spec.Verification == nil {
+			continue
+		}
+		version, ok := getScannedContentVersion(pb, &scanInstance.Spec)
+		if !ok {
+			continue
+		}
+		if version == nil || !version.Verified || version.DataStreamDigest == "" {
+			return "", common.NewNonRetriableCtrlError("the content of ProfileBundle '%s' the scan uses wasn't verified", pb.Name)
+		}
+		if scanInstance.Spec.ContentDigest != "" && scanInstance.Spec.ContentDigest != version.DataStreamDigest {
+			return "", common.NewNonRetriableCtrlError("the scan expects the content digest %s, but ProfileBundle '%s' verified %s",
+				scanInstance.Spec.ContentDigest, pb.Name, version.DataStreamDigest)
+		}
+		return version.DataStreamDigest, nil
+	}
+	return scanInstance.Spec.ContentDigest, nil
+}
+
+// getScannedContentVersion returns whether the scan uses the content of the
+// bundle, and if so, the version of the content it uses, which is nil if
+// the bundle doesn't know about it
+func getScannedContentVersion(pb *compv1alpha1.ProfileBundle, spec *compv1alpha1.ComplianceScanSpec) (*compv1alpha1.ProfileBundleContentVersion, bool) {
+	if spec.Content != pb.Spec.ContentFile {
+		return nil, false
+	}
+	if pb.Spec.ContentSource != nil || spec.ContentSource != nil {
+		if !reflect.DeepEqual(pb.Spec.ContentSource, spec.ContentSource) {
+			return nil, false
+		}
+		return pb.Status.ContentVersion, true
+	}
+
+	scanImage, err := reference.Parse(spec.ContentImage)
+	if err != nil {
+		return nil, false
+	}
+	repository := scanImage.DockerClientDefaults().AsRepository()
+	images := []string{pb.Spec.ContentImage}
+	if pb.Status.ContentVersion != nil {
+		images = append(images, pb.Status.ContentVersion.ContentImage)
+	}
+	for _, version := range pb.Status.PreviousContentVersions {
+		images = append(images, version.ContentImage)
+	}
+	for _, image := range images {
+		pbImage, err := reference.Parse(image)
+		if err != nil || !pbImage.DockerClientDefaults().AsRepository().Equal(repository) {
+			continue
+		}
+		// Scans of pinned content versions reference the image by digest
+		if scanImage.ID != "" {
+			return pb.GetContentVersion(scanImage.ID), true
+		}
+		return pb.Status.ContentVersion, true
+	}
+	return nil, false
 }
 
 func (r *ReconcileComplianceScan) newPlatformScanPod(scanInstance *compv1alpha1.ComplianceScan, logger logr.Logger) *corev1.Pod {
@@ -293,6 +394,10 @@ func (r *ReconcileComplianceScan) newPlatformScanPod(scanInstance *compv1alpha1.
 		// addTailoringVolume function
 		tailoringArg := fmt.Sprintf("--tailoring=%s/tailoring.xml", OpenScapTailoringDir)
 		collectorCmd = append(collectorCmd, tailoringArg)
+		// The CustomRules enabled by a TailoredProfile are passed along
+		// with the tailoring
+		customRulesArg := fmt.Sprintf("--custom-rules=%s/%s", OpenScapTailoringDir, compv1alpha1.CustomRulesTailoringKey)
+		collectorCmd = append(collectorCmd, customRulesArg, "--custom-rules-results-file="+customRuleResultsFile)
 	}
 
 	falseP := false
@@ -302,7 +407,7 @@ func (r *ReconcileComplianceScan) newPlatformScanPod(scanInstance *compv1alpha1.
 		collectorCmd = append(collectorCmd, "--debug")
 	}
 
-	return &corev1.Pod{
+	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      podName,
 			Namespace: common.GetComplianceOperatorNamespace(),
@@ -391,6 +496,7 @@ func (r *ReconcileComplianceScan) newPlatformScanPod(scanInstance *compv1alpha1.
 						"--exit-code-file=/reports/exit_code",
 						"--oscap-output-file=/reports/cmd_output",
 						"--warnings-output-file=/reports/warning_output",
+						"--custom-rules-results-file=" + customRuleResultsFile,
 						"--config-map-name=" + cmName,
 						"--owner=" + scanInstance.Name,
 						"--namespace=" + scanInstance.Namespace,
@@ -530,6 +636,8 @@ func (r *ReconcileComplianceScan) newPlatformScanPod(scanInstance *compv1alpha1.
 			},
 		},
 	}
+	utils.SetContentSource(&pod.Spec, scanInstance.Spec.ContentSource, scanInstance.Spec.Content)
+	return pod
 }
 
 func (r *ReconcileComplianceScan) deleteScanPods(instance *compv1alpha1.ComplianceScan, nodes []corev1.Node, logger logr.Logger) error {
@@ -717,6 +825,9 @@ func (r *ReconcileComplianceScan) reconcileReplicatedTailoringConfigMap(scan *co
 			newCM.Data = make(map[string]string)
 		}
 		newCM.Data["tailoring.xml"] = origData
+		if customRules, ok := origCM.Data[compv1alpha1.CustomRulesTailoringKey]; ok {
+			newCM.Data[compv1alpha1.CustomRulesTailoringKey] = customRules
+		}
 		logger.Info("Creating private Tailoring ConfigMap", "ConfigMap.Name", privName, "ConfigMap.Namespace", privNs)
 		err = r.client.Create(context.TODO(), newCM)
 		// Ignore error if CM already exists
@@ -729,9 +840,11 @@ func (r *ReconcileComplianceScan) reconcileReplicatedTailoringConfigMap(scan *co
 		return err
 	}
 	privData, _ := privCM.Data["tailoring.xml"]
+	origCustomRules, _ := origCM.Data[compv1alpha1.CustomRulesTailoringKey]
+	privCustomRules, _ := privCM.Data[compv1alpha1.CustomRulesTailoringKey]
 
 	// privCM needs update
-	if privData != origData {
+	if privData != origData || privCustomRules != origCustomRules {
 		updatedCM := privCM.DeepCopy()
 		if updatedCM.Data == nil {
 			updatedCM.Data = make(map[string]string)
@@ -742,6 +855,11 @@ func (r *ReconcileComplianceScan) reconcileReplicatedTailoringConfigMap(scan *co
 		updatedCM.Labels[compv1alpha1.ComplianceScanLabel] = scanName
 		updatedCM.Labels[compv1alpha1.ScriptLabel] = ""
 		updatedCM.Data["tailoring.xml"] = origData
+		if origCustomRules != "" {
+			updatedCM.Data[compv1alpha1.CustomRulesTailoringKey] = origCustomRules
+		} else {
+			delete(updatedCM.Data, compv1alpha1.CustomRulesTailoringKey)
+		}
 		logger.Info("Updating private Tailoring ConfigMap", "ConfigMap.Name", privName, "ConfigMap.Namespace", privNs)
 		return r.client.Update(context.TODO(), updatedCM)
 	}
@@ -106,7 +106,7 @@ func (nh *nodeScanTypeHandler) validate() (bool, error) {
 		nh.r.recorder.Event(nh.scan, corev1.EventTypeWarning, "NoMatchingNodes", warning)
 		instanceCopy := nh.scan.DeepCopy()
 		instanceCopy.Status.Result = compv1alpha1.ResultNotApplicable
-		instanceCopy.Status.Phase = compv1alpha1.PhaseDone
+		instanceCopy.Status.SetPhase(compv1alpha1.PhaseDone)
 		err := nh.r.updateStatusWithEvent(instanceCopy, nh.l)
 		return false, err
 	}
@@ -149,7 +149,7 @@ func (nh *nodeScanTypeHandler) handleRunningScan() (bool, error) {
 			// Let's go back to the previous state and make sure all the nodes are covered.
 			nh.l.Info("Phase: Running: A pod is missing. Going to state LAUNCHING to make sure we launch it",
 				"compliancescan", nh.scan.ObjectMeta.Name, "node", node.Name)
-			nh.scan.Status.Phase = compv1alpha1.PhaseLaunching
+			nh.scan.Status.SetPhase(compv1alpha1.PhaseLaunching)
 			err = nh.r.client.Status().Update(context.TODO(), nh.scan)
 			if err != nil {
 				return true, err
@@ -318,7 +318,7 @@ func (ph *platformScanTypeHandler) handleRunningScan() (bool, error) {
 		// Let's go back to the previous state and make sure all the nodes are covered.
 		ph.l.Info("Phase: Running: The platform scan pod is missing. Going to state LAUNCHING to make sure we launch it",
 			"compliancescan")
-		ph.scan.Status.Phase = compv1alpha1.PhaseLaunching
+		ph.scan.Status.SetPhase(compv1alpha1.PhaseLaunching)
 		err = ph.r.client.Status().Update(context.TODO(), ph.scan)
 		if err != nil {
 			return true, err
@@ -0,0 +1,78 @@
+package compliancesuite
+
+import (
+	"context"
+	"reflect"
+
+	monitoring "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/metrics"
+)
+
+// isOwnedByBinding returns true if the suite was created by a
+// ScanSettingBinding
+func isOwnedByBinding(suite *compv1alpha1.ComplianceSuite) bool {
+	owner := metav1.GetControllerOf(suite)
+	return owner != nil && owner.Kind == "ScanSettingBinding" &&
+		owner.APIVersion == compv1alpha1.SchemeGroupVersion.String()
+}
+
+// reconcileDefaultAlertRule creates or updates the PrometheusRule with the
+// default alerts for the results of a suite that wasn't created by a
+// ScanSettingBinding. The alerts of the other suites are configured by the
+// ScanSetting of their binding, which reconciles them. Nothing is done if
+// PrometheusRules aren't available in the cluster.
+func (r *ReconcileComplianceSuite) reconcileDefaultAlertRule(suite *compv1alpha1.ComplianceSuite, logger logr.Logger) error {
+	if isOwnedByBinding(suite) {
+		return nil
+	}
+
+	found := &monitoring.PrometheusRule{}
+	key := types.NamespacedName{Namespace: suite.Namespace, Name: suite.Name + "-alerts"}
+	err := r.client.Get(context.TODO(), key, found)
+	if meta.IsNoMatchError(err) || runtime.IsNotRegisteredError(err) {
+		return nil
+	} else if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	spec := monitoring.PrometheusRuleSpec{
+		Groups: []monitoring.RuleGroup{
+			{
+				Name:  "compliance-" + suite.Name,
+				Rules: metrics.SuiteAlertRules(suite.Name, nil),
+			},
+		},
+	}
+
+	if errors.IsNotFound(err) {
+		rule := &monitoring.PrometheusRule{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.Name,
+				Namespace: key.Namespace,
+			},
+			Spec: spec,
+		}
+		if err := controllerutil.SetControllerReference(suite, rule, r.scheme); err != nil {
+			return err
+		}
+		logger.Info("Creating the PrometheusRule with the default alerts of the suite", "PrometheusRule.Name", rule.Name)
+		return r.client.Create(context.TODO(), rule)
+	}
+
+	// Only update the rules the suite owns
+	if !metav1.IsControlledBy(found, suite) || reflect.DeepEqual(found.Spec, spec) {
+		return nil
+	}
+	found.Spec = spec
+	logger.Info("Updating the PrometheusRule with the default alerts of the suite", "PrometheusRule.Name", found.Name)
+	return r.client.Update(context.TODO(), found)
+}
@@ -3,6 +3,7 @@ package compliancesuite
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -23,8 +24,11 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/cloudevents"
 	"github.com/openshift/compliance-operator/pkg/controller/common"
 	"github.com/openshift/compliance-operator/pkg/controller/metrics"
+	"github.com/openshift/compliance-operator/pkg/controller/notifier"
+	"github.com/openshift/compliance-operator/pkg/controller/shared"
 	"github.com/openshift/compliance-operator/pkg/utils"
 )
 
@@ -37,18 +41,20 @@ const (
 
 // Add creates a new ComplianceSuite Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
-func Add(mgr manager.Manager, met *metrics.Metrics, si utils.CtlplaneSchedulingInfo) error {
-	return add(mgr, newReconciler(mgr, met, si))
+func Add(mgr manager.Manager, deps shared.Dependencies) error {
+	return add(mgr, newReconciler(mgr, deps.Metrics, deps.SchedulingInfo, deps.Notifier, deps.Events))
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager, met *metrics.Metrics, si utils.CtlplaneSchedulingInfo) reconcile.Reconciler {
+func newReconciler(mgr manager.Manager, met *metrics.Metrics, si utils.CtlplaneSchedulingInfo, n *notifier.Notifier, events *cloudevents.Emitter) reconcile.Reconciler {
 	return &ReconcileComplianceSuite{
 		reader:         mgr.GetAPIReader(),
 		client:         mgr.GetClient(),
 		scheme:         mgr.GetScheme(),
 		recorder:       mgr.GetEventRecorderFor("suitectrl"),
 		metrics:        met,
+		events:         events,
+		notifier:       n,
 		schedulingInfo: si,
 	}
 }
@@ -92,6 +98,8 @@ type ReconcileComplianceSuite struct {
 	scheme   *runtime.Scheme
 	recorder record.EventRecorder
 	metrics  *metrics.Metrics
+	events   *cloudevents.Emitter
+	notifier *notifier.Notifier
 	// helps us schedule platform scans on the nodes labeled for the
 	// compliance operator's control plane
 	schedulingInfo utils.CtlplaneSchedulingInfo
@@ -158,6 +166,10 @@ func (r *ReconcileComplianceSuite) Reconcile(request reconcile.Request) (reconci
 		return reconcile.Result{}, r.issueValidationError(suite, errorMsg, reqLogger)
 	}
 
+	if err := r.reconcileDefaultAlertRule(suite, reqLogger); err != nil {
+		return reconcile.Result{}, fmt.Errorf("Error reconciling the default alerts of the suite: %w", err)
+	}
+
 	if suite.Status.Conditions.GetCondition("Processing") == nil {
 		sCopy := suite.DeepCopy()
 		sCopy.Status.SetConditionsProcessing()
@@ -176,26 +188,45 @@ func (r *ReconcileComplianceSuite) Reconcile(request reconcile.Request) (reconci
 		return reconcile.Result{Requeue: true, RequeueAfter: requeueAfterDefault}, err
 	}
 
+	deps := newSuiteDependencyGraph(r.client, suiteCopy)
 	var res reconcile.Result
-	if res, err = r.reconcileRemediations(suiteCopy, reqLogger); err != nil {
+	if res, err = r.reconcileRemediations(suiteCopy, deps, reqLogger); err != nil {
 		return common.ReturnWithRetriableError(reqLogger, err)
 	}
 
 	if suiteCopy.IsResultAvailable() {
 		sCopy := suite.DeepCopy()
 		sCopy.Status.SetConditionReady()
+		if err := r.reconcileRemediationDependencies(sCopy, deps, reqLogger); err != nil {
+			return reconcile.Result{}, fmt.Errorf("Error resolving the remediation dependencies of the suite: %w", err)
+		}
+		verifyRes, err := r.reconcileRemediationVerification(sCopy, reqLogger)
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("Error verifying the remediations of the suite: %w", err)
+		}
+		if res == (reconcile.Result{}) {
+			res = verifyRes
+		}
+		// Reports whether the time zone of the rerunner is supported in
+		// the status
+		if err := r.reconcileScanRerunnerCronJob(sCopy, reqLogger); err != nil {
+			return reconcile.Result{}, err
+		}
 		updateErr := r.client.Status().Update(context.TODO(), sCopy)
 		if updateErr != nil {
 			return reconcile.Result{}, fmt.Errorf("Error setting ready status for suite: %w", updateErr)
 		}
-		return res, r.reconcileScanRerunnerCronJob(suiteCopy, reqLogger)
+		return res, nil
 	}
 
 	return res, nil
 }
 
 func (r *ReconcileComplianceSuite) suiteDeleteHandler(suite *compv1alpha1.ComplianceSuite, logger logr.Logger) error {
-	rerunner := r.getRerunner(suite)
+	rerunner, err := r.getRerunner(suite)
+	if err != nil {
+		return err
+	}
 	if err := r.handleRerunnerDelete(rerunner, suite.Name, logger); err != nil {
 		return err
 	}
@@ -213,6 +244,9 @@ func (r *ReconcileComplianceSuite) validateSuite(suite *compv1alpha1.ComplianceS
 	if isValid, errorMsg := r.validateSchedule(suite); !isValid {
 		return isValid, errorMsg
 	}
+	if err := suite.Spec.ValidateRescanTriggers(); err != nil {
+		return false, fmt.Sprintf("ComplianceSuite's rescan triggers are invalid: %s", err)
+	}
 	return true, ""
 }
 
@@ -343,12 +377,16 @@ func (r *ReconcileComplianceSuite) updateScanStatus(suite *compv1alpha1.Complian
 		return nil
 	}
 	modScanStatus := compv1alpha1.ScanStatusWrapperFromScan(scan)
+	previousPhase := suite.Status.Phase
 
 	// Replace the copy so we use fresh metadata
 	suite = suite.DeepCopy()
 	suite.Status.ScanStatuses[idx] = modScanStatus
 	suite.Status.Phase = suite.LowestCommonState()
 	suite.Status.Result = suite.LowestCommonResult()
+	if completed := suite.LatestCompletedTime(); completed != nil {
+		suite.Status.LastCompletedTime = completed
+	}
 
 	if suite.Status.Result == compv1alpha1.ResultNotApplicable {
 		suite.Status.ErrorMessage = "The suite result is not applicable, please check if you're using the correct platform"
@@ -363,9 +401,48 @@ func (r *ReconcileComplianceSuite) updateScanStatus(suite *compv1alpha1.Complian
 	if err := r.client.Status().Update(context.TODO(), suite); err != nil {
 		return err
 	}
+	if previousPhase != compv1alpha1.PhaseDone && suite.Status.Phase == compv1alpha1.PhaseDone {
+		r.notifyResults(suite, logger)
+	}
+	r.events.ObserveSuite(previousPhase, suite)
 	return r.setSuiteMetric(suite)
 }
 
+// notifyResults notifies the result of a run of the suite that just
+// finished, and the rules that regressed in it
+func (r *ReconcileComplianceSuite) notifyResults(suite *compv1alpha1.ComplianceSuite, logger logr.Logger) {
+	if r.notifier == nil {
+		return
+	}
+	if notification, ok := notifier.ForSuiteResult(suite); ok {
+		r.notifier.Notify(notification, suite.Labels)
+	}
+
+	checks := compv1alpha1.ComplianceCheckResultList{}
+	err := r.client.List(context.TODO(), &checks, client.InNamespace(suite.Namespace),
+		client.MatchingLabels{compv1alpha1.SuiteLabel: suite.Name})
+	if err != nil {
+		logger.Error(err, "Cannot list the check results to notify the regressed rules")
+		return
+	}
+	var regressed []string
+	for i := range checks.Items {
+		check := &checks.Items[i]
+		if _, ok := check.Annotations[compv1alpha1.ComplianceCheckResultRegressedAnnotation]; !ok {
+			continue
+		}
+		rule := check.Annotations[compv1alpha1.ComplianceCheckResultRuleAnnotation]
+		if rule == "" {
+			rule = check.Name
+		}
+		regressed = append(regressed, rule)
+	}
+	if len(regressed) > 0 {
+		sort.Strings(regressed)
+		r.notifier.Notify(notifier.ForRegressedRules(suite, regressed), suite.Labels)
+	}
+}
+
 func (r *ReconcileComplianceSuite) generateEventsForSuite(suite *compv1alpha1.ComplianceSuite, logger logr.Logger) {
 	logger.Info("Generating events for suite")
 
@@ -396,6 +473,7 @@ func (r *ReconcileComplianceSuite) generateEventsForSuite(suite *compv1alpha1.Co
 func (r *ReconcileComplianceSuite) addScanStatus(suite *compv1alpha1.ComplianceSuite, scan *compv1alpha1.ComplianceScan, logger logr.Logger) error {
 	// if not, create the scan status with the name and the current state
 	newScanStatus := compv1alpha1.ScanStatusWrapperFromScan(scan)
+	previousPhase := suite.Status.Phase
 
 	// Replace the copy so we use fresh metadata
 	suite = suite.DeepCopy()
@@ -403,9 +481,13 @@ func (r *ReconcileComplianceSuite) addScanStatus(suite *compv1alpha1.ComplianceS
 	logger.Info("Adding scan status", "ComplianceScan.Name", newScanStatus.Name, "ComplianceScan.Phase", newScanStatus.Phase)
 	suite.Status.Phase = suite.LowestCommonState()
 	suite.Status.Result = suite.LowestCommonResult()
+	if completed := suite.LatestCompletedTime(); completed != nil {
+		suite.Status.LastCompletedTime = completed
+	}
 	if err := r.client.Status().Update(context.TODO(), suite); err != nil {
 		return err
 	}
+	r.events.ObserveSuite(previousPhase, suite)
 	return r.setSuiteMetric(suite)
 }
 
@@ -443,7 +525,7 @@ func newScanForSuite(suite *compv1alpha1.ComplianceSuite, scanWrap *compv1alpha1
 
 // Reconcile the remediation application in the suite. Note that the suite that this takes is already
 // a copy, so it's safe to modify.
-func (r *ReconcileComplianceSuite) reconcileRemediations(suite *compv1alpha1.ComplianceSuite, logger logr.Logger) (reconcile.Result, error) {
+func (r *ReconcileComplianceSuite) reconcileRemediations(suite *compv1alpha1.ComplianceSuite, deps *suiteDependencyGraph, logger logr.Logger) (reconcile.Result, error) {
 	// We don't need to do anything else unless auto-applied is enabled
 	if !suite.ShouldApplyRemediations() {
 		return reconcile.Result{}, nil
@@ -483,6 +565,11 @@ func (r *ReconcileComplianceSuite) reconcileRemediations(suite *compv1alpha1.Com
 			continue
 		}
 
+		// Exported remediations are applied by an external tool
+		if rem.IsManagedExternally() {
+			continue
+		}
+
 		if err := r.applyRemediation(rem, suite, scan, mcfgpools, affectedMcfgPools, logger); err != nil {
 			return reconcile.Result{}, err
 		}
@@ -503,12 +590,30 @@ func (r *ReconcileComplianceSuite) reconcileRemediations(suite *compv1alpha1.Com
 	}
 
 	// Check that all remediations have been applied yet. If not, requeue.
+	var blockedRems map[string]bool
 	for _, rem := range postProcessRemList.Items {
+		if rem.IsManagedExternally() {
+			continue
+		}
 		if !rem.IsApplied() {
 			if rem.Status.ApplicationState == compv1alpha1.RemediationNeedsReview {
 				r.recorder.Event(suite, corev1.EventTypeWarning, "CannotRemediate", "Remediation needs-review. Values not set"+" Remediation:"+rem.Name)
 				continue
 			}
+			// Don't wait for remediations whose dependencies will
+			// never be met
+			if rem.Status.ApplicationState == compv1alpha1.RemediationMissingDependencies {
+				if blockedRems == nil {
+					var err error
+					if blockedRems, err = deps.blockedRemediations(); err != nil {
+						return reconcile.Result{}, err
+					}
+				}
+				if blockedRems[rem.Name] {
+					r.recorder.Event(suite, corev1.EventTypeWarning, "CannotRemediate", "Remediation dependencies can't be met"+" Remediation:"+rem.Name)
+					continue
+				}
+			}
 			logger.Info("Remediation not applied yet. Skipping post-processing", "ComplianceRemediation.Name", rem.Name)
 			return reconcile.Result{Requeue: true, RequeueAfter: 10 * time.Second}, nil
 		}
@@ -593,6 +698,7 @@ func (r *ReconcileComplianceSuite) applyGenericRemediation(rem compv1alpha1.Comp
 	suite *compv1alpha1.ComplianceSuite,
 	logger logr.Logger) error {
 	remCopy := rem.DeepCopy()
+	markForVerification(remCopy)
 	remCopy.Spec.Apply = true
 	if remediationNeedsOutdatedRemoval(remCopy, suite) {
 		logger.Info("Updating Outdated Remediation", "Remediation.Name", remCopy.Name)
@@ -622,6 +728,7 @@ func (r *ReconcileComplianceSuite) applyMcfgRemediationAndPausePool(rem compv1al
 		}
 	}
 
+	markForVerification(remCopy)
 	remCopy.Spec.Apply = true
 	if remediationNeedsOutdatedRemoval(remCopy, suite) {
 		logger.Info("Updating Outdated Remediation", "Remediation.Name", remCopy.Name)
@@ -3,10 +3,13 @@ package compliancesuite
 import (
 	"context"
 	"encoding/json"
+	"time"
 
+	"github.com/openshift/compliance-operator/pkg/controller/common"
 	"github.com/openshift/compliance-operator/pkg/controller/metrics"
 	"github.com/openshift/compliance-operator/pkg/controller/metrics/metricsfakes"
 
+	monitoring "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/go-logr/logr"
 	"github.com/go-logr/zapr"
 	. "github.com/onsi/ginkgo"
@@ -16,11 +19,13 @@ import (
 	mcfgv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
@@ -104,7 +109,7 @@ var _ = Describe("ComplianceSuiteController", func() {
 	})
 
 	reconcileAndGetRemediation := func() *compv1alpha1.ComplianceRemediation {
-		_, err := reconciler.reconcileRemediations(suite, logger)
+		_, err := reconciler.reconcileRemediations(suite, newSuiteDependencyGraph(reconciler.client, suite), logger)
 		Expect(err).To(BeNil())
 
 		rem := &compv1alpha1.ComplianceRemediation{}
@@ -206,7 +211,7 @@ var _ = Describe("ComplianceSuiteController", func() {
 			Expect(err).To(BeNil())
 
 			By("Running a second reconcile loop")
-			_, err = reconciler.reconcileRemediations(suite, logger)
+			_, err = reconciler.reconcileRemediations(suite, newSuiteDependencyGraph(reconciler.client, suite), logger)
 			Expect(err).To(BeNil())
 		}
 
@@ -286,6 +291,55 @@ var _ = Describe("ComplianceSuiteController", func() {
 		})
 	})
 
+	Context("When resolving the remediation dependencies", func() {
+		BeforeEach(func() {
+			remediation := &compv1alpha1.ComplianceRemediation{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      remediationName,
+					Namespace: namespace,
+					Labels: map[string]string{
+						compv1alpha1.SuiteLabel:          suiteName,
+						compv1alpha1.ComplianceScanLabel: "testScanNode",
+					},
+				},
+			}
+			err := reconciler.client.Create(ctx, remediation)
+			Expect(err).To(BeNil())
+			reconciler.recorder = record.NewFakeRecorder(10)
+		})
+
+		It("Should only resolve them again once the remediations change", func() {
+			err := reconciler.reconcileRemediationDependencies(suite, newSuiteDependencyGraph(reconciler.client, suite), logger)
+			Expect(err).To(BeNil())
+			Expect(suite.Status.RemediationDependencies).To(BeNil())
+			Expect(suite.Status.RemediationDependenciesVersion).ToNot(BeEmpty())
+
+			resolved, err := newSuiteDependencyGraph(reconciler.client, suite).isResolved()
+			Expect(err).To(BeNil())
+			Expect(resolved).To(BeTrue())
+
+			By("Adding a dependency that can't be met to the remediation")
+			rem := &compv1alpha1.ComplianceRemediation{}
+			err = reconciler.client.Get(ctx, types.NamespacedName{Name: remediationName, Namespace: namespace}, rem)
+			Expect(err).To(BeNil())
+			rem.Annotations = map[string]string{compv1alpha1.RemediationDependencyAnnotation: "xccdf_rule_missing"}
+			err = reconciler.client.Update(ctx, rem)
+			Expect(err).To(BeNil())
+
+			deps := newSuiteDependencyGraph(reconciler.client, suite)
+			resolved, err = deps.isResolved()
+			Expect(err).To(BeNil())
+			Expect(resolved).To(BeFalse())
+			err = reconciler.reconcileRemediationDependencies(suite, deps, logger)
+			Expect(err).To(BeNil())
+			Expect(suite.Status.RemediationDependencies.UnsatisfiableDependencies).To(HaveLen(1))
+
+			blocked, err := newSuiteDependencyGraph(reconciler.client, suite).blockedRemediations()
+			Expect(err).To(BeNil())
+			Expect(blocked).To(HaveKey(remediationName))
+		})
+	})
+
 	Context("When reconciling MachineConfig remediations", func() {
 		var poolName = "test-pool"
 		BeforeEach(func() {
@@ -357,7 +411,7 @@ var _ = Describe("ComplianceSuiteController", func() {
 			Expect(p.Spec.Paused).To(BeTrue())
 
 			By("Running a second reconcile loop")
-			_, err = reconciler.reconcileRemediations(suite, logger)
+			_, err = reconciler.reconcileRemediations(suite, newSuiteDependencyGraph(reconciler.client, suite), logger)
 			Expect(err).To(BeNil())
 
 			By("the pool should be un-paused")
@@ -436,6 +490,98 @@ var _ = Describe("ComplianceSuiteController", func() {
 				})
 			})
 		})
+
+		Context("Verifying the applied remediations", func() {
+			BeforeEach(func() {
+				suite.Spec.AutoApplyRemediations = true
+				err := reconciler.client.Update(ctx, suite)
+				Expect(err).To(BeNil())
+				suiteAndScansInDonePhase()
+				reconciler.recorder = record.NewFakeRecorder(10)
+			})
+
+			It("Should rescan once the pool is updated and report the result", func() {
+				By("Applying the remediation")
+				rem := reconcileAndGetRemediation()
+				Expect(rem.Spec.Apply).To(BeTrue())
+				Expect(rem.Annotations).To(HaveKey(compv1alpha1.RemediationPendingVerificationAnnotation))
+				rem.Status.ApplicationState = compv1alpha1.RemediationApplied
+				err := reconciler.client.Update(ctx, rem)
+				Expect(err).To(BeNil())
+				_, err = reconciler.reconcileRemediations(suite, newSuiteDependencyGraph(reconciler.client, suite), logger)
+				Expect(err).To(BeNil())
+
+				By("Waiting for the pool to render the remediation")
+				s := suite.DeepCopy()
+				res, err := reconciler.reconcileRemediationVerification(s, logger)
+				Expect(err).To(BeNil())
+				Expect(res.RequeueAfter).To(Equal(requeueAfterVerification))
+				Expect(s.Status.RemediationVerification.Phase).To(Equal(compv1alpha1.RemediationVerificationWaiting))
+				Expect(s.Status.RemediationVerification.Pools).To(ConsistOf(poolName))
+
+				By("The pool finishing its update")
+				p := &mcfgv1.MachineConfigPool{}
+				err = reconciler.client.Get(ctx, types.NamespacedName{Name: poolName}, p)
+				Expect(err).To(BeNil())
+				p.Spec.Configuration.Name = "rendered-test-pool-1"
+				p.Status.Configuration.Name = "rendered-test-pool-1"
+				p.Status.Configuration.Source = []corev1.ObjectReference{{Name: rem.GetMcName()}}
+				p.Status.MachineCount = 3
+				p.Status.UpdatedMachineCount = 3
+				err = reconciler.client.Update(ctx, p)
+				Expect(err).To(BeNil())
+
+				By("Rescanning the scan of the remediation")
+				res, err = reconciler.reconcileRemediationVerification(s, logger)
+				Expect(err).To(BeNil())
+				Expect(res.RequeueAfter).To(BeZero())
+				verification := s.Status.RemediationVerification
+				Expect(verification.Phase).To(Equal(compv1alpha1.RemediationVerificationRescanning))
+				Expect(verification.Remediations).To(ConsistOf(remediationName))
+				scan := &compv1alpha1.ComplianceScan{}
+				scanKey := types.NamespacedName{Name: "testScanNode", Namespace: namespace}
+				err = reconciler.client.Get(ctx, scanKey, scan)
+				Expect(err).To(BeNil())
+				Expect(scan.NeedsRescan()).To(BeTrue())
+
+				By("Waiting for the rescan to finish")
+				_, err = reconciler.reconcileRemediationVerification(s, logger)
+				Expect(err).To(BeNil())
+				Expect(s.Status.RemediationVerification.Phase).To(Equal(compv1alpha1.RemediationVerificationRescanning))
+
+				By("The rescan finishing with a passing check")
+				delete(scan.Annotations, compv1alpha1.ComplianceScanRescanAnnotation)
+				err = reconciler.client.Update(ctx, scan)
+				Expect(err).To(BeNil())
+				scan.Status.Phase = compv1alpha1.PhaseDone
+				scan.Status.StartTimestamp = &metav1.Time{Time: verification.RescanTime.Add(time.Second)}
+				err = reconciler.client.Status().Update(ctx, scan)
+				Expect(err).To(BeNil())
+				check := &compv1alpha1.ComplianceCheckResult{
+					ObjectMeta: metav1.ObjectMeta{Name: remediationName, Namespace: namespace},
+					Status:     compv1alpha1.CheckResultPass,
+				}
+				err = reconciler.client.Create(ctx, check)
+				Expect(err).To(BeNil())
+
+				_, err = reconciler.reconcileRemediationVerification(s, logger)
+				Expect(err).To(BeNil())
+				verification = s.Status.RemediationVerification
+				Expect(verification.Phase).To(Equal(compv1alpha1.RemediationVerificationDone))
+				Expect(verification.Passed).To(Equal(1))
+				Expect(verification.Failed).To(BeZero())
+				Expect(verification.Results).To(ConsistOf(compv1alpha1.RemediationVerificationResult{
+					Remediation: remediationName,
+					CheckResult: remediationName,
+					Status:      compv1alpha1.CheckResultPass,
+				}))
+
+				rem = &compv1alpha1.ComplianceRemediation{}
+				err = reconciler.client.Get(ctx, types.NamespacedName{Name: remediationName, Namespace: namespace}, rem)
+				Expect(err).To(BeNil())
+				Expect(rem.Annotations).ToNot(HaveKey(compv1alpha1.RemediationPendingVerificationAnnotation))
+			})
+		})
 	})
 	// testing for KC remediation
 
@@ -611,7 +757,7 @@ var _ = Describe("ComplianceSuiteController", func() {
 			Expect(err).To(BeNil())
 
 			By("Running a second reconcile loop")
-			_, err = reconciler.reconcileRemediations(suite, logger)
+			_, err = reconciler.reconcileRemediations(suite, newSuiteDependencyGraph(reconciler.client, suite), logger)
 			Expect(err).To(BeNil())
 
 			By("the pool should not be un-paused because the KubeletConfig is not rendered into Machine Config")
@@ -629,7 +775,7 @@ var _ = Describe("ComplianceSuiteController", func() {
 			Expect(err).To(BeNil())
 
 			By("Running a second reconcile loop")
-			_, err = reconciler.reconcileRemediations(suite, logger)
+			_, err = reconciler.reconcileRemediations(suite, newSuiteDependencyGraph(reconciler.client, suite), logger)
 			Expect(err).To(BeNil())
 
 			By("the pool should be un-paused because machine config has been updated with the new kubelet config content")
@@ -715,4 +861,173 @@ var _ = Describe("ComplianceSuiteController", func() {
 		})
 	})
 
+	Context("When reconciling the default alerts of a suite", func() {
+		ruleKey := types.NamespacedName{Name: suiteName + "-alerts", Namespace: namespace}
+
+		BeforeEach(func() {
+			Expect(monitoring.AddToScheme(scheme.Scheme)).To(Succeed())
+		})
+
+		It("Should create the alerts of a suite without a binding", func() {
+			Expect(reconciler.reconcileDefaultAlertRule(suite, logger)).To(Succeed())
+
+			rule := &monitoring.PrometheusRule{}
+			Expect(reconciler.client.Get(ctx, ruleKey, rule)).To(Succeed())
+			Expect(metav1.IsControlledBy(rule, suite)).To(BeTrue())
+			Expect(rule.Spec.Groups).To(HaveLen(1))
+			var alerts []string
+			for _, r := range rule.Spec.Groups[0].Rules {
+				alerts = append(alerts, r.Alert)
+			}
+			Expect(alerts).To(ContainElement("NonCompliant"))
+		})
+
+		It("Should leave the alerts of a suite created by a binding to the binding", func() {
+			isController := true
+			suite.OwnerReferences = []metav1.OwnerReference{{
+				APIVersion: compv1alpha1.SchemeGroupVersion.String(),
+				Kind:       "ScanSettingBinding",
+				Name:       suiteName,
+				Controller: &isController,
+			}}
+			Expect(reconciler.reconcileDefaultAlertRule(suite, logger)).To(Succeed())
+
+			rule := &monitoring.PrometheusRule{}
+			err := reconciler.client.Get(ctx, ruleKey, rule)
+			Expect(kerrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Context("Reconciling the rerunner", func() {
+		getRerunner := func() (*unstructured.Unstructured, error) {
+			rerunner := &unstructured.Unstructured{}
+			rerunner.SetGroupVersionKind(cronJobGVK)
+			key := types.NamespacedName{Name: GetRerunnerName(suiteName), Namespace: common.GetComplianceOperatorNamespace()}
+			return rerunner, reconciler.client.Get(ctx, key, rerunner)
+		}
+
+		BeforeEach(func() {
+			deadline := int64(600)
+			suite.Spec.Schedule = "0 1 * * *"
+			suite.Spec.ScheduleJitter = "30m"
+			suite.Spec.StartingDeadlineSeconds = &deadline
+			suite.Spec.TimeZone = "Europe/Paris"
+			Expect(reconciler.reconcileScanRerunnerCronJob(suite, logger)).To(Succeed())
+		})
+
+		It("creates a batch/v1 CronJob with the schedule options", func() {
+			rerunner, err := getRerunner()
+			Expect(err).To(BeNil())
+			spec := rerunner.Object["spec"].(map[string]interface{})
+			Expect(spec).To(HaveKeyWithValue("schedule", "0 1 * * *"))
+			Expect(spec).To(HaveKeyWithValue("suspend", false))
+			Expect(spec).To(HaveKeyWithValue("startingDeadlineSeconds", int64(600)))
+			Expect(spec).To(HaveKeyWithValue("concurrencyPolicy", "Forbid"))
+			Expect(spec).To(HaveKeyWithValue("timeZone", "Europe/Paris"))
+
+			containers, _, _ := unstructured.NestedSlice(rerunner.Object, "spec", "jobTemplate", "spec", "template", "spec", "containers")
+			Expect(containers).To(HaveLen(1))
+			Expect(containers[0].(map[string]interface{})["command"]).To(ContainElements("--jitter", "30m"))
+		})
+
+		It("updates the CronJob when the options change", func() {
+			suite.Spec.Suspend = true
+			suite.Spec.ScheduleJitter = ""
+			suite.Spec.TimeZone = ""
+			Expect(reconciler.reconcileScanRerunnerCronJob(suite, logger)).To(Succeed())
+
+			rerunner, err := getRerunner()
+			Expect(err).To(BeNil())
+			spec := rerunner.Object["spec"].(map[string]interface{})
+			Expect(spec).To(HaveKeyWithValue("suspend", true))
+			Expect(spec).ToNot(HaveKey("timeZone"))
+			containers, _, _ := unstructured.NestedSlice(rerunner.Object, "spec", "jobTemplate", "spec", "template", "spec", "containers")
+			Expect(containers[0].(map[string]interface{})["command"]).ToNot(ContainElement("--jitter"))
+		})
+
+		It("deletes the CronJob when the schedule is removed", func() {
+			suite.Spec.Schedule = ""
+			Expect(reconciler.reconcileScanRerunnerCronJob(suite, logger)).To(Succeed())
+			_, err := getRerunner()
+			Expect(kerrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("rejects invalid schedule options", func() {
+			suite.Spec.ScheduleJitter = "48h"
+			valid, _ := reconciler.validateSchedule(suite)
+			Expect(valid).To(BeFalse())
+
+			suite.Spec.ScheduleJitter = ""
+			suite.Spec.TimeZone = "Mars/Olympus_Mons"
+			valid, _ = reconciler.validateSchedule(suite)
+			Expect(valid).To(BeFalse())
+		})
+	})
+
+	Context("Reconciling the rerunner on a cluster that doesn't support time zones", func() {
+		var pruning *timeZonePruningClient
+
+		getRerunner := func() *unstructured.Unstructured {
+			rerunner := &unstructured.Unstructured{}
+			rerunner.SetGroupVersionKind(cronJobGVK)
+			key := types.NamespacedName{Name: GetRerunnerName(suiteName), Namespace: common.GetComplianceOperatorNamespace()}
+			Expect(reconciler.client.Get(ctx, key, rerunner)).To(Succeed())
+			return rerunner
+		}
+
+		BeforeEach(func() {
+			pruning = &timeZonePruningClient{Client: reconciler.client}
+			reconciler.client = pruning
+			suite.Spec.Schedule = "0 1 * * *"
+			suite.Spec.TimeZone = "Europe/Paris"
+			Expect(reconciler.reconcileScanRerunnerCronJob(suite, logger)).To(Succeed())
+		})
+
+		It("reports the time zone as unsupported in the suite status", func() {
+			cond := suite.Status.Conditions.GetCondition(compv1alpha1.ConditionTimeZoneSupported)
+			Expect(cond).ToNot(BeNil())
+			Expect(cond.Status).To(Equal(corev1.ConditionFalse))
+			Expect(cond.Reason).To(BeEquivalentTo("Pruned"))
+			Expect(getRerunner().GetAnnotations()).To(HaveKeyWithValue(rerunnerPrunedTimeZoneAnnotation, "Europe/Paris"))
+		})
+
+		It("doesn't update the CronJob again to set the time zone", func() {
+			pruning.updates = 0
+			suite.Status.Conditions = nil
+			Expect(reconciler.reconcileScanRerunnerCronJob(suite, logger)).To(Succeed())
+			Expect(pruning.updates).To(Equal(0))
+			Expect(suite.Status.Conditions.GetCondition(compv1alpha1.ConditionTimeZoneSupported)).ToNot(BeNil())
+		})
+
+		It("removes the condition once the suite has no time zone", func() {
+			suite.Spec.TimeZone = ""
+			Expect(reconciler.reconcileScanRerunnerCronJob(suite, logger)).To(Succeed())
+			Expect(suite.Status.Conditions.GetCondition(compv1alpha1.ConditionTimeZoneSupported)).To(BeNil())
+			Expect(getRerunner().GetAnnotations()).ToNot(HaveKey(rerunnerPrunedTimeZoneAnnotation))
+		})
+	})
 })
+
+// timeZonePruningClient prunes the time zone of the CronJobs it writes, as
+// the API servers that don't support it do
+type timeZonePruningClient struct {
+	client.Client
+	updates int
+}
+
+func (c *timeZonePruningClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	pruneTimeZone(obj)
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *timeZonePruningClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	pruneTimeZone(obj)
+	c.updates++
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func pruneTimeZone(obj runtime.Object) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		unstructured.RemoveNestedField(u.Object, "spec", "timeZone")
+	}
+}
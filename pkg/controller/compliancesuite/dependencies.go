@@ -0,0 +1,149 @@
+package compliancesuite
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/complianceremediation"
+)
+
+// suiteDependencyGraph is the remediation dependency graph of a suite. It's
+// created once per reconcile and only built if the remediations or check
+// results of the suite changed since the dependencies in the status of the
+// suite were resolved.
+type suiteDependencyGraph struct {
+	client  client.Client
+	suite   *compv1alpha1.ComplianceSuite
+	version string
+	graph   *complianceremediation.DependencyGraph
+}
+
+func newSuiteDependencyGraph(c client.Client, suite *compv1alpha1.ComplianceSuite) *suiteDependencyGraph {
+	return &suiteDependencyGraph{client: c, suite: suite}
+}
+
+func (g *suiteDependencyGraph) get() (*complianceremediation.DependencyGraph, error) {
+	if g.graph != nil {
+		return g.graph, nil
+	}
+	graph, err := complianceremediation.BuildDependencyGraph(g.client, g.suite.Name, g.suite.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	g.graph = graph
+	return graph, nil
+}
+
+// getVersion returns a digest of the resource versions of the remediations
+// and check results of the suite, which the graph is built from
+func (g *suiteDependencyGraph) getVersion() (string, error) {
+	if g.version != "" {
+		return g.version, nil
+	}
+	listOpts := []client.ListOption{
+		client.InNamespace(g.suite.Namespace),
+		client.MatchingLabels{compv1alpha1.SuiteLabel: g.suite.Name},
+	}
+	remList := &compv1alpha1.ComplianceRemediationList{}
+	if err := g.client.List(context.TODO(), remList, listOpts...); err != nil {
+		return "", err
+	}
+	checkList := &compv1alpha1.ComplianceCheckResultList{}
+	if err := g.client.List(context.TODO(), checkList, listOpts...); err != nil {
+		return "", err
+	}
+
+	versions := make([]string, 0, len(remList.Items)+len(checkList.Items))
+	for i := range remList.Items {
+		versions = append(versions, "remediation/"+remList.Items[i].Name+"/"+remList.Items[i].ResourceVersion)
+	}
+	for i := range checkList.Items {
+		versions = append(versions, "check/"+checkList.Items[i].Name+"/"+checkList.Items[i].ResourceVersion)
+	}
+	sort.Strings(versions)
+	g.version = fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(versions, "\n"))))
+	return g.version, nil
+}
+
+// isResolved tells whether the dependencies in the status of the suite
+// were resolved for the current remediations and check results
+func (g *suiteDependencyGraph) isResolved() (bool, error) {
+	version, err := g.getVersion()
+	if err != nil {
+		return false, err
+	}
+	return version == g.suite.Status.RemediationDependenciesVersion, nil
+}
+
+// blockedRemediations returns the remediations that have a dependency that
+// can't be met, taking them from the status of the suite if it's up to date
+func (g *suiteDependencyGraph) blockedRemediations() (map[string]bool, error) {
+	resolved, err := g.isResolved()
+	if err != nil {
+		return nil, err
+	}
+	if resolved {
+		blocked := map[string]bool{}
+		if g.suite.Status.RemediationDependencies != nil {
+			for _, dep := range g.suite.Status.RemediationDependencies.UnsatisfiableDependencies {
+				blocked[dep.Remediation] = true
+			}
+		}
+		return blocked, nil
+	}
+	graph, err := g.get()
+	if err != nil {
+		return nil, err
+	}
+	return graph.BlockedRemediations(), nil
+}
+
+// reconcileRemediationDependencies sets the remediation dependencies that
+// can't be met in the status of the given suite. The caller is expected to
+// update the status. Events are only issued when the dependencies change,
+// so that they aren't repeated on every reconcile.
+func (r *ReconcileComplianceSuite) reconcileRemediationDependencies(suite *compv1alpha1.ComplianceSuite, deps *suiteDependencyGraph, logger logr.Logger) error {
+	resolved, err := deps.isResolved()
+	if err != nil {
+		return err
+	} else if resolved {
+		return nil
+	}
+	graph, err := deps.get()
+	if err != nil {
+		return err
+	}
+	suite.Status.RemediationDependenciesVersion = deps.version
+
+	depStatus := graph.Status()
+	if reflect.DeepEqual(depStatus, suite.Status.RemediationDependencies) {
+		return nil
+	}
+	suite.Status.RemediationDependencies = depStatus
+	if depStatus == nil {
+		logger.Info("All remediation dependencies can be met")
+		return nil
+	}
+
+	for _, cycle := range depStatus.Cycles {
+		logger.Info("Remediations depend on each other", "ComplianceRemediations", cycle.Remediations)
+		r.recorder.Eventf(suite, corev1.EventTypeWarning, "RemediationDependencyCycle",
+			"The remediations %s depend on each other and can't be applied", strings.Join(cycle.Remediations, ", "))
+	}
+	if len(depStatus.UnsatisfiableDependencies) > 0 {
+		logger.Info("Remediations have dependencies that can't be met", "count", len(depStatus.UnsatisfiableDependencies))
+		r.recorder.Eventf(suite, corev1.EventTypeWarning, "RemediationDependencyUnsatisfiable",
+			"%d remediation dependencies can't be met, see the status of the suite for details",
+			len(depStatus.UnsatisfiableDependencies))
+	}
+	return nil
+}
@@ -2,6 +2,8 @@ package compliancesuite
 
 import (
 	"context"
+	"fmt"
+	"reflect"
 
 	"github.com/go-logr/logr"
 	"github.com/openshift/compliance-operator/pkg/controller/common"
@@ -10,8 +12,11 @@ import (
 	batchv1beta1 "k8s.io/api/batch/v1beta1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -21,9 +26,37 @@ import (
 
 const rerunnerServiceAccount = "rerunner"
 
+// The rerunners are batch/v1 CronJobs. The vendored API predates them, so
+// they're built with the batch/v1beta1 types, whose spec is the same, and
+// managed as unstructured objects. Clusters that don't serve batch/v1
+// CronJobs yet get batch/v1beta1 ones.
+var (
+	cronJobGVK       = batchv1.SchemeGroupVersion.WithKind("CronJob")
+	legacyCronJobGVK = batchv1beta1.SchemeGroupVersion.WithKind("CronJob")
+)
+
+// rerunnerPrunedTimeZoneAnnotation records the time zone the API server
+// pruned from a rerunner, as the cluster doesn't support the time zone of
+// CronJobs, so that the rerunner isn't updated again to set it
+const rerunnerPrunedTimeZoneAnnotation = "compliance.openshift.io/pruned-time-zone"
+
+// The attributes of the rerunners that are kept in sync with their suite
+var rerunnerSyncedFields = [][]string{
+	{"spec", "schedule"},
+	{"spec", "suspend"},
+	{"spec", "startingDeadlineSeconds"},
+	{"spec", "concurrencyPolicy"},
+	{"spec", "timeZone"},
+	{"spec", "jobTemplate", "spec", "template", "spec", "containers"},
+}
+
 func (r *ReconcileComplianceSuite) reconcileScanRerunnerCronJob(suite *compv1alpha1.ComplianceSuite, logger logr.Logger) error {
-	rerunner := r.getRerunner(suite)
+	rerunner, err := r.getRerunner(suite)
+	if err != nil {
+		return err
+	}
 	if suite.Spec.Schedule == "" {
+		suite.Status.Conditions.RemoveCondition(compv1alpha1.ConditionTimeZoneSupported)
 		return r.handleRerunnerDelete(rerunner, suite.Name, logger)
 	}
 	return r.handleCreate(suite, rerunner, logger)
@@ -40,33 +73,145 @@ func (r *ReconcileComplianceSuite) validateSchedule(suite *compv1alpha1.Complian
 	if err != nil {
 		return false, "ComplianceSuite's schedule is wrongly formatted"
 	}
+	if err := suite.Spec.ValidateScheduleOptions(); err != nil {
+		return false, fmt.Sprintf("ComplianceSuite's schedule options are invalid: %s", err)
+	}
 	return true, ""
 }
 
-func (r *ReconcileComplianceSuite) handleCreate(suite *compv1alpha1.ComplianceSuite, rerunner *batchv1beta1.CronJob, logger logr.Logger) error {
-	key := types.NamespacedName{Name: rerunner.GetName(), Namespace: rerunner.GetNamespace()}
-	found := &batchv1beta1.CronJob{}
-	err := r.client.Get(context.TODO(), key, found)
+func (r *ReconcileComplianceSuite) handleCreate(suite *compv1alpha1.ComplianceSuite, rerunner *unstructured.Unstructured, logger logr.Logger) error {
+	found, err := r.getExistingRerunner(rerunner)
 	if err != nil && errors.IsNotFound(err) {
 		// No re-runner found, create it
-		logger.Info("Creating rerunner", "CronJob.Name", rerunner.GetName())
-		return r.client.Create(context.TODO(), rerunner)
+		logger.Info("Creating rerunner", "CronJob.Name", rerunner.GetName(), "CronJob.APIVersion", rerunner.GetAPIVersion())
+		if err := r.client.Create(context.TODO(), rerunner); err != nil {
+			return err
+		}
+		return r.handlePrunedTimeZone(suite, rerunner, logger)
 	} else if err != nil {
 		return err
 	}
-	if found.Spec.Schedule != suite.Spec.Schedule {
-		cronJobCopy := found.DeepCopy()
-		cronJobCopy.Spec.Schedule = suite.Spec.Schedule
-		logger.Info("Updating rerunner", "CronJob.Name", rerunner.GetName())
-		return r.client.Update(context.TODO(), cronJobCopy)
+
+	cronJobCopy := found.DeepCopy()
+	changed := false
+	for _, field := range rerunnerSyncedFields {
+		// The time zone the cluster doesn't support is left out, as it
+		// would be pruned again
+		if field[len(field)-1] == "timeZone" && isTimeZonePruned(found, suite.Spec.TimeZone) {
+			continue
+		}
+		desired, desiredFound, _ := unstructured.NestedFieldNoCopy(rerunner.Object, field...)
+		current, _, _ := unstructured.NestedFieldNoCopy(found.Object, field...)
+		// The containers are compared by their command, the rest is
+		// defaulted by the API server
+		if field[len(field)-1] == "containers" {
+			desired, current = containerCommands(desired), containerCommands(current)
+			if reflect.DeepEqual(desired, current) {
+				continue
+			}
+			desired, _, _ = unstructured.NestedFieldCopy(rerunner.Object, field...)
+		} else if reflect.DeepEqual(desired, current) {
+			continue
+		}
+		changed = true
+		if !desiredFound {
+			unstructured.RemoveNestedField(cronJobCopy.Object, field...)
+			continue
+		}
+		if err := unstructured.SetNestedField(cronJobCopy.Object, runtime.DeepCopyJSONValue(desired), field...); err != nil {
+			return err
+		}
+	}
+	// Forget the pruned time zone once the suite uses another one
+	if pruned, ok := found.GetAnnotations()[rerunnerPrunedTimeZoneAnnotation]; ok && pruned != suite.Spec.TimeZone {
+		annotations := cronJobCopy.GetAnnotations()
+		delete(annotations, rerunnerPrunedTimeZoneAnnotation)
+		cronJobCopy.SetAnnotations(annotations)
+		changed = true
+	}
+	if !changed {
+		setTimeZoneCondition(suite, isTimeZonePruned(found, suite.Spec.TimeZone))
+		return nil
+	}
+	logger.Info("Updating rerunner", "CronJob.Name", rerunner.GetName())
+	if err := r.client.Update(context.TODO(), cronJobCopy); err != nil {
+		return err
+	}
+	return r.handlePrunedTimeZone(suite, cronJobCopy, logger)
+}
+
+// handlePrunedTimeZone reports in the status of the suite whether the API
+// server pruned the time zone of the rerunner it just wrote, which it does
+// when the cluster doesn't support it. The pruned time zone is recorded in
+// the rerunner so that it isn't updated again to set it.
+func (r *ReconcileComplianceSuite) handlePrunedTimeZone(suite *compv1alpha1.ComplianceSuite, written *unstructured.Unstructured, logger logr.Logger) error {
+	_, kept, _ := unstructured.NestedString(written.Object, "spec", "timeZone")
+	pruned := suite.Spec.TimeZone != "" && !kept
+	setTimeZoneCondition(suite, pruned)
+	if !pruned || isTimeZonePruned(written, suite.Spec.TimeZone) {
+		return nil
+	}
+
+	logger.Info("The cluster doesn't support the time zone of CronJobs, ignoring it",
+		"CronJob.Name", written.GetName(), "TimeZone", suite.Spec.TimeZone)
+	annotations := written.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[rerunnerPrunedTimeZoneAnnotation] = suite.Spec.TimeZone
+	written.SetAnnotations(annotations)
+	return r.client.Update(context.TODO(), written)
+}
+
+// isTimeZonePruned returns whether the API server pruned the given time
+// zone from the rerunner
+func isTimeZonePruned(rerunner *unstructured.Unstructured, timeZone string) bool {
+	if timeZone == "" {
+		return false
+	}
+	if _, ok, _ := unstructured.NestedString(rerunner.Object, "spec", "timeZone"); ok {
+		return false
+	}
+	return rerunner.GetAnnotations()[rerunnerPrunedTimeZoneAnnotation] == timeZone
+}
+
+func setTimeZoneCondition(suite *compv1alpha1.ComplianceSuite, pruned bool) {
+	if pruned {
+		suite.Status.SetConditionTimeZoneUnsupported()
+	} else {
+		suite.Status.Conditions.RemoveCondition(compv1alpha1.ConditionTimeZoneSupported)
+	}
+}
+
+// containerCommands returns the commands of unstructured containers
+func containerCommands(containers interface{}) []interface{} {
+	list, _ := containers.([]interface{})
+	commands := make([]interface{}, 0, len(list))
+	for _, c := range list {
+		container, _ := c.(map[string]interface{})
+		commands = append(commands, container["command"])
 	}
-	return nil
+	return commands
 }
 
-func (r *ReconcileComplianceSuite) handleRerunnerDelete(rerunner *batchv1beta1.CronJob, suiteName string, logger logr.Logger) error {
+// getExistingRerunner gets the rerunner, switching it to a batch/v1beta1
+// CronJob if the cluster doesn't serve batch/v1 ones
+func (r *ReconcileComplianceSuite) getExistingRerunner(rerunner *unstructured.Unstructured) (*unstructured.Unstructured, error) {
 	key := types.NamespacedName{Name: rerunner.GetName(), Namespace: rerunner.GetNamespace()}
-	found := &batchv1beta1.CronJob{}
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(rerunner.GroupVersionKind())
 	err := r.client.Get(context.TODO(), key, found)
+	if err == nil || !meta.IsNoMatchError(err) || rerunner.GroupVersionKind() == legacyCronJobGVK {
+		return found, err
+	}
+
+	// batch/v1beta1 CronJobs have no time zone, the API server prunes it
+	rerunner.SetGroupVersionKind(legacyCronJobGVK)
+	return r.getExistingRerunner(rerunner)
+}
+
+func (r *ReconcileComplianceSuite) handleRerunnerDelete(rerunner *unstructured.Unstructured, suiteName string, logger logr.Logger) error {
+	_, err := r.getExistingRerunner(rerunner)
 	if err != nil && errors.IsNotFound(err) {
 		// No re-runner found, we're good
 		return nil
@@ -98,16 +243,29 @@ func GetRerunnerName(suiteName string) string {
 	return suiteName + "-rerunner"
 }
 
-func (r *ReconcileComplianceSuite) getRerunner(suite *compv1alpha1.ComplianceSuite) *batchv1beta1.CronJob {
+func (r *ReconcileComplianceSuite) getRerunner(suite *compv1alpha1.ComplianceSuite) (*unstructured.Unstructured, error) {
 	falseP := false
 	trueP := true
-	return &batchv1beta1.CronJob{
+	command := []string{
+		"compliance-operator", "suitererunner",
+		"--name", suite.GetName(),
+		"--namespace", suite.GetNamespace(),
+	}
+	if suite.Spec.ScheduleJitter != "" {
+		command = append(command, "--jitter", suite.Spec.ScheduleJitter)
+	}
+	cronJob := &batchv1beta1.CronJob{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      GetRerunnerName(suite.Name),
 			Namespace: common.GetComplianceOperatorNamespace(),
 		},
 		Spec: batchv1beta1.CronJobSpec{
-			Schedule: suite.Spec.Schedule,
+			Schedule:                suite.Spec.Schedule,
+			Suspend:                 &suite.Spec.Suspend,
+			StartingDeadlineSeconds: suite.Spec.StartingDeadlineSeconds,
+			// The rerunner skips the run if the previous one is still in
+			// progress, this keeps rerunners from piling up meanwhile
+			ConcurrencyPolicy: batchv1beta1.ForbidConcurrent,
 			JobTemplate: batchv1beta1.JobTemplateSpec{
 				Spec: batchv1.JobSpec{
 					Template: corev1.PodTemplateSpec{
@@ -134,11 +292,7 @@ func (r *ReconcileComplianceSuite) getRerunner(suite *compv1alpha1.ComplianceSui
 										AllowPrivilegeEscalation: &falseP,
 										ReadOnlyRootFilesystem:   &trueP,
 									},
-									Command: []string{
-										"compliance-operator", "suitererunner",
-										"--name", suite.GetName(),
-										"--namespace", suite.GetNamespace(),
-									},
+									Command: command,
 									Resources: corev1.ResourceRequirements{
 										Requests: corev1.ResourceList{
 											corev1.ResourceMemory: resource.MustParse("20Mi"),
@@ -157,4 +311,18 @@ func (r *ReconcileComplianceSuite) getRerunner(suite *compv1alpha1.ComplianceSui
 			},
 		},
 	}
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cronJob)
+	if err != nil {
+		return nil, err
+	}
+	rerunner := &unstructured.Unstructured{Object: obj}
+	rerunner.SetGroupVersionKind(cronJobGVK)
+	// The batch/v1beta1 types have no time zone
+	if suite.Spec.TimeZone != "" {
+		if err := unstructured.SetNestedField(rerunner.Object, suite.Spec.TimeZone, "spec", "timeZone"); err != nil {
+			return nil, err
+		}
+	}
+	return rerunner, nil
 }
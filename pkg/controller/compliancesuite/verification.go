@@ -0,0 +1,301 @@
+package compliancesuite
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	mcfgv1 "github.com/openshift/machine-config-operator/pkg/apis/machineconfiguration.openshift.io/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/complianceremediation"
+	"github.com/openshift/compliance-operator/pkg/utils"
+)
+
+// How often to check whether the remediations to verify are in effect
+const requeueAfterVerification = 30 * time.Second
+
+// markForVerification marks a remediation the suite is about to apply, so
+// that its scan is rescanned once the remediation is in effect
+func markForVerification(rem *compv1alpha1.ComplianceRemediation) {
+	if rem.Spec.Apply {
+		return
+	}
+	if rem.Annotations == nil {
+		rem.Annotations = make(map[string]string)
+	}
+	rem.Annotations[compv1alpha1.RemediationPendingVerificationAnnotation] = ""
+}
+
+// reconcileRemediationVerification rescans the scans of the remediations
+// the suite applied once they're in effect, and reports whether the
+// remediated checks pass in the status of the given suite. The caller is
+// expected to update the status. For MachineConfig and KubeletConfig
+// remediations, that means waiting for the affected pools to finish
+// updating.
+func (r *ReconcileComplianceSuite) reconcileRemediationVerification(suite *compv1alpha1.ComplianceSuite, logger logr.Logger) (reconcile.Result, error) {
+	verification := suite.Status.RemediationVerification
+	if verification != nil && verification.Phase == compv1alpha1.RemediationVerificationRescanning {
+		return reconcile.Result{}, r.reconcileVerificationRescan(suite, logger)
+	}
+
+	remList := &compv1alpha1.ComplianceRemediationList{}
+	listOpts := client.ListOptions{
+		Namespace:     suite.Namespace,
+		LabelSelector: labels.SelectorFromSet(labels.Set{compv1alpha1.SuiteLabel: suite.Name}),
+	}
+	if err := r.client.List(context.TODO(), remList, &listOpts); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	pending := make([]*compv1alpha1.ComplianceRemediation, 0)
+	for i := range remList.Items {
+		if remList.Items[i].HasAnnotation(compv1alpha1.RemediationPendingVerificationAnnotation) {
+			pending = append(pending, &remList.Items[i])
+		}
+	}
+	if len(pending) == 0 {
+		// The remediations were un-applied or removed while waiting
+		if verification != nil && verification.Phase == compv1alpha1.RemediationVerificationWaiting {
+			suite.Status.RemediationVerification = nil
+		}
+		return reconcile.Result{}, nil
+	}
+
+	// The remediations are only applied once the scans are done
+	if suite.Status.Phase != compv1alpha1.PhaseDone {
+		return reconcile.Result{}, nil
+	}
+
+	mcfgpools := &mcfgv1.MachineConfigPoolList{}
+	if err := r.client.List(context.TODO(), mcfgpools); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	toVerify := make([]string, 0)
+	scansToRescan := make(map[string]bool)
+	poolMachineConfigs := make(map[string][]string)
+	affectedPools := make(map[string]*mcfgv1.MachineConfigPool)
+	waiting := false
+	for _, rem := range pending {
+		if !rem.Spec.Apply || rem.Status.ApplicationState == compv1alpha1.RemediationError ||
+			rem.Status.ApplicationState == compv1alpha1.RemediationNeedsReview {
+			// The remediation won't be in effect, there's nothing to verify
+			logger.Info("Not verifying remediation that isn't applied", "ComplianceRemediation.Name", rem.Name,
+				"ComplianceRemediation.State", rem.Status.ApplicationState)
+			if err := r.clearPendingVerification(rem.Name, rem.Namespace); err != nil {
+				return reconcile.Result{}, err
+			}
+			continue
+		}
+		if !rem.IsApplied() {
+			waiting = true
+		}
+		toVerify = append(toVerify, rem.Name)
+		scansToRescan[rem.GetScan()] = true
+
+		if !utils.IsMachineConfig(rem.Spec.Current.Object) && !utils.IsKubeletConfig(rem.Spec.Current.Object) {
+			continue
+		}
+		scan := &compv1alpha1.ComplianceScan{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Name: rem.GetScan(), Namespace: rem.Namespace}, scan); err != nil {
+			return reconcile.Result{}, err
+		}
+		pool := r.getAffectedMcfgPool(scan, mcfgpools)
+		if pool == nil {
+			continue
+		}
+		affectedPools[pool.Name] = pool
+		if utils.IsMachineConfig(rem.Spec.Current.Object) {
+			mcName := rem.GetMcName()
+			if suite.Spec.MergeMachineConfigRemediations {
+				mcName = rem.GetMergedMcName()
+			}
+			poolMachineConfigs[pool.Name] = append(poolMachineConfigs[pool.Name], mcName)
+		}
+	}
+	if len(toVerify) == 0 {
+		return reconcile.Result{}, nil
+	}
+	sort.Strings(toVerify)
+
+	updatingPools := make([]string, 0)
+	for _, pool := range affectedPools {
+		updated, reason := utils.IsMcfgPoolUpdated(pool, poolMachineConfigs[pool.Name])
+		if updated {
+			var err error
+			updated, err, reason = utils.AreKubeletConfigsRendered(pool, r.client)
+			if err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+		if !updated {
+			logger.Info("Waiting for the pool to update before verifying the remediations", "MachineConfigPool.Name", pool.Name, "Reason", reason)
+			updatingPools = append(updatingPools, pool.Name)
+		}
+	}
+	sort.Strings(updatingPools)
+
+	if waiting || len(updatingPools) > 0 {
+		suite.Status.RemediationVerification = &compv1alpha1.RemediationVerificationStatus{
+			Phase:        compv1alpha1.RemediationVerificationWaiting,
+			Remediations: toVerify,
+			Pools:        updatingPools,
+		}
+		return reconcile.Result{RequeueAfter: requeueAfterVerification}, nil
+	}
+
+	scanNames := make([]string, 0, len(scansToRescan))
+	for scanName := range scansToRescan {
+		scanNames = append(scanNames, scanName)
+	}
+	sort.Strings(scanNames)
+	for _, scanName := range scanNames {
+		if err := r.rescanForVerification(scanName, suite.Namespace); err != nil {
+			logger.Error(err, "Cannot rescan the scan to verify the remediations", "ComplianceScan.Name", scanName)
+			return reconcile.Result{}, err
+		}
+	}
+
+	now := metav1.Now()
+	suite.Status.RemediationVerification = &compv1alpha1.RemediationVerificationStatus{
+		Phase:        compv1alpha1.RemediationVerificationRescanning,
+		Remediations: toVerify,
+		RescanTime:   &now,
+	}
+	logger.Info("Rescanning to verify the remediations", "ComplianceScans", scanNames, "ComplianceRemediations", toVerify)
+	r.recorder.Eventf(suite, corev1.EventTypeNormal, "VerifyingRemediations",
+		"Rescanning %s to verify %d applied remediations", strings.Join(scanNames, ", "), len(toVerify))
+	return reconcile.Result{}, nil
+}
+
+// reconcileVerificationRescan reports the results of the remediated checks
+// once the verification rescan is done
+func (r *ReconcileComplianceSuite) reconcileVerificationRescan(suite *compv1alpha1.ComplianceSuite, logger logr.Logger) error {
+	verification := suite.Status.RemediationVerification.DeepCopy()
+
+	remediations := make([]*compv1alpha1.ComplianceRemediation, 0, len(verification.Remediations))
+	for _, remName := range verification.Remediations {
+		rem := &compv1alpha1.ComplianceRemediation{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: remName, Namespace: suite.Namespace}, rem)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		remediations = append(remediations, rem)
+	}
+
+	checked := make(map[string]bool)
+	for _, rem := range remediations {
+		if checked[rem.GetScan()] {
+			continue
+		}
+		checked[rem.GetScan()] = true
+		scan := &compv1alpha1.ComplianceScan{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Name: rem.GetScan(), Namespace: suite.Namespace}, scan); err != nil {
+			return err
+		}
+		if !isRescannedSince(scan, verification.RescanTime) {
+			logger.Info("Waiting for the verification rescan", "ComplianceScan.Name", scan.Name)
+			return nil
+		}
+	}
+
+	verification.Phase = compv1alpha1.RemediationVerificationDone
+	verification.Results = make([]compv1alpha1.RemediationVerificationResult, 0, len(remediations))
+	failing := make([]string, 0)
+	for _, rem := range remediations {
+		checkName := complianceremediation.GetOwningCheckName(rem)
+		check := &compv1alpha1.ComplianceCheckResult{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: checkName, Namespace: suite.Namespace}, check)
+		if errors.IsNotFound(err) {
+			// The rule is no longer checked
+			continue
+		} else if err != nil {
+			return err
+		}
+		verification.Results = append(verification.Results, compv1alpha1.RemediationVerificationResult{
+			Remediation: rem.Name,
+			CheckResult: check.Name,
+			Status:      check.Status,
+		})
+		if check.Status == compv1alpha1.CheckResultPass {
+			verification.Passed++
+		} else {
+			verification.Failed++
+			failing = append(failing, check.Name)
+		}
+	}
+
+	for _, rem := range remediations {
+		if err := r.clearPendingVerification(rem.Name, rem.Namespace); err != nil {
+			return err
+		}
+	}
+
+	suite.Status.RemediationVerification = verification
+	logger.Info("Verified the remediations", "Passed", verification.Passed, "Failed", verification.Failed)
+	if len(failing) > 0 {
+		r.recorder.Eventf(suite, corev1.EventTypeWarning, "RemediationsNotVerified",
+			"%d of %d remediated checks still don't pass after the rescan: %s",
+			verification.Failed, len(verification.Results), strings.Join(failing, ", "))
+	} else {
+		r.recorder.Eventf(suite, corev1.EventTypeNormal, "RemediationsVerified",
+			"All the %d remediated checks pass after the rescan", verification.Passed)
+	}
+	return nil
+}
+
+// isRescannedSince returns whether the scan started a run at or after the
+// given time and finished it
+func isRescannedSince(scan *compv1alpha1.ComplianceScan, since *metav1.Time) bool {
+	if scan.Status.Phase != compv1alpha1.PhaseDone || scan.NeedsRescan() {
+		return false
+	}
+	started := scan.Status.StartTimestamp
+	return started != nil && since != nil && !started.Before(since)
+}
+
+func (r *ReconcileComplianceSuite) rescanForVerification(scanName, namespace string) error {
+	key := types.NamespacedName{Name: scanName, Namespace: namespace}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		scan := &compv1alpha1.ComplianceScan{}
+		if err := r.client.Get(context.TODO(), key, scan); err != nil {
+			return err
+		}
+		if scan.NeedsRescan() {
+			return nil
+		}
+		if scan.Annotations == nil {
+			scan.Annotations = make(map[string]string)
+		}
+		scan.Annotations[compv1alpha1.ComplianceScanRescanAnnotation] = ""
+		return r.client.Update(context.TODO(), scan)
+	})
+}
+
+func (r *ReconcileComplianceSuite) clearPendingVerification(remName, namespace string) error {
+	key := types.NamespacedName{Name: remName, Namespace: namespace}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		rem := &compv1alpha1.ComplianceRemediation{}
+		if err := r.client.Get(context.TODO(), key, rem); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		if !rem.HasAnnotation(compv1alpha1.RemediationPendingVerificationAnnotation) {
+			return nil
+		}
+		delete(rem.Annotations, compv1alpha1.RemediationPendingVerificationAnnotation)
+		return r.client.Update(context.TODO(), rem)
+	})
+}
@@ -3,25 +3,42 @@ package controller
 import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
+	"github.com/openshift/compliance-operator/pkg/controller/cloudevents"
 	"github.com/openshift/compliance-operator/pkg/controller/metrics"
+	"github.com/openshift/compliance-operator/pkg/controller/notifier"
+	"github.com/openshift/compliance-operator/pkg/controller/shared"
 	"github.com/openshift/compliance-operator/pkg/utils"
 )
 
 // AddToManagerFuncs is a list of functions to add all Controllers to the Manager
-var AddToManagerFuncs []func(manager.Manager, *metrics.Metrics, utils.CtlplaneSchedulingInfo) error
+var AddToManagerFuncs []func(manager.Manager, shared.Dependencies) error
 
 // AddToManager adds all Controllers to the Manager
 func AddToManager(m manager.Manager,
 	met *metrics.Metrics,
 	si utils.CtlplaneSchedulingInfo,
+	events *cloudevents.Emitter,
 ) error {
 	// Add metrics Startup to the manager
 	if err := m.Add(met); err != nil {
 		return err
 	}
 
+	// The notifications of all the controllers are sent by the same
+	// workers
+	n := notifier.New(m.GetClient())
+	if err := m.Add(n); err != nil {
+		return err
+	}
+
+	deps := shared.Dependencies{
+		Metrics:        met,
+		SchedulingInfo: si,
+		Notifier:       n,
+		Events:         events,
+	}
 	for _, f := range AddToManagerFuncs {
-		if err := f(m, met, si); err != nil {
+		if err := f(m, deps); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"fmt"
+
+	monitoring "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+const (
+	// AlertScanStuck fires for a scan that remains in a phase other than
+	// DONE for too long
+	AlertScanStuck = "ComplianceScanStuck"
+	// AlertRemediationFailed fires for a suite with remediations that
+	// failed to be applied
+	AlertRemediationFailed = "ComplianceRemediationFailed"
+	// AlertProfileBundleInvalid fires for a profile bundle whose content
+	// can't be parsed
+	AlertProfileBundleInvalid = "ComplianceProfileBundleInvalid"
+
+	// AlertLabelResult is the label of the result that fired a suite alert
+	AlertLabelResult   = "result"
+	alertLabelSeverity = "severity"
+
+	scanStuckFor         = "2h"
+	remediationErrorFor  = "15m"
+	bundleInvalidFor     = "5m"
+	builtinAlertSeverity = "warning"
+)
+
+// The values of the compliance_state gauge for the results that fire an
+// alert
+var suiteAlertStates = map[v1alpha1.AlertResult]int{
+	v1alpha1.AlertResult(v1alpha1.ResultNonCompliant): METRIC_STATE_NON_COMPLIANT,
+	v1alpha1.AlertResult(v1alpha1.ResultInconsistent): METRIC_STATE_INCONSISTENT,
+	v1alpha1.AlertResult(v1alpha1.ResultError):        METRIC_STATE_ERROR,
+}
+
+var suiteAlertNames = map[v1alpha1.AlertResult]string{
+	v1alpha1.AlertResult(v1alpha1.ResultNonCompliant): "NonCompliant",
+	v1alpha1.AlertResult(v1alpha1.ResultInconsistent): "ComplianceInconsistent",
+	v1alpha1.AlertResult(v1alpha1.ResultError):        "ComplianceError",
+}
+
+// BuiltinAlertRules returns the alerts that the operator fires regardless
+// of the alerting configuration of the ScanSettings
+func BuiltinAlertRules() []monitoring.Rule {
+	return []monitoring.Rule{
+		{
+			Alert: AlertScanStuck,
+			Expr: intstr.FromString(fmt.Sprintf(`%s_%s{phase=~"%s|%s|%s|%s"} == 1`, metricNamespace, metricNameComplianceScanPhase,
+				v1alpha1.PhasePending, v1alpha1.PhaseLaunching, v1alpha1.PhaseRunning, v1alpha1.PhaseAggregating)),
+			For:    scanStuckFor,
+			Labels: map[string]string{alertLabelSeverity: builtinAlertSeverity},
+			Annotations: map[string]string{
+				"summary":     "A compliance scan is stuck",
+				"description": "The compliance scan {{ $labels.name }} has been in the {{ $labels.phase }} phase for more than " + scanStuckFor,
+			},
+		},
+		{
+			Alert: AlertRemediationFailed,
+			Expr: intstr.FromString(fmt.Sprintf(`%s_%s{state="%s"} > 0`, metricNamespace, metricNameComplianceRemediations,
+				v1alpha1.RemediationError)),
+			For:    remediationErrorFor,
+			Labels: map[string]string{alertLabelSeverity: builtinAlertSeverity},
+			Annotations: map[string]string{
+				"summary":     "Compliance remediations failed to be applied",
+				"description": "{{ $value }} remediations of the compliance suite {{ $labels.suite }} failed to be applied",
+			},
+		},
+		{
+			Alert: AlertProfileBundleInvalid,
+			Expr: intstr.FromString(fmt.Sprintf(`%s_%s{status="%s"} == 1`, metricNamespace, metricNameProfileBundleStatus,
+				v1alpha1.DataStreamInvalid)),
+			For:    bundleInvalidFor,
+			Labels: map[string]string{alertLabelSeverity: builtinAlertSeverity},
+			Annotations: map[string]string{
+				"summary":     "A compliance profile bundle is invalid",
+				"description": "The content of the profile bundle {{ $labels.name }} couldn't be parsed",
+			},
+		},
+	}
+}
+
+// SuiteAlertRules returns the alerts for the results of a suite, as
+// configured by the alerting settings of its ScanSetting
+func SuiteAlertRules(suite string, alerting *v1alpha1.ScanSettingAlerting) []monitoring.Rule {
+	var rules []monitoring.Rule
+	for _, result := range alerting.GetAlertResults() {
+		state, ok := suiteAlertStates[result]
+		if !ok {
+			continue
+		}
+		labels := map[string]string{}
+		if alerting != nil {
+			for key, value := range alerting.Labels {
+				labels[key] = value
+			}
+		}
+		labels[alertLabelSeverity] = alerting.GetSeverity(result)
+		labels[AlertLabelResult] = string(result)
+
+		rules = append(rules, monitoring.Rule{
+			Alert:  suiteAlertNames[result],
+			Expr:   intstr.FromString(fmt.Sprintf(`%s_%s{name=%q} == %d`, metricNamespace, metricNameComplianceStateGauge, suite, state)),
+			For:    alerting.GetFor(),
+			Labels: labels,
+			Annotations: map[string]string{
+				"summary":     "The cluster is out-of-compliance",
+				"description": fmt.Sprintf("The compliance suite {{ $labels.name }} returned as %s", result),
+			},
+		})
+	}
+	return rules
+}
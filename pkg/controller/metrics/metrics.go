@@ -4,6 +4,9 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net/http"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	libgocrypto "github.com/openshift/library-go/pkg/crypto"
@@ -21,7 +24,16 @@ const (
 	metricNameComplianceScanStatus        = "compliance_scan_status_total"
 	metricNameComplianceScanError         = "compliance_scan_error_total"
 	metricNameComplianceRemediationStatus = "compliance_remediation_status_total"
+	metricNameComplianceRemediationDrift  = "compliance_remediation_drift_total"
 	metricNameComplianceStateGauge        = "compliance_state"
+	metricNameComplianceCheckResults      = "compliance_check_results"
+	metricNameComplianceFailingRuleInfo   = "compliance_failing_rule_info"
+	metricNameComplianceRemediations      = "compliance_remediations"
+	metricNameComplianceScanPhaseDuration = "compliance_scan_phase_duration_seconds"
+	metricNameComplianceScanPhase         = "compliance_scan_phase"
+	metricNameProfileBundleStatus         = "compliance_profile_bundle_status"
+	metricNameComplianceScanPhaseSeconds  = "compliance_scan_phase_seconds"
+	metricNameComplianceScanDuration      = "compliance_scan_duration_seconds"
 
 	metricLabelScanResult       = "result"
 	metricLabelScanName         = "name"
@@ -30,10 +42,27 @@ const (
 	metricLabelScanError        = "error"
 	metricLabelRemediationName  = "name"
 	metricLabelRemediationState = "state"
+	metricLabelDriftAction      = "action"
+	metricLabelSuite            = "suite"
+	metricLabelScan             = "scan"
+	metricLabelCheckStatus      = "status"
+	metricLabelCheckSeverity    = "severity"
+	metricLabelRule             = "rule"
+	metricLabelBundleName       = "name"
+	metricLabelBundleStatus     = "status"
+
+	// DriftActionReported is used when drift of a remediation was only reported
+	DriftActionReported = "reported"
+	// DriftActionReapplied is used when a drifted remediation was re-applied
+	DriftActionReapplied = "reapplied"
 
 	HandlerPath                  = "/metrics-co"
 	ControllerMetricsServiceName = "metrics-co"
 	MetricsAddrListen            = ":8585"
+
+	// DefaultMaxFailingRulesPerScan is the default number of failing rule
+	// series exported per scan
+	DefaultMaxFailingRulesPerScan = 250
 )
 
 const (
@@ -48,13 +77,91 @@ type Metrics struct {
 	impl    impl
 	log     logr.Logger
 	metrics *ControllerMetrics
+	opts    CardinalityOptions
+
+	// The series currently set per scan and per suite, so that the ones
+	// that are gone can be deleted when the gauges are set again
+	mutex             sync.Mutex
+	checkResultSeries map[string][]prometheus.Labels
+	failingRuleSeries map[string][]prometheus.Labels
+	// The suite and application state each remediation was last seen in,
+	// keyed by namespaced name, and the resulting counts per suite and state
+	remediationStates map[string]remediationState
+	remediationCounts map[string]map[string]int
+	// The phase each scan was last seen in, and since when
+	scanPhases map[string]scanPhase
+	// The data stream status each profile bundle was last seen in
+	bundleStatuses map[string]string
+	// The timings of the current run of each scan that were observed
+	observedRuns map[string]observedRun
+	// When the timings started to be observed. The timings that ended
+	// before were observed by a previous instance of the operator.
+	started time.Time
+}
+
+// CardinalityOptions limit the number of series of the metrics derived
+// from check results
+type CardinalityOptions struct {
+	// Export a series for each failing rule of a scan
+	FailingRules bool
+	// The maximum number of failing rule series per scan. Rules of a
+	// higher severity are exported first. 0 means no limit.
+	MaxFailingRulesPerScan int
+}
+
+// DefaultCardinalityOptions returns the options used unless others are set
+func DefaultCardinalityOptions() CardinalityOptions {
+	return CardinalityOptions{
+		FailingRules:           true,
+		MaxFailingRulesPerScan: DefaultMaxFailingRulesPerScan,
+	}
+}
+
+type scanPhase struct {
+	phase v1alpha1.ComplianceScanStatusPhase
+	since time.Time
+}
+
+type remediationState struct {
+	suite string
+	state string
+}
+
+type observedRun struct {
+	// The start of the run
+	start time.Time
+	// The number of phase timings of the run observed
+	phases int
+	// Whether the duration of the run was observed
+	done bool
+}
+
+// The buckets of the phase and scan duration histograms, from 5 seconds
+// to about 3 hours
+var scanDurationBuckets = prometheus.ExponentialBuckets(5, 2, 12)
+
+// The phases a scan records the timings of
+var timedScanPhases = []v1alpha1.ComplianceScanStatusPhase{
+	v1alpha1.PhasePending,
+	v1alpha1.PhaseLaunching,
+	v1alpha1.PhaseRunning,
+	v1alpha1.PhaseAggregating,
 }
 
 type ControllerMetrics struct {
 	metricComplianceScanError         *prometheus.CounterVec
 	metricComplianceScanStatus        *prometheus.CounterVec
 	metricComplianceRemediationStatus *prometheus.CounterVec
+	metricComplianceRemediationDrift  *prometheus.CounterVec
 	metricComplianceStateGauge        *prometheus.GaugeVec
+	metricComplianceCheckResults      *prometheus.GaugeVec
+	metricComplianceFailingRuleInfo   *prometheus.GaugeVec
+	metricComplianceRemediations      *prometheus.GaugeVec
+	metricComplianceScanPhaseDuration *prometheus.GaugeVec
+	metricComplianceScanPhase         *prometheus.GaugeVec
+	metricProfileBundleStatus         *prometheus.GaugeVec
+	metricComplianceScanPhaseSeconds  *prometheus.HistogramVec
+	metricComplianceScanDuration      *prometheus.HistogramVec
 }
 
 func DefaultControllerMetrics() *ControllerMetrics {
@@ -90,6 +197,17 @@ func DefaultControllerMetrics() *ControllerMetrics {
 				metricLabelRemediationState,
 			},
 		),
+		metricComplianceRemediationDrift: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:      metricNameComplianceRemediationDrift,
+				Namespace: metricNamespace,
+				Help:      "A counter for the total number of times an applied ComplianceRemediation was found to have drifted",
+			},
+			[]string{
+				metricLabelRemediationName,
+				metricLabelDriftAction,
+			},
+		),
 		metricComplianceStateGauge: prometheus.NewGaugeVec(
 			prometheus.GaugeOpts{
 				Name:      metricNameComplianceStateGauge,
@@ -100,6 +218,98 @@ func DefaultControllerMetrics() *ControllerMetrics {
 				metricLabelSuiteName,
 			},
 		),
+		metricComplianceCheckResults: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name:      metricNameComplianceCheckResults,
+				Namespace: metricNamespace,
+				Help:      "A gauge for the number of ComplianceCheckResults of the latest run of a ComplianceScan by status and severity",
+			},
+			[]string{
+				metricLabelSuite,
+				metricLabelScan,
+				metricLabelCheckStatus,
+				metricLabelCheckSeverity,
+			},
+		),
+		metricComplianceFailingRuleInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name:      metricNameComplianceFailingRuleInfo,
+				Namespace: metricNamespace,
+				Help:      "A gauge set to 1 for each rule that failed in the latest run of a ComplianceScan, and whose ComplianceCheckResult is kept",
+			},
+			[]string{
+				metricLabelScan,
+				metricLabelRule,
+				metricLabelCheckSeverity,
+			},
+		),
+		metricComplianceRemediations: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name:      metricNameComplianceRemediations,
+				Namespace: metricNamespace,
+				Help:      "A gauge for the number of ComplianceRemediations of a ComplianceSuite by application state",
+			},
+			[]string{
+				metricLabelSuite,
+				metricLabelRemediationState,
+			},
+		),
+		metricComplianceScanPhaseDuration: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name:      metricNameComplianceScanPhaseDuration,
+				Namespace: metricNamespace,
+				Help:      "A gauge for the number of seconds the latest run of a ComplianceScan spent in a phase",
+			},
+			[]string{
+				metricLabelScanName,
+				metricLabelScanPhase,
+			},
+		),
+		metricComplianceScanPhase: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name:      metricNameComplianceScanPhase,
+				Namespace: metricNamespace,
+				Help:      "A gauge set to 1 for the phase a ComplianceScan is in",
+			},
+			[]string{
+				metricLabelScanName,
+				metricLabelScanPhase,
+			},
+		),
+		metricProfileBundleStatus: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name:      metricNameProfileBundleStatus,
+				Namespace: metricNamespace,
+				Help:      "A gauge set to 1 for the data stream status a ProfileBundle is in",
+			},
+			[]string{
+				metricLabelBundleName,
+				metricLabelBundleStatus,
+			},
+		),
+		metricComplianceScanPhaseSeconds: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:      metricNameComplianceScanPhaseSeconds,
+				Namespace: metricNamespace,
+				Help:      "A histogram of the number of seconds the runs of a ComplianceScan spent in each phase",
+				Buckets:   scanDurationBuckets,
+			},
+			[]string{
+				metricLabelScanName,
+				metricLabelScanPhase,
+			},
+		),
+		metricComplianceScanDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:      metricNameComplianceScanDuration,
+				Namespace: metricNamespace,
+				Help:      "A histogram of the number of seconds the runs of a ComplianceScan took from PENDING to DONE",
+				Buckets:   scanDurationBuckets,
+			},
+			[]string{
+				metricLabelScanName,
+			},
+		),
 	}
 }
 
@@ -108,9 +318,27 @@ func NewMetrics(imp impl) *Metrics {
 		impl:    imp,
 		log:     ctrllog.Log.WithName("metrics"),
 		metrics: DefaultControllerMetrics(),
+		opts:    DefaultCardinalityOptions(),
+
+		checkResultSeries: map[string][]prometheus.Labels{},
+		failingRuleSeries: map[string][]prometheus.Labels{},
+		remediationStates: map[string]remediationState{},
+		remediationCounts: map[string]map[string]int{},
+		scanPhases:        map[string]scanPhase{},
+		bundleStatuses:    map[string]string{},
+		observedRuns:      map[string]observedRun{},
+		started:           time.Now(),
 	}
 }
 
+// SetCardinalityOptions sets the options limiting the number of series of
+// the metrics derived from check results
+func (m *Metrics) SetCardinalityOptions(opts CardinalityOptions) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.opts = opts
+}
+
 // New returns a new default Metrics instance.
 func New() *Metrics {
 	return NewMetrics(&defaultImpl{})
@@ -122,7 +350,16 @@ func (m *Metrics) Register() error {
 		metricNameComplianceScanError:         m.metrics.metricComplianceScanError,
 		metricNameComplianceScanStatus:        m.metrics.metricComplianceScanStatus,
 		metricNameComplianceRemediationStatus: m.metrics.metricComplianceRemediationStatus,
+		metricNameComplianceRemediationDrift:  m.metrics.metricComplianceRemediationDrift,
 		metricNameComplianceStateGauge:        m.metrics.metricComplianceStateGauge,
+		metricNameComplianceCheckResults:      m.metrics.metricComplianceCheckResults,
+		metricNameComplianceFailingRuleInfo:   m.metrics.metricComplianceFailingRuleInfo,
+		metricNameComplianceRemediations:      m.metrics.metricComplianceRemediations,
+		metricNameComplianceScanPhaseDuration: m.metrics.metricComplianceScanPhaseDuration,
+		metricNameComplianceScanPhase:         m.metrics.metricComplianceScanPhase,
+		metricNameProfileBundleStatus:         m.metrics.metricProfileBundleStatus,
+		metricNameComplianceScanPhaseSeconds:  m.metrics.metricComplianceScanPhaseSeconds,
+		metricNameComplianceScanDuration:      m.metrics.metricComplianceScanDuration,
 	} {
 		m.log.Info(fmt.Sprintf("Registering metric: %s", name))
 		if err := m.impl.Register(collector); err != nil {
@@ -154,8 +391,12 @@ func (m *Metrics) Start(s <-chan struct{}) error {
 	return nil
 }
 
-// IncComplianceScanStatus also increments error if necessary
+// IncComplianceScanStatus also increments error if necessary, sets the
+// duration of the phase the scan left, if any, and observes the timings the
+// status recorded
 func (m *Metrics) IncComplianceScanStatus(name string, status v1alpha1.ComplianceScanStatus) {
+	m.observeScanPhase(name, status.Phase, time.Now())
+	m.observePhaseTimings(name, status)
 	m.metrics.metricComplianceScanStatus.With(prometheus.Labels{
 		metricLabelScanName:   name,
 		metricLabelScanPhase:  string(status.Phase),
@@ -177,6 +418,15 @@ func (m *Metrics) IncComplianceRemediationStatus(name string, status v1alpha1.Co
 	}).Inc()
 }
 
+// IncComplianceRemediationDrift increments the ComplianceRemediation drift counter.
+// The action is either DriftActionReported or DriftActionReapplied.
+func (m *Metrics) IncComplianceRemediationDrift(name, action string) {
+	m.metrics.metricComplianceRemediationDrift.With(prometheus.Labels{
+		metricLabelRemediationName: name,
+		metricLabelDriftAction:     action,
+	}).Inc()
+}
+
 // SetComplianceStateError sets the compliance_state gauge to 3.
 func (m *Metrics) SetComplianceStateError(name string) {
 	m.metrics.metricComplianceStateGauge.WithLabelValues(name).Set(METRIC_STATE_ERROR)
@@ -196,3 +446,273 @@ func (m *Metrics) SetComplianceStateOutOfCompliance(name string) {
 func (m *Metrics) SetComplianceStateInCompliance(name string) {
 	m.metrics.metricComplianceStateGauge.WithLabelValues(name).Set(METRIC_STATE_COMPLIANT)
 }
+
+// observeScanPhase sets the phase a scan is in, and the duration of the
+// phase it left. The duration of the phase a scan was in when the operator
+// started isn't observed, as it's unknown since when the scan was in it.
+func (m *Metrics) observeScanPhase(name string, phase v1alpha1.ComplianceScanStatusPhase, now time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	previous, ok := m.scanPhases[name]
+	if ok && previous.phase == phase {
+		return
+	}
+	if ok && previous.phase != "" {
+		previousLabels := prometheus.Labels{
+			metricLabelScanName:  name,
+			metricLabelScanPhase: string(previous.phase),
+		}
+		m.metrics.metricComplianceScanPhaseDuration.With(previousLabels).Set(now.Sub(previous.since).Seconds())
+		m.metrics.metricComplianceScanPhase.Delete(previousLabels)
+	}
+	m.scanPhases[name] = scanPhase{phase: phase, since: now}
+	if phase != "" {
+		m.metrics.metricComplianceScanPhase.With(prometheus.Labels{
+			metricLabelScanName:  name,
+			metricLabelScanPhase: string(phase),
+		}).Set(1)
+	}
+}
+
+// observePhaseTimings observes the durations of the phases the current run
+// of a scan left, and of the run once done, as recorded in its status. Each
+// timing is observed once, as the scan status is reported on every update.
+func (m *Metrics) observePhaseTimings(name string, status v1alpha1.ComplianceScanStatus) {
+	if status.StartTimestamp == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	run, ok := m.observedRuns[name]
+	if !ok || !run.start.Equal(status.StartTimestamp.Time) {
+		run = observedRun{start: status.StartTimestamp.Time}
+	}
+	// Unless this instance of the operator saw the run before, the timings
+	// that ended before it started were observed by the previous one
+	seenBefore := func(end time.Time) bool {
+		return !ok && end.Before(m.started)
+	}
+
+	for ; run.phases < len(status.PhaseTimings); run.phases++ {
+		timing := status.PhaseTimings[run.phases]
+		if timing.EndTime == nil {
+			break
+		}
+		if seenBefore(timing.EndTime.Time) {
+			continue
+		}
+		m.metrics.metricComplianceScanPhaseSeconds.With(prometheus.Labels{
+			metricLabelScanName:  name,
+			metricLabelScanPhase: string(timing.Phase),
+		}).Observe(timing.EndTime.Sub(timing.StartTime.Time).Seconds())
+	}
+
+	if !run.done && status.Phase == v1alpha1.PhaseDone && status.EndTimestamp != nil {
+		run.done = true
+		if !seenBefore(status.EndTimestamp.Time) {
+			m.metrics.metricComplianceScanDuration.With(prometheus.Labels{
+				metricLabelScanName: name,
+			}).Observe(status.EndTimestamp.Sub(status.StartTimestamp.Time).Seconds())
+		}
+	}
+	m.observedRuns[name] = run
+}
+
+// SetComplianceCheckResults sets the check result gauges of a scan from the
+// check result counts of its latest run and the check results that failed
+// in it, replacing the series of the previous run
+func (m *Metrics) SetComplianceCheckResults(suite, scan string, counts []v1alpha1.CheckResultCount, failing []v1alpha1.ComplianceCheckResult) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.deleteSeries(m.metrics.metricComplianceCheckResults, m.checkResultSeries, scan)
+	m.deleteSeries(m.metrics.metricComplianceFailingRuleInfo, m.failingRuleSeries, scan)
+
+	for _, count := range counts {
+		labels := prometheus.Labels{
+			metricLabelSuite:         suite,
+			metricLabelScan:          scan,
+			metricLabelCheckStatus:   string(count.Status),
+			metricLabelCheckSeverity: string(count.Severity),
+		}
+		m.metrics.metricComplianceCheckResults.With(labels).Set(float64(count.Count))
+		m.checkResultSeries[scan] = append(m.checkResultSeries[scan], labels)
+	}
+
+	if !m.opts.FailingRules {
+		return
+	}
+	failing = append([]v1alpha1.ComplianceCheckResult(nil), failing...)
+	sort.SliceStable(failing, func(i, j int) bool {
+		return severityRank(failing[i].Severity) > severityRank(failing[j].Severity)
+	})
+	if m.opts.MaxFailingRulesPerScan > 0 && len(failing) > m.opts.MaxFailingRulesPerScan {
+		m.log.Info("Limiting the failing rule series of the scan", "scan", scan,
+			"failing", len(failing), "limit", m.opts.MaxFailingRulesPerScan)
+		failing = failing[:m.opts.MaxFailingRulesPerScan]
+	}
+	for _, check := range failing {
+		rule := check.Annotations[v1alpha1.RuleIDAnnotationKey]
+		if rule == "" {
+			rule = check.ID
+		}
+		labels := prometheus.Labels{
+			metricLabelScan:          scan,
+			metricLabelRule:          rule,
+			metricLabelCheckSeverity: string(check.Severity),
+		}
+		m.metrics.metricComplianceFailingRuleInfo.With(labels).Set(1)
+		m.failingRuleSeries[scan] = append(m.failingRuleSeries[scan], labels)
+	}
+}
+
+// DeleteComplianceScan deletes the series of a scan that's gone
+func (m *Metrics) DeleteComplianceScan(scan string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.deleteSeries(m.metrics.metricComplianceCheckResults, m.checkResultSeries, scan)
+	m.deleteSeries(m.metrics.metricComplianceFailingRuleInfo, m.failingRuleSeries, scan)
+	if previous, ok := m.scanPhases[scan]; ok {
+		m.metrics.metricComplianceScanPhase.Delete(prometheus.Labels{
+			metricLabelScanName:  scan,
+			metricLabelScanPhase: string(previous.phase),
+		})
+	}
+	delete(m.scanPhases, scan)
+
+	for _, phase := range timedScanPhases {
+		m.metrics.metricComplianceScanPhaseSeconds.Delete(prometheus.Labels{
+			metricLabelScanName:  scan,
+			metricLabelScanPhase: string(phase),
+		})
+	}
+	m.metrics.metricComplianceScanDuration.Delete(prometheus.Labels{
+		metricLabelScanName: scan,
+	})
+	delete(m.observedRuns, scan)
+}
+
+// SetProfileBundleStatus sets the data stream status a profile bundle is in
+func (m *Metrics) SetProfileBundleStatus(name string, status v1alpha1.DataStreamStatusType) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if previous, ok := m.bundleStatuses[name]; ok {
+		if previous == string(status) {
+			return
+		}
+		m.metrics.metricProfileBundleStatus.Delete(prometheus.Labels{
+			metricLabelBundleName:   name,
+			metricLabelBundleStatus: previous,
+		})
+	}
+	m.bundleStatuses[name] = string(status)
+	m.metrics.metricProfileBundleStatus.With(prometheus.Labels{
+		metricLabelBundleName:   name,
+		metricLabelBundleStatus: string(status),
+	}).Set(1)
+}
+
+// DeleteProfileBundle deletes the series of a profile bundle that's gone
+func (m *Metrics) DeleteProfileBundle(name string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if previous, ok := m.bundleStatuses[name]; ok {
+		m.metrics.metricProfileBundleStatus.Delete(prometheus.Labels{
+			metricLabelBundleName:   name,
+			metricLabelBundleStatus: previous,
+		})
+	}
+	delete(m.bundleStatuses, name)
+}
+
+// SetComplianceRemediationState counts a remediation under the current
+// application state in the number of remediations of its suite, moving it
+// from the state it was last counted in
+func (m *Metrics) SetComplianceRemediationState(rem *v1alpha1.ComplianceRemediation) {
+	suite, ok := rem.Labels[v1alpha1.SuiteLabel]
+	if !ok {
+		return
+	}
+	state := rem.Status.ApplicationState
+	if state == "" {
+		state = v1alpha1.RemediationPending
+	}
+	current := remediationState{suite: suite, state: string(state)}
+	key := rem.GetNamespace() + "/" + rem.GetName()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	previous, ok := m.remediationStates[key]
+	if ok && previous == current {
+		return
+	} else if ok {
+		m.uncountRemediation(previous)
+	}
+	m.remediationStates[key] = current
+	if m.remediationCounts[suite] == nil {
+		m.remediationCounts[suite] = map[string]int{}
+	}
+	m.remediationCounts[suite][current.state]++
+	m.metrics.metricComplianceRemediations.With(prometheus.Labels{
+		metricLabelSuite:            suite,
+		metricLabelRemediationState: current.state,
+	}).Set(float64(m.remediationCounts[suite][current.state]))
+}
+
+// DeleteComplianceRemediationState stops counting a remediation that was
+// deleted
+func (m *Metrics) DeleteComplianceRemediationState(namespace, name string) {
+	key := namespace + "/" + name
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if previous, ok := m.remediationStates[key]; ok {
+		m.uncountRemediation(previous)
+		delete(m.remediationStates, key)
+	}
+}
+
+func (m *Metrics) uncountRemediation(rs remediationState) {
+	labels := prometheus.Labels{
+		metricLabelSuite:            rs.suite,
+		metricLabelRemediationState: rs.state,
+	}
+	counts := m.remediationCounts[rs.suite]
+	counts[rs.state]--
+	if counts[rs.state] > 0 {
+		m.metrics.metricComplianceRemediations.With(labels).Set(float64(counts[rs.state]))
+		return
+	}
+	m.metrics.metricComplianceRemediations.Delete(labels)
+	delete(counts, rs.state)
+	if len(counts) == 0 {
+		delete(m.remediationCounts, rs.suite)
+	}
+}
+
+func (m *Metrics) deleteSeries(vec *prometheus.GaugeVec, series map[string][]prometheus.Labels, key string) {
+	for _, labels := range series[key] {
+		vec.Delete(labels)
+	}
+	delete(series, key)
+}
+
+func severityRank(severity v1alpha1.ComplianceCheckResultSeverity) int {
+	switch severity {
+	case v1alpha1.CheckResultSeverityHigh:
+		return 3
+	case v1alpha1.CheckResultSeverityMedium:
+		return 2
+	case v1alpha1.CheckResultSeverityLow:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -18,11 +18,15 @@ package metrics
 
 import (
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
 	"github.com/openshift/compliance-operator/pkg/controller/metrics/metricsfakes"
@@ -117,6 +121,19 @@ func TestFileIntegrityMetrics(t *testing.T) {
 				require.Equal(t, 1, getMetricValue(ctr))
 			},
 		},
+		{ // remediation drift
+			when: func(m *Metrics) {
+				m.IncComplianceRemediationDrift("rem", DriftActionReapplied)
+			},
+			then: func(m *Metrics) {
+				ctr, err := m.metrics.metricComplianceRemediationDrift.GetMetricWith(prometheus.Labels{
+					metricLabelRemediationName: "rem",
+					metricLabelDriftAction:     DriftActionReapplied,
+				})
+				require.Nil(t, err)
+				require.Equal(t, 1, getMetricValue(ctr))
+			},
+		},
 	} {
 		mock := &metricsfakes.FakeImpl{}
 		sut := New()
@@ -126,3 +143,211 @@ func TestFileIntegrityMetrics(t *testing.T) {
 		tc.then(sut)
 	}
 }
+
+func TestCheckResultMetrics(t *testing.T) {
+	t.Parallel()
+
+	newCheck := func(rule string, status v1alpha1.ComplianceCheckStatus, severity v1alpha1.ComplianceCheckResultSeverity) v1alpha1.ComplianceCheckResult {
+		check := v1alpha1.ComplianceCheckResult{
+			ID:       "xccdf_org.ssgproject.content_rule_" + rule,
+			Status:   status,
+			Severity: severity,
+		}
+		check.Annotations = map[string]string{v1alpha1.RuleIDAnnotationKey: rule}
+		return check
+	}
+
+	sut := New()
+	sut.impl = &metricsfakes.FakeImpl{}
+	sut.SetCardinalityOptions(CardinalityOptions{FailingRules: true, MaxFailingRulesPerScan: 2})
+
+	sut.SetComplianceCheckResults("suite", "scan", []v1alpha1.CheckResultCount{
+		{Status: v1alpha1.CheckResultFail, Severity: v1alpha1.CheckResultSeverityHigh, Count: 2},
+		{Status: v1alpha1.CheckResultFail, Severity: v1alpha1.CheckResultSeverityLow, Count: 1},
+		{Status: v1alpha1.CheckResultPass, Severity: v1alpha1.CheckResultSeverityHigh, Count: 1},
+	}, []v1alpha1.ComplianceCheckResult{
+		newCheck("a", v1alpha1.CheckResultFail, v1alpha1.CheckResultSeverityLow),
+		newCheck("b", v1alpha1.CheckResultFail, v1alpha1.CheckResultSeverityHigh),
+		newCheck("c", v1alpha1.CheckResultFail, v1alpha1.CheckResultSeverityHigh),
+	})
+	require.Equal(t, 2.0, testutil.ToFloat64(sut.metrics.metricComplianceCheckResults.With(prometheus.Labels{
+		metricLabelSuite: "suite", metricLabelScan: "scan", metricLabelCheckStatus: "FAIL", metricLabelCheckSeverity: "high",
+	})))
+	require.Equal(t, 3, testutil.CollectAndCount(sut.metrics.metricComplianceCheckResults))
+	// The low severity failure is over the limit
+	require.Equal(t, 2, testutil.CollectAndCount(sut.metrics.metricComplianceFailingRuleInfo))
+	require.Equal(t, 1.0, testutil.ToFloat64(sut.metrics.metricComplianceFailingRuleInfo.With(prometheus.Labels{
+		metricLabelScan: "scan", metricLabelRule: "b", metricLabelCheckSeverity: "high",
+	})))
+
+	// The next run replaces the series of the previous one
+	sut.SetComplianceCheckResults("suite", "scan", []v1alpha1.CheckResultCount{
+		{Status: v1alpha1.CheckResultPass, Severity: v1alpha1.CheckResultSeverityHigh, Count: 1},
+	}, nil)
+	require.Equal(t, 1, testutil.CollectAndCount(sut.metrics.metricComplianceCheckResults))
+	require.Equal(t, 0, testutil.CollectAndCount(sut.metrics.metricComplianceFailingRuleInfo))
+
+	sut.DeleteComplianceScan("scan")
+	require.Equal(t, 0, testutil.CollectAndCount(sut.metrics.metricComplianceCheckResults))
+
+	rems := []v1alpha1.ComplianceRemediation{{}, {}, {}}
+	for i := range rems {
+		rems[i].Name = fmt.Sprintf("rem-%d", i)
+		rems[i].Namespace = "ns"
+		rems[i].Labels = map[string]string{v1alpha1.SuiteLabel: "suite"}
+	}
+	rems[1].Status.ApplicationState = v1alpha1.RemediationApplied
+	rems[2].Status.ApplicationState = v1alpha1.RemediationApplied
+	for i := range rems {
+		sut.SetComplianceRemediationState(&rems[i])
+	}
+	appliedLabels := prometheus.Labels{
+		metricLabelSuite: "suite", metricLabelRemediationState: string(v1alpha1.RemediationApplied),
+	}
+	require.Equal(t, 2, testutil.CollectAndCount(sut.metrics.metricComplianceRemediations))
+	require.Equal(t, 2.0, testutil.ToFloat64(sut.metrics.metricComplianceRemediations.With(appliedLabels)))
+
+	// A remediation moves from one state to another
+	rems[0].Status.ApplicationState = v1alpha1.RemediationApplied
+	sut.SetComplianceRemediationState(&rems[0])
+	sut.SetComplianceRemediationState(&rems[0])
+	require.Equal(t, 1, testutil.CollectAndCount(sut.metrics.metricComplianceRemediations))
+	require.Equal(t, 3.0, testutil.ToFloat64(sut.metrics.metricComplianceRemediations.With(appliedLabels)))
+
+	for i := range rems {
+		sut.DeleteComplianceRemediationState("ns", rems[i].Name)
+	}
+	require.Equal(t, 0, testutil.CollectAndCount(sut.metrics.metricComplianceRemediations))
+}
+
+func TestScanPhaseDurationMetrics(t *testing.T) {
+	t.Parallel()
+
+	sut := New()
+	sut.impl = &metricsfakes.FakeImpl{}
+
+	start := time.Now()
+	sut.observeScanPhase("scan", v1alpha1.PhaseRunning, start)
+	sut.observeScanPhase("scan", v1alpha1.PhaseRunning, start.Add(time.Minute))
+	require.Equal(t, 0, testutil.CollectAndCount(sut.metrics.metricComplianceScanPhaseDuration))
+
+	sut.observeScanPhase("scan", v1alpha1.PhaseAggregating, start.Add(90*time.Second))
+	require.Equal(t, 90.0, testutil.ToFloat64(sut.metrics.metricComplianceScanPhaseDuration.With(prometheus.Labels{
+		metricLabelScanName: "scan", metricLabelScanPhase: string(v1alpha1.PhaseRunning),
+	})))
+}
+
+func TestPhaseTimingMetrics(t *testing.T) {
+	t.Parallel()
+
+	sut := New()
+	sut.impl = &metricsfakes.FakeImpl{}
+
+	sampleCount := func(col prometheus.Collector) uint64 {
+		m := dto.Metric{}
+		require.Nil(t, col.(prometheus.Metric).Write(&m))
+		return m.Histogram.GetSampleCount()
+	}
+	phaseSeconds := func(scan string, phase v1alpha1.ComplianceScanStatusPhase) prometheus.Collector {
+		return sut.metrics.metricComplianceScanPhaseSeconds.With(prometheus.Labels{
+			metricLabelScanName: scan, metricLabelScanPhase: string(phase),
+		}).(prometheus.Collector)
+	}
+	at := func(offset time.Duration) *metav1.Time {
+		t := metav1.NewTime(sut.started.Add(offset))
+		return &t
+	}
+
+	status := v1alpha1.ComplianceScanStatus{
+		Phase:          v1alpha1.PhaseRunning,
+		StartTimestamp: at(time.Second),
+		PhaseTimings: []v1alpha1.ComplianceScanPhaseTiming{
+			{Phase: v1alpha1.PhasePending, StartTime: *at(time.Second), EndTime: at(2 * time.Second)},
+			{Phase: v1alpha1.PhaseLaunching, StartTime: *at(2 * time.Second), EndTime: at(10 * time.Second)},
+			{Phase: v1alpha1.PhaseRunning, StartTime: *at(10 * time.Second)},
+		},
+	}
+	sut.IncComplianceScanStatus("scan", status)
+	sut.IncComplianceScanStatus("scan", status)
+	require.Equal(t, uint64(1), sampleCount(phaseSeconds("scan", v1alpha1.PhaseLaunching)))
+	require.Equal(t, 0, testutil.CollectAndCount(sut.metrics.metricComplianceScanDuration))
+
+	status.Phase = v1alpha1.PhaseDone
+	status.PhaseTimings[2].EndTime = at(time.Minute)
+	status.EndTimestamp = at(time.Minute)
+	sut.IncComplianceScanStatus("scan", status)
+	sut.IncComplianceScanStatus("scan", status)
+	require.Equal(t, uint64(1), sampleCount(phaseSeconds("scan", v1alpha1.PhaseRunning)))
+	require.Equal(t, uint64(1), sampleCount(phaseSeconds("scan", v1alpha1.PhaseLaunching)))
+	require.Equal(t, uint64(1), sampleCount(sut.metrics.metricComplianceScanDuration.With(prometheus.Labels{
+		metricLabelScanName: "scan",
+	}).(prometheus.Collector)))
+
+	// The timings of a run that ended before the operator started were
+	// observed by the previous instance of the operator
+	sut.IncComplianceScanStatus("old-scan", v1alpha1.ComplianceScanStatus{
+		Phase:          v1alpha1.PhaseDone,
+		StartTimestamp: at(-time.Hour),
+		EndTimestamp:   at(-time.Minute),
+		PhaseTimings: []v1alpha1.ComplianceScanPhaseTiming{
+			{Phase: v1alpha1.PhaseRunning, StartTime: *at(-time.Hour), EndTime: at(-time.Minute)},
+		},
+	})
+	require.Equal(t, 3, testutil.CollectAndCount(sut.metrics.metricComplianceScanPhaseSeconds))
+
+	sut.DeleteComplianceScan("scan")
+	require.Equal(t, 0, testutil.CollectAndCount(sut.metrics.metricComplianceScanPhaseSeconds))
+	require.Equal(t, 0, testutil.CollectAndCount(sut.metrics.metricComplianceScanDuration))
+}
+
+func TestStatusGauges(t *testing.T) {
+	t.Parallel()
+
+	sut := New()
+	sut.impl = &metricsfakes.FakeImpl{}
+
+	sut.observeScanPhase("scan", v1alpha1.PhaseRunning, time.Now())
+	sut.observeScanPhase("scan", v1alpha1.PhaseAggregating, time.Now())
+	require.Equal(t, 1, testutil.CollectAndCount(sut.metrics.metricComplianceScanPhase))
+	require.Equal(t, 1.0, testutil.ToFloat64(sut.metrics.metricComplianceScanPhase.With(prometheus.Labels{
+		metricLabelScanName: "scan", metricLabelScanPhase: string(v1alpha1.PhaseAggregating),
+	})))
+	sut.DeleteComplianceScan("scan")
+	require.Equal(t, 0, testutil.CollectAndCount(sut.metrics.metricComplianceScanPhase))
+
+	sut.SetProfileBundleStatus("ocp4", v1alpha1.DataStreamPending)
+	sut.SetProfileBundleStatus("ocp4", v1alpha1.DataStreamInvalid)
+	require.Equal(t, 1, testutil.CollectAndCount(sut.metrics.metricProfileBundleStatus))
+	require.Equal(t, 1.0, testutil.ToFloat64(sut.metrics.metricProfileBundleStatus.With(prometheus.Labels{
+		metricLabelBundleName: "ocp4", metricLabelBundleStatus: string(v1alpha1.DataStreamInvalid),
+	})))
+	sut.DeleteProfileBundle("ocp4")
+	require.Equal(t, 0, testutil.CollectAndCount(sut.metrics.metricProfileBundleStatus))
+}
+
+func TestSuiteAlertRules(t *testing.T) {
+	t.Parallel()
+
+	rules := SuiteAlertRules("my-suite", nil)
+	require.Len(t, rules, 3)
+	require.Equal(t, "NonCompliant", rules[0].Alert)
+	require.Equal(t, `compliance_operator_compliance_state{name="my-suite"} == 1`, rules[0].Expr.String())
+	require.Equal(t, v1alpha1.DefaultAlertFor, rules[0].For)
+	require.Equal(t, v1alpha1.DefaultAlertSeverity, rules[0].Labels[alertLabelSeverity])
+
+	rules = SuiteAlertRules("my-suite", &v1alpha1.ScanSettingAlerting{
+		Results:    []v1alpha1.AlertResult{v1alpha1.AlertResult(v1alpha1.ResultError)},
+		Severities: map[string]string{string(v1alpha1.ResultError): "critical"},
+		For:        "30m",
+		Labels:     map[string]string{"team": "security", alertLabelSeverity: "info"},
+	})
+	require.Len(t, rules, 1)
+	require.Equal(t, "ComplianceError", rules[0].Alert)
+	require.Equal(t, `compliance_operator_compliance_state{name="my-suite"} == 3`, rules[0].Expr.String())
+	require.Equal(t, "30m", rules[0].For)
+	require.Equal(t, map[string]string{
+		"team":             "security",
+		alertLabelSeverity: "critical",
+		AlertLabelResult:   string(v1alpha1.ResultError),
+	}, rules[0].Labels)
+}
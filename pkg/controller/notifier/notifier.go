@@ -0,0 +1,379 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+var log = logf.Log.WithName("notifier")
+
+const (
+	// The time an attempt to send a notification can take
+	requestTimeout = 10 * time.Second
+	// The time a notification is retried for at most, whatever the retries
+	// of its notifier, so that an unreachable webhook only holds one of the
+	// shared workers for a while
+	maxRetryTime = time.Minute
+	// The key of the ConfigMap that holds the CAs of the webhook
+	caBundleKey = "ca-bundle.crt"
+	// The number of notifications sent at the same time
+	defaultWorkers = 4
+	// The number of notifications that can wait to be sent. Beyond that,
+	// notifications fail right away.
+	defaultQueueSize = 256
+)
+
+// Notification is what's sent to the webhooks of the notifiers
+type Notification struct {
+	// The transition the notification is sent for
+	Event compv1alpha1.NotificationEvent `json:"event"`
+	// The object the notification is about
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// The suite of the object, or the object itself
+	Suite string `json:"suite,omitempty"`
+	// The result of the suite, or the application state of the
+	// remediation
+	Result string `json:"result,omitempty"`
+	// The rules the notification is about, e.g. the rules that regressed
+	Rules []string `json:"rules,omitempty"`
+	// A human readable description of the transition
+	Message string `json:"message"`
+	// When the transition happened
+	Time time.Time `json:"time"`
+}
+
+// Notifier sends notifications through the ComplianceNotifiers of a
+// namespace. The notifications are queued and sent by a fixed number of
+// workers, so that slow webhooks never block the reconciliations. A single
+// Notifier is shared by the controllers. A nil Notifier doesn't send
+// anything.
+type Notifier struct {
+	client  client.Client
+	log     logr.Logger
+	queue   chan delivery
+	workers int
+	// Waits for the queued notifications to be sent
+	inFlight sync.WaitGroup
+	// Returns the policy to retry a notification with
+	newBackOff func() backoff.BackOff
+}
+
+// delivery is a notification queued to be sent through a notifier
+type delivery struct {
+	notifier     *compv1alpha1.ComplianceNotifier
+	notification Notification
+}
+
+// New returns a Notifier that finds the ComplianceNotifiers, and the
+// Secrets and ConfigMaps they reference, with the given client. The
+// notifications are only sent once the Notifier is started.
+func New(c client.Client) *Notifier {
+	return &Notifier{
+		client:     c,
+		log:        log,
+		queue:      make(chan delivery, defaultQueueSize),
+		workers:    defaultWorkers,
+		newBackOff: newRetryBackOff,
+	}
+}
+
+// newRetryBackOff returns the policy notifications are retried with
+func newRetryBackOff() backoff.BackOff {
+	b := backoff.NewExponentialBackOff()
+	b.MaxElapsedTime = maxRetryTime
+	return b
+}
+
+// Start sends the queued notifications until the stop channel is closed,
+// and waits for the ones being sent. It implements the Runnable interface
+// of the manager.
+func (n *Notifier) Start(stop <-chan struct{}) error {
+	n.log.Info("Starting to send notifications", "workers", n.workers)
+	var workers sync.WaitGroup
+	for i := 0; i < n.workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				case d := <-n.queue:
+					n.deliver(d.notifier, d.notification)
+					n.inFlight.Done()
+				}
+			}
+		}()
+	}
+	<-stop
+	workers.Wait()
+	return nil
+}
+
+// Notify sends a notification through the notifiers of its namespace that
+// want its event and select the suite with the given labels. The
+// notifications are sent in the background, and their delivery reported in
+// the status of the notifiers.
+func (n *Notifier) Notify(notification Notification, suiteLabels map[string]string) {
+	if n == nil {
+		return
+	}
+	if notification.Time.IsZero() {
+		notification.Time = time.Now()
+	}
+
+	notifiers := compv1alpha1.ComplianceNotifierList{}
+	if err := n.client.List(context.TODO(), &notifiers, client.InNamespace(notification.Namespace)); err != nil {
+		n.log.Error(err, "Cannot list the notifiers", "namespace", notification.Namespace)
+		return
+	}
+
+	for i := range notifiers.Items {
+		notifier := &notifiers.Items[i]
+		if !notifier.WantsEvent(notification.Event) {
+			continue
+		}
+		if selected, err := selectsSuite(notifier, suiteLabels); err != nil {
+			n.log.Error(err, "Invalid suite selector", "ComplianceNotifier.Name", notifier.Name)
+			continue
+		} else if !selected {
+			continue
+		}
+
+		n.inFlight.Add(1)
+		select {
+		case n.queue <- delivery{notifier: notifier, notification: notification}:
+		default:
+			n.inFlight.Done()
+			n.report(notifier, &compv1alpha1.NotificationDelivery{
+				Event:        notification.Event,
+				Object:       notification.Kind + "/" + notification.Name,
+				Time:         metav1.Now(),
+				ErrorMessage: "too many notifications are waiting to be sent",
+			})
+		}
+	}
+}
+
+// wait waits for the queued notifications to be sent
+func (n *Notifier) wait() {
+	n.inFlight.Wait()
+}
+
+func selectsSuite(notifier *compv1alpha1.ComplianceNotifier, suiteLabels map[string]string) (bool, error) {
+	if notifier.Spec.SuiteSelector == nil {
+		return true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(notifier.Spec.SuiteSelector)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(suiteLabels)), nil
+}
+
+// deliver sends a notification through a notifier and reports the delivery
+// in its status
+func (n *Notifier) deliver(notifier *compv1alpha1.ComplianceNotifier, notification Notification) {
+	logger := n.log.WithValues("ComplianceNotifier.Name", notifier.Name, "event", notification.Event)
+	delivery := n.send(notifier, notification)
+	if delivery.ErrorMessage != "" {
+		logger.Info("Couldn't deliver the notification", "attempts", delivery.Attempts, "error", delivery.ErrorMessage)
+	} else {
+		logger.Info("Delivered the notification", "attempts", delivery.Attempts)
+	}
+	n.report(notifier, delivery)
+}
+
+// report reports the delivery of a notification in the status of the
+// notifier
+func (n *Notifier) report(notifier *compv1alpha1.ComplianceNotifier, delivery *compv1alpha1.NotificationDelivery) {
+	key := types.NamespacedName{Name: notifier.Name, Namespace: notifier.Namespace}
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current := &compv1alpha1.ComplianceNotifier{}
+		if err := n.client.Get(context.TODO(), key, current); err != nil {
+			return err
+		}
+		if delivery.ErrorMessage != "" {
+			current.Status.Failed++
+			current.Status.LastFailure = delivery.DeepCopy()
+		} else {
+			current.Status.Delivered++
+		}
+		current.Status.LastDelivery = delivery.DeepCopy()
+		return n.client.Status().Update(context.TODO(), current)
+	})
+	if err != nil {
+		n.log.Error(err, "Cannot report the delivery of the notification",
+			"ComplianceNotifier.Name", notifier.Name, "event", delivery.Event)
+	}
+}
+
+// send sends a notification to the webhook of a notifier, retrying when it
+// can't be reached or answers with a server error, until the retries of the
+// notifier are used up or maxRetryTime has elapsed
+func (n *Notifier) send(notifier *compv1alpha1.ComplianceNotifier, notification Notification) *compv1alpha1.NotificationDelivery {
+	delivery := &compv1alpha1.NotificationDelivery{
+		Event:  notification.Event,
+		Object: notification.Kind + "/" + notification.Name,
+	}
+	fail := func(err error) *compv1alpha1.NotificationDelivery {
+		delivery.Time = metav1.Now()
+		delivery.ErrorMessage = err.Error()
+		return delivery
+	}
+
+	body, err := Render(notifier, notification)
+	if err != nil {
+		return fail(err)
+	}
+	url, err := n.getURL(notifier)
+	if err != nil {
+		return fail(err)
+	}
+	httpClient, err := n.getHTTPClient(notifier)
+	if err != nil {
+		return fail(err)
+	}
+	headers := http.Header{"Content-Type": []string{"application/json"}}
+	if notifier.Spec.HMAC != nil {
+		key, err := n.getSecretKey(notifier.Namespace, notifier.Spec.HMAC.Secret)
+		if err != nil {
+			return fail(err)
+		}
+		header := notifier.Spec.HMAC.Header
+		if header == "" {
+			header = compv1alpha1.DefaultNotifierHMACHeader
+		}
+		headers.Set(header, Sign(key, body))
+	}
+
+	err = backoff.Retry(func() error {
+		delivery.Attempts++
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		req.Header = headers.Clone()
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+		delivery.StatusCode = int32(resp.StatusCode)
+		switch {
+		case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+			return fmt.Errorf("the webhook answered with %s", resp.Status)
+		case resp.StatusCode >= 300:
+			return backoff.Permanent(fmt.Errorf("the webhook answered with %s", resp.Status))
+		}
+		return nil
+	}, backoff.WithMaxRetries(n.newBackOff(), uint64(notifier.GetRetries())))
+	if err != nil {
+		return fail(err)
+	}
+	delivery.Time = metav1.Now()
+	return delivery
+}
+
+// Sign returns the signature of a body, as sent in the HMAC header
+func Sign(key, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (n *Notifier) getURL(notifier *compv1alpha1.ComplianceNotifier) (string, error) {
+	if notifier.Spec.URLSecret != nil {
+		url, err := n.getSecretKey(notifier.Namespace, *notifier.Spec.URLSecret)
+		if err != nil {
+			return "", err
+		}
+		return string(bytes.TrimSpace(url)), nil
+	}
+	if notifier.Spec.URL == "" {
+		return "", fmt.Errorf("the notifier has neither a url nor a urlSecret")
+	}
+	return notifier.Spec.URL, nil
+}
+
+func (n *Notifier) getSecretKey(namespace string, selector compv1alpha1.NotifierSecretKeySelector) ([]byte, error) {
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Name: selector.Name, Namespace: namespace}
+	if err := n.client.Get(context.TODO(), key, secret); err != nil {
+		return nil, fmt.Errorf("getting the Secret %s: %w", selector.Name, err)
+	}
+	value, ok := secret.Data[selector.Key]
+	if !ok {
+		return nil, fmt.Errorf("the Secret %s has no key %s", selector.Name, selector.Key)
+	}
+	return value, nil
+}
+
+func (n *Notifier) getHTTPClient(notifier *compv1alpha1.ComplianceNotifier) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if notifier.Spec.TLS != nil && notifier.Spec.TLS.CAConfigMap != "" {
+		cm := &corev1.ConfigMap{}
+		key := types.NamespacedName{Name: notifier.Spec.TLS.CAConfigMap, Namespace: notifier.Namespace}
+		if err := n.client.Get(context.TODO(), key, cm); err != nil {
+			return nil, fmt.Errorf("getting the ConfigMap %s: %w", notifier.Spec.TLS.CAConfigMap, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(cm.Data[caBundleKey])) {
+			return nil, fmt.Errorf("the ConfigMap %s has no certificate in its %s key", cm.Name, caBundleKey)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if notifier.Spec.TLS != nil && notifier.Spec.TLS.ClientCertSecret != "" {
+		secret := &corev1.Secret{}
+		key := types.NamespacedName{Name: notifier.Spec.TLS.ClientCertSecret, Namespace: notifier.Namespace}
+		if err := n.client.Get(context.TODO(), key, secret); err != nil {
+			return nil, fmt.Errorf("getting the Secret %s: %w", notifier.Spec.TLS.ClientCertSecret, err)
+		}
+		cert, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+		if err != nil {
+			return nil, fmt.Errorf("loading the client certificate of the Secret %s: %w", secret.Name, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return &http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+			// A client is made for every notification
+			DisableKeepAlives: true,
+		},
+	}, nil
+}
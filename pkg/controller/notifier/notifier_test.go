@@ -0,0 +1,348 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/openshift/compliance-operator/pkg/apis"
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+const testNamespace = "test-ns"
+
+// webhook records the requests it receives, answering the first ones with
+// the given status codes and the rest with 200
+type webhook struct {
+	sync.Mutex
+	codes   []int
+	bodies  [][]byte
+	headers []http.Header
+}
+
+func (w *webhook) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	w.Lock()
+	defer w.Unlock()
+	body, _ := ioutil.ReadAll(req.Body)
+	w.bodies = append(w.bodies, body)
+	w.headers = append(w.headers, req.Header)
+	code := http.StatusOK
+	if len(w.codes) > 0 {
+		code, w.codes = w.codes[0], w.codes[1:]
+	}
+	rw.WriteHeader(code)
+}
+
+func newStoppedNotifier(t *testing.T, objs ...runtime.Object) *Notifier {
+	cscheme := scheme.Scheme
+	require.NoError(t, apis.AddToScheme(cscheme))
+	n := New(fake.NewFakeClientWithScheme(cscheme, objs...))
+	n.newBackOff = func() backoff.BackOff {
+		return &backoff.ZeroBackOff{}
+	}
+	return n
+}
+
+func newNotifier(t *testing.T, objs ...runtime.Object) *Notifier {
+	n := newStoppedNotifier(t, objs...)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		require.NoError(t, n.Start(stop))
+	}()
+	t.Cleanup(func() {
+		close(stop)
+		<-done
+	})
+	return n
+}
+
+func newComplianceNotifier(url string, events ...compv1alpha1.NotificationEvent) *compv1alpha1.ComplianceNotifier {
+	return &compv1alpha1.ComplianceNotifier{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "notifier",
+			Namespace: testNamespace,
+		},
+		Spec: compv1alpha1.ComplianceNotifierSpec{
+			Events: events,
+			URL:    url,
+		},
+	}
+}
+
+func newSuite(result compv1alpha1.ComplianceScanStatusResult) *compv1alpha1.ComplianceSuite {
+	suite := &compv1alpha1.ComplianceSuite{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "suite",
+			Namespace: testNamespace,
+			Labels:    map[string]string{"env": "prod"},
+		},
+	}
+	suite.Status.Phase = compv1alpha1.PhaseDone
+	suite.Status.Result = result
+	return suite
+}
+
+func getStatus(t *testing.T, n *Notifier) compv1alpha1.ComplianceNotifierStatus {
+	found := &compv1alpha1.ComplianceNotifier{}
+	key := types.NamespacedName{Name: "notifier", Namespace: testNamespace}
+	require.NoError(t, n.client.Get(context.TODO(), key, found))
+	return found.Status
+}
+
+func TestNotifySuiteResult(t *testing.T) {
+	hook := &webhook{}
+	server := httptest.NewServer(hook)
+	defer server.Close()
+
+	n := newNotifier(t, newComplianceNotifier(server.URL, compv1alpha1.NotifySuiteNonCompliant))
+	suite := newSuite(compv1alpha1.ResultNonCompliant)
+	notification, ok := ForSuiteResult(suite)
+	require.True(t, ok)
+	n.Notify(notification, suite.Labels)
+	n.wait()
+
+	require.Len(t, hook.bodies, 1)
+	received := Notification{}
+	require.NoError(t, json.Unmarshal(hook.bodies[0], &received))
+	require.Equal(t, compv1alpha1.NotifySuiteNonCompliant, received.Event)
+	require.Equal(t, "suite", received.Suite)
+	require.Equal(t, "NON-COMPLIANT", received.Result)
+	require.Equal(t, "application/json", hook.headers[0].Get("Content-Type"))
+
+	status := getStatus(t, n)
+	require.Equal(t, int64(1), status.Delivered)
+	require.Equal(t, int64(0), status.Failed)
+	require.Equal(t, "ComplianceSuite/suite", status.LastDelivery.Object)
+	require.Equal(t, int32(http.StatusOK), status.LastDelivery.StatusCode)
+}
+
+func TestNotifyFiltersEventsAndSuites(t *testing.T) {
+	hook := &webhook{}
+	server := httptest.NewServer(hook)
+	defer server.Close()
+
+	notifier := newComplianceNotifier(server.URL, compv1alpha1.NotifySuiteNonCompliant)
+	notifier.Spec.SuiteSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}
+	n := newNotifier(t, notifier)
+
+	suite := newSuite(compv1alpha1.ResultCompliant)
+	notification, _ := ForSuiteResult(suite)
+	n.Notify(notification, map[string]string{"env": "dev"})
+	suite = newSuite(compv1alpha1.ResultNonCompliant)
+	notification, _ = ForSuiteResult(suite)
+	n.Notify(notification, suite.Labels)
+	n.wait()
+
+	require.Empty(t, hook.bodies)
+}
+
+func TestNotifyRetries(t *testing.T) {
+	hook := &webhook{codes: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	server := httptest.NewServer(hook)
+	defer server.Close()
+
+	n := newNotifier(t, newComplianceNotifier(server.URL, compv1alpha1.NotifyRuleRegressed))
+	n.Notify(ForRegressedRules(newSuite(compv1alpha1.ResultNonCompliant), []string{"rule-a", "rule-b"}), nil)
+	n.wait()
+
+	require.Len(t, hook.bodies, 3)
+	status := getStatus(t, n)
+	require.Equal(t, int64(1), status.Delivered)
+	require.Equal(t, int32(3), status.LastDelivery.Attempts)
+}
+
+func TestNotifyFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		codes    []int
+		retries  int32
+		attempts int
+	}{
+		{"client errors aren't retried", []int{http.StatusBadRequest}, 3, 1},
+		{"server errors are retried until the retries run out", []int{500, 500, 500}, 2, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := &webhook{codes: tt.codes}
+			server := httptest.NewServer(hook)
+			defer server.Close()
+
+			notifier := newComplianceNotifier(server.URL, compv1alpha1.NotifySuiteError)
+			notifier.Spec.Retries = &tt.retries
+			n := newNotifier(t, notifier)
+			notification, _ := ForSuiteResult(newSuite(compv1alpha1.ResultError))
+			n.Notify(notification, nil)
+			n.wait()
+
+			require.Len(t, hook.bodies, tt.attempts)
+			status := getStatus(t, n)
+			require.Equal(t, int64(0), status.Delivered)
+			require.Equal(t, int64(1), status.Failed)
+			require.Equal(t, int32(tt.codes[0]), status.LastFailure.StatusCode)
+			require.NotEmpty(t, status.LastFailure.ErrorMessage)
+		})
+	}
+}
+
+// steppingClock moves forward by step every time it's read
+type steppingClock struct {
+	now  time.Time
+	step time.Duration
+}
+
+func (c *steppingClock) Now() time.Time {
+	c.now = c.now.Add(c.step)
+	return c.now
+}
+
+func TestNotifyStopsRetryingAfterMaxRetryTime(t *testing.T) {
+	hook := &webhook{codes: []int{500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 500}}
+	server := httptest.NewServer(hook)
+	defer server.Close()
+
+	retries := int32(10)
+	notifier := newComplianceNotifier(server.URL, compv1alpha1.NotifySuiteError)
+	notifier.Spec.Retries = &retries
+	n := newNotifier(t, notifier)
+	n.newBackOff = func() backoff.BackOff {
+		b := newRetryBackOff().(*backoff.ExponentialBackOff)
+		b.InitialInterval = time.Millisecond
+		b.RandomizationFactor = 0
+		b.Clock = &steppingClock{step: maxRetryTime / 4}
+		return b
+	}
+	notification, _ := ForSuiteResult(newSuite(compv1alpha1.ResultError))
+	n.Notify(notification, nil)
+	n.wait()
+
+	require.Len(t, hook.bodies, 4)
+	status := getStatus(t, n)
+	require.Equal(t, int64(1), status.Failed)
+	require.Equal(t, int32(4), status.LastFailure.Attempts)
+}
+
+func TestNotifyFailsWhenTheQueueIsFull(t *testing.T) {
+	hook := &webhook{}
+	server := httptest.NewServer(hook)
+	defer server.Close()
+
+	n := newStoppedNotifier(t, newComplianceNotifier(server.URL, compv1alpha1.NotifySuiteError))
+	n.queue = make(chan delivery, 1)
+	notification, _ := ForSuiteResult(newSuite(compv1alpha1.ResultError))
+	n.Notify(notification, nil)
+	n.Notify(notification, nil)
+
+	status := getStatus(t, n)
+	require.Equal(t, int64(1), status.Failed)
+	require.Contains(t, status.LastFailure.ErrorMessage, "too many notifications")
+
+	// The queued notification is sent once the notifier starts
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		_ = n.Start(stop)
+	}()
+	n.wait()
+	require.Len(t, hook.bodies, 1)
+}
+
+func TestNotifySignsAndReadsURLSecret(t *testing.T) {
+	hook := &webhook{}
+	server := httptest.NewServer(hook)
+	defer server.Close()
+
+	notifier := newComplianceNotifier("", compv1alpha1.NotifyRemediationFailed)
+	notifier.Spec.URLSecret = &compv1alpha1.NotifierSecretKeySelector{Name: "webhook", Key: "url"}
+	notifier.Spec.HMAC = &compv1alpha1.NotifierHMAC{
+		Secret: compv1alpha1.NotifierSecretKeySelector{Name: "webhook", Key: "hmac"},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "webhook", Namespace: testNamespace},
+		Data: map[string][]byte{
+			"url":  []byte(server.URL + "\n"),
+			"hmac": []byte("s3cr3t"),
+		},
+	}
+	n := newNotifier(t, notifier, secret)
+
+	rem := &compv1alpha1.ComplianceRemediation{
+		ObjectMeta: metav1.ObjectMeta{Name: "rem", Namespace: testNamespace},
+	}
+	rem.Status.ApplicationState = compv1alpha1.RemediationError
+	n.Notify(ForRemediationFailure(rem), nil)
+	n.wait()
+
+	require.Len(t, hook.bodies, 1)
+	require.Equal(t, Sign([]byte("s3cr3t"), hook.bodies[0]), hook.headers[0].Get(compv1alpha1.DefaultNotifierHMACHeader))
+}
+
+func TestRender(t *testing.T) {
+	notification := ForRegressedRules(newSuite(compv1alpha1.ResultNonCompliant), []string{"rule-a", "rule-b"})
+
+	tests := []struct {
+		name     string
+		format   compv1alpha1.NotifierFormat
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:   "slack",
+			format: compv1alpha1.NotifierFormatSlack,
+			want:   `{"text":"*RuleRegressed* 2 rules of the compliance suite test-ns/suite passed before and now fail: rule-a, rule-b"}`,
+		},
+		{
+			name:     "template",
+			template: `{"summary": {{ json .Message }}, "rules": {{ json (join .Rules " ") }}}`,
+			want:     `{"summary": "2 rules of the compliance suite test-ns/suite passed before and now fail: rule-a, rule-b", "rules": "rule-a rule-b"}`,
+		},
+		{
+			name:     "template with an unknown field",
+			template: `{"summary": {{ json .Missing }}}`,
+			wantErr:  true,
+		},
+		{
+			name:     "template rendering invalid JSON",
+			template: `{{ .Message }}`,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := newComplianceNotifier("", compv1alpha1.NotifyRuleRegressed)
+			notifier.Spec.Format = tt.format
+			notifier.Spec.BodyTemplate = tt.template
+			body, err := Render(notifier, notification)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, string(body))
+		})
+	}
+}
+
+func TestListRules(t *testing.T) {
+	rules := make([]string, maxListedRules+2)
+	for i := range rules {
+		rules[i] = "r"
+	}
+	require.Equal(t, "r, r, r, r, r, r, r, r, r, r and 2 more", listRules(rules))
+}
@@ -0,0 +1,128 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+// The number of rules listed in the messages of the notifications
+const maxListedRules = 10
+
+// The functions the body templates can use on top of the builtin ones
+var templateFuncs = template.FuncMap{
+	// json quotes a value as JSON, e.g. {{ json .Message }}
+	"json": func(v interface{}) (string, error) {
+		out, err := json.Marshal(v)
+		return string(out), err
+	},
+	"join": strings.Join,
+}
+
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// Render returns the body of a notification, in the format of the notifier
+func Render(notifier *compv1alpha1.ComplianceNotifier, notification Notification) ([]byte, error) {
+	switch notifier.GetFormat() {
+	case compv1alpha1.NotifierFormatSlack:
+		return json.Marshal(slackMessage{
+			Text: fmt.Sprintf("*%s* %s", notification.Event, notification.Message),
+		})
+	case compv1alpha1.NotifierFormatGeneric:
+		if notifier.Spec.BodyTemplate == "" {
+			return json.Marshal(notification)
+		}
+		return renderTemplate(notifier.Spec.BodyTemplate, notification)
+	}
+	return nil, fmt.Errorf("unknown notification format %s", notifier.Spec.Format)
+}
+
+func renderTemplate(text string, notification Notification) ([]byte, error) {
+	tmpl, err := template.New("body").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the body template: %w", err)
+	}
+	out := bytes.Buffer{}
+	if err := tmpl.Execute(&out, notification); err != nil {
+		return nil, fmt.Errorf("rendering the body template: %w", err)
+	}
+	if !json.Valid(out.Bytes()) {
+		return nil, fmt.Errorf("the body template didn't render valid JSON")
+	}
+	return out.Bytes(), nil
+}
+
+// The events sent when a run of a suite finishes with a result
+var suiteResultEvents = map[compv1alpha1.ComplianceScanStatusResult]compv1alpha1.NotificationEvent{
+	compv1alpha1.ResultCompliant:    compv1alpha1.NotifySuiteCompliant,
+	compv1alpha1.ResultNonCompliant: compv1alpha1.NotifySuiteNonCompliant,
+	compv1alpha1.ResultInconsistent: compv1alpha1.NotifySuiteInconsistent,
+	compv1alpha1.ResultError:        compv1alpha1.NotifySuiteError,
+}
+
+// ForSuiteResult returns the notification of a run of a suite that just
+// finished, unless its result doesn't have one
+func ForSuiteResult(suite *compv1alpha1.ComplianceSuite) (Notification, bool) {
+	event, ok := suiteResultEvents[suite.Status.Result]
+	if !ok {
+		return Notification{}, false
+	}
+	message := fmt.Sprintf("The compliance suite %s/%s finished as %s", suite.Namespace, suite.Name, suite.Status.Result)
+	if suite.Status.Result == compv1alpha1.ResultError && suite.Status.ErrorMessage != "" {
+		message += ": " + suite.Status.ErrorMessage
+	}
+	return Notification{
+		Event:     event,
+		Kind:      "ComplianceSuite",
+		Name:      suite.Name,
+		Namespace: suite.Namespace,
+		Suite:     suite.Name,
+		Result:    string(suite.Status.Result),
+		Message:   message,
+	}, true
+}
+
+// ForRegressedRules returns the notification of the rules of a suite that
+// passed in the previous run and failed in the latest one
+func ForRegressedRules(suite *compv1alpha1.ComplianceSuite, rules []string) Notification {
+	return Notification{
+		Event:     compv1alpha1.NotifyRuleRegressed,
+		Kind:      "ComplianceSuite",
+		Name:      suite.Name,
+		Namespace: suite.Namespace,
+		Suite:     suite.Name,
+		Result:    string(suite.Status.Result),
+		Rules:     rules,
+		Message: fmt.Sprintf("%d rules of the compliance suite %s/%s passed before and now fail: %s",
+			len(rules), suite.Namespace, suite.Name, listRules(rules)),
+	}
+}
+
+// ForRemediationFailure returns the notification of a remediation that
+// failed to be applied
+func ForRemediationFailure(rem *compv1alpha1.ComplianceRemediation) Notification {
+	return Notification{
+		Event:     compv1alpha1.NotifyRemediationFailed,
+		Kind:      "ComplianceRemediation",
+		Name:      rem.Name,
+		Namespace: rem.Namespace,
+		Suite:     rem.Labels[compv1alpha1.SuiteLabel],
+		Result:    string(rem.Status.ApplicationState),
+		Message: fmt.Sprintf("The remediation %s/%s couldn't be applied: %s",
+			rem.Namespace, rem.Name, rem.Status.ErrorMessage),
+	}
+}
+
+// listRules lists rules in a message, eliding those past the first ones
+func listRules(rules []string) string {
+	if len(rules) <= maxListedRules {
+		return strings.Join(rules, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(rules[:maxListedRules], ", "), len(rules)-maxListedRules)
+}
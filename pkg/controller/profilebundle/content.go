@@ -0,0 +1,217 @@
+package profilebundle
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	compliancev1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/common"
+	"github.com/openshift/compliance-operator/pkg/utils"
+)
+
+const (
+	// contentConfigAnnotation records how the content of the bundle is
+	// fetched, verified and parsed, as not all of it shows in the commands
+	// of the workload
+	contentConfigAnnotation = "compliance.openshift.io/content-config"
+
+	verificationKeyVolumeName = "verification-key"
+	verificationKeyDir        = "/verification-key"
+)
+
+// validateContentVerification checks that the verification of the bundle
+// can be done
+func validateContentVerification(pb *compliancev1alpha1.ProfileBundle) error {
+	verification := pb.Spec.Verification
+	if verification == nil {
+		return nil
+	}
+	if verification.Digest == "" && verification.PublicKey == nil {
+		return common.NewNonRetriableCtrlError("at least one of 'digest' or 'publicKey' must be set in the verification")
+	}
+	if verification.PublicKey != nil && pb.Spec.ContentSource != nil {
+		return common.NewNonRetriableCtrlError("signatures can only be verified for content images, use a digest with a 'contentSource'")
+	}
+	return nil
+}
+
+// validateControlFrameworks checks that the frameworks registered by the
+// bundle have distinct names and valid reference patterns
+func validateControlFrameworks(pb *compliancev1alpha1.ProfileBundle) error {
+	names := map[string]bool{}
+	for _, framework := range pb.Spec.ControlFrameworks {
+		if names[framework.Name] {
+			return common.NewNonRetriableCtrlError("the control framework %s is registered more than once", framework.Name)
+		}
+		names[framework.Name] = true
+		if _, err := regexp.Compile(framework.ReferencePattern); err != nil {
+			return common.NewNonRetriableCtrlError("invalid reference pattern of the control framework %s: %s", framework.Name, err)
+		}
+	}
+	return nil
+}
+
+// setContentVerification makes the profile parser verify the data stream
+// before parsing it
+func setContentVerification(podSpec *corev1.PodSpec, pb *compliancev1alpha1.ProfileBundle) {
+	verification := pb.Spec.Verification
+	if verification == nil {
+		return
+	}
+
+	var parser *corev1.Container
+	for i := range podSpec.InitContainers {
+		if podSpec.InitContainers[i].Name == "profileparser" {
+			parser = &podSpec.InitContainers[i]
+		}
+	}
+	if parser == nil {
+		return
+	}
+
+	if verification.Digest != "" {
+		parser.Command = append(parser.Command, "--verify-digest", verification.Digest)
+	}
+	if verification.PublicKey == nil {
+		return
+	}
+
+	// The signature is copied out of the content image along with the data
+	// stream. A missing signature fails the container, which surfaces in
+	// the status of the bundle, rather than the parser.
+	signatureFile := path.Join("/", pb.GetSignatureFile())
+	for i := range podSpec.InitContainers {
+		container := &podSpec.InitContainers[i]
+		if container.Name == utils.ContentContainerName {
+			container.Command = []string{
+				"sh",
+				"-c",
+				fmt.Sprintf("cp %s /content | /bin/true; cp %s /content || "+
+					"{ echo 'The signature %s was not found in the content image' >&2; exit 1; }",
+					path.Join("/", pb.Spec.ContentFile), signatureFile, signatureFile),
+			}
+			container.TerminationMessagePolicy = corev1.TerminationMessageFallbackToLogsOnError
+		}
+	}
+
+	keySelector := verification.PublicKey.KeyConfigMap
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: verificationKeyVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: keySelector.Name},
+				Items:                []corev1.KeyToPath{{Key: keySelector.Key, Path: keySelector.Key}},
+			},
+		},
+	})
+	parser.VolumeMounts = append(parser.VolumeMounts, corev1.VolumeMount{
+		Name:      verificationKeyVolumeName,
+		MountPath: verificationKeyDir,
+		ReadOnly:  true,
+	})
+	parser.Command = append(parser.Command,
+		"--public-key-file", path.Join(verificationKeyDir, keySelector.Key),
+		"--signature-file", path.Join("/content", path.Base(signatureFile)))
+}
+
+// validateContentVolume checks that the PersistentVolumeClaim the content
+// is loaded from can be mounted read-only by several pods at once, as the
+// scans mount it on every node they run on
+func (r *ReconcileProfileBundle) validateContentVolume(pb *compliancev1alpha1.ProfileBundle) error {
+	source := pb.Spec.ContentSource
+	if source == nil || source.PersistentVolumeClaim == nil {
+		return nil
+	}
+	pvc := &corev1.PersistentVolumeClaim{}
+	key := types.NamespacedName{Name: source.PersistentVolumeClaim.ClaimName, Namespace: pb.Namespace}
+	if err := r.client.Get(context.TODO(), key, pvc); errors.IsNotFound(err) {
+		return common.NewNonRetriableCtrlError("the PersistentVolumeClaim %s of the content source was not found", key.Name)
+	} else if err != nil {
+		return err
+	}
+	if err := utils.ValidateContentVolumeAccessModes(pvc); err != nil {
+		return common.WrapNonRetriableCtrlError(err)
+	}
+	return nil
+}
+
+// getContentSourceDigest returns a digest of the content in the ConfigMap
+// or Secret the bundle loads it from, so that updating it gets the content
+// parsed again. It's empty for other sources, or if the object doesn't
+// exist yet, in which case the content can't be fetched anyway.
+func (r *ReconcileProfileBundle) getContentSourceDigest(pb *compliancev1alpha1.ProfileBundle) (string, error) {
+	source := pb.Spec.ContentSource
+	if source == nil {
+		return "", nil
+	}
+
+	var content []byte
+	switch {
+	case source.ConfigMap != nil:
+		cm := &corev1.ConfigMap{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: source.ConfigMap.Name, Namespace: pb.Namespace}, cm)
+		if errors.IsNotFound(err) {
+			return "", nil
+		} else if err != nil {
+			return "", err
+		}
+		if data, ok := cm.Data[source.ConfigMap.Key]; ok {
+			content = []byte(data)
+		} else {
+			content = cm.BinaryData[source.ConfigMap.Key]
+		}
+	case source.Secret != nil:
+		secret := &corev1.Secret{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: source.Secret.Name, Namespace: pb.Namespace}, secret)
+		if errors.IsNotFound(err) {
+			return "", nil
+		} else if err != nil {
+			return "", err
+		}
+		content = secret.Data[source.Secret.Key]
+	default:
+		return "", nil
+	}
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// getContentConfigHash returns a hash of how the content of the bundle is
+// fetched, verified and parsed, along with the digest of the content in
+// the source, see getContentSourceDigest. It's an empty string if the
+// content comes from an image, isn't verified and only references the
+// built-in frameworks.
+func getContentConfigHash(pb *compliancev1alpha1.ProfileBundle, sourceDigest string) string {
+	if pb.Spec.ContentSource == nil && pb.Spec.Verification == nil && len(pb.Spec.ControlFrameworks) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(struct {
+		ContentSource       *compliancev1alpha1.ContentSource       `json:"contentSource,omitempty"`
+		ContentSourceDigest string                                  `json:"contentSourceDigest,omitempty"`
+		Verification        *compliancev1alpha1.ContentVerification `json:"verification,omitempty"`
+		ControlFrameworks   []compliancev1alpha1.ControlFramework   `json:"controlFrameworks,omitempty"`
+	}{pb.Spec.ContentSource, sourceDigest, pb.Spec.Verification, pb.Spec.ControlFrameworks})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// contentConfigNeedsUpdate returns true if the way the content is fetched,
+// verified or parsed, or the content in its source, changed since the
+// workload was created
+func contentConfigNeedsUpdate(depl, found *appsv1.Deployment) bool {
+	return depl.Spec.Template.Annotations[contentConfigAnnotation] != found.Spec.Template.Annotations[contentConfigAnnotation]
+}
@@ -0,0 +1,57 @@
+package profilebundle
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	compliancev1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+// contentSourceMapper enqueues the ProfileBundles that load their content
+// from a ConfigMap or Secret when it changes
+type contentSourceMapper struct {
+	client.Client
+}
+
+func (m *contentSourceMapper) Map(obj handler.MapObject) []reconcile.Request {
+	var requests []reconcile.Request
+
+	pbList := compliancev1alpha1.ProfileBundleList{}
+	err := m.List(context.TODO(), &pbList, client.InNamespace(obj.Meta.GetNamespace()))
+	if err != nil {
+		return requests
+	}
+
+	for i := range pbList.Items {
+		pb := &pbList.Items[i]
+		if !usesContentSource(pb, obj) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: pb.GetName(), Namespace: pb.GetNamespace()},
+		})
+	}
+
+	return requests
+}
+
+func usesContentSource(pb *compliancev1alpha1.ProfileBundle, obj handler.MapObject) bool {
+	source := pb.Spec.ContentSource
+	if source == nil {
+		return false
+	}
+	name := obj.Meta.GetName()
+	switch obj.Object.(type) {
+	case *corev1.ConfigMap:
+		return (source.ConfigMap != nil && source.ConfigMap.Name == name) ||
+			(source.HTTPS != nil && source.HTTPS.CAConfigMap == name)
+	case *corev1.Secret:
+		return source.Secret != nil && source.Secret.Name == name
+	}
+	return false
+}
@@ -5,11 +5,13 @@ import (
 	"time"
 
 	"github.com/openshift/compliance-operator/pkg/controller/metrics"
+	"github.com/openshift/compliance-operator/pkg/controller/shared"
 
 	// #nosec G505
 
 	"fmt"
 	"path"
+	"strings"
 
 	"github.com/go-logr/logr"
 	ocpimg "github.com/openshift/api/image/v1"
@@ -42,8 +44,8 @@ var oneReplica int32 = 1
 
 // Add creates a new ProfileBundle Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
-func Add(mgr manager.Manager, met *metrics.Metrics, si utils.CtlplaneSchedulingInfo) error {
-	return add(mgr, newReconciler(mgr, met, si))
+func Add(mgr manager.Manager, deps shared.Dependencies) error {
+	return add(mgr, newReconciler(mgr, deps.Metrics, deps.SchedulingInfo))
 }
 
 // newReconciler returns a new reconcile.Reconciler
@@ -71,6 +73,20 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Watch for changes to the ConfigMaps and Secrets the content is
+	// loaded from, so that updated content is parsed again
+	contentSourceHandler := &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: &contentSourceMapper{mgr.GetClient()},
+	}
+	err = c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, contentSourceHandler)
+	if err != nil {
+		return err
+	}
+	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, contentSourceHandler)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -128,9 +144,16 @@ func (r *ReconcileProfileBundle) Reconcile(request reconcile.Request) (reconcile
 		}
 	} else {
 		// The object is being deleted
+		r.metrics.DeleteProfileBundle(instance.Name)
 		return reconcile.Result{}, r.profileBundleDeleteHandler(instance, reqLogger)
 	}
 
+	// The status is also updated by the profileparser, which triggers a
+	// reconcile
+	if instance.Status.DataStreamStatus != "" {
+		r.metrics.SetProfileBundleStatus(instance.Name, instance.Status.DataStreamStatus)
+	}
+
 	// We should always start with an appropriate status
 	if instance.Status.DataStreamStatus == "" {
 		pb := instance.DeepCopy()
@@ -151,7 +174,7 @@ func (r *ReconcileProfileBundle) Reconcile(request reconcile.Request) (reconcile
 	}
 
 	annotations := map[string]string{}
-	isISTag, isTagImageRef, err := r.pointsToISTag(instance.Spec.ContentImage)
+	isISTag, isTagImageRef, err := r.resolveContentImage(instance)
 	if err != nil {
 		if common.IsRetriable(err) {
 			return reconcile.Result{}, err
@@ -176,10 +199,18 @@ func (r *ReconcileProfileBundle) Reconcile(request reconcile.Request) (reconcile
 		ref, _ := reference.Parse(instance.Spec.ContentImage)
 		annotations = getISTagAnnotation(ref.NameString(), getISTagNamespace(ref))
 		effectiveImage = isTagImageRef
+	} else if instance.Spec.ContentSource != nil {
+		// The content is fetched by the operator itself
+		effectiveImage = utils.GetComponentImage(utils.OPERATOR)
+	}
+
+	sourceDigest, err := r.getContentSourceDigest(instance)
+	if err != nil {
+		return reconcile.Result{}, err
 	}
 
 	// Define a new Pod object
-	depl := r.newWorkloadForBundle(instance, effectiveImage)
+	depl := r.newWorkloadForBundle(instance, effectiveImage, sourceDigest)
 
 	found := &appsv1.Deployment{}
 	err = r.client.Get(context.TODO(), types.NamespacedName{Name: depl.Name, Namespace: depl.Namespace}, found)
@@ -196,7 +227,7 @@ func (r *ReconcileProfileBundle) Reconcile(request reconcile.Request) (reconcile
 		return reconcile.Result{}, err
 	}
 
-	if workloadNeedsUpdate(effectiveImage, found) {
+	if workloadNeedsUpdate(effectiveImage, found) || contentConfigNeedsUpdate(depl, found) {
 		pbCopy := instance.DeepCopy()
 		pbCopy.Status.DataStreamStatus = compliancev1alpha1.DataStreamPending
 		pbCopy.Status.ErrorMessage = ""
@@ -253,6 +284,26 @@ func (r *ReconcileProfileBundle) Reconcile(request reconcile.Request) (reconcile
 		return reconcile.Result{}, nil
 	}
 
+	// Fetching from a content source and copying the signature of the
+	// content out of the image report why they failed
+	if fetchErr := contentFetchError(relevantPod); fetchErr != "" && (instance.Spec.ContentSource != nil || instance.GetSignatureFile() != "") {
+		pbCopy := instance.DeepCopy()
+		pbCopy.Status.DataStreamStatus = compliancev1alpha1.DataStreamInvalid
+		if instance.Spec.ContentSource != nil {
+			pbCopy.Status.ErrorMessage = "Fetching the content failed. Verify Spec.ContentSource: " + fetchErr
+		} else {
+			pbCopy.Status.ErrorMessage = "Fetching the content signature failed. Verify Spec.ContentImage: " + fetchErr
+		}
+		pbCopy.Status.SetConditionInvalid()
+		err = r.client.Status().Update(context.TODO(), pbCopy)
+		if err != nil {
+			reqLogger.Error(err, "Couldn't update ProfileBundle status")
+			return reconcile.Result{}, err
+		}
+		// this was a fatal error, don't requeue
+		return reconcile.Result{}, nil
+	}
+
 	// Pod already exists and its init container at least ran - don't requeue
 	reqLogger.Info("Skip reconcile: Workload already up-to-date", "Deployment.Namespace", found.Namespace, "Deployment.Name", found.Name)
 
@@ -273,7 +324,7 @@ func (r *ReconcileProfileBundle) Reconcile(request reconcile.Request) (reconcile
 
 func (r *ReconcileProfileBundle) profileBundleDeleteHandler(pb *compliancev1alpha1.ProfileBundle, logger logr.Logger) error {
 	logger.Info("The ProfileBundle is being deleted")
-	pod := r.newWorkloadForBundle(pb, "")
+	pod := r.newWorkloadForBundle(pb, "", "")
 	logger.Info("Deleting profileparser workload", "Pod.Name", pod.Name)
 	err := r.client.Delete(context.TODO(), pod)
 	if err != nil && !errors.IsNotFound(err) {
@@ -289,6 +340,32 @@ func (r *ReconcileProfileBundle) profileBundleDeleteHandler(pb *compliancev1alph
 	return nil
 }
 
+// resolveContentImage validates where the content of the bundle comes
+// from, how it's verified and parsed, and resolves the content image if it points to an ImageStreamTag
+func (r *ReconcileProfileBundle) resolveContentImage(pb *compliancev1alpha1.ProfileBundle) (bool, string, error) {
+	if err := validateContentVerification(pb); err != nil {
+		return false, "", err
+	}
+	if err := validateControlFrameworks(pb); err != nil {
+		return false, "", err
+	}
+
+	if pb.Spec.ContentSource == nil {
+		if pb.Spec.ContentImage == "" {
+			return false, "", common.NewNonRetriableCtrlError("either 'contentImage' or 'contentSource' must be set")
+		}
+		return r.pointsToISTag(pb.Spec.ContentImage)
+	}
+
+	if pb.Spec.ContentImage != "" {
+		return false, "", common.NewNonRetriableCtrlError("'contentImage' and 'contentSource' can't be combined")
+	}
+	if err := utils.ValidateContentSource(pb.Spec.ContentSource); err != nil {
+		return false, "", common.WrapNonRetriableCtrlError(err)
+	}
+	return false, "", r.validateContentVolume(pb)
+}
+
 func (r *ReconcileProfileBundle) pointsToISTag(contentImageRef string) (bool, string, error) {
 	ref, err := reference.Parse(contentImageRef)
 	if err != nil {
@@ -402,11 +479,11 @@ func getISTagAnnotation(isTagName, isTagNamespace string) map[string]string {
 	}
 }
 
-func (r *ReconcileProfileBundle) newWorkloadForBundle(pb *compliancev1alpha1.ProfileBundle, image string) *appsv1.Deployment {
+func (r *ReconcileProfileBundle) newWorkloadForBundle(pb *compliancev1alpha1.ProfileBundle, image, sourceDigest string) *appsv1.Deployment {
 	falseP := false
 	trueP := true
 	labels := getWorkloadLabels(pb)
-	return &appsv1.Deployment{
+	depl := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      pb.Name + "-" + pb.Namespace + "-pp",
 			Namespace: common.GetComplianceOperatorNamespace(),
@@ -481,6 +558,16 @@ func (r *ReconcileProfileBundle) newWorkloadForBundle(pb *compliancev1alpha1.Pro
 								"--namespace", pb.Namespace,
 								"--ds-path", path.Join("/content", pb.Spec.ContentFile),
 							},
+							Env: []corev1.EnvVar{
+								{
+									Name: "POD_NAME",
+									ValueFrom: &corev1.EnvVarSource{
+										FieldRef: &corev1.ObjectFieldSelector{
+											FieldPath: "metadata.name",
+										},
+									},
+								},
+							},
 							VolumeMounts: []corev1.VolumeMount{
 								{
 									Name:      "content-dir",
@@ -534,6 +621,12 @@ func (r *ReconcileProfileBundle) newWorkloadForBundle(pb *compliancev1alpha1.Pro
 			},
 		},
 	}
+	if hash := getContentConfigHash(pb, sourceDigest); hash != "" {
+		depl.Spec.Template.Annotations[contentConfigAnnotation] = hash
+	}
+	utils.SetContentSource(&depl.Spec.Template.Spec, pb.Spec.ContentSource, pb.Spec.ContentFile)
+	setContentVerification(&depl.Spec.Template.Spec, pb)
+	return depl
 }
 
 // podStartupError returns false if for some reason the pod couldn't even
@@ -562,6 +655,30 @@ func podStartupError(pod *corev1.Pod) bool {
 	return false
 }
 
+// contentFetchError returns the error of the init container fetching the
+// content from a content source, if it failed
+func contentFetchError(pod *corev1.Pod) string {
+	for _, initStatus := range pod.Status.InitContainerStatuses {
+		if initStatus.Name != utils.ContentContainerName {
+			continue
+		}
+		// A failed init container is restarted, so the error might only
+		// be found in the last state while it's backing off
+		terminated := initStatus.State.Terminated
+		if terminated == nil && initStatus.State.Waiting != nil {
+			terminated = initStatus.LastTerminationState.Terminated
+		}
+		if terminated == nil || terminated.ExitCode == 0 {
+			return ""
+		}
+		if msg := strings.TrimSpace(terminated.Message); msg != "" {
+			return msg
+		}
+		return fmt.Sprintf("the content fetcher exited with %d", terminated.ExitCode)
+	}
+	return ""
+}
+
 func workloadNeedsUpdate(image string, depl *appsv1.Deployment) bool {
 	initContainers := depl.Spec.Template.Spec.InitContainers
 	if len(initContainers) != 2 {
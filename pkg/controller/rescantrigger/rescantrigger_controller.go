@@ -0,0 +1,367 @@
+package rescantrigger
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/shared"
+)
+
+var log = logf.Log.WithName("rescantriggerctrl")
+
+const (
+	// How long to wait before retrying to watch the kinds that aren't
+	// served, e.g. because their CRD isn't installed yet
+	requeueAfterWatchError = time.Minute
+	// How many changes are listed in the reason of a rescan
+	maxReasons = 5
+)
+
+// Add creates a new rescan trigger Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager, _ shared.Dependencies) error {
+	dyn, err := dynamic.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return err
+	}
+	r := newReconciler(mgr.GetClient(), mgr.GetEventRecorderFor("rescantriggerctrl"))
+	r.watcher = newInformerWatcher(dyn, mgr.GetRESTMapper(), r.trigger)
+	return add(mgr, r)
+}
+
+// newReconciler returns a new ReconcileRescanTrigger without a watcher
+func newReconciler(c client.Client, recorder record.EventRecorder) *ReconcileRescanTrigger {
+	return &ReconcileRescanTrigger{
+		client:   c,
+		recorder: recorder,
+		changes:  make(chan event.GenericEvent, 100),
+		pending:  make(map[types.NamespacedName]*pendingRescan),
+	}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r *ReconcileRescanTrigger) error {
+	// Create a new controller
+	c, err := controller.New("rescantrigger-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the ComplianceSuites, so that the resources their
+	// triggers select get watched
+	err = c.Watch(&source.Kind{Type: &compv1alpha1.ComplianceSuite{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for the suites whose triggers saw a change
+	err = c.Watch(&source.Channel{Source: r.changes}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// blank assignment to verify that ReconcileRescanTrigger implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileRescanTrigger{}
+
+// pendingRescan is a rescan waiting for the resources that triggered it to
+// settle
+type pendingRescan struct {
+	// When the last change was seen
+	lastChange time.Time
+	// The changes, in the order they were seen
+	reasons []string
+	// How many changes aren't listed in reasons
+	moreReasons int
+	// The types of the scans to rescan, all of them if allTypes is set
+	scanTypes map[compv1alpha1.ComplianceScanType]bool
+	allTypes  bool
+}
+
+// ReconcileRescanTrigger rescans the scans of the ComplianceSuites whose
+// rescan triggers saw the resources they watch change
+type ReconcileRescanTrigger struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client   client.Client
+	recorder record.EventRecorder
+	watcher  watcher
+	// Receives the suites whose triggers saw a change
+	changes chan event.GenericEvent
+
+	sync.Mutex
+	// The rescans waiting for the changes to settle, by suite
+	pending map[types.NamespacedName]*pendingRescan
+}
+
+// InjectStopChannel is called by the manager, the watches of the triggers
+// run until it's closed
+func (r *ReconcileRescanTrigger) InjectStopChannel(stop <-chan struct{}) error {
+	if w, ok := r.watcher.(*informerWatcher); ok {
+		w.Lock()
+		w.stop = stop
+		w.Unlock()
+	}
+	return nil
+}
+
+// Reconcile makes sure the resources the rescan triggers of a ComplianceSuite select are watched and rescans its
+// scans once the changes they saw settled.
+func (r *ReconcileRescanTrigger) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+
+	suite := &compv1alpha1.ComplianceSuite{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, suite)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			r.forget(request.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+		reqLogger.Error(err, "Cannot get the suite")
+		return reconcile.Result{}, err
+	}
+
+	if !suite.GetDeletionTimestamp().IsZero() || len(suite.Spec.RescanTriggers) == 0 {
+		r.forget(request.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+
+	// The suite controller reports invalid triggers
+	if err := suite.Spec.ValidateRescanTriggers(); err != nil {
+		reqLogger.Info("Ignoring the invalid rescan triggers", "error", err.Error())
+		r.forget(request.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+
+	result := reconcile.Result{}
+	for i := range suite.Spec.RescanTriggers {
+		trigger := &suite.Spec.RescanTriggers[i]
+		if err := r.watcher.watch(trigger.GroupVersionKind(), trigger.Namespace); err != nil {
+			reqLogger.Info("Cannot watch the resources of a rescan trigger", "Kind", trigger.GroupVersionKind().String(), "error", err.Error())
+			r.recorder.Eventf(suite, corev1.EventTypeWarning, "RescanTriggerError",
+				"Cannot watch the %s resources: %s", trigger.GroupVersionKind().String(), err)
+			result.RequeueAfter = requeueAfterWatchError
+		}
+	}
+
+	pending := r.getPending(request.NamespacedName)
+	if pending == nil {
+		return result, nil
+	}
+
+	delay, _ := suite.Spec.GetRescanTriggerDelay()
+	if wait := delay - time.Since(pending.lastChange); wait > 0 {
+		reqLogger.Info("Waiting for the changes to settle before rescanning", "RequeueAfter", wait)
+		return reconcile.Result{RequeueAfter: wait}, nil
+	}
+
+	scans, err := r.rescan(suite, pending, reqLogger)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	reason := pending.reason()
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &compv1alpha1.ComplianceSuite{}
+		if err := r.client.Get(context.TODO(), request.NamespacedName, latest); err != nil {
+			return err
+		}
+		latest.Status.LastRescanTrigger = &compv1alpha1.RescanTriggerStatus{
+			Time:   metav1.Now(),
+			Reason: reason,
+			Scans:  scans,
+		}
+		return r.client.Status().Update(context.TODO(), latest)
+	})
+	if err != nil {
+		reqLogger.Error(err, "Cannot record the rescan in the suite status")
+		return reconcile.Result{}, err
+	}
+
+	reqLogger.Info("Rescanned the suite", "Reason", reason, "Scans", scans)
+	r.recorder.Eventf(suite, corev1.EventTypeNormal, "RescanTriggered", "Rescanning %d scans: %s", len(scans), reason)
+	r.clearPending(request.NamespacedName, pending.lastChange)
+	return result, nil
+}
+
+// rescan annotates the scans of the suite the pending rescan applies to.
+// The scans that are still running are rescanned once they're done.
+func (r *ReconcileRescanTrigger) rescan(suite *compv1alpha1.ComplianceSuite, pending *pendingRescan, logger logr.Logger) ([]string, error) {
+	scanList := &compv1alpha1.ComplianceScanList{}
+	listOpts := &client.ListOptions{
+		LabelSelector: labels.SelectorFromSet(map[string]string{compv1alpha1.SuiteLabel: suite.Name}),
+		Namespace:     suite.Namespace,
+	}
+	if err := r.client.List(context.TODO(), scanList, listOpts); err != nil {
+		logger.Error(err, "Cannot list the scans of the suite")
+		return nil, err
+	}
+
+	rescanned := make([]string, 0)
+	for i := range scanList.Items {
+		scan := &scanList.Items[i]
+		scanType, err := scan.GetScanTypeIfValid()
+		if err != nil || !(pending.allTypes || pending.scanTypes[scanType]) {
+			continue
+		}
+
+		key := types.NamespacedName{Name: scan.Name, Namespace: scan.Namespace}
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			latest := &compv1alpha1.ComplianceScan{}
+			if err := r.client.Get(context.TODO(), key, latest); err != nil {
+				return err
+			}
+			if latest.NeedsRescan() {
+				return nil
+			}
+			if latest.Annotations == nil {
+				latest.Annotations = make(map[string]string)
+			}
+			latest.Annotations[compv1alpha1.ComplianceScanRescanAnnotation] = ""
+			return r.client.Update(context.TODO(), latest)
+		})
+		if err != nil {
+			logger.Error(err, "Cannot rescan the scan", "ComplianceScan.Name", scan.Name)
+			return nil, err
+		}
+		rescanned = append(rescanned, scan.Name)
+	}
+	sort.Strings(rescanned)
+	return rescanned, nil
+}
+
+// trigger is called by the watches with the resources that changed. It
+// records a pending rescan for the suites with a matching trigger and
+// queues them.
+func (r *ReconcileRescanTrigger) trigger(gvk schema.GroupVersionKind, obj *unstructured.Unstructured, verb string) {
+	suiteList := &compv1alpha1.ComplianceSuiteList{}
+	if err := r.client.List(context.TODO(), suiteList); err != nil {
+		log.Error(err, "Cannot list the suites to trigger rescans")
+		return
+	}
+
+	reason := describeChange(gvk, obj, verb)
+	now := time.Now()
+	for i := range suiteList.Items {
+		suite := &suiteList.Items[i]
+		matched := false
+		for j := range suite.Spec.RescanTriggers {
+			trigger := &suite.Spec.RescanTriggers[j]
+			if trigger.GroupVersionKind() != gvk || !trigger.Matches(obj) {
+				continue
+			}
+			matched = true
+			r.addPending(types.NamespacedName{Name: suite.Name, Namespace: suite.Namespace}, now, reason, trigger.ScanType)
+		}
+		if matched {
+			log.Info("Rescan triggered", "ComplianceSuite.Name", suite.Name, "Reason", reason)
+			r.changes <- event.GenericEvent{Meta: suite, Object: suite}
+		}
+	}
+}
+
+func (r *ReconcileRescanTrigger) addPending(key types.NamespacedName, now time.Time, reason string, scanType compv1alpha1.ComplianceScanType) {
+	r.Lock()
+	defer r.Unlock()
+
+	pending, ok := r.pending[key]
+	if !ok {
+		pending = &pendingRescan{scanTypes: make(map[compv1alpha1.ComplianceScanType]bool)}
+		r.pending[key] = pending
+	}
+	pending.lastChange = now
+	if scanType == "" {
+		pending.allTypes = true
+	} else {
+		pending.scanTypes[scanType] = true
+	}
+	for _, existing := range pending.reasons {
+		if existing == reason {
+			return
+		}
+	}
+	if len(pending.reasons) < maxReasons {
+		pending.reasons = append(pending.reasons, reason)
+	} else {
+		pending.moreReasons++
+	}
+}
+
+// getPending returns a copy of the pending rescan of the suite, if any
+func (r *ReconcileRescanTrigger) getPending(key types.NamespacedName) *pendingRescan {
+	r.Lock()
+	defer r.Unlock()
+
+	pending, ok := r.pending[key]
+	if !ok {
+		return nil
+	}
+	copied := *pending
+	copied.reasons = append([]string(nil), pending.reasons...)
+	copied.scanTypes = make(map[compv1alpha1.ComplianceScanType]bool, len(pending.scanTypes))
+	for scanType := range pending.scanTypes {
+		copied.scanTypes[scanType] = true
+	}
+	return &copied
+}
+
+// clearPending removes the pending rescan of the suite unless a new change
+// was seen since it was handled
+func (r *ReconcileRescanTrigger) clearPending(key types.NamespacedName, handled time.Time) {
+	r.Lock()
+	defer r.Unlock()
+
+	if pending, ok := r.pending[key]; ok && !pending.lastChange.After(handled) {
+		delete(r.pending, key)
+	}
+}
+
+func (r *ReconcileRescanTrigger) forget(key types.NamespacedName) {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.pending, key)
+}
+
+func (p *pendingRescan) reason() string {
+	reason := strings.Join(p.reasons, ", ")
+	if p.moreReasons > 0 {
+		reason = fmt.Sprintf("%s and %d more changes", reason, p.moreReasons)
+	}
+	return reason
+}
+
+// describeChange returns e.g. "APIServer cluster updated"
+func describeChange(gvk schema.GroupVersionKind, obj *unstructured.Unstructured, verb string) string {
+	name := obj.GetName()
+	if obj.GetNamespace() != "" {
+		name = obj.GetNamespace() + "/" + name
+	}
+	return fmt.Sprintf("%s %s %s", gvk.Kind, name, verb)
+}
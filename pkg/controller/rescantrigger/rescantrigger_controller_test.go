@@ -0,0 +1,300 @@
+package rescantrigger
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/openshift/compliance-operator/pkg/apis"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+// fakeWatcher records the kinds it's asked to watch
+type fakeWatcher struct {
+	watched map[watchKey]bool
+	err     error
+}
+
+func (w *fakeWatcher) watch(gvk schema.GroupVersionKind, namespace string) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.watched[watchKey{gvk: gvk, namespace: namespace}] = true
+	return nil
+}
+
+// fakeResource answers the lists with the given error, if any, and never
+// sends any watch event
+type fakeResource struct {
+	dynamic.NamespaceableResourceInterface
+	err error
+}
+
+func (r *fakeResource) List(_ context.Context, _ metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &unstructured.UnstructuredList{}, nil
+}
+
+func (r *fakeResource) Watch(_ context.Context, _ metav1.ListOptions) (watch.Interface, error) {
+	return watch.NewFake(), nil
+}
+
+type fakeDynamic struct {
+	dynamic.Interface
+	resource *fakeResource
+}
+
+func (d *fakeDynamic) Resource(_ schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return d.resource
+}
+
+func newResource(apiVersion, kind, name string, generation int64) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetName(name)
+	obj.SetGeneration(generation)
+	obj.SetResourceVersion(fmt.Sprintf("%d", generation))
+	obj.SetLabels(map[string]string{"machineconfiguration.openshift.io/role": "worker"})
+	return obj
+}
+
+var _ = Describe("ReconcileRescanTrigger", func() {
+	var (
+		reconciler *ReconcileRescanTrigger
+		watcher    *fakeWatcher
+		suite      *compv1alpha1.ComplianceSuite
+		ctx        = context.Background()
+		namespace  = "test-ns"
+		suiteKey   = types.NamespacedName{Name: "suite", Namespace: namespace}
+		apiServer  = schema.GroupVersionKind{Group: "config.openshift.io", Version: "v1", Kind: "APIServer"}
+		mc         = schema.GroupVersionKind{Group: "machineconfiguration.openshift.io", Version: "v1", Kind: "MachineConfig"}
+	)
+
+	newScan := func(name string, scanType compv1alpha1.ComplianceScanType) *compv1alpha1.ComplianceScan {
+		return &compv1alpha1.ComplianceScan{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{compv1alpha1.SuiteLabel: suiteKey.Name},
+			},
+			Spec: compv1alpha1.ComplianceScanSpec{ScanType: scanType},
+		}
+	}
+
+	isRescanned := func(name string) bool {
+		scan := &compv1alpha1.ComplianceScan{}
+		err := reconciler.client.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, scan)
+		Expect(err).To(BeNil())
+		return scan.NeedsRescan()
+	}
+
+	getSuite := func() *compv1alpha1.ComplianceSuite {
+		found := &compv1alpha1.ComplianceSuite{}
+		err := reconciler.client.Get(ctx, suiteKey, found)
+		Expect(err).To(BeNil())
+		return found
+	}
+
+	BeforeEach(func() {
+		suite = &compv1alpha1.ComplianceSuite{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      suiteKey.Name,
+				Namespace: namespace,
+			},
+			Spec: compv1alpha1.ComplianceSuiteSpec{
+				ComplianceSuiteSettings: compv1alpha1.ComplianceSuiteSettings{
+					RescanTriggerDelay: "0s",
+					RescanTriggers: []compv1alpha1.RescanTrigger{
+						{
+							APIVersion: "config.openshift.io/v1",
+							Kind:       "APIServer",
+							Name:       "cluster",
+							ScanType:   compv1alpha1.ScanTypePlatform,
+						},
+						{
+							APIVersion: "machineconfiguration.openshift.io/v1",
+							Kind:       "MachineConfig",
+							LabelSelector: &metav1.LabelSelector{
+								MatchLabels: map[string]string{"machineconfiguration.openshift.io/role": "worker"},
+							},
+							ScanType: compv1alpha1.ScanTypeNode,
+						},
+					},
+				},
+			},
+		}
+
+		objs := []runtime.Object{
+			suite,
+			newScan("platform", compv1alpha1.ScanTypePlatform),
+			newScan("workers", compv1alpha1.ScanTypeNode),
+		}
+		cscheme := scheme.Scheme
+		err := apis.AddToScheme(cscheme)
+		Expect(err).To(BeNil())
+		client := fake.NewFakeClientWithScheme(cscheme, objs...)
+
+		reconciler = newReconciler(client, record.NewFakeRecorder(100))
+		watcher = &fakeWatcher{watched: make(map[watchKey]bool)}
+		reconciler.watcher = watcher
+	})
+
+	It("watches the resources of the triggers", func() {
+		result, err := reconciler.Reconcile(reconcile.Request{NamespacedName: suiteKey})
+		Expect(err).To(BeNil())
+		Expect(result).To(Equal(reconcile.Result{}))
+		Expect(watcher.watched).To(HaveKey(watchKey{gvk: apiServer}))
+		Expect(watcher.watched).To(HaveKey(watchKey{gvk: mc}))
+		Expect(isRescanned("platform")).To(BeFalse())
+		Expect(isRescanned("workers")).To(BeFalse())
+	})
+
+	It("retries watching the kinds that aren't served", func() {
+		watcher.err = fmt.Errorf("no matches for kind")
+		result, err := reconciler.Reconcile(reconcile.Request{NamespacedName: suiteKey})
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(Equal(requeueAfterWatchError))
+	})
+
+	It("only rescans the scans of the type of the matching trigger", func() {
+		reconciler.trigger(apiServer, newResource("config.openshift.io/v1", "APIServer", "cluster", 2), "updated")
+		Expect(reconciler.changes).To(HaveLen(1))
+
+		_, err := reconciler.Reconcile(reconcile.Request{NamespacedName: suiteKey})
+		Expect(err).To(BeNil())
+		Expect(isRescanned("platform")).To(BeTrue())
+		Expect(isRescanned("workers")).To(BeFalse())
+
+		status := getSuite().Status.LastRescanTrigger
+		Expect(status).NotTo(BeNil())
+		Expect(status.Reason).To(Equal("APIServer cluster updated"))
+		Expect(status.Scans).To(Equal([]string{"platform"}))
+		Expect(reconciler.pending).To(BeEmpty())
+	})
+
+	It("ignores the resources the triggers don't select", func() {
+		reconciler.trigger(apiServer, newResource("config.openshift.io/v1", "APIServer", "other", 2), "updated")
+		master := newResource("machineconfiguration.openshift.io/v1", "MachineConfig", "99-master", 1)
+		master.SetLabels(map[string]string{"machineconfiguration.openshift.io/role": "master"})
+		reconciler.trigger(mc, master, "created")
+		Expect(reconciler.changes).To(BeEmpty())
+		Expect(reconciler.pending).To(BeEmpty())
+	})
+
+	It("waits for the changes to settle before rescanning", func() {
+		suite.Spec.RescanTriggerDelay = "1h"
+		err := reconciler.client.Update(ctx, suite)
+		Expect(err).To(BeNil())
+
+		for i := 0; i < 3; i++ {
+			reconciler.trigger(mc, newResource("machineconfiguration.openshift.io/v1", "MachineConfig", "99-worker", 1), "created")
+		}
+		reconciler.trigger(mc, newResource("machineconfiguration.openshift.io/v1", "MachineConfig", "99-worker-ssh", 1), "created")
+
+		result, err := reconciler.Reconcile(reconcile.Request{NamespacedName: suiteKey})
+		Expect(err).To(BeNil())
+		Expect(result.RequeueAfter).To(BeNumerically(">", 59*time.Minute))
+		Expect(isRescanned("workers")).To(BeFalse())
+
+		// Pretend the changes were seen an hour ago
+		reconciler.pending[suiteKey].lastChange = time.Now().Add(-time.Hour)
+		_, err = reconciler.Reconcile(reconcile.Request{NamespacedName: suiteKey})
+		Expect(err).To(BeNil())
+		Expect(isRescanned("workers")).To(BeTrue())
+		Expect(isRescanned("platform")).To(BeFalse())
+		Expect(getSuite().Status.LastRescanTrigger.Reason).To(Equal("MachineConfig 99-worker created, MachineConfig 99-worker-ssh created"))
+	})
+})
+
+var _ = Describe("Change handler", func() {
+	var (
+		changes []string
+		handler cache.ResourceEventHandler
+		gvk     = schema.GroupVersionKind{Group: "config.openshift.io", Version: "v1", Kind: "APIServer"}
+		started = time.Now()
+	)
+
+	BeforeEach(func() {
+		changes = nil
+		handler = newChangeHandler(gvk, started, func(_ schema.GroupVersionKind, obj *unstructured.Unstructured, verb string) {
+			changes = append(changes, describeChange(gvk, obj, verb))
+		})
+	})
+
+	It("skips the resources listed when the watch starts", func() {
+		old := newResource("config.openshift.io/v1", "APIServer", "old", 1)
+		old.SetCreationTimestamp(metav1.NewTime(started.Add(-time.Hour)))
+		handler.OnAdd(old)
+		created := newResource("config.openshift.io/v1", "APIServer", "created", 1)
+		created.SetCreationTimestamp(metav1.NewTime(started.Add(time.Minute)))
+		handler.OnAdd(created)
+		Expect(changes).To(Equal([]string{"APIServer created created"}))
+	})
+
+	It("skips the updates that don't change the generation", func() {
+		oldObj := newResource("config.openshift.io/v1", "APIServer", "cluster", 1)
+		statusOnly := oldObj.DeepCopy()
+		statusOnly.SetResourceVersion("10")
+		handler.OnUpdate(oldObj, statusOnly)
+		Expect(changes).To(BeEmpty())
+
+		handler.OnUpdate(oldObj, newResource("config.openshift.io/v1", "APIServer", "cluster", 2))
+		handler.OnDelete(cache.DeletedFinalStateUnknown{Obj: oldObj})
+		Expect(changes).To(Equal([]string{"APIServer cluster updated", "APIServer cluster deleted"}))
+	})
+})
+
+var _ = Describe("Informer watcher", func() {
+	var (
+		watcher  *informerWatcher
+		resource *fakeResource
+		stop     chan struct{}
+		gvk      = schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+	)
+
+	BeforeEach(func() {
+		mapper := meta.NewDefaultRESTMapper(nil)
+		mapper.Add(gvk, meta.RESTScopeRoot)
+		resource = &fakeResource{}
+		watcher = newInformerWatcher(&fakeDynamic{resource: resource}, mapper, nil)
+		stop = make(chan struct{})
+		watcher.stop = stop
+	})
+
+	AfterEach(func() {
+		close(stop)
+	})
+
+	It("reports the resources the operator can't list", func() {
+		resource.err = kerrors.NewForbidden(schema.GroupResource{Group: "example.com", Resource: "widgets"}, "", fmt.Errorf("denied"))
+		err := watcher.watch(gvk, "")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("isn't allowed to list"))
+		Expect(watcher.informers).To(BeEmpty())
+
+		By("Watching them once they can be listed")
+		resource.err = nil
+		Expect(watcher.watch(gvk, "")).To(Succeed())
+		Expect(watcher.informers).To(HaveLen(1))
+	})
+})
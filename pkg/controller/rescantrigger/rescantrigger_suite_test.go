@@ -0,0 +1,13 @@
+package rescantrigger
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRescantrigger(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Rescantrigger Suite")
+}
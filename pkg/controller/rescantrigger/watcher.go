@@ -0,0 +1,156 @@
+package rescantrigger
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+)
+
+// changeFunc is called with the resources the watches see created, updated
+// or deleted
+type changeFunc func(gvk schema.GroupVersionKind, obj *unstructured.Unstructured, verb string)
+
+// watcher starts watching the resources of a kind
+type watcher interface {
+	// watch makes sure the resources of the kind in the namespace, or in
+	// all namespaces if it's empty, are watched
+	watch(gvk schema.GroupVersionKind, namespace string) error
+}
+
+type watchKey struct {
+	gvk       schema.GroupVersionKind
+	namespace string
+}
+
+// informerWatcher watches arbitrary kinds with an informer each. As the
+// watched kinds are only known at runtime, the informers are built on the
+// dynamic client rather than on the manager's cache. The role of the
+// operator only allows it to watch the config.openshift.io resources and
+// the MachineConfigs, the other kinds fail to be watched unless the role is
+// extended. The informers are
+// never stopped before the operator, as a kind that's no longer used by
+// any trigger is cheap to keep watching and likely to be used again.
+type informerWatcher struct {
+	sync.Mutex
+	dynamic   dynamic.Interface
+	mapper    meta.RESTMapper
+	onChange  changeFunc
+	stop      <-chan struct{}
+	informers map[watchKey]cache.SharedIndexInformer
+}
+
+func newInformerWatcher(dyn dynamic.Interface, mapper meta.RESTMapper, onChange changeFunc) *informerWatcher {
+	return &informerWatcher{
+		dynamic:   dyn,
+		mapper:    mapper,
+		onChange:  onChange,
+		informers: make(map[watchKey]cache.SharedIndexInformer),
+	}
+}
+
+func (w *informerWatcher) watch(gvk schema.GroupVersionKind, namespace string) error {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.stop == nil {
+		return fmt.Errorf("the watches can't be started before the manager")
+	}
+
+	mapping, err := w.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return err
+	}
+	var resource dynamic.ResourceInterface = w.dynamic.Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace && namespace != "" {
+		resource = w.dynamic.Resource(mapping.Resource).Namespace(namespace)
+	} else {
+		namespace = ""
+	}
+
+	key := watchKey{gvk: gvk, namespace: namespace}
+	if _, ok := w.informers[key]; ok {
+		return nil
+	}
+
+	// The informer would retry listing the resources forever, so the
+	// resources the operator can't list are reported before starting it
+	if _, err := resource.List(context.TODO(), metav1.ListOptions{Limit: 1}); err != nil {
+		if errors.IsForbidden(err) {
+			return fmt.Errorf("the operator isn't allowed to list the %s resources, its role only grants "+
+				"the config.openshift.io ones and the MachineConfigs: %w", gvk.String(), err)
+		}
+		return err
+	}
+
+	informer := cache.NewSharedIndexInformer(&cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			return resource.List(context.TODO(), opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			return resource.Watch(context.TODO(), opts)
+		},
+	}, &unstructured.Unstructured{}, 0, cache.Indexers{})
+	informer.AddEventHandler(newChangeHandler(gvk, time.Now(), w.onChange))
+	w.informers[key] = informer
+
+	log.Info("Watching resources for rescan triggers", "Kind", gvk.String(), "Namespace", namespace)
+	go informer.Run(w.stop)
+	return nil
+}
+
+// newChangeHandler returns an event handler that calls onChange with the
+// resources that actually changed. The resources the informer lists when
+// it starts are only passed if they were created after it started, and the
+// updates that only touch the status of resources that keep track of their
+// generation are skipped.
+func newChangeHandler(gvk schema.GroupVersionKind, started time.Time, onChange changeFunc) cache.ResourceEventHandler {
+	// The creation timestamps only have a precision of seconds
+	started = started.Truncate(time.Second)
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			u, ok := obj.(*unstructured.Unstructured)
+			if !ok || u.GetCreationTimestamp().Time.Before(started) {
+				return
+			}
+			onChange(gvk, u, "created")
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldU, ok := oldObj.(*unstructured.Unstructured)
+			if !ok {
+				return
+			}
+			newU, ok := newObj.(*unstructured.Unstructured)
+			if !ok {
+				return
+			}
+			if oldU.GetResourceVersion() == newU.GetResourceVersion() {
+				return
+			}
+			if newU.GetGeneration() != 0 && oldU.GetGeneration() == newU.GetGeneration() {
+				return
+			}
+			onChange(gvk, newU, "updated")
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			u, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				return
+			}
+			onChange(gvk, u, "deleted")
+		},
+	}
+}
@@ -0,0 +1,92 @@
+package scansettingbinding
+
+import (
+	"context"
+	"reflect"
+
+	monitoring "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	compliancev1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/metrics"
+)
+
+// getAlertRuleName returns the name of the PrometheusRule holding the
+// alerts for the results of the suite of a binding
+func getAlertRuleName(instance *compliancev1alpha1.ScanSettingBinding) string {
+	return instance.Name + "-alerts"
+}
+
+// reconcileAlertRule creates, updates or deletes the PrometheusRule of a
+// binding according to the alerting settings of its ScanSetting. Nothing
+// is done if PrometheusRules aren't available in the cluster.
+func (r *ReconcileScanSettingBinding) reconcileAlertRule(instance *compliancev1alpha1.ScanSettingBinding, logger logr.Logger) error {
+	var alerting *compliancev1alpha1.ScanSettingAlerting
+	if instance.SettingsRef != nil {
+		setting := &compliancev1alpha1.ScanSetting{}
+		key := types.NamespacedName{Namespace: instance.Namespace, Name: instance.SettingsRef.Name}
+		if err := r.client.Get(context.TODO(), key, setting); err != nil {
+			return err
+		}
+		alerting = setting.Alerting
+	}
+
+	found := &monitoring.PrometheusRule{}
+	key := types.NamespacedName{Namespace: instance.Namespace, Name: getAlertRuleName(instance)}
+	err := r.client.Get(context.TODO(), key, found)
+	if meta.IsNoMatchError(err) || runtime.IsNotRegisteredError(err) {
+		logger.Info("PrometheusRules aren't available, not creating the alerts of the binding")
+		return nil
+	} else if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	if alerting != nil && alerting.Disabled {
+		if !exists {
+			return nil
+		}
+		logger.Info("Alerts are disabled, deleting the PrometheusRule", "PrometheusRule.Name", found.Name)
+		if err := r.client.Delete(context.TODO(), found); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	spec := monitoring.PrometheusRuleSpec{
+		Groups: []monitoring.RuleGroup{
+			{
+				Name:  "compliance-" + instance.Name,
+				Rules: metrics.SuiteAlertRules(instance.Name, alerting),
+			},
+		},
+	}
+
+	if !exists {
+		rule := &monitoring.PrometheusRule{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.Name,
+				Namespace: key.Namespace,
+			},
+			Spec: spec,
+		}
+		if err := controllerutil.SetControllerReference(instance, rule, r.scheme); err != nil {
+			return err
+		}
+		logger.Info("Creating the PrometheusRule of the binding", "PrometheusRule.Name", rule.Name)
+		return r.client.Create(context.TODO(), rule)
+	}
+
+	if reflect.DeepEqual(found.Spec, spec) {
+		return nil
+	}
+	found.Spec = spec
+	logger.Info("Updating the PrometheusRule of the binding", "PrometheusRule.Name", found.Name)
+	return r.client.Update(context.TODO(), found)
+}
@@ -0,0 +1,149 @@
+package scansettingbinding
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	compliancev1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+type controlState struct {
+	covered bool
+	passed  bool
+	failed  bool
+}
+
+// getControlCoverage computes how the results of the suite cover the
+// controls of the frameworks that the rules of the scanned bundles map to.
+// scanBundles maps the name of each scan to the name of its bundle.
+func (r *ReconcileScanSettingBinding) getControlCoverage(instance *compliancev1alpha1.ScanSettingBinding,
+	suite *compliancev1alpha1.ComplianceSuite, scanBundles map[string]string) ([]compliancev1alpha1.ControlFrameworkCoverage, error) {
+	results := compliancev1alpha1.ComplianceCheckResultList{}
+	err := r.client.List(context.TODO(), &results, client.InNamespace(suite.Namespace),
+		client.MatchingLabels{compliancev1alpha1.SuiteLabel: suite.Name})
+	if err != nil {
+		return nil, err
+	}
+
+	// The results name the rules the way they were parsed, the catalogs
+	// with the name of the bundle as a prefix
+	ruleResults := map[string][]compliancev1alpha1.ComplianceCheckStatus{}
+	for i := range results.Items {
+		result := &results.Items[i]
+		bundle, ok := scanBundles[result.Labels[compliancev1alpha1.ComplianceScanLabel]]
+		if !ok {
+			continue
+		}
+		rule := fmt.Sprintf("%s-%s", bundle, result.Annotations[compliancev1alpha1.ComplianceCheckResultRuleAnnotation])
+		ruleResults[rule] = append(ruleResults[rule], result.Status)
+	}
+
+	catalogs, err := r.getControlCatalogs(instance, suite.Namespace, scanBundles)
+	if err != nil {
+		return nil, err
+	}
+
+	// framework -> control -> state
+	frameworks := map[string]map[string]*controlState{}
+	for i := range catalogs {
+		catalog := &catalogs[i]
+		if frameworks[catalog.Framework] == nil {
+			frameworks[catalog.Framework] = map[string]*controlState{}
+		}
+		controls := frameworks[catalog.Framework]
+		for _, control := range catalog.Controls {
+			state, ok := controls[control.ID]
+			if !ok {
+				state = &controlState{}
+				controls[control.ID] = state
+			}
+			for _, rule := range control.Rules {
+				for _, status := range ruleResults[rule] {
+					state.covered = true
+					switch status {
+					case compliancev1alpha1.CheckResultPass:
+						state.passed = true
+					case compliancev1alpha1.CheckResultFail, compliancev1alpha1.CheckResultInconsistent:
+						state.failed = true
+					}
+				}
+			}
+		}
+	}
+
+	coverage := make([]compliancev1alpha1.ControlFrameworkCoverage, 0, len(frameworks))
+	for framework, controls := range frameworks {
+		fwCoverage := compliancev1alpha1.ControlFrameworkCoverage{
+			Framework:     framework,
+			TotalControls: len(controls),
+		}
+		for id, state := range controls {
+			if !state.covered {
+				continue
+			}
+			fwCoverage.CoveredControls++
+			if state.failed {
+				fwCoverage.FailedControls++
+				fwCoverage.FailedControlIDs = append(fwCoverage.FailedControlIDs, id)
+			} else if state.passed {
+				fwCoverage.PassedControls++
+			}
+		}
+		sort.Strings(fwCoverage.FailedControlIDs)
+		coverage = append(coverage, fwCoverage)
+	}
+	sort.Slice(coverage, func(i, j int) bool {
+		return coverage[i].Framework < coverage[j].Framework
+	})
+	return coverage, nil
+}
+
+// getControlCatalogs returns the catalogs of the scanned bundles. For the
+// bundles the binding pins, these are the catalogs parsed from the pinned
+// content version.
+func (r *ReconcileScanSettingBinding) getControlCatalogs(instance *compliancev1alpha1.ScanSettingBinding,
+	namespace string, scanBundles map[string]string) ([]compliancev1alpha1.ControlCatalog, error) {
+	catalogList := compliancev1alpha1.ControlCatalogList{}
+	if err := r.client.List(context.TODO(), &catalogList, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	bundles := map[string]bool{}
+	for _, bundle := range scanBundles {
+		bundles[bundle] = true
+	}
+
+	var catalogs []compliancev1alpha1.ControlCatalog
+	for _, catalog := range catalogList.Items {
+		owner := catalog.Labels[compliancev1alpha1.ProfileBundleOwnerLabel]
+		archivedFrom := catalog.Labels[compliancev1alpha1.ArchivedContentLabel]
+		if owner != "" && bundles[owner] {
+			pinned := instance.GetPinnedContentDigest(owner)
+			if pinned == "" || catalog.Annotations[compliancev1alpha1.ProfileContentDigestAnnotation] == pinned {
+				catalogs = append(catalogs, catalog)
+			}
+		} else if archivedFrom != "" && bundles[archivedFrom] {
+			pinned := instance.GetPinnedContentDigest(archivedFrom)
+			if pinned != "" && catalog.Annotations[compliancev1alpha1.ProfileContentDigestAnnotation] == pinned {
+				catalogs = append(catalogs, catalog)
+			}
+		}
+	}
+	return catalogs, nil
+}
+
+// getControlCoverageVersion returns a digest of the runs of the scans of
+// the suite, which changes whenever one of them runs again
+func getControlCoverageVersion(suite *compliancev1alpha1.ComplianceSuite) string {
+	runs := make([]string, 0, len(suite.Status.ScanStatuses))
+	for _, scanStatus := range suite.Status.ScanStatuses {
+		runs = append(runs, fmt.Sprintf("%s/%d", scanStatus.Name, scanStatus.CurrentIndex))
+	}
+	sort.Strings(runs)
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(runs, "\n"))))
+}
@@ -9,10 +9,12 @@ import (
 	"time"
 
 	"github.com/openshift/compliance-operator/pkg/controller/metrics"
+	"github.com/openshift/compliance-operator/pkg/controller/shared"
 
 	"github.com/go-logr/logr"
 	"github.com/openshift/compliance-operator/pkg/controller/common"
 	"github.com/openshift/compliance-operator/pkg/utils"
+	"github.com/openshift/library-go/pkg/image/reference"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -44,8 +46,8 @@ const (
 
 var log = logf.Log.WithName("scansettingbindingctrl")
 
-func Add(mgr manager.Manager, met *metrics.Metrics, _ utils.CtlplaneSchedulingInfo) error {
-	return add(mgr, newReconciler(mgr, met))
+func Add(mgr manager.Manager, deps shared.Dependencies) error {
+	return add(mgr, newReconciler(mgr, deps.Metrics))
 }
 
 // newReconciler returns a new reconcile.Reconciler
@@ -154,6 +156,19 @@ func (r *ReconcileScanSettingBinding) Reconcile(request reconcile.Request) (reco
 		return reconcile.Result{}, nil
 	}
 
+	if msg, err := r.validateContentVersions(instance); err != nil {
+		return reconcile.Result{}, err
+	} else if msg != "" {
+		r.Eventf(instance, corev1.EventTypeWarning, "ContentVersionUnavailable", msg)
+		ssb := instance.DeepCopy()
+		ssb.Status.SetConditionInvalid(msg)
+		if updateErr := r.client.Status().Update(context.TODO(), ssb); updateErr != nil {
+			return reconcile.Result{}, fmt.Errorf("couldn't update ScanSettingBinding condition: %w", updateErr)
+		}
+		// Don't requeue, the binding needs to pin another version
+		return reconcile.Result{}, nil
+	}
+
 	suite := compliancev1alpha1.ComplianceSuite{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      instance.Name,
@@ -168,47 +183,64 @@ func (r *ReconcileScanSettingBinding) Reconcile(request reconcile.Request) (reco
 	}
 
 	var nodeProduct string
+	pinnedTPs := map[string]bool{}
+	scanBundles := map[string]string{}
 	for i := range instance.Profiles {
 		ss := &instance.Profiles[i]
 
 		key := types.NamespacedName{Namespace: instance.Namespace, Name: ss.Name}
-		profileObj, geterr := getUnstructured(r, instance, key, ss.Kind, ss.APIGroup, reqLogger)
+		profileObj, geterr := r.getPinnedProfile(instance, key, ss.Kind)
 		if geterr != nil {
 			return reconcile.Result{}, geterr
 		}
+		if profileObj == nil {
+			profileObj, geterr = getUnstructured(r, instance, key, ss.Kind, ss.APIGroup, reqLogger)
+			if geterr != nil {
+				return reconcile.Result{}, geterr
+			}
+		}
+
+		// Archived content and pinned copies are managed by the operator
+		// and can't be bound directly
+		if compliancev1alpha1.IsArchivedContent(profileObj) {
+			msg := fmt.Sprintf("%s %s is archived content and can't be bound", ss.Kind, ss.Name)
+			r.Eventf(instance, corev1.EventTypeWarning, "ArchivedContent", msg)
+
+			ssb := instance.DeepCopy()
+			ssb.Status.SetConditionInvalid(msg)
+			if updateErr := r.client.Status().Update(context.TODO(), ssb); updateErr != nil {
+				return reconcile.Result{}, fmt.Errorf("couldn't update ScanSettingBinding condition: %w", updateErr)
+			}
+			// Don't requeue in this case, nothing we can do
+			return reconcile.Result{}, nil
+		}
 
 		if profileObj.GetKind() == "TailoredProfile" {
-			val, found, nsErr := unstructured.NestedString(
-				profileObj.Object, "status", "state")
-			if nsErr != nil {
-				reqLogger.Error(nsErr, "Fetching state of tailored profile",
-					"TailoredProfile", profileObj.GetName())
+			if result, done, err := r.checkTailoredProfileState(instance, profileObj, reqLogger); done {
+				return result, err
 			}
-			if !found {
-				reqLogger.Info("Requeuing as TailoredProfile hasn't been processed",
-					"TailoredProfile", profileObj.GetName())
-				return reconcile.Result{Requeue: true, RequeueAfter: requeueAfterDefault}, nil
+
+			pinnedTP, err := r.getPinnedTailoredProfile(instance, profileObj, reqLogger)
+			if err != nil {
+				return common.ReturnWithRetriableError(reqLogger, err)
 			}
-			if val == string(compliancev1alpha1.TailoredProfileStateError) {
-				msg := "The TailoredProfile referenced has an error and is not usable"
-				ssb := instance.DeepCopy()
-				ssb.Status.SetConditionInvalid(msg)
-				if updateErr := r.client.Status().Update(context.TODO(), ssb); updateErr != nil {
-					return reconcile.Result{}, fmt.Errorf("couldn't update ScanSettingBinding condition: %w", updateErr)
+			if pinnedTP != nil {
+				if result, done, err := r.checkTailoredProfileState(instance, pinnedTP, reqLogger); done {
+					return result, err
 				}
-				return reconcile.Result{}, nil
-			}
-			if val != string(compliancev1alpha1.TailoredProfileStateReady) {
-				reqLogger.Info("Requeuing as TailoredProfile isn't yet ready",
-					"TailoredProfile", profileObj.GetName())
-				return reconcile.Result{Requeue: true, RequeueAfter: requeueAfterDefault}, nil
+				profileObj = pinnedTP
+				pinnedTPs[pinnedTP.GetName()] = true
 			}
 		}
 
-		scan, product, err := newCompScanFromBindingProfile(r, instance, profileObj, log)
+		scan, product, bundle, err := newCompScanFromBindingProfile(r, instance, profileObj, log)
 		if err != nil {
 			return common.ReturnWithRetriableError(reqLogger, err)
 		}
+		// The TailoredProfile might be the copy pinned to a content
+		// version, the scan is still named after the reference
+		scan.Name = ss.Name
+		scanBundles[scan.Name] = bundle
 
 		nodeProduct = getRelevantProduct(nodeProduct, product)
 
@@ -228,13 +260,21 @@ func (r *ReconcileScanSettingBinding) Reconcile(request reconcile.Request) (reco
 		suite.Spec.Scans = append(suite.Spec.Scans, *scan)
 	}
 
+	if err := r.deleteUnusedPinnedTailoredProfiles(instance, pinnedTPs, reqLogger); err != nil {
+		return reconcile.Result{}, err
+	}
+
 	if instance.SettingsRef != nil {
-		err := r.applyConstraint(instance, &suite, instance.SettingsRef, log)
+		err := r.applyConstraint(instance, &suite, instance.SettingsRef, scanBundles, log)
 		if err != nil {
 			return common.ReturnWithRetriableError(reqLogger, err)
 		}
 	}
 
+	if err := r.reconcileAlertRule(instance, reqLogger); err != nil {
+		return reconcile.Result{}, err
+	}
+
 	found := compliancev1alpha1.ComplianceSuite{}
 	err = r.client.Get(context.TODO(), types.NamespacedName{Namespace: suite.Namespace, Name: suite.Name}, &found)
 	if errors.IsNotFound(err) {
@@ -289,8 +329,9 @@ func (r *ReconcileScanSettingBinding) Reconcile(request reconcile.Request) (reco
 		return reconcile.Result{}, err
 	}
 
+	ssb := instance.DeepCopy()
+	statusNeedsUpdate := false
 	if scanSettingBindingStatusNeedsUpdate(instance) {
-		ssb := instance.DeepCopy()
 		ssb.Status.SetConditionReady()
 		group := found.GroupVersionKind().Group
 		ssb.Status.OutputRef = &corev1.TypedLocalObjectReference{
@@ -298,13 +339,30 @@ func (r *ReconcileScanSettingBinding) Reconcile(request reconcile.Request) (reco
 			Kind:     found.GroupVersionKind().Kind,
 			Name:     found.GetName(),
 		}
-		if updateErr := r.client.Status().Update(context.TODO(), ssb); updateErr != nil {
-			return reconcile.Result{}, fmt.Errorf("couldn't update ScanSettingBinding condition: %w", updateErr)
-		}
+		statusNeedsUpdate = true
 	} else {
 		reqLogger.Info("Suite does not need update", "suite.Name", suite.Name)
 	}
 
+	// The coverage only changes along with the results, so it's computed
+	// once per completed run of the suite
+	coverageVersion := getControlCoverageVersion(&found)
+	if found.Status.Phase == compliancev1alpha1.PhaseDone && coverageVersion != instance.Status.ControlCoverageVersion {
+		coverage, err := r.getControlCoverage(instance, &found, scanBundles)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		ssb.Status.ControlCoverage = coverage
+		ssb.Status.ControlCoverageVersion = coverageVersion
+		statusNeedsUpdate = true
+	}
+
+	if statusNeedsUpdate {
+		if updateErr := r.client.Status().Update(context.TODO(), ssb); updateErr != nil {
+			return reconcile.Result{}, fmt.Errorf("couldn't update ScanSettingBinding status: %w", updateErr)
+		}
+	}
+
 	if found.Status.Phase == compliancev1alpha1.PhaseDone {
 		reqLogger.Info("Generating events for scansettingbinding")
 		common.GenerateEventForResult(r.recorder, instance, instance, found.Status.Result)
@@ -325,10 +383,13 @@ func isDifferentProduct(nodeProduct, incomingProduct string) bool {
 	return incomingProduct != "" && incomingProduct != nodeProduct
 }
 
+// applyConstraint applies the ScanSetting to the suite. As node scans are
+// split per role, scanBundles is updated with the names of the new scans.
 func (r *ReconcileScanSettingBinding) applyConstraint(
 	instance *compliancev1alpha1.ScanSettingBinding,
 	suite *compliancev1alpha1.ComplianceSuite,
 	constraintRef *compliancev1alpha1.NamedObjectReference,
+	scanBundles map[string]string,
 	logger logr.Logger,
 ) error {
 	key := types.NamespacedName{Namespace: instance.Namespace, Name: constraintRef.Name}
@@ -354,7 +415,7 @@ func (r *ReconcileScanSettingBinding) applyConstraint(
 	}
 
 	// create per-role scans
-	suite.Spec.Scans = r.createScansWithSelector(suite, &v1setting, logger)
+	suite.Spec.Scans = r.createScansWithSelector(suite, &v1setting, scanBundles, logger)
 	// apply settings for suite - deep copy to future proof in case there are any slices or so later
 	suite.Spec.ComplianceSuiteSettings = *v1setting.ComplianceSuiteSettings.DeepCopy()
 	// apply settings for scans, need to DeepCopy as ScanSetting contains a slice
@@ -390,6 +451,7 @@ func (r *ReconcileScanSettingBinding) validateRoles(setting *compliancev1alpha1.
 func (r *ReconcileScanSettingBinding) createScansWithSelector(
 	suite *compliancev1alpha1.ComplianceSuite,
 	v1setting *compliancev1alpha1.ScanSetting,
+	scanBundles map[string]string,
 	logger logr.Logger,
 ) []compliancev1alpha1.ComplianceScanSpecWrapper {
 	scansWithSelector := make([]compliancev1alpha1.ComplianceScanSpecWrapper, 0)
@@ -400,6 +462,7 @@ func (r *ReconcileScanSettingBinding) createScansWithSelector(
 				scanCopy := scan.DeepCopy()
 				scanCopy.Name = scan.Name + "-" + r.sanitizeRoleForName(role)
 				scanCopy.NodeSelector = utils.GetNodeRoleSelector(role)
+				scanBundles[scanCopy.Name] = scanBundles[scan.Name]
 				logger.Info("Adding per-role scan", "scanCopy.Name", scanCopy.Name)
 				scansWithSelector = append(scansWithSelector, *scanCopy)
 			}
@@ -428,11 +491,14 @@ func (r *ReconcileScanSettingBinding) sanitizeRoleForName(roleName string) strin
 
 }
 
-func newCompScanFromBindingProfile(r *ReconcileScanSettingBinding, instance *compliancev1alpha1.ScanSettingBinding, profile *unstructured.Unstructured, logger logr.Logger) (*compliancev1alpha1.ComplianceScanSpecWrapper, string, error) {
+// newCompScanFromBindingProfile returns the scan of a profile of the binding
+// along with the product it targets and the name of its ProfileBundle
+func newCompScanFromBindingProfile(r *ReconcileScanSettingBinding, instance *compliancev1alpha1.ScanSettingBinding, profile *unstructured.Unstructured, logger logr.Logger) (*compliancev1alpha1.ComplianceScanSpecWrapper, string, string, error) {
 	parsedProfReference, err := resolveProfileReference(r, instance, profile, logger)
 	if err != nil {
-		return nil, "", err
+		return nil, "", "", err
 	}
+	parsedProfReference.pinnedDigest = instance.GetPinnedContentDigest(parsedProfReference.profileBundle.GetName())
 
 	scan, platform, err := profileReferenceToScan(parsedProfReference)
 	if err != nil {
@@ -440,10 +506,10 @@ func newCompScanFromBindingProfile(r *ReconcileScanSettingBinding, instance *com
 			instance, corev1.EventTypeWarning, "ScanCreateError",
 			"Cannot create scan: %v", err,
 		)
-		return nil, "", err
+		return nil, "", "", err
 	}
 
-	return scan, platform, nil
+	return scan, platform, parsedProfReference.profileBundle.GetName(), nil
 }
 
 type profileReference struct {
@@ -452,6 +518,9 @@ type profileReference struct {
 	tailoredProfile *unstructured.Unstructured
 	profile         *unstructured.Unstructured
 	profileBundle   *unstructured.Unstructured
+
+	// The digest of the content version the binding pins the bundle to
+	pinnedDigest string
 }
 
 func profileReferenceToScan(reference *profileReference) (*compliancev1alpha1.ComplianceScanSpecWrapper, string, error) {
@@ -462,7 +531,7 @@ func profileReferenceToScan(reference *profileReference) (*compliancev1alpha1.Co
 		Name:               reference.name,
 	}
 
-	err = fillContentData(reference.profileBundle, &scan)
+	err = fillContentData(reference.profileBundle, reference.pinnedDigest, &scan)
 	if err != nil {
 		return nil, "", err
 	}
@@ -501,7 +570,7 @@ func profileReferenceToScan(reference *profileReference) (*compliancev1alpha1.Co
 	return &scan, product, nil
 }
 
-func fillContentData(bundle *unstructured.Unstructured, scan *compliancev1alpha1.ComplianceScanSpecWrapper) error {
+func fillContentData(bundle *unstructured.Unstructured, pinnedDigest string, scan *compliancev1alpha1.ComplianceScanSpecWrapper) error {
 	if err := isCmpv1Alpha1Gvk(bundle, "ProfileBundle"); err != nil {
 		return common.WrapNonRetriableCtrlError(err)
 	}
@@ -521,6 +590,41 @@ func fillContentData(bundle *unstructured.Unstructured, scan *compliancev1alpha1
 
 	scan.Content = v1alphaBundle.Spec.ContentFile
 	scan.ContentImage = v1alphaBundle.Spec.ContentImage
+	scan.ContentSource = v1alphaBundle.Spec.ContentSource.DeepCopy()
+
+	if pinnedDigest == "" {
+		return setVerifiedContentDigest(&v1alphaBundle, v1alphaBundle.Status.ContentVersion, scan)
+	}
+	if v1alphaBundle.Spec.ContentSource != nil {
+		return common.NewNonRetriableCtrlError("the content of ProfileBundle '%s' can't be pinned as it's not loaded from an image",
+			v1alphaBundle.GetName())
+	}
+	version := v1alphaBundle.GetContentVersion(pinnedDigest)
+	if version == nil {
+		return common.NewNonRetriableCtrlError("content version %s of ProfileBundle '%s' is not available",
+			pinnedDigest, v1alphaBundle.GetName())
+	}
+	image, err := reference.Parse(version.ContentImage)
+	if err != nil {
+		return common.WrapNonRetriableCtrlError(err)
+	}
+	image.Tag = ""
+	image.ID = pinnedDigest
+	scan.ContentImage = image.Exact()
+	return setVerifiedContentDigest(&v1alphaBundle, version, scan)
+}
+
+// setVerifiedContentDigest makes the scan refuse to run against any content
+// other than the verified version, if the bundle verifies its content
+func setVerifiedContentDigest(pb *compliancev1alpha1.ProfileBundle, version *compliancev1alpha1.ProfileBundleContentVersion,
+	scan *compliancev1alpha1.ComplianceScanSpecWrapper) error {
+	if pb.Spec.Verification == nil {
+		return nil
+	}
+	if version == nil || !version.Verified || version.DataStreamDigest == "" {
+		return common.NewNonRetriableCtrlError("the content of ProfileBundle '%s' wasn't verified", pb.GetName())
+	}
+	scan.ContentDigest = version.DataStreamDigest
 	return nil
 }
 
@@ -609,6 +713,17 @@ func resolveProfileReference(r *ReconcileScanSettingBinding, instance *complianc
 			if err != nil {
 				return nil, err
 			}
+
+			// Copies pinned to a content version are owned by the bundle,
+			// as the Profile they extend might only be archived
+			extends, _, _ := unstructured.NestedString(profile.Object, "spec", "extends")
+			if extends != "" && profile.GetAnnotations()[compliancev1alpha1.PinnedContentDigestAnnotation] != "" {
+				key := types.NamespacedName{Namespace: instance.Namespace, Name: extends}
+				profReference.profile, err = r.getPinnedProfile(instance, key, "Profile")
+				if err != nil {
+					return nil, err
+				}
+			}
 		} else {
 			return nil, common.NewNonRetriableCtrlError("TailoredProfile must be owned by a Profile or ProfileBundle")
 		}
@@ -623,6 +738,199 @@ func resolveProfileReference(r *ReconcileScanSettingBinding, instance *complianc
 	return &profReference, nil
 }
 
+// validateContentVersions makes sure that the content versions the binding
+// pins are still available. If one isn't, a message explaining why is
+// returned. Bundles that are still being parsed are not checked.
+func (r *ReconcileScanSettingBinding) validateContentVersions(instance *compliancev1alpha1.ScanSettingBinding) (string, error) {
+	for _, pin := range instance.ContentVersions {
+		pb := compliancev1alpha1.ProfileBundle{}
+		key := types.NamespacedName{Namespace: instance.Namespace, Name: pin.ProfileBundle}
+		if err := r.client.Get(context.TODO(), key, &pb); errors.IsNotFound(err) {
+			return fmt.Sprintf("The pinned ProfileBundle %s doesn't exist", pin.ProfileBundle), nil
+		} else if err != nil {
+			return "", err
+		}
+		if pb.Status.DataStreamStatus != compliancev1alpha1.DataStreamValid {
+			continue
+		}
+		if pb.GetContentVersion(pin.ImageDigest) == nil {
+			return fmt.Sprintf("The content version %s of ProfileBundle %s is not available", pin.ImageDigest, pin.ProfileBundle), nil
+		}
+	}
+	return "", nil
+}
+
+// getPinnedProfile returns the referenced Profile as parsed from a content
+// version the binding pins, or nil if there's none and the current Profile
+// should be used
+func (r *ReconcileScanSettingBinding) getPinnedProfile(instance *compliancev1alpha1.ScanSettingBinding, key types.NamespacedName, kind string) (*unstructured.Unstructured, error) {
+	if kind != "Profile" {
+		return nil, nil
+	}
+
+	for _, pin := range instance.ContentVersions {
+		o, err := common.GetPinnedContent(r.client, kind, key, pin.ImageDigest)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if o.GetLabels()[compliancev1alpha1.ProfileBundleOwnerLabel] == pin.ProfileBundle {
+			return o, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// checkTailoredProfileState makes sure that the TailoredProfile is ready to
+// be used. If it isn't, the reconcile loop needs to return the given result
+// and error.
+func (r *ReconcileScanSettingBinding) checkTailoredProfileState(instance *compliancev1alpha1.ScanSettingBinding, tp *unstructured.Unstructured,
+	logger logr.Logger) (reconcile.Result, bool, error) {
+	val, found, nsErr := unstructured.NestedString(tp.Object, "status", "state")
+	if nsErr != nil {
+		logger.Error(nsErr, "Fetching state of tailored profile",
+			"TailoredProfile", tp.GetName())
+	}
+	if !found {
+		logger.Info("Requeuing as TailoredProfile hasn't been processed",
+			"TailoredProfile", tp.GetName())
+		return reconcile.Result{Requeue: true, RequeueAfter: requeueAfterDefault}, true, nil
+	}
+	if val == string(compliancev1alpha1.TailoredProfileStateError) {
+		msg := "The TailoredProfile referenced has an error and is not usable"
+		ssb := instance.DeepCopy()
+		ssb.Status.SetConditionInvalid(msg)
+		if updateErr := r.client.Status().Update(context.TODO(), ssb); updateErr != nil {
+			return reconcile.Result{}, true, fmt.Errorf("couldn't update ScanSettingBinding condition: %w", updateErr)
+		}
+		return reconcile.Result{}, true, nil
+	}
+	if val != string(compliancev1alpha1.TailoredProfileStateReady) {
+		logger.Info("Requeuing as TailoredProfile isn't yet ready",
+			"TailoredProfile", tp.GetName())
+		return reconcile.Result{Requeue: true, RequeueAfter: requeueAfterDefault}, true, nil
+	}
+	return reconcile.Result{}, false, nil
+}
+
+// getPinnedTailoredProfile returns a copy of the TailoredProfile that's
+// built from the content version the binding pins its ProfileBundle to, or
+// nil if the bundle isn't pinned. The copy is owned by the binding and
+// named after the digest of the content image, the TailoredProfile
+// controller resolves its profiles, rules and variables as parsed from it.
+func (r *ReconcileScanSettingBinding) getPinnedTailoredProfile(instance *compliancev1alpha1.ScanSettingBinding, tp *unstructured.Unstructured,
+	logger logr.Logger) (*unstructured.Unstructured, error) {
+	reference, err := resolveProfileReference(r, instance, tp, logger)
+	if err != nil {
+		return nil, err
+	}
+	digest := instance.GetPinnedContentDigest(reference.profileBundle.GetName())
+	if digest == "" {
+		return nil, nil
+	}
+
+	v1alphaTp := compliancev1alpha1.TailoredProfile{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(tp.Object, &v1alphaTp); err != nil {
+		return nil, common.WrapNonRetriableCtrlError(err)
+	}
+
+	pinned := &compliancev1alpha1.TailoredProfile{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        compliancev1alpha1.GetArchivedContentName(tp.GetName(), digest),
+			Namespace:   tp.GetNamespace(),
+			Labels:      v1alphaTp.GetLabels(),
+			Annotations: v1alphaTp.GetAnnotations(),
+		},
+		Spec: v1alphaTp.Spec,
+	}
+	if pinned.Annotations == nil {
+		pinned.Annotations = make(map[string]string)
+	}
+	pinned.Annotations[compliancev1alpha1.PinnedContentDigestAnnotation] = digest
+	if pinned.Labels == nil {
+		pinned.Labels = make(map[string]string)
+	}
+	pinned.Labels[compliancev1alpha1.ArchivedContentLabel] = reference.profileBundle.GetName()
+	// The TailoredProfile controller makes the extended Profile or the
+	// ProfileBundle the controller of the copy
+	if err := controllerutil.SetOwnerReference(instance, pinned, r.scheme); err != nil {
+		return nil, err
+	}
+
+	found := &compliancev1alpha1.TailoredProfile{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Name: pinned.Name, Namespace: pinned.Namespace}, found)
+	if errors.IsNotFound(err) {
+		logger.Info("Creating a copy of the TailoredProfile pinned to a content version",
+			"TailoredProfile", tp.GetName(), "digest", digest)
+		if err := r.client.Create(context.TODO(), pinned); err != nil {
+			return nil, err
+		}
+		return nil, common.NewRetriableCtrlErrorWithCustomHandler(func() (reconcile.Result, error) {
+			return reconcile.Result{RequeueAfter: requeueAfterDefault, Requeue: true}, nil
+		}, "TailoredProfile %s was pinned to content version %s", tp.GetName(), digest)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !reflect.DeepEqual(found.Spec, pinned.Spec) || !compliancev1alpha1.IsArchivedContent(found) {
+		foundCopy := found.DeepCopy()
+		foundCopy.Spec = pinned.Spec
+		if foundCopy.Labels == nil {
+			foundCopy.Labels = make(map[string]string)
+		}
+		foundCopy.Labels[compliancev1alpha1.ArchivedContentLabel] = pinned.Labels[compliancev1alpha1.ArchivedContentLabel]
+		logger.Info("Updating the copy of the TailoredProfile pinned to a content version",
+			"TailoredProfile", tp.GetName(), "digest", digest)
+		if err := r.client.Update(context.TODO(), foundCopy); err != nil {
+			return nil, err
+		}
+		return nil, common.NewRetriableCtrlErrorWithCustomHandler(func() (reconcile.Result, error) {
+			return reconcile.Result{RequeueAfter: requeueAfterDefault, Requeue: true}, nil
+		}, "TailoredProfile %s was updated", pinned.Name)
+	}
+
+	obj := unstructured.Unstructured{}
+	obj.Object, err = runtime.DefaultUnstructuredConverter.ToUnstructured(found)
+	if err != nil {
+		return nil, common.WrapNonRetriableCtrlError(err)
+	}
+	obj.SetGroupVersionKind(newCmpv1Alpha1Gvk("TailoredProfile"))
+	return &obj, nil
+}
+
+// deleteUnusedPinnedTailoredProfiles deletes the copies of TailoredProfiles
+// the binding no longer pins
+func (r *ReconcileScanSettingBinding) deleteUnusedPinnedTailoredProfiles(instance *compliancev1alpha1.ScanSettingBinding, used map[string]bool,
+	logger logr.Logger) error {
+	tpList := compliancev1alpha1.TailoredProfileList{}
+	if err := r.client.List(context.TODO(), &tpList, client.InNamespace(instance.Namespace)); err != nil {
+		return err
+	}
+
+	for i := range tpList.Items {
+		tp := &tpList.Items[i]
+		if used[tp.Name] || tp.GetPinnedContentDigest() == "" || !isOwnedBy(tp, instance) {
+			continue
+		}
+		logger.Info("Deleting the copy of a TailoredProfile that is no longer pinned", "TailoredProfile", tp.Name)
+		if err := r.client.Delete(context.TODO(), tp); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func isOwnedBy(obj, owner metav1.Object) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == owner.GetUID() {
+			return true
+		}
+	}
+	return false
+}
+
 func resolveProfile(r *ReconcileScanSettingBinding, instance *compliancev1alpha1.ScanSettingBinding, profReference *profileReference, logger logr.Logger) (*unstructured.Unstructured, error) {
 	return resolveTypedParent(r, instance, "Profile", profReference.tailoredProfile, logger)
 }
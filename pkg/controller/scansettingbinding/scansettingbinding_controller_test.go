@@ -5,12 +5,14 @@ import (
 	"regexp"
 	"strings"
 
+	monitoring "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/go-logr/zapr"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 	conditions "github.com/operator-framework/operator-sdk/pkg/status"
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -158,7 +160,7 @@ var _ = Describe("Testing scansettingbinding controller", func() {
 		objs = append(objs, ssb, pBundleRhcos, profRhcosE8, tpRhcosE8, scratchTP, suite, setting)
 
 		scheme := scheme.Scheme
-		scheme.AddKnownTypes(compv1alpha1.SchemeGroupVersion, objs...)
+		scheme.AddKnownTypes(compv1alpha1.SchemeGroupVersion, append(objs, &compv1alpha1.TailoredProfileList{}, &compv1alpha1.ProfileList{})...)
 
 		client := fake.NewFakeClientWithScheme(scheme, pBundleRhcos, setting)
 
@@ -527,6 +529,598 @@ var _ = Describe("Testing scansettingbinding controller", func() {
 		})
 	})
 
+	Context("Pins the content version of a ProfileBundle", func() {
+		const (
+			currentDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+			pinnedDigest  = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+		)
+
+		var archivedProfile *compv1alpha1.Profile
+
+		JustBeforeEach(func() {
+			pbCopy := pBundleRhcos.DeepCopy()
+			pbCopy.Status.ContentVersion = &compv1alpha1.ProfileBundleContentVersion{
+				DataStreamVersion: "0.1.55",
+				ContentImage:      pBundleRhcos.Spec.ContentImage,
+				ImageDigest:       currentDigest,
+			}
+			pbCopy.Status.PreviousContentVersions = []compv1alpha1.ProfileBundleContentVersion{
+				{
+					DataStreamVersion: "0.1.54",
+					ContentImage:      "quay.io/compliance-operator/compliance-operator-content:v0.1.54",
+					ImageDigest:       pinnedDigest,
+				},
+			}
+			err := reconciler.client.Status().Update(context.TODO(), pbCopy)
+			Expect(err).To(BeNil())
+
+			profCopy := profRhcosE8.DeepCopy()
+			profCopy.Labels = map[string]string{compv1alpha1.ProfileBundleOwnerLabel: pBundleRhcos.Name}
+			profCopy.Annotations = map[string]string{
+				compv1alpha1.ProfileContentDigestAnnotation:  currentDigest,
+				compv1alpha1.ProfileContentDigestsAnnotation: currentDigest,
+			}
+			for key, value := range profRhcosE8.Annotations {
+				profCopy.Annotations[key] = value
+			}
+			err = reconciler.client.Update(context.TODO(), profCopy)
+			Expect(err).To(BeNil())
+
+			archivedProfile = profCopy.DeepCopy()
+			archivedProfile.ResourceVersion = ""
+			archivedProfile.Name = compv1alpha1.GetArchivedContentName(profRhcosE8.Name, pinnedDigest)
+			archivedProfile.Labels = map[string]string{compv1alpha1.ArchivedContentLabel: pBundleRhcos.Name}
+			archivedProfile.Annotations[compv1alpha1.ProfileContentDigestAnnotation] = pinnedDigest
+			archivedProfile.Annotations[compv1alpha1.ProfileContentDigestsAnnotation] = pinnedDigest
+			archivedProfile.Annotations[compv1alpha1.ArchivedFromAnnotation] = profRhcosE8.Name
+			archivedProfile.ID = "xccdf_org.ssgproject.content_profile_e8_old"
+			err = reconciler.client.Create(context.TODO(), archivedProfile)
+			Expect(err).To(BeNil())
+
+			ssb = &compv1alpha1.ScanSettingBinding{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      "pinned-compliance-requirements",
+					Namespace: common.GetComplianceOperatorNamespace(),
+				},
+				Profiles: []compv1alpha1.NamedObjectReference{
+					{
+						Name:     profRhcosE8.Name,
+						Kind:     profRhcosE8.Kind,
+						APIGroup: profRhcosE8.APIVersion,
+					},
+				},
+				SettingsRef: &compv1alpha1.NamedObjectReference{
+					Name:     setting.Name,
+					Kind:     setting.Kind,
+					APIGroup: setting.APIVersion,
+				},
+				ContentVersions: []compv1alpha1.ContentVersionPin{
+					{
+						ProfileBundle: pBundleRhcos.Name,
+						ImageDigest:   pinnedDigest,
+					},
+				},
+			}
+			ssb.Status.SetConditionPending()
+
+			err = reconciler.client.Create(context.TODO(), ssb)
+			Expect(err).To(BeNil())
+		})
+
+		It("Should scan with the pinned content version", func() {
+			_, err := reconciler.Reconcile(reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: ssb.Namespace,
+					Name:      ssb.Name,
+				},
+			})
+			Expect(err).To(BeNil())
+
+			err = reconciler.client.Get(context.TODO(), types.NamespacedName{Name: ssb.Name, Namespace: ssb.Namespace}, suite)
+			Expect(err).To(BeNil())
+			Expect(suite.Spec.Scans).To(HaveLen(2))
+			for _, scan := range suite.Spec.Scans {
+				Expect(scan.ContentImage).To(Equal("quay.io/compliance-operator/compliance-operator-content@" + pinnedDigest))
+				Expect(scan.Profile).To(Equal(archivedProfile.ID))
+				Expect(scan.Name).To(HavePrefix(profRhcosE8.Name + "-"))
+			}
+		})
+
+		It("Should scan with a copy archived after a later content version that didn't change it", func() {
+			const laterDigest = "sha256:4444444444444444444444444444444444444444444444444444444444444444"
+			err := reconciler.client.Delete(context.TODO(), archivedProfile)
+			Expect(err).To(BeNil())
+			archivedProfile.ResourceVersion = ""
+			archivedProfile.Name = compv1alpha1.GetArchivedContentName(profRhcosE8.Name, laterDigest)
+			archivedProfile.Annotations[compv1alpha1.ProfileContentDigestAnnotation] = laterDigest
+			archivedProfile.Annotations[compv1alpha1.ProfileContentDigestsAnnotation] = pinnedDigest + "," + laterDigest
+			err = reconciler.client.Create(context.TODO(), archivedProfile)
+			Expect(err).To(BeNil())
+
+			_, err = reconciler.Reconcile(reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: ssb.Namespace,
+					Name:      ssb.Name,
+				},
+			})
+			Expect(err).To(BeNil())
+
+			err = reconciler.client.Get(context.TODO(), types.NamespacedName{Name: ssb.Name, Namespace: ssb.Namespace}, suite)
+			Expect(err).To(BeNil())
+			for _, scan := range suite.Spec.Scans {
+				Expect(scan.Profile).To(Equal(archivedProfile.ID))
+			}
+		})
+
+		It("Should scan with a copy of the TailoredProfile pinned to the content version", func() {
+			ssb.Profiles = []compv1alpha1.NamedObjectReference{
+				{
+					Name:     tpRhcosE8.Name,
+					Kind:     "TailoredProfile",
+					APIGroup: compv1alpha1.SchemeGroupVersion.String(),
+				},
+			}
+			err := reconciler.client.Update(context.TODO(), ssb)
+			Expect(err).To(BeNil())
+
+			key := types.NamespacedName{Namespace: ssb.Namespace, Name: ssb.Name}
+			_, err = reconciler.Reconcile(reconcile.Request{NamespacedName: key})
+			Expect(err).To(BeNil())
+
+			By("creating the copy of the TailoredProfile")
+			pinnedTP := &compv1alpha1.TailoredProfile{}
+			pinnedKey := types.NamespacedName{
+				Namespace: tpRhcosE8.Namespace,
+				Name:      compv1alpha1.GetArchivedContentName(tpRhcosE8.Name, pinnedDigest),
+			}
+			err = reconciler.client.Get(context.TODO(), pinnedKey, pinnedTP)
+			Expect(err).To(BeNil())
+			Expect(pinnedTP.GetPinnedContentDigest()).To(Equal(pinnedDigest))
+			Expect(pinnedTP.Labels).To(HaveKeyWithValue(compv1alpha1.ArchivedContentLabel, pBundleRhcos.Name))
+			Expect(pinnedTP.Spec).To(Equal(tpRhcosE8.Spec))
+			Expect(pinnedTP.OwnerReferences).To(HaveLen(1))
+			Expect(pinnedTP.OwnerReferences[0].Kind).To(Equal("ScanSettingBinding"))
+
+			err = reconciler.client.Get(context.TODO(), key, suite)
+			Expect(err).ToNot(BeNil())
+
+			By("scanning with the copy once the TailoredProfile controller built it")
+			pinnedTP.OwnerReferences = append(pinnedTP.OwnerReferences, v1.OwnerReference{
+				Name:       pBundleRhcos.Name,
+				Kind:       "ProfileBundle",
+				APIVersion: compv1alpha1.SchemeGroupVersion.String(),
+				Controller: &[]bool{true}[0],
+			})
+			pinnedTP.Status = compv1alpha1.TailoredProfileStatus{
+				ID: "xccdf_compliance.openshift.io_profile_" + pinnedKey.Name,
+				OutputRef: compv1alpha1.OutputRef{
+					Name:      pinnedKey.Name + "-tp",
+					Namespace: pinnedKey.Namespace,
+				},
+				State: compv1alpha1.TailoredProfileStateReady,
+			}
+			err = reconciler.client.Update(context.TODO(), pinnedTP)
+			Expect(err).To(BeNil())
+
+			_, err = reconciler.Reconcile(reconcile.Request{NamespacedName: key})
+			Expect(err).To(BeNil())
+
+			err = reconciler.client.Get(context.TODO(), key, suite)
+			Expect(err).To(BeNil())
+			Expect(suite.Spec.Scans).To(HaveLen(2))
+			for _, scan := range suite.Spec.Scans {
+				Expect(scan.ContentImage).To(Equal("quay.io/compliance-operator/compliance-operator-content@" + pinnedDigest))
+				Expect(scan.Profile).To(Equal(pinnedTP.Status.ID))
+				Expect(scan.TailoringConfigMap.Name).To(Equal(pinnedTP.Status.OutputRef.Name))
+				Expect(scan.Name).To(HavePrefix(tpRhcosE8.Name + "-"))
+				Expect(scan.ScanType).To(Equal(compv1alpha1.ScanTypeNode))
+			}
+
+			By("deleting the copy once the binding no longer pins the content")
+			err = reconciler.client.Get(context.TODO(), key, ssb)
+			Expect(err).To(BeNil())
+			ssb.ContentVersions = nil
+			err = reconciler.client.Update(context.TODO(), ssb)
+			Expect(err).To(BeNil())
+
+			_, err = reconciler.Reconcile(reconcile.Request{NamespacedName: key})
+			Expect(err).To(BeNil())
+
+			err = reconciler.client.Get(context.TODO(), pinnedKey, pinnedTP)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("Should not bind an archived Profile", func() {
+			ssb.Profiles[0].Name = archivedProfile.Name
+			ssb.ContentVersions = nil
+			err := reconciler.client.Update(context.TODO(), ssb)
+			Expect(err).To(BeNil())
+
+			key := types.NamespacedName{Namespace: ssb.Namespace, Name: ssb.Name}
+			_, err = reconciler.Reconcile(reconcile.Request{NamespacedName: key})
+			Expect(err).To(BeNil())
+
+			err = reconciler.client.Get(context.TODO(), key, ssb)
+			Expect(err).To(BeNil())
+			Expect(ssb.Status.Conditions.IsTrueFor("Ready")).To(BeFalse())
+			Expect(ssb.Status.Conditions.GetCondition("Ready").Reason).To(BeEquivalentTo("Invalid"))
+			Expect(ssb.Status.Conditions.GetCondition("Ready").Message).To(ContainSubstring("archived"))
+
+			err = reconciler.client.Get(context.TODO(), key, suite)
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("Should report a content version that isn't available", func() {
+			ssb.ContentVersions[0].ImageDigest = "sha256:3333333333333333333333333333333333333333333333333333333333333333"
+			err := reconciler.client.Update(context.TODO(), ssb)
+			Expect(err).To(BeNil())
+
+			_, err = reconciler.Reconcile(reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: ssb.Namespace,
+					Name:      ssb.Name,
+				},
+			})
+			Expect(err).To(BeNil())
+
+			err = reconciler.client.Get(context.TODO(), types.NamespacedName{
+				Namespace: ssb.Namespace,
+				Name:      ssb.Name,
+			}, ssb)
+			Expect(err).To(BeNil())
+			Expect(ssb.Status.Conditions.IsTrueFor("Ready")).To(BeFalse())
+			Expect(ssb.Status.Conditions.GetCondition("Ready").Reason).To(BeEquivalentTo("Invalid"))
+
+			err = reconciler.client.Get(context.TODO(), types.NamespacedName{Name: ssb.Name, Namespace: ssb.Namespace}, suite)
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("Verifies the content of a ProfileBundle", func() {
+		const dsDigest = "sha256:4444444444444444444444444444444444444444444444444444444444444444"
+
+		JustBeforeEach(func() {
+			pbCopy := pBundleRhcos.DeepCopy()
+			pbCopy.Spec.Verification = &compv1alpha1.ContentVerification{Digest: dsDigest}
+			pbCopy.Status.ContentVersion = &compv1alpha1.ProfileBundleContentVersion{
+				ContentImage:     pBundleRhcos.Spec.ContentImage,
+				DataStreamDigest: dsDigest,
+				Verified:         true,
+			}
+			err := reconciler.client.Status().Update(context.TODO(), pbCopy)
+			Expect(err).To(BeNil())
+
+			ssb = &compv1alpha1.ScanSettingBinding{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      "verified-compliance-requirements",
+					Namespace: common.GetComplianceOperatorNamespace(),
+				},
+				Profiles: []compv1alpha1.NamedObjectReference{
+					{
+						Name:     profRhcosE8.Name,
+						Kind:     profRhcosE8.Kind,
+						APIGroup: profRhcosE8.APIVersion,
+					},
+				},
+				SettingsRef: &compv1alpha1.NamedObjectReference{
+					Name:     setting.Name,
+					Kind:     setting.Kind,
+					APIGroup: setting.APIVersion,
+				},
+			}
+			ssb.Status.SetConditionPending()
+
+			err = reconciler.client.Create(context.TODO(), ssb)
+			Expect(err).To(BeNil())
+		})
+
+		It("Should only scan with the verified content", func() {
+			_, err := reconciler.Reconcile(reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: ssb.Namespace,
+					Name:      ssb.Name,
+				},
+			})
+			Expect(err).To(BeNil())
+
+			err = reconciler.client.Get(context.TODO(), types.NamespacedName{Name: ssb.Name, Namespace: ssb.Namespace}, suite)
+			Expect(err).To(BeNil())
+			Expect(suite.Spec.Scans).To(HaveLen(2))
+			for _, scan := range suite.Spec.Scans {
+				Expect(scan.ContentDigest).To(Equal(dsDigest))
+			}
+		})
+
+		It("Should refuse content that wasn't verified", func() {
+			pb := &compv1alpha1.ProfileBundle{}
+			err := reconciler.client.Get(context.TODO(), types.NamespacedName{
+				Namespace: pBundleRhcos.Namespace,
+				Name:      pBundleRhcos.Name,
+			}, pb)
+			Expect(err).To(BeNil())
+			pb.Status.ContentVersion.Verified = false
+			err = reconciler.client.Status().Update(context.TODO(), pb)
+			Expect(err).To(BeNil())
+
+			_, err = reconciler.Reconcile(reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: ssb.Namespace,
+					Name:      ssb.Name,
+				},
+			})
+			Expect(err).To(BeNil())
+
+			err = reconciler.client.Get(context.TODO(), types.NamespacedName{
+				Namespace: ssb.Namespace,
+				Name:      ssb.Name,
+			}, ssb)
+			Expect(err).To(BeNil())
+			Expect(ssb.Status.Conditions.IsTrueFor("Ready")).To(BeFalse())
+
+			err = reconciler.client.Get(context.TODO(), types.NamespacedName{Name: ssb.Name, Namespace: ssb.Namespace}, suite)
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("Reports the control coverage of the latest scans", func() {
+		JustBeforeEach(func() {
+			scheme.Scheme.AddKnownTypes(compv1alpha1.SchemeGroupVersion,
+				&compv1alpha1.ControlCatalog{}, &compv1alpha1.ControlCatalogList{},
+				&compv1alpha1.ComplianceCheckResult{}, &compv1alpha1.ComplianceCheckResultList{})
+			reconciler.recorder = &common.SafeRecorder{}
+
+			catalog := &compv1alpha1.ControlCatalog{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      "rhcos4-nist-800-53",
+					Namespace: common.GetComplianceOperatorNamespace(),
+					Labels: map[string]string{
+						compv1alpha1.ProfileBundleOwnerLabel: pBundleRhcos.Name,
+					},
+				},
+				ControlCatalogPayload: compv1alpha1.ControlCatalogPayload{
+					Framework: "NIST-800-53",
+					Controls: []compv1alpha1.ControlCatalogControl{
+						{ID: "AC-2", Rules: []string{"rhcos4-no-empty-passwords"}},
+						{ID: "AU-2", Rules: []string{"rhcos4-audit-enabled", "rhcos4-no-empty-passwords"}},
+						{ID: "CM-6", Rules: []string{"rhcos4-audit-enabled"}},
+						{ID: "SC-7", Rules: []string{"rhcos4-firewall-enabled"}},
+					},
+				},
+			}
+			err := reconciler.client.Create(context.TODO(), catalog)
+			Expect(err).To(BeNil())
+
+			ssb = &compv1alpha1.ScanSettingBinding{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      "covered-compliance-requirements",
+					Namespace: common.GetComplianceOperatorNamespace(),
+				},
+				Profiles: []compv1alpha1.NamedObjectReference{
+					{
+						Name:     profRhcosE8.Name,
+						Kind:     profRhcosE8.Kind,
+						APIGroup: profRhcosE8.APIVersion,
+					},
+				},
+				SettingsRef: &compv1alpha1.NamedObjectReference{
+					Name:     setting.Name,
+					Kind:     setting.Kind,
+					APIGroup: setting.APIVersion,
+				},
+			}
+			ssb.Status.SetConditionPending()
+
+			err = reconciler.client.Create(context.TODO(), ssb)
+			Expect(err).To(BeNil())
+
+			_, err = reconciler.Reconcile(reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: ssb.Namespace,
+					Name:      ssb.Name,
+				},
+			})
+			Expect(err).To(BeNil())
+
+			results := map[string]compv1alpha1.ComplianceCheckStatus{
+				"rhcos4-e8-master-no-empty-passwords": compv1alpha1.CheckResultPass,
+				"rhcos4-e8-worker-no-empty-passwords": compv1alpha1.CheckResultPass,
+				"rhcos4-e8-master-audit-enabled":      compv1alpha1.CheckResultPass,
+				"rhcos4-e8-worker-audit-enabled":      compv1alpha1.CheckResultFail,
+			}
+			for name, status := range results {
+				scan := strings.Join(strings.Split(name, "-")[:3], "-")
+				ccr := &compv1alpha1.ComplianceCheckResult{
+					ObjectMeta: v1.ObjectMeta{
+						Name:      name,
+						Namespace: common.GetComplianceOperatorNamespace(),
+						Labels: map[string]string{
+							compv1alpha1.SuiteLabel:          ssb.Name,
+							compv1alpha1.ComplianceScanLabel: scan,
+						},
+						Annotations: map[string]string{
+							compv1alpha1.ComplianceCheckResultRuleAnnotation: strings.TrimPrefix(name, scan+"-"),
+						},
+					},
+					Status: status,
+				}
+				err = reconciler.client.Create(context.TODO(), ccr)
+				Expect(err).To(BeNil())
+			}
+		})
+
+		It("Should compute the coverage once the suite is done", func() {
+			err := reconciler.client.Get(context.TODO(), types.NamespacedName{Name: ssb.Name, Namespace: ssb.Namespace}, suite)
+			Expect(err).To(BeNil())
+			suite.Status.Phase = compv1alpha1.PhaseDone
+			suite.Status.ScanStatuses = []compv1alpha1.ComplianceScanStatusWrapper{
+				{
+					Name:                 "rhcos4-e8-worker",
+					ComplianceScanStatus: compv1alpha1.ComplianceScanStatus{Phase: compv1alpha1.PhaseDone},
+				},
+			}
+			err = reconciler.client.Status().Update(context.TODO(), suite)
+			Expect(err).To(BeNil())
+
+			_, err = reconciler.Reconcile(reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: ssb.Namespace,
+					Name:      ssb.Name,
+				},
+			})
+			Expect(err).To(BeNil())
+
+			err = reconciler.client.Get(context.TODO(), types.NamespacedName{
+				Namespace: ssb.Namespace,
+				Name:      ssb.Name,
+			}, ssb)
+			Expect(err).To(BeNil())
+			Expect(ssb.Status.ControlCoverage).To(Equal([]compv1alpha1.ControlFrameworkCoverage{
+				{
+					Framework:        "NIST-800-53",
+					TotalControls:    4,
+					CoveredControls:  3,
+					PassedControls:   1,
+					FailedControls:   2,
+					FailedControlIDs: []string{"AU-2", "CM-6"},
+				},
+			}))
+			Expect(ssb.Status.ControlCoverageVersion).NotTo(BeEmpty())
+
+			By("Only computing it again once the suite completes another run")
+			ccr := &compv1alpha1.ComplianceCheckResult{}
+			err = reconciler.client.Get(context.TODO(), types.NamespacedName{
+				Namespace: ssb.Namespace,
+				Name:      "rhcos4-e8-worker-audit-enabled",
+			}, ccr)
+			Expect(err).To(BeNil())
+			ccr.Status = compv1alpha1.CheckResultPass
+			Expect(reconciler.client.Update(context.TODO(), ccr)).To(Succeed())
+
+			reconcileAndGetCoverage := func() []compv1alpha1.ControlFrameworkCoverage {
+				_, err := reconciler.Reconcile(reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Namespace: ssb.Namespace,
+						Name:      ssb.Name,
+					},
+				})
+				Expect(err).To(BeNil())
+				err = reconciler.client.Get(context.TODO(), types.NamespacedName{
+					Namespace: ssb.Namespace,
+					Name:      ssb.Name,
+				}, ssb)
+				Expect(err).To(BeNil())
+				return ssb.Status.ControlCoverage
+			}
+			Expect(reconcileAndGetCoverage()[0].FailedControls).To(Equal(2))
+
+			err = reconciler.client.Get(context.TODO(), types.NamespacedName{Name: ssb.Name, Namespace: ssb.Namespace}, suite)
+			Expect(err).To(BeNil())
+			suite.Status.ScanStatuses[0].CurrentIndex++
+			Expect(reconciler.client.Status().Update(context.TODO(), suite)).To(Succeed())
+			Expect(reconcileAndGetCoverage()[0].FailedControls).To(Equal(0))
+		})
+
+		It("Should not report coverage while the suite is running", func() {
+			_, err := reconciler.Reconcile(reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: ssb.Namespace,
+					Name:      ssb.Name,
+				},
+			})
+			Expect(err).To(BeNil())
+
+			err = reconciler.client.Get(context.TODO(), types.NamespacedName{
+				Namespace: ssb.Namespace,
+				Name:      ssb.Name,
+			}, ssb)
+			Expect(err).To(BeNil())
+			Expect(ssb.Status.ControlCoverage).To(BeEmpty())
+		})
+	})
+
+	Context("Reconciles the alerts of the binding", func() {
+		var ruleKey types.NamespacedName
+
+		reconcileBinding := func() {
+			_, err := reconciler.Reconcile(reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Namespace: ssb.Namespace,
+					Name:      ssb.Name,
+				},
+			})
+			Expect(err).To(BeNil())
+		}
+
+		JustBeforeEach(func() {
+			err := monitoring.AddToScheme(scheme.Scheme)
+			Expect(err).To(BeNil())
+
+			ssb = &compv1alpha1.ScanSettingBinding{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      "alerting-compliance-requirements",
+					Namespace: common.GetComplianceOperatorNamespace(),
+				},
+				Profiles: []compv1alpha1.NamedObjectReference{
+					{
+						Name:     profRhcosE8.Name,
+						Kind:     profRhcosE8.Kind,
+						APIGroup: profRhcosE8.APIVersion,
+					},
+				},
+				SettingsRef: &compv1alpha1.NamedObjectReference{
+					Name:     setting.Name,
+					Kind:     setting.Kind,
+					APIGroup: setting.APIVersion,
+				},
+			}
+			ssb.Status.SetConditionPending()
+			err = reconciler.client.Create(context.TODO(), ssb)
+			Expect(err).To(BeNil())
+			ruleKey = types.NamespacedName{Namespace: ssb.Namespace, Name: ssb.Name + "-alerts"}
+		})
+
+		It("Creates the default alerts for the results of the suite", func() {
+			reconcileBinding()
+
+			rule := &monitoring.PrometheusRule{}
+			err := reconciler.client.Get(context.TODO(), ruleKey, rule)
+			Expect(err).To(BeNil())
+			Expect(rule.OwnerReferences).To(HaveLen(1))
+			Expect(rule.OwnerReferences[0].Name).To(Equal(ssb.Name))
+			Expect(rule.Spec.Groups).To(HaveLen(1))
+			Expect(rule.Spec.Groups[0].Rules).To(HaveLen(3))
+			Expect(rule.Spec.Groups[0].Rules[0].Expr.String()).To(ContainSubstring(`name="` + ssb.Name + `"`))
+		})
+
+		It("Follows the alerting settings of the ScanSetting", func() {
+			reconcileBinding()
+
+			setting.Alerting = &compv1alpha1.ScanSettingAlerting{
+				Results: []compv1alpha1.AlertResult{compv1alpha1.AlertResult(compv1alpha1.ResultNonCompliant)},
+				For:     "1h",
+				Labels:  map[string]string{"team": "security"},
+			}
+			err := reconciler.client.Update(context.TODO(), setting)
+			Expect(err).To(BeNil())
+			reconcileBinding()
+
+			rule := &monitoring.PrometheusRule{}
+			err = reconciler.client.Get(context.TODO(), ruleKey, rule)
+			Expect(err).To(BeNil())
+			Expect(rule.Spec.Groups[0].Rules).To(HaveLen(1))
+			Expect(rule.Spec.Groups[0].Rules[0].For).To(Equal("1h"))
+			Expect(rule.Spec.Groups[0].Rules[0].Labels).To(HaveKeyWithValue("team", "security"))
+
+			setting.Alerting.Disabled = true
+			err = reconciler.client.Update(context.TODO(), setting)
+			Expect(err).To(BeNil())
+			reconcileBinding()
+
+			err = reconciler.client.Get(context.TODO(), ruleKey, rule)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
 	Context("Detects error if unexistent profile", func() {
 		JustBeforeEach(func() {
 			ssb = &compv1alpha1.ScanSettingBinding{
@@ -16,6 +16,18 @@ type tailoredProfileMapper struct {
 func (s *tailoredProfileMapper) Map(obj handler.MapObject) []reconcile.Request {
 	var requests []reconcile.Request
 
+	// Copies of TailoredProfiles pinned to a content version are owned by
+	// the ScanSettingBinding that pins them
+	for _, ref := range obj.Meta.GetOwnerReferences() {
+		if ref.Kind == "ScanSettingBinding" {
+			objKey := types.NamespacedName{
+				Name:      ref.Name,
+				Namespace: obj.Meta.GetNamespace(),
+			}
+			requests = append(requests, reconcile.Request{NamespacedName: objKey})
+		}
+	}
+
 	ssbList := v1alpha1.ScanSettingBindingList{}
 	err := s.List(context.TODO(), &ssbList, &client.ListOptions{})
 	if err != nil {
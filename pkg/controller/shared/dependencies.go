@@ -0,0 +1,20 @@
+package shared
+
+import (
+	"github.com/openshift/compliance-operator/pkg/controller/cloudevents"
+	"github.com/openshift/compliance-operator/pkg/controller/metrics"
+	"github.com/openshift/compliance-operator/pkg/controller/notifier"
+	"github.com/openshift/compliance-operator/pkg/utils"
+)
+
+// Dependencies holds what the operator shares between its controllers.
+// Controllers pick the fields they need, so that adding a dependency
+// doesn't change the signature of every controller.
+type Dependencies struct {
+	Metrics        *metrics.Metrics
+	SchedulingInfo utils.CtlplaneSchedulingInfo
+	// Sends the notifications of the ComplianceNotifiers
+	Notifier *notifier.Notifier
+	// Emits CloudEvents, if a sink is configured
+	Events *cloudevents.Emitter
+}
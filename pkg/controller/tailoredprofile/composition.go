@@ -0,0 +1,287 @@
+package tailoredprofile
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	cmpv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/common"
+)
+
+// composedProfile is the result of flattening a TailoredProfile that extends
+// several Profiles and TailoredProfiles
+type composedProfile struct {
+	// The first Profile found, which the tailoring extends in XCCDF terms.
+	// Its rules and variable values are inherited by OpenSCAP, so only
+	// the differences need to be written out.
+	base *cmpv1alpha1.Profile
+	pb   *cmpv1alpha1.ProfileBundle
+	// Whether the rules are selected, by name
+	selections map[string]bool
+	// The values of variables, by name
+	values map[string]string
+	// The values of variables the base profile sets, which OpenSCAP
+	// inherits, by name
+	baseValues map[string]string
+	// The CustomRules that are enabled, by name
+	customRules map[string]bool
+	// The rules the selectors of the TailoredProfile itself resolved to
+	resolved *cmpv1alpha1.ResolvedRuleSelections
+}
+
+// composeProfile flattens the rule selections and variable values of the
+// Profiles and TailoredProfiles a TailoredProfile extends. They are applied
+// depth first in the order they are listed, each one overriding those
+// before it, and the TailoredProfile's own selections and values are
+// applied last.
+func (r *ReconcileTailoredProfile) composeProfile(tp *cmpv1alpha1.TailoredProfile) (*composedProfile, error) {
+	c := &composedProfile{
+		selections:  make(map[string]bool),
+		values:      make(map[string]string),
+		baseValues:  make(map[string]string),
+		customRules: make(map[string]bool),
+	}
+	if err := r.composeInto(c, tp, []string{tp.Name}); err != nil {
+		return nil, err
+	}
+	if c.pb == nil {
+		// None of the extended TailoredProfiles extend a Profile
+		pb, err := r.getProfileBundleFromComposed(c, tp)
+		if err != nil {
+			return nil, err
+		}
+		c.pb = pb
+	}
+	return c, nil
+}
+
+// getProfileBundleFromComposed gets the ProfileBundle from the first
+// rule or variable that exists
+func (r *ReconcileTailoredProfile) getProfileBundleFromComposed(c *composedProfile, tp *cmpv1alpha1.TailoredProfile) (*cmpv1alpha1.ProfileBundle, error) {
+	for _, name := range sortedBoolKeys(c.selections) {
+		rule := &cmpv1alpha1.Rule{}
+		err := r.getContent(tp, "Rule", types.NamespacedName{Name: name, Namespace: tp.Namespace}, rule)
+		if kerrors.IsNotFound(err) {
+			// We'll validate this later in the Reconcile loop
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		return r.getProfileBundleFrom("Rule", rule)
+	}
+
+	for _, name := range sortedStringKeys(c.values) {
+		variable := &cmpv1alpha1.Variable{}
+		err := r.getContent(tp, "Variable", types.NamespacedName{Name: name, Namespace: tp.Namespace}, variable)
+		if kerrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		return r.getProfileBundleFrom("Variable", variable)
+	}
+
+	return nil, common.NewNonRetriableCtrlError("Unable to get ProfileBundle from the extended profiles")
+}
+
+func (r *ReconcileTailoredProfile) composeInto(c *composedProfile, tp *cmpv1alpha1.TailoredProfile, chain []string) error {
+	for _, ref := range tp.GetExtendedProfiles() {
+		var err error
+		if ref.Kind == "TailoredProfile" {
+			err = r.composeTailoredProfile(c, tp, ref.Name, chain)
+		} else {
+			err = r.composeProfileRules(c, tp, ref.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var pbName string
+	if c.pb != nil {
+		pbName = c.pb.Name
+	}
+	selected, resolved, err := r.resolveRuleSelectors(tp, pbName, getProductType(tp, c.base))
+	if err != nil {
+		return err
+	}
+	// Only the resolution of the TailoredProfile being reconciled is
+	// reported in its status
+	if len(chain) == 1 {
+		c.resolved = resolved
+	}
+
+	for _, selection := range selected.Spec.EnableRules {
+		c.selections[selection.Name] = true
+	}
+	for _, selection := range selected.Spec.DisableRules {
+		c.selections[selection.Name] = false
+	}
+	for _, setValue := range tp.Spec.SetValues {
+		c.values[setValue.Name] = setValue.Value
+	}
+	for _, customRule := range tp.Spec.CustomRules {
+		c.customRules[customRule.Name] = true
+	}
+	return nil
+}
+
+func (r *ReconcileTailoredProfile) composeTailoredProfile(c *composedProfile, tp *cmpv1alpha1.TailoredProfile, name string, chain []string) error {
+	for _, seen := range chain {
+		if seen == name {
+			return common.NewNonRetriableCtrlError("TailoredProfiles extend each other in a cycle: %s",
+				strings.Join(append(chain, name), " -> "))
+		}
+	}
+
+	extended := &cmpv1alpha1.TailoredProfile{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: tp.Namespace}, extended)
+	if kerrors.IsNotFound(err) {
+		return common.NewNonRetriableCtrlError("fetching TailoredProfile to be extended: %w", err)
+	} else if err != nil {
+		return err
+	}
+	if cmpv1alpha1.IsArchivedContent(extended) {
+		return common.NewNonRetriableCtrlError("TailoredProfile %s is pinned to a content version and can't be extended", name)
+	}
+
+	// The extended TailoredProfile is resolved against the same content
+	// version as the one extending it
+	if digest := tp.GetPinnedContentDigest(); digest != "" {
+		if extended.Annotations == nil {
+			extended.Annotations = make(map[string]string)
+		}
+		extended.Annotations[cmpv1alpha1.PinnedContentDigestAnnotation] = digest
+	}
+
+	next := append(append([]string{}, chain...), name)
+	return r.composeInto(c, extended, next)
+}
+
+func (r *ReconcileTailoredProfile) composeProfileRules(c *composedProfile, tp *cmpv1alpha1.TailoredProfile, name string) error {
+	p := &cmpv1alpha1.Profile{}
+	err := r.getContent(tp, "Profile", types.NamespacedName{Name: name, Namespace: tp.Namespace}, p)
+	if kerrors.IsNotFound(err) {
+		return common.NewNonRetriableCtrlError("fetching profile to be extended: %w", err)
+	} else if err != nil {
+		return err
+	}
+
+	pb, err := r.getProfileBundleFrom("Profile", p)
+	if err != nil {
+		return err
+	}
+	if c.pb == nil {
+		c.pb = pb
+	} else if c.pb.GetUID() != pb.GetUID() {
+		return common.NewNonRetriableCtrlError("profile %s not owned by expected ProfileBundle %s",
+			p.GetName(), c.pb.GetName())
+	}
+
+	isBase := c.base == nil
+	if isBase {
+		c.base = p
+	}
+	for _, rule := range p.GetEffectiveRules() {
+		c.selections[string(rule)] = true
+	}
+	for _, value := range p.RefinedValues {
+		if value.Value == "" {
+			// The selector didn't match any of the variable's values
+			continue
+		}
+		c.values[value.Variable] = value.Value
+		if isBase {
+			c.baseValues[value.Variable] = value.Value
+		}
+	}
+	return nil
+}
+
+// getComposedRules returns the rules that need to be enabled and disabled
+// on top of the base profile, sorted by name
+func (r *ReconcileTailoredProfile) getComposedRules(tp *cmpv1alpha1.TailoredProfile, c *composedProfile) ([]*cmpv1alpha1.Rule, []*cmpv1alpha1.Rule, error) {
+	inBase := make(map[string]bool)
+	if c.base != nil {
+		for _, rule := range c.base.GetEffectiveRules() {
+			inBase[string(rule)] = true
+		}
+	}
+
+	var enabled, disabled []*cmpv1alpha1.Rule
+	for _, name := range sortedBoolKeys(c.selections) {
+		selected := c.selections[name]
+		if selected == inBase[name] {
+			continue
+		}
+
+		rule := &cmpv1alpha1.Rule{}
+		err := r.getContent(tp, "Rule", types.NamespacedName{Name: name, Namespace: tp.Namespace}, rule)
+		if kerrors.IsNotFound(err) {
+			return nil, nil, common.NewNonRetriableCtrlError("Fetching rule: %w", err)
+		} else if err != nil {
+			return nil, nil, err
+		}
+		if !isOwnedBy(rule, c.pb) {
+			return nil, nil, common.NewNonRetriableCtrlError("rule %s not owned by expected ProfileBundle %s",
+				rule.GetName(), c.pb.GetName())
+		}
+
+		if selected {
+			enabled = append(enabled, rule)
+		} else {
+			disabled = append(disabled, rule)
+		}
+	}
+	return enabled, disabled, nil
+}
+
+// getComposedVariables returns the variables with the values that were
+// set, sorted by name. The values the base profile already sets are left
+// out.
+func (r *ReconcileTailoredProfile) getComposedVariables(tp *cmpv1alpha1.TailoredProfile, c *composedProfile) ([]*cmpv1alpha1.Variable, error) {
+	variables := []*cmpv1alpha1.Variable{}
+	for _, name := range sortedStringKeys(c.values) {
+		if baseValue, ok := c.baseValues[name]; ok && baseValue == c.values[name] {
+			continue
+		}
+		variable := &cmpv1alpha1.Variable{}
+		err := r.getContent(tp, "Variable", types.NamespacedName{Name: name, Namespace: tp.Namespace}, variable)
+		if kerrors.IsNotFound(err) {
+			return nil, common.NewNonRetriableCtrlError("fetching variable: %w", err)
+		} else if err != nil {
+			return nil, err
+		}
+		if !isOwnedBy(variable, c.pb) {
+			return nil, common.NewNonRetriableCtrlError("variable %s not owned by expected ProfileBundle %s",
+				variable.GetName(), c.pb.GetName())
+		}
+		if err := variable.SetValue(c.values[name]); err != nil {
+			return nil, common.NewNonRetriableCtrlError("setting variable: %s", err)
+		}
+		variables = append(variables, variable)
+	}
+	return variables, nil
+}
+
+func sortedBoolKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,43 @@
+package tailoredprofile
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	cmpv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+// customRuleMapper enqueues the TailoredProfiles that enable a CustomRule
+// when it changes, so that the copy in their output is updated
+type customRuleMapper struct {
+	client.Client
+}
+
+func (m *customRuleMapper) Map(obj handler.MapObject) []reconcile.Request {
+	var requests []reconcile.Request
+
+	tpList := cmpv1alpha1.TailoredProfileList{}
+	err := m.List(context.TODO(), &tpList, client.InNamespace(obj.Meta.GetNamespace()))
+	if err != nil {
+		return requests
+	}
+
+	for i := range tpList.Items {
+		tp := &tpList.Items[i]
+		for _, customRule := range tp.Spec.CustomRules {
+			if customRule.Name != obj.Meta.GetName() {
+				continue
+			}
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: tp.GetName(), Namespace: tp.GetNamespace()},
+			})
+			break
+		}
+	}
+
+	return requests
+}
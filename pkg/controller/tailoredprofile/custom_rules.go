@@ -0,0 +1,81 @@
+package tailoredprofile
+
+import (
+	"context"
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	cmpv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/common"
+	"github.com/openshift/compliance-operator/pkg/utils"
+)
+
+// getCustomRules fetches the CustomRules with the given names and makes
+// sure that they can be evaluated. Only the name and the spec of the rules
+// are kept, as that's all the scan needs.
+func (r *ReconcileTailoredProfile) getCustomRules(tp *cmpv1alpha1.TailoredProfile, extended *cmpv1alpha1.Profile, names []string) ([]cmpv1alpha1.CustomRule, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	if getProductType(tp, extended) == string(cmpv1alpha1.ScanTypeNode) {
+		return nil, common.NewNonRetriableCtrlError("CustomRules can only be enabled by TailoredProfiles of the Platform type")
+	}
+
+	customRules := make([]cmpv1alpha1.CustomRule, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			return nil, common.NewNonRetriableCtrlError("CustomRule '%s' appears twice in customRules", name)
+		}
+		seen[name] = true
+
+		customRule := &cmpv1alpha1.CustomRule{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: tp.Namespace}, customRule)
+		if kerrors.IsNotFound(err) {
+			return nil, common.NewNonRetriableCtrlError("fetching CustomRule: %w", err)
+		} else if err != nil {
+			return nil, err
+		}
+
+		if customRule.Spec.CheckType != "" && customRule.Spec.CheckType != cmpv1alpha1.CheckTypePlatform {
+			return nil, common.NewNonRetriableCtrlError("CustomRule '%s' has the unsupported check type '%s'",
+				name, customRule.Spec.CheckType)
+		}
+		if _, _, err := utils.ParseCustomRuleQueries(&customRule.Spec); err != nil {
+			return nil, common.NewNonRetriableCtrlError("CustomRule '%s' is invalid: %s", name, err)
+		}
+
+		customRules = append(customRules, cmpv1alpha1.CustomRule{
+			ObjectMeta: metav1.ObjectMeta{Name: customRule.Name},
+			Spec:       customRule.Spec,
+		})
+	}
+	return customRules, nil
+}
+
+// setCustomRulesData stores the CustomRules in the output ConfigMap, so
+// that the scans using the TailoredProfile can evaluate them
+func setCustomRulesData(tpcm *corev1.ConfigMap, customRules []cmpv1alpha1.CustomRule) error {
+	if len(customRules) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(customRules)
+	if err != nil {
+		return err
+	}
+	tpcm.Data[cmpv1alpha1.CustomRulesTailoringKey] = string(data)
+	return nil
+}
+
+func customRuleNames(selections []cmpv1alpha1.RuleReferenceSpec) []string {
+	names := make([]string, 0, len(selections))
+	for _, selection := range selections {
+		names = append(names, selection.Name)
+	}
+	return names
+}
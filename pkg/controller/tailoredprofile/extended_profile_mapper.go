@@ -0,0 +1,60 @@
+package tailoredprofile
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	cmpv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+// extendedProfileMapper enqueues the TailoredProfiles that extend a
+// TailoredProfile, directly or through other TailoredProfiles
+type extendedProfileMapper struct {
+	client.Client
+}
+
+func (m *extendedProfileMapper) Map(obj handler.MapObject) []reconcile.Request {
+	var requests []reconcile.Request
+
+	tpList := cmpv1alpha1.TailoredProfileList{}
+	err := m.List(context.TODO(), &tpList, client.InNamespace(obj.Meta.GetNamespace()))
+	if err != nil {
+		return requests
+	}
+
+	// Walk the TailoredProfiles that extend the changed one, then those
+	// extending them, and so on. The visited ones are skipped, so cycles
+	// end the walk.
+	visited := map[string]bool{obj.Meta.GetName(): true}
+	pending := []string{obj.Meta.GetName()}
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+		for i := range tpList.Items {
+			tp := &tpList.Items[i]
+			if visited[tp.GetName()] || !extendsTailoredProfile(tp, name) {
+				continue
+			}
+			visited[tp.GetName()] = true
+			pending = append(pending, tp.GetName())
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: tp.GetName(), Namespace: tp.GetNamespace()},
+			})
+		}
+	}
+
+	return requests
+}
+
+func extendsTailoredProfile(tp *cmpv1alpha1.TailoredProfile, name string) bool {
+	for _, ref := range tp.Spec.ExtendsProfiles {
+		if ref.Kind == "TailoredProfile" && ref.Name == name {
+			return true
+		}
+	}
+	return false
+}
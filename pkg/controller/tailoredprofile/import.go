@@ -0,0 +1,108 @@
+package tailoredprofile
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	cmpv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/common"
+	"github.com/openshift/compliance-operator/pkg/xccdf"
+)
+
+// importTailoring converts the profile of the tailoring file the
+// TailoredProfile imports into its spec. It returns a copy of the
+// TailoredProfile to update, or nil if the tailoring file didn't change
+// since it was last imported.
+func (r *ReconcileTailoredProfile) importTailoring(tp *cmpv1alpha1.TailoredProfile) (*cmpv1alpha1.TailoredProfile, error) {
+	source := tp.Spec.ImportTailoring
+	if len(tp.Spec.ExtendsProfiles) > 0 {
+		return nil, common.NewNonRetriableCtrlError("importTailoring and extendsProfiles can't be used together")
+	}
+
+	cm := &corev1.ConfigMap{}
+	cmKey := types.NamespacedName{Name: source.ConfigMap, Namespace: tp.GetNamespace()}
+	if err := r.client.Get(context.TODO(), cmKey, cm); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, common.NewNonRetriableCtrlError("the ConfigMap %s holding the tailoring file doesn't exist", source.ConfigMap)
+		}
+		return nil, err
+	}
+	data, ok := cm.Data[source.GetKey()]
+	if !ok {
+		return nil, common.NewNonRetriableCtrlError("the ConfigMap %s doesn't contain the key %s", source.ConfigMap, source.GetKey())
+	}
+
+	digest := getImportedTailoringDigest(source, data)
+	if tp.GetAnnotations()[cmpv1alpha1.ImportedTailoringDigestAnnotation] == digest {
+		return nil, nil
+	}
+
+	pb := &cmpv1alpha1.ProfileBundle{}
+	pbKey := types.NamespacedName{Name: source.ProfileBundle, Namespace: tp.GetNamespace()}
+	if err := r.client.Get(context.TODO(), pbKey, pb); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, common.NewNonRetriableCtrlError("the ProfileBundle %s doesn't exist", source.ProfileBundle)
+		}
+		return nil, err
+	}
+	// The bundle is still being parsed, try again later
+	if pb.Status.DataStreamStatus != cmpv1alpha1.DataStreamValid {
+		return nil, fmt.Errorf("the content of ProfileBundle %s isn't valid yet", pb.Name)
+	}
+
+	tailoring, err := xccdf.ParseTailoring(strings.NewReader(data))
+	if err != nil {
+		return nil, common.WrapNonRetriableCtrlError(err)
+	}
+	profile, err := tailoring.GetProfile(source.ProfileID)
+	if err != nil {
+		return nil, common.WrapNonRetriableCtrlError(err)
+	}
+	content, err := common.GetBundleContent(r.client, pb.Name, pb.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	rationale := fmt.Sprintf("Imported from the XCCDF tailoring %s", tailoring.ID)
+	spec, err := xccdf.TailoringProfileToSpec(profile, content, rationale)
+	if err != nil {
+		return nil, common.WrapNonRetriableCtrlError(err)
+	}
+
+	tpCopy := tp.DeepCopy()
+	tpCopy.Spec.Extends = spec.Extends
+	tpCopy.Spec.EnableRules = spec.EnableRules
+	tpCopy.Spec.DisableRules = spec.DisableRules
+	tpCopy.Spec.SetValues = spec.SetValues
+
+	anns := tpCopy.GetAnnotations()
+	if anns == nil {
+		anns = make(map[string]string)
+	}
+	anns[cmpv1alpha1.ImportedTailoringDigestAnnotation] = digest
+	// Keep the product type of the extended profile, otherwise it's
+	// inferred from the name of the TailoredProfile
+	if extended, ok := content.Profiles[profile.Extends]; ok {
+		if _, ok := anns[cmpv1alpha1.ProductTypeAnnotation]; !ok {
+			if productType, ok := extended.Annotations[cmpv1alpha1.ProductTypeAnnotation]; ok {
+				anns[cmpv1alpha1.ProductTypeAnnotation] = productType
+			}
+		}
+	}
+	tpCopy.SetAnnotations(anns)
+	return tpCopy, nil
+}
+
+// getImportedTailoringDigest returns a digest of the tailoring file and of
+// what is imported from it
+func getImportedTailoringDigest(source *cmpv1alpha1.TailoringImportSource, data string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n", source.ProfileBundle, source.ProfileID)
+	h.Write([]byte(data))
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
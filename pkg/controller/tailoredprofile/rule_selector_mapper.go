@@ -0,0 +1,41 @@
+package tailoredprofile
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	cmpv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+// ruleSelectorMapper enqueues the TailoredProfiles that select rules
+// through selectors when a Rule in their namespace changes, so that the
+// selectors are resolved again
+type ruleSelectorMapper struct {
+	client.Client
+}
+
+func (m *ruleSelectorMapper) Map(obj handler.MapObject) []reconcile.Request {
+	var requests []reconcile.Request
+
+	tpList := cmpv1alpha1.TailoredProfileList{}
+	err := m.List(context.TODO(), &tpList, client.InNamespace(obj.Meta.GetNamespace()))
+	if err != nil {
+		return requests
+	}
+
+	for i := range tpList.Items {
+		tp := &tpList.Items[i]
+		if !tp.HasRuleSelectors() {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: tp.GetName(), Namespace: tp.GetNamespace()},
+		})
+	}
+
+	return requests
+}
@@ -0,0 +1,340 @@
+package tailoredprofile
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	cmpv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/controller/common"
+)
+
+// resolveRuleSelectors returns a copy of the TailoredProfile where the
+// rules selected through selectors are listed by name, along with the
+// names of those rules. Rules that are also selected by name keep that
+// selection, and rules matched by both enabling and disabling selectors
+// are disabled. Selectors that don't set a check type don't match the
+// rules of the other product type. If pbName is empty, the rules are scoped to
+// the bundles of the product the TailoredProfile is annotated with, if
+// any, and the selectors have to match rules of a single ProfileBundle.
+func (r *ReconcileTailoredProfile) resolveRuleSelectors(tp *cmpv1alpha1.TailoredProfile, pbName, productType string) (*cmpv1alpha1.TailoredProfile, *cmpv1alpha1.ResolvedRuleSelections, error) {
+	if !tp.HasRuleSelectors() {
+		return tp, nil, nil
+	}
+
+	ruleList, err := r.listRules(tp, pbName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if product := tp.GetAnnotations()[cmpv1alpha1.ProductAnnotation]; pbName == "" && product != "" {
+		bundles, err := r.getProductBundles(tp, product)
+		if err != nil {
+			return nil, nil, err
+		}
+		ruleList.Items = filterRulesByBundle(ruleList.Items, bundles)
+	}
+	defaultCheckType := getDefaultCheckType(productType)
+
+	named := make(map[string]bool)
+	for _, selection := range append(tp.Spec.EnableRules, tp.Spec.DisableRules...) {
+		if selection.Selector == nil {
+			named[selection.Name] = true
+		}
+	}
+
+	enabled, err := matchRuleSelections(tp.Spec.EnableRules, ruleList.Items, named, defaultCheckType)
+	if err != nil {
+		return nil, nil, err
+	}
+	disabled, err := matchRuleSelections(tp.Spec.DisableRules, ruleList.Items, named, defaultCheckType)
+	if err != nil {
+		return nil, nil, err
+	}
+	for name := range disabled {
+		delete(enabled, name)
+	}
+
+	if pbName == "" {
+		if err := assertSingleProfileBundle(ruleList.Items, enabled, disabled); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	resolvedTP := tp.DeepCopy()
+	resolvedTP.Spec.EnableRules = expandRuleSelections(tp.Spec.EnableRules, enabled)
+	resolvedTP.Spec.DisableRules = expandRuleSelections(tp.Spec.DisableRules, disabled)
+
+	resolved := &cmpv1alpha1.ResolvedRuleSelections{
+		Enabled:  sortedStringKeys(enabled),
+		Disabled: sortedStringKeys(disabled),
+	}
+	return resolvedTP, resolved, nil
+}
+
+// listRules lists the rules of the given ProfileBundle, or of all of them
+// if pbName is empty. If the TailoredProfile is pinned to a content
+// version, the rules are listed as parsed from it.
+func (r *ReconcileTailoredProfile) listRules(tp *cmpv1alpha1.TailoredProfile, pbName string) (*cmpv1alpha1.RuleList, error) {
+	ruleList := cmpv1alpha1.RuleList{}
+	if digest := tp.GetPinnedContentDigest(); digest != "" {
+		items, err := common.ListPinnedContent(r.client, "Rule", tp.Namespace, pbName, digest)
+		if err != nil {
+			return nil, err
+		}
+		ruleList.Items = make([]cmpv1alpha1.Rule, len(items))
+		for i := range items {
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(items[i].Object, &ruleList.Items[i]); err != nil {
+				return nil, err
+			}
+		}
+		return &ruleList, nil
+	}
+
+	// Archived rules don't have the owner label, so they're never selected
+	listOpts := []client.ListOption{client.InNamespace(tp.Namespace)}
+	if pbName != "" {
+		listOpts = append(listOpts, client.MatchingLabels{cmpv1alpha1.ProfileBundleOwnerLabel: pbName})
+	} else {
+		listOpts = append(listOpts, client.HasLabels{cmpv1alpha1.ProfileBundleOwnerLabel})
+	}
+	if err := r.client.List(context.TODO(), &ruleList, listOpts...); err != nil {
+		return nil, err
+	}
+	return &ruleList, nil
+}
+
+// getProductBundles returns the names of the ProfileBundles that have
+// profiles for the given product
+func (r *ReconcileTailoredProfile) getProductBundles(tp *cmpv1alpha1.TailoredProfile, product string) (map[string]bool, error) {
+	var profiles []metav1.Object
+	if digest := tp.GetPinnedContentDigest(); digest != "" {
+		items, err := common.ListPinnedContent(r.client, "Profile", tp.Namespace, "", digest)
+		if err != nil {
+			return nil, err
+		}
+		for i := range items {
+			profiles = append(profiles, &items[i])
+		}
+	} else {
+		profileList := cmpv1alpha1.ProfileList{}
+		err := r.client.List(context.TODO(), &profileList, client.InNamespace(tp.Namespace),
+			client.HasLabels{cmpv1alpha1.ProfileBundleOwnerLabel})
+		if err != nil {
+			return nil, err
+		}
+		for i := range profileList.Items {
+			profiles = append(profiles, &profileList.Items[i])
+		}
+	}
+
+	bundles := make(map[string]bool)
+	for _, profile := range profiles {
+		if profile.GetAnnotations()[cmpv1alpha1.ProductAnnotation] == product {
+			bundles[profile.GetLabels()[cmpv1alpha1.ProfileBundleOwnerLabel]] = true
+		}
+	}
+	return bundles, nil
+}
+
+func filterRulesByBundle(rules []cmpv1alpha1.Rule, bundles map[string]bool) []cmpv1alpha1.Rule {
+	var filtered []cmpv1alpha1.Rule
+	for i := range rules {
+		if bundles[rules[i].GetLabels()[cmpv1alpha1.ProfileBundleOwnerLabel]] {
+			filtered = append(filtered, rules[i])
+		}
+	}
+	return filtered
+}
+
+// getProductType returns the product type of the TailoredProfile, falling
+// back to the one of the Profile it extends, if any
+func getProductType(tp *cmpv1alpha1.TailoredProfile, extended *cmpv1alpha1.Profile) string {
+	if productType, ok := tp.GetAnnotations()[cmpv1alpha1.ProductTypeAnnotation]; ok {
+		return productType
+	}
+	if extended != nil {
+		return extended.GetAnnotations()[cmpv1alpha1.ProductTypeAnnotation]
+	}
+	if strings.HasSuffix(tp.GetName(), "-node") {
+		return string(cmpv1alpha1.ScanTypeNode)
+	}
+	return string(cmpv1alpha1.ScanTypePlatform)
+}
+
+// getDefaultCheckType returns the check type of the rules that can be
+// evaluated by a scan of the given product type
+func getDefaultCheckType(productType string) string {
+	if strings.EqualFold(productType, string(cmpv1alpha1.ScanTypeNode)) {
+		return cmpv1alpha1.CheckTypeNode
+	}
+	return cmpv1alpha1.CheckTypePlatform
+}
+
+// matchRuleSelections returns the rules matched by the selectors in the
+// given selections, mapped to the rationale of the first selector that
+// matched them. Selectors that don't set a check type match rules of the
+// default one and rules without a check. Rules in skip are left out.
+func matchRuleSelections(selections []cmpv1alpha1.RuleReferenceSpec, rules []cmpv1alpha1.Rule, skip map[string]bool, defaultCheckType string) (map[string]string, error) {
+	matched := make(map[string]string)
+	for _, selection := range selections {
+		if selection.Selector == nil {
+			continue
+		}
+		if selection.Selector.IsEmpty() {
+			return nil, common.NewNonRetriableCtrlError("rule selectors need to set at least one criterion")
+		}
+		selector, err := newRuleSelector(selection.Selector, defaultCheckType)
+		if err != nil {
+			return nil, err
+		}
+		for i := range rules {
+			rule := &rules[i]
+			if skip[rule.Name] {
+				continue
+			}
+			if _, ok := matched[rule.Name]; ok {
+				continue
+			}
+			if selector.matches(rule) {
+				matched[rule.Name] = selection.Rationale
+			}
+		}
+	}
+	return matched, nil
+}
+
+// ruleSelector is a RuleSelectorSpec with its label selector and
+// annotation patterns parsed once, to be matched against all the rules
+type ruleSelector struct {
+	spec             *cmpv1alpha1.RuleSelectorSpec
+	labelSelector    labels.Selector
+	annotations      map[string]*regexp.Regexp
+	defaultCheckType string
+}
+
+func newRuleSelector(spec *cmpv1alpha1.RuleSelectorSpec, defaultCheckType string) (*ruleSelector, error) {
+	selector := &ruleSelector{
+		spec:             spec,
+		annotations:      make(map[string]*regexp.Regexp, len(spec.MatchAnnotations)),
+		defaultCheckType: defaultCheckType,
+	}
+	if spec.LabelSelector != nil {
+		labelSelector, err := metav1.LabelSelectorAsSelector(spec.LabelSelector)
+		if err != nil {
+			return nil, common.NewNonRetriableCtrlError("parsing the label selector of a rule selector: %s", err)
+		}
+		selector.labelSelector = labelSelector
+	}
+	for key, pattern := range spec.MatchAnnotations {
+		selector.annotations[key] = compileAnnotationPattern(pattern)
+	}
+	return selector, nil
+}
+
+func (s *ruleSelector) matches(rule *cmpv1alpha1.Rule) bool {
+	if s.labelSelector != nil && !s.labelSelector.Matches(labels.Set(rule.GetLabels())) {
+		return false
+	}
+
+	for key, re := range s.annotations {
+		value, ok := rule.GetAnnotations()[key]
+		if !ok || !annotationMatches(value, re) {
+			return false
+		}
+	}
+
+	if len(s.spec.Severities) > 0 {
+		found := false
+		for _, severity := range s.spec.Severities {
+			if strings.EqualFold(severity, rule.Severity) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if s.spec.CheckType != "" && s.spec.CheckType != rule.CheckType {
+		return false
+	} else if s.spec.CheckType == "" && rule.CheckType != s.defaultCheckType && rule.CheckType != cmpv1alpha1.CheckTypeNone {
+		// Rules of the other check type can't be evaluated by the scan
+		return false
+	}
+	return true
+}
+
+// compileAnnotationPattern turns an annotation pattern, where '*' matches
+// any sequence of characters, into a regular expression. The rest of the
+// pattern is quoted, so it always compiles.
+func compileAnnotationPattern(pattern string) *regexp.Regexp {
+	return regexp.MustCompile("^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$")
+}
+
+// annotationMatches returns true if the annotation value, or any of the
+// items if it's a list, matches the pattern
+func annotationMatches(value string, re *regexp.Regexp) bool {
+	if re.MatchString(value) {
+		return true
+	}
+	for _, item := range strings.FieldsFunc(value, func(c rune) bool { return c == ';' || c == ',' }) {
+		if re.MatchString(strings.TrimSpace(item)) {
+			return true
+		}
+	}
+	return false
+}
+
+// assertSingleProfileBundle makes sure that the selected rules all come
+// from the same ProfileBundle, which can't be determined otherwise when
+// the TailoredProfile doesn't extend a Profile
+func assertSingleProfileBundle(rules []cmpv1alpha1.Rule, selected ...map[string]string) error {
+	var expectedPB, expectedFrom string
+	for i := range rules {
+		rule := &rules[i]
+		isSelected := false
+		for _, names := range selected {
+			if _, ok := names[rule.Name]; ok {
+				isSelected = true
+			}
+		}
+		if !isSelected {
+			continue
+		}
+
+		pbName := rule.GetLabels()[cmpv1alpha1.ProfileBundleOwnerLabel]
+		if expectedPB == "" {
+			expectedPB = pbName
+			expectedFrom = rule.Name
+		} else if expectedPB != pbName {
+			return common.NewNonRetriableCtrlError("rule selectors matched rule '%s' from ProfileBundle '%s' and rule '%s' from ProfileBundle '%s', "+
+				"use the '%s' label to select rules from a single ProfileBundle",
+				expectedFrom, expectedPB, rule.Name, pbName, cmpv1alpha1.ProfileBundleOwnerLabel)
+		}
+	}
+	return nil
+}
+
+// expandRuleSelections replaces the selectors in the selections with the
+// rules they matched
+func expandRuleSelections(selections []cmpv1alpha1.RuleReferenceSpec, matched map[string]string) []cmpv1alpha1.RuleReferenceSpec {
+	var expanded []cmpv1alpha1.RuleReferenceSpec
+	for _, selection := range selections {
+		if selection.Selector == nil {
+			expanded = append(expanded, selection)
+		}
+	}
+	for _, name := range sortedStringKeys(matched) {
+		expanded = append(expanded, cmpv1alpha1.RuleReferenceSpec{
+			Name:      name,
+			Rationale: matched[name],
+		})
+	}
+	return expanded
+}
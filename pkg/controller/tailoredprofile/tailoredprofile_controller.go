@@ -3,10 +3,11 @@ package tailoredprofile
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/openshift/compliance-operator/pkg/controller/metrics"
-	"github.com/openshift/compliance-operator/pkg/utils"
+	"github.com/openshift/compliance-operator/pkg/controller/shared"
 
 	"github.com/go-logr/logr"
 	"github.com/openshift/compliance-operator/pkg/controller/common"
@@ -14,8 +15,10 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -37,8 +40,8 @@ const (
 
 // Add creates a new TailoredProfile Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
-func Add(mgr manager.Manager, met *metrics.Metrics, _ utils.CtlplaneSchedulingInfo) error {
-	return add(mgr, newReconciler(mgr, met))
+func Add(mgr manager.Manager, deps shared.Dependencies) error {
+	return add(mgr, newReconciler(mgr, deps.Metrics))
 }
 
 // newReconciler returns a new reconcile.Reconciler
@@ -60,6 +63,42 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Watch for changes to the TailoredProfiles that other TailoredProfiles
+	// extend, so that the changes are reflected in the flattened result
+	err = c.Watch(&source.Kind{Type: &cmpv1alpha1.TailoredProfile{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: &extendedProfileMapper{mgr.GetClient()},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to Rules, which might change the rules that
+	// selectors match
+	err = c.Watch(&source.Kind{Type: &cmpv1alpha1.Rule{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: &ruleSelectorMapper{mgr.GetClient()},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to CustomRules, which are copied to the output
+	// of the TailoredProfiles enabling them
+	err = c.Watch(&source.Kind{Type: &cmpv1alpha1.CustomRule{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: &customRuleMapper{mgr.GetClient()},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the ConfigMaps holding the tailoring files that
+	// TailoredProfiles import
+	err = c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: &tailoringSourceMapper{mgr.GetClient()},
+	})
+	if err != nil {
+		return err
+	}
+
 	err = c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestForOwner{
 		IsController: true,
 		OwnerType:    &cmpv1alpha1.TailoredProfile{},
@@ -106,8 +145,31 @@ func (r *ReconcileTailoredProfile) Reconcile(request reconcile.Request) (reconci
 		return reconcile.Result{}, err
 	}
 
+	if instance.Spec.ImportTailoring != nil {
+		imported, importErr := r.importTailoring(instance)
+		if importErr != nil && !common.IsRetriable(importErr) {
+			err = r.handleTailoredProfileStatusError(instance, importErr)
+			return reconcile.Result{}, err
+		} else if importErr != nil {
+			return reconcile.Result{}, importErr
+		}
+		// This update will trigger a requeue with the imported spec
+		if imported != nil {
+			reqLogger.Info("Importing the tailoring file", "ConfigMap", instance.Spec.ImportTailoring.ConfigMap)
+			return reconcile.Result{}, r.client.Update(context.TODO(), imported)
+		}
+	}
+
+	if instance.IsComposed() {
+		return r.reconcileComposed(instance, reqLogger)
+	}
+
 	var pb *cmpv1alpha1.ProfileBundle
 	var p *cmpv1alpha1.Profile
+	// The TailoredProfile with the rules matched by selectors listed by
+	// name. Only used for reading, updates are done on the instance.
+	var selected *cmpv1alpha1.TailoredProfile
+	var resolved *cmpv1alpha1.ResolvedRuleSelections
 
 	if instance.Spec.Extends != "" {
 		var pbgetErr error
@@ -125,11 +187,39 @@ func (r *ReconcileTailoredProfile) Reconcile(request reconcile.Request) (reconci
 		// This update will trigger a requeue with the new object.
 		if needsControllerRef(instance) {
 			tpCopy := instance.DeepCopy()
-			return r.setOwnership(tpCopy, p)
+			if tpCopy.GetPinnedContentDigest() == "" {
+				return r.setOwnership(tpCopy, p)
+			}
+			// The Profile might only be an archived copy, which can't be
+			// an owner under its original name
+			if _, ok := tpCopy.Annotations[cmpv1alpha1.ProductTypeAnnotation]; !ok {
+				if productType, ok := p.Annotations[cmpv1alpha1.ProductTypeAnnotation]; ok {
+					tpCopy.Annotations[cmpv1alpha1.ProductTypeAnnotation] = productType
+				}
+			}
+			return r.setOwnership(tpCopy, pb)
+		}
+
+		var resolveErr error
+		selected, resolved, resolveErr = r.resolveRuleSelectors(instance, pb.Name, getProductType(instance, p))
+		if resolveErr != nil && !common.IsRetriable(resolveErr) {
+			err = r.handleTailoredProfileStatusError(instance, resolveErr)
+			return reconcile.Result{}, err
+		} else if resolveErr != nil {
+			return reconcile.Result{}, resolveErr
 		}
 	} else {
+		var resolveErr error
+		selected, resolved, resolveErr = r.resolveRuleSelectors(instance, "", getProductType(instance, nil))
+		if resolveErr != nil && !common.IsRetriable(resolveErr) {
+			err = r.handleTailoredProfileStatusError(instance, resolveErr)
+			return reconcile.Result{}, err
+		} else if resolveErr != nil {
+			return reconcile.Result{}, resolveErr
+		}
+
 		var pbgetErr error
-		pb, pbgetErr = r.getProfileBundleFromRulesOrVars(instance)
+		pb, pbgetErr = r.getProfileBundleFromRulesOrVars(selected)
 		if pbgetErr != nil && !common.IsRetriable(pbgetErr) {
 			// the Profile or ProfileBundle objects didn't exist. Surface the error.
 			err = r.handleTailoredProfileStatusError(instance, pbgetErr)
@@ -163,7 +253,7 @@ func (r *ReconcileTailoredProfile) Reconcile(request reconcile.Request) (reconci
 		}
 	}
 
-	rules, ruleErr := r.getRulesFromSelections(instance, pb)
+	rules, ruleErr := r.getRulesFromSelections(selected, pb)
 	if ruleErr != nil && !common.IsRetriable(ruleErr) {
 		// Surface the error.
 		suerr := r.handleTailoredProfileStatusError(instance, ruleErr)
@@ -187,15 +277,102 @@ func (r *ReconcileTailoredProfile) Reconcile(request reconcile.Request) (reconci
 		return reconcile.Result{}, varErr
 	}
 
+	customRules, customRuleErr := r.getCustomRules(instance, p, customRuleNames(instance.Spec.CustomRules))
+	if customRuleErr != nil && !common.IsRetriable(customRuleErr) {
+		// Surface the error.
+		suerr := r.handleTailoredProfileStatusError(instance, customRuleErr)
+		return reconcile.Result{}, suerr
+	} else if customRuleErr != nil {
+		return reconcile.Result{}, customRuleErr
+	}
+
 	// Get tailored profile config map
 	tpcm := newTailoredProfileCM(instance)
 
-	tpcm.Data[tailoringFile], err = xccdf.TailoredProfileToXML(instance, p, pb, rules, variables)
+	tpcm.Data[tailoringFile], err = xccdf.TailoredProfileToXML(selected, p, pb, rules, variables)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := setCustomRulesData(tpcm, customRules); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	return r.ensureOutputObject(instance, tpcm, resolved, reqLogger)
+}
+
+// reconcileComposed writes the flattened rule selections and variable
+// values of a TailoredProfile that extends several profiles to its
+// output ConfigMap
+func (r *ReconcileTailoredProfile) reconcileComposed(instance *cmpv1alpha1.TailoredProfile, logger logr.Logger) (reconcile.Result, error) {
+	if instance.Spec.Extends != "" {
+		err := common.NewNonRetriableCtrlError("extends and extendsProfiles can't be used together")
+		return reconcile.Result{}, r.handleTailoredProfileStatusError(instance, err)
+	}
+
+	composed, err := r.composeProfile(instance)
+	if err != nil && !common.IsRetriable(err) {
+		return reconcile.Result{}, r.handleTailoredProfileStatusError(instance, err)
+	} else if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// Make TailoredProfile be owned by the ProfileBundle, it depends on
+	// several Profiles. This update will trigger a requeue with the new
+	// object.
+	if needsControllerRef(instance) {
+		tpCopy := instance.DeepCopy()
+		anns := tpCopy.GetAnnotations()
+		if anns == nil {
+			anns = make(map[string]string)
+		}
+		if _, ok := anns[cmpv1alpha1.ProductTypeAnnotation]; !ok && composed.base != nil {
+			if productType, ok := composed.base.Annotations[cmpv1alpha1.ProductTypeAnnotation]; ok {
+				anns[cmpv1alpha1.ProductTypeAnnotation] = productType
+				tpCopy.SetAnnotations(anns)
+			}
+		}
+		return r.setOwnership(tpCopy, composed.pb)
+	}
+
+	enabled, disabled, err := r.getComposedRules(instance, composed)
+	if err != nil && !common.IsRetriable(err) {
+		return reconcile.Result{}, r.handleTailoredProfileStatusError(instance, err)
+	} else if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	rules := make(map[string]*cmpv1alpha1.Rule, len(enabled))
+	for _, rule := range enabled {
+		rules[rule.Name] = rule
+	}
+	if ruleValidErr := assertValidRuleTypes(rules); ruleValidErr != nil {
+		return reconcile.Result{}, r.handleTailoredProfileStatusError(instance, ruleValidErr)
+	}
+
+	variables, err := r.getComposedVariables(instance, composed)
+	if err != nil && !common.IsRetriable(err) {
+		return reconcile.Result{}, r.handleTailoredProfileStatusError(instance, err)
+	} else if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	customRules, err := r.getCustomRules(instance, composed.base, sortedBoolKeys(composed.customRules))
+	if err != nil && !common.IsRetriable(err) {
+		return reconcile.Result{}, r.handleTailoredProfileStatusError(instance, err)
+	} else if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	tpcm := newTailoredProfileCM(instance)
+	tpcm.Data[tailoringFile], err = xccdf.ComposedProfileToXML(instance, composed.base, composed.pb, enabled, disabled, variables)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
+	if err := setCustomRulesData(tpcm, customRules); err != nil {
+		return reconcile.Result{}, err
+	}
 
-	return r.ensureOutputObject(instance, tpcm, reqLogger)
+	return r.ensureOutputObject(instance, tpcm, composed.resolved, logger)
 }
 
 // getProfileInfoFromExtends gets the Profile and ProfileBundle where the rules come from
@@ -203,7 +380,7 @@ func (r *ReconcileTailoredProfile) Reconcile(request reconcile.Request) (reconci
 func (r *ReconcileTailoredProfile) getProfileInfoFromExtends(tp *cmpv1alpha1.TailoredProfile) (*cmpv1alpha1.Profile, *cmpv1alpha1.ProfileBundle, error) {
 	p := &cmpv1alpha1.Profile{}
 	// Get the Profile being extended
-	err := r.client.Get(context.TODO(), types.NamespacedName{Name: tp.Spec.Extends, Namespace: tp.Namespace}, p)
+	err := r.getContent(tp, "Profile", types.NamespacedName{Name: tp.Spec.Extends, Namespace: tp.Namespace}, p)
 	if kerrors.IsNotFound(err) {
 		return nil, nil, common.NewNonRetriableCtrlError("fetching profile to be extended: %w", err)
 	}
@@ -225,7 +402,7 @@ func (r *ReconcileTailoredProfile) getProfileBundleFromRulesOrVars(tp *cmpv1alph
 	for _, selection := range append(tp.Spec.EnableRules, tp.Spec.DisableRules...) {
 		rule := &cmpv1alpha1.Rule{}
 		ruleKey := types.NamespacedName{Name: selection.Name, Namespace: tp.Namespace}
-		geterr := r.client.Get(context.TODO(), ruleKey, rule)
+		geterr := r.getContent(tp, "Rule", ruleKey, rule)
 		if geterr != nil {
 			// We'll validate this later in the Reconcile loop
 			if kerrors.IsNotFound(geterr) {
@@ -249,7 +426,7 @@ func (r *ReconcileTailoredProfile) getProfileBundleFromRulesOrVars(tp *cmpv1alph
 	for _, setValues := range tp.Spec.SetValues {
 		variable := &cmpv1alpha1.Variable{}
 		varKey := types.NamespacedName{Name: setValues.Name, Namespace: tp.Namespace}
-		err := r.client.Get(context.TODO(), varKey, variable)
+		err := r.getContent(tp, "Variable", varKey, variable)
 		if err != nil {
 			// We'll verify this later in the reconcile loop
 			if kerrors.IsNotFound(err) {
@@ -283,7 +460,7 @@ func (r *ReconcileTailoredProfile) getRulesFromSelections(tp *cmpv1alpha1.Tailor
 		}
 		rule := &cmpv1alpha1.Rule{}
 		ruleKey := types.NamespacedName{Name: selection.Name, Namespace: tp.Namespace}
-		err := r.client.Get(context.TODO(), ruleKey, rule)
+		err := r.getContent(tp, "Rule", ruleKey, rule)
 		if err != nil {
 			if kerrors.IsNotFound(err) {
 				return nil, common.NewNonRetriableCtrlError("Fetching rule: %w", err)
@@ -307,7 +484,7 @@ func (r *ReconcileTailoredProfile) getVariablesFromSelections(tp *cmpv1alpha1.Ta
 	for _, setValues := range tp.Spec.SetValues {
 		variable := &cmpv1alpha1.Variable{}
 		varKey := types.NamespacedName{Name: setValues.Name, Namespace: tp.Namespace}
-		err := r.client.Get(context.TODO(), varKey, variable)
+		err := r.getContent(tp, "Variable", varKey, variable)
 		if err != nil {
 			if kerrors.IsNotFound(err) {
 				return nil, common.NewNonRetriableCtrlError("fetching variable: %w", err)
@@ -332,7 +509,32 @@ func (r *ReconcileTailoredProfile) getVariablesFromSelections(tp *cmpv1alpha1.Ta
 	return variableList, nil
 }
 
-func (r *ReconcileTailoredProfile) updateTailoredProfileStatusReady(tp *cmpv1alpha1.TailoredProfile, out metav1.Object) error {
+// getContent fetches a profile, rule or variable. If the TailoredProfile is
+// pinned to a content version, the object is fetched as parsed from it.
+// Archived copies can't be referenced directly and aren't found.
+func (r *ReconcileTailoredProfile) getContent(tp *cmpv1alpha1.TailoredProfile, kind string, key types.NamespacedName, obj runtime.Object) error {
+	digest := tp.GetPinnedContentDigest()
+	if digest == "" {
+		if err := r.client.Get(context.TODO(), key, obj); err != nil {
+			return err
+		}
+		if accessor, err := meta.Accessor(obj); err == nil && cmpv1alpha1.IsArchivedContent(accessor) {
+			return kerrors.NewNotFound(schema.GroupResource{
+				Group:    cmpv1alpha1.SchemeGroupVersion.Group,
+				Resource: kind,
+			}, key.Name)
+		}
+		return nil
+	}
+
+	pinned, err := common.GetPinnedContent(r.client, kind, key, digest)
+	if err != nil {
+		return err
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(pinned.Object, obj)
+}
+
+func (r *ReconcileTailoredProfile) updateTailoredProfileStatusReady(tp *cmpv1alpha1.TailoredProfile, out metav1.Object, resolved *cmpv1alpha1.ResolvedRuleSelections) error {
 	// Never update the original (update the copy)
 	tpCopy := tp.DeepCopy()
 	tpCopy.Status.State = cmpv1alpha1.TailoredProfileStateReady
@@ -342,6 +544,7 @@ func (r *ReconcileTailoredProfile) updateTailoredProfileStatusReady(tp *cmpv1alp
 		Namespace: out.GetNamespace(),
 	}
 	tpCopy.Status.ID = xccdf.GetXCCDFProfileID(tp)
+	tpCopy.Status.ResolvedRules = resolved
 	return r.client.Status().Update(context.TODO(), tpCopy)
 }
 
@@ -393,7 +596,7 @@ func (r *ReconcileTailoredProfile) deleteOutputObject(tp *cmpv1alpha1.TailoredPr
 	return nil
 }
 
-func (r *ReconcileTailoredProfile) ensureOutputObject(tp *cmpv1alpha1.TailoredProfile, tpcm *corev1.ConfigMap, logger logr.Logger) (reconcile.Result, error) {
+func (r *ReconcileTailoredProfile) ensureOutputObject(tp *cmpv1alpha1.TailoredProfile, tpcm *corev1.ConfigMap, resolved *cmpv1alpha1.ResolvedRuleSelections, logger logr.Logger) (reconcile.Result, error) {
 	// Set TailoredProfile instance as the owner and controller
 	if err := controllerutil.SetControllerReference(tp, tpcm, r.scheme); err != nil {
 		return reconcile.Result{}, err
@@ -404,9 +607,9 @@ func (r *ReconcileTailoredProfile) ensureOutputObject(tp *cmpv1alpha1.TailoredPr
 	err := r.client.Get(context.TODO(), types.NamespacedName{Name: tpcm.Name, Namespace: tpcm.Namespace}, found)
 	if err != nil && kerrors.IsNotFound(err) {
 		// update status
-		err = r.updateTailoredProfileStatusReady(tp, tpcm)
+		err = r.updateTailoredProfileStatusReady(tp, tpcm, resolved)
 		if err != nil {
-			fmt.Printf("Couldn't update TailoredProfile status: %v\n", err)
+			logger.Error(err, "Couldn't update TailoredProfile status")
 			return reconcile.Result{}, err
 		}
 
@@ -428,10 +631,19 @@ func (r *ReconcileTailoredProfile) ensureOutputObject(tp *cmpv1alpha1.TailoredPr
 	update.Data = tpcm.Data
 	err = r.client.Update(context.TODO(), update)
 	if err != nil {
-		fmt.Printf("Couldn't update TailoredProfile configMap: %v\n", err)
+		logger.Error(err, "Couldn't update TailoredProfile configMap")
 		return reconcile.Result{}, err
 	}
 
+	// The rules matched by selectors change along with the content
+	if !reflect.DeepEqual(tp.Status.ResolvedRules, resolved) {
+		err = r.updateTailoredProfileStatusReady(tp, tpcm, resolved)
+		if err != nil {
+			logger.Error(err, "Couldn't update TailoredProfile status")
+			return reconcile.Result{}, err
+		}
+	}
+
 	logger.Info("Skip reconcile: ConfigMap already exists and is up-to-date", "ConfigMap.Namespace", found.Namespace, "ConfigMap.Name", found.Name)
 	return reconcile.Result{}, nil
 }
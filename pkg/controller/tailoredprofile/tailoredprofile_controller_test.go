@@ -2,6 +2,7 @@ package tailoredprofile
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 
@@ -18,6 +19,7 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	cmpv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
@@ -94,11 +96,13 @@ var _ = Describe("TailoredprofileController", func() {
 
 			// Rules and Variables 1, 2, 3, 4 are owned by pb1
 			if i < 5 {
+				r.Labels = map[string]string{compv1alpha1.ProfileBundleOwnerLabel: pb1.Name}
 				crefErr := controllerutil.SetControllerReference(pb1, r, cscheme)
 				Expect(crefErr).To(BeNil())
 				crefErr = controllerutil.SetControllerReference(pb1, v, cscheme)
 				Expect(crefErr).To(BeNil())
 			} else {
+				r.Labels = map[string]string{compv1alpha1.ProfileBundleOwnerLabel: pb2.Name}
 				crefErr := controllerutil.SetControllerReference(pb2, r, cscheme)
 				Expect(crefErr).To(BeNil())
 				crefErr = controllerutil.SetControllerReference(pb2, v, cscheme)
@@ -122,6 +126,698 @@ var _ = Describe("TailoredprofileController", func() {
 		r = &ReconcileTailoredProfile{client: client, scheme: cscheme, metrics: mockMetrics}
 	})
 
+	When("extending several profiles", func() {
+		var tpName = "composed"
+		BeforeEach(func() {
+			pb1 := &compv1alpha1.ProfileBundle{}
+			Expect(r.client.Get(ctx, types.NamespacedName{Name: "pb-1", Namespace: namespace}, pb1)).To(Succeed())
+			otherProfile := &compv1alpha1.Profile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "other-profile",
+					Namespace: namespace,
+				},
+				ProfilePayload: compv1alpha1.ProfilePayload{
+					ID: "profile_2",
+					Rules: []compv1alpha1.ProfileRule{
+						"rule-3",
+						"rule-4",
+					},
+				},
+			}
+			Expect(controllerutil.SetControllerReference(pb1, otherProfile, r.scheme)).To(Succeed())
+			Expect(r.client.Create(ctx, otherProfile)).To(Succeed())
+
+			baseTP := &compv1alpha1.TailoredProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "base-tailoring",
+					Namespace: namespace,
+				},
+				Spec: compv1alpha1.TailoredProfileSpec{
+					Extends: profileName,
+					DisableRules: []compv1alpha1.RuleReferenceSpec{
+						{
+							Name:      "rule-1",
+							Rationale: "Why not",
+						},
+					},
+					SetValues: []compv1alpha1.VariableValueSpec{
+						{
+							Name:  "var-1",
+							Value: "1",
+						},
+					},
+				},
+			}
+			Expect(r.client.Create(ctx, baseTP)).To(Succeed())
+
+			tp := &compv1alpha1.TailoredProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      tpName,
+					Namespace: namespace,
+				},
+				Spec: compv1alpha1.TailoredProfileSpec{
+					ExtendsProfiles: []compv1alpha1.ExtendedProfileReference{
+						{
+							Name: "base-tailoring",
+							Kind: "TailoredProfile",
+						},
+						{
+							Name: "other-profile",
+						},
+					},
+					DisableRules: []compv1alpha1.RuleReferenceSpec{
+						{
+							Name:      "rule-4",
+							Rationale: "Why not",
+						},
+					},
+					SetValues: []compv1alpha1.VariableValueSpec{
+						{
+							Name:  "var-1",
+							Value: "2",
+						},
+					},
+				},
+			}
+			Expect(r.client.Create(ctx, tp)).To(Succeed())
+		})
+
+		It("merges the rules and variables of all of them", func() {
+			tpKey := types.NamespacedName{
+				Name:      tpName,
+				Namespace: namespace,
+			}
+			tpReq := reconcile.Request{}
+			tpReq.Name = tpName
+			tpReq.Namespace = namespace
+
+			By("Reconciling the first time (setting ownership)")
+			_, err := r.Reconcile(tpReq)
+			Expect(err).To(BeNil())
+
+			tp := &compv1alpha1.TailoredProfile{}
+			Expect(r.client.Get(ctx, tpKey, tp)).To(Succeed())
+
+			By("Sets the profile bundle as the owner")
+			ownerRefs := tp.GetOwnerReferences()
+			Expect(ownerRefs).To(HaveLen(1))
+			Expect(ownerRefs[0].Kind).To(Equal("ProfileBundle"))
+
+			By("Reconciling a second time")
+			_, err = r.Reconcile(tpReq)
+			Expect(err).To(BeNil())
+			Expect(r.client.Get(ctx, tpKey, tp)).To(Succeed())
+			Expect(tp.Status.State).To(Equal(compv1alpha1.TailoredProfileStateReady))
+
+			By("Generated a tailoring that extends the first profile found")
+			cm := &corev1.ConfigMap{}
+			cmKey := types.NamespacedName{
+				Name:      tp.Status.OutputRef.Name,
+				Namespace: tp.Status.OutputRef.Namespace,
+			}
+			Expect(r.client.Get(ctx, cmKey, cm)).To(Succeed())
+			data := cm.Data["tailoring.xml"]
+			Expect(data).To(ContainSubstring(`extends="profile_1"`))
+			Expect(data).To(ContainSubstring(`select idref="rule_1" selected="false"`))
+			Expect(data).To(ContainSubstring(`select idref="rule_3" selected="true"`))
+			Expect(data).NotTo(ContainSubstring(`rule_2`))
+			Expect(data).NotTo(ContainSubstring(`rule_4`))
+			Expect(data).To(ContainSubstring(`idref="var_1">2<`))
+		})
+
+		It("carries the variable values of the extended profiles", func() {
+			base := &compv1alpha1.Profile{}
+			Expect(r.client.Get(ctx, types.NamespacedName{Name: profileName, Namespace: namespace}, base)).To(Succeed())
+			base.RefinedValues = []compv1alpha1.ProfileRefinedValue{
+				{Variable: "var-2", Value: "2", Profile: profileName},
+				{Variable: "var-3", Value: "3", Profile: profileName},
+			}
+			Expect(r.client.Update(ctx, base)).To(Succeed())
+
+			other := &compv1alpha1.Profile{}
+			Expect(r.client.Get(ctx, types.NamespacedName{Name: "other-profile", Namespace: namespace}, other)).To(Succeed())
+			other.RefinedValues = []compv1alpha1.ProfileRefinedValue{
+				{Variable: "var-2", Value: "22", Profile: "other-profile"},
+				{Variable: "var-4", Value: "4", Profile: "other-profile"},
+			}
+			Expect(r.client.Update(ctx, other)).To(Succeed())
+
+			tpReq := reconcile.Request{}
+			tpReq.Name = tpName
+			tpReq.Namespace = namespace
+			for i := 0; i < 2; i++ {
+				_, err := r.Reconcile(tpReq)
+				Expect(err).To(BeNil())
+			}
+
+			tp := &compv1alpha1.TailoredProfile{}
+			Expect(r.client.Get(ctx, types.NamespacedName{Name: tpName, Namespace: namespace}, tp)).To(Succeed())
+			Expect(tp.Status.State).To(Equal(compv1alpha1.TailoredProfileStateReady))
+			cm := &corev1.ConfigMap{}
+			cmKey := types.NamespacedName{
+				Name:      tp.Status.OutputRef.Name,
+				Namespace: tp.Status.OutputRef.Namespace,
+			}
+			Expect(r.client.Get(ctx, cmKey, cm)).To(Succeed())
+			data := cm.Data["tailoring.xml"]
+			By("overriding the values of the base profile")
+			Expect(data).To(ContainSubstring(`idref="var_2">22<`))
+			By("setting the values of the other profiles")
+			Expect(data).To(ContainSubstring(`idref="var_4">4<`))
+			By("leaving out the values OpenSCAP inherits from the base profile")
+			Expect(data).NotTo(ContainSubstring(`var_3`))
+			By("keeping the values the TailoredProfiles set")
+			Expect(data).To(ContainSubstring(`idref="var_1">2<`))
+		})
+
+		It("selects the rules the extended profiles inherit", func() {
+			other := &compv1alpha1.Profile{}
+			Expect(r.client.Get(ctx, types.NamespacedName{Name: "other-profile", Namespace: namespace}, other)).To(Succeed())
+			other.Rules = []compv1alpha1.ProfileRule{"rule-4"}
+			other.Extends = []string{"parent-profile"}
+			other.RuleSources = []compv1alpha1.ProfileRuleSource{
+				{Rule: "rule-3", Profile: "parent-profile"},
+				{Rule: "rule-4", Profile: "other-profile"},
+			}
+			Expect(r.client.Update(ctx, other)).To(Succeed())
+
+			tpReq := reconcile.Request{}
+			tpReq.Name = tpName
+			tpReq.Namespace = namespace
+			for i := 0; i < 2; i++ {
+				_, err := r.Reconcile(tpReq)
+				Expect(err).To(BeNil())
+			}
+
+			tp := &compv1alpha1.TailoredProfile{}
+			Expect(r.client.Get(ctx, types.NamespacedName{Name: tpName, Namespace: namespace}, tp)).To(Succeed())
+			Expect(tp.Status.State).To(Equal(compv1alpha1.TailoredProfileStateReady))
+			cm := &corev1.ConfigMap{}
+			cmKey := types.NamespacedName{
+				Name:      tp.Status.OutputRef.Name,
+				Namespace: tp.Status.OutputRef.Namespace,
+			}
+			Expect(r.client.Get(ctx, cmKey, cm)).To(Succeed())
+			Expect(cm.Data["tailoring.xml"]).To(ContainSubstring(`select idref="rule_3" selected="true"`))
+		})
+
+		It("enqueues the TailoredProfiles extending a changed one transitively", func() {
+			top := &compv1alpha1.TailoredProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "top",
+					Namespace: namespace,
+				},
+				Spec: compv1alpha1.TailoredProfileSpec{
+					ExtendsProfiles: []compv1alpha1.ExtendedProfileReference{
+						{
+							Name: tpName,
+							Kind: "TailoredProfile",
+						},
+					},
+				},
+			}
+			Expect(r.client.Create(ctx, top)).To(Succeed())
+
+			baseTP := &compv1alpha1.TailoredProfile{}
+			Expect(r.client.Get(ctx, types.NamespacedName{Name: "base-tailoring", Namespace: namespace}, baseTP)).To(Succeed())
+			mapper := &extendedProfileMapper{r.client}
+			requests := mapper.Map(handler.MapObject{Meta: baseTP, Object: baseTP})
+			Expect(requests).To(ConsistOf(
+				reconcile.Request{NamespacedName: types.NamespacedName{Name: tpName, Namespace: namespace}},
+				reconcile.Request{NamespacedName: types.NamespacedName{Name: "top", Namespace: namespace}},
+			))
+		})
+
+		It("reports an error when TailoredProfiles extend each other", func() {
+			baseTP := &compv1alpha1.TailoredProfile{}
+			Expect(r.client.Get(ctx, types.NamespacedName{Name: "base-tailoring", Namespace: namespace}, baseTP)).To(Succeed())
+			baseTP.Spec.Extends = ""
+			baseTP.Spec.ExtendsProfiles = []compv1alpha1.ExtendedProfileReference{
+				{
+					Name: tpName,
+					Kind: "TailoredProfile",
+				},
+			}
+			Expect(r.client.Update(ctx, baseTP)).To(Succeed())
+
+			tpReq := reconcile.Request{}
+			tpReq.Name = tpName
+			tpReq.Namespace = namespace
+			_, err := r.Reconcile(tpReq)
+			Expect(err).To(BeNil())
+
+			tp := &compv1alpha1.TailoredProfile{}
+			Expect(r.client.Get(ctx, types.NamespacedName{Name: tpName, Namespace: namespace}, tp)).To(Succeed())
+			Expect(tp.Status.State).To(Equal(compv1alpha1.TailoredProfileStateError))
+			Expect(tp.Status.ErrorMessage).To(ContainSubstring("composed -> base-tailoring -> composed"))
+		})
+	})
+
+	When("selecting rules with selectors", func() {
+		var tpName = "selected"
+		const nistAnnotation = "control.compliance.openshift.io/NIST-800-53"
+
+		setRuleAttributes := func(name, severity, controls string) {
+			rule := &compv1alpha1.Rule{}
+			Expect(r.client.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, rule)).To(Succeed())
+			rule.Severity = severity
+			if controls != "" {
+				rule.Annotations = map[string]string{nistAnnotation: controls}
+			}
+			Expect(r.client.Update(ctx, rule)).To(Succeed())
+		}
+
+		reconcileTwice := func() *compv1alpha1.TailoredProfile {
+			tpReq := reconcile.Request{}
+			tpReq.Name = tpName
+			tpReq.Namespace = namespace
+			for i := 0; i < 2; i++ {
+				_, err := r.Reconcile(tpReq)
+				Expect(err).To(BeNil())
+			}
+			tp := &compv1alpha1.TailoredProfile{}
+			Expect(r.client.Get(ctx, types.NamespacedName{Name: tpName, Namespace: namespace}, tp)).To(Succeed())
+			return tp
+		}
+
+		BeforeEach(func() {
+			setRuleAttributes("rule-1", "high", "AC-2;AC-3")
+			setRuleAttributes("rule-2", "medium", "CM-6")
+			setRuleAttributes("rule-3", "high", "AC-7(a)")
+			setRuleAttributes("rule-4", "low", "")
+			setRuleAttributes("rule-5", "high", "AC-2")
+		})
+
+		It("resolves the selectors to the rules of the extended profile's bundle", func() {
+			tp := &compv1alpha1.TailoredProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      tpName,
+					Namespace: namespace,
+				},
+				Spec: compv1alpha1.TailoredProfileSpec{
+					Extends: profileName,
+					EnableRules: []compv1alpha1.RuleReferenceSpec{
+						{
+							Selector: &compv1alpha1.RuleSelectorSpec{
+								MatchAnnotations: map[string]string{nistAnnotation: "AC-*"},
+							},
+							Rationale: "Access control",
+						},
+					},
+					DisableRules: []compv1alpha1.RuleReferenceSpec{
+						{
+							Name:      "rule-1",
+							Rationale: "Why not",
+						},
+						{
+							Selector: &compv1alpha1.RuleSelectorSpec{
+								Severities: []string{"low"},
+							},
+							Rationale: "Not worth it",
+						},
+					},
+				},
+			}
+			Expect(r.client.Create(ctx, tp)).To(Succeed())
+
+			tp = reconcileTwice()
+			Expect(tp.Status.State).To(Equal(compv1alpha1.TailoredProfileStateReady))
+			Expect(tp.Status.ResolvedRules).NotTo(BeNil())
+			Expect(tp.Status.ResolvedRules.Enabled).To(Equal([]string{"rule-3"}))
+			Expect(tp.Status.ResolvedRules.Disabled).To(Equal([]string{"rule-4"}))
+
+			By("Keeping the selectors in the spec")
+			Expect(tp.Spec.EnableRules).To(HaveLen(1))
+			Expect(tp.Spec.EnableRules[0].Selector).NotTo(BeNil())
+
+			cm := &corev1.ConfigMap{}
+			cmKey := types.NamespacedName{
+				Name:      tp.Status.OutputRef.Name,
+				Namespace: tp.Status.OutputRef.Namespace,
+			}
+			Expect(r.client.Get(ctx, cmKey, cm)).To(Succeed())
+			data := cm.Data["tailoring.xml"]
+			Expect(data).To(ContainSubstring(`select idref="rule_1" selected="false"`))
+			Expect(data).To(ContainSubstring(`select idref="rule_3" selected="true"`))
+			Expect(data).To(ContainSubstring(`select idref="rule_4" selected="false"`))
+			Expect(data).NotTo(ContainSubstring(`rule_5`))
+
+			By("Resolving the selectors again when the rules change")
+			setRuleAttributes("rule-2", "medium", "AC-17")
+			tp = reconcileTwice()
+			Expect(tp.Status.ResolvedRules.Enabled).To(Equal([]string{"rule-2", "rule-3"}))
+		})
+
+		It("resolves the selectors of a TailoredProfile written from scratch", func() {
+			tp := &compv1alpha1.TailoredProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      tpName,
+					Namespace: namespace,
+				},
+				Spec: compv1alpha1.TailoredProfileSpec{
+					EnableRules: []compv1alpha1.RuleReferenceSpec{
+						{
+							Selector: &compv1alpha1.RuleSelectorSpec{
+								CheckType: compv1alpha1.CheckTypePlatform,
+							},
+							Rationale: "Platform rules",
+						},
+					},
+				},
+			}
+			Expect(r.client.Create(ctx, tp)).To(Succeed())
+
+			tp = reconcileTwice()
+			Expect(tp.Status.State).To(Equal(compv1alpha1.TailoredProfileStateReady))
+			Expect(tp.GetOwnerReferences()[0].Name).To(Equal("pb-2"))
+			Expect(tp.Status.ResolvedRules.Enabled).To(Equal([]string{"rule-5", "rule-6"}))
+		})
+
+		It("only matches the rules of the product and product type of the TailoredProfile", func() {
+			pb2 := &compv1alpha1.ProfileBundle{}
+			Expect(r.client.Get(ctx, types.NamespacedName{Name: "pb-2", Namespace: namespace}, pb2)).To(Succeed())
+			nodeProfile := &compv1alpha1.Profile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "node-profile",
+					Namespace: namespace,
+					Labels:    map[string]string{compv1alpha1.ProfileBundleOwnerLabel: pb2.Name},
+					Annotations: map[string]string{
+						compv1alpha1.ProductAnnotation: "rhcos4",
+					},
+				},
+				ProfilePayload: compv1alpha1.ProfilePayload{
+					ID: "profile_node",
+				},
+			}
+			Expect(controllerutil.SetControllerReference(pb2, nodeProfile, r.scheme)).To(Succeed())
+			Expect(r.client.Create(ctx, nodeProfile)).To(Succeed())
+			setRuleAttributes("rule-8", "high", "")
+
+			tp := &compv1alpha1.TailoredProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      tpName,
+					Namespace: namespace,
+					Annotations: map[string]string{
+						compv1alpha1.ProductAnnotation:     "rhcos4",
+						compv1alpha1.ProductTypeAnnotation: string(compv1alpha1.ScanTypeNode),
+					},
+				},
+				Spec: compv1alpha1.TailoredProfileSpec{
+					EnableRules: []compv1alpha1.RuleReferenceSpec{
+						{
+							Selector: &compv1alpha1.RuleSelectorSpec{
+								Severities: []string{"high"},
+							},
+							Rationale: "High severity",
+						},
+					},
+				},
+			}
+			Expect(r.client.Create(ctx, tp)).To(Succeed())
+
+			tp = reconcileTwice()
+			Expect(tp.Status.State).To(Equal(compv1alpha1.TailoredProfileStateReady))
+			Expect(tp.GetOwnerReferences()[0].Name).To(Equal("pb-2"))
+			// rule-5 is a high severity Platform rule
+			Expect(tp.Status.ResolvedRules.Enabled).To(Equal([]string{"rule-8"}))
+		})
+
+		It("reports an error when the selectors match rules of several bundles", func() {
+			tp := &compv1alpha1.TailoredProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      tpName,
+					Namespace: namespace,
+				},
+				Spec: compv1alpha1.TailoredProfileSpec{
+					EnableRules: []compv1alpha1.RuleReferenceSpec{
+						{
+							Selector: &compv1alpha1.RuleSelectorSpec{
+								Severities: []string{"high"},
+							},
+							Rationale: "High severity",
+						},
+					},
+				},
+			}
+			Expect(r.client.Create(ctx, tp)).To(Succeed())
+
+			tp = reconcileTwice()
+			Expect(tp.Status.State).To(Equal(compv1alpha1.TailoredProfileStateError))
+			Expect(tp.Status.ErrorMessage).To(ContainSubstring(compv1alpha1.ProfileBundleOwnerLabel))
+		})
+	})
+
+	When("enabling CustomRules", func() {
+		var tpName = "custom"
+
+		reconcileTwice := func() *compv1alpha1.TailoredProfile {
+			tpReq := reconcile.Request{}
+			tpReq.Name = tpName
+			tpReq.Namespace = namespace
+			for i := 0; i < 2; i++ {
+				_, err := r.Reconcile(tpReq)
+				Expect(err).To(BeNil())
+			}
+			tp := &compv1alpha1.TailoredProfile{}
+			Expect(r.client.Get(ctx, types.NamespacedName{Name: tpName, Namespace: namespace}, tp)).To(Succeed())
+			return tp
+		}
+
+		BeforeEach(func() {
+			customRule := &compv1alpha1.CustomRule{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "custom-rule",
+					Namespace: namespace,
+				},
+				Spec: compv1alpha1.CustomRuleSpec{
+					Title: "The default namespace is labeled",
+					Input: compv1alpha1.CustomRuleInput{
+						APIPath: "/api/v1/namespaces/default",
+						Filter:  ".metadata.labels",
+					},
+					Expression: `.secure == "true"`,
+				},
+			}
+			Expect(r.client.Create(ctx, customRule)).To(Succeed())
+		})
+
+		It("copies the CustomRules to the output ConfigMap", func() {
+			tp := &compv1alpha1.TailoredProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      tpName,
+					Namespace: namespace,
+				},
+				Spec: compv1alpha1.TailoredProfileSpec{
+					Extends:     profileName,
+					CustomRules: []compv1alpha1.RuleReferenceSpec{{Name: "custom-rule"}},
+				},
+			}
+			Expect(r.client.Create(ctx, tp)).To(Succeed())
+
+			tp = reconcileTwice()
+			Expect(tp.Status.State).To(Equal(compv1alpha1.TailoredProfileStateReady))
+
+			cm := &corev1.ConfigMap{}
+			cmKey := types.NamespacedName{Name: tp.Status.OutputRef.Name, Namespace: tp.Status.OutputRef.Namespace}
+			Expect(r.client.Get(ctx, cmKey, cm)).To(Succeed())
+			Expect(cm.Data).To(HaveKey(compv1alpha1.CustomRulesTailoringKey))
+
+			var customRules []compv1alpha1.CustomRule
+			Expect(json.Unmarshal([]byte(cm.Data[compv1alpha1.CustomRulesTailoringKey]), &customRules)).To(Succeed())
+			Expect(customRules).To(HaveLen(1))
+			Expect(customRules[0].Name).To(Equal("custom-rule"))
+			Expect(customRules[0].Spec.Input.Filter).To(Equal(".metadata.labels"))
+		})
+
+		It("reports an error when the CustomRule doesn't exist", func() {
+			tp := &compv1alpha1.TailoredProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      tpName,
+					Namespace: namespace,
+				},
+				Spec: compv1alpha1.TailoredProfileSpec{
+					Extends:     profileName,
+					CustomRules: []compv1alpha1.RuleReferenceSpec{{Name: "nonexistent"}},
+				},
+			}
+			Expect(r.client.Create(ctx, tp)).To(Succeed())
+
+			tp = reconcileTwice()
+			Expect(tp.Status.State).To(Equal(compv1alpha1.TailoredProfileStateError))
+			Expect(tp.Status.ErrorMessage).To(ContainSubstring("nonexistent"))
+		})
+
+		It("reports an error for node TailoredProfiles", func() {
+			tp := &compv1alpha1.TailoredProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      tpName,
+					Namespace: namespace,
+					Annotations: map[string]string{
+						compv1alpha1.ProductTypeAnnotation: string(compv1alpha1.ScanTypeNode),
+					},
+				},
+				Spec: compv1alpha1.TailoredProfileSpec{
+					Extends:     profileName,
+					CustomRules: []compv1alpha1.RuleReferenceSpec{{Name: "custom-rule"}},
+				},
+			}
+			Expect(r.client.Create(ctx, tp)).To(Succeed())
+
+			tp = reconcileTwice()
+			Expect(tp.Status.State).To(Equal(compv1alpha1.TailoredProfileStateError))
+			Expect(tp.Status.ErrorMessage).To(ContainSubstring("Platform"))
+		})
+	})
+
+	When("pinned to a content version", func() {
+		const (
+			currentDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+			pinnedDigest  = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+		)
+		var tpName = "pinned"
+
+		archive := func(obj interface {
+			runtime.Object
+			metav1.Object
+		}, setOldContent func()) {
+			name := obj.GetName()
+			Expect(r.client.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, obj)).To(Succeed())
+			current := obj.DeepCopyObject().(interface {
+				runtime.Object
+				metav1.Object
+			})
+			current.SetAnnotations(map[string]string{
+				compv1alpha1.ProfileContentDigestAnnotation:  currentDigest,
+				compv1alpha1.ProfileContentDigestsAnnotation: currentDigest,
+			})
+			Expect(r.client.Update(ctx, current)).To(Succeed())
+
+			setOldContent()
+			obj.SetName(compv1alpha1.GetArchivedContentName(name, pinnedDigest))
+			obj.SetResourceVersion("")
+			obj.SetLabels(map[string]string{compv1alpha1.ArchivedContentLabel: "pb-1"})
+			obj.SetAnnotations(map[string]string{
+				compv1alpha1.ProfileContentDigestAnnotation:  pinnedDigest,
+				compv1alpha1.ProfileContentDigestsAnnotation: pinnedDigest,
+				compv1alpha1.ArchivedFromAnnotation:          name,
+			})
+			Expect(r.client.Create(ctx, obj)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			p := &compv1alpha1.Profile{ObjectMeta: metav1.ObjectMeta{Name: profileName}}
+			archive(p, func() { p.ID = "profile_1_old" })
+			rule := &compv1alpha1.Rule{ObjectMeta: metav1.ObjectMeta{Name: "rule-3"}}
+			archive(rule, func() { rule.ID = "rule_3_old" })
+		})
+
+		It("resolves the extended profile and rules as parsed from that version", func() {
+			tp := &compv1alpha1.TailoredProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      tpName,
+					Namespace: namespace,
+					Annotations: map[string]string{
+						compv1alpha1.PinnedContentDigestAnnotation: pinnedDigest,
+					},
+				},
+				Spec: compv1alpha1.TailoredProfileSpec{
+					Extends: profileName,
+					EnableRules: []compv1alpha1.RuleReferenceSpec{
+						{
+							Name:      "rule-3",
+							Rationale: "Why not",
+						},
+						{
+							Name:      "rule-4",
+							Rationale: "Why not",
+						},
+					},
+				},
+			}
+			Expect(r.client.Create(ctx, tp)).To(Succeed())
+
+			tpReq := reconcile.Request{NamespacedName: types.NamespacedName{Name: tpName, Namespace: namespace}}
+			_, err := r.Reconcile(tpReq)
+			Expect(err).To(BeNil())
+
+			By("Setting the bundle as the owner, as the Profile might be archived")
+			Expect(r.client.Get(ctx, tpReq.NamespacedName, tp)).To(Succeed())
+			ownerRefs := tp.GetOwnerReferences()
+			Expect(ownerRefs).To(HaveLen(1))
+			Expect(ownerRefs[0].Kind).To(Equal("ProfileBundle"))
+
+			_, err = r.Reconcile(tpReq)
+			Expect(err).To(BeNil())
+			Expect(r.client.Get(ctx, tpReq.NamespacedName, tp)).To(Succeed())
+			Expect(tp.Status.State).To(Equal(compv1alpha1.TailoredProfileStateReady))
+
+			cm := &corev1.ConfigMap{}
+			Expect(r.client.Get(ctx, types.NamespacedName{Name: tp.Status.OutputRef.Name, Namespace: namespace}, cm)).To(Succeed())
+			data := cm.Data["tailoring.xml"]
+			Expect(data).To(ContainSubstring(`extends="profile_1_old"`))
+			Expect(data).To(ContainSubstring(`select idref="rule_3_old" selected="true"`))
+			// Objects parsed before the content digests were recorded are
+			// the same in all versions
+			Expect(data).To(ContainSubstring(`select idref="rule_4" selected="true"`))
+		})
+
+		It("doesn't find archived copies when not pinned", func() {
+			tp := &compv1alpha1.TailoredProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      tpName,
+					Namespace: namespace,
+				},
+				Spec: compv1alpha1.TailoredProfileSpec{
+					Extends: compv1alpha1.GetArchivedContentName(profileName, pinnedDigest),
+				},
+			}
+			Expect(r.client.Create(ctx, tp)).To(Succeed())
+
+			tpReq := reconcile.Request{NamespacedName: types.NamespacedName{Name: tpName, Namespace: namespace}}
+			_, err := r.Reconcile(tpReq)
+			Expect(err).To(BeNil())
+			Expect(r.client.Get(ctx, tpReq.NamespacedName, tp)).To(Succeed())
+			Expect(tp.Status.State).To(Equal(compv1alpha1.TailoredProfileStateError))
+			Expect(tp.Status.ErrorMessage).To(ContainSubstring("not found"))
+		})
+
+		It("reports an error for rules that weren't part of that version", func() {
+			rule := &compv1alpha1.Rule{}
+			Expect(r.client.Get(ctx, types.NamespacedName{Name: "rule-4", Namespace: namespace}, rule)).To(Succeed())
+			rule.Annotations = map[string]string{compv1alpha1.ProfileContentDigestsAnnotation: currentDigest}
+			Expect(r.client.Update(ctx, rule)).To(Succeed())
+
+			tp := &compv1alpha1.TailoredProfile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      tpName,
+					Namespace: namespace,
+					Annotations: map[string]string{
+						compv1alpha1.PinnedContentDigestAnnotation: pinnedDigest,
+					},
+				},
+				Spec: compv1alpha1.TailoredProfileSpec{
+					Extends: profileName,
+					EnableRules: []compv1alpha1.RuleReferenceSpec{
+						{
+							Name:      "rule-4",
+							Rationale: "Why not",
+						},
+					},
+				},
+			}
+			Expect(r.client.Create(ctx, tp)).To(Succeed())
+
+			tpReq := reconcile.Request{NamespacedName: types.NamespacedName{Name: tpName, Namespace: namespace}}
+			for i := 0; i < 2; i++ {
+				_, err := r.Reconcile(tpReq)
+				Expect(err).To(BeNil())
+			}
+			Expect(r.client.Get(ctx, tpReq.NamespacedName, tp)).To(Succeed())
+			Expect(tp.Status.State).To(Equal(compv1alpha1.TailoredProfileStateError))
+			Expect(tp.Status.ErrorMessage).To(ContainSubstring("rule-4"))
+		})
+	})
+
 	When("extending a profile", func() {
 		var tpName = "tailoring"
 		BeforeEach(func() {
@@ -581,6 +1277,108 @@ var _ = Describe("TailoredprofileController", func() {
 		})
 	})
 
+	When("importing a tailoring file from a ConfigMap", func() {
+		var tpName = "imported"
+		var tpKey = types.NamespacedName{Name: tpName, Namespace: namespace}
+		var tpReq = reconcile.Request{NamespacedName: tpKey}
+		var tailoring = `<?xml version="1.0" encoding="UTF-8"?>
+<xccdf:Tailoring xmlns:xccdf="http://checklists.nist.gov/xccdf/1.2" id="xccdf_scap-workbench_tailoring_default">
+  <xccdf:benchmark href="ssg-ocp4-ds.xml"/>
+  <xccdf:version time="2021-06-01T10:00:00">1</xccdf:version>
+  <xccdf:Profile id="xccdf_org.ssgproject.content_profile_imported" extends="profile_1">
+    <xccdf:title override="true">Imported</xccdf:title>
+    <xccdf:select idref="rule_1" selected="false"/>
+    %s
+  </xccdf:Profile>
+</xccdf:Tailoring>
+`
+		BeforeEach(func() {
+			pb1 := &compv1alpha1.ProfileBundle{}
+			Expect(r.client.Get(ctx, types.NamespacedName{Name: "pb-1", Namespace: namespace}, pb1)).To(Succeed())
+			pb1.Status.DataStreamStatus = compv1alpha1.DataStreamValid
+			Expect(r.client.Status().Update(ctx, pb1)).To(Succeed())
+
+			p := &compv1alpha1.Profile{}
+			Expect(r.client.Get(ctx, types.NamespacedName{Name: profileName, Namespace: namespace}, p)).To(Succeed())
+			p.Labels = map[string]string{compv1alpha1.ProfileBundleOwnerLabel: pb1.Name}
+			Expect(r.client.Update(ctx, p)).To(Succeed())
+
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "workbench", Namespace: namespace},
+				Data: map[string]string{
+					"tailoring.xml": fmt.Sprintf(tailoring, `<xccdf:select idref="rule_3" selected="true"/>`),
+				},
+			}
+			Expect(r.client.Create(ctx, cm)).To(Succeed())
+
+			tp := &compv1alpha1.TailoredProfile{
+				ObjectMeta: metav1.ObjectMeta{Name: tpName, Namespace: namespace},
+				Spec: compv1alpha1.TailoredProfileSpec{
+					Title:       "Imported",
+					Description: "Imported from SCAP Workbench",
+					ImportTailoring: &compv1alpha1.TailoringImportSource{
+						ConfigMap:     "workbench",
+						ProfileBundle: pb1.Name,
+					},
+				},
+			}
+			Expect(r.client.Create(ctx, tp)).To(Succeed())
+		})
+
+		It("converts the tailoring into the spec and imports it again when it changes", func() {
+			_, err := r.Reconcile(tpReq)
+			Expect(err).To(BeNil())
+
+			tp := &compv1alpha1.TailoredProfile{}
+			Expect(r.client.Get(ctx, tpKey, tp)).To(Succeed())
+			Expect(tp.Spec.Extends).To(Equal(profileName))
+			Expect(tp.Spec.DisableRules).To(HaveLen(1))
+			Expect(tp.Spec.DisableRules[0].Name).To(Equal("rule-1"))
+			Expect(tp.Spec.EnableRules).To(HaveLen(1))
+			Expect(tp.Spec.EnableRules[0].Name).To(Equal("rule-3"))
+			Expect(tp.GetAnnotations()).To(HaveKey(compv1alpha1.ImportedTailoringDigestAnnotation))
+
+			By("Reconciling until the TailoredProfile is ready")
+			for i := 0; i < 2; i++ {
+				_, err = r.Reconcile(tpReq)
+				Expect(err).To(BeNil())
+			}
+			tp = &compv1alpha1.TailoredProfile{}
+			Expect(r.client.Get(ctx, tpKey, tp)).To(Succeed())
+			Expect(tp.Status.State).To(Equal(compv1alpha1.TailoredProfileStateReady))
+			Expect(tp.Spec.EnableRules).To(HaveLen(1))
+
+			By("Updating the tailoring file")
+			cm := &corev1.ConfigMap{}
+			Expect(r.client.Get(ctx, types.NamespacedName{Name: "workbench", Namespace: namespace}, cm)).To(Succeed())
+			cm.Data["tailoring.xml"] = fmt.Sprintf(tailoring, "")
+			Expect(r.client.Update(ctx, cm)).To(Succeed())
+
+			_, err = r.Reconcile(tpReq)
+			Expect(err).To(BeNil())
+			tp = &compv1alpha1.TailoredProfile{}
+			Expect(r.client.Get(ctx, tpKey, tp)).To(Succeed())
+			Expect(tp.Spec.EnableRules).To(BeEmpty())
+			Expect(tp.Spec.DisableRules).To(HaveLen(1))
+		})
+
+		It("reports an error if the tailoring doesn't match the bundle", func() {
+			cm := &corev1.ConfigMap{}
+			Expect(r.client.Get(ctx, types.NamespacedName{Name: "workbench", Namespace: namespace}, cm)).To(Succeed())
+			cm.Data["tailoring.xml"] = fmt.Sprintf(tailoring, `<xccdf:select idref="rule_5" selected="true"/>`)
+			Expect(r.client.Update(ctx, cm)).To(Succeed())
+
+			_, err := r.Reconcile(tpReq)
+			Expect(err).To(BeNil())
+
+			tp := &compv1alpha1.TailoredProfile{}
+			Expect(r.client.Get(ctx, tpKey, tp)).To(Succeed())
+			Expect(tp.Status.State).To(Equal(compv1alpha1.TailoredProfileStateError))
+			Expect(tp.Status.ErrorMessage).To(ContainSubstring("the rule rule_5 isn't part of the bundle"))
+			Expect(tp.Spec.Extends).To(BeEmpty())
+		})
+	})
+
 	When("Creating a custom TailoredProfile from scratch", func() {
 		var tpName = "tailoring"
 		Context("with all well-formed values", func() {
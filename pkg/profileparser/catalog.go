@@ -0,0 +1,129 @@
+package profileparser
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cmpv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+// controlCatalogBuilder collects the controls that the rules of a bundle
+// map to, from the control annotations of the rules. Rules are parsed
+// concurrently, hence the mutex.
+type controlCatalogBuilder struct {
+	mutex sync.Mutex
+	// framework -> control -> rules
+	controls map[string]map[string][]string
+}
+
+func newControlCatalogBuilder() *controlCatalogBuilder {
+	return &controlCatalogBuilder{
+		controls: make(map[string]map[string][]string),
+	}
+}
+
+// addRule records the controls the rule maps to. The rule is expected to
+// still have the name it was parsed with, the name of the bundle is added
+// as a prefix.
+func (b *controlCatalogBuilder) addRule(pb *cmpv1alpha1.ProfileBundle, rule *cmpv1alpha1.Rule) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	ruleName := GetPrefixedName(pb.Name, rule.Name)
+	for key, value := range rule.Annotations {
+		if !strings.HasPrefix(key, controlAnnotationBase) {
+			continue
+		}
+		framework := strings.TrimPrefix(key, controlAnnotationBase)
+		if b.controls[framework] == nil {
+			b.controls[framework] = make(map[string][]string)
+		}
+		for _, control := range strings.Split(value, ";") {
+			if control == "" {
+				continue
+			}
+			b.controls[framework][control] = append(b.controls[framework][control], ruleName)
+		}
+	}
+}
+
+// catalogs returns a ControlCatalog per framework, with the controls and
+// their rules sorted
+func (b *controlCatalogBuilder) catalogs(pb *cmpv1alpha1.ProfileBundle, nonce string) []*cmpv1alpha1.ControlCatalog {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	catalogs := make([]*cmpv1alpha1.ControlCatalog, 0, len(b.controls))
+	for framework, controls := range b.controls {
+		catalog := &cmpv1alpha1.ControlCatalog{
+			TypeMeta: metav1.TypeMeta{
+				Kind:       "ControlCatalog",
+				APIVersion: cmpv1alpha1.SchemeGroupVersion.String(),
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      strings.ToLower(framework),
+				Namespace: pb.Namespace,
+				Labels: map[string]string{
+					cmpv1alpha1.ControlCatalogFrameworkLabel: framework,
+				},
+			},
+			ControlCatalogPayload: cmpv1alpha1.ControlCatalogPayload{
+				Framework: framework,
+			},
+		}
+		for id, rules := range controls {
+			sort.Strings(rules)
+			catalog.Controls = append(catalog.Controls, cmpv1alpha1.ControlCatalogControl{
+				ID:    id,
+				Rules: rules,
+			})
+		}
+		sort.Slice(catalog.Controls, func(i, j int) bool {
+			return catalog.Controls[i].ID < catalog.Controls[j].ID
+		})
+		annotateWithNonce(catalog, nonce)
+		catalogs = append(catalogs, catalog)
+	}
+	sort.Slice(catalogs, func(i, j int) bool {
+		return catalogs[i].Name < catalogs[j].Name
+	})
+	return catalogs
+}
+
+// writeControlCatalogs creates or updates the catalogs of the bundle. As
+// they're derived from the rules, they're left out of the changelog.
+func writeControlCatalogs(builder *controlCatalogBuilder, pb *cmpv1alpha1.ProfileBundle, pcfg *ParserConfig, nonce string) error {
+	catalogCfg := *pcfg
+	catalogCfg.Changelog = nil
+
+	for _, catalog := range builder.catalogs(pb, nonce) {
+		err := parseAction(catalog, "ControlCatalog", pb, &catalogCfg, func(found, updated interface{}) error {
+			foundCatalog, ok := found.(*cmpv1alpha1.ControlCatalog)
+			if !ok {
+				return fmt.Errorf("unexpected type")
+			}
+			updatedCatalog, ok := updated.(*cmpv1alpha1.ControlCatalog)
+			if !ok {
+				return fmt.Errorf("unexpected type")
+			}
+
+			foundCatalog.Labels = updatedCatalog.Labels
+			foundCatalog.Annotations = updatedCatalog.Annotations
+			foundCatalog.ControlCatalogPayload = *updatedCatalog.ControlCatalogPayload.DeepCopy()
+			return pcfg.Client.Update(context.TODO(), foundCatalog)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := deleteObsoleteItems(&catalogCfg, "ControlCatalog", pb.Name, pb.Namespace, nonce); err != nil {
+		return err
+	}
+	return deleteUnretainedArchives(&catalogCfg, "ControlCatalog", pb.Name, pb.Namespace)
+}
@@ -0,0 +1,84 @@
+package profileparser
+
+import (
+	"strings"
+
+	"github.com/antchfx/xmlquery"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cmpv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+var _ = Describe("Building control catalogs", func() {
+	const ruleXML = `<xccdf-1.2:Rule xmlns:xccdf-1.2="http://checklists.nist.gov/xccdf/1.2" id="xccdf_org.ssgproject.content_rule_audit_enabled">
+  <xccdf-1.2:reference href="http://nvlpubs.nist.gov/nistpubs/SpecialPublications/NIST.SP.800-53r4.pdf">AU-2</xccdf-1.2:reference>
+  <xccdf-1.2:reference href="https://example.com/frameworks/acme">ACME-1.1</xccdf-1.2:reference>
+</xccdf-1.2:Rule>`
+
+	var pb *cmpv1alpha1.ProfileBundle
+
+	BeforeEach(func() {
+		pb = &cmpv1alpha1.ProfileBundle{
+			ObjectMeta: metav1.ObjectMeta{Name: "ocp4", Namespace: "test"},
+		}
+	})
+
+	It("Lists the controls of each framework with their rules", func() {
+		builder := newControlCatalogBuilder()
+		builder.addRule(pb, &cmpv1alpha1.Rule{ObjectMeta: metav1.ObjectMeta{
+			Name: "kubelet-anonymous-auth",
+			Annotations: map[string]string{
+				controlAnnotationBase + "NIST-800-53": "AC-2;CM-6",
+				controlAnnotationBase + "CIS-OCP":     "4.2.1",
+				rhacmStdsAnnotationKey:                "NIST-800-53,CIS-OCP",
+			},
+		}})
+		builder.addRule(pb, &cmpv1alpha1.Rule{ObjectMeta: metav1.ObjectMeta{
+			Name: "audit-enabled",
+			Annotations: map[string]string{
+				controlAnnotationBase + "NIST-800-53": "CM-6",
+			},
+		}})
+
+		catalogs := builder.catalogs(pb, "nonce")
+		Expect(catalogs).To(HaveLen(2))
+		Expect(catalogs[0].Name).To(Equal("cis-ocp"))
+		Expect(catalogs[0].Framework).To(Equal("CIS-OCP"))
+		Expect(catalogs[0].Labels).To(HaveKeyWithValue(cmpv1alpha1.ControlCatalogFrameworkLabel, "CIS-OCP"))
+		Expect(catalogs[0].Controls).To(Equal([]cmpv1alpha1.ControlCatalogControl{
+			{ID: "4.2.1", Rules: []string{"ocp4-kubelet-anonymous-auth"}},
+		}))
+		Expect(catalogs[1].Name).To(Equal("nist-800-53"))
+		Expect(catalogs[1].Controls).To(Equal([]cmpv1alpha1.ControlCatalogControl{
+			{ID: "AC-2", Rules: []string{"ocp4-kubelet-anonymous-auth"}},
+			{ID: "CM-6", Rules: []string{"ocp4-audit-enabled", "ocp4-kubelet-anonymous-auth"}},
+		}))
+	})
+
+	It("Maps rules to the frameworks registered by the bundle", func() {
+		doc, err := xmlquery.Parse(strings.NewReader(ruleXML))
+		Expect(err).To(BeNil())
+		ruleObj := xmlquery.FindOne(doc, "//xccdf-1.2:Rule")
+		Expect(ruleObj).ToNot(BeNil())
+
+		stdParser := newStandardParser()
+		err = stdParser.registerFrameworks([]cmpv1alpha1.ControlFramework{
+			{Name: "ACME", ReferencePattern: `^https://example\.com/frameworks/acme$`},
+		})
+		Expect(err).To(BeNil())
+
+		annotations, err := stdParser.parseXmlNode(ruleObj)
+		Expect(err).To(BeNil())
+		Expect(annotations).To(HaveKeyWithValue(controlAnnotationBase+"NIST-800-53", "AU-2"))
+		Expect(annotations).To(HaveKeyWithValue(controlAnnotationBase+"ACME", "ACME-1.1"))
+	})
+
+	It("Rejects frameworks with invalid reference patterns", func() {
+		err := newStandardParser().registerFrameworks([]cmpv1alpha1.ControlFramework{
+			{Name: "ACME", ReferencePattern: `(`},
+		})
+		Expect(err).ToNot(BeNil())
+	})
+})
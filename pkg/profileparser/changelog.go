@@ -0,0 +1,174 @@
+package profileparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ContentChangelog records how the profiles, rules and variables of a
+// bundle changed while parsing a new version of its content
+type ContentChangelog struct {
+	// The version of the benchmark that was parsed
+	DataStreamVersion string `json:"dataStreamVersion,omitempty"`
+	// The version of the benchmark that was parsed before, if known
+	PreviousDataStreamVersion string `json:"previousDataStreamVersion,omitempty"`
+	// The digest of the content image that was parsed
+	ImageDigest string `json:"imageDigest,omitempty"`
+	// The digest of the content image that was parsed before, if known
+	PreviousImageDigest string `json:"previousImageDigest,omitempty"`
+
+	Profiles  ContentChanges `json:"profiles"`
+	Rules     ContentChanges `json:"rules"`
+	Variables ContentChanges `json:"variables"`
+
+	// Set if the changelog was too large to be rendered in full, see Render
+	Truncated bool `json:"truncated,omitempty"`
+
+	// Objects that already existed before parsing
+	existing int
+	mutex    sync.Mutex
+}
+
+// ContentChanges lists the objects of one kind that changed
+type ContentChanges struct {
+	Added    []string          `json:"added,omitempty"`
+	Removed  []string          `json:"removed,omitempty"`
+	Modified []ModifiedContent `json:"modified,omitempty"`
+}
+
+// ModifiedContent is an object whose content changed along with the
+// fields that changed
+type ModifiedContent struct {
+	Name   string   `json:"name"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+// NewContentChangelog returns an empty changelog
+func NewContentChangelog() *ContentChangelog {
+	return &ContentChangelog{}
+}
+
+func (c *ContentChangelog) changesOf(kind string) *ContentChanges {
+	switch kind {
+	case "Profile":
+		return &c.Profiles
+	case "Rule":
+		return &c.Rules
+	default:
+		return &c.Variables
+	}
+}
+
+func (c *ContentChangelog) recordAdded(kind, name string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	changes := c.changesOf(kind)
+	changes.Added = append(changes.Added, name)
+}
+
+func (c *ContentChangelog) recordRemoved(kind, name string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	changes := c.changesOf(kind)
+	changes.Removed = append(changes.Removed, name)
+	c.existing++
+}
+
+func (c *ContentChangelog) recordFound(kind, name string, fields []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.existing++
+	if len(fields) == 0 {
+		return
+	}
+	changes := c.changesOf(kind)
+	changes.Modified = append(changes.Modified, ModifiedContent{Name: name, Fields: fields})
+}
+
+// IsInitial returns true if the content was parsed for the first time, in
+// which case everything was added and there's nothing worth reporting
+func (c *ContentChangelog) IsInitial() bool {
+	return c.existing == 0
+}
+
+// IsEmpty returns true if nothing changed
+func (c *ContentChangelog) IsEmpty() bool {
+	return c.Profiles.count() == 0 && c.Rules.count() == 0 && c.Variables.count() == 0
+}
+
+// Summary returns the number of added, removed and modified objects of
+// all kinds. The lists of objects are sorted, since they're recorded in
+// the order they were parsed.
+func (c *ContentChangelog) Summary() (added, removed, modified int) {
+	for _, changes := range c.allChanges() {
+		changes.sort()
+		added += len(changes.Added)
+		removed += len(changes.Removed)
+		modified += len(changes.Modified)
+	}
+	return added, removed, modified
+}
+
+// Render returns the changelog as JSON of at most maxSize bytes. If the
+// full changelog is larger, the fields of the modified objects are dropped
+// first, then the lists of objects are shortened until it fits, and the
+// changelog is marked as truncated. Call Summary before, since the counts
+// no longer match afterwards.
+func (c *ContentChangelog) Render(maxSize int) ([]byte, error) {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil || len(data) <= maxSize {
+		return data, err
+	}
+
+	c.Truncated = true
+	for _, changes := range c.allChanges() {
+		for i := range changes.Modified {
+			changes.Modified[i].Fields = nil
+		}
+	}
+	for {
+		data, err = json.MarshalIndent(c, "", "  ")
+		if err != nil || len(data) <= maxSize {
+			return data, err
+		}
+		shortened := false
+		for _, changes := range c.allChanges() {
+			if changes.halve() {
+				shortened = true
+			}
+		}
+		if !shortened {
+			return nil, fmt.Errorf("the changelog doesn't fit in %d bytes", maxSize)
+		}
+	}
+}
+
+func (c *ContentChangelog) allChanges() []*ContentChanges {
+	return []*ContentChanges{&c.Profiles, &c.Rules, &c.Variables}
+}
+
+func (c *ContentChanges) count() int {
+	return len(c.Added) + len(c.Removed) + len(c.Modified)
+}
+
+func (c *ContentChanges) sort() {
+	sort.Strings(c.Added)
+	sort.Strings(c.Removed)
+	sort.Slice(c.Modified, func(i, j int) bool {
+		return c.Modified[i].Name < c.Modified[j].Name
+	})
+}
+
+// halve drops the second half of the lists of objects and returns false
+// if they were all empty already
+func (c *ContentChanges) halve() bool {
+	if c.count() == 0 {
+		return false
+	}
+	c.Added = c.Added[:len(c.Added)/2]
+	c.Removed = c.Removed[:len(c.Removed)/2]
+	c.Modified = c.Modified[:len(c.Modified)/2]
+	return true
+}
@@ -0,0 +1,119 @@
+package profileparser
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	compapis "github.com/openshift/compliance-operator/pkg/apis"
+	cmpv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Recording the content changelog", func() {
+	var (
+		pb   *cmpv1alpha1.ProfileBundle
+		pcfg *ParserConfig
+	)
+
+	newRule := func(name, severity, nonce string) *cmpv1alpha1.Rule {
+		return &cmpv1alpha1.Rule{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "changelog-ns",
+				Annotations: map[string]string{
+					cmpv1alpha1.ProfileImageDigestAnnotation: nonce,
+				},
+			},
+			RulePayload: cmpv1alpha1.RulePayload{
+				ID:       "xccdf_org.ssgproject.content_rule_" + name,
+				Title:    name,
+				Severity: severity,
+			},
+		}
+	}
+
+	updateRule := func(found, updated interface{}) error {
+		foundRule := found.(*cmpv1alpha1.Rule)
+		foundRule.Annotations = updated.(*cmpv1alpha1.Rule).Annotations
+		foundRule.RulePayload = updated.(*cmpv1alpha1.Rule).RulePayload
+		return pcfg.Client.Update(context.TODO(), foundRule)
+	}
+
+	BeforeEach(func() {
+		cmpScheme := k8sruntime.NewScheme()
+		Expect(compapis.AddToScheme(cmpScheme)).To(Succeed())
+		pb = &cmpv1alpha1.ProfileBundle{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "changelog",
+				Namespace: "changelog-ns",
+				UID:       "changelog-uid",
+			},
+		}
+		pcfg = &ParserConfig{
+			ProfileBundleKey: types.NamespacedName{Name: pb.Name, Namespace: pb.Namespace},
+			Client:           fake.NewFakeClientWithScheme(cmpScheme, pb),
+			Scheme:           cmpScheme,
+			Changelog:        NewContentChangelog(),
+		}
+	})
+
+	It("should treat the first parse as initial", func() {
+		Expect(parseAction(newRule("a", "high", "n1"), "Rule", pb, pcfg, updateRule)).To(Succeed())
+		Expect(pcfg.Changelog.Rules.Added).To(Equal([]string{"changelog-a"}))
+		Expect(pcfg.Changelog.IsInitial()).To(BeTrue())
+	})
+
+	It("should record added, removed and modified objects", func() {
+		for _, name := range []string{"a", "b", "c"} {
+			Expect(parseAction(newRule(name, "high", "n1"), "Rule", pb, pcfg, updateRule)).To(Succeed())
+		}
+
+		pcfg.Changelog = NewContentChangelog()
+		Expect(parseAction(newRule("a", "high", "n2"), "Rule", pb, pcfg, updateRule)).To(Succeed())
+		Expect(parseAction(newRule("b", "low", "n2"), "Rule", pb, pcfg, updateRule)).To(Succeed())
+		Expect(parseAction(newRule("d", "high", "n2"), "Rule", pb, pcfg, updateRule)).To(Succeed())
+		Expect(deleteObsoleteItems(pcfg, "Rule", pb.Name, pb.Namespace, "n2")).To(Succeed())
+
+		Expect(pcfg.Changelog.IsInitial()).To(BeFalse())
+		Expect(pcfg.Changelog.IsEmpty()).To(BeFalse())
+		added, removed, modified := pcfg.Changelog.Summary()
+		Expect([]int{added, removed, modified}).To(Equal([]int{1, 1, 1}))
+		Expect(pcfg.Changelog.Rules.Added).To(Equal([]string{"changelog-d"}))
+		Expect(pcfg.Changelog.Rules.Removed).To(Equal([]string{"changelog-c"}))
+		Expect(pcfg.Changelog.Rules.Modified).To(Equal([]ModifiedContent{
+			{Name: "changelog-b", Fields: []string{"severity"}},
+		}))
+	})
+
+	It("should report changed annotations", func() {
+		found := newRule("a", "high", "n1")
+		updated := newRule("a", "high", "n2")
+		updated.Annotations[cmpv1alpha1.RuleIDAnnotationKey] = "a"
+		fields, err := getChangedFields(found, updated)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fields).To(Equal([]string{"metadata.annotations." + cmpv1alpha1.RuleIDAnnotationKey}))
+	})
+	It("should truncate changelogs that are too large", func() {
+		changelog := NewContentChangelog()
+		for i := 0; i < 100; i++ {
+			changelog.recordAdded("Rule", fmt.Sprintf("rule-%03d", i))
+			changelog.recordFound("Profile", fmt.Sprintf("profile-%03d", i), []string{"rules", "description"})
+		}
+
+		full, err := changelog.Render(1024 * 1024)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changelog.Truncated).To(BeFalse())
+
+		data, err := changelog.Render(len(full) / 4)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(data)).To(BeNumerically("<=", len(full)/4))
+		Expect(changelog.Truncated).To(BeTrue())
+		Expect(changelog.Rules.Added).ToNot(BeEmpty())
+		Expect(changelog.Profiles.Modified[0].Fields).To(BeNil())
+	})
+})
@@ -0,0 +1,75 @@
+package profileparser
+
+import (
+	"reflect"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	cmpv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+// getChangedFields compares an object as found in the cluster with the
+// same object as parsed from the new content and returns the names of the
+// fields whose content changed. Annotations that change on every parse
+// are ignored.
+func getChangedFields(found, updated runtime.Object) ([]string, error) {
+	foundMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(found)
+	if err != nil {
+		return nil, err
+	}
+	updatedMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(updated)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	for _, key := range unionOfKeys(foundMap, updatedMap) {
+		switch key {
+		case "apiVersion", "kind", "metadata":
+			continue
+		}
+		if !reflect.DeepEqual(foundMap[key], updatedMap[key]) {
+			fields = append(fields, key)
+		}
+	}
+
+	foundAnnotations := getComparableAnnotations(foundMap)
+	updatedAnnotations := getComparableAnnotations(updatedMap)
+	for _, key := range unionOfKeys(foundAnnotations, updatedAnnotations) {
+		if !reflect.DeepEqual(foundAnnotations[key], updatedAnnotations[key]) {
+			fields = append(fields, "metadata.annotations."+key)
+		}
+	}
+
+	return fields, nil
+}
+
+func getComparableAnnotations(obj map[string]interface{}) map[string]interface{} {
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	comparable := make(map[string]interface{}, len(annotations))
+	for key, value := range annotations {
+		switch key {
+		case cmpv1alpha1.ProfileImageDigestAnnotation, cmpv1alpha1.ProfileContentDigestAnnotation,
+			cmpv1alpha1.ProfileContentDigestsAnnotation:
+			continue
+		}
+		comparable[key] = value
+	}
+	return comparable
+}
+
+func unionOfKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -42,6 +42,38 @@ type ParserConfig struct {
 	ProfileBundleKey types.NamespacedName
 	Client           runtimeclient.Client
 	Scheme           *k8sruntime.Scheme
+	// The digest of the content image that is being parsed, if known
+	ContentDigest string
+	// The digests of the previous content versions whose objects are kept
+	// available. When an object parsed from one of these is updated or
+	// removed, a copy of it is archived first.
+	RetainedContentDigests []string
+	// If set, records how the content changed compared to the objects
+	// that already exist
+	Changelog *ContentChangelog
+}
+
+// getRetained returns the digests that are retained, in the same order
+func (pcfg *ParserConfig) getRetained(digests []string) []string {
+	var retained []string
+	for _, digest := range digests {
+		if digest != pcfg.ContentDigest && pcfg.isRetained(digest) {
+			retained = append(retained, digest)
+		}
+	}
+	return retained
+}
+
+func (pcfg *ParserConfig) isRetained(digest string) bool {
+	if digest == "" {
+		return false
+	}
+	for _, retained := range pcfg.RetainedContentDigests {
+		if retained == digest {
+			return true
+		}
+	}
+	return false
 }
 
 func LogAndReturnError(errormsg string) error {
@@ -60,6 +92,10 @@ func ParseBundle(contentDom *xmlquery.Node, pb *cmpv1alpha1.ProfileBundle, pcfg
 	var wg sync.WaitGroup
 	wg.Add(3)
 	stdParser := newStandardParser()
+	if err := stdParser.registerFrameworks(pb.Spec.ControlFrameworks); err != nil {
+		return err
+	}
+	catalogs := newControlCatalogBuilder()
 	nonce := names.SimpleNameGenerator.GenerateName(fmt.Sprintf("pb-%s", pb.Name))
 	go func() {
 		profErr := ParseProfilesAndDo(contentDom, pb, nonce, func(p *cmpv1alpha1.Profile) error {
@@ -84,7 +120,11 @@ func ParseBundle(contentDom *xmlquery.Node, pb *cmpv1alpha1.ProfileBundle, pcfg
 			errChan <- profErr
 		}
 
-		if err := deleteObsoleteItems(pcfg.Client, "Profile", pb.Name, pb.Namespace, nonce); err != nil {
+		if err := deleteObsoleteItems(pcfg, "Profile", pb.Name, pb.Namespace, nonce); err != nil {
+			errChan <- err
+		}
+
+		if err := deleteUnretainedArchives(pcfg, "Profile", pb.Name, pb.Namespace); err != nil {
 			errChan <- err
 		}
 		wg.Done()
@@ -96,6 +136,7 @@ func ParseBundle(contentDom *xmlquery.Node, pb *cmpv1alpha1.ProfileBundle, pcfg
 				r.Annotations = make(map[string]string)
 			}
 			r.Annotations[cmpv1alpha1.RuleIDAnnotationKey] = r.Name
+			catalogs.addRule(pb, r)
 
 			err := parseAction(r, "Rule", pb, pcfg, func(found, updated interface{}) error {
 				foundRule, ok := found.(*cmpv1alpha1.Rule)
@@ -116,9 +157,15 @@ func ParseBundle(contentDom *xmlquery.Node, pb *cmpv1alpha1.ProfileBundle, pcfg
 
 		if ruleErr != nil {
 			errChan <- ruleErr
+		} else if err := writeControlCatalogs(catalogs, pb, pcfg, nonce); err != nil {
+			errChan <- err
+		}
+
+		if err := deleteObsoleteItems(pcfg, "Rule", pb.Name, pb.Namespace, nonce); err != nil {
+			errChan <- err
 		}
 
-		if err := deleteObsoleteItems(pcfg.Client, "Rule", pb.Name, pb.Namespace, nonce); err != nil {
+		if err := deleteUnretainedArchives(pcfg, "Rule", pb.Name, pb.Namespace); err != nil {
 			errChan <- err
 		}
 		wg.Done()
@@ -147,7 +194,11 @@ func ParseBundle(contentDom *xmlquery.Node, pb *cmpv1alpha1.ProfileBundle, pcfg
 			errChan <- varErr
 		}
 
-		if err := deleteObsoleteItems(pcfg.Client, "Variable", pb.Name, pb.Namespace, nonce); err != nil {
+		if err := deleteObsoleteItems(pcfg, "Variable", pb.Name, pb.Namespace, nonce); err != nil {
+			errChan <- err
+		}
+
+		if err := deleteUnretainedArchives(pcfg, "Variable", pb.Name, pb.Namespace); err != nil {
 			errChan <- err
 		}
 		wg.Done()
@@ -192,11 +243,47 @@ func parseAction(parsedItem parsedItemIface, kind string, pb *cmpv1alpha1.Profil
 		return err
 	}
 
+	if pcfg.ContentDigest != "" {
+		setContentDigests(parsedItem, pcfg.ContentDigest, []string{pcfg.ContentDigest})
+	}
+
+	// Keep the object as parsed from a previous content version around
+	// before overwriting it. Objects whose content didn't change aren't
+	// archived, they record the content versions they cover instead.
+	existed := false
+	archivingUpdateFn := func(found, updated interface{}) error {
+		existed = true
+		foundItem, ok := found.(parsedItemIface)
+		if !ok {
+			return fmt.Errorf("unexpected type")
+		}
+		fields, err := getChangedFields(foundItem, parsedItem)
+		if err != nil {
+			return err
+		}
+		if pcfg.Changelog != nil {
+			pcfg.Changelog.recordFound(kind, foundItem.GetName(), fields)
+		}
+		if len(fields) > 0 {
+			if err := archiveIfRetained(pcfg, foundItem); err != nil {
+				return err
+			}
+		} else if pcfg.ContentDigest != "" {
+			digests := append(pcfg.getRetained(cmpv1alpha1.GetContentDigests(foundItem)), pcfg.ContentDigest)
+			setContentDigests(parsedItem, pcfg.ContentDigest, digests)
+		}
+		return updateFn(found, updated)
+	}
+
 	key := types.NamespacedName{Name: parsedItem.GetName(), Namespace: parsedItem.GetNamespace()}
-	if err := createOrUpdate(pcfg.Client, kind, key, parsedItem, updateFn); err != nil {
+	if err := createOrUpdate(pcfg.Client, kind, key, parsedItem, archivingUpdateFn); err != nil {
 		return err
 	}
 
+	if !existed && pcfg.Changelog != nil {
+		pcfg.Changelog.recordAdded(kind, parsedItem.GetName())
+	}
+
 	return nil
 }
 
@@ -223,20 +310,15 @@ func createOrUpdate(cli runtimeclient.Client, kind string, key types.NamespacedN
 	return nil
 }
 
-func deleteObsoleteItems(cli runtimeclient.Client, kind string, pbName, namespace string, nonce string) error {
-	list := unstructured.UnstructuredList{}
-	list.SetGroupVersionKind(schema.GroupVersionKind{
-		Group:   cmpv1alpha1.SchemeGroupVersion.Group,
-		Version: cmpv1alpha1.SchemeGroupVersion.Version,
-		Kind:    kind + "List",
-	})
+func deleteObsoleteItems(pcfg *ParserConfig, kind string, pbName, namespace string, nonce string) error {
+	list := newUnstructuredList(kind)
 	inNs := runtimeclient.InNamespace(namespace)
 	withPbOwnerLabel := runtimeclient.MatchingLabels{
 		cmpv1alpha1.ProfileBundleOwnerLabel: pbName,
 	}
 
 	log.Info("Checking for unused object", "kind", kind, "owner", pbName, "namespace", namespace)
-	if err := cli.List(context.TODO(), &list, inNs, withPbOwnerLabel); err != nil {
+	if err := pcfg.Client.List(context.TODO(), list, inNs, withPbOwnerLabel); err != nil {
 		return err
 	}
 
@@ -245,7 +327,7 @@ func deleteObsoleteItems(cli runtimeclient.Client, kind string, pbName, namespac
 	// if this ever becomes a performance problem, use labels instead
 	// with a short version of the hash
 	for i := range list.Items {
-		err := deleteIfNotCurrentDigest(cli, nonce, &list.Items[i])
+		err := deleteIfNotCurrentDigest(pcfg, kind, nonce, &list.Items[i])
 		if err != nil {
 			return err
 		}
@@ -254,14 +336,109 @@ func deleteObsoleteItems(cli runtimeclient.Client, kind string, pbName, namespac
 	return nil
 }
 
-func deleteIfNotCurrentDigest(client runtimeclient.Client, imageDigest string, item *unstructured.Unstructured) error {
+func deleteIfNotCurrentDigest(pcfg *ParserConfig, kind, imageDigest string, item *unstructured.Unstructured) error {
 	itemDigest := item.GetAnnotations()[cmpv1alpha1.ProfileImageDigestAnnotation]
 	if itemDigest == imageDigest {
 		return nil
 	}
 
+	if err := archiveIfRetained(pcfg, item); err != nil {
+		return err
+	}
+
+	if pcfg.Changelog != nil {
+		pcfg.Changelog.recordRemoved(kind, item.GetName())
+	}
+
 	log.Info("Deleting object no longer used by the current profileBundle", "kind", item.GetKind(), "name", item.GetName())
-	return client.Delete(context.TODO(), item)
+	return pcfg.Client.Delete(context.TODO(), item)
+}
+
+// setContentDigests records the digest of the content image the object was
+// parsed from last, and all of those it was parsed from without changes
+func setContentDigests(item parsedItemIface, digest string, digests []string) {
+	annotations := item.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[cmpv1alpha1.ProfileContentDigestAnnotation] = digest
+	annotations[cmpv1alpha1.ProfileContentDigestsAnnotation] = strings.Join(digests, ",")
+	item.SetAnnotations(annotations)
+}
+
+// archiveIfRetained creates a copy of an object that was parsed from any
+// of the retained content versions, unless the copy already exists. The
+// copy is no longer labeled as owned by the bundle, so that it doesn't
+// show up as part of the current content, and only lists the retained
+// content versions it covers.
+func archiveIfRetained(pcfg *ParserConfig, item parsedItemIface) error {
+	itemDigest := item.GetAnnotations()[cmpv1alpha1.ProfileContentDigestAnnotation]
+	retained := pcfg.getRetained(cmpv1alpha1.GetContentDigests(item))
+	if itemDigest == pcfg.ContentDigest || len(retained) == 0 {
+		return nil
+	}
+
+	pbName := item.GetLabels()[cmpv1alpha1.ProfileBundleOwnerLabel]
+	archived, ok := item.DeepCopyObject().(parsedItemIface)
+	if !ok {
+		return fmt.Errorf("unexpected type")
+	}
+	archived.SetName(cmpv1alpha1.GetArchivedContentName(item.GetName(), itemDigest))
+	archived.SetResourceVersion("")
+	archived.SetUID("")
+	archived.SetCreationTimestamp(metav1.Time{})
+	labels := archived.GetLabels()
+	delete(labels, cmpv1alpha1.ProfileBundleOwnerLabel)
+	labels[cmpv1alpha1.ArchivedContentLabel] = pbName
+	archived.SetLabels(labels)
+	setContentDigests(archived, itemDigest, retained)
+	annotations := archived.GetAnnotations()
+	annotations[cmpv1alpha1.ArchivedFromAnnotation] = item.GetName()
+	archived.SetAnnotations(annotations)
+
+	log.Info("Archiving object parsed from a previous content version", "name", item.GetName(), "digest", itemDigest)
+	err := pcfg.Client.Create(context.TODO(), archived)
+	if errors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// deleteUnretainedArchives deletes the archived copies of objects whose
+// content version is no longer retained
+func deleteUnretainedArchives(pcfg *ParserConfig, kind string, pbName, namespace string) error {
+	list := newUnstructuredList(kind)
+	inNs := runtimeclient.InNamespace(namespace)
+	withArchivedLabel := runtimeclient.MatchingLabels{
+		cmpv1alpha1.ArchivedContentLabel: pbName,
+	}
+
+	if err := pcfg.Client.List(context.TODO(), list, inNs, withArchivedLabel); err != nil {
+		return err
+	}
+
+	for i := range list.Items {
+		item := &list.Items[i]
+		if len(pcfg.getRetained(cmpv1alpha1.GetContentDigests(item))) > 0 {
+			continue
+		}
+		log.Info("Deleting object archived from a content version that is no longer retained", "kind", item.GetKind(), "name", item.GetName())
+		if err := pcfg.Client.Delete(context.TODO(), item); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newUnstructuredList(kind string) *unstructured.UnstructuredList {
+	list := unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   cmpv1alpha1.SchemeGroupVersion.Group,
+		Version: cmpv1alpha1.SchemeGroupVersion.Version,
+		Kind:    kind + "List",
+	})
+	return &list
 }
 
 func getVariableType(varNode *xmlquery.Node) cmpv1alpha1.VariableType {
@@ -281,6 +458,16 @@ func getVariableType(varNode *xmlquery.Node) cmpv1alpha1.VariableType {
 	return cmpv1alpha1.VarTypeString
 }
 
+// GetDataStreamVersion returns the version of the first benchmark in the
+// data stream, or an empty string if it has none
+func GetDataStreamVersion(contentDom *xmlquery.Node) string {
+	version := xmlquery.FindOne(contentDom, "//xccdf-1.2:Benchmark/xccdf-1.2:version")
+	if version == nil {
+		return ""
+	}
+	return strings.TrimSpace(version.InnerText())
+}
+
 func ParseProfilesAndDo(contentDom *xmlquery.Node, pb *cmpv1alpha1.ProfileBundle, nonce string, action func(p *cmpv1alpha1.Profile) error) error {
 	benchmarks := xmlquery.Find(contentDom, "//xccdf-1.2:Benchmark")
 	for _, bench := range benchmarks {
@@ -295,6 +482,15 @@ func ParseProfilesAndDo(contentDom *xmlquery.Node, pb *cmpv1alpha1.ProfileBundle
 
 func parseProfileFromNode(profileRoot *xmlquery.Node, pb *cmpv1alpha1.ProfileBundle, defType cmpv1alpha1.ComplianceScanType, defName, nonce string, action func(p *cmpv1alpha1.Profile) error) error {
 	profileObjs := xmlquery.Find(profileRoot, "//xccdf-1.2:Profile")
+	profilesByID := make(map[string]*xmlquery.Node, len(profileObjs))
+	for _, profileObj := range profileObjs {
+		profilesByID[profileObj.SelectAttr("id")] = profileObj
+	}
+	valuesByID := make(map[string]*xmlquery.Node)
+	for _, valueObj := range xmlquery.Find(profileRoot, "//xccdf-1.2:Value") {
+		valuesByID[valueObj.SelectAttr("id")] = valueObj
+	}
+
 	for _, profileObj := range profileObjs {
 
 		id := profileObj.SelectAttr("id")
@@ -315,30 +511,93 @@ func parseProfileFromNode(profileRoot *xmlquery.Node, pb *cmpv1alpha1.ProfileBun
 		productType, productName := getProductTypeAndName(profileObj, defType, defName)
 		log.Info("Platform info", "type", productType, "name", productName)
 
-		ruleObjs := profileObj.SelectElements("xccdf-1.2:select")
+		// The profiles this one extends come first, so that this one
+		// can override what they select and set
+		chain, err := getProfileChain(profileObj, profilesByID)
+		if err != nil {
+			return err
+		}
+
+		// The rules and values the profile selects and sets itself, as
+		// opposed to those it ends up with once it inherits those of the
+		// profiles it extends
 		selectedrules := []cmpv1alpha1.ProfileRule{}
-		for _, ruleObj := range ruleObjs {
-			idref := ruleObj.SelectAttr("idref")
-			if idref == "" {
-				log.Info("no idref in rule")
-				continue
+		selectedvalues := []cmpv1alpha1.ProfileValue{}
+		effectiverules := []cmpv1alpha1.ProfileRule{}
+		ruleSources := map[cmpv1alpha1.ProfileRule]string{}
+		refinedValues := []cmpv1alpha1.ProfileRefinedValue{}
+		for i, chainObj := range chain {
+			isOwn := i == len(chain)-1
+			chainProfileName := GetPrefixedName(pb.Name, xccdf.GetProfileNameFromID(chainObj.SelectAttr("id")))
+
+			ruleObjs := chainObj.SelectElements("xccdf-1.2:select")
+			for _, ruleObj := range ruleObjs {
+				idref := ruleObj.SelectAttr("idref")
+				if idref == "" {
+					log.Info("no idref in rule")
+					continue
+				}
+				ruleName := cmpv1alpha1.NewProfileRule(GetPrefixedName(pb.Name, xccdf.GetRuleNameFromID(idref)))
+				_, isSelected := ruleSources[ruleName]
+				selected := ruleObj.SelectAttr("selected")
+				if selected == "true" && isOwn {
+					selectedrules = append(selectedrules, ruleName)
+				}
+				if selected == "true" && !isSelected {
+					effectiverules = append(effectiverules, ruleName)
+					ruleSources[ruleName] = chainProfileName
+				} else if selected != "true" && isSelected {
+					effectiverules = removeProfileRule(effectiverules, ruleName)
+					delete(ruleSources, ruleName)
+				}
 			}
-			selected := ruleObj.SelectAttr("selected")
-			if selected == "true" {
-				ruleName := GetPrefixedName(pb.Name, xccdf.GetRuleNameFromID(idref))
-				selectedrules = append(selectedrules, cmpv1alpha1.NewProfileRule(ruleName))
+
+			valueObjs := chainObj.SelectElements("xccdf-1.2:set-value")
+			for _, valueObj := range valueObjs {
+				idref := valueObj.SelectAttr("idref")
+				if idref == "" {
+					log.Info("no idref in rule")
+					continue
+				}
+				if isOwn {
+					selectedvalues = append(selectedvalues, cmpv1alpha1.ProfileValue(idref))
+				}
+				refinedValues = setRefinedValue(refinedValues, cmpv1alpha1.ProfileRefinedValue{
+					Variable: GetPrefixedName(pb.Name, xccdf.GetVariableNameFromID(idref)),
+					Value:    strings.TrimSpace(valueObj.InnerText()),
+					Profile:  chainProfileName,
+				})
+			}
+
+			refineObjs := chainObj.SelectElements("xccdf-1.2:refine-value")
+			for _, refineObj := range refineObjs {
+				idref := refineObj.SelectAttr("idref")
+				if idref == "" {
+					log.Info("no idref in refine-value")
+					continue
+				}
+				selector := refineObj.SelectAttr("selector")
+				refinedValues = setRefinedValue(refinedValues, cmpv1alpha1.ProfileRefinedValue{
+					Variable: GetPrefixedName(pb.Name, xccdf.GetVariableNameFromID(idref)),
+					Selector: selector,
+					Value:    getSelectedValue(valuesByID[idref], selector),
+					Profile:  chainProfileName,
+				})
 			}
 		}
 
-		selectedvalues := []cmpv1alpha1.ProfileValue{}
-		valueObjs := profileObj.SelectElements("xccdf-1.2:set-value")
-		for _, valueObj := range valueObjs {
-			idref := valueObj.SelectAttr("idref")
-			if idref == "" {
-				log.Info("no idref in rule")
-				continue
+		var extends []string
+		var rulesBySource []cmpv1alpha1.ProfileRuleSource
+		if len(chain) > 1 {
+			for i := len(chain) - 2; i >= 0; i-- {
+				extends = append(extends, GetPrefixedName(pb.Name, xccdf.GetProfileNameFromID(chain[i].SelectAttr("id"))))
+			}
+			for _, rule := range effectiverules {
+				rulesBySource = append(rulesBySource, cmpv1alpha1.ProfileRuleSource{
+					Rule:    rule,
+					Profile: ruleSources[rule],
+				})
 			}
-			selectedvalues = append(selectedvalues, cmpv1alpha1.ProfileValue(idref))
 		}
 
 		p := cmpv1alpha1.Profile{
@@ -355,17 +614,20 @@ func parseProfileFromNode(profileRoot *xmlquery.Node, pb *cmpv1alpha1.ProfileBun
 				},
 			},
 			ProfilePayload: cmpv1alpha1.ProfilePayload{
-				ID:          id,
-				Title:       title.InnerText(),
-				Description: utils.XmlNodeAsMarkdown(description),
-				Rules:       selectedrules,
-				Values:      selectedvalues,
+				ID:            id,
+				Title:         title.InnerText(),
+				Description:   utils.XmlNodeAsMarkdown(description),
+				Rules:         selectedrules,
+				Values:        selectedvalues,
+				Extends:       extends,
+				RuleSources:   rulesBySource,
+				RefinedValues: refinedValues,
 			},
 		}
 
 		annotateWithNonce(&p, nonce)
 
-		err := action(&p)
+		err = action(&p)
 		if err != nil {
 			log.Error(err, "couldn't execute action")
 			return err
@@ -375,6 +637,63 @@ func parseProfileFromNode(profileRoot *xmlquery.Node, pb *cmpv1alpha1.ProfileBun
 	return nil
 }
 
+// getProfileChain returns the profiles that the given profile extends,
+// directly or not, followed by the profile itself. The profile that
+// doesn't extend any other comes first.
+func getProfileChain(profileObj *xmlquery.Node, profilesByID map[string]*xmlquery.Node) ([]*xmlquery.Node, error) {
+	chain := []*xmlquery.Node{profileObj}
+	seen := map[string]bool{profileObj.SelectAttr("id"): true}
+	for current := profileObj; current.SelectAttr("extends") != ""; {
+		extendedID := current.SelectAttr("extends")
+		if seen[extendedID] {
+			return nil, LogAndReturnError(fmt.Sprintf("profile %s extends itself through %s", profileObj.SelectAttr("id"), extendedID))
+		}
+		extended, ok := profilesByID[extendedID]
+		if !ok {
+			return nil, LogAndReturnError(fmt.Sprintf("profile %s extends the unknown profile %s", current.SelectAttr("id"), extendedID))
+		}
+		seen[extendedID] = true
+		chain = append([]*xmlquery.Node{extended}, chain...)
+		current = extended
+	}
+	return chain, nil
+}
+
+// getSelectedValue returns the value of the given XCCDF Value that the
+// selector picks, or its default value if there's no selector
+func getSelectedValue(valueObj *xmlquery.Node, selector string) string {
+	if valueObj == nil {
+		return ""
+	}
+	for _, value := range valueObj.SelectElements("xccdf-1.2:value") {
+		if value.SelectAttr("selector") == selector {
+			return strings.TrimSpace(value.InnerText())
+		}
+	}
+	return ""
+}
+
+// setRefinedValue sets the value of a variable, replacing the value that a
+// profile earlier in the extends chain set
+func setRefinedValue(values []cmpv1alpha1.ProfileRefinedValue, value cmpv1alpha1.ProfileRefinedValue) []cmpv1alpha1.ProfileRefinedValue {
+	for i := range values {
+		if values[i].Variable == value.Variable {
+			values[i] = value
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+func removeProfileRule(rules []cmpv1alpha1.ProfileRule, rule cmpv1alpha1.ProfileRule) []cmpv1alpha1.ProfileRule {
+	for i := range rules {
+		if rules[i] == rule {
+			return append(rules[:i], rules[i+1:]...)
+		}
+	}
+	return rules
+}
+
 func getProductTypeAndName(root *xmlquery.Node, defaultType cmpv1alpha1.ComplianceScanType, defaultName string) (cmpv1alpha1.ComplianceScanType, string) {
 	p := root.SelectElement("xccdf-1.2:platform")
 
@@ -799,6 +1118,17 @@ func (p *referenceParser) registerStandard(name, hrefRegexp string) error {
 	return nil
 }
 
+// registerFrameworks registers the frameworks of a bundle in addition to
+// the built-in ones
+func (p *referenceParser) registerFrameworks(frameworks []cmpv1alpha1.ControlFramework) error {
+	for _, framework := range frameworks {
+		if err := p.registerStandard(framework.Name, framework.ReferencePattern); err != nil {
+			return fmt.Errorf("invalid reference pattern of the control framework %s: %w", framework.Name, err)
+		}
+	}
+	return nil
+}
+
 func (p *referenceParser) registerFormatter(formatter annotationsFormatterFn) {
 	p.annotationFormatters = append(p.annotationFormatters, formatter)
 }
@@ -3,12 +3,14 @@ package profileparser
 import (
 	"context"
 	"os"
+	"strings"
 
 	"github.com/antchfx/xmlquery"
 	"github.com/go-logr/zapr"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	gomegatypes "github.com/onsi/gomega/types"
+	compapis "github.com/openshift/compliance-operator/pkg/apis"
 	cmpv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
 	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -19,6 +21,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apiserver/pkg/storage/names"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 // FIXME: code duplication
@@ -654,3 +657,229 @@ var _ = Describe("Testing CPE string parsing in isolation", func() {
 		})
 	})
 })
+
+var _ = Describe("Archiving the content of previous versions", func() {
+	const (
+		digest1 = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+		digest2 = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+		digest3 = "sha256:3333333333333333333333333333333333333333333333333333333333333333"
+	)
+
+	var (
+		pb   *cmpv1alpha1.ProfileBundle
+		pcfg *ParserConfig
+	)
+
+	newRule := func(severity string) *cmpv1alpha1.Rule {
+		return &cmpv1alpha1.Rule{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "a",
+				Namespace: "archive-ns",
+			},
+			RulePayload: cmpv1alpha1.RulePayload{
+				ID:       "xccdf_org.ssgproject.content_rule_a",
+				Title:    "a",
+				Severity: severity,
+			},
+		}
+	}
+
+	updateRule := func(found, updated interface{}) error {
+		foundRule := found.(*cmpv1alpha1.Rule)
+		foundRule.Annotations = updated.(*cmpv1alpha1.Rule).Annotations
+		foundRule.RulePayload = updated.(*cmpv1alpha1.Rule).RulePayload
+		return pcfg.Client.Update(context.TODO(), foundRule)
+	}
+
+	parseVersion := func(digest, severity string, retained ...string) {
+		pcfg.ContentDigest = digest
+		pcfg.RetainedContentDigests = retained
+		Expect(parseAction(newRule(severity), "Rule", pb, pcfg, updateRule)).To(Succeed())
+	}
+
+	listArchives := func() []cmpv1alpha1.Rule {
+		rules := cmpv1alpha1.RuleList{}
+		err := pcfg.Client.List(context.TODO(), &rules, runtimeclient.HasLabels{cmpv1alpha1.ArchivedContentLabel})
+		Expect(err).ToNot(HaveOccurred())
+		return rules.Items
+	}
+
+	BeforeEach(func() {
+		cmpScheme := k8sruntime.NewScheme()
+		Expect(compapis.AddToScheme(cmpScheme)).To(Succeed())
+		pb = &cmpv1alpha1.ProfileBundle{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "archive",
+				Namespace: "archive-ns",
+				UID:       "archive-uid",
+			},
+		}
+		pcfg = &ParserConfig{
+			ProfileBundleKey: types.NamespacedName{Name: pb.Name, Namespace: pb.Namespace},
+			Client:           fake.NewFakeClientWithScheme(cmpScheme, pb),
+			Scheme:           cmpScheme,
+		}
+	})
+
+	It("should only archive objects whose content changed", func() {
+		parseVersion(digest1, "high")
+		parseVersion(digest2, "high", digest1)
+		Expect(listArchives()).To(BeEmpty())
+
+		current := &cmpv1alpha1.Rule{}
+		err := pcfg.Client.Get(context.TODO(), types.NamespacedName{Name: "archive-a", Namespace: pb.Namespace}, current)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cmpv1alpha1.GetContentDigests(current)).To(Equal([]string{digest1, digest2}))
+
+		parseVersion(digest3, "low", digest2, digest1)
+		archives := listArchives()
+		Expect(archives).To(HaveLen(1))
+		Expect(archives[0].Name).To(Equal(cmpv1alpha1.GetArchivedContentName("archive-a", digest2)))
+		Expect(archives[0].Severity).To(Equal("high"))
+		Expect(archives[0].Annotations[cmpv1alpha1.ArchivedFromAnnotation]).To(Equal("archive-a"))
+		Expect(cmpv1alpha1.GetContentDigests(&archives[0])).To(Equal([]string{digest1, digest2}))
+
+		err = pcfg.Client.Get(context.TODO(), types.NamespacedName{Name: "archive-a", Namespace: pb.Namespace}, current)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cmpv1alpha1.GetContentDigests(current)).To(Equal([]string{digest3}))
+	})
+
+	It("should only keep the content versions that are retained", func() {
+		parseVersion(digest1, "high")
+		parseVersion(digest2, "high", digest1)
+		parseVersion(digest3, "low", digest2)
+
+		archives := listArchives()
+		Expect(archives).To(HaveLen(1))
+		Expect(cmpv1alpha1.GetContentDigests(&archives[0])).To(Equal([]string{digest2}))
+
+		Expect(deleteUnretainedArchives(pcfg, "Rule", pb.Name, pb.Namespace)).To(Succeed())
+		Expect(listArchives()).To(HaveLen(1))
+
+		pcfg.RetainedContentDigests = nil
+		Expect(deleteUnretainedArchives(pcfg, "Rule", pb.Name, pb.Namespace)).To(Succeed())
+		Expect(listArchives()).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Testing parse profiles that extend others", func() {
+	const content = `<xccdf-1.2:Benchmark xmlns:xccdf-1.2="http://checklists.nist.gov/xccdf/1.2" id="xccdf_org.ssgproject.content_benchmark_OCP-4">
+  <xccdf-1.2:Profile id="xccdf_org.ssgproject.content_profile_base">
+    <xccdf-1.2:title>Base</xccdf-1.2:title>
+    <xccdf-1.2:description>The base profile</xccdf-1.2:description>
+    <xccdf-1.2:select idref="xccdf_org.ssgproject.content_rule_audit_enabled" selected="true"/>
+    <xccdf-1.2:select idref="xccdf_org.ssgproject.content_rule_kubelet_anonymous_auth" selected="true"/>
+    <xccdf-1.2:refine-value idref="xccdf_org.ssgproject.content_value_var_kubelet_timeout" selector="5min"/>
+    <xccdf-1.2:refine-value idref="xccdf_org.ssgproject.content_value_var_audit_level" selector="low"/>
+  </xccdf-1.2:Profile>
+  <xccdf-1.2:Profile id="xccdf_org.ssgproject.content_profile_middle" extends="xccdf_org.ssgproject.content_profile_base">
+    <xccdf-1.2:title>Middle</xccdf-1.2:title>
+    <xccdf-1.2:description>The profile in the middle</xccdf-1.2:description>
+    <xccdf-1.2:select idref="xccdf_org.ssgproject.content_rule_kubelet_anonymous_auth" selected="false"/>
+    <xccdf-1.2:select idref="xccdf_org.ssgproject.content_rule_etcd_encryption" selected="true"/>
+    <xccdf-1.2:refine-value idref="xccdf_org.ssgproject.content_value_var_kubelet_timeout" selector="10min"/>
+  </xccdf-1.2:Profile>
+  <xccdf-1.2:Profile id="xccdf_org.ssgproject.content_profile_derived" extends="xccdf_org.ssgproject.content_profile_middle">
+    <xccdf-1.2:title>Derived</xccdf-1.2:title>
+    <xccdf-1.2:description>The derived profile</xccdf-1.2:description>
+    <xccdf-1.2:select idref="xccdf_org.ssgproject.content_rule_audit_enabled" selected="true"/>
+    <xccdf-1.2:select idref="xccdf_org.ssgproject.content_rule_kubelet_anonymous_auth" selected="true"/>
+    <xccdf-1.2:set-value idref="xccdf_org.ssgproject.content_value_var_audit_level">high</xccdf-1.2:set-value>
+  </xccdf-1.2:Profile>
+  <xccdf-1.2:Value id="xccdf_org.ssgproject.content_value_var_kubelet_timeout" type="string">
+    <xccdf-1.2:title>Kubelet timeout</xccdf-1.2:title>
+    <xccdf-1.2:value>5m0s</xccdf-1.2:value>
+    <xccdf-1.2:value selector="5min">5m0s</xccdf-1.2:value>
+    <xccdf-1.2:value selector="10min">10m0s</xccdf-1.2:value>
+  </xccdf-1.2:Value>
+  <xccdf-1.2:Value id="xccdf_org.ssgproject.content_value_var_audit_level" type="string">
+    <xccdf-1.2:title>Audit level</xccdf-1.2:title>
+    <xccdf-1.2:value>low</xccdf-1.2:value>
+    <xccdf-1.2:value selector="low">low</xccdf-1.2:value>
+  </xccdf-1.2:Value>
+</xccdf-1.2:Benchmark>`
+
+	var profiles map[string]*cmpv1alpha1.Profile
+
+	parseProfiles := func(content string) error {
+		contentDom, err := xmlquery.Parse(strings.NewReader(content))
+		Expect(err).To(BeNil())
+		profiles = make(map[string]*cmpv1alpha1.Profile)
+		pb := &cmpv1alpha1.ProfileBundle{ObjectMeta: metav1.ObjectMeta{Name: "ocp4", Namespace: "test"}}
+		return ParseProfilesAndDo(contentDom, pb, "nonce", func(p *cmpv1alpha1.Profile) error {
+			profiles[p.Name] = p
+			return nil
+		})
+	}
+
+	BeforeEach(func() {
+		Expect(parseProfiles(content)).To(Succeed())
+		Expect(profiles).To(HaveLen(3))
+	})
+
+	It("Records the extends chain", func() {
+		Expect(profiles["base"].Extends).To(BeEmpty())
+		Expect(profiles["middle"].Extends).To(Equal([]string{"ocp4-base"}))
+		Expect(profiles["derived"].Extends).To(Equal([]string{"ocp4-middle", "ocp4-base"}))
+	})
+
+	It("Only lists the rules and values the profile sets itself", func() {
+		Expect(profiles["middle"].Rules).To(Equal([]cmpv1alpha1.ProfileRule{
+			"ocp4-etcd-encryption",
+		}))
+		Expect(profiles["middle"].Values).To(BeEmpty())
+		Expect(profiles["derived"].Rules).To(Equal([]cmpv1alpha1.ProfileRule{
+			"ocp4-audit-enabled",
+			"ocp4-kubelet-anonymous-auth",
+		}))
+		Expect(profiles["derived"].Values).To(Equal([]cmpv1alpha1.ProfileValue{
+			"xccdf_org.ssgproject.content_value_var_audit_level",
+		}))
+	})
+
+	It("Selects the rules of the extended profiles", func() {
+		Expect(profiles["middle"].GetEffectiveRules()).To(Equal([]cmpv1alpha1.ProfileRule{
+			"ocp4-audit-enabled",
+			"ocp4-etcd-encryption",
+		}))
+		Expect(profiles["derived"].GetEffectiveRules()).To(Equal([]cmpv1alpha1.ProfileRule{
+			"ocp4-audit-enabled",
+			"ocp4-etcd-encryption",
+			"ocp4-kubelet-anonymous-auth",
+		}))
+	})
+
+	It("Records which profile selected each rule", func() {
+		Expect(profiles["base"].RuleSources).To(BeEmpty())
+		Expect(profiles["derived"].RuleSources).To(Equal([]cmpv1alpha1.ProfileRuleSource{
+			{Rule: "ocp4-audit-enabled", Profile: "ocp4-base"},
+			{Rule: "ocp4-etcd-encryption", Profile: "ocp4-middle"},
+			{Rule: "ocp4-kubelet-anonymous-auth", Profile: "ocp4-derived"},
+		}))
+	})
+
+	It("Records the values each profile sets", func() {
+		Expect(profiles["base"].RefinedValues).To(Equal([]cmpv1alpha1.ProfileRefinedValue{
+			{Variable: "ocp4-var-kubelet-timeout", Selector: "5min", Value: "5m0s", Profile: "ocp4-base"},
+			{Variable: "ocp4-var-audit-level", Selector: "low", Value: "low", Profile: "ocp4-base"},
+		}))
+		Expect(profiles["derived"].RefinedValues).To(Equal([]cmpv1alpha1.ProfileRefinedValue{
+			{Variable: "ocp4-var-kubelet-timeout", Selector: "10min", Value: "10m0s", Profile: "ocp4-middle"},
+			{Variable: "ocp4-var-audit-level", Value: "high", Profile: "ocp4-derived"},
+		}))
+	})
+
+	It("Fails on profiles that extend themselves", func() {
+		cyclic := strings.Replace(content,
+			`id="xccdf_org.ssgproject.content_profile_base"`,
+			`id="xccdf_org.ssgproject.content_profile_base" extends="xccdf_org.ssgproject.content_profile_derived"`, 1)
+		Expect(parseProfiles(cyclic)).ToNot(Succeed())
+	})
+
+	It("Fails on profiles that extend unknown ones", func() {
+		dangling := strings.Replace(content,
+			`extends="xccdf_org.ssgproject.content_profile_base"`,
+			`extends="xccdf_org.ssgproject.content_profile_unknown"`, 1)
+		Expect(parseProfiles(dangling)).ToNot(Succeed())
+	})
+})
@@ -54,8 +54,12 @@ func jsonIsSubsetR(a, b []byte, diff *JSONDiff, prefix interface{}) (bool, *JSON
 	switch ja := jai.(type) {
 	case map[string]interface{}:
 		// Cast B to same type as A
-		// TODO: Add a check to see if this fails
-		jb := jbi.(map[string]interface{})
+		jb, ok := jbi.(map[string]interface{})
+		if !ok {
+			diff.Rows = append(diff.Rows, JSONDiffRow{
+				Key: sprefix, Expected: ja, Got: jbi})
+			return false, diff, nil
+		}
 
 		// Iterate all keys of ja and check if each is present
 		// and equal to the same key in jb
@@ -96,8 +100,12 @@ func jsonIsSubsetR(a, b []byte, diff *JSONDiff, prefix interface{}) (bool, *JSON
 	// Compare arrays
 	case []interface{}:
 		// Case jbi to an array as well
-		// TODO: Add a check to see if this fails
-		jb := jbi.([]interface{})
+		jb, ok := jbi.([]interface{})
+		if !ok {
+			diff.Rows = append(diff.Rows, JSONDiffRow{
+				Key: sprefix, Expected: ja, Got: jbi})
+			return false, diff, nil
+		}
 
 		// Check if length is equal first
 		if len(jb) != len(ja) {
@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"fmt"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+const (
+	// ContentContainerName is the name of the init container that puts the
+	// content in place for the rest of the pod
+	ContentContainerName = "content-container"
+
+	contentSourceVolumeName   = "content-source"
+	contentSourceDir          = "/content-source"
+	contentSourceCAVolumeName = "content-source-ca"
+	contentSourceCADir        = "/content-source-ca"
+	contentSourceCAKey        = "ca-bundle.crt"
+)
+
+// ValidateContentSource checks that exactly one source is set, and that
+// content downloaded over HTTPS has a checksum to be verified against
+func ValidateContentSource(source *compv1alpha1.ContentSource) error {
+	set := 0
+	for _, isSet := range []bool{source.ConfigMap != nil, source.Secret != nil,
+		source.PersistentVolumeClaim != nil, source.HTTPS != nil} {
+		if isSet {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("exactly one of configMap, secret, persistentVolumeClaim or https must be set in the contentSource")
+	}
+	if source.HTTPS != nil && source.Checksum == "" {
+		return fmt.Errorf("a checksum is required to download the content over HTTPS")
+	}
+	return nil
+}
+
+// ValidateContentVolumeAccessModes checks that a PersistentVolumeClaim the
+// content is loaded from can be mounted by several pods at once, which
+// ReadWriteOnce volumes can't be on different nodes
+func ValidateContentVolumeAccessModes(pvc *corev1.PersistentVolumeClaim) error {
+	for _, mode := range pvc.Spec.AccessModes {
+		if mode == corev1.ReadOnlyMany || mode == corev1.ReadWriteMany {
+			return nil
+		}
+	}
+	return fmt.Errorf("the PersistentVolumeClaim %s of the content source needs the %s or %s access mode",
+		pvc.Name, corev1.ReadOnlyMany, corev1.ReadWriteMany)
+}
+
+// SetContentSource replaces the init container that copies the content
+// out of the content image with one that fetches it from the given source
+// into the same place. The pod is left as is if there's no source.
+func SetContentSource(podSpec *corev1.PodSpec, source *compv1alpha1.ContentSource, contentFile string) {
+	if source == nil {
+		return
+	}
+
+	var container *corev1.Container
+	for i := range podSpec.InitContainers {
+		if podSpec.InitContainers[i].Name == ContentContainerName {
+			container = &podSpec.InitContainers[i]
+		}
+	}
+	if container == nil {
+		return
+	}
+
+	container.Image = GetComponentImage(OPERATOR)
+	container.Command = []string{
+		"compliance-operator", "fetch-content",
+		"--output", path.Join("/content", contentFile),
+	}
+	if source.Checksum != "" {
+		container.Command = append(container.Command, "--checksum", source.Checksum)
+	}
+	// The errors of the fetcher are surfaced in the ProfileBundle status
+	container.TerminationMessagePolicy = corev1.TerminationMessageFallbackToLogsOnError
+	// Unlike cp, the fetcher is a Go binary and might need to decompress
+	container.Resources.Limits = corev1.ResourceList{
+		corev1.ResourceMemory: resource.MustParse("100Mi"),
+		corev1.ResourceCPU:    resource.MustParse("100m"),
+	}
+
+	var volumeSource *corev1.VolumeSource
+	switch {
+	case source.ConfigMap != nil:
+		volumeSource = &corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: source.ConfigMap.Name},
+				Items:                []corev1.KeyToPath{{Key: source.ConfigMap.Key, Path: source.ConfigMap.Key}},
+			},
+		}
+		container.Command = append(container.Command, "--file", path.Join(contentSourceDir, source.ConfigMap.Key))
+	case source.Secret != nil:
+		volumeSource = &corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: source.Secret.Name,
+				Items:      []corev1.KeyToPath{{Key: source.Secret.Key, Path: source.Secret.Key}},
+			},
+		}
+		container.Command = append(container.Command, "--file", path.Join(contentSourceDir, source.Secret.Key))
+	case source.PersistentVolumeClaim != nil:
+		volumeSource = &corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: source.PersistentVolumeClaim.ClaimName,
+				ReadOnly:  true,
+			},
+		}
+		container.Command = append(container.Command, "--file", path.Join(contentSourceDir, source.PersistentVolumeClaim.Path))
+	case source.HTTPS != nil:
+		container.Command = append(container.Command, "--url", source.HTTPS.URL)
+		if source.HTTPS.CAConfigMap != "" {
+			addContentSourceVolume(podSpec, container, contentSourceCAVolumeName, contentSourceCADir, corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: source.HTTPS.CAConfigMap},
+					Items:                []corev1.KeyToPath{{Key: contentSourceCAKey, Path: contentSourceCAKey}},
+				},
+			})
+			container.Command = append(container.Command, "--ca-file", path.Join(contentSourceCADir, contentSourceCAKey))
+		}
+	}
+
+	if volumeSource != nil {
+		addContentSourceVolume(podSpec, container, contentSourceVolumeName, contentSourceDir, *volumeSource)
+	}
+}
+
+func addContentSourceVolume(podSpec *corev1.PodSpec, container *corev1.Container, name, mountPath string, source corev1.VolumeSource) {
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name:         name,
+		VolumeSource: source,
+	})
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      name,
+		MountPath: mountPath,
+		ReadOnly:  true,
+	})
+}
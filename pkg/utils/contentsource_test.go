@@ -0,0 +1,95 @@
+package utils
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+var _ = Describe("Content sources", func() {
+	const checksum = "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	var podSpec *corev1.PodSpec
+
+	BeforeEach(func() {
+		podSpec = &corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				{Name: ContentContainerName, Image: "quay.io/compliance/content:latest"},
+				{Name: "profileparser"},
+			},
+		}
+	})
+
+	It("requires exactly one source", func() {
+		Expect(ValidateContentSource(&compv1alpha1.ContentSource{})).ToNot(Succeed())
+		Expect(ValidateContentSource(&compv1alpha1.ContentSource{
+			ConfigMap: &compv1alpha1.ContentKeySelector{Name: "content", Key: "ds.xml"},
+			Secret:    &compv1alpha1.ContentKeySelector{Name: "content", Key: "ds.xml"},
+		})).ToNot(Succeed())
+		Expect(ValidateContentSource(&compv1alpha1.ContentSource{
+			ConfigMap: &compv1alpha1.ContentKeySelector{Name: "content", Key: "ds.xml"},
+		})).To(Succeed())
+	})
+
+	It("requires volumes that several pods can mount", func() {
+		pvc := &corev1.PersistentVolumeClaim{}
+		pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+		Expect(ValidateContentVolumeAccessModes(pvc)).ToNot(Succeed())
+		pvc.Spec.AccessModes = append(pvc.Spec.AccessModes, corev1.ReadOnlyMany)
+		Expect(ValidateContentVolumeAccessModes(pvc)).To(Succeed())
+	})
+
+	It("requires a checksum for HTTPS URLs", func() {
+		source := &compv1alpha1.ContentSource{
+			HTTPS: &compv1alpha1.ContentHTTPSSource{URL: "https://example.com/ssg-ocp4-ds.xml"},
+		}
+		Expect(ValidateContentSource(source)).ToNot(Succeed())
+		source.Checksum = checksum
+		Expect(ValidateContentSource(source)).To(Succeed())
+	})
+
+	It("leaves the pod as is without a source", func() {
+		SetContentSource(podSpec, nil, "ssg-ocp4-ds.xml")
+		Expect(podSpec.InitContainers[0].Image).To(Equal("quay.io/compliance/content:latest"))
+		Expect(podSpec.Volumes).To(BeEmpty())
+	})
+
+	It("fetches the content from a mounted ConfigMap", func() {
+		SetContentSource(podSpec, &compv1alpha1.ContentSource{
+			ConfigMap: &compv1alpha1.ContentKeySelector{Name: "content", Key: "ds.xml.bz2"},
+			Checksum:  checksum,
+		}, "ssg-ocp4-ds.xml")
+
+		container := podSpec.InitContainers[0]
+		Expect(container.Image).To(Equal(GetComponentImage(OPERATOR)))
+		Expect(container.Command).To(Equal([]string{
+			"compliance-operator", "fetch-content",
+			"--output", "/content/ssg-ocp4-ds.xml",
+			"--checksum", checksum,
+			"--file", "/content-source/ds.xml.bz2",
+		}))
+		Expect(container.VolumeMounts).To(ContainElement(corev1.VolumeMount{
+			Name: contentSourceVolumeName, MountPath: contentSourceDir, ReadOnly: true,
+		}))
+		Expect(podSpec.Volumes).To(HaveLen(1))
+		Expect(podSpec.Volumes[0].ConfigMap.Name).To(Equal("content"))
+	})
+
+	It("downloads the content from an HTTPS URL with a custom CA", func() {
+		SetContentSource(podSpec, &compv1alpha1.ContentSource{
+			HTTPS: &compv1alpha1.ContentHTTPSSource{
+				URL:         "https://example.com/ssg-ocp4-ds.xml",
+				CAConfigMap: "content-ca",
+			},
+			Checksum: checksum,
+		}, "ssg-ocp4-ds.xml")
+
+		container := podSpec.InitContainers[0]
+		Expect(container.Command).To(ContainElements("--url", "https://example.com/ssg-ocp4-ds.xml",
+			"--ca-file", "/content-source-ca/ca-bundle.crt"))
+		Expect(podSpec.Volumes).To(HaveLen(1))
+		Expect(podSpec.Volumes[0].ConfigMap.Name).To(Equal("content-ca"))
+	})
+})
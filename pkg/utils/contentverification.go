@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"strings"
+)
+
+// ContentDigest returns the SHA-256 digest of the content in the
+// sha256:<hex> format
+func ContentDigest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// VerifyContentDigest checks that the content has the expected digest
+func VerifyContentDigest(content []byte, expected string) error {
+	if actual := ContentDigest(content); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("the digest of the data stream is %s instead of %s", actual, expected)
+	}
+	return nil
+}
+
+// VerifyContentSignature checks a cosign-style signature of the content: a
+// base64-encoded signature made with the private key matching the given
+// PEM-encoded public key. ECDSA and RSA (PKCS #1 v1.5) signatures are made
+// over the SHA-256 digest of the content, Ed25519 ones over the content
+// itself.
+func VerifyContentSignature(content, publicKeyPEM, signature []byte) error {
+	block, _ := pem.Decode(publicKeyPEM)
+	if block == nil {
+		return fmt.Errorf("the public key isn't PEM-encoded")
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("parsing the public key: %w", err)
+	}
+
+	rawSignature, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(signature)))
+	if err != nil {
+		return fmt.Errorf("the signature isn't base64-encoded: %w", err)
+	}
+
+	digest := sha256.Sum256(content)
+	var valid bool
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(key, digest[:], rawSignature)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], rawSignature) == nil
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, content, rawSignature)
+	default:
+		return fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+	if !valid {
+		return fmt.Errorf("the signature of the data stream doesn't match the public key")
+	}
+	return nil
+}
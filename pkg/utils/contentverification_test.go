@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Verifying content", func() {
+	content := []byte("<ds:data-stream-collection/>")
+	digest := sha256.Sum256(content)
+
+	encodePublicKey := func(publicKey interface{}) []byte {
+		der, err := x509.MarshalPKIXPublicKey(publicKey)
+		Expect(err).To(BeNil())
+		return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	}
+
+	encodeSignature := func(signature []byte) []byte {
+		return []byte(base64.StdEncoding.EncodeToString(signature) + "\n")
+	}
+
+	It("verifies the digest of the content", func() {
+		Expect(VerifyContentDigest(content, ContentDigest(content))).To(Succeed())
+		Expect(VerifyContentDigest([]byte("tampered"), ContentDigest(content))).ToNot(Succeed())
+	})
+
+	It("verifies ECDSA signatures", func() {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).To(BeNil())
+		signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		Expect(err).To(BeNil())
+
+		publicKey := encodePublicKey(&key.PublicKey)
+		Expect(VerifyContentSignature(content, publicKey, encodeSignature(signature))).To(Succeed())
+		Expect(VerifyContentSignature([]byte("tampered"), publicKey, encodeSignature(signature))).ToNot(Succeed())
+	})
+
+	It("verifies RSA signatures", func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).To(BeNil())
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		Expect(err).To(BeNil())
+
+		publicKey := encodePublicKey(&key.PublicKey)
+		Expect(VerifyContentSignature(content, publicKey, encodeSignature(signature))).To(Succeed())
+		Expect(VerifyContentSignature([]byte("tampered"), publicKey, encodeSignature(signature))).ToNot(Succeed())
+	})
+
+	It("verifies Ed25519 signatures", func() {
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).To(BeNil())
+		signature := ed25519.Sign(private, content)
+
+		publicKey := encodePublicKey(public)
+		Expect(VerifyContentSignature(content, publicKey, encodeSignature(signature))).To(Succeed())
+		Expect(VerifyContentSignature([]byte("tampered"), publicKey, encodeSignature(signature))).ToNot(Succeed())
+	})
+
+	It("rejects a public key that isn't PEM-encoded", func() {
+		Expect(VerifyContentSignature(content, []byte("not a key"), []byte(""))).ToNot(Succeed())
+	})
+})
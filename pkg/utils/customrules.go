@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/itchyny/gojq"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+// CustomRuleResult is the result of evaluating a CustomRule, as passed from
+// the platform scan to the aggregator
+type CustomRuleResult struct {
+	// The name of the CustomRule
+	Name         string                                     `json:"name"`
+	Status       compv1alpha1.ComplianceCheckStatus         `json:"status"`
+	Severity     compv1alpha1.ComplianceCheckResultSeverity `json:"severity"`
+	Description  string                                     `json:"description,omitempty"`
+	Instructions string                                     `json:"instructions,omitempty"`
+	Warnings     []string                                   `json:"warnings,omitempty"`
+}
+
+// ParseCustomRuleQueries parses the filter and the expression of a
+// CustomRule, returning an error if any of them isn't valid jq
+func ParseCustomRuleQueries(spec *compv1alpha1.CustomRuleSpec) (filter, expression *gojq.Query, err error) {
+	if spec.Input.Filter != "" {
+		filter, err = gojq.Parse(spec.Input.Filter)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing the filter: %w", err)
+		}
+	}
+	expression, err = gojq.Parse(spec.Expression)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing the expression: %w", err)
+	}
+	return filter, expression, nil
+}
+
+// EvaluateCustomRule evaluates a CustomRule against the resource it
+// checks, which is nil if the resource doesn't exist. Evaluation errors
+// result in the ERROR status, with the error as a warning.
+func EvaluateCustomRule(ctx context.Context, rule *compv1alpha1.CustomRule, resource interface{}) *CustomRuleResult {
+	result := newCustomRuleResult(rule)
+
+	passed, err := evaluateCustomRuleQueries(ctx, &rule.Spec, resource)
+	if err != nil {
+		return CustomRuleErrorResult(rule, err)
+	}
+	if passed {
+		result.Status = compv1alpha1.CheckResultPass
+	} else {
+		result.Status = compv1alpha1.CheckResultFail
+		if rule.Spec.FailureMessage != "" {
+			result.Warnings = append(result.Warnings, rule.Spec.FailureMessage)
+		}
+	}
+	return result
+}
+
+// CustomRuleErrorResult returns the result of a CustomRule that couldn't
+// be evaluated
+func CustomRuleErrorResult(rule *compv1alpha1.CustomRule, err error) *CustomRuleResult {
+	result := newCustomRuleResult(rule)
+	result.Status = compv1alpha1.CheckResultError
+	result.Warnings = append(result.Warnings, err.Error())
+	return result
+}
+
+func newCustomRuleResult(rule *compv1alpha1.CustomRule) *CustomRuleResult {
+	description := rule.Spec.Title
+	if rule.Spec.Description != "" {
+		description = description + "\n" + rule.Spec.Description
+	}
+	return &CustomRuleResult{
+		Name:         rule.Name,
+		Severity:     compv1alpha1.ComplianceCheckResultSeverity(rule.GetSeverity()),
+		Description:  description,
+		Instructions: rule.Spec.Instructions,
+	}
+}
+
+func evaluateCustomRuleQueries(ctx context.Context, spec *compv1alpha1.CustomRuleSpec, resource interface{}) (bool, error) {
+	filter, expression, err := ParseCustomRuleQueries(spec)
+	if err != nil {
+		return false, err
+	}
+
+	input := resource
+	if filter != nil && resource != nil {
+		input, err = runSingleValueQuery(ctx, filter, resource)
+		if err != nil {
+			return false, fmt.Errorf("filtering %s: %w", spec.Input.APIPath, err)
+		}
+	}
+
+	value, err := runSingleValueQuery(ctx, expression, input)
+	if err != nil {
+		return false, fmt.Errorf("evaluating the expression: %w", err)
+	}
+	passed, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("the expression returned a %T instead of a boolean", value)
+	}
+	return passed, nil
+}
+
+func runSingleValueQuery(ctx context.Context, query *gojq.Query, input interface{}) (interface{}, error) {
+	iter := query.RunWithContext(ctx, input)
+	value, ok := iter.Next()
+	if !ok {
+		return nil, fmt.Errorf("no value was returned")
+	}
+	if err, ok := value.(error); ok {
+		return nil, err
+	}
+	if _, more := iter.Next(); more {
+		return nil, fmt.Errorf("more than one value was returned")
+	}
+	return value, nil
+}
+
+// ParseCustomRuleResults parses the results of the CustomRules that a
+// platform scan stored in its result ConfigMap
+func ParseCustomRuleResults(scanName, namespace, data string) ([]*ParseResult, error) {
+	var results []CustomRuleResult
+	if err := json.Unmarshal([]byte(data), &results); err != nil {
+		return nil, fmt.Errorf("parsing the results of the custom rules: %w", err)
+	}
+
+	parsedResults := make([]*ParseResult, 0, len(results))
+	for i := range results {
+		parsedResults = append(parsedResults, &ParseResult{
+			Id:          results[i].Name,
+			CheckResult: newCustomRuleCheckResult(scanName, namespace, &results[i]),
+		})
+	}
+	return parsedResults, nil
+}
+
+func newCustomRuleCheckResult(scanName, namespace string, result *CustomRuleResult) *compv1alpha1.ComplianceCheckResult {
+	return &compv1alpha1.ComplianceCheckResult{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      compv1alpha1.GetCustomRuleCheckResultName(scanName, result.Name),
+			Namespace: namespace,
+			Labels: map[string]string{
+				compv1alpha1.CustomRuleLabel: result.Name,
+			},
+		},
+		ID:           result.Name,
+		Status:       result.Status,
+		Severity:     result.Severity,
+		Description:  result.Description,
+		Instructions: result.Instructions,
+		Warnings:     result.Warnings,
+	}
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Evaluating CustomRules", func() {
+	var (
+		ctx        = context.Background()
+		customRule *compv1alpha1.CustomRule
+		resource   interface{}
+	)
+
+	BeforeEach(func() {
+		customRule = &compv1alpha1.CustomRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "tls-profile-is-modern"},
+			Spec: compv1alpha1.CustomRuleSpec{
+				Title:    "The API server uses the Modern TLS profile",
+				Severity: "high",
+				Input: compv1alpha1.CustomRuleInput{
+					APIPath: "/apis/config.openshift.io/v1/apiservers/cluster",
+					Filter:  ".spec.tlsSecurityProfile",
+				},
+				Expression:     `. != null and .type == "Modern"`,
+				FailureMessage: "The TLS profile isn't Modern",
+			},
+		}
+		Expect(json.Unmarshal([]byte(`{"spec": {"tlsSecurityProfile": {"type": "Modern"}}}`), &resource)).To(Succeed())
+	})
+
+	It("passes when the expression returns true", func() {
+		result := EvaluateCustomRule(ctx, customRule, resource)
+		Expect(result.Name).To(Equal("tls-profile-is-modern"))
+		Expect(result.Status).To(Equal(compv1alpha1.CheckResultPass))
+		Expect(result.Severity).To(BeEquivalentTo("high"))
+		Expect(result.Warnings).To(BeEmpty())
+	})
+
+	It("fails with the failure message when the expression returns false", func() {
+		Expect(json.Unmarshal([]byte(`{"spec": {"tlsSecurityProfile": {"type": "Old"}}}`), &resource)).To(Succeed())
+		result := EvaluateCustomRule(ctx, customRule, resource)
+		Expect(result.Status).To(Equal(compv1alpha1.CheckResultFail))
+		Expect(result.Warnings).To(ConsistOf("The TLS profile isn't Modern"))
+	})
+
+	It("evaluates the expression against null when the resource doesn't exist", func() {
+		result := EvaluateCustomRule(ctx, customRule, nil)
+		Expect(result.Status).To(Equal(compv1alpha1.CheckResultFail))
+	})
+
+	It("reports an error when the expression doesn't return a boolean", func() {
+		customRule.Spec.Expression = ".type"
+		result := EvaluateCustomRule(ctx, customRule, resource)
+		Expect(result.Status).To(Equal(compv1alpha1.CheckResultError))
+		Expect(result.Warnings).To(HaveLen(1))
+	})
+
+	It("reports an error when the filter returns several values", func() {
+		customRule.Spec.Input.Filter = ".spec[], .spec[]"
+		result := EvaluateCustomRule(ctx, customRule, resource)
+		Expect(result.Status).To(Equal(compv1alpha1.CheckResultError))
+	})
+
+	It("parses the results into check results", func() {
+		results := []*CustomRuleResult{EvaluateCustomRule(ctx, customRule, resource)}
+		data, err := json.Marshal(results)
+		Expect(err).To(BeNil())
+
+		parsed, err := ParseCustomRuleResults("ocp4-cis", "openshift-compliance", string(data))
+		Expect(err).To(BeNil())
+		Expect(parsed).To(HaveLen(1))
+		Expect(parsed[0].Id).To(Equal("tls-profile-is-modern"))
+		Expect(parsed[0].CheckResult.Name).To(Equal("ocp4-cis-custom-tls-profile-is-modern"))
+		Expect(parsed[0].CheckResult.Status).To(Equal(compv1alpha1.CheckResultPass))
+		Expect(parsed[0].CheckResult.Labels).To(HaveKeyWithValue(compv1alpha1.CustomRuleLabel, "tls-profile-is-modern"))
+	})
+})
@@ -185,6 +185,39 @@ func AreKubeletConfigsRendered(pool *mcfgv1.MachineConfigPool, client runtimecli
 	}
 }
 
+// IsMcfgPoolUpdated checks whether all the machines of a MachineConfig Pool
+// run its latest rendered config, and whether that config includes the
+// given MachineConfigs. If not, the returned string tells what the pool is
+// still waiting for.
+func IsMcfgPoolUpdated(pool *mcfgv1.MachineConfigPool, machineConfigs []string) (bool, string) {
+	if pool.Spec.Paused {
+		return false, fmt.Sprintf("pool %s is paused", pool.Name)
+	}
+	if pool.Status.ObservedGeneration < pool.Generation {
+		return false, fmt.Sprintf("pool %s wasn't observed by the machine config controller yet", pool.Name)
+	}
+	if pool.Status.Configuration.Name != pool.Spec.Configuration.Name {
+		return false, fmt.Sprintf("pool %s is updating to %s", pool.Name, pool.Spec.Configuration.Name)
+	}
+	if pool.Status.UpdatedMachineCount != pool.Status.MachineCount {
+		return false, fmt.Sprintf("pool %s has %d of %d machines updated", pool.Name,
+			pool.Status.UpdatedMachineCount, pool.Status.MachineCount)
+	}
+	for _, mcName := range machineConfigs {
+		found := false
+		for i := range pool.Status.Configuration.Source {
+			if pool.Status.Configuration.Source[i].Name == mcName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, fmt.Sprintf("machine config %s isn't rendered in pool %s yet", mcName, pool.Name)
+		}
+	}
+	return true, ""
+}
+
 func GetKCFromMC(mc *mcfgv1.MachineConfig, client runtimeclient.Client) (*mcfgv1.KubeletConfig, error) {
 	if mc == nil {
 		return nil, fmt.Errorf("machine config is nil")
@@ -421,8 +421,37 @@ func ParseContent(dsReader io.Reader) (*xmlquery.Node, error) {
 	return dsDom, nil
 }
 
+// ContentTables holds the lookup tables of a data stream that parsing the
+// results of a scan against it needs. Building them once allows parsing the
+// results of many nodes without walking the data stream for each of them.
+type ContentTables struct {
+	rules        NodeByIdHashTable
+	questions    NodeByIdHashTable
+	defs         NodeByIdHashTable
+	ovalTestVars nodeByIdHashVariablesTable
+}
+
+// NewContentTables builds the lookup tables of a data stream
+func NewContentTables(dsDom *xmlquery.Node) *ContentTables {
+	statesTable := newStateHashTable(dsDom)
+	objsTable := newObjHashTable(dsDom)
+	return &ContentTables{
+		rules:        newRuleHashTable(dsDom),
+		questions:    NewOcilQuestionTable(dsDom),
+		defs:         NewDefHashTable(dsDom),
+		ovalTestVars: newValueListTable(dsDom, statesTable, objsTable),
+	}
+}
+
 func ParseResultsFromContentAndXccdf(scheme *runtime.Scheme, scanName string, namespace string,
 	dsDom *xmlquery.Node, resultsReader io.Reader) ([]*ParseResult, error) {
+	return ParseResultsFromContentTablesAndXccdf(scheme, scanName, namespace, NewContentTables(dsDom), resultsReader)
+}
+
+// ParseResultsFromContentTablesAndXccdf parses the results of a scan with
+// the lookup tables of the data stream the scan ran against
+func ParseResultsFromContentTablesAndXccdf(scheme *runtime.Scheme, scanName string, namespace string,
+	tables *ContentTables, resultsReader io.Reader) ([]*ParseResult, error) {
 
 	resultsDom, err := xmlquery.Parse(resultsReader)
 	if err != nil {
@@ -435,12 +464,6 @@ func ParseResultsFromContentAndXccdf(scheme *runtime.Scheme, scanName string, na
 		valuesList[strings.TrimPrefix(codeNode.SelectAttr("idref"), valuePrefix)] = codeNode.InnerText()
 	}
 
-	ruleTable := newRuleHashTable(dsDom)
-	questionsTable := NewOcilQuestionTable(dsDom)
-	statesTable := newStateHashTable(dsDom)
-	objsTable := newObjHashTable(dsDom)
-	defTable := NewDefHashTable(dsDom)
-	ovalTestVarTable := newValueListTable(dsDom, statesTable, objsTable)
 	results := resultsDom.SelectElements("//rule-result")
 	parsedResults := make([]*ParseResult, 0)
 	var remErrs string
@@ -451,13 +474,13 @@ func ParseResultsFromContentAndXccdf(scheme *runtime.Scheme, scanName string, na
 			continue
 		}
 
-		resultRule := ruleTable[ruleIDRef]
+		resultRule := tables.rules[ruleIDRef]
 		if resultRule == nil {
 			continue
 		}
 
-		instructions := GetInstructionsForRule(resultRule, questionsTable)
-		ruleValues := getValueListUsedForRule(resultRule, ovalTestVarTable, defTable, valuesList)
+		instructions := GetInstructionsForRule(resultRule, tables.questions)
+		ruleValues := getValueListUsedForRule(resultRule, tables.ovalTestVars, tables.defs, valuesList)
 		resCheck, err := newComplianceCheckResult(result, resultRule, ruleIDRef, instructions, scanName, namespace, ruleValues)
 		if err != nil {
 			continue
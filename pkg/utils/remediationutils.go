@@ -29,6 +29,18 @@ func IsKubeletConfig(obj *unstructured.Unstructured) bool {
 	return IsKind(obj, "KubeletConfig")
 }
 
+// RemoveEmptyStrings returns the strings of s that aren't empty, such as
+// the items of a comma-separated annotation value after splitting it
+func RemoveEmptyStrings(s []string) []string {
+	var result []string
+	for _, str := range s {
+		if str != "" {
+			result = append(result, str)
+		}
+	}
+	return result
+}
+
 func HaveOutdatedRemediations(client runtimeclient.Client) (error, bool) {
 	remList := &compv1alpha1.ComplianceRemediationList{}
 	listOpts := runtimeclient.ListOptions{
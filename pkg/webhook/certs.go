@@ -0,0 +1,286 @@
+package webhook
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	admissionregv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/utils"
+)
+
+const (
+	// ServiceName is the name of the Service fronting the webhook server
+	ServiceName = "compliance-operator-webhook"
+	// CertSecretName is the name of the Secret holding the webhook PKI
+	CertSecretName = "compliance-operator-webhook-cert"
+	// ConfigurationName is the name of both the validating and the
+	// mutating webhook configuration
+	ConfigurationName = "compliance-operator-webhook"
+
+	caCertName = "compliance-operator-webhook-ca"
+	// the certificates are valid for a year and renewed on operator
+	// start if they're about to expire within a month.
+	certValidityDays = 365
+	certRenewBefore  = 30 * 24 * time.Hour
+)
+
+// EnsureServingCert makes sure that a CA and a serving certificate for the
+// webhook Service exist in the operator namespace, and writes the serving
+// certificate into certDir so the webhook server can pick it up. It returns
+// the CA bundle clients should use to verify the server.
+func EnsureServingCert(ctx context.Context, kClient kubernetes.Interface, ns, certDir string) ([]byte, error) {
+	secret, err := kClient.CoreV1().Secrets(ns).Get(ctx, CertSecretName, metav1.GetOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	if kerrors.IsNotFound(err) || certNeedsRenewal(secret.Data[corev1.TLSCertKey]) {
+		newSecret, genErr := newServingCertSecret(ns)
+		if genErr != nil {
+			return nil, genErr
+		}
+		if kerrors.IsNotFound(err) {
+			secret, err = kClient.CoreV1().Secrets(ns).Create(ctx, newSecret, metav1.CreateOptions{})
+		} else {
+			secretCopy := secret.DeepCopy()
+			secretCopy.Data = newSecret.Data
+			secret, err = kClient.CoreV1().Secrets(ns).Update(ctx, secretCopy, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(certDir, 0700); err != nil {
+		return nil, err
+	}
+	for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		if err := ioutil.WriteFile(filepath.Join(certDir, key), secret.Data[key], 0600); err != nil {
+			return nil, err
+		}
+	}
+
+	return secret.Data["ca.crt"], nil
+}
+
+func newServingCertSecret(ns string) (*corev1.Secret, error) {
+	caCert, caKey, err := utils.ComplianceOperatorRootCA(caCertName, certValidityDays)
+	if err != nil {
+		return nil, fmt.Errorf("creating webhook CA: %w", err)
+	}
+
+	serviceHost := fmt.Sprintf("%s.%s.svc", ServiceName, ns)
+	cert, key, err := utils.NewServerCert(caCert, caKey, serviceHost, certValidityDays)
+	if err != nil {
+		return nil, fmt.Errorf("creating webhook serving certificate: %w", err)
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      CertSecretName,
+			Namespace: ns,
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       cert,
+			corev1.TLSPrivateKeyKey: key,
+			"ca.crt":                caCert,
+		},
+	}, nil
+}
+
+// certNeedsRenewal returns true if the PEM-encoded certificate can't be
+// parsed or expires soon
+func certNeedsRenewal(certPEM []byte) bool {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return true
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return true
+	}
+	return time.Now().Add(certRenewBefore).After(cert.NotAfter)
+}
+
+// EnsureService creates the Service that the API server uses to reach the
+// webhook server
+func EnsureService(ctx context.Context, kClient kubernetes.Interface, ns string) error {
+	_, err := kClient.CoreV1().Services(ns).Create(ctx, &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ServiceName,
+			Namespace: ns,
+			Labels: map[string]string{
+				"name": "compliance-operator",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "webhook",
+					Port:       443,
+					TargetPort: intstr.FromInt(ServerPort),
+					Protocol:   corev1.ProtocolTCP,
+				},
+			},
+			Selector: map[string]string{
+				"name": "compliance-operator",
+			},
+			Type: corev1.ServiceTypeClusterIP,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil && !kerrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// EnsureConfigurations creates or updates the ValidatingWebhookConfiguration
+// and MutatingWebhookConfiguration pointing the API server to the webhook
+// Service. The failure policy is Ignore so that the cluster isn't blocked
+// while the operator isn't running; the controllers still validate their
+// objects when reconciling.
+func EnsureConfigurations(ctx context.Context, kClient kubernetes.Interface, ns string, caBundle []byte) error {
+	if err := ensureValidatingConfiguration(ctx, kClient, newValidatingConfiguration(ns, caBundle)); err != nil {
+		return err
+	}
+	return ensureMutatingConfiguration(ctx, kClient, newMutatingConfiguration(ns, caBundle))
+}
+
+func ensureValidatingConfiguration(ctx context.Context, kClient kubernetes.Interface,
+	config *admissionregv1.ValidatingWebhookConfiguration) error {
+	configs := kClient.AdmissionregistrationV1().ValidatingWebhookConfigurations()
+	_, err := configs.Create(ctx, config, metav1.CreateOptions{})
+	if !kerrors.IsAlreadyExists(err) {
+		return err
+	}
+	found, err := configs.Get(ctx, config.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	foundCopy := found.DeepCopy()
+	foundCopy.Webhooks = config.Webhooks
+	_, err = configs.Update(ctx, foundCopy, metav1.UpdateOptions{})
+	return err
+}
+
+func ensureMutatingConfiguration(ctx context.Context, kClient kubernetes.Interface,
+	config *admissionregv1.MutatingWebhookConfiguration) error {
+	configs := kClient.AdmissionregistrationV1().MutatingWebhookConfigurations()
+	_, err := configs.Create(ctx, config, metav1.CreateOptions{})
+	if !kerrors.IsAlreadyExists(err) {
+		return err
+	}
+	found, err := configs.Get(ctx, config.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	foundCopy := found.DeepCopy()
+	foundCopy.Webhooks = config.Webhooks
+	_, err = configs.Update(ctx, foundCopy, metav1.UpdateOptions{})
+	return err
+}
+
+func newValidatingConfiguration(ns string, caBundle []byte) *admissionregv1.ValidatingWebhookConfiguration {
+	resources := []string{
+		"compliancescans",
+		"compliancesuites",
+		"customrules",
+		"scansettings",
+		"scansettingbindings",
+		"tailoredprofiles",
+	}
+	webhooks := make([]admissionregv1.ValidatingWebhook, 0, len(resources))
+	for _, res := range resources {
+		svc, rules, params := webhookParams(ns, res)
+		webhooks = append(webhooks, admissionregv1.ValidatingWebhook{
+			Name:                    singular(res) + ".validate.compliance.openshift.io",
+			ClientConfig:            admissionregv1.WebhookClientConfig{Service: svc, CABundle: caBundle},
+			Rules:                   rules,
+			FailurePolicy:           params.failurePolicy,
+			SideEffects:             params.sideEffects,
+			AdmissionReviewVersions: params.reviewVersions,
+		})
+	}
+	return &admissionregv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: ConfigurationName},
+		Webhooks:   webhooks,
+	}
+}
+
+func newMutatingConfiguration(ns string, caBundle []byte) *admissionregv1.MutatingWebhookConfiguration {
+	svc, rules, params := webhookParams(ns, "compliancescans")
+	svc.Path = stringPtr(mutatePathPrefix + "compliancescan")
+	// The defaults only matter when creating scans
+	rules[0].Operations = []admissionregv1.OperationType{admissionregv1.Create}
+	return &admissionregv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: ConfigurationName},
+		Webhooks: []admissionregv1.MutatingWebhook{
+			{
+				Name:                    "compliancescan.mutate.compliance.openshift.io",
+				ClientConfig:            admissionregv1.WebhookClientConfig{Service: svc, CABundle: caBundle},
+				Rules:                   rules,
+				FailurePolicy:           params.failurePolicy,
+				SideEffects:             params.sideEffects,
+				AdmissionReviewVersions: params.reviewVersions,
+			},
+		},
+	}
+}
+
+type webhookCommonParams struct {
+	failurePolicy  *admissionregv1.FailurePolicyType
+	sideEffects    *admissionregv1.SideEffectClass
+	reviewVersions []string
+}
+
+// webhookParams returns the service reference, the rules and the settings
+// shared by all webhooks served for the given resource
+func webhookParams(ns, resource string) (*admissionregv1.ServiceReference, []admissionregv1.RuleWithOperations, webhookCommonParams) {
+	failurePolicy := admissionregv1.Ignore
+	sideEffects := admissionregv1.SideEffectClassNone
+	port := int32(443)
+	svc := &admissionregv1.ServiceReference{
+		Namespace: ns,
+		Name:      ServiceName,
+		Path:      stringPtr(validatePathPrefix + singular(resource)),
+		Port:      &port,
+	}
+	rules := []admissionregv1.RuleWithOperations{
+		{
+			Operations: []admissionregv1.OperationType{admissionregv1.Create, admissionregv1.Update},
+			Rule: admissionregv1.Rule{
+				APIGroups:   []string{compv1alpha1.SchemeGroupVersion.Group},
+				APIVersions: []string{compv1alpha1.SchemeGroupVersion.Version},
+				Resources:   []string{resource},
+			},
+		},
+	}
+	return svc, rules, webhookCommonParams{
+		failurePolicy: &failurePolicy,
+		sideEffects:   &sideEffects,
+		// controller-runtime decodes v1beta1 AdmissionReviews
+		reviewVersions: []string{"v1beta1"},
+	}
+}
+
+func singular(resource string) string {
+	return resource[:len(resource)-1]
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+type scanValidator struct{}
+
+func (v *scanValidator) newObject() runtime.Object {
+	return &compv1alpha1.ComplianceScan{}
+}
+
+func (v *scanValidator) unchanged(obj, old runtime.Object) bool {
+	return reflect.DeepEqual(obj.(*compv1alpha1.ComplianceScan).Spec, old.(*compv1alpha1.ComplianceScan).Spec)
+}
+
+func (v *scanValidator) validate(_ context.Context, obj runtime.Object) error {
+	scan := obj.(*compv1alpha1.ComplianceScan)
+	return validateScanSpec(&scan.Spec)
+}
+
+// scanDefaulter sets the same defaults that the ComplianceScan controller
+// would otherwise set when first reconciling a scan
+type scanDefaulter struct{}
+
+func (d *scanDefaulter) newObject() runtime.Object {
+	return &compv1alpha1.ComplianceScan{}
+}
+
+func (d *scanDefaulter) setDefaults(obj runtime.Object) {
+	scan := obj.(*compv1alpha1.ComplianceScan)
+	if scan.Spec.ScanType == "" {
+		scan.Spec.ScanType = compv1alpha1.ScanTypeNode
+	} else if scanType, err := scan.GetScanTypeIfValid(); err == nil {
+		// Normalize the case so that consumers can compare directly
+		scan.Spec.ScanType = scanType
+	}
+
+	if scan.Spec.RawResultStorage.Size == "" {
+		scan.Spec.RawResultStorage.Size = compv1alpha1.DefaultRawStorageSize
+	}
+
+	if len(scan.Spec.RawResultStorage.PVAccessModes) == 0 {
+		scan.Spec.RawResultStorage.PVAccessModes = defaultAccessMode()
+	}
+}
+
+func defaultAccessMode() []corev1.PersistentVolumeAccessMode {
+	return []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
+}
+
+func validateScanSpec(spec *compv1alpha1.ComplianceScanSpec) error {
+	if spec.ScanType != "" {
+		s := compv1alpha1.ComplianceScan{Spec: *spec}
+		if _, err := s.GetScanTypeIfValid(); err != nil {
+			return fmt.Errorf("scan type '%s' is not valid", spec.ScanType)
+		}
+	}
+
+	return validateScanSettings(&spec.ComplianceScanSettings)
+}
+
+// validateScanSettings validates the settings that are shared between a
+// ComplianceScan and the ScanSetting it might have been created from
+func validateScanSettings(settings *compv1alpha1.ComplianceScanSettings) error {
+	storage := settings.RawResultStorage
+	if storage.Size != "" {
+		if _, err := resource.ParseQuantity(storage.Size); err != nil {
+			return fmt.Errorf("raw result storage size '%s' is not valid: %w", storage.Size, err)
+		}
+	}
+
+	for _, mode := range storage.PVAccessModes {
+		switch mode {
+		case corev1.ReadWriteOnce, corev1.ReadOnlyMany, corev1.ReadWriteMany:
+		default:
+			return fmt.Errorf("raw result storage access mode '%s' is not valid", mode)
+		}
+	}
+
+	if settings.CheckResultRetention != nil {
+		return settings.CheckResultRetention.Validate()
+	}
+	return nil
+}
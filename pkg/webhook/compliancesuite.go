@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/robfig/cron/v3"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+type suiteValidator struct{}
+
+func (v *suiteValidator) newObject() runtime.Object {
+	return &compv1alpha1.ComplianceSuite{}
+}
+
+func (v *suiteValidator) unchanged(obj, old runtime.Object) bool {
+	return reflect.DeepEqual(obj.(*compv1alpha1.ComplianceSuite).Spec, old.(*compv1alpha1.ComplianceSuite).Spec)
+}
+
+func (v *suiteValidator) validate(_ context.Context, obj runtime.Object) error {
+	suite := obj.(*compv1alpha1.ComplianceSuite)
+	if err := validateSchedule(suite.Spec.Schedule); err != nil {
+		return err
+	}
+	if err := suite.Spec.ValidateScheduleOptions(); err != nil {
+		return err
+	}
+	if err := suite.Spec.ValidateRescanTriggers(); err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(suite.Spec.Scans))
+	for i := range suite.Spec.Scans {
+		scan := &suite.Spec.Scans[i]
+		if scan.Name == "" {
+			return fmt.Errorf("scan number %d has no name", i)
+		}
+		if seen[scan.Name] {
+			return fmt.Errorf("scan '%s' appears more than once in the suite", scan.Name)
+		}
+		seen[scan.Name] = true
+
+		if err := validateScanSpec(&scan.ComplianceScanSpec); err != nil {
+			return fmt.Errorf("scan '%s': %w", scan.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateSchedule uses the same parser as the ComplianceSuite controller
+func validateSchedule(schedule string) error {
+	if schedule == "" {
+		return nil
+	}
+	if _, err := cron.ParseStandard(schedule); err != nil {
+		return fmt.Errorf("schedule '%s' is not a valid cron expression: %w", schedule, err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	"github.com/openshift/compliance-operator/pkg/utils"
+)
+
+type customRuleValidator struct {
+	client client.Reader
+}
+
+func (v *customRuleValidator) newObject() runtime.Object {
+	return &compv1alpha1.CustomRule{}
+}
+
+func (v *customRuleValidator) unchanged(obj, old runtime.Object) bool {
+	return reflect.DeepEqual(obj.(*compv1alpha1.CustomRule).Spec, old.(*compv1alpha1.CustomRule).Spec)
+}
+
+// validate checks that the filter and the expression of the CustomRule
+// are valid jq and that its name doesn't collide with a rule of the
+// content. Whether they return the right values can only be known once
+// they're evaluated against the resource.
+func (v *customRuleValidator) validate(ctx context.Context, obj runtime.Object) error {
+	customRule := obj.(*compv1alpha1.CustomRule)
+	if customRule.Spec.CheckType != "" && customRule.Spec.CheckType != compv1alpha1.CheckTypePlatform {
+		return fmt.Errorf("only CustomRules of the %s check type are supported", compv1alpha1.CheckTypePlatform)
+	}
+	if customRule.Spec.Expression == "" {
+		return fmt.Errorf("the CustomRule needs an expression")
+	}
+	if _, _, err := utils.ParseCustomRuleQueries(&customRule.Spec); err != nil {
+		return fmt.Errorf("invalid CustomRule: %w", err)
+	}
+	return validateCustomRuleName(ctx, v.client, customRule.Namespace, customRule.Name)
+}
+
+// validateCustomRuleName checks that no rule of the content has the name of
+// the CustomRule, or the name its ComplianceCheckResults are given, as their
+// results would overwrite each other. Lookup errors don't deny the request.
+func validateCustomRuleName(ctx context.Context, c client.Reader, namespace, name string) error {
+	for _, ruleName := range []string{name, compv1alpha1.CustomRuleCheckResultPrefix + "-" + name} {
+		rule := &compv1alpha1.Rule{}
+		err := c.Get(ctx, types.NamespacedName{Name: ruleName, Namespace: namespace}, rule)
+		if err == nil {
+			return fmt.Errorf("the name of CustomRule '%s' collides with Rule '%s' of the content", name, ruleName)
+		} else if !kerrors.IsNotFound(err) {
+			log.Error(err, "Couldn't look up Rule", "Rule.Name", ruleName)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+// roleValRegexp evaluates role values. This needs to be kept in sync
+// with the ScanSettingBinding controller.
+const roleValRegexp = `^([a-zA-Z0-9-]){1,39}$`
+
+type scanSettingValidator struct {
+	roleVal *regexp.Regexp
+}
+
+func newScanSettingValidator() *scanSettingValidator {
+	return &scanSettingValidator{roleVal: regexp.MustCompile(roleValRegexp)}
+}
+
+func (v *scanSettingValidator) newObject() runtime.Object {
+	return &compv1alpha1.ScanSetting{}
+}
+
+func (v *scanSettingValidator) unchanged(obj, old runtime.Object) bool {
+	setting := obj.(*compv1alpha1.ScanSetting)
+	oldSetting := old.(*compv1alpha1.ScanSetting)
+	return reflect.DeepEqual(setting.ComplianceSuiteSettings, oldSetting.ComplianceSuiteSettings) &&
+		reflect.DeepEqual(setting.ComplianceScanSettings, oldSetting.ComplianceScanSettings) &&
+		reflect.DeepEqual(setting.Roles, oldSetting.Roles)
+}
+
+func (v *scanSettingValidator) validate(_ context.Context, obj runtime.Object) error {
+	setting := obj.(*compv1alpha1.ScanSetting)
+	if err := validateSchedule(setting.Schedule); err != nil {
+		return err
+	}
+	if err := setting.ValidateScheduleOptions(); err != nil {
+		return err
+	}
+	if err := setting.ValidateRescanTriggers(); err != nil {
+		return err
+	}
+
+	if err := v.validateRoles(setting.Roles); err != nil {
+		return err
+	}
+
+	return validateScanSettings(&setting.ComplianceScanSettings)
+}
+
+// validateRoles mirrors the checks the ScanSettingBinding controller does
+// before creating per-role scans. Note that empty roles are allowed, they
+// only result in a warning when the setting is used.
+func (v *scanSettingValidator) validateRoles(roles []string) error {
+	if len(roles) == 1 && roles[0] == compv1alpha1.AllRoles {
+		return nil
+	}
+	for _, role := range roles {
+		if role == compv1alpha1.AllRoles {
+			return fmt.Errorf("role %s cannot be used alongside other roles", compv1alpha1.AllRoles)
+		}
+		if !v.roleVal.MatchString(role) {
+			return fmt.Errorf("role %s is invalid", role)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+type bindingValidator struct {
+	client client.Reader
+}
+
+func (v *bindingValidator) newObject() runtime.Object {
+	return &compv1alpha1.ScanSettingBinding{}
+}
+
+func (v *bindingValidator) unchanged(obj, old runtime.Object) bool {
+	ssb := obj.(*compv1alpha1.ScanSettingBinding)
+	oldSsb := old.(*compv1alpha1.ScanSettingBinding)
+	return reflect.DeepEqual(ssb.Profiles, oldSsb.Profiles) && reflect.DeepEqual(ssb.SettingsRef, oldSsb.SettingsRef)
+}
+
+func (v *bindingValidator) validate(ctx context.Context, obj runtime.Object) error {
+	ssb := obj.(*compv1alpha1.ScanSettingBinding)
+	if len(ssb.Profiles) == 0 {
+		return fmt.Errorf("at least one profile needs to be referenced")
+	}
+
+	for i := range ssb.Profiles {
+		ref := &ssb.Profiles[i]
+		if ref.Kind != "Profile" && ref.Kind != "TailoredProfile" {
+			return fmt.Errorf("profile reference '%s' has kind '%s', expected Profile or TailoredProfile", ref.Name, ref.Kind)
+		}
+		if err := validateNamedReference(ref); err != nil {
+			return err
+		}
+		if err := v.validateNotArchived(ctx, ssb.Namespace, ref); err != nil {
+			return err
+		}
+	}
+
+	if ssb.SettingsRef != nil {
+		if ssb.SettingsRef.Kind != "ScanSetting" {
+			return fmt.Errorf("settings reference '%s' has kind '%s', expected ScanSetting",
+				ssb.SettingsRef.Name, ssb.SettingsRef.Kind)
+		}
+		if err := validateNamedReference(ssb.SettingsRef); err != nil {
+			return err
+		}
+	}
+
+	return v.validateSingleProduct(ctx, ssb)
+}
+
+// validateNotArchived makes sure that the binding doesn't reference an
+// archived Profile or a TailoredProfile pinned to a content version. Those
+// are managed by the operator, content versions are pinned through the
+// contentVersions of the binding instead.
+func (v *bindingValidator) validateNotArchived(ctx context.Context, namespace string, ref *compv1alpha1.NamedObjectReference) error {
+	var obj interface {
+		runtime.Object
+		metav1.Object
+	}
+	if ref.Kind == "Profile" {
+		obj = &compv1alpha1.Profile{}
+	} else {
+		obj = &compv1alpha1.TailoredProfile{}
+	}
+	err := v.client.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: namespace}, obj)
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			log.Error(err, "Couldn't look up profile, skipping archived content validation", "Name", ref.Name)
+		}
+		return nil
+	}
+	if compv1alpha1.IsArchivedContent(obj) {
+		return fmt.Errorf("%s '%s' is archived content and can't be bound, pin the content version instead", ref.Kind, ref.Name)
+	}
+	return nil
+}
+
+// validateSingleProduct makes sure that all the node Profiles referenced
+// by the binding are for the same product, otherwise the resulting per-role
+// scans would collide. References that don't exist yet are allowed, as
+// they might be created after the binding.
+func (v *bindingValidator) validateSingleProduct(ctx context.Context, ssb *compv1alpha1.ScanSettingBinding) error {
+	var nodeProduct string
+	for i := range ssb.Profiles {
+		ref := &ssb.Profiles[i]
+		if ref.Kind != "Profile" {
+			continue
+		}
+
+		p := &compv1alpha1.Profile{}
+		err := v.client.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: ssb.Namespace}, p)
+		if err != nil {
+			if !kerrors.IsNotFound(err) {
+				log.Error(err, "Couldn't look up Profile, skipping product validation", "Profile.Name", ref.Name)
+			}
+			continue
+		}
+
+		annotations := p.GetAnnotations()
+		if !strings.EqualFold(annotations[compv1alpha1.ProductTypeAnnotation], string(compv1alpha1.ScanTypeNode)) {
+			continue
+		}
+		product := annotations[compv1alpha1.ProductAnnotation]
+		if product == "" {
+			continue
+		}
+		if nodeProduct == "" {
+			nodeProduct = product
+		} else if nodeProduct != product {
+			return fmt.Errorf("ScanSettingBinding defines multiple products: %s and %s", product, nodeProduct)
+		}
+	}
+	return nil
+}
+
+func validateNamedReference(ref *compv1alpha1.NamedObjectReference) error {
+	if ref.Name == "" {
+		return fmt.Errorf("%s reference has no name", ref.Kind)
+	}
+	if ref.APIGroup != compv1alpha1.SchemeGroupVersion.String() {
+		return fmt.Errorf("%s reference '%s' has apiGroup '%s', expected %s",
+			ref.Kind, ref.Name, ref.APIGroup, compv1alpha1.SchemeGroupVersion.String())
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+type tailoredProfileValidator struct {
+	client client.Reader
+}
+
+func (v *tailoredProfileValidator) newObject() runtime.Object {
+	return &compv1alpha1.TailoredProfile{}
+}
+
+func (v *tailoredProfileValidator) unchanged(obj, old runtime.Object) bool {
+	return reflect.DeepEqual(obj.(*compv1alpha1.TailoredProfile).Spec, old.(*compv1alpha1.TailoredProfile).Spec)
+}
+
+// validate checks the references of the TailoredProfile. Lookup errors
+// other than the object not being found don't deny the request, the
+// TailoredProfile controller will surface those later.
+func (v *tailoredProfileValidator) validate(ctx context.Context, obj runtime.Object) error {
+	tp := obj.(*compv1alpha1.TailoredProfile)
+
+	if tp.Spec.Extends == "" && len(tp.Spec.ExtendsProfiles) == 0 && len(tp.Spec.EnableRules) == 0 &&
+		len(tp.Spec.DisableRules) == 0 && len(tp.Spec.SetValues) == 0 && tp.Spec.ImportTailoring == nil {
+		return fmt.Errorf("the TailoredProfile needs to either extend a Profile, select rules or variables, or import a tailoring file")
+	}
+
+	if tp.Spec.ImportTailoring != nil && len(tp.Spec.ExtendsProfiles) > 0 {
+		return fmt.Errorf("importTailoring and extendsProfiles can't be combined")
+	}
+
+	if tp.Spec.Extends != "" && len(tp.Spec.ExtendsProfiles) > 0 {
+		return fmt.Errorf("extends and extendsProfiles can't be combined")
+	}
+
+	// Copies pinned to a content version reference the content as parsed
+	// from that version, which the TailoredProfile controller resolves
+	if tp.GetPinnedContentDigest() != "" {
+		return nil
+	}
+
+	if err := v.validateExtendedProfiles(ctx, tp); err != nil {
+		return err
+	}
+
+	if err := v.validateRules(ctx, tp); err != nil {
+		return err
+	}
+
+	if err := v.validateCustomRules(ctx, tp); err != nil {
+		return err
+	}
+
+	return v.validateVariables(ctx, tp)
+}
+
+// validateExtendedProfiles checks that the extended Profiles and
+// TailoredProfiles exist. Cycles between TailoredProfiles are detected by
+// the controller, as they can be introduced by updating any of them.
+func (v *tailoredProfileValidator) validateExtendedProfiles(ctx context.Context, tp *compv1alpha1.TailoredProfile) error {
+	seen := make(map[compv1alpha1.ExtendedProfileReference]bool)
+	for _, ref := range tp.GetExtendedProfiles() {
+		if seen[ref] {
+			return fmt.Errorf("the %s '%s' is extended more than once", ref.Kind, ref.Name)
+		}
+		seen[ref] = true
+
+		var extended interface {
+			runtime.Object
+			metav1.Object
+		} = &compv1alpha1.Profile{}
+		if ref.Kind == "TailoredProfile" {
+			if ref.Name == tp.Name {
+				return fmt.Errorf("the TailoredProfile can't extend itself")
+			}
+			extended = &compv1alpha1.TailoredProfile{}
+		}
+		err := v.client.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: tp.Namespace}, extended)
+		if kerrors.IsNotFound(err) || (err == nil && compv1alpha1.IsArchivedContent(extended)) {
+			return fmt.Errorf("the %s '%s' to be extended was not found", ref.Kind, ref.Name)
+		} else if err != nil {
+			log.Error(err, "Couldn't look up extended profile", "Kind", ref.Kind, "Name", ref.Name)
+		}
+	}
+	return nil
+}
+
+func (v *tailoredProfileValidator) validateRules(ctx context.Context, tp *compv1alpha1.TailoredProfile) error {
+	seen := make(map[string]bool, len(tp.Spec.EnableRules)+len(tp.Spec.DisableRules))
+	var expectedCheckType, expectedFrom string
+	for _, selection := range append(tp.Spec.EnableRules, tp.Spec.DisableRules...) {
+		if selection.Selector != nil {
+			if err := validateRuleSelector(&selection); err != nil {
+				return err
+			}
+			continue
+		}
+		if selection.Name == "" {
+			return fmt.Errorf("rule selections need to set either a name or a selector")
+		}
+		if seen[selection.Name] {
+			return fmt.Errorf("rule '%s' appears twice in selections (enableRules or disableRules)", selection.Name)
+		}
+		seen[selection.Name] = true
+
+		rule := &compv1alpha1.Rule{}
+		err := v.client.Get(ctx, types.NamespacedName{Name: selection.Name, Namespace: tp.Namespace}, rule)
+		if kerrors.IsNotFound(err) || (err == nil && compv1alpha1.IsArchivedContent(rule)) {
+			return fmt.Errorf("rule '%s' was not found", selection.Name)
+		} else if err != nil {
+			log.Error(err, "Couldn't look up Rule", "Rule.Name", selection.Name)
+			continue
+		}
+
+		// CheckTypeNone fits every type since it's merely informational
+		if rule.CheckType == compv1alpha1.CheckTypeNone {
+			continue
+		}
+		if expectedCheckType == "" {
+			expectedCheckType = rule.CheckType
+			expectedFrom = rule.Name
+		} else if expectedCheckType != rule.CheckType {
+			return fmt.Errorf("rule '%s' with type '%s' doesn't match the type '%s' of rule '%s'",
+				rule.Name, rule.CheckType, expectedCheckType, expectedFrom)
+		}
+	}
+	return nil
+}
+
+// validateRuleSelector checks that a selection with a selector doesn't
+// also set a name and that the selector won't select every rule. Which
+// rules the selector matches is resolved by the controller, as it changes
+// along with the content.
+func validateRuleSelector(selection *compv1alpha1.RuleReferenceSpec) error {
+	if selection.Name != "" {
+		return fmt.Errorf("rule selection '%s' can't set both a name and a selector", selection.Name)
+	}
+	if selection.Selector.IsEmpty() {
+		return fmt.Errorf("rule selectors need to set at least one criterion")
+	}
+	if selection.Selector.LabelSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(selection.Selector.LabelSelector); err != nil {
+			return fmt.Errorf("invalid label selector in rule selector: %w", err)
+		}
+	}
+	return nil
+}
+
+// validateCustomRules checks that the enabled CustomRules exist and don't
+// collide with the rules of the content. Whether
+// the TailoredProfile is of the Platform type is checked by the
+// controller, as it might depend on the extended Profile.
+func (v *tailoredProfileValidator) validateCustomRules(ctx context.Context, tp *compv1alpha1.TailoredProfile) error {
+	seen := make(map[string]bool, len(tp.Spec.CustomRules))
+	for _, selection := range tp.Spec.CustomRules {
+		if selection.Selector != nil {
+			return fmt.Errorf("CustomRules can only be enabled by name")
+		}
+		if selection.Name == "" {
+			return fmt.Errorf("CustomRule selections need to set a name")
+		}
+		if seen[selection.Name] {
+			return fmt.Errorf("CustomRule '%s' appears twice in customRules", selection.Name)
+		}
+		seen[selection.Name] = true
+
+		customRule := &compv1alpha1.CustomRule{}
+		err := v.client.Get(ctx, types.NamespacedName{Name: selection.Name, Namespace: tp.Namespace}, customRule)
+		if kerrors.IsNotFound(err) {
+			return fmt.Errorf("CustomRule '%s' was not found", selection.Name)
+		} else if err != nil {
+			log.Error(err, "Couldn't look up CustomRule", "CustomRule.Name", selection.Name)
+		}
+		// The content might have gained a colliding rule since the
+		// CustomRule was created
+		if err := validateCustomRuleName(ctx, v.client, tp.Namespace, selection.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *tailoredProfileValidator) validateVariables(ctx context.Context, tp *compv1alpha1.TailoredProfile) error {
+	seen := make(map[string]bool, len(tp.Spec.SetValues))
+	for _, setValue := range tp.Spec.SetValues {
+		if seen[setValue.Name] {
+			return fmt.Errorf("variable '%s' is set more than once", setValue.Name)
+		}
+		seen[setValue.Name] = true
+
+		variable := &compv1alpha1.Variable{}
+		err := v.client.Get(ctx, types.NamespacedName{Name: setValue.Name, Namespace: tp.Namespace}, variable)
+		if kerrors.IsNotFound(err) || (err == nil && compv1alpha1.IsArchivedContent(variable)) {
+			return fmt.Errorf("variable '%s' was not found", setValue.Name)
+		} else if err != nil {
+			log.Error(err, "Couldn't look up Variable", "Variable.Name", setValue.Name)
+			continue
+		}
+
+		// SetValue validates the value against the variable's type and
+		// selections, same as the TailoredProfile controller does
+		if err := variable.SetValue(setValue.Value); err != nil {
+			return fmt.Errorf("setting variable '%s': %w", setValue.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	crwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var log = logf.Log.WithName("webhook")
+
+const (
+	// ServerPort is the port the webhook server listens on inside the
+	// operator pod
+	ServerPort = 9443
+	// DefaultCertDir is where the serving certificate of the webhook
+	// server is written to
+	DefaultCertDir = "/var/run/secrets/webhook-cert"
+
+	validatePathPrefix = "/validate-compliance-openshift-io-v1alpha1-"
+	mutatePathPrefix   = "/mutate-compliance-openshift-io-v1alpha1-"
+)
+
+// validator validates a single kind of object. Validation errors are
+// returned to the user as the reason the request was denied.
+type validator interface {
+	// newObject returns an empty object of the validated kind
+	newObject() runtime.Object
+	// unchanged returns true if an update didn't touch any of the
+	// fields subject to validation. This prevents us from blocking
+	// updates done by the operator itself (e.g. finalizers or
+	// annotations) on objects that became invalid after creation.
+	unchanged(obj, old runtime.Object) bool
+	validate(ctx context.Context, obj runtime.Object) error
+}
+
+// defaulter sets defaults on a single kind of object
+type defaulter interface {
+	newObject() runtime.Object
+	setDefaults(obj runtime.Object)
+}
+
+type validatingHandler struct {
+	validator validator
+	decoder   *admission.Decoder
+}
+
+var _ admission.Handler = &validatingHandler{}
+
+func (h *validatingHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	obj := h.validator.newObject()
+	if err := h.decoder.Decode(req, obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	// Objects that are going away don't need to be valid anymore
+	if accessor, err := meta.Accessor(obj); err == nil && accessor.GetDeletionTimestamp() != nil {
+		return admission.Allowed("")
+	}
+
+	if req.Operation == admissionv1beta1.Update {
+		old := h.validator.newObject()
+		if err := h.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if h.validator.unchanged(obj, old) {
+			return admission.Allowed("")
+		}
+	}
+
+	if err := h.validator.validate(ctx, obj); err != nil {
+		log.Info("Denying request", "Request.Kind", req.Kind.Kind,
+			"Request.Namespace", req.Namespace, "Request.Name", req.Name, "reason", err.Error())
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
+
+type defaultingHandler struct {
+	defaulter defaulter
+	decoder   *admission.Decoder
+}
+
+var _ admission.Handler = &defaultingHandler{}
+
+func (h *defaultingHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	obj := h.defaulter.newObject()
+	if err := h.decoder.Decode(req, obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	h.defaulter.setDefaults(obj)
+
+	marshaled, err := json.Marshal(obj)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+// handlerPaths maps the path a handler is served on to the handler itself
+func handlerPaths(c client.Reader, decoder *admission.Decoder) map[string]admission.Handler {
+	validating := func(v validator) admission.Handler {
+		return &validatingHandler{validator: v, decoder: decoder}
+	}
+	return map[string]admission.Handler{
+		mutatePathPrefix + "compliancescan":       &defaultingHandler{defaulter: &scanDefaulter{}, decoder: decoder},
+		validatePathPrefix + "compliancescan":     validating(&scanValidator{}),
+		validatePathPrefix + "compliancesuite":    validating(&suiteValidator{}),
+		validatePathPrefix + "customrule":         validating(&customRuleValidator{client: c}),
+		validatePathPrefix + "scansetting":        validating(newScanSettingValidator()),
+		validatePathPrefix + "scansettingbinding": validating(&bindingValidator{client: c}),
+		validatePathPrefix + "tailoredprofile":    validating(&tailoredProfileValidator{client: c}),
+	}
+}
+
+// AddToManager registers the validating and defaulting webhooks of the
+// compliance-operator objects with the manager's webhook server.
+func AddToManager(mgr manager.Manager, certDir string) error {
+	decoder, err := admission.NewDecoder(mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+
+	srv := mgr.GetWebhookServer()
+	srv.Port = ServerPort
+	srv.CertDir = certDir
+
+	// We use the API reader so that lookups work for objects in
+	// namespaces that the manager's cache doesn't track.
+	for path, h := range handlerPaths(mgr.GetAPIReader(), decoder) {
+		srv.Register(path, &crwebhook.Admission{Handler: h})
+	}
+	return nil
+}
@@ -0,0 +1,584 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/openshift/compliance-operator/pkg/apis"
+	compv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+func newRequest(op admissionv1beta1.Operation, obj, old runtime.Object) admission.Request {
+	req := admission.Request{
+		AdmissionRequest: admissionv1beta1.AdmissionRequest{
+			Operation: op,
+		},
+	}
+	raw, err := json.Marshal(obj)
+	Expect(err).To(BeNil())
+	req.Object = runtime.RawExtension{Raw: raw}
+	if old != nil {
+		rawOld, err := json.Marshal(old)
+		Expect(err).To(BeNil())
+		req.OldObject = runtime.RawExtension{Raw: rawOld}
+	}
+	return req
+}
+
+var _ = Describe("Webhooks", func() {
+	var (
+		ctx       = context.Background()
+		namespace = "test-ns"
+		handlers  map[string]admission.Handler
+	)
+
+	handle := func(kind string, op admissionv1beta1.Operation, obj, old runtime.Object) admission.Response {
+		h, ok := handlers[validatePathPrefix+kind]
+		Expect(ok).To(BeTrue())
+		return h.Handle(ctx, newRequest(op, obj, old))
+	}
+
+	BeforeEach(func() {
+		cscheme := scheme.Scheme
+		Expect(apis.AddToScheme(cscheme)).To(BeNil())
+
+		objs := []runtime.Object{
+			&compv1alpha1.Profile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rhcos4-moderate",
+					Namespace: namespace,
+					Annotations: map[string]string{
+						compv1alpha1.ProductTypeAnnotation: "Node",
+						compv1alpha1.ProductAnnotation:     "rhcos4",
+					},
+				},
+			},
+			&compv1alpha1.Profile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "other-moderate",
+					Namespace: namespace,
+					Annotations: map[string]string{
+						compv1alpha1.ProductTypeAnnotation: "Node",
+						compv1alpha1.ProductAnnotation:     "other",
+					},
+				},
+			},
+			&compv1alpha1.Profile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ocp4-moderate",
+					Namespace: namespace,
+					Annotations: map[string]string{
+						compv1alpha1.ProductTypeAnnotation: "Platform",
+						compv1alpha1.ProductAnnotation:     "ocp4",
+					},
+				},
+			},
+			&compv1alpha1.Profile{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rhcos4-moderate-111111111111",
+					Namespace: namespace,
+					Labels:    map[string]string{compv1alpha1.ArchivedContentLabel: "rhcos4"},
+				},
+			},
+			&compv1alpha1.Rule{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "node-rule-111111111111",
+					Namespace: namespace,
+					Labels:    map[string]string{compv1alpha1.ArchivedContentLabel: "rhcos4"},
+				},
+			},
+			&compv1alpha1.Rule{
+				ObjectMeta:  metav1.ObjectMeta{Name: "node-rule", Namespace: namespace},
+				RulePayload: compv1alpha1.RulePayload{CheckType: compv1alpha1.CheckTypeNode},
+			},
+			&compv1alpha1.Rule{
+				ObjectMeta:  metav1.ObjectMeta{Name: "platform-rule", Namespace: namespace},
+				RulePayload: compv1alpha1.RulePayload{CheckType: compv1alpha1.CheckTypePlatform},
+			},
+			&compv1alpha1.Rule{
+				ObjectMeta:  metav1.ObjectMeta{Name: "custom-legacy-check", Namespace: namespace},
+				RulePayload: compv1alpha1.RulePayload{CheckType: compv1alpha1.CheckTypePlatform},
+			},
+			&compv1alpha1.CustomRule{
+				ObjectMeta: metav1.ObjectMeta{Name: "custom-rule", Namespace: namespace},
+				Spec: compv1alpha1.CustomRuleSpec{
+					Title:      "A custom rule",
+					Input:      compv1alpha1.CustomRuleInput{APIPath: "/api/v1/namespaces/default"},
+					Expression: `.metadata.labels["secure"] == "true"`,
+				},
+			},
+			&compv1alpha1.CustomRule{
+				ObjectMeta: metav1.ObjectMeta{Name: "platform-rule", Namespace: namespace},
+				Spec: compv1alpha1.CustomRuleSpec{
+					Title:      "A custom rule created before the content had a rule of the same name",
+					Input:      compv1alpha1.CustomRuleInput{APIPath: "/api/v1/namespaces/default"},
+					Expression: `.metadata.labels["secure"] == "true"`,
+				},
+			},
+			&compv1alpha1.Variable{
+				ObjectMeta: metav1.ObjectMeta{Name: "int-var", Namespace: namespace},
+				VariablePayload: compv1alpha1.VariablePayload{
+					Type: compv1alpha1.VarTypeNumber,
+				},
+			},
+		}
+		client := fake.NewFakeClientWithScheme(cscheme, objs...)
+		decoder, err := admission.NewDecoder(cscheme)
+		Expect(err).To(BeNil())
+		handlers = handlerPaths(client, decoder)
+	})
+
+	Context("validating ComplianceScans", func() {
+		It("allows a valid scan", func() {
+			scan := &compv1alpha1.ComplianceScan{
+				ObjectMeta: metav1.ObjectMeta{Name: "scan", Namespace: namespace},
+				Spec:       compv1alpha1.ComplianceScanSpec{ScanType: "platform"},
+			}
+			Expect(handle("compliancescan", admissionv1beta1.Create, scan, nil).Allowed).To(BeTrue())
+		})
+
+		It("denies an unknown scan type", func() {
+			scan := &compv1alpha1.ComplianceScan{
+				ObjectMeta: metav1.ObjectMeta{Name: "scan", Namespace: namespace},
+				Spec:       compv1alpha1.ComplianceScanSpec{ScanType: "foo"},
+			}
+			Expect(handle("compliancescan", admissionv1beta1.Create, scan, nil).Allowed).To(BeFalse())
+		})
+
+		It("denies an invalid storage size", func() {
+			scan := &compv1alpha1.ComplianceScan{
+				ObjectMeta: metav1.ObjectMeta{Name: "scan", Namespace: namespace},
+			}
+			scan.Spec.RawResultStorage.Size = "lots"
+			Expect(handle("compliancescan", admissionv1beta1.Create, scan, nil).Allowed).To(BeFalse())
+		})
+
+		It("denies an invalid check result retention status", func() {
+			scan := &compv1alpha1.ComplianceScan{
+				ObjectMeta: metav1.ObjectMeta{Name: "scan", Namespace: namespace},
+			}
+			scan.Spec.CheckResultRetention = &compv1alpha1.CheckResultRetentionSettings{
+				Statuses: []compv1alpha1.ComplianceCheckStatus{compv1alpha1.CheckResultFail, "FAILED"},
+			}
+			Expect(handle("compliancescan", admissionv1beta1.Create, scan, nil).Allowed).To(BeFalse())
+			scan.Spec.CheckResultRetention.Statuses[1] = compv1alpha1.CheckResultManual
+			Expect(handle("compliancescan", admissionv1beta1.Create, scan, nil).Allowed).To(BeTrue())
+		})
+
+		It("allows updates that don't touch the spec of an invalid scan", func() {
+			scan := &compv1alpha1.ComplianceScan{
+				ObjectMeta: metav1.ObjectMeta{Name: "scan", Namespace: namespace},
+				Spec:       compv1alpha1.ComplianceScanSpec{ScanType: "foo"},
+			}
+			updated := scan.DeepCopy()
+			updated.SetFinalizers([]string{"foo"})
+			Expect(handle("compliancescan", admissionv1beta1.Update, updated, scan).Allowed).To(BeTrue())
+		})
+	})
+
+	Context("defaulting ComplianceScans", func() {
+		It("sets the scan type, storage size and access modes", func() {
+			scan := &compv1alpha1.ComplianceScan{
+				ObjectMeta: metav1.ObjectMeta{Name: "scan", Namespace: namespace},
+			}
+			h := handlers[mutatePathPrefix+"compliancescan"]
+			resp := h.Handle(ctx, newRequest(admissionv1beta1.Create, scan, nil))
+			Expect(resp.Allowed).To(BeTrue())
+
+			paths := map[string]interface{}{}
+			for _, p := range resp.Patches {
+				paths[p.Path] = p.Value
+			}
+			Expect(paths).To(HaveKeyWithValue("/spec/scanType", "Node"))
+			Expect(paths).To(HaveKeyWithValue("/spec/rawResultStorage/size", "1Gi"))
+			Expect(paths).To(HaveKey("/spec/rawResultStorage/pvAccessModes"))
+		})
+	})
+
+	Context("validating ComplianceSuites", func() {
+		It("denies an invalid schedule", func() {
+			suite := &compv1alpha1.ComplianceSuite{
+				ObjectMeta: metav1.ObjectMeta{Name: "suite", Namespace: namespace},
+			}
+			suite.Spec.Schedule = "every other day"
+			Expect(handle("compliancesuite", admissionv1beta1.Create, suite, nil).Allowed).To(BeFalse())
+		})
+
+		It("denies invalid schedule options", func() {
+			suite := &compv1alpha1.ComplianceSuite{
+				ObjectMeta: metav1.ObjectMeta{Name: "suite", Namespace: namespace},
+			}
+			suite.Spec.Schedule = "0 1 * * *"
+			suite.Spec.ScheduleJitter = "soon"
+			Expect(handle("compliancesuite", admissionv1beta1.Create, suite, nil).Allowed).To(BeFalse())
+
+			suite.Spec.ScheduleJitter = "1h"
+			suite.Spec.TimeZone = "Local"
+			Expect(handle("compliancesuite", admissionv1beta1.Create, suite, nil).Allowed).To(BeFalse())
+
+			suite.Spec.TimeZone = "America/New_York"
+			Expect(handle("compliancesuite", admissionv1beta1.Create, suite, nil).Allowed).To(BeTrue())
+		})
+
+		It("denies invalid rescan triggers", func() {
+			suite := &compv1alpha1.ComplianceSuite{
+				ObjectMeta: metav1.ObjectMeta{Name: "suite", Namespace: namespace},
+			}
+			suite.Spec.RescanTriggers = []compv1alpha1.RescanTrigger{{APIVersion: "config.openshift.io/v1"}}
+			Expect(handle("compliancesuite", admissionv1beta1.Create, suite, nil).Allowed).To(BeFalse())
+
+			suite.Spec.RescanTriggers[0].Kind = "APIServer"
+			suite.Spec.RescanTriggerDelay = "-1m"
+			Expect(handle("compliancesuite", admissionv1beta1.Create, suite, nil).Allowed).To(BeFalse())
+
+			suite.Spec.RescanTriggerDelay = "10m"
+			Expect(handle("compliancesuite", admissionv1beta1.Create, suite, nil).Allowed).To(BeTrue())
+		})
+
+		It("denies duplicate scan names", func() {
+			suite := &compv1alpha1.ComplianceSuite{
+				ObjectMeta: metav1.ObjectMeta{Name: "suite", Namespace: namespace},
+				Spec: compv1alpha1.ComplianceSuiteSpec{
+					Scans: []compv1alpha1.ComplianceScanSpecWrapper{{Name: "a"}, {Name: "a"}},
+				},
+			}
+			Expect(handle("compliancesuite", admissionv1beta1.Create, suite, nil).Allowed).To(BeFalse())
+		})
+
+		It("allows a valid suite", func() {
+			suite := &compv1alpha1.ComplianceSuite{
+				ObjectMeta: metav1.ObjectMeta{Name: "suite", Namespace: namespace},
+				Spec: compv1alpha1.ComplianceSuiteSpec{
+					Scans: []compv1alpha1.ComplianceScanSpecWrapper{{Name: "a"}, {Name: "b"}},
+				},
+			}
+			suite.Spec.Schedule = "0 1 * * *"
+			Expect(handle("compliancesuite", admissionv1beta1.Create, suite, nil).Allowed).To(BeTrue())
+		})
+	})
+
+	Context("validating ScanSettings", func() {
+		It("denies mixing @all with other roles", func() {
+			setting := &compv1alpha1.ScanSetting{
+				ObjectMeta: metav1.ObjectMeta{Name: "setting", Namespace: namespace},
+				Roles:      []string{compv1alpha1.AllRoles, "worker"},
+			}
+			Expect(handle("scansetting", admissionv1beta1.Create, setting, nil).Allowed).To(BeFalse())
+		})
+
+		It("denies invalid roles", func() {
+			setting := &compv1alpha1.ScanSetting{
+				ObjectMeta: metav1.ObjectMeta{Name: "setting", Namespace: namespace},
+				Roles:      []string{"wo/rker"},
+			}
+			Expect(handle("scansetting", admissionv1beta1.Create, setting, nil).Allowed).To(BeFalse())
+		})
+
+		It("allows the default settings", func() {
+			setting := &compv1alpha1.ScanSetting{
+				ObjectMeta: metav1.ObjectMeta{Name: "setting", Namespace: namespace},
+				Roles:      []string{"master", "worker"},
+			}
+			setting.Schedule = "0 1 * * *"
+			Expect(handle("scansetting", admissionv1beta1.Create, setting, nil).Allowed).To(BeTrue())
+		})
+	})
+
+	Context("validating ScanSettingBindings", func() {
+		newBinding := func(profiles ...string) *compv1alpha1.ScanSettingBinding {
+			ssb := &compv1alpha1.ScanSettingBinding{
+				ObjectMeta: metav1.ObjectMeta{Name: "binding", Namespace: namespace},
+				SettingsRef: &compv1alpha1.NamedObjectReference{
+					Name:     "default",
+					Kind:     "ScanSetting",
+					APIGroup: "compliance.openshift.io/v1alpha1",
+				},
+			}
+			for _, p := range profiles {
+				ssb.Profiles = append(ssb.Profiles, compv1alpha1.NamedObjectReference{
+					Name:     p,
+					Kind:     "Profile",
+					APIGroup: "compliance.openshift.io/v1alpha1",
+				})
+			}
+			return ssb
+		}
+
+		It("allows a node and a platform profile", func() {
+			ssb := newBinding("rhcos4-moderate", "ocp4-moderate")
+			Expect(handle("scansettingbinding", admissionv1beta1.Create, ssb, nil).Allowed).To(BeTrue())
+		})
+
+		It("allows profiles that don't exist yet", func() {
+			ssb := newBinding("rhcos4-moderate", "not-yet-parsed")
+			Expect(handle("scansettingbinding", admissionv1beta1.Create, ssb, nil).Allowed).To(BeTrue())
+		})
+
+		It("denies multiple node products", func() {
+			ssb := newBinding("rhcos4-moderate", "other-moderate")
+			resp := handle("scansettingbinding", admissionv1beta1.Create, ssb, nil)
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(string(resp.Result.Reason)).To(ContainSubstring("multiple products"))
+		})
+
+		It("denies archived profiles", func() {
+			ssb := newBinding("rhcos4-moderate-111111111111")
+			resp := handle("scansettingbinding", admissionv1beta1.Create, ssb, nil)
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(string(resp.Result.Reason)).To(ContainSubstring("archived"))
+		})
+
+		It("denies references of the wrong kind", func() {
+			ssb := newBinding("rhcos4-moderate")
+			ssb.SettingsRef.Kind = "Profile"
+			Expect(handle("scansettingbinding", admissionv1beta1.Create, ssb, nil).Allowed).To(BeFalse())
+		})
+	})
+
+	Context("validating TailoredProfiles", func() {
+		newTP := func() *compv1alpha1.TailoredProfile {
+			return &compv1alpha1.TailoredProfile{
+				ObjectMeta: metav1.ObjectMeta{Name: "tp", Namespace: namespace},
+				Spec: compv1alpha1.TailoredProfileSpec{
+					Extends: "rhcos4-moderate",
+				},
+			}
+		}
+
+		It("allows a valid TailoredProfile", func() {
+			tp := newTP()
+			tp.Spec.DisableRules = []compv1alpha1.RuleReferenceSpec{{Name: "node-rule"}}
+			tp.Spec.SetValues = []compv1alpha1.VariableValueSpec{{Name: "int-var", Value: "5"}}
+			Expect(handle("tailoredprofile", admissionv1beta1.Create, tp, nil).Allowed).To(BeTrue())
+		})
+
+		It("denies extending a Profile that doesn't exist", func() {
+			tp := newTP()
+			tp.Spec.Extends = "nonexistent"
+			Expect(handle("tailoredprofile", admissionv1beta1.Create, tp, nil).Allowed).To(BeFalse())
+		})
+
+		It("denies combining extends and extendsProfiles", func() {
+			tp := newTP()
+			tp.Spec.ExtendsProfiles = []compv1alpha1.ExtendedProfileReference{{Name: "ocp4-moderate"}}
+			resp := handle("tailoredprofile", admissionv1beta1.Create, tp, nil)
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(string(resp.Result.Reason)).To(ContainSubstring("can't be combined"))
+		})
+
+		It("allows a TailoredProfile that only imports a tailoring file", func() {
+			tp := newTP()
+			tp.Spec.Extends = ""
+			tp.Spec.ImportTailoring = &compv1alpha1.TailoringImportSource{ConfigMap: "workbench", ProfileBundle: "rhcos4"}
+			Expect(handle("tailoredprofile", admissionv1beta1.Create, tp, nil).Allowed).To(BeTrue())
+		})
+
+		It("denies combining importTailoring and extendsProfiles", func() {
+			tp := newTP()
+			tp.Spec.Extends = ""
+			tp.Spec.ExtendsProfiles = []compv1alpha1.ExtendedProfileReference{{Name: "ocp4-moderate"}}
+			tp.Spec.ImportTailoring = &compv1alpha1.TailoringImportSource{ConfigMap: "workbench", ProfileBundle: "rhcos4"}
+			resp := handle("tailoredprofile", admissionv1beta1.Create, tp, nil)
+			Expect(resp.Allowed).To(BeFalse())
+			Expect(string(resp.Result.Reason)).To(ContainSubstring("importTailoring and extendsProfiles"))
+		})
+
+		It("denies a TailoredProfile extending itself", func() {
+			tp := newTP()
+			tp.Spec.Extends = ""
+			tp.Spec.ExtendsProfiles = []compv1alpha1.ExtendedProfileReference{
+				{Name: "rhcos4-moderate"},
+				{Name: "tp", Kind: "TailoredProfile"},
+			}
+			Expect(handle("tailoredprofile", admissionv1beta1.Create, tp, nil).Allowed).To(BeFalse())
+		})
+
+		It("denies extending a TailoredProfile that doesn't exist", func() {
+			tp := newTP()
+			tp.Spec.Extends = ""
+			tp.Spec.ExtendsProfiles = []compv1alpha1.ExtendedProfileReference{
+				{Name: "rhcos4-moderate"},
+				{Name: "nonexistent", Kind: "TailoredProfile"},
+			}
+			Expect(handle("tailoredprofile", admissionv1beta1.Create, tp, nil).Allowed).To(BeFalse())
+		})
+
+		It("allows selecting rules with selectors", func() {
+			tp := newTP()
+			tp.Spec.EnableRules = []compv1alpha1.RuleReferenceSpec{{
+				Selector: &compv1alpha1.RuleSelectorSpec{Severities: []string{"high"}},
+			}}
+			Expect(handle("tailoredprofile", admissionv1beta1.Create, tp, nil).Allowed).To(BeTrue())
+		})
+
+		It("denies selectors that would select every rule", func() {
+			tp := newTP()
+			tp.Spec.DisableRules = []compv1alpha1.RuleReferenceSpec{{
+				Selector: &compv1alpha1.RuleSelectorSpec{},
+			}}
+			Expect(handle("tailoredprofile", admissionv1beta1.Create, tp, nil).Allowed).To(BeFalse())
+		})
+
+		It("denies selections with both a name and a selector", func() {
+			tp := newTP()
+			tp.Spec.EnableRules = []compv1alpha1.RuleReferenceSpec{{
+				Name:     "node-rule",
+				Selector: &compv1alpha1.RuleSelectorSpec{CheckType: compv1alpha1.CheckTypeNode},
+			}}
+			Expect(handle("tailoredprofile", admissionv1beta1.Create, tp, nil).Allowed).To(BeFalse())
+		})
+
+		It("denies nonexistent rules", func() {
+			tp := newTP()
+			tp.Spec.EnableRules = []compv1alpha1.RuleReferenceSpec{{Name: "nonexistent"}}
+			Expect(handle("tailoredprofile", admissionv1beta1.Create, tp, nil).Allowed).To(BeFalse())
+		})
+
+		It("denies archived profiles and rules", func() {
+			tp := newTP()
+			tp.Spec.Extends = "rhcos4-moderate-111111111111"
+			Expect(handle("tailoredprofile", admissionv1beta1.Create, tp, nil).Allowed).To(BeFalse())
+
+			tp = newTP()
+			tp.Spec.EnableRules = []compv1alpha1.RuleReferenceSpec{{Name: "node-rule-111111111111"}}
+			Expect(handle("tailoredprofile", admissionv1beta1.Create, tp, nil).Allowed).To(BeFalse())
+		})
+
+		It("doesn't look up the references of pinned copies", func() {
+			tp := newTP()
+			tp.Annotations = map[string]string{compv1alpha1.PinnedContentDigestAnnotation: "sha256:1111111111111111"}
+			tp.Spec.EnableRules = []compv1alpha1.RuleReferenceSpec{{Name: "removed-since"}}
+			Expect(handle("tailoredprofile", admissionv1beta1.Create, tp, nil).Allowed).To(BeTrue())
+		})
+
+		It("denies rules selected twice", func() {
+			tp := newTP()
+			tp.Spec.EnableRules = []compv1alpha1.RuleReferenceSpec{{Name: "node-rule"}}
+			tp.Spec.DisableRules = []compv1alpha1.RuleReferenceSpec{{Name: "node-rule"}}
+			Expect(handle("tailoredprofile", admissionv1beta1.Create, tp, nil).Allowed).To(BeFalse())
+		})
+
+		It("denies mixing rule types", func() {
+			tp := newTP()
+			tp.Spec.EnableRules = []compv1alpha1.RuleReferenceSpec{{Name: "node-rule"}, {Name: "platform-rule"}}
+			Expect(handle("tailoredprofile", admissionv1beta1.Create, tp, nil).Allowed).To(BeFalse())
+		})
+
+		It("denies variable values of the wrong type", func() {
+			tp := newTP()
+			tp.Spec.SetValues = []compv1alpha1.VariableValueSpec{{Name: "int-var", Value: "five"}}
+			Expect(handle("tailoredprofile", admissionv1beta1.Create, tp, nil).Allowed).To(BeFalse())
+		})
+
+		It("allows enabling CustomRules", func() {
+			tp := newTP()
+			tp.Spec.Extends = "ocp4-moderate"
+			tp.Spec.CustomRules = []compv1alpha1.RuleReferenceSpec{{Name: "custom-rule"}}
+			Expect(handle("tailoredprofile", admissionv1beta1.Create, tp, nil).Allowed).To(BeTrue())
+		})
+
+		It("denies nonexistent CustomRules", func() {
+			tp := newTP()
+			tp.Spec.Extends = "ocp4-moderate"
+			tp.Spec.CustomRules = []compv1alpha1.RuleReferenceSpec{{Name: "nonexistent"}}
+			Expect(handle("tailoredprofile", admissionv1beta1.Create, tp, nil).Allowed).To(BeFalse())
+		})
+
+		It("denies CustomRules that collide with a rule of the content", func() {
+			tp := newTP()
+			tp.Spec.Extends = "ocp4-moderate"
+			tp.Spec.CustomRules = []compv1alpha1.RuleReferenceSpec{{Name: "platform-rule"}}
+			Expect(handle("tailoredprofile", admissionv1beta1.Create, tp, nil).Allowed).To(BeFalse())
+		})
+
+		It("denies selecting CustomRules with selectors", func() {
+			tp := newTP()
+			tp.Spec.Extends = "ocp4-moderate"
+			tp.Spec.CustomRules = []compv1alpha1.RuleReferenceSpec{{
+				Selector: &compv1alpha1.RuleSelectorSpec{Severities: []string{"high"}},
+			}}
+			Expect(handle("tailoredprofile", admissionv1beta1.Create, tp, nil).Allowed).To(BeFalse())
+		})
+	})
+
+	Context("validating CustomRules", func() {
+		newCustomRule := func() *compv1alpha1.CustomRule {
+			return &compv1alpha1.CustomRule{
+				ObjectMeta: metav1.ObjectMeta{Name: "custom", Namespace: namespace},
+				Spec: compv1alpha1.CustomRuleSpec{
+					Title: "Custom",
+					Input: compv1alpha1.CustomRuleInput{
+						APIPath: "/apis/config.openshift.io/v1/apiservers/cluster",
+						Filter:  ".spec.tlsSecurityProfile",
+					},
+					Expression: `.type == "Modern"`,
+				},
+			}
+		}
+
+		It("allows a valid CustomRule", func() {
+			Expect(handle("customrule", admissionv1beta1.Create, newCustomRule(), nil).Allowed).To(BeTrue())
+		})
+
+		It("denies an invalid filter", func() {
+			customRule := newCustomRule()
+			customRule.Spec.Input.Filter = ".spec | ["
+			Expect(handle("customrule", admissionv1beta1.Create, customRule, nil).Allowed).To(BeFalse())
+		})
+
+		It("denies an invalid expression", func() {
+			customRule := newCustomRule()
+			customRule.Spec.Expression = "if . then"
+			Expect(handle("customrule", admissionv1beta1.Create, customRule, nil).Allowed).To(BeFalse())
+		})
+
+		It("denies names that collide with a rule of the content", func() {
+			customRule := newCustomRule()
+			customRule.Name = "platform-rule"
+			Expect(handle("customrule", admissionv1beta1.Create, customRule, nil).Allowed).To(BeFalse())
+
+			By("Denying names whose check results would collide")
+			customRule.Name = "legacy-check"
+			Expect(handle("customrule", admissionv1beta1.Create, customRule, nil).Allowed).To(BeFalse())
+		})
+
+		It("denies node checks", func() {
+			customRule := newCustomRule()
+			customRule.Spec.CheckType = compv1alpha1.CheckTypeNode
+			Expect(handle("customrule", admissionv1beta1.Create, customRule, nil).Allowed).To(BeFalse())
+		})
+	})
+
+	Context("generating the webhook PKI and configurations", func() {
+		It("creates a serving certificate that doesn't need renewal", func() {
+			secret, err := newServingCertSecret(namespace)
+			Expect(err).To(BeNil())
+			Expect(secret.Data).To(HaveKey("ca.crt"))
+			Expect(secret.Data).To(HaveKey(corev1.TLSPrivateKeyKey))
+			Expect(certNeedsRenewal(secret.Data[corev1.TLSCertKey])).To(BeFalse())
+			Expect(certNeedsRenewal([]byte("garbage"))).To(BeTrue())
+		})
+
+		It("points every webhook to a registered handler", func() {
+			vwc := newValidatingConfiguration(namespace, []byte("ca"))
+			Expect(vwc.Webhooks).To(HaveLen(6))
+			for _, wh := range vwc.Webhooks {
+				Expect(handlers).To(HaveKey(*wh.ClientConfig.Service.Path))
+			}
+			mwc := newMutatingConfiguration(namespace, []byte("ca"))
+			for _, wh := range mwc.Webhooks {
+				Expect(handlers).To(HaveKey(*wh.ClientConfig.Service.Path))
+			}
+		})
+	})
+})
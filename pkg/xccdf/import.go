@@ -0,0 +1,267 @@
+package xccdf
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	cmpv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+)
+
+// The elements of a tailoring file as written by other tools, e.g. SCAP
+// Workbench. Unlike the elements above, they are matched by namespace
+// rather than by prefix.
+
+// Tailoring is a parsed XCCDF tailoring file
+type Tailoring struct {
+	XMLName   xml.Name `xml:"http://checklists.nist.gov/xccdf/1.2 Tailoring"`
+	ID        string   `xml:"id,attr"`
+	Benchmark struct {
+		Href string `xml:"href,attr"`
+	} `xml:"http://checklists.nist.gov/xccdf/1.2 benchmark"`
+	Version struct {
+		Time  string `xml:"time,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"http://checklists.nist.gov/xccdf/1.2 version"`
+	Profiles []TailoringProfile `xml:"http://checklists.nist.gov/xccdf/1.2 Profile"`
+}
+
+// TailoringProfile is a profile of a parsed tailoring file
+type TailoringProfile struct {
+	ID           string              `xml:"id,attr"`
+	Extends      string              `xml:"extends,attr"`
+	Titles       []string            `xml:"http://checklists.nist.gov/xccdf/1.2 title"`
+	Descriptions []string            `xml:"http://checklists.nist.gov/xccdf/1.2 description"`
+	Selections   []TailoringSelect   `xml:"http://checklists.nist.gov/xccdf/1.2 select"`
+	Values       []TailoringSetValue `xml:"http://checklists.nist.gov/xccdf/1.2 set-value"`
+	RefineValues []TailoringRefine   `xml:"http://checklists.nist.gov/xccdf/1.2 refine-value"`
+}
+
+// TailoringSelect selects or deselects a rule
+type TailoringSelect struct {
+	IDRef    string `xml:"idref,attr"`
+	Selected bool   `xml:"selected,attr"`
+}
+
+// TailoringSetValue sets a variable to a value
+type TailoringSetValue struct {
+	IDRef string `xml:"idref,attr"`
+	Value string `xml:",chardata"`
+}
+
+// TailoringRefine sets a variable to one of the values it lists, by selector
+type TailoringRefine struct {
+	IDRef    string `xml:"idref,attr"`
+	Selector string `xml:"selector,attr"`
+}
+
+// BundleContent is the content of a ProfileBundle that a tailoring is
+// validated against, keyed by XCCDF ID
+type BundleContent struct {
+	Profiles  map[string]*cmpv1alpha1.Profile
+	Rules     map[string]*cmpv1alpha1.Rule
+	Variables map[string]*cmpv1alpha1.Variable
+}
+
+var profileIDRegex = regexp.MustCompile(`^xccdf_[^_]+_profile_`)
+
+// ParseTailoring parses an XCCDF 1.2 tailoring file
+func ParseTailoring(r io.Reader) (*Tailoring, error) {
+	tailoring := &Tailoring{}
+	if err := xml.NewDecoder(r).Decode(tailoring); err != nil {
+		return nil, fmt.Errorf("parsing the tailoring file: %w", err)
+	}
+	if len(tailoring.Profiles) == 0 {
+		return nil, fmt.Errorf("the tailoring file doesn't contain any profile")
+	}
+	return tailoring, nil
+}
+
+// GetProfile returns the profile of the tailoring with the given ID. If
+// the ID is empty, the tailoring must contain a single profile.
+func (t *Tailoring) GetProfile(id string) (*TailoringProfile, error) {
+	if id == "" {
+		if len(t.Profiles) > 1 {
+			return nil, fmt.Errorf("the tailoring file contains %d profiles, one must be chosen", len(t.Profiles))
+		}
+		return &t.Profiles[0], nil
+	}
+	for i := range t.Profiles {
+		if t.Profiles[i].ID == id {
+			return &t.Profiles[i], nil
+		}
+	}
+	return nil, fmt.Errorf("the tailoring file doesn't contain the profile %s", id)
+}
+
+// GetTailoredProfileName gets a TailoredProfile name from the XCCDF ID of
+// a tailored profile, whatever its namespace
+func GetTailoredProfileName(id string) string {
+	trimedName := profileIDRegex.ReplaceAllString(id, "")
+	return strings.ToLower(strings.ReplaceAll(trimedName, "_", "-"))
+}
+
+// TailoringProfileToSpec converts a profile of a tailoring file into the
+// spec of a TailoredProfile. The profile it extends, its rules and its
+// variables must all be part of the bundle content, and the values must
+// be valid for their variables. Every error found is reported at once.
+func TailoringProfileToSpec(profile *TailoringProfile, content *BundleContent, rationale string) (*cmpv1alpha1.TailoredProfileSpec, error) {
+	spec := &cmpv1alpha1.TailoredProfileSpec{
+		Title:       firstNonEmpty(profile.Titles, profile.ID),
+		Description: firstNonEmpty(profile.Descriptions, "Imported from the XCCDF profile "+profile.ID),
+	}
+	var errs []string
+
+	if profile.Extends != "" {
+		extended, ok := content.Profiles[profile.Extends]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("the extended profile %s isn't part of the bundle", profile.Extends))
+		} else {
+			spec.Extends = extended.Name
+		}
+	}
+
+	for _, selection := range profile.Selections {
+		rule, ok := content.Rules[selection.IDRef]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("the rule %s isn't part of the bundle", selection.IDRef))
+			continue
+		}
+		ref := cmpv1alpha1.RuleReferenceSpec{Name: rule.Name, Rationale: rationale}
+		if selection.Selected {
+			spec.EnableRules = append(spec.EnableRules, ref)
+		} else {
+			spec.DisableRules = append(spec.DisableRules, ref)
+		}
+	}
+
+	// Later values override earlier ones, as they do in XCCDF
+	setValue := func(variable *cmpv1alpha1.Variable, value string) {
+		for i := range spec.SetValues {
+			if spec.SetValues[i].Name == variable.Name {
+				spec.SetValues[i].Value = value
+				return
+			}
+		}
+		spec.SetValues = append(spec.SetValues, cmpv1alpha1.VariableValueSpec{
+			Name:      variable.Name,
+			Rationale: rationale,
+			Value:     value,
+		})
+	}
+
+	for _, refine := range profile.RefineValues {
+		variable, ok := content.Variables[refine.IDRef]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("the variable %s isn't part of the bundle", refine.IDRef))
+			continue
+		}
+		value, ok := getSelectorValue(variable, refine.Selector)
+		if !ok {
+			errs = append(errs, fmt.Sprintf("the variable %s doesn't have the selector %s", refine.IDRef, refine.Selector))
+			continue
+		}
+		setValue(variable, value)
+	}
+
+	for _, value := range profile.Values {
+		variable, ok := content.Variables[value.IDRef]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("the variable %s isn't part of the bundle", value.IDRef))
+			continue
+		}
+		if err := variable.DeepCopy().SetValue(value.Value); err != nil {
+			errs = append(errs, fmt.Sprintf("invalid value '%s' for the variable %s: %s", value.Value, value.IDRef, err))
+			continue
+		}
+		setValue(variable, value.Value)
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("the profile %s doesn't match the bundle: %s", profile.ID, strings.Join(errs, "; "))
+	}
+	return spec, nil
+}
+
+func getSelectorValue(variable *cmpv1alpha1.Variable, selector string) (string, bool) {
+	for _, selection := range variable.Selections {
+		if selection.Description == selector {
+			return selection.Value, true
+		}
+	}
+	return "", false
+}
+
+func firstNonEmpty(values []string, fallback string) string {
+	for _, value := range values {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			return trimmed
+		}
+	}
+	return fallback
+}
+
+// PortableTailoring rewrites a tailoring generated for a TailoredProfile so
+// that it can be used outside of the cluster. The benchmark is referenced
+// by the given href instead of the path the content is mounted at in the
+// scanner pods, by default the file name of the data stream.
+func PortableTailoring(tailoring string, benchmarkHref string) (string, error) {
+	parsed, err := ParseTailoring(strings.NewReader(tailoring))
+	if err != nil {
+		return "", err
+	}
+	if len(parsed.Profiles) != 1 {
+		return "", fmt.Errorf("expected a single profile in the tailoring, found %d", len(parsed.Profiles))
+	}
+	if benchmarkHref == "" {
+		benchmarkHref = filepath.Base(parsed.Benchmark.Href)
+	}
+
+	profile := parsed.Profiles[0]
+	element := TailoringElement{
+		XMLNamespaceURI: XCCDFURI,
+		ID:              parsed.ID,
+		Benchmark:       BenchmarkElement{Href: benchmarkHref},
+		Version: VersionElement{
+			Time:  parsed.Version.Time,
+			Value: parsed.Version.Value,
+		},
+		Profile: ProfileElement{
+			ID:      profile.ID,
+			Extends: profile.Extends,
+		},
+	}
+	if len(profile.Titles) > 0 {
+		element.Profile.Title = &TitleOrDescriptionElement{Override: true, Value: profile.Titles[0]}
+	}
+	if len(profile.Descriptions) > 0 {
+		element.Profile.Description = &TitleOrDescriptionElement{Override: true, Value: profile.Descriptions[0]}
+	}
+	for _, selection := range profile.Selections {
+		element.Profile.Selections = append(element.Profile.Selections, SelectElement{
+			IDRef:    selection.IDRef,
+			Selected: selection.Selected,
+		})
+	}
+	for _, value := range profile.Values {
+		element.Profile.Values = append(element.Profile.Values, SetValueElement{
+			IDRef: value.IDRef,
+			Value: value.Value,
+		})
+	}
+	for _, refine := range profile.RefineValues {
+		element.Profile.RefineValues = append(element.Profile.RefineValues, RefineValueElement{
+			IDRef:    refine.IDRef,
+			Selector: refine.Selector,
+		})
+	}
+
+	output, err := xml.MarshalIndent(element, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return XMLHeader + "\n" + string(output) + "\n", nil
+}
@@ -0,0 +1,161 @@
+package xccdf
+
+import (
+	"strings"
+
+	cmpv1alpha1 "github.com/openshift/compliance-operator/pkg/apis/compliance/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const workbenchTailoring = `<?xml version="1.0" encoding="UTF-8"?>
+<xccdf:Tailoring xmlns:xccdf="http://checklists.nist.gov/xccdf/1.2" id="xccdf_scap-workbench_tailoring_default">
+  <xccdf:benchmark href="/usr/share/xml/scap/ssg/content/ssg-ocp4-ds.xml"/>
+  <xccdf:version time="2021-06-01T10:00:00">1</xccdf:version>
+  <xccdf:Profile id="xccdf_org.ssgproject.content_profile_cis_customized" extends="xccdf_org.ssgproject.content_profile_cis">
+    <xccdf:title xmlns:xhtml="http://www.w3.org/1999/xhtml" xml:lang="en-US" override="true">CIS customized</xccdf:title>
+    <xccdf:select idref="xccdf_org.ssgproject.content_rule_audit_logging" selected="false"/>
+    <xccdf:select idref="xccdf_org.ssgproject.content_rule_network_policies" selected="true"/>
+    <xccdf:set-value idref="xccdf_org.ssgproject.content_value_timeout">300</xccdf:set-value>
+    <xccdf:refine-value idref="xccdf_org.ssgproject.content_value_mode" selector="strict"/>
+  </xccdf:Profile>
+</xccdf:Tailoring>
+`
+
+func newImportContent() *BundleContent {
+	return &BundleContent{
+		Profiles: map[string]*cmpv1alpha1.Profile{
+			"xccdf_org.ssgproject.content_profile_cis": {
+				ObjectMeta:     v1.ObjectMeta{Name: "ocp4-cis"},
+				ProfilePayload: cmpv1alpha1.ProfilePayload{ID: "xccdf_org.ssgproject.content_profile_cis"},
+			},
+		},
+		Rules: map[string]*cmpv1alpha1.Rule{
+			"xccdf_org.ssgproject.content_rule_audit_logging": {
+				ObjectMeta: v1.ObjectMeta{Name: "ocp4-audit-logging"},
+			},
+			"xccdf_org.ssgproject.content_rule_network_policies": {
+				ObjectMeta: v1.ObjectMeta{Name: "ocp4-network-policies"},
+			},
+		},
+		Variables: map[string]*cmpv1alpha1.Variable{
+			"xccdf_org.ssgproject.content_value_timeout": {
+				ObjectMeta:      v1.ObjectMeta{Name: "ocp4-timeout"},
+				VariablePayload: cmpv1alpha1.VariablePayload{Type: cmpv1alpha1.VarTypeNumber},
+			},
+			"xccdf_org.ssgproject.content_value_mode": {
+				ObjectMeta: v1.ObjectMeta{Name: "ocp4-mode"},
+				VariablePayload: cmpv1alpha1.VariablePayload{
+					Type: cmpv1alpha1.VarTypeString,
+					Selections: []cmpv1alpha1.ValueSelection{
+						{Description: "lenient", Value: "warn"},
+						{Description: "strict", Value: "enforce"},
+					},
+				},
+			},
+		},
+	}
+}
+
+var _ = Describe("Importing tailoring files", func() {
+	var content *BundleContent
+
+	BeforeEach(func() {
+		content = newImportContent()
+	})
+
+	It("converts a profile into a TailoredProfile spec", func() {
+		tailoring, err := ParseTailoring(strings.NewReader(workbenchTailoring))
+		Expect(err).To(BeNil())
+		profile, err := tailoring.GetProfile("")
+		Expect(err).To(BeNil())
+		Expect(GetTailoredProfileName(profile.ID)).To(Equal("cis-customized"))
+
+		spec, err := TailoringProfileToSpec(profile, content, "imported")
+		Expect(err).To(BeNil())
+		Expect(spec.Extends).To(Equal("ocp4-cis"))
+		Expect(spec.Title).To(Equal("CIS customized"))
+		Expect(spec.Description).To(ContainSubstring(profile.ID))
+		Expect(spec.EnableRules).To(ConsistOf(cmpv1alpha1.RuleReferenceSpec{Name: "ocp4-network-policies", Rationale: "imported"}))
+		Expect(spec.DisableRules).To(ConsistOf(cmpv1alpha1.RuleReferenceSpec{Name: "ocp4-audit-logging", Rationale: "imported"}))
+		Expect(spec.SetValues).To(ConsistOf(
+			cmpv1alpha1.VariableValueSpec{Name: "ocp4-mode", Rationale: "imported", Value: "enforce"},
+			cmpv1alpha1.VariableValueSpec{Name: "ocp4-timeout", Rationale: "imported", Value: "300"},
+		))
+	})
+
+	It("reports every item that doesn't match the bundle", func() {
+		delete(content.Rules, "xccdf_org.ssgproject.content_rule_audit_logging")
+		content.Variables["xccdf_org.ssgproject.content_value_mode"].Selections = nil
+		invalid := strings.Replace(workbenchTailoring, ">300<", ">five minutes<", 1)
+
+		tailoring, err := ParseTailoring(strings.NewReader(invalid))
+		Expect(err).To(BeNil())
+		_, err = TailoringProfileToSpec(&tailoring.Profiles[0], content, "imported")
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("content_rule_audit_logging isn't part of the bundle"))
+		Expect(err.Error()).To(ContainSubstring("doesn't have the selector strict"))
+		Expect(err.Error()).To(ContainSubstring("invalid value 'five minutes'"))
+	})
+
+	It("requires choosing among several profiles", func() {
+		tailoring, err := ParseTailoring(strings.NewReader(workbenchTailoring))
+		Expect(err).To(BeNil())
+		tailoring.Profiles = append(tailoring.Profiles, TailoringProfile{ID: "other"})
+		_, err = tailoring.GetProfile("")
+		Expect(err).ToNot(BeNil())
+		profile, err := tailoring.GetProfile("other")
+		Expect(err).To(BeNil())
+		Expect(profile.ID).To(Equal("other"))
+	})
+
+	It("exports the tailoring of a TailoredProfile", func() {
+		tp := &cmpv1alpha1.TailoredProfile{
+			ObjectMeta: v1.ObjectMeta{Name: "cis-customized"},
+			Spec: cmpv1alpha1.TailoredProfileSpec{
+				Title:       "CIS customized",
+				Description: "Customized",
+				DisableRules: []cmpv1alpha1.RuleReferenceSpec{
+					{Name: "ocp4-audit-logging"},
+				},
+			},
+		}
+		p := &cmpv1alpha1.Profile{ProfilePayload: cmpv1alpha1.ProfilePayload{ID: "xccdf_org.ssgproject.content_profile_cis"}}
+		pb := &cmpv1alpha1.ProfileBundle{Spec: cmpv1alpha1.ProfileBundleSpec{ContentFile: "ssg-ocp4-ds.xml"}}
+		rules := map[string]*cmpv1alpha1.Rule{
+			"ocp4-audit-logging": {RulePayload: cmpv1alpha1.RulePayload{ID: "xccdf_org.ssgproject.content_rule_audit_logging"}},
+		}
+		generated, err := TailoredProfileToXML(tp, p, pb, rules, nil)
+		Expect(err).To(BeNil())
+		Expect(generated).To(ContainSubstring(`href="/content/ssg-ocp4-ds.xml"`))
+
+		exported, err := PortableTailoring(generated, "")
+		Expect(err).To(BeNil())
+		Expect(exported).To(ContainSubstring(`href="ssg-ocp4-ds.xml"`))
+
+		// The exported tailoring can be imported back
+		tailoring, err := ParseTailoring(strings.NewReader(exported))
+		Expect(err).To(BeNil())
+		spec, err := TailoringProfileToSpec(&tailoring.Profiles[0], content, "imported")
+		Expect(err).To(BeNil())
+		Expect(spec.Extends).To(Equal("ocp4-cis"))
+		Expect(spec.Title).To(Equal("CIS customized"))
+		Expect(spec.Description).To(Equal("Customized"))
+		Expect(spec.DisableRules).To(ConsistOf(cmpv1alpha1.RuleReferenceSpec{Name: "ocp4-audit-logging", Rationale: "imported"}))
+	})
+
+	It("keeps the refined values when exporting a tailoring", func() {
+		exported, err := PortableTailoring(workbenchTailoring, "ssg-ocp4-ds.xml")
+		Expect(err).To(BeNil())
+		Expect(exported).To(ContainSubstring(`refine-value idref="xccdf_org.ssgproject.content_value_mode" selector="strict"`))
+
+		tailoring, err := ParseTailoring(strings.NewReader(exported))
+		Expect(err).To(BeNil())
+		Expect(tailoring.Profiles[0].RefineValues).To(ConsistOf(TailoringRefine{
+			IDRef:    "xccdf_org.ssgproject.content_value_mode",
+			Selector: "strict",
+		}))
+	})
+})
@@ -48,13 +48,14 @@ type VersionElement struct {
 }
 
 type ProfileElement struct {
-	XMLName     xml.Name                   `xml:"xccdf-1.2:Profile"`
-	ID          string                     `xml:"id,attr"`
-	Extends     string                     `xml:"extends,attr,omitempty"`
-	Title       *TitleOrDescriptionElement `xml:"xccdf-1.2:title"`
-	Description *TitleOrDescriptionElement `xml:"xccdf-1.2:description"`
-	Selections  []SelectElement
-	Values      []SetValueElement
+	XMLName      xml.Name                   `xml:"xccdf-1.2:Profile"`
+	ID           string                     `xml:"id,attr"`
+	Extends      string                     `xml:"extends,attr,omitempty"`
+	Title        *TitleOrDescriptionElement `xml:"xccdf-1.2:title"`
+	Description  *TitleOrDescriptionElement `xml:"xccdf-1.2:description"`
+	Selections   []SelectElement
+	Values       []SetValueElement
+	RefineValues []RefineValueElement
 }
 
 type TitleOrDescriptionElement struct {
@@ -74,6 +75,12 @@ type SetValueElement struct {
 	Value   string   `xml:",chardata"`
 }
 
+type RefineValueElement struct {
+	XMLName  xml.Name `xml:"xccdf-1.2:refine-value"`
+	IDRef    string   `xml:"idref,attr"`
+	Selector string   `xml:"selector,attr"`
+}
+
 // GetXCCDFProfileID gets a profile xccdf ID from the TailoredProfile object
 func GetXCCDFProfileID(tp *cmpv1alpha1.TailoredProfile) string {
 	return fmt.Sprintf("xccdf_%s_profile_%s", XCCDFNamespace, tp.Name)
@@ -136,6 +143,25 @@ func getValuesFromVariables(variables []*cmpv1alpha1.Variable) []SetValueElement
 
 // TailoredProfileToXML gets an XML string from a TailoredProfile and the corresponding Profile
 func TailoredProfileToXML(tp *cmpv1alpha1.TailoredProfile, p *cmpv1alpha1.Profile, pb *cmpv1alpha1.ProfileBundle, rules map[string]*cmpv1alpha1.Rule, variables []*cmpv1alpha1.Variable) (string, error) {
+	return tailoringToXML(tp, p, pb, getSelections(tp, rules), variables)
+}
+
+// ComposedProfileToXML gets an XML string from a TailoredProfile that
+// extends several profiles. The rule selections and variable values of
+// all of them are expected to be flattened already, relative to the
+// given Profile that the tailored profile extends in XCCDF terms, if any.
+func ComposedProfileToXML(tp *cmpv1alpha1.TailoredProfile, base *cmpv1alpha1.Profile, pb *cmpv1alpha1.ProfileBundle, enabled, disabled []*cmpv1alpha1.Rule, variables []*cmpv1alpha1.Variable) (string, error) {
+	selections := []SelectElement{}
+	for _, rule := range enabled {
+		selections = append(selections, getSelectElementFromCRRule(rule, true))
+	}
+	for _, rule := range disabled {
+		selections = append(selections, getSelectElementFromCRRule(rule, false))
+	}
+	return tailoringToXML(tp, base, pb, selections, variables)
+}
+
+func tailoringToXML(tp *cmpv1alpha1.TailoredProfile, p *cmpv1alpha1.Profile, pb *cmpv1alpha1.ProfileBundle, selections []SelectElement, variables []*cmpv1alpha1.Variable) (string, error) {
 	tailoring := TailoringElement{
 		XMLNamespaceURI: XCCDFURI,
 		ID:              getTailoringID(tp),
@@ -151,7 +177,7 @@ func TailoredProfileToXML(tp *cmpv1alpha1.TailoredProfile, p *cmpv1alpha1.Profil
 		},
 		Profile: ProfileElement{
 			ID:         GetXCCDFProfileID(tp),
-			Selections: getSelections(tp, rules),
+			Selections: selections,
 			Values:     getValuesFromVariables(variables),
 		},
 	}